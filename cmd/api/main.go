@@ -10,6 +10,7 @@ import (
 
 	"github.com/kainuguru/kainuguru-api/cmd/api/server"
 	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/monitoring"
 	"github.com/kainuguru/kainuguru-api/pkg/logger"
 	"github.com/rs/zerolog/log"
 )
@@ -37,6 +38,12 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to setup logger")
 	}
 
+	// Setup tracing
+	shutdownTracing, err := monitoring.InitTracing(context.Background(), cfg.Tracing, "kainuguru-api", env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup tracing")
+	}
+
 	// Create server
 	srv, err := server.New(cfg)
 	if err != nil {
@@ -70,6 +77,9 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error().Err(err).Msg("Failed to shutdown server gracefully")
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
 
 	log.Info().Msg("Server shutdown complete")
 }
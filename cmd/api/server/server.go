@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"time"
 
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -20,14 +21,18 @@ import (
 	"github.com/kainuguru/kainuguru-api/internal/middleware"
 	"github.com/kainuguru/kainuguru-api/internal/repositories"
 	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
 	"github.com/kainuguru/kainuguru-api/internal/services/wizard"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
 )
 
 type Server struct {
-	app    *fiber.App
-	config *config.Config
-	db     *database.BunDB
-	redis  *cache.RedisClient
+	app        *fiber.App
+	config     *config.Config
+	db         *database.BunDB
+	redis      *cache.RedisClient
+	queueRedis *goredis.Client
+	services   *services.ServiceFactory
 }
 
 func New(cfg *config.Config) (*Server, error) {
@@ -51,6 +56,21 @@ func New(cfg *config.Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize Redis: %w", err)
 	}
 
+	// Connect to the worker job queue for the job admin API. The API starts
+	// without it; the job queries and mutations then report it unavailable.
+	// Workers report job progress to Redis whichever backend queues the jobs.
+	var jobProgress *worker.ProgressStore
+	queueRedis, err := worker.NewRedisClient(context.Background(), cfg.Redis)
+	if err != nil {
+		log.Warn().Err(err).Msg("Job queue Redis unavailable, job progress is disabled")
+	} else {
+		jobProgress = worker.NewProgressStore(queueRedis, "")
+	}
+	jobQueue, err := worker.NewQueue(cfg.Worker, queueRedis, db.DB, worker.DefaultQueueName)
+	if err != nil {
+		log.Warn().Err(err).Msg("Job queue unavailable, job administration is disabled")
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  cfg.Server.ReadTimeout,
@@ -63,13 +83,15 @@ func New(cfg *config.Config) (*Server, error) {
 	setupMiddleware(app, cfg, redis)
 
 	// Setup routes
-	setupRoutes(app, db, redis, cfg)
+	serviceFactory := setupRoutes(app, db, redis, jobQueue, jobProgress, cfg)
 
 	return &Server{
-		app:    app,
-		config: cfg,
-		db:     db,
-		redis:  redis,
+		app:        app,
+		config:     cfg,
+		db:         db,
+		redis:      redis,
+		queueRedis: queueRedis,
+		services:   serviceFactory,
 	}, nil
 }
 
@@ -87,6 +109,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		log.Error().Err(err).Msg("Failed to shutdown HTTP server")
 	}
 
+	// Write buffered search analytics before the database goes away
+	if err := s.services.SearchService().Close(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush search analytics")
+	}
+
 	// Close database connections
 	if err := s.db.Close(); err != nil {
 		log.Error().Err(err).Msg("Failed to close database")
@@ -96,6 +123,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if err := s.redis.Close(); err != nil {
 		log.Error().Err(err).Msg("Failed to close Redis")
 	}
+	if s.queueRedis != nil {
+		if err := s.queueRedis.Close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close job queue Redis")
+		}
+	}
 
 	return nil
 }
@@ -108,6 +140,9 @@ func setupMiddleware(app *fiber.App, cfg *config.Config, redis *cache.RedisClien
 	// Recovery middleware
 	app.Use(recover.New())
 
+	// Tracing middleware, first so the request span covers the others
+	app.Use(middleware.Tracing())
+
 	// Request ID middleware
 	app.Use(requestid.New())
 
@@ -128,9 +163,9 @@ func setupMiddleware(app *fiber.App, cfg *config.Config, redis *cache.RedisClien
 	app.Use(middleware.Logger())
 }
 
-func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, cfg *config.Config) {
+func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, jobQueue worker.Queue, jobProgress *worker.ProgressStore, cfg *config.Config) *services.ServiceFactory {
 	// Health check endpoint
-	app.Get("/health", handlers.Health(db, redis))
+	app.Get("/health", handlers.Health(db, redis, partition.NewManager(db.DB, cfg.Partitions)))
 
 	// Static file server for flyer images
 	// Serves files from /kainuguru-public at /static URL path
@@ -143,7 +178,7 @@ func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, c
 	})
 
 	// Initialize service factory
-	serviceFactory := services.NewServiceFactoryWithConfig(db.DB, cfg)
+	serviceFactory := services.NewServiceFactoryWithConfig(db.DB, cfg).WithRedis(redis).WithJobQueue(jobQueue).WithJobProgress(jobProgress)
 	authService := serviceFactory.AuthService()
 
 	// Initialize wizard service with cache and dependencies
@@ -160,6 +195,9 @@ func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, c
 	// Initialize rate limiter
 	rateLimiter := cache.NewRateLimiter(redis.Client())
 
+	// Admin user IDs were validated when the config was loaded
+	adminUserIDs, _ := cfg.Auth.AdminIDs()
+
 	// Configure GraphQL handler with all services
 	graphqlConfig := handlers.GraphQLConfig{
 		StoreService:               serviceFactory.StoreService(),
@@ -168,6 +206,7 @@ func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, c
 		ProductService:             serviceFactory.ProductService(),
 		ProductMasterService:       serviceFactory.ProductMasterService(),
 		ExtractionJobService:       serviceFactory.ExtractionJobService(),
+		JobAdminService:            serviceFactory.JobAdminService(),
 		SearchService:              serviceFactory.SearchService(),
 		AuthService:                authService,
 		ShoppingListService:        serviceFactory.ShoppingListService(),
@@ -176,6 +215,7 @@ func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, c
 		WizardService:              wizardService,
 		UserStorePreferenceService: serviceFactory.UserStorePreferenceService(),
 		RateLimiter:                rateLimiter,
+		AdminUserIDs:               adminUserIDs,
 		DB:                         db.DB,
 	}
 
@@ -218,6 +258,8 @@ func setupRoutes(app *fiber.App, db *database.BunDB, redis *cache.RedisClient, c
 
 	// GraphQL playground (development only)
 	app.Get("/playground", handlers.PlaygroundHandler())
+
+	return serviceFactory
 }
 
 func errorHandler(c *fiber.Ctx, err error) error {
@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
@@ -12,10 +16,30 @@ import (
 	"github.com/kainuguru/kainuguru-api/internal/config"
 	"github.com/kainuguru/kainuguru-api/internal/database"
 	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/archive"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/uptrace/bun"
 )
 
+const usage = `Archive ended flyers and move old data to archives.
+
+Usage:
+  archive-flyers [-debug] [-dry-run] [command] [flags] [args]
+
+Commands:
+  mark                                       Mark flyers ended over a week ago archived (default)
+  archive [-type T] [-older-than DAYS]       Move rows older than DAYS to archives, every type unless -type
+  list [-type T] [-limit N] [-offset N]      List archives, newest first
+  show <id>                                  Show an archive's manifest
+  restore <id>                               Verify an archive and restore its rows
+  delete <id>                                Delete an archive and its manifest
+  expire                                     Delete archives past their retention
+  stats                                      Show archive counts, sizes and storage health
+
+Types: products, price_history, flyers, extraction_jobs
+`
+
 var (
 	debug      bool
 	dryRun     bool
@@ -24,8 +48,9 @@ var (
 
 func main() {
 	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
-	flag.BoolVar(&dryRun, "dry-run", false, "Preview what would be archived without making changes")
+	flag.BoolVar(&dryRun, "dry-run", false, "Preview what would be marked archived without making changes")
 	flag.StringVar(&configPath, "config", "", "Path to custom config file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
 	flag.Parse()
 
 	// Setup logger
@@ -67,12 +92,28 @@ func main() {
 
 	log.Info().Msg("Database connection established")
 
-	// Create services
-	flyerService := services.NewFlyerService(db)
-
 	// Create context
 	ctx := context.Background()
 
+	command := flag.Arg(0)
+	if command == "" || command == "mark" {
+		if err := markFlyers(ctx, db); err != nil {
+			log.Fatal().Err(err).Msg("Failed to archive flyers")
+		}
+		return
+	}
+
+	archiver := services.NewServiceFactoryWithConfig(db, cfg).ArchiverService()
+	if err := run(ctx, archiver, command, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "archive-flyers %s: %v\n", command, err)
+		os.Exit(1)
+	}
+}
+
+// markFlyers marks flyers that ended over a week ago archived, listing them first
+func markFlyers(ctx context.Context, db *bun.DB) error {
+	flyerService := services.NewFlyerService(db)
+
 	// Check what would be archived
 	cutoffDate := time.Now().AddDate(0, 0, -7)
 	log.Info().
@@ -87,7 +128,7 @@ func main() {
 		StoreID int       `bun:"store_id"`
 	}
 
-	err = db.NewSelect().
+	err := db.NewSelect().
 		TableExpr("flyers").
 		Column("id", "title", "valid_to", "store_id").
 		Where("valid_to < ?", cutoffDate).
@@ -95,12 +136,12 @@ func main() {
 		Scan(ctx, &flyersToArchive)
 
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to query flyers")
+		return fmt.Errorf("failed to query flyers: %w", err)
 	}
 
 	if len(flyersToArchive) == 0 {
 		log.Info().Msg("No flyers to archive")
-		return
+		return nil
 	}
 
 	log.Info().
@@ -119,13 +160,13 @@ func main() {
 
 	if dryRun {
 		log.Info().Msg("Dry run - no changes made")
-		return
+		return nil
 	}
 
 	// Archive old flyers
 	archived, err := flyerService.ArchiveOldFlyers(ctx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to archive flyers")
+		return err
 	}
 
 	log.Info().
@@ -140,7 +181,7 @@ func main() {
 	}
 
 	err = db.NewRaw(`
-		SELECT 
+		SELECT
 			COUNT(*) as total_flyers,
 			SUM(CASE WHEN is_archived = false THEN 1 ELSE 0 END) as active_flyers,
 			SUM(CASE WHEN is_archived = true THEN 1 ELSE 0 END) as archived_flyers
@@ -158,4 +199,217 @@ func main() {
 	}
 
 	log.Info().Msg("Archive process completed successfully")
+	return nil
+}
+
+// run executes one command against the archiver
+func run(ctx context.Context, archiver archive.ArchiverService, command string, args []string) error {
+	switch command {
+	case "archive":
+		return archiveData(ctx, archiver, args)
+	case "list":
+		return listArchives(ctx, archiver, args)
+	case "show":
+		id, err := singleArg("show", "archive id", args)
+		if err != nil {
+			return err
+		}
+		meta, err := archiver.GetArchiveMetadata(ctx, id)
+		if err != nil {
+			return err
+		}
+		return printJSON(meta)
+	case "restore":
+		id, err := singleArg("restore", "archive id", args)
+		if err != nil {
+			return err
+		}
+		meta, err := archiver.GetArchiveMetadata(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := archiver.RestoreArchivedData(ctx, id, meta.DataType); err != nil {
+			return err
+		}
+		fmt.Printf("Restored %d %s records from archive %s\n", meta.RecordCount, meta.DataType, id)
+		return nil
+	case "delete":
+		id, err := singleArg("delete", "archive id", args)
+		if err != nil {
+			return err
+		}
+		if err := archiver.DeleteArchive(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("Archive %s deleted\n", id)
+		return nil
+	case "expire":
+		expired, err := archiver.DeleteExpiredArchives(ctx)
+		for _, meta := range expired {
+			fmt.Printf("Deleted %s (retention ended %s)\n", meta.ID, meta.RetentionUntil.Local().Format(time.DateOnly))
+		}
+		if err != nil {
+			return fmt.Errorf("deleted %d expired archives before failing: %w", len(expired), err)
+		}
+		fmt.Printf("Deleted %d expired archives\n", len(expired))
+		return nil
+	case "stats":
+		return printStats(ctx, archiver)
+	default:
+		return fmt.Errorf("unknown command, run archive-flyers -h for usage")
+	}
+}
+
+// archiveData archives the given type, or every type with flyers after the
+// products and prices referencing them
+func archiveData(ctx context.Context, archiver archive.ArchiverService, args []string) error {
+	var dataType string
+	var days int
+	flags := flag.NewFlagSet("archive", flag.ContinueOnError)
+	flags.StringVar(&dataType, "type", "", "Only archive this type")
+	flags.IntVar(&days, "older-than", 90, "Archive rows older than this many days")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if days <= 0 {
+		return fmt.Errorf("-older-than must be positive")
+	}
+	olderThan := time.Duration(days) * 24 * time.Hour
+
+	steps := []struct {
+		dataType archive.ArchiveDataType
+		run      func(context.Context, time.Duration) (*archive.ArchivalResult, error)
+	}{
+		{archive.ArchiveTypeProducts, archiver.ArchiveOldProducts},
+		{archive.ArchiveTypePriceHistory, archiver.ArchiveOldPrices},
+		{archive.ArchiveTypeFlyers, archiver.ArchiveOldFlyers},
+		{archive.ArchiveTypeExtractionJobs, archiver.ArchiveCompletedExtractionJobs},
+	}
+
+	ran := false
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tARCHIVE\tRECORDS\tSIZE\tRATIO\tDURATION\tERROR")
+	for _, step := range steps {
+		if dataType != "" && string(step.dataType) != dataType {
+			continue
+		}
+		ran = true
+		result, err := step.run(ctx, olderThan)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		archiveID := result.ArchiveID
+		if archiveID == "" {
+			archiveID = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%.2f\t%s\t%s\n",
+			step.dataType, archiveID, result.RecordsArchived,
+			formatBytes(result.ArchiveSize), result.CompressionRatio,
+			result.Duration.Round(time.Millisecond), truncate(result.Error, 60),
+		)
+	}
+	if !ran {
+		return fmt.Errorf("unknown type %q", dataType)
+	}
+	return w.Flush()
+}
+
+func listArchives(ctx context.Context, archiver archive.ArchiverService, args []string) error {
+	var dataType string
+	var limit, offset int
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.StringVar(&dataType, "type", "", "Only list archives of this type")
+	flags.IntVar(&limit, "limit", 50, "Maximum archives to list")
+	flags.IntVar(&offset, "offset", 0, "Archives to skip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	archives, err := archiver.ListArchives(ctx, archive.ArchiveDataType(dataType), limit, offset)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tRECORDS\tSIZE\tCREATED\tRETAINED UNTIL\tERROR")
+	for _, meta := range archives {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			meta.ID, meta.DataType, meta.Status, meta.RecordCount,
+			formatBytes(meta.ArchiveSize),
+			meta.CreatedAt.Local().Format(time.DateTime),
+			meta.RetentionUntil.Local().Format(time.DateOnly),
+			truncate(meta.Error, 60),
+		)
+	}
+	return w.Flush()
+}
+
+func printStats(ctx context.Context, archiver archive.ArchiverService) error {
+	stats, err := archiver.GetArchivalStatistics(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Archives\t%d\n", stats.TotalArchives)
+	fmt.Fprintf(w, "Archived size\t%s\n", formatBytes(stats.TotalArchivedBytes))
+	fmt.Fprintf(w, "Average compression\t%.2f\n", stats.AverageCompression)
+	for _, dataType := range []archive.ArchiveDataType{
+		archive.ArchiveTypeProducts,
+		archive.ArchiveTypePriceHistory,
+		archive.ArchiveTypeFlyers,
+		archive.ArchiveTypeExtractionJobs,
+	} {
+		fmt.Fprintf(w, "  %s\t%d (%s)\n", dataType, stats.ArchivesByType[dataType], formatBytes(stats.SizeByType[dataType]))
+	}
+	if stats.OldestArchive != nil {
+		fmt.Fprintf(w, "Oldest\t%s\n", stats.OldestArchive.Local().Format(time.DateTime))
+		fmt.Fprintf(w, "Newest\t%s\n", stats.NewestArchive.Local().Format(time.DateTime))
+	}
+	if health := stats.StorageHealth; health != nil {
+		status := "available"
+		if !health.Available {
+			status = "unavailable"
+		}
+		if len(health.Issues) > 0 {
+			status += " (" + strings.Join(health.Issues, "; ") + ")"
+		}
+		fmt.Fprintf(w, "Storage\t%s, %s used\n", status, formatBytes(health.UsedSpace))
+	}
+	return w.Flush()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func singleArg(command, name string, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("usage: archive-flyers %s <%s>", command, strings.ReplaceAll(name, " ", "-"))
+	}
+	return args[0], nil
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func truncate(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/rs/zerolog"
+	"github.com/uptrace/bun"
+)
+
+// field is a set of derived product columns the command fills in
+type field struct {
+	name string
+	// columns are loaded for apply; id and valid_from are always loaded
+	columns []string
+	// pending keeps the products missing the field, unless --all is set
+	pending func(q *bun.SelectQuery) *bun.SelectQuery
+	// saved are the columns written back
+	saved []string
+	// apply computes the field and reports whether the product got a value
+	apply func(p *models.Product) bool
+	// describe adds the computed value to debug logs
+	describe func(e *zerolog.Event, p *models.Product) *zerolog.Event
+}
+
+// fields are filled in during enrichment, but products saved before their
+// migration (041 for promotions, 046 for unit prices, 047 for stemmed names)
+// have none until backfilled
+var fields = map[string]field{
+	"unit-prices": {
+		name: "unit price",
+		columns: []string{"name", "current_price", "original_price", "card_price", "app_price",
+			"special_discount", "promotion", "effective_regular_price", "unit_size"},
+		pending: func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("normalized_unit_price IS NULL")
+		},
+		saved: []string{"unit_quantity", "unit_base", "normalized_unit_price"},
+		apply: func(p *models.Product) bool {
+			p.ApplyUnitPrice()
+			return p.NormalizedUnitPrice != nil
+		},
+		describe: func(e *zerolog.Event, p *models.Product) *zerolog.Event {
+			return e.Float64("unit_price", *p.NormalizedUnitPrice).Str("unit", *p.UnitBase)
+		},
+	},
+	"promotions": {
+		name: "promotion",
+		columns: []string{"name", "current_price", "original_price", "card_price", "app_price",
+			"special_discount", "promotion", "unit_size"},
+		pending: func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("promotion IS NULL").Where("special_discount IS NOT NULL AND special_discount != ''")
+		},
+		saved: []string{"promotion", "card_price", "effective_price", "effective_regular_price",
+			"unit_quantity", "unit_base", "normalized_unit_price"},
+		apply: func(p *models.Product) bool {
+			// Loyalty-only deals are only known from an earlier extraction
+			p.ApplyPromotion(p.Promotion != nil && p.Promotion.LoyaltyOnly)
+			return p.Promotion != nil
+		},
+		describe: func(e *zerolog.Event, p *models.Product) *zerolog.Event {
+			return e.Str("promotion", string(p.Promotion.Type)).Float64("effective_price", *p.EffectivePrice)
+		},
+	},
+	"stemmed-names": {
+		name:    "stemmed name",
+		columns: []string{"name", "normalized_name"},
+		pending: func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("stemmed_name IS NULL")
+		},
+		// Updating the rows fires the search vector trigger, which indexes the new stems
+		saved: []string{"normalized_name", "stemmed_name"},
+		apply: func(p *models.Product) bool {
+			if p.NormalizedName == "" {
+				p.NormalizedName = services.NormalizeProductText(p.Name)
+			}
+			p.StemmedName = services.StemProductText(p.NormalizedName)
+			return p.StemmedName != ""
+		},
+		describe: func(e *zerolog.Event, p *models.Product) *zerolog.Event {
+			return e.Str("stemmed_name", p.StemmedName)
+		},
+	},
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"sort"
+	"strings"
+
+	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
+
+	"github.com/joho/godotenv"
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/uptrace/bun"
+)
+
+var (
+	debug      bool
+	dryRun     bool
+	all        bool
+	fieldName  string
+	batchSize  int
+	configPath string
+)
+
+func main() {
+	flag.StringVar(&fieldName, "field", "", "Product field to fill in: "+strings.Join(fieldNames(), ", "))
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.BoolVar(&dryRun, "dry-run", false, "Compute the field without saving it")
+	flag.BoolVar(&all, "all", false, "Recompute every product, not only those missing the field")
+	flag.IntVar(&batchSize, "batch-size", 500, "Products loaded and updated per batch")
+	flag.StringVar(&configPath, "config", "", "Path to custom config file")
+	flag.Parse()
+
+	// Setup logger
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	f, ok := fields[fieldName]
+	if !ok {
+		log.Fatal().Str("field", fieldName).Strs("fields", fieldNames()).Msg("--field must name a backfillable field")
+	}
+	if batchSize <= 0 {
+		log.Fatal().Int("batch_size", batchSize).Msg("--batch-size must be positive")
+	}
+
+	log.Info().Str("field", fieldName).Bool("all", all).Bool("dry_run", dryRun).Msg("Starting " + f.name + " backfill")
+
+	// Load .env file explicitly
+	if err := godotenv.Load(); err != nil {
+		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	}
+
+	// Get environment
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	// Load configuration
+	cfg, err := config.Load(env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Connect to database
+	bunDB, err := database.NewBun(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer bunDB.Close()
+
+	log.Info().Msg("Database connection established")
+
+	processed, filled := backfill(context.Background(), bunDB.DB, f)
+
+	if dryRun {
+		log.Info().Msg("Dry run - no changes made")
+	}
+
+	log.Info().
+		Str("field", fieldName).
+		Int("processed", processed).
+		Int("filled", filled).
+		Int("unknown", processed-filled).
+		Msg("Backfill completed")
+}
+
+// backfill walks the products in id order, in batches of batchSize, and saves
+// the field of each batch in one transaction
+func backfill(ctx context.Context, db *bun.DB, f field) (processed, filled int) {
+	lastID := 0
+	for {
+		var products []*models.Product
+		q := db.NewSelect().
+			Model(&products).
+			Column("id", "valid_from").
+			Column(f.columns...).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize)
+		if !all {
+			q = f.pending(q)
+		}
+		if err := q.Scan(ctx); err != nil {
+			log.Fatal().Err(err).Int("after_id", lastID).Msg("Failed to load products")
+		}
+		if len(products) == 0 {
+			return processed, filled
+		}
+
+		for _, p := range products {
+			if f.apply(p) {
+				filled++
+				f.describe(log.Debug().Int("product_id", p.ID).Str("name", p.Name), p).Msg("Computed " + f.name)
+			}
+		}
+		processed += len(products)
+		lastID = products[len(products)-1].ID
+
+		if !dryRun {
+			if err := save(ctx, db, f, products); err != nil {
+				log.Fatal().Err(err).Int("after_id", lastID).Msg("Failed to save " + f.name)
+			}
+		}
+		log.Info().Int("processed", processed).Int("filled", filled).Int("last_id", lastID).Msg("Batch completed")
+	}
+}
+
+// save writes the field's columns of a batch in one transaction
+func save(ctx context.Context, db *bun.DB, f field, products []*models.Product) error {
+	return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, p := range products {
+			if _, err := tx.NewUpdate().
+				Model(p).
+				Column(f.saved...).
+				Where("p.id = ?", p.ID).
+				Where("p.valid_from = ?", p.ValidFrom).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func fieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
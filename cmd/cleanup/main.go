@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
+
+	"github.com/joho/godotenv"
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/archive"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Clean up and optimize file storage.
+
+Usage:
+  cleanup [-debug] [-dry-run] <command> [flags] [args]
+
+Commands:
+  orphans                                    Delete images no flyer page uses, and unused shared images
+  temp [-older-than HOURS]                   Delete temporary files older than HOURS
+  archives                                   Delete archive files past their retention
+  optimize [flags]                           Recompress old page images to WebP and share identical images
+      -webp                                  Recompress to WebP (default true)
+      -dedupe                                Share identical images (default true)
+      -quality Q                             WebP quality 1-100, the configured quality when 0
+      -older-than DAYS                       Only recompress pages of flyers ended DAYS ago, configured when 0
+      -max-width W, -max-height H            Shrink larger images to fit
+  validate                                   Report broken image references and orphaned files
+  stats                                      Show cleanup history and storage utilization
+  schedule [-disable] [-dry-run] <type> <cron>
+                                             Schedule a cleanup for the worker's run_cleanups job
+  run-due                                    Run the scheduled cleanups that are due
+
+Types: orphaned_images, expired_archives, temp_files, optimize_storage
+`
+
+var (
+	debug  bool
+	dryRun bool
+)
+
+func main() {
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.BoolVar(&dryRun, "dry-run", false, "Report what would be deleted or rewritten without making changes")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	command := flag.Arg(0)
+	if command == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	}
+
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	cfg, err := config.Load(env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	bunDB, err := database.NewBun(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer bunDB.Close()
+
+	cleaner := services.NewServiceFactoryWithConfig(bunDB.DB, cfg).CleanerService()
+	if err := run(context.Background(), cleaner, command, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "cleanup %s: %v\n", command, err)
+		os.Exit(1)
+	}
+}
+
+// run executes one command against the cleaner
+func run(ctx context.Context, cleaner archive.CleanerService, command string, args []string) error {
+	switch command {
+	case "orphans":
+		result, err := cleaner.CleanOrphanedImages(ctx, dryRun)
+		if err != nil {
+			return err
+		}
+		return printResults(result)
+	case "temp":
+		flags := flag.NewFlagSet("temp", flag.ContinueOnError)
+		hours := flags.Int("older-than", 24, "Delete temporary files older than this many hours")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *hours <= 0 {
+			return fmt.Errorf("-older-than must be positive")
+		}
+		result, err := cleaner.CleanTempFiles(ctx, time.Duration(*hours)*time.Hour, dryRun)
+		if err != nil {
+			return err
+		}
+		return printResults(result)
+	case "archives":
+		result, err := cleaner.CleanExpiredArchives(ctx, dryRun)
+		if err != nil {
+			return err
+		}
+		return printResults(result)
+	case "optimize":
+		return optimize(ctx, cleaner, args)
+	case "validate":
+		result, err := cleaner.ValidateImageReferences(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	case "stats":
+		return printStats(ctx, cleaner)
+	case "schedule":
+		return schedule(ctx, cleaner, args)
+	case "run-due":
+		results, err := cleaner.RunDueCleanups(ctx)
+		if printErr := printResults(results...); printErr != nil {
+			return printErr
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown command, run cleanup -h for usage")
+	}
+}
+
+func optimize(ctx context.Context, cleaner archive.CleanerService, args []string) error {
+	options := &archive.OptimizationOptions{DryRun: dryRun}
+	var days int
+	flags := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	flags.BoolVar(&options.ConvertToWebP, "webp", true, "Recompress page images to WebP")
+	flags.BoolVar(&options.RemoveDuplicates, "dedupe", true, "Share identical images between pages")
+	flags.IntVar(&options.ImageQuality, "quality", 0, "WebP quality 1-100, the configured quality when 0")
+	flags.IntVar(&days, "older-than", 0, "Only recompress pages of flyers ended this many days ago, the configured age when 0")
+	flags.IntVar(&options.MaxImageWidth, "max-width", 0, "Shrink wider images to this width")
+	flags.IntVar(&options.MaxImageHeight, "max-height", 0, "Shrink taller images to this height")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if options.ImageQuality < 0 || options.ImageQuality > 100 {
+		return fmt.Errorf("-quality must be between 1 and 100")
+	}
+	options.OlderThan = time.Duration(days) * 24 * time.Hour
+	options.ResizeImages = options.MaxImageWidth > 0 || options.MaxImageHeight > 0
+
+	result, err := cleaner.OptimizeStorage(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Images processed\t%d\n", result.ImagesProcessed)
+	fmt.Fprintf(w, "Recompressed\t%d\n", result.ImagesCompressed)
+	fmt.Fprintf(w, "Resized\t%d\n", result.ImagesResized)
+	fmt.Fprintf(w, "Duplicates removed\t%d\n", result.DuplicatesRemoved)
+	fmt.Fprintf(w, "Space saved\t%s\n", formatBytes(result.SpaceSaved))
+	fmt.Fprintf(w, "Duration\t%s\n", result.Duration.Round(time.Millisecond))
+	if dryRun {
+		fmt.Fprintln(w, "Dry run\tno files were changed")
+	}
+	for _, msg := range result.Errors {
+		fmt.Fprintf(w, "Error\t%s\n", msg)
+	}
+	return w.Flush()
+}
+
+func schedule(ctx context.Context, cleaner archive.CleanerService, args []string) error {
+	var disable, scheduledDryRun bool
+	flags := flag.NewFlagSet("schedule", flag.ContinueOnError)
+	flags.BoolVar(&disable, "disable", false, "Save the schedule disabled")
+	flags.BoolVar(&scheduledDryRun, "dry-run", false, "Only report what the scheduled cleanup would do")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: cleanup schedule [-disable] [-dry-run] <type> <cron>")
+	}
+
+	cleanupConfig := &archive.CleanupConfig{
+		Type:     flags.Arg(0),
+		Schedule: flags.Arg(1),
+		Enabled:  !disable,
+		DryRun:   scheduledDryRun || dryRun,
+	}
+	if err := cleaner.ScheduleRegularCleanup(ctx, cleanupConfig); err != nil {
+		return err
+	}
+	state := "enabled"
+	if disable {
+		state = "disabled"
+	}
+	fmt.Printf("Scheduled %s cleanup at %q (%s)\n", cleanupConfig.Type, cleanupConfig.Schedule, state)
+	return nil
+}
+
+func printResults(results ...*archive.CleanupResult) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tPROCESSED\tDELETED\tFREED\tDURATION\tDRY RUN\tERRORS")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%t\t%d\n",
+			result.OperationType, result.FilesProcessed, result.FilesDeleted,
+			formatBytes(result.BytesFreed), result.Duration.Round(time.Millisecond),
+			result.DryRun, len(result.Errors),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, result := range results {
+		for _, msg := range result.Errors {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.OperationType, msg)
+		}
+	}
+	return nil
+}
+
+func printStats(ctx context.Context, cleaner archive.CleanerService) error {
+	stats, err := cleaner.GetCleanupStatistics(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Cleanups\t%d\n", stats.TotalCleanupOperations)
+	fmt.Fprintf(w, "Files deleted\t%d\n", stats.TotalFilesDeleted)
+	fmt.Fprintf(w, "Space freed\t%s\n", formatBytes(stats.TotalBytesFreed))
+	types := make([]string, 0, len(stats.CleanupsByType))
+	for operationType := range stats.CleanupsByType {
+		types = append(types, operationType)
+	}
+	sort.Strings(types)
+	for _, operationType := range types {
+		fmt.Fprintf(w, "  %s\t%d (%s)\n", operationType, stats.CleanupsByType[operationType], formatBytes(stats.BytesFreedByType[operationType]))
+	}
+	if stats.LastCleanupTime != nil {
+		fmt.Fprintf(w, "Last cleanup\t%s\n", stats.LastCleanupTime.Local().Format(time.DateTime))
+		fmt.Fprintf(w, "Average duration\t%s\n", stats.AverageCleanupDuration.Round(time.Millisecond))
+	}
+	if u := stats.StorageUtilization; u != nil {
+		if u.TotalSpace > 0 {
+			fmt.Fprintf(w, "Disk\t%s of %s used (%.1f%%), %s free\n",
+				formatBytes(u.UsedSpace), formatBytes(u.TotalSpace), u.UsagePercent, formatBytes(u.FreeSpace))
+		}
+		fmt.Fprintf(w, "Images\t%s\n", formatBytes(u.ImageStorage))
+		fmt.Fprintf(w, "Archives\t%s\n", formatBytes(u.ArchiveStorage))
+		fmt.Fprintf(w, "Temporary files\t%s\n", formatBytes(u.TempStorage))
+	}
+	for _, scheduled := range stats.ScheduledCleanups {
+		state := "enabled"
+		if !scheduled.Enabled {
+			state = "disabled"
+		}
+		fmt.Fprintf(w, "Schedule\t%s at %q, %s, next %s\n",
+			scheduled.Type, scheduled.Schedule, state, scheduled.NextRun.Local().Format(time.DateTime))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(stats.RecentOperations) > 0 {
+		fmt.Println()
+		fmt.Println("Recent cleanups:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WHEN\tOPERATION\tDELETED\tFREED\tDRY RUN\tERRORS")
+		for _, op := range stats.RecentOperations {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%t\t%s\n",
+				op.CreatedAt.Local().Format(time.DateTime), op.OperationType, op.FilesDeleted,
+				formatBytes(op.BytesFreed), op.DryRun, truncate(strings.Join(op.Errors, "; "), 60))
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
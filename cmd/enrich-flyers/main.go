@@ -14,6 +14,7 @@ import (
 	"github.com/kainuguru/kainuguru-api/internal/config"
 	"github.com/kainuguru/kainuguru-api/internal/database"
 	"github.com/kainuguru/kainuguru-api/internal/services/enrichment"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -24,9 +25,13 @@ var (
 	forceReprocess bool
 	maxPages       int
 	batchSize      int
+	concurrency    int
 	dryRun         bool
 	debug          bool
 	configPath     string
+	resumeRunID    int64
+	retryFailed    bool
+	workerMode     bool
 )
 
 func main() {
@@ -35,11 +40,19 @@ func main() {
 	flag.BoolVar(&forceReprocess, "force-reprocess", false, "Reprocess completed pages")
 	flag.IntVar(&maxPages, "max-pages", 0, "Maximum pages to process (0=all)")
 	flag.IntVar(&batchSize, "batch-size", 10, "Pages per batch")
+	flag.IntVar(&concurrency, "concurrency", 0, "Pages extracted in parallel (0=provider default from config)")
 	flag.BoolVar(&dryRun, "dry-run", false, "Preview what would be processed")
 	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
 	flag.StringVar(&configPath, "config", "", "Path to custom config file")
+	flag.Int64Var(&resumeRunID, "resume", 0, "Resume an interrupted enrichment run by ID (uses the run's stored options)")
+	flag.BoolVar(&retryFailed, "retry-failed", false, "With --resume, re-process only the pages that failed in that run")
+	flag.BoolVar(&workerMode, "worker", false, "Run as a queue worker processing per-page extraction jobs")
 	flag.Parse()
 
+	if retryFailed && resumeRunID == 0 {
+		log.Fatal().Msg("--retry-failed requires --resume <run-id>")
+	}
+
 	// Setup logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	if debug {
@@ -101,6 +114,11 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to create orchestrator")
 	}
 
+	if workerMode {
+		runWorker(ctx, cfg, db, orchestrator)
+		return
+	}
+
 	// Parse date override
 	var targetDate time.Time
 	if dateOverride != "" {
@@ -119,7 +137,10 @@ func main() {
 		ForceReprocess: forceReprocess,
 		MaxPages:       maxPages,
 		BatchSize:      batchSize,
+		Concurrency:    concurrency,
 		DryRun:         dryRun,
+		ResumeRunID:    resumeRunID,
+		RetryFailed:    retryFailed,
 	}
 
 	log.Info().
@@ -128,7 +149,10 @@ func main() {
 		Bool("force_reprocess", forceReprocess).
 		Int("max_pages", maxPages).
 		Int("batch_size", batchSize).
+		Int("concurrency", concurrency).
 		Bool("dry_run", dryRun).
+		Int64("resume_run_id", resumeRunID).
+		Bool("retry_failed", retryFailed).
 		Msg("Processing options")
 
 	// Run enrichment
@@ -138,3 +162,38 @@ func main() {
 
 	log.Info().Msg("Enrichment completed successfully")
 }
+
+// runWorker processes extract_products jobs from the job queue until shutdown
+func runWorker(ctx context.Context, cfg *config.Config, db *database.BunDB, orchestrator *enrichment.Orchestrator) {
+	redisClient, err := worker.NewRedisClient(ctx, cfg.Redis)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Worker mode requires Redis")
+	}
+	defer redisClient.Close()
+
+	workerConcurrency := concurrency
+	if workerConcurrency <= 0 {
+		workerConcurrency = orchestrator.Concurrency()
+	}
+
+	queue, err := worker.NewQueue(cfg.Worker, redisClient, db.DB, worker.DefaultQueueName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create job queue")
+	}
+	processor := worker.NewWorkerProcessor(queue, redisClient, worker.ProcessorConfig{
+		Concurrency: workerConcurrency,
+	})
+	orchestrator.PageJobHandler(queue).Register(processor)
+
+	if err := processor.Start(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start worker processor")
+	}
+	log.Info().Int("concurrency", workerConcurrency).Str("queue", worker.DefaultQueueName).Msg("Enrichment worker started")
+
+	<-ctx.Done()
+
+	if err := processor.Stop(); err != nil {
+		log.Error().Err(err).Msg("Failed to stop worker processor")
+	}
+	log.Info().Msg("Enrichment worker stopped")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
+
+	"github.com/joho/godotenv"
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	debug      bool
+	dryRun     bool
+	filePath   string
+	configPath string
+)
+
+// barcodeMapping is one GTIN→product master row of the import file
+type barcodeMapping struct {
+	line     int
+	gtin     string
+	masterID int64
+}
+
+func main() {
+	flag.StringVar(&filePath, "file", "", "CSV file with gtin,master_id rows (required)")
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.BoolVar(&dryRun, "dry-run", false, "Validate the file without making changes")
+	flag.StringVar(&configPath, "config", "", "Path to custom config file")
+	flag.Parse()
+
+	// Setup logger
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	if filePath == "" {
+		log.Fatal().Msg("--file is required")
+	}
+
+	log.Info().Str("file", filePath).Msg("Starting barcode import")
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open import file")
+	}
+	defer file.Close()
+
+	mappings, invalid := readMappings(file)
+	log.Info().
+		Int("valid", len(mappings)).
+		Int("invalid", invalid).
+		Msg("Import file parsed")
+
+	if dryRun {
+		for _, m := range mappings {
+			log.Info().Int("line", m.line).Str("gtin", m.gtin).Int64("master_id", m.masterID).Msg("Would assign barcode")
+		}
+		log.Info().Msg("Dry run - no changes made")
+		return
+	}
+
+	// Load .env file explicitly
+	if err := godotenv.Load(); err != nil {
+		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	}
+
+	// Get environment
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	// Load configuration
+	cfg, err := config.Load(env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Connect to database
+	bunDB, err := database.NewBun(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer bunDB.Close()
+
+	log.Info().Msg("Database connection established")
+
+	masterService := services.NewProductMasterService(bunDB.DB)
+	ctx := context.Background()
+
+	assigned, failed := 0, 0
+	for _, m := range mappings {
+		if _, err := masterService.AssignBarcode(ctx, m.masterID, m.gtin); err != nil {
+			failed++
+			log.Warn().Err(err).Int("line", m.line).Str("gtin", m.gtin).Int64("master_id", m.masterID).Msg("Failed to assign barcode")
+			continue
+		}
+		assigned++
+		log.Debug().Str("gtin", m.gtin).Int64("master_id", m.masterID).Msg("Barcode assigned")
+	}
+
+	log.Info().
+		Int("assigned", assigned).
+		Int("failed", failed).
+		Int("invalid", invalid).
+		Msg("Barcode import completed")
+}
+
+// readMappings parses gtin,master_id rows, skipping a header row and logging
+// rows with an invalid barcode or master ID
+func readMappings(r io.Reader) ([]barcodeMapping, int) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var mappings []barcodeMapping
+	invalid := 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Fatal().Err(err).Int("line", line).Msg("Failed to read import file")
+		}
+
+		mapping, err := parseMapping(record)
+		if err != nil {
+			// Allow a "gtin,master_id" header
+			if line == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "gtin") {
+				continue
+			}
+			invalid++
+			log.Warn().Err(err).Int("line", line).Strs("record", record).Msg("Skipping invalid row")
+			continue
+		}
+		mapping.line = line
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, invalid
+}
+
+func parseMapping(record []string) (barcodeMapping, error) {
+	if len(record) < 2 {
+		return barcodeMapping{}, fmt.Errorf("expected gtin,master_id, got %d columns", len(record))
+	}
+
+	gtin, err := normalize.NormalizeGTIN(record[0])
+	if err != nil {
+		return barcodeMapping{}, fmt.Errorf("barcode %q: %w", record[0], err)
+	}
+
+	masterID, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+	if err != nil || masterID <= 0 {
+		return barcodeMapping{}, fmt.Errorf("invalid master ID %q", record[1])
+	}
+
+	return barcodeMapping{gtin: gtin, masterID: masterID}, nil
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
+
+	"github.com/joho/godotenv"
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const usage = `Inspect and repair the worker job queue.
+
+Usage:
+  jobs [-debug] <command> [flags] [args]
+
+Commands:
+  list [-status S] [-type T] [-limit N] [-offset N]   List queued jobs, newest first
+  get <id>                                            Show a job with its payload, error and progress
+  watch <id>                                          Follow a job's progress until it finishes
+  stats                                               Show job counts and paused job types
+  retry <id>                                          Run a dead or delayed job again now
+  retry-dead [-type T] [-error TEXT]                  Retry every matching dead letter
+  cancel <id>                                         Cancel a waiting job or ask a running one to stop
+  pause <type>                                        Stop workers from picking up a job type
+  resume <type>                                       Let workers pick up a paused job type
+  workflows [-status S] [-name N] [-limit N] [-offset N]
+                                                      List workflows, newest first
+  workflow <id>                                       Show a workflow with its steps
+  start-workflow [-params JSON] <name>                Start a workflow, e.g. flyer_pipeline
+`
+
+var debug bool
+
+func main() {
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	// Setup logger
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	} else {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load .env file explicitly
+	if err := godotenv.Load(); err != nil {
+		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	}
+
+	// Get environment
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	// Load configuration
+	cfg, err := config.Load(env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Connect to database
+	db, err := database.NewBun(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	// Job progress lives in Redis even when Postgres queues the jobs
+	var progress *worker.ProgressStore
+	redisClient, err := worker.NewRedisClient(ctx, cfg.Redis)
+	switch {
+	case err == nil:
+		defer redisClient.Close()
+		progress = worker.NewProgressStore(redisClient, "")
+	case cfg.Worker.QueueBackend == worker.QueueBackendPostgres:
+		log.Warn().Err(err).Msg("Redis unavailable, running jobs show no progress and can't be cancelled")
+	default:
+		log.Fatal().Err(err).Msg("Failed to connect to Redis")
+	}
+
+	queue, err := worker.NewQueue(cfg.Worker, redisClient, db.DB, worker.DefaultQueueName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open job queue")
+	}
+	log.Debug().Str("queue", worker.DefaultQueueName).Str("backend", cfg.Worker.QueueBackend).Msg("Job queue opened")
+
+	admin := services.NewServiceFactory(db.DB).WithJobQueue(queue).WithJobProgress(progress).JobAdminService()
+	if err := run(ctx, admin, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "jobs %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+// run executes one command against the job admin service
+func run(ctx context.Context, admin services.JobAdminService, command string, args []string) error {
+	switch command {
+	case "list":
+		return listJobs(ctx, admin, args)
+	case "get":
+		id, err := singleArg("get", "job id", args)
+		if err != nil {
+			return err
+		}
+		job, err := admin.GetJob(ctx, id)
+		if err != nil {
+			return err
+		}
+		return printJSON(job)
+	case "watch":
+		id, err := singleArg("watch", "job id", args)
+		if err != nil {
+			return err
+		}
+		return watchJob(ctx, admin, id)
+	case "stats":
+		return printStats(ctx, admin)
+	case "retry":
+		id, err := singleArg("retry", "job id", args)
+		if err != nil {
+			return err
+		}
+		job, err := admin.RetryJob(ctx, id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Job %s is %s\n", job.ID, job.Status)
+		return nil
+	case "retry-dead":
+		return retryDeadLetters(ctx, admin, args)
+	case "cancel":
+		id, err := singleArg("cancel", "job id", args)
+		if err != nil {
+			return err
+		}
+		job, err := admin.CancelJob(ctx, id)
+		if err != nil {
+			return err
+		}
+		if job.Status == worker.JobStatusProcessing {
+			fmt.Printf("Job %s asked to stop\n", job.ID)
+			return nil
+		}
+		fmt.Printf("Job %s cancelled\n", job.ID)
+		return nil
+	case "pause":
+		jobType, err := singleArg("pause", "job type", args)
+		if err != nil {
+			return err
+		}
+		if err := admin.PauseJobType(ctx, worker.JobType(jobType)); err != nil {
+			return err
+		}
+		fmt.Printf("Job type %s paused\n", jobType)
+		return nil
+	case "resume":
+		jobType, err := singleArg("resume", "job type", args)
+		if err != nil {
+			return err
+		}
+		if err := admin.ResumeJobType(ctx, worker.JobType(jobType)); err != nil {
+			return err
+		}
+		fmt.Printf("Job type %s resumed\n", jobType)
+		return nil
+	case "workflows":
+		return listWorkflows(ctx, admin, args)
+	case "workflow":
+		id, err := singleArg("workflow", "workflow id", args)
+		if err != nil {
+			return err
+		}
+		return printWorkflow(ctx, admin, id)
+	case "start-workflow":
+		return startWorkflow(ctx, admin, args)
+	default:
+		return fmt.Errorf("unknown command, run jobs -h for usage")
+	}
+}
+
+func listJobs(ctx context.Context, admin services.JobAdminService, args []string) error {
+	var status, jobType string
+	var filter worker.JobFilter
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.StringVar(&status, "status", "", "pending, retrying, processing or failed")
+	flags.StringVar(&jobType, "type", "", "Job type, e.g. scrape_flyer")
+	flags.IntVar(&filter.Limit, "limit", 50, "Maximum jobs to list (at most 500)")
+	flags.IntVar(&filter.Offset, "offset", 0, "Jobs to skip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	filter.Status = worker.JobStatus(status)
+	filter.Type = worker.JobType(jobType)
+
+	jobs, err := admin.ListJobs(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tPRIORITY\tATTEMPTS\tPROGRESS\tCREATED\tERROR")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d/%d\t%s\t%s\t%s\n",
+			job.ID, job.Type, job.Status, job.Priority,
+			job.Attempts, job.MaxAttempts,
+			formatProgress(job.Progress),
+			job.CreatedAt.Local().Format(time.DateTime),
+			truncate(job.Error, 60),
+		)
+	}
+	return w.Flush()
+}
+
+// watchJob prints a line for every update of the job until it finishes
+func watchJob(ctx context.Context, admin services.JobAdminService, id string) error {
+	jobs, err := admin.WatchJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	for job := range jobs {
+		line := fmt.Sprintf("%s  %-10s  %s", time.Now().Format(time.TimeOnly), job.Status, formatProgress(job.Progress))
+		if job.Progress != nil && len(job.Progress.Counters) > 0 {
+			line += "  " + formatCounters(job.Progress.Counters)
+		}
+		if job.Error != "" {
+			line += "  " + truncate(job.Error, 60)
+		}
+		fmt.Println(line)
+	}
+	return ctx.Err()
+}
+
+func printStats(ctx context.Context, admin services.JobAdminService) error {
+	stats, err := admin.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	paused, err := admin.PausedJobTypes(ctx)
+	if err != nil {
+		return err
+	}
+
+	pausedTypes := make([]string, len(paused))
+	for i, jobType := range paused {
+		pausedTypes[i] = string(jobType)
+	}
+	if len(pausedTypes) == 0 {
+		pausedTypes = []string{"none"}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Pending\t%d\n", stats.Pending)
+	fmt.Fprintf(w, "Scheduled\t%d\n", stats.Scheduled)
+	fmt.Fprintf(w, "Processing\t%d\n", stats.Processing)
+	fmt.Fprintf(w, "Dead letters\t%d\n", stats.DeadLetter)
+	fmt.Fprintf(w, "Paused types\t%s\n", strings.Join(pausedTypes, ", "))
+	return w.Flush()
+}
+
+func retryDeadLetters(ctx context.Context, admin services.JobAdminService, args []string) error {
+	var jobType string
+	var filter services.DeadLetterFilter
+	flags := flag.NewFlagSet("retry-dead", flag.ContinueOnError)
+	flags.StringVar(&jobType, "type", "", "Only retry dead letters of this job type")
+	flags.StringVar(&filter.ErrorContains, "error", "", "Only retry dead letters whose error contains this text")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	filter.Type = worker.JobType(jobType)
+
+	retried, err := admin.RetryDeadLetters(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("retried %d dead letters before failing: %w", retried, err)
+	}
+	fmt.Printf("Retried %d dead letters\n", retried)
+	return nil
+}
+
+func listWorkflows(ctx context.Context, admin services.JobAdminService, args []string) error {
+	var status string
+	var filter worker.WorkflowFilter
+	flags := flag.NewFlagSet("workflows", flag.ContinueOnError)
+	flags.StringVar(&status, "status", "", "running, completed, failed or cancelled")
+	flags.StringVar(&filter.Name, "name", "", "Workflow name, e.g. flyer_pipeline")
+	flags.IntVar(&filter.Limit, "limit", 50, "Maximum workflows to list (at most 500)")
+	flags.IntVar(&filter.Offset, "offset", 0, "Workflows to skip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	filter.Status = worker.WorkflowStatus(status)
+
+	workflows, err := admin.ListWorkflows(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tCREATED\tERROR")
+	for _, wf := range workflows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			wf.ID, wf.Name, wf.Status,
+			wf.CreatedAt.Local().Format(time.DateTime),
+			truncate(wf.Error, 60),
+		)
+	}
+	return w.Flush()
+}
+
+// printWorkflow prints a workflow and a line per step, child steps under their parent
+func printWorkflow(ctx context.Context, admin services.JobAdminService, id string) error {
+	wf, err := admin.GetWorkflow(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Workflow %s (%s) is %s\n", wf.ID, wf.Name, wf.Status)
+	if wf.Error != "" {
+		fmt.Printf("Error: %s\n", wf.Error)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tJOB\tDEPENDS ON\tERROR")
+	var printSteps func(parent, indent string)
+	printSteps = func(parent, indent string) {
+		for _, step := range wf.Steps {
+			if step.Parent != parent {
+				continue
+			}
+			dependsOn := strings.Join(step.DependsOn, ", ")
+			if dependsOn == "" {
+				dependsOn = "-"
+			}
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n",
+				indent, step.Name, step.Status, step.Job.ID, dependsOn, truncate(step.Error, 60))
+			printSteps(step.Name, indent+"  ")
+		}
+	}
+	printSteps("", "")
+	return w.Flush()
+}
+
+func startWorkflow(ctx context.Context, admin services.JobAdminService, args []string) error {
+	var paramsJSON string
+	flags := flag.NewFlagSet("start-workflow", flag.ContinueOnError)
+	flags.StringVar(&paramsJSON, "params", "", `Workflow parameters as a JSON object, e.g. {"stores":["iki"]}`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	name, err := singleArg("start-workflow", "workflow name", flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var params map[string]interface{}
+	if paramsJSON != "" {
+		if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+			return fmt.Errorf("invalid -params: %w", err)
+		}
+	}
+
+	wf, err := admin.StartWorkflow(ctx, name, params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Workflow %s started as %s\n", wf.Name, wf.ID)
+	return nil
+}
+
+func formatProgress(progress *worker.Progress) string {
+	if progress == nil {
+		return "-"
+	}
+	if progress.Step == "" {
+		return fmt.Sprintf("%.0f%%", progress.Percent)
+	}
+	return fmt.Sprintf("%.0f%% %s", progress.Percent, progress.Step)
+}
+
+func formatCounters(counters map[string]int64) string {
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, counters[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+func singleArg(command, name string, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("usage: jobs %s <%s>", command, strings.ReplaceAll(name, " ", "-"))
+	}
+	return args[0], nil
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func truncate(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
@@ -20,6 +20,8 @@ func main() {
 		configPath = flag.String("config", "configs/development.yaml", "Path to config file")
 		action     = flag.String("action", "up", "Migration action: up, down, reset, status")
 		steps      = flag.Int("steps", 0, "Number of migration steps (0 = all)")
+		to         = flag.String("to", "", "Version to migrate up to, or roll back to, e.g. 052")
+		dryRun     = flag.Bool("dry-run", false, "Print the SQL plan of up or down without running it")
 	)
 	flag.Parse()
 
@@ -53,19 +55,34 @@ func main() {
 	m := migrator.New(db.DB)
 
 	ctx := context.Background()
+	opts := migrator.RunOptions{Steps: *steps, To: *to, DryRun: *dryRun}
 
 	// Execute migration action
 	switch *action {
 	case "up":
+		if *dryRun {
+			fmt.Println("📝 Planning database migrations UP (dry run)...")
+			if err := m.Up(ctx, opts); err != nil {
+				log.Fatal().Err(err).Msg("Failed to plan migrations UP")
+			}
+			break
+		}
 		fmt.Println("📈 Running database migrations UP...")
-		if err := m.Up(ctx, *steps); err != nil {
+		if err := m.Up(ctx, opts); err != nil {
 			log.Fatal().Err(err).Msg("Failed to run migrations UP")
 		}
 		fmt.Println("✅ Migrations completed successfully")
 
 	case "down":
+		if *dryRun {
+			fmt.Println("📝 Planning database migrations DOWN (dry run)...")
+			if err := m.Down(ctx, opts); err != nil {
+				log.Fatal().Err(err).Msg("Failed to plan migrations DOWN")
+			}
+			break
+		}
 		fmt.Println("📉 Running database migrations DOWN...")
-		if err := m.Down(ctx, *steps); err != nil {
+		if err := m.Down(ctx, opts); err != nil {
 			log.Fatal().Err(err).Msg("Failed to run migrations DOWN")
 		}
 		fmt.Println("✅ Migrations rolled back successfully")
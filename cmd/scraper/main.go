@@ -2,11 +2,8 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -16,12 +13,15 @@ import (
 
 	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/kainuguru/kainuguru-api/internal/config"
 	"github.com/kainuguru/kainuguru-api/internal/database"
-	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/monitoring"
 	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/enrichment"
+	"github.com/kainuguru/kainuguru-api/internal/services/ingestion"
 	"github.com/kainuguru/kainuguru-api/internal/services/scraper"
-	"github.com/kainuguru/kainuguru-api/internal/services/storage"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
 	"github.com/kainuguru/kainuguru-api/pkg/pdf"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -49,6 +49,12 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
+	// Setup tracing
+	shutdownTracing, err := monitoring.InitTracing(context.Background(), cfg.Tracing, "kainuguru-scraper", "development")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup tracing")
+	}
+
 	// Initialize database
 	bunDB, err := database.NewBun(cfg.Database)
 	if err != nil {
@@ -73,6 +79,27 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Connect to the job queue so new pages are extracted by the enrichment workers.
+	// Without a queue the pages stay pending for the enrich-flyers CLI.
+	var jobQueue enrichment.JobEnqueuer
+	var redisClient *redis.Client
+	if cfg.Worker.QueueBackend != worker.QueueBackendPostgres {
+		redisClient, err = worker.NewRedisClient(ctx, cfg.Redis)
+		if err != nil {
+			log.Warn().Err(err).Msg("Redis unavailable")
+		} else {
+			defer redisClient.Close()
+		}
+	}
+	queue, err := worker.NewQueue(cfg.Worker, redisClient, db, worker.DefaultQueueName)
+	if err != nil {
+		log.Warn().Err(err).Msg("Job queue unavailable, pages will not be enqueued for extraction")
+	} else {
+		jobQueue = queue
+	}
+
+	pipeline := ingestion.NewPipeline(serviceFactory, pdfProcessor, jobQueue)
+
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -108,7 +135,7 @@ func main() {
 		defer ticker.Stop()
 
 		// Run once immediately
-		runScrapingCycle(ctx, scrapers, serviceFactory, pdfProcessor)
+		runScrapingCycle(ctx, scrapers, pipeline)
 
 		for {
 			select {
@@ -116,7 +143,7 @@ func main() {
 				log.Info().Msg("Scraper worker shutting down...")
 				return
 			case <-ticker.C:
-				runScrapingCycle(ctx, scrapers, serviceFactory, pdfProcessor)
+				runScrapingCycle(ctx, scrapers, pipeline)
 			}
 		}
 	}()
@@ -136,14 +163,20 @@ func main() {
 	// Give some time for cleanup
 	time.Sleep(2 * time.Second)
 
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
+
 	fmt.Println("👋 Scraper worker stopped")
 }
 
-func runScrapingCycle(ctx context.Context, scrapers []scraper.Scraper, factory *services.ServiceFactory, pdfProcessor *pdf.Processor) {
+func runScrapingCycle(ctx context.Context, scrapers []scraper.Scraper, pipeline *ingestion.Pipeline) {
 	log.Info().Msg("⏰ Starting scraping cycle")
 
 	for _, s := range scrapers {
-		if err := processScraper(ctx, s, factory, pdfProcessor); err != nil {
+		if err := pipeline.ScrapeStore(ctx, s); err != nil {
 			log.Error().
 				Err(err).
 				Str("store", s.GetStoreInfo().Name).
@@ -155,328 +188,6 @@ func runScrapingCycle(ctx context.Context, scrapers []scraper.Scraper, factory *
 	fmt.Println("---")
 }
 
-func processScraper(ctx context.Context, s scraper.Scraper, factory *services.ServiceFactory, pdfProcessor *pdf.Processor) error {
-	storeInfo := s.GetStoreInfo()
-
-	log.Info().Str("store", storeInfo.Name).Msg("Processing store")
-
-	// 1. Scrape current flyers
-	flyerInfos, err := s.ScrapeCurrentFlyers(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to scrape: %w", err)
-	}
-
-	log.Info().
-		Str("store", storeInfo.Name).
-		Int("count", len(flyerInfos)).
-		Msg("Found flyers")
-
-	// 2. Process each flyer
-	for _, flyerInfo := range flyerInfos {
-		if err := processFlyerInfo(ctx, flyerInfo, s, factory, pdfProcessor); err != nil {
-			log.Error().
-				Err(err).
-				Str("store", storeInfo.Name).
-				Str("flyer", flyerInfo.Title).
-				Msg("Failed to process flyer")
-			continue
-		}
-	}
-
-	return nil
-}
-
-func processFlyerInfo(ctx context.Context, flyerInfo scraper.FlyerInfo, s scraper.Scraper, factory *services.ServiceFactory, pdfProcessor *pdf.Processor) error {
-	flyerService := factory.FlyerService()
-	flyerPageService := factory.FlyerPageService()
-	storeService := factory.StoreService()
-	storageService := factory.FlyerStorageService()
-
-	// 1. Get store from database by code
-	store, err := storeService.GetByCode(ctx, flyerInfo.StoreCode)
-	if err != nil {
-		return fmt.Errorf("failed to get store by code %q: %w", flyerInfo.StoreCode, err)
-	}
-
-	// 2. Check if this exact flyer already exists (by source URL)
-	existingFlyer, err := flyerService.GetBySourceURL(ctx, flyerInfo.FlyerURL)
-	if err == nil && existingFlyer != nil {
-		log.Info().
-			Int("flyerId", existingFlyer.ID).
-			Str("store", store.Code).
-			Str("url", flyerInfo.FlyerURL).
-			Msg("Flyer already exists (same source URL), skipping")
-		return nil
-	}
-
-	// 3. Create flyer record FIRST (we need the ID for folder paths)
-	title := flyerInfo.Title
-	flyer := &models.Flyer{
-		StoreID:   store.ID,
-		Title:     &title,
-		ValidFrom: flyerInfo.ValidFrom,
-		ValidTo:   flyerInfo.ValidTo,
-		SourceURL: &flyerInfo.FlyerURL,
-		Status:    string(models.FlyerStatusPending),
-		Store:     store, // IMPORTANT: Set for path generation
-	}
-
-	if err := flyerService.Create(ctx, flyer); err != nil {
-		return fmt.Errorf("failed to create flyer: %w", err)
-	}
-
-	log.Info().
-		Int("flyerId", flyer.ID).
-		Str("store", store.Code).
-		Str("title", title).
-		Msg("Created flyer record")
-
-	// 4. Try to get page images directly via scraper (works for iPaper-based stores like Maxima)
-	pageInfos, err := s.ScrapeFlyer(ctx, flyerInfo)
-	if err == nil && len(pageInfos) > 0 {
-		// Check if the first page is a PDF (IKI returns PDF URL, not images)
-		// If so, fall back to PDF processing
-		if len(pageInfos) == 1 && pageInfos[0].FileType == "pdf" {
-			log.Debug().
-				Int("flyerId", flyer.ID).
-				Msg("ScrapeFlyer returned PDF, falling back to PDF processing")
-		} else {
-			// Store provides direct image URLs (e.g., Maxima via iPaper)
-			return processDirectImagePages(ctx, flyer, pageInfos, flyerService, flyerPageService, storageService)
-		}
-	}
-
-	// 5. Fall back to PDF download and conversion (e.g., IKI)
-	return processPDFFlyer(ctx, flyer, flyerInfo, flyerService, flyerPageService, storageService, pdfProcessor)
-}
-
-// processDirectImagePages handles stores that provide direct image URLs (like Maxima via iPaper)
-func processDirectImagePages(ctx context.Context, flyer *models.Flyer, pageInfos []scraper.PageInfo, flyerService services.FlyerService, flyerPageService services.FlyerPageService, storageService storage.FlyerStorageService) error {
-	log.Info().
-		Int("flyerId", flyer.ID).
-		Int("pages", len(pageInfos)).
-		Msg("Processing direct image pages")
-
-	var flyerPages []*models.FlyerPage
-
-	for _, pageInfo := range pageInfos {
-		// Download image from CDN URL
-		imageData, err := downloadFile(ctx, pageInfo.ImageURL)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Int("page", pageInfo.PageNumber).
-				Str("url", pageInfo.ImageURL).
-				Msg("Failed to download page image")
-			continue
-		}
-
-		log.Debug().
-			Int("flyerId", flyer.ID).
-			Int("page", pageInfo.PageNumber).
-			Int("bytes", len(imageData)).
-			Msg("Downloaded page image")
-
-		// Save to storage and get public URL
-		publicURL, err := storageService.SaveFlyerPage(ctx, flyer, pageInfo.PageNumber, bytes.NewReader(imageData))
-		if err != nil {
-			log.Error().
-				Err(err).
-				Int("page", pageInfo.PageNumber).
-				Msg("Failed to save page to storage")
-			continue
-		}
-
-		// Create flyer page record
-		flyerPage := &models.FlyerPage{
-			FlyerID:          flyer.ID,
-			PageNumber:       pageInfo.PageNumber,
-			ImageURL:         &publicURL,
-			ExtractionStatus: string(models.FlyerPageStatusPending),
-		}
-
-		flyerPages = append(flyerPages, flyerPage)
-
-		log.Debug().
-			Int("flyerId", flyer.ID).
-			Int("page", pageInfo.PageNumber).
-			Str("url", publicURL).
-			Msg("Saved flyer page")
-	}
-
-	// Update page count
-	pageCount := len(flyerPages)
-	flyer.PageCount = &pageCount
-	flyerService.Update(ctx, flyer)
-
-	// Batch create flyer pages
-	if len(flyerPages) > 0 {
-		if err := flyerPageService.CreateBatch(ctx, flyerPages); err != nil {
-			flyerService.FailProcessing(ctx, flyer.ID)
-			return fmt.Errorf("failed to create flyer pages: %w", err)
-		}
-
-		log.Info().
-			Int("flyerId", flyer.ID).
-			Int("pages", len(flyerPages)).
-			Msg("Created flyer page records")
-	}
-
-	// Mark flyer as completed
-	if err := flyerService.CompleteProcessing(ctx, flyer.ID, len(flyerPages)); err != nil {
-		log.Error().Err(err).Msg("Failed to mark flyer as completed")
-	}
-
-	log.Info().
-		Int("flyerId", flyer.ID).
-		Str("store", flyer.Store.Code).
-		Int("pages", len(flyerPages)).
-		Str("folder", flyer.GetFolderName()).
-		Msg("✅ Flyer processed successfully (direct images)")
-
-	return nil
-}
-
-// processPDFFlyer handles stores that provide PDF URLs (like IKI)
-func processPDFFlyer(ctx context.Context, flyer *models.Flyer, flyerInfo scraper.FlyerInfo, flyerService services.FlyerService, flyerPageService services.FlyerPageService, storageService storage.FlyerStorageService, pdfProcessor *pdf.Processor) error {
-	// Download PDF
-	pdfData, err := downloadFile(ctx, flyerInfo.FlyerURL)
-	if err != nil {
-		flyerService.FailProcessing(ctx, flyer.ID)
-		return fmt.Errorf("failed to download PDF: %w", err)
-	}
-
-	log.Info().
-		Int("flyerId", flyer.ID).
-		Int("bytes", len(pdfData)).
-		Msg("Downloaded PDF")
-
-	// Save PDF to temp file
-	tempDir := "/tmp/kainuguru/pdf"
-	os.MkdirAll(tempDir, 0755) // Ensure temp dir exists
-
-	tempPDFPath := filepath.Join(tempDir, fmt.Sprintf("flyer-%d.pdf", flyer.ID))
-	if err := os.MkdirAll(filepath.Dir(tempPDFPath), 0755); err != nil {
-		flyerService.FailProcessing(ctx, flyer.ID)
-		return fmt.Errorf("failed to create temp dir: %w", err)
-	}
-
-	if err := os.WriteFile(tempPDFPath, pdfData, 0644); err != nil {
-		flyerService.FailProcessing(ctx, flyer.ID)
-		return fmt.Errorf("failed to save temp PDF: %w", err)
-	}
-	defer os.Remove(tempPDFPath)
-
-	// Convert PDF to images
-	result, err := pdfProcessor.ProcessPDF(ctx, tempPDFPath)
-	if err != nil || !result.Success {
-		flyerService.FailProcessing(ctx, flyer.ID)
-		return fmt.Errorf("failed to convert PDF to images: %w", err)
-	}
-
-	log.Info().
-		Int("flyerId", flyer.ID).
-		Int("pages", result.PageCount).
-		Msg("Converted PDF to images")
-
-	// Update flyer page count
-	pageCount := result.PageCount
-	flyer.PageCount = &pageCount
-	flyerService.Update(ctx, flyer)
-
-	// Save each page image to storage
-	var flyerPages []*models.FlyerPage
-
-	for i, imagePath := range result.OutputFiles {
-		pageNumber := i + 1
-
-		// Read image data
-		imageData, err := os.ReadFile(imagePath)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Int("page", pageNumber).
-				Msg("Failed to read image file")
-			continue
-		}
-
-		// Save to storage and get public URL
-		publicURL, err := storageService.SaveFlyerPage(ctx, flyer, pageNumber, bytes.NewReader(imageData))
-		if err != nil {
-			log.Error().
-				Err(err).
-				Int("page", pageNumber).
-				Msg("Failed to save page to storage")
-			continue
-		}
-
-		// Create flyer page record
-		flyerPage := &models.FlyerPage{
-			FlyerID:          flyer.ID,
-			PageNumber:       pageNumber,
-			ImageURL:         &publicURL,
-			ExtractionStatus: string(models.FlyerPageStatusPending),
-		}
-
-		flyerPages = append(flyerPages, flyerPage)
-
-		log.Debug().
-			Int("flyerId", flyer.ID).
-			Int("page", pageNumber).
-			Str("url", publicURL).
-			Msg("Saved flyer page")
-
-		// Clean up temp image file
-		os.Remove(imagePath)
-	}
-
-	// 9. Batch create flyer pages
-	if len(flyerPages) > 0 {
-		if err := flyerPageService.CreateBatch(ctx, flyerPages); err != nil {
-			flyerService.FailProcessing(ctx, flyer.ID)
-			return fmt.Errorf("failed to create flyer pages: %w", err)
-		}
-
-		log.Info().
-			Int("flyerId", flyer.ID).
-			Int("pages", len(flyerPages)).
-			Msg("Created flyer page records")
-	}
-
-	// 10. Enforce storage limit (keep only 2 flyers per store)
-	if err := storageService.EnforceStorageLimit(ctx, flyer.Store.Code); err != nil {
-		log.Warn().
-			Err(err).
-			Str("store", flyer.Store.Code).
-			Msg("Failed to enforce storage limit (non-critical)")
-	}
-
-	// 11. Mark old flyers from this store as archived (manual update for now)
-	// Note: This will be handled better when we implement the archive service properly
-	oldFlyers, _ := flyerService.GetFlyersByStore(ctx, flyer.StoreID, services.FlyerFilters{
-		IsArchived: &[]bool{false}[0],
-	})
-	for _, oldFlyer := range oldFlyers {
-		if oldFlyer.ID != flyer.ID && oldFlyer.ValidTo.Before(flyer.ValidFrom) {
-			flyerService.ArchiveFlyer(ctx, oldFlyer.ID)
-		}
-	}
-
-	// 12. Mark flyer as completed
-	if err := flyerService.CompleteProcessing(ctx, flyer.ID, len(flyerPages)); err != nil {
-		log.Error().Err(err).Msg("Failed to mark flyer as completed")
-	}
-
-	log.Info().
-		Int("flyerId", flyer.ID).
-		Str("store", flyer.Store.Code).
-		Int("pages", len(flyerPages)).
-		Str("folder", flyer.GetFolderName()).
-		Msg("✅ Flyer processed successfully (PDF)")
-
-	return nil
-}
-
 // loadEnvFile loads .env file into OS environment variables
 func loadEnvFile(filename string) error {
 	file, err := os.Open(filename)
@@ -505,25 +216,3 @@ func loadEnvFile(filename string) error {
 
 	return scanner.Err()
 }
-
-func downloadFile(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; KainuguruBot/1.0)")
-
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	return io.ReadAll(resp.Body)
-}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
+
+	"github.com/joho/godotenv"
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/services/matching"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	debug           bool
+	dryRun          bool
+	minSamples      int
+	targetPrecision float64
+	targetRecall    float64
+	configPath      string
+)
+
+func main() {
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.BoolVar(&dryRun, "dry-run", false, "Train and report without saving the models")
+	flag.IntVar(&minSamples, "min-samples", matching.DefaultTrainerOptions.MinSamples, "Reviewed matches needed to train a category model")
+	flag.Float64Var(&targetPrecision, "target-precision", matching.DefaultTrainerOptions.TargetPrecision, "Precision required above the auto-match threshold")
+	flag.Float64Var(&targetRecall, "target-recall", matching.DefaultTrainerOptions.TargetRecall, "Share of true matches that must reach the review threshold")
+	flag.StringVar(&configPath, "config", "", "Path to custom config file")
+	flag.Parse()
+
+	// Setup logger
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	log.Info().Msg("Starting matcher training")
+
+	// Load .env file explicitly
+	if err := godotenv.Load(); err != nil {
+		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	}
+
+	// Get environment
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	// Load configuration
+	cfg, err := config.Load(env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Connect to database
+	bunDB, err := database.NewBun(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer bunDB.Close()
+
+	log.Info().Msg("Database connection established")
+
+	opts := matching.DefaultTrainerOptions
+	opts.MinSamples = minSamples
+	opts.TargetPrecision = targetPrecision
+	opts.TargetRecall = targetRecall
+
+	trainer := matching.NewTrainer(bunDB.DB, opts)
+	ctx := context.Background()
+
+	samples, err := trainer.LoadSamples(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load reviewed matches")
+	}
+	log.Info().Int("samples", len(samples)).Msg("Reviewed matches loaded")
+
+	report, err := trainer.Train(ctx, samples)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Training failed")
+	}
+
+	for _, cat := range report.Categories {
+		category := cat.Category
+		if category == "" {
+			category = "(global)"
+		}
+		log.Info().
+			Str("category", category).
+			Int("training_samples", cat.TrainingSamples).
+			Int("evaluation_samples", cat.After.Samples).
+			Float64("precision_before", cat.Before.Precision).
+			Float64("recall_before", cat.Before.Recall).
+			Float64("threshold_before", cat.Before.Threshold).
+			Float64("precision_after", cat.After.Precision).
+			Float64("recall_after", cat.After.Recall).
+			Float64("auto_match_threshold", cat.Model.AutoMatchThreshold).
+			Float64("review_threshold", cat.Model.ReviewThreshold).
+			Msg("Category trained")
+
+		for _, name := range trainer.StrategyNames() {
+			log.Debug().Str("category", category).Str("strategy", name).Float64("weight", cat.Model.Weights[name]).Msg("Learned weight")
+		}
+	}
+
+	if dryRun {
+		log.Info().Msg("Dry run - models not saved")
+		return
+	}
+
+	if err := trainer.Save(ctx, report); err != nil {
+		log.Fatal().Err(err).Msg("Failed to save matcher models")
+	}
+
+	log.Info().
+		Int("models", len(report.Categories)).
+		Msg("Matcher training completed")
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	_ "github.com/kainuguru/kainuguru-api/internal/bootstrap"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/joho/godotenv"
+	"github.com/kainuguru/kainuguru-api/internal/cache"
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/monitoring"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/enrichment"
+	"github.com/kainuguru/kainuguru-api/internal/services/ingestion"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
+	"github.com/kainuguru/kainuguru-api/internal/services/scraper"
+	"github.com/kainuguru/kainuguru-api/internal/services/search"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	"github.com/kainuguru/kainuguru-api/internal/workers"
+	"github.com/kainuguru/kainuguru-api/pkg/pdf"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// schedulerLeaderResource is the lock replicas campaign for to run the job schedules
+const schedulerLeaderResource = "worker_scheduler"
+
+var (
+	addr         string
+	concurrency  int
+	drainTimeout time.Duration
+	schedule     bool
+	debug        bool
+)
+
+func main() {
+	flag.StringVar(&addr, "addr", ":9091", "Address of the health and metrics endpoints")
+	flag.IntVar(&concurrency, "concurrency", 0, "Jobs processed in parallel (0=worker.num_workers from config)")
+	flag.DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute, "How long shutdown waits for in-flight jobs before cancelling them")
+	flag.BoolVar(&schedule, "schedule", true, "Campaign for leadership and run the job schedules when elected")
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.Parse()
+
+	// Setup logger
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	if debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	} else {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+
+	log.Info().Msg("Starting Job Worker")
+
+	// Load .env file explicitly (needed for go run)
+	if err := godotenv.Load(); err != nil {
+		log.Debug().Err(err).Msg("No .env file found, using environment variables")
+	}
+
+	// Get environment
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	// Load configuration
+	cfg, err := config.Load(env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Setup tracing
+	shutdownTracing, err := monitoring.InitTracing(context.Background(), cfg.Tracing, "kainuguru-worker", env)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup tracing")
+	}
+
+	// Extraction jobs need the LLM
+	if cfg.OpenAI.APIKey == "" {
+		log.Fatal().Msg("OPENAI_API_KEY environment variable is required")
+	}
+
+	// Connect to database
+	db, err := database.NewBun(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	// Create context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Handle graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Info().Msg("Shutdown signal received, draining in-flight jobs...")
+		cancel()
+	}()
+
+	// The job queue uses go-redis v8, the services use the v9 cache client
+	queueRedis, err := worker.NewRedisClient(ctx, cfg.Redis)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to Redis")
+	}
+	defer queueRedis.Close()
+
+	cacheRedis, err := cache.NewRedis(cache.Config{
+		Host:       cfg.Redis.Host,
+		Port:       cfg.Redis.Port,
+		Password:   cfg.Redis.Password,
+		DB:         cfg.Redis.DB,
+		MaxRetries: cfg.Redis.MaxRetries,
+		PoolSize:   cfg.Redis.PoolSize,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to Redis")
+	}
+	defer cacheRedis.Close()
+
+	serviceFactory := services.NewServiceFactoryWithConfig(db.DB, cfg).WithRedis(cacheRedis)
+
+	orchestrator, err := enrichment.NewOrchestrator(ctx, db, cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create orchestrator")
+	}
+
+	workerConcurrency := concurrency
+	if workerConcurrency <= 0 {
+		workerConcurrency = cfg.Worker.NumWorkers
+	}
+
+	queue, err := worker.NewQueue(cfg.Worker, queueRedis, db.DB, worker.DefaultQueueName)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create job queue")
+	}
+	processor := worker.NewWorkerProcessor(queue, queueRedis, worker.ProcessorConfig{
+		Concurrency: workerConcurrency,
+		JobTimeout:  cfg.Worker.JobTimeout,
+	})
+
+	partitions := partition.NewManager(db.DB, cfg.Partitions)
+
+	registerHandlers(processor, queue, cfg, db, cacheRedis, serviceFactory, orchestrator, partitions)
+	for _, jobType := range worker.JobTypes {
+		if !processor.HasHandler(jobType) {
+			log.Fatal().Str("job_type", string(jobType)).Msg("No handler registered for job type")
+		}
+	}
+
+	// Jobs run under their own context so a shutdown lets them finish
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	if err := processor.Start(jobCtx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start worker processor")
+	}
+	log.Info().Int("concurrency", workerConcurrency).Str("queue", worker.DefaultQueueName).Str("backend", cfg.Worker.QueueBackend).Msg("Worker processor started")
+
+	elector := worker.NewLeaderElector(queueRedis, schedulerLeaderResource, worker.DefaultLeaderTTL)
+	schedulerDone := make(chan struct{})
+	if schedule {
+		go func() {
+			defer close(schedulerDone)
+			elector.Run(ctx, func(leadCtx context.Context) {
+				runScheduler(leadCtx, queue, queueRedis)
+			})
+		}()
+	} else {
+		close(schedulerDone)
+		log.Info().Msg("Scheduling disabled, only processing jobs")
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           newHTTPHandler(db, cfg, queueRedis, processor, elector, partitions),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		log.Info().Str("addr", addr).Msg("Serving health and metrics")
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Health and metrics server failed")
+		}
+	}()
+
+	<-ctx.Done()
+
+	// Stop scheduling first so no new work is queued by this replica
+	<-schedulerDone
+
+	drainJobs(processor, cancelJobs)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to shutdown health and metrics server")
+	}
+
+	// Write buffered search analytics before the database goes away
+	if err := serviceFactory.SearchService().Close(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush search analytics")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
+
+	log.Info().Msg("Job worker stopped")
+}
+
+// registerHandlers installs a handler for every job type
+func registerHandlers(
+	processor *worker.WorkerProcessor,
+	queue worker.Queue,
+	cfg *config.Config,
+	db *database.BunDB,
+	cacheRedis *cache.RedisClient,
+	serviceFactory *services.ServiceFactory,
+	orchestrator *enrichment.Orchestrator,
+	partitions *partition.Manager,
+) {
+	// Same page rendering as the scraper binary
+	pdfConfig := pdf.DefaultProcessorConfig()
+	pdfConfig.TempDir = filepath.Join(cfg.Storage.BasePath, "temp")
+	pdfConfig.DPI = 150
+	pdfConfig.Quality = 85
+
+	scraperConfig := scraper.ScraperConfig{
+		UserAgent: cfg.Scraper.UserAgent,
+		Timeout:   cfg.Scraper.RequestTimeout,
+		RateLimit: cfg.Scraper.RequestDelay,
+	}
+	scrapers := []scraper.Scraper{
+		scraper.NewIKIScraper(scraperConfig),
+		scraper.NewMaximaScraper(scraperConfig),
+	}
+
+	// Jobs fanned out through the workflow engine join the workflow of the job queueing them
+	workflows := worker.NewWorkflowEngine(db.DB, queue)
+	workflows.Register(processor)
+
+	pipeline := ingestion.NewPipeline(serviceFactory, pdf.NewProcessor(pdfConfig), workflows)
+	ingestion.NewScrapeJobHandler(pipeline, scrapers).Register(processor)
+	orchestrator.PageJobHandler(workflows).Register(processor)
+	search.NewSuggestionsJobHandler(serviceFactory.SearchService()).Register(processor)
+	partition.NewJobHandler(partitions).Register(processor)
+	workers.NewJobHandlers(db.DB, cacheRedis.Client(), serviceFactory).Register(processor)
+}
+
+// runScheduler runs the default job schedules until the leadership is lost
+func runScheduler(ctx context.Context, queue worker.Queue, redisClient *goredis.Client) {
+	scheduler := worker.NewJobScheduler(queue, redisClient)
+	if err := scheduler.Start(); err != nil {
+		log.Error().Err(err).Msg("Failed to start job scheduler")
+		return
+	}
+	if err := scheduler.SetupDefaultSchedules(); err != nil {
+		log.Error().Err(err).Msg("Failed to set up default schedules")
+	}
+
+	monitoring.WorkerSchedulerLeader.Set(1)
+	log.Info().Msg("Elected scheduler leader, running job schedules")
+
+	<-ctx.Done()
+
+	scheduler.Stop()
+	monitoring.WorkerSchedulerLeader.Set(0)
+	log.Info().Msg("Stopped running job schedules")
+}
+
+// drainJobs waits for in-flight jobs to finish, cancelling them once the drain
+// timeout passes. Cancelled jobs are retried by the queue.
+func drainJobs(processor *worker.WorkerProcessor, cancelJobs context.CancelFunc) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := processor.Stop(); err != nil {
+			log.Error().Err(err).Msg("Failed to stop worker processor")
+		}
+	}()
+
+	select {
+	case <-stopped:
+		log.Info().Msg("In-flight jobs drained")
+	case <-time.After(drainTimeout):
+		log.Warn().Dur("drain_timeout", drainTimeout).Msg("Drain timeout reached, cancelling in-flight jobs")
+		cancelJobs()
+		<-stopped
+	}
+}
+
+// newHTTPHandler serves the health checks and the Prometheus metrics
+func newHTTPHandler(db *database.BunDB, cfg *config.Config, redisClient *goredis.Client, processor *worker.WorkerProcessor, elector *worker.LeaderElector, partitions *partition.Manager) http.Handler {
+	health := monitoring.NewHealthChecker(db.DB, cfg.App.Version)
+	health.AddCheck("redis", func(ctx context.Context) monitoring.CheckResult {
+		start := time.Now()
+		pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+
+		if err := redisClient.Ping(pingCtx).Err(); err != nil {
+			return monitoring.CheckResult{
+				Status:  monitoring.HealthStatusUnhealthy,
+				Message: "Redis connection failed",
+				Latency: time.Since(start).String(),
+				Error:   err.Error(),
+			}
+		}
+		return monitoring.CheckResult{
+			Status:  monitoring.HealthStatusHealthy,
+			Message: "Redis is responsive",
+			Latency: time.Since(start).String(),
+		}
+	})
+	health.AddCheck("processor", func(ctx context.Context) monitoring.CheckResult {
+		if !processor.IsRunning() {
+			return monitoring.CheckResult{
+				Status:  monitoring.HealthStatusUnhealthy,
+				Message: "Worker processor is not running",
+			}
+		}
+		message := "Processing jobs"
+		if elector.IsLeader() {
+			message = "Processing jobs and running schedules"
+		}
+		return monitoring.CheckResult{
+			Status:  monitoring.HealthStatusHealthy,
+			Message: message,
+		}
+	})
+
+	health.AddCheck("partitions", func(ctx context.Context) monitoring.CheckResult {
+		result := partitions.Health(ctx)
+		return monitoring.CheckResult{
+			Status:  monitoring.HealthStatus(result.Status),
+			Message: result.Message,
+		}
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/health", health.HTTPHandler())
+	mux.Handle("/health/live", health.LivenessHandler())
+	mux.Handle("/health/ready", health.ReadinessHandler())
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
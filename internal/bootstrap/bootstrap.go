@@ -15,4 +15,5 @@ func init() {
 	services.RegisterShoppingListItemRepositoryFactory(repositories.NewShoppingListItemRepository)
 	services.RegisterExtractionJobRepositoryFactory(repositories.NewExtractionJobRepository)
 	services.RegisterPriceHistoryRepositoryFactory(repositories.NewPriceHistoryRepository)
+	services.RegisterEnrichmentRunRepositoryFactory(repositories.NewEnrichmentRunRepository)
 }
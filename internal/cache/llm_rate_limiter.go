@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// llmAcquireScript atomically reserves one request and the estimated tokens in the
+// current one-minute window. It returns 1 when the reservation fits, 0 otherwise.
+// A request larger than the whole TPM budget is still admitted into an empty window
+// so that it cannot block forever.
+var llmAcquireScript = redis.NewScript(`
+local reqs = tonumber(redis.call('GET', KEYS[1]) or '0')
+local toks = tonumber(redis.call('GET', KEYS[2]) or '0')
+local rpm = tonumber(ARGV[1])
+local tpm = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+if rpm > 0 and reqs + 1 > rpm then
+  return 0
+end
+if tpm > 0 and toks > 0 and toks + cost > tpm then
+  return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ttl)
+redis.call('INCRBY', KEYS[2], cost)
+redis.call('EXPIRE', KEYS[2], ttl)
+return 1
+`)
+
+// LLMRateLimiter enforces requests-per-minute and tokens-per-minute budgets for an
+// LLM provider. Budgets live in Redis so that every process sharing the provider
+// account (enrichment CLI, workers) draws from the same window.
+type LLMRateLimiter struct {
+	client   *redis.Client
+	provider string
+	rpm      int
+	tpm      int
+	now      func() time.Time
+}
+
+// NewLLMRateLimiter creates a limiter for the given provider. A zero rpm or tpm disables that budget.
+func NewLLMRateLimiter(client *redis.Client, provider string, rpm, tpm int) *LLMRateLimiter {
+	return &LLMRateLimiter{
+		client:   client,
+		provider: provider,
+		rpm:      rpm,
+		tpm:      tpm,
+		now:      time.Now,
+	}
+}
+
+// Wait blocks until the request fits into the current window's budgets
+func (l *LLMRateLimiter) Wait(ctx context.Context, estimatedTokens int) error {
+	if l.rpm <= 0 && l.tpm <= 0 {
+		return nil
+	}
+
+	for {
+		window := l.now().Truncate(time.Minute)
+		reqKey, tokKey := l.keys(window)
+
+		allowed, err := llmAcquireScript.Run(ctx, l.client, []string{reqKey, tokKey},
+			l.rpm, l.tpm, estimatedTokens, int((2 * time.Minute).Seconds())).Int()
+		if err != nil {
+			return fmt.Errorf("llm rate limit check failed: %w", err)
+		}
+		if allowed == 1 {
+			return nil
+		}
+
+		// Sleep until the next window opens; jitter spreads out waiting workers
+		wait := window.Add(time.Minute).Sub(l.now()) + time.Duration(rand.Int63n(int64(500*time.Millisecond)))
+		log.Debug().
+			Str("provider", l.provider).
+			Dur("wait", wait).
+			Msg("LLM rate limit budget exhausted, waiting for next window")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Record corrects the token budget with the tokens the request actually used
+func (l *LLMRateLimiter) Record(ctx context.Context, estimatedTokens, actualTokens int) {
+	if l.tpm <= 0 || actualTokens <= 0 || actualTokens == estimatedTokens {
+		return
+	}
+	_, tokKey := l.keys(l.now().Truncate(time.Minute))
+	if err := l.client.IncrBy(ctx, tokKey, int64(actualTokens-estimatedTokens)).Err(); err != nil {
+		log.Warn().Err(err).Str("provider", l.provider).Msg("Failed to record LLM token usage")
+	}
+}
+
+func (l *LLMRateLimiter) keys(window time.Time) (string, string) {
+	prefix := fmt.Sprintf("llm:ratelimit:%s:%d", l.provider, window.Unix())
+	return prefix + ":requests", prefix + ":tokens"
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
@@ -33,6 +34,10 @@ func NewRedis(cfg Config) (*RedisClient, error) {
 		PoolSize:   cfg.PoolSize,
 	})
 
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		return nil, fmt.Errorf("failed to trace Redis: %w", err)
+	}
+
 	// Test connection
 	ctx := context.Background()
 	if err := rdb.Ping(ctx).Err(); err != nil {
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// SearchSynonymsTTL bounds how long the cached dictionary survives without an invalidation
+	SearchSynonymsTTL = 24 * time.Hour
+
+	searchSynonymsKey = "search:synonyms"
+)
+
+// SynonymCache shares the search synonym dictionary between API instances
+type SynonymCache struct {
+	redis *RedisClient
+}
+
+// NewSynonymCache creates a new synonym cache instance
+func NewSynonymCache(redis *RedisClient) *SynonymCache {
+	return &SynonymCache{
+		redis: redis,
+	}
+}
+
+// GetSynonyms returns the cached dictionary, or nil when it is not cached
+// Key pattern: search:synonyms
+func (c *SynonymCache) GetSynonyms(ctx context.Context) ([]*models.SearchSynonym, error) {
+	data, err := c.redis.Get(ctx, searchSynonymsKey)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search synonyms from Redis: %w", err)
+	}
+
+	var synonyms []*models.SearchSynonym
+	if err := json.Unmarshal([]byte(data), &synonyms); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal search synonyms: %w", err)
+	}
+	return synonyms, nil
+}
+
+// SaveSynonyms caches the whole dictionary
+func (c *SynonymCache) SaveSynonyms(ctx context.Context, synonyms []*models.SearchSynonym) error {
+	if synonyms == nil {
+		synonyms = []*models.SearchSynonym{}
+	}
+	data, err := json.Marshal(synonyms)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search synonyms: %w", err)
+	}
+
+	if err := c.redis.Set(ctx, searchSynonymsKey, data, SearchSynonymsTTL); err != nil {
+		return fmt.Errorf("failed to save search synonyms to Redis: %w", err)
+	}
+	return nil
+}
+
+// Invalidate drops the cached dictionary so every instance reloads it from the database
+func (c *SynonymCache) Invalidate(ctx context.Context) error {
+	if err := c.redis.Del(ctx, searchSynonymsKey); err != nil {
+		return fmt.Errorf("failed to invalidate search synonyms: %w", err)
+	}
+	return nil
+}
@@ -5,23 +5,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kainuguru/kainuguru-api/internal/database"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig    `mapstructure:"server"`
-	Database database.Config `mapstructure:"database"`
-	Redis    RedisConfig     `mapstructure:"redis"`
-	Logging  LoggingConfig   `mapstructure:"logging"`
-	OpenAI   OpenAIConfig    `mapstructure:"openai"`
-	Scraper  ScraperConfig   `mapstructure:"scraper"`
-	Worker   WorkerConfig    `mapstructure:"worker"`
-	CORS     CORSConfig      `mapstructure:"cors"`
-	Auth     AuthConfig      `mapstructure:"auth"`
-	App      AppConfig       `mapstructure:"app"`
-	Email    EmailConfig     `mapstructure:"email"`
-	Storage  StorageConfig   `mapstructure:"storage"`
+	Server     ServerConfig    `mapstructure:"server"`
+	Database   database.Config `mapstructure:"database"`
+	Redis      RedisConfig     `mapstructure:"redis"`
+	Logging    LoggingConfig   `mapstructure:"logging"`
+	OpenAI     OpenAIConfig    `mapstructure:"openai"`
+	Scraper    ScraperConfig   `mapstructure:"scraper"`
+	Worker     WorkerConfig    `mapstructure:"worker"`
+	CORS       CORSConfig      `mapstructure:"cors"`
+	Auth       AuthConfig      `mapstructure:"auth"`
+	App        AppConfig       `mapstructure:"app"`
+	Email      EmailConfig     `mapstructure:"email"`
+	Storage    StorageConfig   `mapstructure:"storage"`
+	Partitions PartitionConfig `mapstructure:"partitions"`
+	Cleanup    CleanupConfig   `mapstructure:"cleanup"`
+	Tracing    TracingConfig   `mapstructure:"tracing"`
 }
 
 type ServerConfig struct {
@@ -56,6 +60,56 @@ type OpenAIConfig struct {
 	Temperature float64       `mapstructure:"temperature"`
 	Timeout     time.Duration `mapstructure:"timeout"`
 	MaxRetries  int           `mapstructure:"max_retries"`
+
+	// Provider names the LLM provider behind BaseURL ("openai", "openrouter"); derived from BaseURL when empty
+	Provider string `mapstructure:"provider"`
+	// Limits holds worker concurrency and rate limits keyed by provider name
+	Limits map[string]LLMProviderLimits `mapstructure:"limits"`
+
+	// Overrides for the active provider's limits (0 = use Limits)
+	Concurrency       int `mapstructure:"concurrency"`
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	TokensPerMinute   int `mapstructure:"tokens_per_minute"`
+}
+
+// LLMProviderLimits bounds how hard a single provider account may be driven
+type LLMProviderLimits struct {
+	Concurrency       int `mapstructure:"concurrency"`         // Parallel page extractions per process
+	RequestsPerMinute int `mapstructure:"requests_per_minute"` // Shared across processes via Redis (0 = unlimited)
+	TokensPerMinute   int `mapstructure:"tokens_per_minute"`   // Shared across processes via Redis (0 = unlimited)
+}
+
+// ProviderName returns the configured provider or derives it from the base URL
+func (c OpenAIConfig) ProviderName() string {
+	if c.Provider != "" {
+		return strings.ToLower(c.Provider)
+	}
+	switch {
+	case strings.Contains(c.BaseURL, "openrouter.ai"):
+		return "openrouter"
+	case c.BaseURL == "" || strings.Contains(c.BaseURL, "api.openai.com"):
+		return "openai"
+	default:
+		return "custom"
+	}
+}
+
+// ProviderLimits returns the limits of the active provider with overrides applied
+func (c OpenAIConfig) ProviderLimits() LLMProviderLimits {
+	limits := c.Limits[c.ProviderName()]
+	if c.Concurrency > 0 {
+		limits.Concurrency = c.Concurrency
+	}
+	if c.RequestsPerMinute > 0 {
+		limits.RequestsPerMinute = c.RequestsPerMinute
+	}
+	if c.TokensPerMinute > 0 {
+		limits.TokensPerMinute = c.TokensPerMinute
+	}
+	if limits.Concurrency <= 0 {
+		limits.Concurrency = 1
+	}
+	return limits
 }
 
 type ScraperConfig struct {
@@ -78,6 +132,29 @@ type WorkerConfig struct {
 	JobTimeout         time.Duration `mapstructure:"job_timeout"`
 	MaxRetryAttempts   int           `mapstructure:"max_retry_attempts"`
 	MaxRetries         int           `mapstructure:"max_retries"`
+	QueueBackend       string        `mapstructure:"queue_backend"`
+	VisibilityTimeout  time.Duration `mapstructure:"visibility_timeout"`
+}
+
+// PartitionConfig controls the weekly partitions of the products table
+type PartitionConfig struct {
+	WeeksAhead     int `mapstructure:"weeks_ahead"`     // Weeks after the current one to keep partitions ready for
+	RetentionWeeks int `mapstructure:"retention_weeks"` // Weeks a partition stays attached after it ends
+}
+
+// CleanupConfig controls the storage optimization of the cleanup service
+type CleanupConfig struct {
+	WebPQuality       int `mapstructure:"webp_quality"`        // Quality old page images are recompressed to WebP at, 1-100
+	OptimizeAfterDays int `mapstructure:"optimize_after_days"` // Days after a flyer ends before its page images are recompressed
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported. Without an
+// exporter, spans go to the OTLP endpoint when one is set and nowhere otherwise.
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`     // otlp, stdout or none
+	Endpoint    string  `mapstructure:"endpoint"`     // OTLP/HTTP collector URL, e.g. http://otel-collector:4318
+	SampleRatio float64 `mapstructure:"sample_ratio"` // Fraction of new traces recorded; traces started upstream follow the caller
+	ServiceName string  `mapstructure:"service_name"`
 }
 
 type CORSConfig struct {
@@ -97,6 +174,22 @@ type AuthConfig struct {
 
 	// Clerk authentication (replaces JWT-based auth when enabled)
 	Clerk ClerkConfig `mapstructure:"clerk"`
+
+	// Users allowed to use the admin API: job queue, catalogue and search administration
+	AdminUserIDs []string `mapstructure:"admin_user_ids"`
+}
+
+// AdminIDs parses the admin user allow-list
+func (c AuthConfig) AdminIDs() ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(c.AdminUserIDs))
+	for _, raw := range c.AdminUserIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin user ID %q: %w", raw, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 type ClerkConfig struct {
@@ -206,6 +299,16 @@ func Load(env string) (*Config, error) {
 		}
 	}
 
+	// Handle comma-separated admin user IDs
+	if adminIDs := v.GetString("auth.admin_user_ids"); adminIDs != "" {
+		cfg.Auth.AdminUserIDs = nil
+		for _, id := range strings.Split(adminIDs, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				cfg.Auth.AdminUserIDs = append(cfg.Auth.AdminUserIDs, id)
+			}
+		}
+	}
+
 	// Validate required fields
 	if err := validateConfig(&cfg); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
@@ -241,6 +344,24 @@ func bindEnvironmentVariables(v *viper.Viper) {
 	v.BindEnv("redis.max_retries", "REDIS_MAX_RETRIES")
 	v.BindEnv("redis.pool_size", "REDIS_POOL_SIZE")
 
+	// Worker configuration
+	v.BindEnv("worker.queue_backend", "WORKER_QUEUE_BACKEND")
+	v.BindEnv("worker.visibility_timeout", "WORKER_VISIBILITY_TIMEOUT")
+
+	// Partition configuration
+	v.BindEnv("partitions.weeks_ahead", "PARTITIONS_WEEKS_AHEAD")
+	v.BindEnv("partitions.retention_weeks", "PARTITIONS_RETENTION_WEEKS")
+
+	// Cleanup configuration
+	v.BindEnv("cleanup.webp_quality", "CLEANUP_WEBP_QUALITY")
+	v.BindEnv("cleanup.optimize_after_days", "CLEANUP_OPTIMIZE_AFTER_DAYS")
+
+	// Tracing configuration
+	v.BindEnv("tracing.exporter", "TRACING_EXPORTER")
+	v.BindEnv("tracing.endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT")
+	v.BindEnv("tracing.sample_ratio", "TRACING_SAMPLE_RATIO")
+	v.BindEnv("tracing.service_name", "OTEL_SERVICE_NAME")
+
 	// CORS configuration
 	v.BindEnv("cors.allowed_origins", "CORS_ALLOWED_ORIGINS")
 	v.BindEnv("cors.allowed_methods", "CORS_ALLOWED_METHODS")
@@ -261,6 +382,10 @@ func bindEnvironmentVariables(v *viper.Viper) {
 	v.BindEnv("openai.temperature", "OPENAI_TEMPERATURE")
 	v.BindEnv("openai.timeout", "OPENAI_TIMEOUT")
 	v.BindEnv("openai.max_retries", "OPENAI_MAX_RETRIES")
+	v.BindEnv("openai.provider", "OPENAI_PROVIDER")
+	v.BindEnv("openai.concurrency", "OPENAI_CONCURRENCY")
+	v.BindEnv("openai.requests_per_minute", "OPENAI_REQUESTS_PER_MINUTE")
+	v.BindEnv("openai.tokens_per_minute", "OPENAI_TOKENS_PER_MINUTE")
 
 	// Logging configuration
 	v.BindEnv("logging.level", "LOG_LEVEL")
@@ -271,6 +396,7 @@ func bindEnvironmentVariables(v *viper.Viper) {
 	v.BindEnv("auth.session_secret", "SESSION_SECRET")
 
 	// Clerk authentication
+	v.BindEnv("auth.admin_user_ids", "ADMIN_USER_IDS")
 	v.BindEnv("auth.clerk.enabled", "CLERK_ENABLED")
 	v.BindEnv("auth.clerk.secret_key", "CLERK_SECRET_KEY")
 	v.BindEnv("auth.clerk.publishable_key", "CLERK_PUBLISHABLE_KEY")
@@ -319,6 +445,21 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("redis.max_retries", 3)
 	v.SetDefault("redis.pool_size", 10)
 
+	// Worker defaults
+	v.SetDefault("worker.queue_backend", "redis")
+	v.SetDefault("worker.visibility_timeout", "5m")
+
+	// Partition defaults
+	v.SetDefault("partitions.weeks_ahead", 4)
+	v.SetDefault("partitions.retention_weeks", 8)
+
+	// Cleanup defaults
+	v.SetDefault("cleanup.webp_quality", 80)
+	v.SetDefault("cleanup.optimize_after_days", 30)
+
+	// Tracing defaults
+	v.SetDefault("tracing.sample_ratio", 1.0)
+
 	// CORS defaults
 	v.SetDefault("cors.allowed_origins", []string{"http://localhost:3000", "http://localhost:8080"})
 	v.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
@@ -338,6 +479,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("openai.temperature", 0.1)
 	v.SetDefault("openai.timeout", "120s")
 	v.SetDefault("openai.max_retries", 3)
+	v.SetDefault("openai.limits.openai.concurrency", 4)
+	v.SetDefault("openai.limits.openai.requests_per_minute", 500)
+	v.SetDefault("openai.limits.openai.tokens_per_minute", 300000)
+	v.SetDefault("openai.limits.openrouter.concurrency", 4)
+	v.SetDefault("openai.limits.openrouter.requests_per_minute", 200)
+	v.SetDefault("openai.limits.custom.concurrency", 1)
 
 	// Logging defaults
 	v.SetDefault("logging.level", "info")
@@ -389,6 +536,10 @@ func validateConfig(cfg *Config) error {
 		}
 	}
 
+	if _, err := cfg.Auth.AdminIDs(); err != nil {
+		return err
+	}
+
 	// OpenAI validation - only required for production
 	if cfg.App.Environment == "production" {
 		if cfg.OpenAI.APIKey == "" {
@@ -10,6 +10,7 @@ import (
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
 	"github.com/uptrace/bun/extra/bundebug"
+	"github.com/uptrace/bun/extra/bunotel"
 )
 
 type BunDB struct {
@@ -49,6 +50,9 @@ func NewBun(cfg Config) (*BunDB, error) {
 		))
 	}
 
+	// Trace queries as spans of the request or job running them
+	db.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName(cfg.Name)))
+
 	// Test connection
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
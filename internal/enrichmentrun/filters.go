@@ -0,0 +1,16 @@
+package enrichmentrun
+
+// Filters define the available options when listing enrichment runs.
+type Filters struct {
+	Status   []string
+	Limit    int
+	Offset   int
+	OrderBy  string
+	OrderDir string
+}
+
+// PageFilters define the available options when listing pages of a run.
+type PageFilters struct {
+	Status   []string
+	FlyerIDs []int
+}
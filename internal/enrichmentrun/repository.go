@@ -0,0 +1,22 @@
+package enrichmentrun
+
+import (
+	"context"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+// Repository describes persistence operations for enrichment runs.
+type Repository interface {
+	// Run operations
+	GetByID(ctx context.Context, id int64) (*models.EnrichmentRun, error)
+	GetAll(ctx context.Context, filters *Filters) ([]*models.EnrichmentRun, error)
+	Create(ctx context.Context, run *models.EnrichmentRun) error
+	Update(ctx context.Context, run *models.EnrichmentRun) error
+	RefreshTotals(ctx context.Context, runID int64) error
+
+	// Page operations
+	GetPage(ctx context.Context, runID int64, flyerPageID int) (*models.EnrichmentRunPage, error)
+	GetPages(ctx context.Context, runID int64, filters *PageFilters) ([]*models.EnrichmentRunPage, error)
+	UpsertPage(ctx context.Context, page *models.EnrichmentRunPage) error
+}
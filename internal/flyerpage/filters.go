@@ -1,5 +1,7 @@
 package flyerpage
 
+import "time"
+
 // Filters define the available options when querying flyer pages.
 type Filters struct {
 	FlyerIDs    []int
@@ -7,6 +9,10 @@ type Filters struct {
 	HasImage    *bool
 	HasProducts *bool
 	PageNumbers []int
+	// ProcessingBefore also keeps pages left processing since before this time
+	ProcessingBefore *time.Time
+	// MaxAttempts keeps only pages with fewer extraction attempts (0 = no limit)
+	MaxAttempts int
 	Limit       int
 	Offset      int
 	OrderBy     string
@@ -0,0 +1,22 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/middleware"
+)
+
+// requireAdmin returns the authenticated user if they are on the admin allow-list
+// (auth.admin_user_ids). Every admin API resolver calls it before doing anything.
+func (r *Resolver) requireAdmin(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("authentication required")
+	}
+	if _, ok := r.adminUserIDs[userID]; !ok {
+		return uuid.Nil, fmt.Errorf("admin access required")
+	}
+	return userID, nil
+}
@@ -0,0 +1,89 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/middleware"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	admin := uuid.New()
+	shopper := uuid.New()
+	r := NewServiceResolver(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []uuid.UUID{admin}, nil)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr string
+	}{
+		{"anonymous", context.Background(), "authentication required"},
+		{"shopper", context.WithValue(context.Background(), middleware.UserContextKey, shopper), "admin access required"},
+		{"admin", context.WithValue(context.Background(), middleware.UserContextKey, admin), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := r.requireAdmin(tt.ctx)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("requireAdmin() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("requireAdmin() error = %v", err)
+			}
+			if userID != admin {
+				t.Errorf("requireAdmin() = %s, want %s", userID, admin)
+			}
+		})
+	}
+}
+
+func TestAdminResolvers_RejectShoppers(t *testing.T) {
+	// No services: a resolver reaching one would panic
+	r := NewServiceResolver(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	query, mutation, subscription := &queryResolver{r}, &mutationResolver{r}, &subscriptionResolver{r}
+	ctx := context.WithValue(context.Background(), middleware.UserContextKey, uuid.New())
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"QueueJobs", func() error { _, err := query.QueueJobs(ctx, nil); return err }},
+		{"Workflows", func() error { _, err := query.Workflows(ctx, nil); return err }},
+		{"PauseJobType", func() error { _, err := mutation.PauseJobType(ctx, "scrape_flyer"); return err }},
+		{"CancelQueueJob", func() error { _, err := mutation.CancelQueueJob(ctx, "job-1"); return err }},
+		{"QueueJobUpdated", func() error { _, err := subscription.QueueJobUpdated(ctx, "job-1"); return err }},
+		{"MergeProductMasters", func() error { _, err := mutation.MergeProductMasters(ctx, []int{1}, 2); return err }},
+		{"SplitProductMaster", func() error { _, err := mutation.SplitProductMaster(ctx, 1, []int{3}); return err }},
+		{"PendingProductMasterMatches", func() error { _, err := query.PendingProductMasterMatches(ctx, nil, nil); return err }},
+		{"AcceptProductMasterMatch", func() error { _, err := mutation.AcceptProductMasterMatch(ctx, 1); return err }},
+		{"RejectProductMasterMatch", func() error { _, err := mutation.RejectProductMasterMatch(ctx, 1); return err }},
+		{"ReassignProductMasterMatch", func() error { _, err := mutation.ReassignProductMasterMatch(ctx, 1, 2); return err }},
+		{"SearchSynonyms", func() error { _, err := query.SearchSynonyms(ctx, nil); return err }},
+		{"CreateSearchSynonym", func() error {
+			_, err := mutation.CreateSearchSynonym(ctx, model.SearchSynonymInput{})
+			return err
+		}},
+		{"UpdateSearchSynonym", func() error {
+			_, err := mutation.UpdateSearchSynonym(ctx, 1, model.SearchSynonymInput{})
+			return err
+		}},
+		{"DeleteSearchSynonym", func() error { _, err := mutation.DeleteSearchSynonym(ctx, 1); return err }},
+		{"TopSearchQueries", func() error { _, err := query.TopSearchQueries(ctx, nil); return err }},
+		{"ZeroResultSearchQueries", func() error { _, err := query.ZeroResultSearchQueries(ctx, nil); return err }},
+		{"SearchClickThrough", func() error { _, err := query.SearchClickThrough(ctx, nil); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err == nil || err.Error() != "admin access required" {
+				t.Errorf("%s() by a shopper error = %v, want admin access required", tt.name, err)
+			}
+		})
+	}
+}
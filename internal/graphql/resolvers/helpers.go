@@ -106,6 +106,10 @@ func convertProductFilters(filters *model.ProductFilters, limit, offset int) ser
 		serviceFilters.IsAvailable = filters.IsAvailable
 		serviceFilters.MinPrice = filters.MinPrice
 		serviceFilters.MaxPrice = filters.MaxPrice
+		if filters.SortBy != nil && *filters.SortBy == model.ProductSortUnitPrice {
+			serviceFilters.OrderBy = "normalized_unit_price"
+			serviceFilters.OrderDir = "ASC" // within the most common unit base; products without a known size sort last
+		}
 	}
 
 	return serviceFilters
@@ -442,6 +446,8 @@ func convertPriceHistoryToGraphQL(ph *models.PriceHistory) *model.PriceHistory {
 		FlyerID:          flyerID,
 		Price:            ph.Price,
 		OriginalPrice:    ph.OriginalPrice,
+		CardPrice:        ph.CardPrice,
+		AppPrice:         ph.AppPrice,
 		Currency:         ph.Currency,
 		IsOnSale:         ph.IsOnSale,
 		RecordedAt:       ph.RecordedAt.Format(time.RFC3339),
@@ -488,6 +494,7 @@ func convertProductMasterToGraphQL(pm *models.ProductMaster) *model.ProductMaste
 		Category:            pm.Category,
 		Subcategory:         pm.Subcategory,
 		StandardUnitSize:    formatFloatPtr(pm.StandardSize),
+		Barcode:             pm.Barcode,
 		StandardUnitType:    pm.UnitType,
 		StandardPackageSize: nil, // Not in DB model
 		StandardWeight:      nil, // Not in DB model
@@ -510,6 +517,73 @@ func convertProductMasterToGraphQL(pm *models.ProductMaster) *model.ProductMaste
 	}
 }
 
+// matchReviewStatusToGraphQL maps stored review statuses to the GraphQL enum
+var matchReviewStatusToGraphQL = map[string]model.MatchReviewStatus{
+	string(models.MatchReviewStatusPending):  model.MatchReviewStatusPending,
+	string(models.MatchReviewStatusApproved): model.MatchReviewStatusApproved,
+	string(models.MatchReviewStatusRejected): model.MatchReviewStatusRejected,
+}
+
+// convertProductMasterMatchToGraphQL converts models.ProductMasterMatch to model.ProductMasterMatch
+func convertProductMasterMatchToGraphQL(pmm *models.ProductMasterMatch) *model.ProductMasterMatch {
+	if pmm == nil {
+		return nil
+	}
+
+	status, ok := matchReviewStatusToGraphQL[pmm.ReviewStatus]
+	if !ok {
+		status = model.MatchReviewStatusPending
+	}
+
+	var reviewedAt *string
+	if pmm.ReviewedAt != nil {
+		formatted := pmm.ReviewedAt.Format(time.RFC3339)
+		reviewedAt = &formatted
+	}
+
+	return &model.ProductMasterMatch{
+		ID:           int(pmm.ID),
+		Product:      pmm.Product,
+		Candidate:    convertProductMasterToGraphQL(pmm.Master),
+		Confidence:   pmm.Confidence,
+		MatchType:    pmm.MatchType,
+		ReviewStatus: status,
+		ReviewedAt:   reviewedAt,
+		CreatedAt:    pmm.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// convertSearchSynonymToGraphQL converts models.SearchSynonym to model.SearchSynonym
+// Stored types are the lowercase enum values ("brand_alias" <-> BRAND_ALIAS)
+func convertSearchSynonymToGraphQL(ss *models.SearchSynonym) *model.SearchSynonym {
+	if ss == nil {
+		return nil
+	}
+
+	replacements := ss.Replacements
+	if replacements == nil {
+		replacements = []string{}
+	}
+
+	return &model.SearchSynonym{
+		ID:           int(ss.ID),
+		Type:         model.SearchSynonymType(strings.ToUpper(ss.Type)),
+		Term:         ss.Term,
+		Replacements: replacements,
+		CreatedAt:    ss.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    ss.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// convertSearchSynonymInput converts model.SearchSynonymInput to models.SearchSynonym
+func convertSearchSynonymInput(input model.SearchSynonymInput) *models.SearchSynonym {
+	return &models.SearchSynonym{
+		Type:         strings.ToLower(string(input.Type)),
+		Term:         input.Term,
+		Replacements: input.Replacements,
+	}
+}
+
 // convertStoreLocationToGraphQL converts models.StoreLocation to model.StoreLocation
 // These are structurally identical, so we can just cast
 func convertStoreLocationToGraphQL(sl *models.StoreLocation) *model.StoreLocation {
@@ -613,6 +687,71 @@ func convertFlyerPageToGraphQL(fp *models.FlyerPage) *model.FlyerPage {
 	}
 }
 
+var promotionTypesToGraphQL = map[models.PromotionType]model.PromotionType{
+	models.PromotionTypeBuyGet:          model.PromotionTypeBuyGet,
+	models.PromotionTypeMultiBuy:        model.PromotionTypeMultiBuy,
+	models.PromotionTypeNthItemDiscount: model.PromotionTypeNthItemDiscount,
+	models.PromotionTypeLoyalty:         model.PromotionTypeLoyalty,
+}
+
+var loyaltyProgramsToGraphQL = map[models.LoyaltyProgram]model.LoyaltyProgram{
+	models.LoyaltyProgramAitvaras: model.LoyaltyProgramAitvaras,
+	models.LoyaltyProgramManoRimi: model.LoyaltyProgramManoRimi,
+	models.LoyaltyProgramIki:      model.LoyaltyProgramIki,
+}
+
+// convertLoyaltyProgramsToGraphQL converts stored loyalty programmes, skipping unknown ones
+func convertLoyaltyProgramsToGraphQL(programs models.LoyaltyPrograms) []model.LoyaltyProgram {
+	result := make([]model.LoyaltyProgram, 0, len(programs))
+	for _, program := range programs {
+		if converted, ok := loyaltyProgramsToGraphQL[models.LoyaltyProgram(program)]; ok {
+			result = append(result, converted)
+		}
+	}
+	return result
+}
+
+// convertLoyaltyProgramsFromGraphQL converts GraphQL loyalty programmes to stored values
+func convertLoyaltyProgramsFromGraphQL(programs []model.LoyaltyProgram) []string {
+	result := make([]string, 0, len(programs))
+	for _, program := range programs {
+		for stored, converted := range loyaltyProgramsToGraphQL {
+			if converted == program {
+				result = append(result, string(stored))
+			}
+		}
+	}
+	return result
+}
+
+func convertPromotionToGraphQL(promo *models.Promotion) *model.ProductPromotion {
+	if promo == nil {
+		return nil
+	}
+	promoType, ok := promotionTypesToGraphQL[promo.Type]
+	if !ok {
+		return nil
+	}
+
+	result := &model.ProductPromotion{
+		Type:               promoType,
+		LoyaltyOnly:        promo.LoyaltyOnly,
+		QualifyingQuantity: promo.QualifyingQuantity(),
+	}
+	switch promo.Type {
+	case models.PromotionTypeBuyGet:
+		result.BuyQuantity = &promo.BuyQuantity
+		result.FreeQuantity = &promo.FreeQuantity
+	case models.PromotionTypeMultiBuy:
+		result.BundleQuantity = &promo.BundleQuantity
+		result.BundlePrice = &promo.BundlePrice
+	case models.PromotionTypeNthItemDiscount:
+		result.NthItem = &promo.NthItem
+		result.NthDiscountPercent = &promo.NthDiscountPercent
+	}
+	return result
+}
+
 // formatFloatPtr converts *float64 to *string
 func formatFloatPtr(f *float64) *string {
 	if f == nil {
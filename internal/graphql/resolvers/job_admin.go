@@ -0,0 +1,394 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// Job Queue Resolvers
+
+// QueueJobs lists the jobs in the worker job queue, newest first
+func (r *queryResolver) QueueJobs(ctx context.Context, filter *model.QueueJobFilter) ([]*model.QueueJob, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	jobs, err := r.jobAdminService.ListJobs(ctx, convertQueueJobFilter(filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	result := make([]*model.QueueJob, len(jobs))
+	for i, job := range jobs {
+		if result[i], err = convertQueueJobToGraphQL(job); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// QueueJob returns a job in the worker job queue, or nil once it left the queue
+func (r *queryResolver) QueueJob(ctx context.Context, id string) (*model.QueueJob, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	job, err := r.jobAdminService.GetJob(ctx, id)
+	if err != nil {
+		if apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return convertQueueJobToGraphQL(job)
+}
+
+// QueueStats returns the job counts of the worker job queue
+func (r *queryResolver) QueueStats(ctx context.Context) (*model.QueueStats, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return r.queueStats(ctx)
+}
+
+// RetryQueueJob runs a dead letter or delayed job again now, with fresh attempts
+func (r *mutationResolver) RetryQueueJob(ctx context.Context, id string) (*model.QueueJob, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	job, err := r.jobAdminService.RetryJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+	return convertQueueJobToGraphQL(job)
+}
+
+// RetryDeadLetterJobs retries every dead letter matching the filter
+func (r *mutationResolver) RetryDeadLetterJobs(ctx context.Context, filter *model.DeadLetterFilter) (int, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	serviceFilter := services.DeadLetterFilter{}
+	if filter != nil {
+		if filter.Type != nil {
+			serviceFilter.Type = worker.JobType(*filter.Type)
+		}
+		if filter.ErrorContains != nil {
+			serviceFilter.ErrorContains = *filter.ErrorContains
+		}
+	}
+
+	retried, err := r.jobAdminService.RetryDeadLetters(ctx, serviceFilter)
+	if err != nil {
+		return retried, fmt.Errorf("failed to retry dead letters: %w", err)
+	}
+	return retried, nil
+}
+
+// CancelQueueJob removes a job that is waiting to run, or asks a running one to stop
+func (r *mutationResolver) CancelQueueJob(ctx context.Context, id string) (*model.QueueJob, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	job, err := r.jobAdminService.CancelJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return convertQueueJobToGraphQL(job)
+}
+
+// PauseJobType stops workers from picking up jobs of a type; queued jobs wait
+func (r *mutationResolver) PauseJobType(ctx context.Context, typeArg string) (*model.QueueStats, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := r.jobAdminService.PauseJobType(ctx, worker.JobType(typeArg)); err != nil {
+		return nil, fmt.Errorf("failed to pause job type: %w", err)
+	}
+	return r.queueStats(ctx)
+}
+
+// ResumeJobType lets workers pick up jobs of a paused type again
+func (r *mutationResolver) ResumeJobType(ctx context.Context, typeArg string) (*model.QueueStats, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := r.jobAdminService.ResumeJobType(ctx, worker.JobType(typeArg)); err != nil {
+		return nil, fmt.Errorf("failed to resume job type: %w", err)
+	}
+	return r.queueStats(ctx)
+}
+
+// QueueJobUpdated streams a job's state and progress until it completes, fails for good or is cancelled
+func (r *subscriptionResolver) QueueJobUpdated(ctx context.Context, id string) (<-chan *model.QueueJob, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	jobs, err := r.jobAdminService.WatchJob(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch job: %w", err)
+	}
+
+	updates := make(chan *model.QueueJob)
+	go func() {
+		defer close(updates)
+		for job := range jobs {
+			update, err := convertQueueJobToGraphQL(job)
+			if err != nil {
+				continue
+			}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// Workflows lists workflows, newest first, without their steps
+func (r *queryResolver) Workflows(ctx context.Context, filter *model.WorkflowFilter) ([]*model.Workflow, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	workflows, err := r.jobAdminService.ListWorkflows(ctx, convertWorkflowFilter(filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	result := make([]*model.Workflow, len(workflows))
+	for i, wf := range workflows {
+		if result[i], err = convertWorkflowToGraphQL(wf); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Workflow returns a workflow with its steps
+func (r *queryResolver) Workflow(ctx context.Context, id string) (*model.Workflow, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	wf, err := r.jobAdminService.GetWorkflow(ctx, id)
+	if err != nil {
+		if apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+	return convertWorkflowToGraphQL(wf)
+}
+
+// StartWorkflow starts a named workflow with parameters given as a JSON object
+func (r *mutationResolver) StartWorkflow(ctx context.Context, name string, params *string) (*model.Workflow, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var workflowParams map[string]interface{}
+	if params != nil && *params != "" {
+		if err := json.Unmarshal([]byte(*params), &workflowParams); err != nil {
+			return nil, fmt.Errorf("params must be a JSON object: %w", err)
+		}
+	}
+
+	wf, err := r.jobAdminService.StartWorkflow(ctx, name, workflowParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start workflow: %w", err)
+	}
+	return convertWorkflowToGraphQL(wf)
+}
+
+func (r *Resolver) queueStats(ctx context.Context) (*model.QueueStats, error) {
+	stats, err := r.jobAdminService.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	paused, err := r.jobAdminService.PausedJobTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paused job types: %w", err)
+	}
+
+	pausedTypes := make([]string, len(paused))
+	for i, jobType := range paused {
+		pausedTypes[i] = string(jobType)
+	}
+	return &model.QueueStats{
+		Pending:        int(stats.Pending),
+		Scheduled:      int(stats.Scheduled),
+		Processing:     int(stats.Processing),
+		DeadLetter:     int(stats.DeadLetter),
+		PausedJobTypes: pausedTypes,
+	}, nil
+}
+
+// convertQueueJobFilter converts model.QueueJobFilter to worker.JobFilter
+func convertQueueJobFilter(filter *model.QueueJobFilter) worker.JobFilter {
+	result := worker.JobFilter{}
+	if filter == nil {
+		return result
+	}
+	if filter.Status != nil {
+		result.Status = worker.JobStatus(strings.ToLower(string(*filter.Status)))
+	}
+	if filter.Type != nil {
+		result.Type = worker.JobType(*filter.Type)
+	}
+	if filter.Limit != nil {
+		result.Limit = *filter.Limit
+	}
+	if filter.Offset != nil {
+		result.Offset = *filter.Offset
+	}
+	return result
+}
+
+// convertQueueJobToGraphQL converts worker.Job to model.QueueJob with its payload as JSON
+func convertQueueJobToGraphQL(job *worker.Job) (*model.QueueJob, error) {
+	payload := []byte("{}")
+	if job.Payload != nil {
+		var err error
+		if payload, err = json.Marshal(job.Payload); err != nil {
+			return nil, fmt.Errorf("failed to marshal payload of job %s: %w", job.ID, err)
+		}
+	}
+
+	result := &model.QueueJob{
+		ID:              job.ID,
+		Type:            string(job.Type),
+		Status:          model.QueueJobStatus(strings.ToUpper(string(job.Status))),
+		Priority:        job.Priority,
+		Payload:         string(payload),
+		Attempts:        job.Attempts,
+		MaxAttempts:     job.MaxAttempts,
+		ScheduledAt:     job.ScheduledAt,
+		StartedAt:       job.StartedAt,
+		CompletedAt:     job.CompletedAt,
+		CreatedAt:       job.CreatedAt,
+		UpdatedAt:       job.UpdatedAt,
+		Progress:        convertQueueJobProgressToGraphQL(job.Progress),
+		CancelRequested: job.CancelRequested,
+	}
+	if job.Error != "" {
+		result.Error = &job.Error
+	}
+	return result, nil
+}
+
+// convertQueueJobProgressToGraphQL converts worker.Progress to model.QueueJobProgress
+func convertQueueJobProgressToGraphQL(progress *worker.Progress) *model.QueueJobProgress {
+	if progress == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(progress.Counters))
+	for name := range progress.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &model.QueueJobProgress{
+		Percent:   progress.Percent,
+		Counters:  make([]*model.QueueJobCounter, len(names)),
+		UpdatedAt: progress.UpdatedAt,
+	}
+	for i, name := range names {
+		result.Counters[i] = &model.QueueJobCounter{Name: name, Value: int(progress.Counters[name])}
+	}
+	if progress.Step != "" {
+		result.Step = &progress.Step
+	}
+	return result
+}
+
+// convertWorkflowFilter converts model.WorkflowFilter to worker.WorkflowFilter
+func convertWorkflowFilter(filter *model.WorkflowFilter) worker.WorkflowFilter {
+	result := worker.WorkflowFilter{}
+	if filter == nil {
+		return result
+	}
+	if filter.Status != nil {
+		result.Status = worker.WorkflowStatus(strings.ToLower(string(*filter.Status)))
+	}
+	if filter.Name != nil {
+		result.Name = *filter.Name
+	}
+	if filter.Limit != nil {
+		result.Limit = *filter.Limit
+	}
+	if filter.Offset != nil {
+		result.Offset = *filter.Offset
+	}
+	return result
+}
+
+// convertWorkflowToGraphQL converts worker.Workflow to model.Workflow with its params as JSON
+func convertWorkflowToGraphQL(wf *worker.Workflow) (*model.Workflow, error) {
+	params := []byte("{}")
+	if wf.Params != nil {
+		var err error
+		if params, err = json.Marshal(wf.Params); err != nil {
+			return nil, fmt.Errorf("failed to marshal params of workflow %s: %w", wf.ID, err)
+		}
+	}
+
+	result := &model.Workflow{
+		ID:          wf.ID,
+		Name:        wf.Name,
+		Status:      model.WorkflowStatus(strings.ToUpper(string(wf.Status))),
+		Params:      string(params),
+		CreatedAt:   wf.CreatedAt,
+		UpdatedAt:   wf.UpdatedAt,
+		CompletedAt: wf.CompletedAt,
+		Steps:       make([]*model.WorkflowStep, len(wf.Steps)),
+	}
+	if wf.Error != "" {
+		result.Error = &wf.Error
+	}
+	for i, step := range wf.Steps {
+		result.Steps[i] = convertWorkflowStepToGraphQL(step)
+	}
+	return result, nil
+}
+
+// convertWorkflowStepToGraphQL converts worker.WorkflowStep to model.WorkflowStep
+func convertWorkflowStepToGraphQL(step *worker.WorkflowStep) *model.WorkflowStep {
+	result := &model.WorkflowStep{
+		Name:        step.Name,
+		DependsOn:   step.DependsOn,
+		JobID:       step.Job.ID,
+		JobType:     string(step.Job.Type),
+		Status:      model.WorkflowStepStatus(strings.ToUpper(string(step.Status))),
+		CreatedAt:   step.CreatedAt,
+		UpdatedAt:   step.UpdatedAt,
+		CompletedAt: step.CompletedAt,
+	}
+	if result.DependsOn == nil {
+		result.DependsOn = []string{}
+	}
+	if step.Parent != "" {
+		result.Parent = &step.Parent
+	}
+	if step.Error != "" {
+		result.Error = &step.Error
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kainuguru/kainuguru-api/internal/graphql/dataloaders"
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/middleware"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+// User Loyalty Programme Resolvers
+
+// LoyaltyPrograms resolves the loyaltyPrograms field on User type
+func (r *userResolver) LoyaltyPrograms(ctx context.Context, obj *models.User) ([]model.LoyaltyProgram, error) {
+	return convertLoyaltyProgramsToGraphQL(obj.LoyaltyPrograms), nil
+}
+
+// SetLoyaltyPrograms replaces the loyalty programmes of the current user
+func (r *mutationResolver) SetLoyaltyPrograms(ctx context.Context, programs []model.LoyaltyProgram) (*models.User, error) {
+	// Get authenticated user
+	userID, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	stored := convertLoyaltyProgramsFromGraphQL(programs)
+	user, err := r.authService.UpdateUser(ctx, userID, &models.UserUpdateInput{LoyaltyPrograms: &stored})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set loyalty programs: %w", err)
+	}
+
+	return user, nil
+}
+
+// currentLoyaltyPrograms returns the loyalty programmes of the authenticated user,
+// or nil for anonymous requests
+func (r *Resolver) currentLoyaltyPrograms(ctx context.Context) models.LoyaltyPrograms {
+	userID, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	// Use DataLoader so every product of a response shares one user lookup
+	user, err := dataloaders.FromContext(ctx).UserLoader.Load(ctx, userID.String())()
+	if err != nil || user == nil {
+		return nil
+	}
+	return user.LoyaltyPrograms
+}
+
+// isLoyaltyMemberAt checks if the authenticated user gets member prices at the store
+func (r *Resolver) isLoyaltyMemberAt(ctx context.Context, storeID int) bool {
+	programs := r.currentLoyaltyPrograms(ctx)
+	if len(programs) == 0 {
+		return false
+	}
+
+	store, err := dataloaders.FromContext(ctx).StoreLoader.Load(ctx, storeID)()
+	if err != nil || store == nil {
+		return false
+	}
+	return programs.CoversStore(store.Code)
+}
+
+// loyaltyStoreIDs returns the IDs of the stores where the authenticated user gets member prices
+func (r *Resolver) loyaltyStoreIDs(ctx context.Context) []int {
+	var storeIDs []int
+	for _, code := range r.currentLoyaltyPrograms(ctx).StoreCodes() {
+		store, err := r.storeService.GetByCode(ctx, code)
+		if err != nil || store == nil {
+			continue
+		}
+		storeIDs = append(storeIDs, store.ID)
+	}
+	return storeIDs
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/kainuguru/kainuguru-api/internal/graphql/dataloaders"
 	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
 	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/services/search"
 )
 
 // Product nested field resolvers
@@ -33,6 +34,7 @@ func (r *productResolver) Price(ctx context.Context, obj *models.Product) (*mode
 		}
 	}
 
+	loyaltyMember := r.isLoyaltyMemberAt(ctx, obj.StoreID)
 	price := &model.ProductPrice{
 		Current:         obj.CurrentPrice,
 		Original:        obj.OriginalPrice,
@@ -40,8 +42,19 @@ func (r *productResolver) Price(ctx context.Context, obj *models.Product) (*mode
 		Discount:        &discount,
 		DiscountPercent: &discountPercent,
 		SpecialDiscount: obj.SpecialDiscount,
+		Promotion:       convertPromotionToGraphQL(obj.GetPromotion()),
 		DiscountAmount:  discount,
 		IsDiscounted:    obj.IsOnSale,
+		Effective:       obj.DealPrice(),
+		Regular:         obj.RegularPrice(),
+		Card:            obj.CardPrice,
+		App:             obj.AppPrice,
+		Eligible:        obj.EligiblePrice(loyaltyMember),
+		UnitBase:        obj.UnitBase,
+		PerUnit:         obj.NormalizedUnitPrice,
+	}
+	if perUnit, _, ok := obj.EffectiveUnitPrice(obj.GetPromotion().QualifyingQuantity(), loyaltyMember); ok {
+		price.EligiblePerUnit = &perUnit
 	}
 
 	return price, nil
@@ -141,6 +154,31 @@ func (r *productResolver) PriceHistory(ctx context.Context, obj *models.Product)
 	return []*model.PriceHistory{}, nil
 }
 
+// SimilarProducts returns current products with a similar name, brand or category
+func (r *productResolver) SimilarProducts(ctx context.Context, obj *models.Product, first *int) ([]*model.SimilarProduct, error) {
+	limit := 10
+	if first != nil {
+		limit = *first
+	}
+
+	response, err := r.searchService.FindSimilarProducts(ctx, &search.SimilarProductsRequest{
+		ProductID: obj.ID,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find similar products: %w", err)
+	}
+
+	result := make([]*model.SimilarProduct, len(response.Products))
+	for i, similar := range response.Products {
+		result[i] = &model.SimilarProduct{
+			Product:         similar.Product,
+			SimilarityScore: similar.SimilarityScore,
+		}
+	}
+	return result, nil
+}
+
 func (r *productResolver) CreatedAt(ctx context.Context, obj *models.Product) (string, error) {
 	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
 }
@@ -0,0 +1,135 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+// Product Master Administration Resolvers
+
+// MergeProductMasters merges duplicate product masters into the target master
+func (r *mutationResolver) MergeProductMasters(ctx context.Context, sourceIDs []int, targetID int) (*model.ProductMaster, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(sourceIDs))
+	for i, id := range sourceIDs {
+		ids[i] = int64(id)
+	}
+
+	pm, err := r.productMasterService.MergeProductMasters(ctx, ids, int64(targetID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge product masters: %w", err)
+	}
+
+	return convertProductMasterToGraphQL(pm), nil
+}
+
+// SplitProductMaster moves products of a product master to a new master
+func (r *mutationResolver) SplitProductMaster(ctx context.Context, id int, productIDs []int) (*model.ProductMaster, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	pm, err := r.productMasterService.SplitProductMaster(ctx, int64(id), productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split product master: %w", err)
+	}
+
+	return convertProductMasterToGraphQL(pm), nil
+}
+
+// Product Master Match Review Resolvers
+
+// PendingProductMasterMatches lists matches waiting for review with the product and candidate master
+func (r *queryResolver) PendingProductMasterMatches(ctx context.Context, first *int, after *string) (*model.ProductMasterMatchConnection, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	pager := newDefaultPagination(first, after)
+	matches, err := r.productMasterService.GetPendingMatches(ctx, pager.LimitWithExtra(), pager.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending matches: %w", err)
+	}
+
+	return buildProductMasterMatchConnection(matches, pager.Limit(), pager.Offset()), nil
+}
+
+// AcceptProductMasterMatch approves a pending match
+func (r *mutationResolver) AcceptProductMasterMatch(ctx context.Context, id int) (*model.ProductMasterMatch, error) {
+	userID, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := r.productMasterService.AcceptMatch(ctx, int64(id), userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept match: %w", err)
+	}
+
+	return convertProductMasterMatchToGraphQL(match), nil
+}
+
+// RejectProductMasterMatch rejects a pending match so it is never proposed again
+func (r *mutationResolver) RejectProductMasterMatch(ctx context.Context, id int) (*model.ProductMasterMatch, error) {
+	userID, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := r.productMasterService.RejectMatch(ctx, int64(id), userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject match: %w", err)
+	}
+
+	return convertProductMasterMatchToGraphQL(match), nil
+}
+
+// ReassignProductMasterMatch rejects a pending match and links the product to another master
+func (r *mutationResolver) ReassignProductMasterMatch(ctx context.Context, id int, masterID int) (*model.ProductMasterMatch, error) {
+	userID, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := r.productMasterService.ReassignMatch(ctx, int64(id), int64(masterID), userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign match: %w", err)
+	}
+
+	return convertProductMasterMatchToGraphQL(match), nil
+}
+
+func buildProductMasterMatchConnection(matches []*models.ProductMasterMatch, limit, offset int) *model.ProductMasterMatchConnection {
+	hasNextPage := len(matches) > limit
+	if hasNextPage {
+		matches = matches[:limit]
+	}
+
+	edges := make([]*model.ProductMasterMatchEdge, len(matches))
+	for i, match := range matches {
+		edges[i] = &model.ProductMasterMatchEdge{
+			Node:   convertProductMasterMatchToGraphQL(match),
+			Cursor: encodeCursor(offset + i),
+		}
+	}
+
+	var endCursor *string
+	if len(edges) > 0 {
+		endCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.ProductMasterMatchConnection{
+		Edges: edges,
+		PageInfo: &model.PageInfo{
+			HasNextPage: hasNextPage,
+			EndCursor:   endCursor,
+		},
+		TotalCount: len(edges),
+	}
+}
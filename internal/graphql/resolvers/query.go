@@ -5,9 +5,11 @@ import (
 	"fmt"
 
 	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/middleware"
 	"github.com/kainuguru/kainuguru-api/internal/models"
 	"github.com/kainuguru/kainuguru-api/internal/services"
 	"github.com/kainuguru/kainuguru-api/internal/services/search"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/uptrace/bun"
 )
@@ -294,10 +296,23 @@ func (r *queryResolver) SearchProducts(ctx context.Context, input model.SearchIn
 		Offset:      0,
 		PreferFuzzy: preferFuzzy,
 	}
+	if userID, ok := middleware.GetUserFromContext(ctx); ok {
+		searchReq.UserID = userID.String()
+	}
 
 	if input.First != nil {
 		searchReq.Limit = *input.First
 	}
+	if input.SortBy != nil {
+		switch *input.SortBy {
+		case model.SearchSortPrice:
+			searchReq.SortBy = search.SortByPrice
+			searchReq.LoyaltyStoreIDs = r.loyaltyStoreIDs(ctx)
+		case model.SearchSortUnitPrice:
+			searchReq.SortBy = search.SortByUnitPrice
+			searchReq.LoyaltyStoreIDs = r.loyaltyStoreIDs(ctx)
+		}
+	}
 
 	// Use search service for full-text search
 	response, err := r.searchService.SearchProducts(ctx, searchReq)
@@ -370,6 +385,7 @@ func (r *queryResolver) SearchProducts(ctx context.Context, input model.SearchIn
 
 	// Convert search results to SearchResult
 	return &model.SearchResult{
+		SearchID:    response.SearchID,
 		QueryString: input.Q,
 		TotalCount:  response.TotalCount,
 		Products:    products,
@@ -395,6 +411,18 @@ func (r *queryResolver) ProductMaster(ctx context.Context, id int) (*model.Produ
 	return convertProductMasterToGraphQL(pm), nil
 }
 
+// ProductMasterByBarcode looks up a product master by a scanned EAN/UPC/GTIN barcode
+func (r *queryResolver) ProductMasterByBarcode(ctx context.Context, gtin string) (*model.ProductMaster, error) {
+	pm, err := r.productMasterService.GetByBarcode(ctx, gtin)
+	if err != nil {
+		if apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return convertProductMasterToGraphQL(pm), nil
+}
+
 func (r *queryResolver) ProductMasters(ctx context.Context, filters *model.ProductMasterFilters, first *int, after *string) (*model.ProductMasterConnection, error) {
 	pager := newDefaultPagination(first, after)
 	limit := pager.Limit()
@@ -1,6 +1,7 @@
 package resolvers
 
 import (
+	"github.com/google/uuid"
 	"github.com/kainuguru/kainuguru-api/internal/cache"
 	"github.com/kainuguru/kainuguru-api/internal/services"
 	"github.com/kainuguru/kainuguru-api/internal/services/auth"
@@ -17,6 +18,7 @@ type Resolver struct {
 	productService               services.ProductService
 	productMasterService         services.ProductMasterService
 	extractionJobService         services.ExtractionJobService
+	jobAdminService              services.JobAdminService
 	searchService                search.Service
 	authService                  auth.AuthService
 	shoppingListService          services.ShoppingListService
@@ -25,6 +27,7 @@ type Resolver struct {
 	wizardService                wizard.Service
 	userStorePreferenceService   services.UserStorePreferenceService
 	rateLimiter                  *cache.RateLimiter
+	adminUserIDs                 map[uuid.UUID]struct{}
 	db                           *bun.DB
 }
 
@@ -36,6 +39,7 @@ func NewServiceResolver(
 	productService services.ProductService,
 	productMasterService services.ProductMasterService,
 	extractionJobService services.ExtractionJobService,
+	jobAdminService services.JobAdminService,
 	searchService search.Service,
 	authService auth.AuthService,
 	shoppingListService services.ShoppingListService,
@@ -44,8 +48,14 @@ func NewServiceResolver(
 	wizardService wizard.Service,
 	userStorePreferenceService services.UserStorePreferenceService,
 	rateLimiter *cache.RateLimiter,
+	adminUserIDs []uuid.UUID,
 	db *bun.DB,
 ) *Resolver {
+	admins := make(map[uuid.UUID]struct{}, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = struct{}{}
+	}
+
 	return &Resolver{
 		storeService:                storeService,
 		flyerService:                flyerService,
@@ -53,6 +63,7 @@ func NewServiceResolver(
 		productService:              productService,
 		productMasterService:        productMasterService,
 		extractionJobService:        extractionJobService,
+		jobAdminService:             jobAdminService,
 		searchService:               searchService,
 		authService:                 authService,
 		shoppingListService:         shoppingListService,
@@ -61,6 +72,7 @@ func NewServiceResolver(
 		wizardService:               wizardService,
 		userStorePreferenceService:  userStorePreferenceService,
 		rateLimiter:                 rateLimiter,
+		adminUserIDs:                admins,
 		db:                          db,
 	}
 }
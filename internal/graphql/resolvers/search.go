@@ -0,0 +1,82 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/middleware"
+	"github.com/kainuguru/kainuguru-api/internal/services/search"
+)
+
+// Search Autocomplete and History Resolvers
+
+// SearchSuggestions returns autocomplete suggestions for a partially typed query
+func (r *queryResolver) SearchSuggestions(ctx context.Context, prefix string, first *int) ([]*model.SearchSuggestion, error) {
+	limit := 10
+	if first != nil {
+		limit = *first
+	}
+
+	response, err := r.searchService.GetSearchSuggestions(ctx, &search.SuggestionRequest{
+		PartialQuery: prefix,
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search suggestions: %w", err)
+	}
+
+	result := make([]*model.SearchSuggestion, len(response.Suggestions))
+	for i, suggestion := range response.Suggestions {
+		result[i] = &model.SearchSuggestion{
+			Text:       suggestion.Text,
+			Frequency:  int(suggestion.Frequency),
+			MinPrice:   suggestion.MinPrice,
+			MaxPrice:   suggestion.MaxPrice,
+			StoreCount: suggestion.StoreCount,
+		}
+	}
+	return result, nil
+}
+
+// RecentSearches returns the authenticated user's latest distinct queries
+func (r *queryResolver) RecentSearches(ctx context.Context, first *int) ([]*model.RecentSearch, error) {
+	userID, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("authentication required")
+	}
+
+	limit := 10
+	if first != nil {
+		limit = *first
+	}
+
+	searches, err := r.searchService.GetRecentSearches(ctx, userID.String(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent searches: %w", err)
+	}
+
+	result := make([]*model.RecentSearch, len(searches))
+	for i, recent := range searches {
+		result[i] = &model.RecentSearch{
+			Query:       recent.Query,
+			ResultCount: recent.ResultCount,
+			SearchedAt:  recent.SearchedAt.Format(time.RFC3339),
+		}
+	}
+	return result, nil
+}
+
+// ClearRecentSearches removes the authenticated user's search history
+func (r *mutationResolver) ClearRecentSearches(ctx context.Context) (bool, error) {
+	userID, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("authentication required")
+	}
+
+	if err := r.searchService.ClearRecentSearches(ctx, userID.String()); err != nil {
+		return false, fmt.Errorf("failed to clear recent searches: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,143 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+	"github.com/kainuguru/kainuguru-api/internal/services/search"
+)
+
+// Search Analytics Resolvers
+
+const (
+	defaultSearchAnalyticsDays  = 30
+	defaultSearchAnalyticsLimit = 20
+)
+
+// TopSearchQueries returns the most searched queries
+func (r *queryResolver) TopSearchQueries(ctx context.Context, filter *model.SearchAnalyticsFilter) ([]*model.SearchQueryStats, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	serviceFilter, err := convertSearchAnalyticsFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := r.searchService.GetTopSearchQueries(ctx, serviceFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top search queries: %w", err)
+	}
+	return convertSearchQueryStatsToGraphQL(stats), nil
+}
+
+// ZeroResultSearchQueries returns the queries that most often found nothing
+func (r *queryResolver) ZeroResultSearchQueries(ctx context.Context, filter *model.SearchAnalyticsFilter) ([]*model.SearchQueryStats, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	serviceFilter, err := convertSearchAnalyticsFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := r.searchService.GetZeroResultQueries(ctx, serviceFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zero-result search queries: %w", err)
+	}
+	return convertSearchQueryStatsToGraphQL(stats), nil
+}
+
+// SearchClickThrough returns searches and result clicks per day
+func (r *queryResolver) SearchClickThrough(ctx context.Context, filter *model.SearchAnalyticsFilter) ([]*model.SearchClickThroughDay, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	serviceFilter, err := convertSearchAnalyticsFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := r.searchService.GetSearchClickThrough(ctx, serviceFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search click-through: %w", err)
+	}
+
+	result := make([]*model.SearchClickThroughDay, len(days))
+	for i, day := range days {
+		result[i] = &model.SearchClickThroughDay{
+			Day:                day.Day.Format("2006-01-02"),
+			SearchCount:        int(day.SearchCount),
+			ClickCount:         int(day.ClickCount),
+			ClickedSearchCount: int(day.ClickedSearchCount),
+			ClickThroughRate:   day.ClickThroughRate,
+		}
+	}
+	return result, nil
+}
+
+// RecordSearchClick records a product opened from search results; anonymous shoppers may report clicks
+func (r *mutationResolver) RecordSearchClick(ctx context.Context, input model.SearchClickInput) (bool, error) {
+	err := r.searchService.RecordSearchClick(ctx, &search.SearchClick{
+		SearchID:  input.SearchID,
+		ProductID: input.ProductID,
+		Position:  input.Position,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record search click: %w", err)
+	}
+	return true, nil
+}
+
+// convertSearchAnalyticsFilter converts model.SearchAnalyticsFilter to search.SearchAnalyticsFilter,
+// covering the last 30 days and 20 queries by default
+func convertSearchAnalyticsFilter(filter *model.SearchAnalyticsFilter) (*search.SearchAnalyticsFilter, error) {
+	result := &search.SearchAnalyticsFilter{
+		To:    time.Now().UTC(),
+		Limit: defaultSearchAnalyticsLimit,
+	}
+
+	if filter != nil && filter.To != nil {
+		to, err := time.Parse("2006-01-02", *filter.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", *filter.To)
+		}
+		result.To = to
+	}
+	result.From = result.To.AddDate(0, 0, -(defaultSearchAnalyticsDays - 1))
+	if filter != nil && filter.From != nil {
+		from, err := time.Parse("2006-01-02", *filter.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", *filter.From)
+		}
+		result.From = from
+	}
+	if filter != nil && filter.Limit != nil {
+		result.Limit = *filter.Limit
+	}
+
+	return result, nil
+}
+
+// convertSearchQueryStatsToGraphQL converts search.SearchQueryStats to model.SearchQueryStats
+func convertSearchQueryStatsToGraphQL(stats []*search.SearchQueryStats) []*model.SearchQueryStats {
+	result := make([]*model.SearchQueryStats, len(stats))
+	for i, s := range stats {
+		result[i] = &model.SearchQueryStats{
+			Query:              s.Query,
+			SearchCount:        int(s.SearchCount),
+			ZeroResultCount:    int(s.ZeroResultCount),
+			ClickCount:         int(s.ClickCount),
+			ClickedSearchCount: int(s.ClickedSearchCount),
+			ClickThroughRate:   s.ClickThroughRate,
+			AverageQueryTimeMs: float64(s.AverageQueryTime) / float64(time.Millisecond),
+			LastSearchedOn:     s.LastSearchedOn.Format("2006-01-02"),
+		}
+	}
+	return result
+}
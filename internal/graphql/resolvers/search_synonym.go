@@ -0,0 +1,80 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kainuguru/kainuguru-api/internal/graphql/model"
+)
+
+// Search Dictionary Administration Resolvers
+
+// SearchSynonyms lists the search dictionary entries, optionally of one type
+func (r *queryResolver) SearchSynonyms(ctx context.Context, typeArg *model.SearchSynonymType) ([]*model.SearchSynonym, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var synonymType string
+	if typeArg != nil {
+		synonymType = strings.ToLower(string(*typeArg))
+	}
+
+	entries, err := r.searchService.ListSynonyms(ctx, synonymType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search synonyms: %w", err)
+	}
+
+	result := make([]*model.SearchSynonym, len(entries))
+	for i, entry := range entries {
+		result[i] = convertSearchSynonymToGraphQL(entry)
+	}
+	return result, nil
+}
+
+// CreateSearchSynonym adds a synonym, expansion, brand alias or stop-phrase
+func (r *mutationResolver) CreateSearchSynonym(ctx context.Context, input model.SearchSynonymInput) (*model.SearchSynonym, error) {
+	userID, err := r.requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := convertSearchSynonymInput(input)
+	createdBy := userID.String()
+	entry.CreatedBy = &createdBy
+
+	created, err := r.searchService.CreateSynonym(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search synonym: %w", err)
+	}
+
+	return convertSearchSynonymToGraphQL(created), nil
+}
+
+// UpdateSearchSynonym replaces a search dictionary entry
+func (r *mutationResolver) UpdateSearchSynonym(ctx context.Context, id int, input model.SearchSynonymInput) (*model.SearchSynonym, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	updated, err := r.searchService.UpdateSynonym(ctx, int64(id), convertSearchSynonymInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update search synonym: %w", err)
+	}
+
+	return convertSearchSynonymToGraphQL(updated), nil
+}
+
+// DeleteSearchSynonym removes a search dictionary entry
+func (r *mutationResolver) DeleteSearchSynonym(ctx context.Context, id int) (bool, error) {
+	if _, err := r.requireAdmin(ctx); err != nil {
+		return false, err
+	}
+
+	if err := r.searchService.DeleteSynonym(ctx, int64(id)); err != nil {
+		return false, fmt.Errorf("failed to delete search synonym: %w", err)
+	}
+
+	return true, nil
+}
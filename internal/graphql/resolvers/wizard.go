@@ -627,11 +627,9 @@ func mapWizardSessionToGraphQL(session *models.WizardSession) *model.WizardSessi
 func mapSuggestionsToGraphQL(suggestions []models.Suggestion) []*model.Suggestion {
 	gqlSuggestions := make([]*model.Suggestion, 0, len(suggestions))
 	for _, sug := range suggestions {
-		// Build size comparison text if available
 		var sizeComparison *string
-		if sug.SizeValue != nil && sug.SizeUnit != nil {
-			sizeText := fmt.Sprintf("%v %s", *sug.SizeValue, *sug.SizeUnit)
-			sizeComparison = &sizeText
+		if sug.SizeComparison != "" {
+			sizeComparison = &sug.SizeComparison
 		}
 
 		// Product field populated by field resolver using DataLoader (T068)
@@ -1,17 +1,23 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/kainuguru/kainuguru-api/internal/cache"
 	"github.com/kainuguru/kainuguru-api/internal/graphql/dataloaders"
 	"github.com/kainuguru/kainuguru-api/internal/graphql/generated"
@@ -31,6 +37,7 @@ type GraphQLConfig struct {
 	ProductService             services.ProductService
 	ProductMasterService       services.ProductMasterService
 	ExtractionJobService       services.ExtractionJobService
+	JobAdminService            services.JobAdminService
 	SearchService              search.Service
 	AuthService                auth.AuthService
 	ShoppingListService        services.ShoppingListService
@@ -39,6 +46,7 @@ type GraphQLConfig struct {
 	WizardService              wizard.Service
 	UserStorePreferenceService services.UserStorePreferenceService
 	RateLimiter                *cache.RateLimiter
+	AdminUserIDs               []uuid.UUID
 	DB                         *bun.DB
 }
 
@@ -52,6 +60,7 @@ func GraphQLHandler(config GraphQLConfig) fiber.Handler {
 		config.ProductService,
 		config.ProductMasterService,
 		config.ExtractionJobService,
+		config.JobAdminService,
 		config.SearchService,
 		config.AuthService,
 		config.ShoppingListService,
@@ -60,6 +69,7 @@ func GraphQLHandler(config GraphQLConfig) fiber.Handler {
 		config.WizardService,
 		config.UserStorePreferenceService,
 		config.RateLimiter,
+		config.AdminUserIDs,
 		config.DB,
 	)
 
@@ -68,16 +78,10 @@ func GraphQLHandler(config GraphQLConfig) fiber.Handler {
 		Resolvers: serviceResolver,
 	})
 
-	// Create GraphQL server with proper configuration
-	srv := handler.NewDefaultServer(schema)
-	srv.AddTransport(transport.Options{})
-	srv.AddTransport(transport.POST{})
-	srv.Use(extension.Introspection{})
-
 	// Add AroundOperations to inject dataloaders into context
 	// This ensures dataloaders are available in all field resolvers including
 	// those executed in goroutines by gqlgen's FieldSet.Dispatch
-	srv.AroundOperations(func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	injectDataloaders := func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
 		// Create fresh dataloaders for this operation
 		loaders := dataloaders.NewLoaders(
 			config.StoreService,
@@ -90,7 +94,23 @@ func GraphQLHandler(config GraphQLConfig) fiber.Handler {
 		)
 		ctx = dataloaders.AddToContext(ctx, loaders)
 		return next(ctx)
-	})
+	}
+
+	// Create GraphQL server with proper configuration
+	srv := handler.NewDefaultServer(schema)
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+	srv.Use(newGraphQLTracing())
+	srv.AroundOperations(injectDataloaders)
+
+	// Subscriptions stream as server-sent events from a server of their own,
+	// since the default server's POST transport would answer them first
+	streamSrv := handler.New(schema)
+	streamSrv.AddTransport(transport.SSE{KeepAlivePingInterval: sseKeepAliveInterval})
+	streamSrv.Use(extension.Introspection{})
+	streamSrv.Use(newGraphQLTracing())
+	streamSrv.AroundOperations(injectDataloaders)
 
 	return func(c *fiber.Ctx) error {
 		// Get context from Fiber (may have auth info from middleware)
@@ -107,6 +127,9 @@ func GraphQLHandler(config GraphQLConfig) fiber.Handler {
 
 		// Create HTTP request for gqlgen server
 		body, _ := json.Marshal(req)
+		if strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+			return streamGraphQL(c, streamSrv, ctx, body)
+		}
 		httpReq := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq = httpReq.WithContext(ctx)
@@ -133,6 +156,79 @@ func GraphQLHandler(config GraphQLConfig) fiber.Handler {
 	}
 }
 
+// sseKeepAliveInterval is how often an idle subscription stream is pinged
+const sseKeepAliveInterval = 15 * time.Second
+
+// streamGraphQL runs a request asking for server-sent events, e.g. a subscription,
+// writing each result to the client as it is produced until the operation ends or
+// the client goes away.
+func streamGraphQL(c *fiber.Ctx, srv *handler.Server, ctx context.Context, body []byte) error {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		httpReq := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq = httpReq.WithContext(streamCtx)
+
+		stream := &sseResponseWriter{header: make(http.Header), w: w, cancel: cancel}
+		defer stream.close()
+		srv.ServeHTTP(stream, httpReq)
+		stream.Flush()
+	})
+	return nil
+}
+
+// sseResponseWriter lets gqlgen's SSE transport write to a fasthttp body stream.
+// A failed flush means the client went away, which ends the operation.
+type sseResponseWriter struct {
+	header http.Header
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closed bool
+}
+
+func (s *sseResponseWriter) Header() http.Header {
+	return s.header
+}
+
+// WriteHeader is a no-op; the stream's status and headers are sent before it starts
+func (s *sseResponseWriter) WriteHeader(statusCode int) {}
+
+func (s *sseResponseWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return s.w.Write(p)
+}
+
+func (s *sseResponseWriter) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if err := s.w.Flush(); err != nil {
+		s.cancel()
+	}
+}
+
+// close stops writes to the stream, which fasthttp reuses once the stream writer returns
+func (s *sseResponseWriter) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
 // graphQLRequest represents a GraphQL request body
 type graphQLRequest struct {
 	Query         string                 `json:"query"`
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,6 +10,10 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/middleware"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
 	"github.com/stretchr/testify/require"
 )
 
@@ -65,3 +70,57 @@ func TestGraphQLHandlerPropagatesRequestContextCancellation(t *testing.T) {
 		t.Fatal("downstream context did not observe cancellation")
 	}
 }
+
+// watchingJobAdmin streams the given snapshots of any watched job
+type watchingJobAdmin struct {
+	services.JobAdminService
+	snapshots []*worker.Job
+}
+
+func (a *watchingJobAdmin) WatchJob(ctx context.Context, id string) (<-chan *worker.Job, error) {
+	jobs := make(chan *worker.Job, len(a.snapshots))
+	for _, job := range a.snapshots {
+		jobs <- job
+	}
+	close(jobs)
+	return jobs, nil
+}
+
+func TestGraphQLHandlerStreamsSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	admin := uuid.New()
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(context.WithValue(context.Background(), middleware.UserContextKey, admin))
+		return c.Next()
+	})
+	app.All("/graphql", GraphQLHandler(GraphQLConfig{
+		AdminUserIDs: []uuid.UUID{admin},
+		JobAdminService: &watchingJobAdmin{snapshots: []*worker.Job{
+			{ID: "job-1", Status: worker.JobStatusProcessing, Progress: &worker.Progress{Percent: 40, Step: "extracting"}},
+			{ID: "job-1", Status: worker.JobStatusCompleted, Progress: &worker.Progress{Percent: 100}},
+		}},
+	}))
+
+	body := `{"query":"subscription { queueJobUpdated(id: \"job-1\") { status progress { percent step } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	stream, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	events := strings.Split(strings.TrimSpace(string(stream)), "\n\n")
+	require.Equal(t, []string{
+		":",
+		`event: next` + "\n" + `data: {"data":{"queueJobUpdated":{"status":"PROCESSING","progress":{"percent":40,"step":"extracting"}}}}`,
+		`event: next` + "\n" + `data: {"data":{"queueJobUpdated":{"status":"COMPLETED","progress":{"percent":100,"step":null}}}}`,
+		"event: complete",
+	}, events)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const graphQLTracerName = "github.com/kainuguru/kainuguru-api/internal/handlers/graphql"
+
+// graphQLTracing is a gqlgen extension starting a span for every operation
+// response and every field with a resolver of its own. Fields read straight
+// from their parent's result would only add noise to the trace.
+type graphQLTracing struct {
+	tracer trace.Tracer
+}
+
+var (
+	_ graphql.HandlerExtension    = graphQLTracing{}
+	_ graphql.ResponseInterceptor = graphQLTracing{}
+	_ graphql.FieldInterceptor    = graphQLTracing{}
+)
+
+func newGraphQLTracing() graphQLTracing {
+	return graphQLTracing{tracer: otel.Tracer(graphQLTracerName)}
+}
+
+func (graphQLTracing) ExtensionName() string {
+	return "OpenTelemetryTracing"
+}
+
+func (graphQLTracing) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (t graphQLTracing) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+
+	name := oc.OperationName
+	operationType := ""
+	if oc.Operation != nil {
+		operationType = string(oc.Operation.Operation)
+		if name == "" {
+			name = oc.Operation.Name
+		}
+	}
+	spanName := "GraphQL " + operationType
+	if name != "" {
+		spanName += " " + name
+	}
+
+	ctx, span := t.tracer.Start(ctx, spanName, trace.WithAttributes(
+		semconv.GraphQLOperationName(name),
+		semconv.GraphQLOperationTypeKey.String(operationType),
+	))
+	defer span.End()
+
+	response := next(ctx)
+	if response != nil && len(response.Errors) > 0 {
+		span.SetStatus(codes.Error, response.Errors.Error())
+	}
+	return response
+}
+
+func (t graphQLTracing) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := t.tracer.Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+	if fc.Field.Definition != nil {
+		span.SetAttributes(attribute.String("graphql.field.type", fc.Field.Definition.Type.String()))
+	}
+
+	result, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if errs := graphql.GetFieldErrors(ctx, fc); len(errs) > 0 {
+		span.SetStatus(codes.Error, errs.Error())
+	}
+	return result, err
+}
@@ -7,19 +7,21 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/kainuguru/kainuguru-api/internal/cache"
 	"github.com/kainuguru/kainuguru-api/internal/database"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
 	"github.com/rs/zerolog/log"
 )
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status    string            `json:"status"`
-	Timestamp time.Time         `json:"timestamp"`
-	Services  map[string]string `json:"services"`
-	Version   string            `json:"version"`
+	Status     string            `json:"status"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Services   map[string]string `json:"services"`
+	Partitions *partition.Health `json:"partitions,omitempty"`
+	Version    string            `json:"version"`
 }
 
 // Health returns a health check handler
-func Health(db *database.BunDB, redis *cache.RedisClient) fiber.Handler {
+func Health(db *database.BunDB, redis *cache.RedisClient, partitions *partition.Manager) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -49,6 +51,14 @@ func Health(db *database.BunDB, redis *cache.RedisClient) fiber.Handler {
 			response.Services["redis"] = "healthy"
 		}
 
+		// Check products partitions; missing future weeks only degrade the service
+		response.Partitions = partitions.Health(ctx)
+		response.Services["partitions"] = string(response.Partitions.Status)
+		if response.Partitions.Status == partition.HealthStatusUnhealthy {
+			log.Error().Str("message", response.Partitions.Message).Msg("Partition health check failed")
+			response.Status = "unhealthy"
+		}
+
 		// Set appropriate status code
 		statusCode := fiber.StatusOK
 		if response.Status != "healthy" {
@@ -45,7 +45,7 @@ func NewAuthMiddleware(cfg AuthMiddlewareConfig) fiber.Handler {
 			return c.Next()
 		}
 
-		session, err := cfg.SessionService.ValidateSession(c.Context(), claims.SessionID)
+		session, err := cfg.SessionService.ValidateSession(c.UserContext(), claims.SessionID)
 		if err != nil {
 			if cfg.Required {
 				return unauthorizedResponse(c, "Invalid or expired session", err.Error())
@@ -53,7 +53,7 @@ func NewAuthMiddleware(cfg AuthMiddlewareConfig) fiber.Handler {
 			return c.Next()
 		}
 
-		ctx := context.WithValue(c.Context(), UserContextKey, claims.UserID)
+		ctx := context.WithValue(c.UserContext(), UserContextKey, claims.UserID)
 		ctx = context.WithValue(ctx, SessionContextKey, session.ID)
 		ctx = context.WithValue(ctx, ClaimsContextKey, claims)
 		c.SetUserContext(ctx)
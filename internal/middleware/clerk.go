@@ -66,7 +66,7 @@ func NewClerkMiddleware(cfg ClerkMiddlewareConfig) fiber.Handler {
 		clerkSessionID := claims.SessionID
 
 		// Store Clerk data in context
-		ctx := context.WithValue(c.Context(), ClerkUserIDKey, clerkUserID)
+		ctx := context.WithValue(c.UserContext(), ClerkUserIDKey, clerkUserID)
 		ctx = context.WithValue(ctx, ClerkSessionIDKey, clerkSessionID)
 		ctx = context.WithValue(ctx, ClerkSessionClaims, claims)
 
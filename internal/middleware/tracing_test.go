@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	app := fiber.New()
+	app.Use(Tracing())
+
+	var handlerSpan trace.SpanContext
+	app.Get("/flyers/:id", func(c *fiber.Ctx) error {
+		handlerSpan = trace.SpanContextFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "down")
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/flyers/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatalf("app.Test error: %v", err)
+	}
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/broken", nil), -1); err != nil {
+		t.Fatalf("app.Test error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+
+	ok := spans[0]
+	if ok.Name() != "GET /flyers/:id" {
+		t.Errorf("span name = %q, want the route", ok.Name())
+	}
+	if ok.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", ok.SpanKind())
+	}
+	if got := ok.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("trace ID = %s, want the caller's %s", got, traceID)
+	}
+	if handlerSpan.SpanID() != ok.SpanContext().SpanID() {
+		t.Error("handler's user context does not carry the request span")
+	}
+	if !hasAttribute(ok, semconv.HTTPResponseStatusCode(fiber.StatusOK)) {
+		t.Errorf("attributes = %v, want status 200", ok.Attributes())
+	}
+
+	broken := spans[1]
+	if broken.Parent().IsValid() {
+		t.Error("request without trace headers did not start a new trace")
+	}
+	if !hasAttribute(broken, semconv.HTTPResponseStatusCode(fiber.StatusServiceUnavailable)) {
+		t.Errorf("attributes = %v, want status 503", broken.Attributes())
+	}
+	if broken.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for a 503", broken.Status().Code)
+	}
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, want attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun/dialect"
+)
+
+// migrationLockID is the Postgres advisory lock every migrator of a database takes
+const migrationLockID int64 = 4_170_520_911_336
+
+// withLock runs fn holding the migration lock, waiting for another migrator
+// holding it to finish. Databases other than Postgres are not locked.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if m.db.Dialect().Name() != dialect.PG {
+		return fn()
+	}
+
+	// Advisory locks belong to a session, so take and release it on one connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection for the migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(?)", migrationLockID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	if !locked {
+		fmt.Fprintln(m.out, "Waiting for another migrator to finish...")
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock(?)", migrationLockID); err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock(?)", migrationLockID); err != nil {
+			fmt.Fprintf(m.out, "Failed to release the migration lock: %v\n", err)
+		}
+	}()
+
+	return fn()
+}
@@ -3,18 +3,27 @@ package migrator
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kainuguru/kainuguru-api/migrations"
 	"github.com/uptrace/bun"
 )
 
-// Migrator handles database migrations
+// Migrator handles database migrations. Every applied migration is recorded with
+// the checksum of its file, and Up and Down refuse to run once an applied file was
+// edited. On Postgres they hold an advisory lock, so migrators started together
+// run one after the other.
 type Migrator struct {
-	db *bun.DB
+	db           *bun.DB
+	fsys         fs.FS
+	goMigrations []GoMigration
+	out          io.Writer
 }
 
 // Migration represents a database migration
@@ -24,307 +33,442 @@ type Migration struct {
 	ID        int       `bun:"id,pk,autoincrement"`
 	Version   string    `bun:"version,unique,notnull"`
 	Name      string    `bun:"name,notnull"`
+	Checksum  string    `bun:"checksum,nullzero"` // sha256 of the SQL file; empty for Go migrations
 	AppliedAt time.Time `bun:"applied_at,notnull,default:current_timestamp"`
 }
 
-// New creates a new migrator instance
+// RunOptions selects the migrations Up and Down run
+type RunOptions struct {
+	// Steps is how many migrations to run; 0 means all of them for Up and one for Down
+	Steps int
+	// To is the version Up migrates to, including it, or Down rolls back to, keeping it
+	To string
+	// DryRun prints the SQL that would run instead of running it
+	DryRun bool
+}
+
+// New creates a migrator for the embedded migrations and the registered Go migrations
 func New(db *bun.DB) *Migrator {
-	return &Migrator{db: db}
+	return NewWithSource(db, migrations.FS, registered)
 }
 
-// Up runs pending migrations
-func (m *Migrator) Up(ctx context.Context, steps int) error {
-	// Create migrations table if it doesn't exist
-	if err := m.ensureMigrationsTable(ctx); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
-	}
-
-	// Get applied migrations
-	applied, err := m.getAppliedMigrations(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
+// NewWithSource creates a migrator for the migrations of fsys and the given Go migrations
+func NewWithSource(db *bun.DB, fsys fs.FS, goMigrations []GoMigration) *Migrator {
+	return &Migrator{
+		db:           db,
+		fsys:         fsys,
+		goMigrations: goMigrations,
+		out:          os.Stdout,
 	}
+}
 
-	// Get all migration files
-	migrations, err := m.getMigrationFiles()
-	if err != nil {
-		return fmt.Errorf("failed to get migration files: %w", err)
-	}
+// migrationState is the source migrations next to the ones the database applied
+type migrationState struct {
+	migrations []*migration
+	applied    map[string]*Migration
+	// drifted lists applied SQL migrations whose file changed since
+	drifted []string
+}
 
-	// Filter out already applied migrations
-	pending := make([]string, 0)
-	for _, migration := range migrations {
-		version := m.extractVersion(migration)
-		if !contains(applied, version) {
-			pending = append(pending, migration)
+// Up runs pending migrations
+func (m *Migrator) Up(ctx context.Context, opts RunOptions) error {
+	if opts.DryRun {
+		state, err := m.loadState(ctx, false)
+		if err != nil {
+			return err
 		}
-	}
-
-	if len(pending) == 0 {
-		fmt.Println("No pending migrations")
+		pending, err := state.pending(opts)
+		if err != nil {
+			return err
+		}
+		m.printPlan("up", pending)
 		return nil
 	}
 
-	// Limit steps if specified
-	if steps > 0 && len(pending) > steps {
-		pending = pending[:steps]
-	}
-
-	// Apply migrations
-	for _, migration := range pending {
-		if err := m.applyMigration(ctx, migration); err != nil {
-			return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+	return m.withLock(ctx, func() error {
+		state, err := m.loadState(ctx, true)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("Applied migration: %s\n", migration)
-	}
-
-	return nil
+		if err := state.checkDrift(); err != nil {
+			return err
+		}
+		pending, err := state.pending(opts)
+		if err != nil {
+			return err
+		}
+		return m.applyAll(ctx, pending)
+	})
 }
 
 // Down rolls back migrations
-func (m *Migrator) Down(ctx context.Context, steps int) error {
-	if steps <= 0 {
-		steps = 1
-	}
-
-	// Get applied migrations
-	applied, err := m.getAppliedMigrations(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %w", err)
-	}
-
-	if len(applied) == 0 {
-		fmt.Println("No migrations to rollback")
+func (m *Migrator) Down(ctx context.Context, opts RunOptions) error {
+	if opts.DryRun {
+		state, err := m.loadState(ctx, false)
+		if err != nil {
+			return err
+		}
+		rollback, err := state.rollback(opts)
+		if err != nil {
+			return err
+		}
+		m.printPlan("down", rollback)
 		return nil
 	}
 
-	// Sort in reverse order
-	sort.Slice(applied, func(i, j int) bool {
-		return applied[i] > applied[j]
-	})
-
-	// Limit steps
-	if len(applied) > steps {
-		applied = applied[:steps]
-	}
-
-	// Rollback migrations
-	for _, version := range applied {
-		if err := m.rollbackMigration(ctx, version); err != nil {
-			return fmt.Errorf("failed to rollback migration %s: %w", version, err)
+	return m.withLock(ctx, func() error {
+		state, err := m.loadState(ctx, true)
+		if err != nil {
+			return err
+		}
+		if err := state.checkDrift(); err != nil {
+			return err
+		}
+		rollback, err := state.rollback(opts)
+		if err != nil {
+			return err
+		}
+		if len(rollback) == 0 {
+			fmt.Fprintln(m.out, "No migrations to rollback")
+			return nil
 		}
-		fmt.Printf("Rolled back migration: %s\n", version)
-	}
 
-	return nil
+		for _, mig := range rollback {
+			if err := m.rollbackMigration(ctx, mig); err != nil {
+				return fmt.Errorf("failed to rollback migration %s: %w", mig.Name, err)
+			}
+			fmt.Fprintf(m.out, "Rolled back migration: %s\n", mig.Name)
+		}
+		return nil
+	})
 }
 
 // Reset drops all tables and reapplies all migrations
 func (m *Migrator) Reset(ctx context.Context) error {
-	// Drop all tables
-	if err := m.dropAllTables(ctx); err != nil {
-		return fmt.Errorf("failed to drop tables: %w", err)
-	}
+	return m.withLock(ctx, func() error {
+		// Drop all tables
+		if err := m.dropAllTables(ctx); err != nil {
+			return fmt.Errorf("failed to drop tables: %w", err)
+		}
 
-	// Run all migrations
-	return m.Up(ctx, 0)
+		state, err := m.loadState(ctx, true)
+		if err != nil {
+			return err
+		}
+		pending, err := state.pending(RunOptions{})
+		if err != nil {
+			return err
+		}
+		return m.applyAll(ctx, pending)
+	})
 }
 
-// Status returns migration status
+// Status returns migration status, listing the pending, skipped and edited migrations
 func (m *Migrator) Status(ctx context.Context) (string, error) {
-	// Ensure migrations table exists
-	if err := m.ensureMigrationsTable(ctx); err != nil {
-		return "", fmt.Errorf("failed to create migrations table: %w", err)
+	state, err := m.loadState(ctx, false)
+	if err != nil {
+		return "", err
 	}
-
-	applied, err := m.getAppliedMigrations(ctx)
+	pending, err := state.pending(RunOptions{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get applied migrations: %w", err)
+		return "", err
 	}
 
-	migrations, err := m.getMigrationFiles()
-	if err != nil {
-		return "", fmt.Errorf("failed to get migration files: %w", err)
+	var skipped []string
+	available := 0
+	for _, mig := range state.migrations {
+		if mig.Skipped {
+			skipped = append(skipped, mig.Name)
+			continue
+		}
+		available++
 	}
 
 	var status strings.Builder
-	status.WriteString(fmt.Sprintf("Applied migrations: %d\n", len(applied)))
-	status.WriteString(fmt.Sprintf("Available migrations: %d\n", len(migrations)))
-
-	pending := 0
-	for _, migration := range migrations {
-		version := m.extractVersion(migration)
-		if !contains(applied, version) {
-			pending++
+	status.WriteString(fmt.Sprintf("Applied migrations: %d\n", len(state.applied)))
+	status.WriteString(fmt.Sprintf("Available migrations: %d\n", available))
+	status.WriteString(fmt.Sprintf("Pending migrations: %d\n", len(pending)))
+	for _, mig := range pending {
+		status.WriteString(fmt.Sprintf("  pending  %s (%s)\n", mig.Name, mig.kind()))
+	}
+	status.WriteString(fmt.Sprintf("Skipped migrations: %d\n", len(skipped)))
+	for _, name := range skipped {
+		status.WriteString(fmt.Sprintf("  skipped  %s\n", name))
+	}
+	if len(state.drifted) > 0 {
+		status.WriteString(fmt.Sprintf("Edited after being applied: %d\n", len(state.drifted)))
+		for _, name := range state.drifted {
+			status.WriteString(fmt.Sprintf("  edited   %s\n", name))
 		}
 	}
-	status.WriteString(fmt.Sprintf("Pending migrations: %d\n", pending))
-
 	return status.String(), nil
 }
 
-// ensureMigrationsTable creates the migrations table if it doesn't exist
-func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
-	_, err := m.db.NewCreateTable().Model((*Migration)(nil)).IfNotExists().Exec(ctx)
-	return err
-}
-
-// getAppliedMigrations returns list of applied migration versions
-func (m *Migrator) getAppliedMigrations(ctx context.Context) ([]string, error) {
-	var migrations []Migration
-	err := m.db.NewSelect().Model(&migrations).Order("version ASC").Scan(ctx)
+// loadState reads the source migrations and the applied ones. With write it
+// creates or upgrades the migrations table and records the checksums of
+// migrations applied before checksums were kept.
+func (m *Migrator) loadState(ctx context.Context, write bool) (*migrationState, error) {
+	migrations, err := loadMigrations(m.fsys, m.goMigrations)
 	if err != nil {
 		return nil, err
 	}
 
-	versions := make([]string, len(migrations))
-	for i, migration := range migrations {
-		versions[i] = migration.Version
+	if write {
+		if err := m.ensureMigrationsTable(ctx); err != nil {
+			return nil, fmt.Errorf("failed to create migrations table: %w", err)
+		}
 	}
-	return versions, nil
-}
-
-// getMigrationFiles returns sorted list of migration files
-func (m *Migrator) getMigrationFiles() ([]string, error) {
-	files, err := filepath.Glob("migrations/*.sql")
+	applied, err := m.getAppliedMigrations(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
 
-	// Filter out the init schema and skipped files, only include numbered migrations
-	var migrations []string
-	for _, file := range files {
-		base := filepath.Base(file)
-		// Include all numbered migrations except 000_ (init schema) and .skip files
-		if !strings.HasPrefix(base, "000_") && !strings.HasSuffix(base, ".skip") && strings.HasSuffix(base, ".sql") {
-			migrations = append(migrations, base)
-		}
+	state := &migrationState{
+		migrations: migrations,
+		applied:    make(map[string]*Migration, len(applied)),
 	}
+	for _, record := range applied {
+		state.applied[record.Version] = record
 
-	sort.Strings(migrations)
-	return migrations, nil
-}
-
-// extractVersion extracts version from migration filename
-func (m *Migrator) extractVersion(filename string) string {
-	parts := strings.Split(filename, "_")
-	if len(parts) > 0 {
-		return parts[0]
+		mig := state.find(record.Version)
+		if mig == nil || mig.Go != nil {
+			continue
+		}
+		if record.Checksum == "" {
+			if !write {
+				continue
+			}
+			record.Checksum = mig.Checksum
+			_, err := m.db.NewUpdate().Model(record).Column("checksum").WherePK().Exec(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to record checksum of %s: %w", mig.Name, err)
+			}
+			continue
+		}
+		if record.Checksum != mig.Checksum {
+			state.drifted = append(state.drifted, mig.Name)
+		}
 	}
-	return filename
+	return state, nil
 }
 
-// applyMigration applies a single migration
-func (m *Migrator) applyMigration(ctx context.Context, filename string) error {
-	content, err := os.ReadFile(filepath.Join("migrations", filename))
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+// find returns the migration run for the version, ignoring skipped files
+func (s *migrationState) find(version string) *migration {
+	for _, mig := range s.migrations {
+		if mig.Version == version && !mig.Skipped {
+			return mig
+		}
 	}
+	return nil
+}
 
-	// Extract UP migration (before "-- +goose Down")
-	sqlContent := string(content)
-	upSQL := m.extractUpSQL(sqlContent)
-
-	// Execute migration
-	_, err = m.db.ExecContext(ctx, upSQL)
-	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+func (s *migrationState) hasVersion(version int) bool {
+	for _, mig := range s.migrations {
+		if versionNumber(mig.Version) == version && !mig.Skipped {
+			return true
+		}
 	}
+	return false
+}
 
-	// Record migration
-	migration := &Migration{
-		Version: m.extractVersion(filename),
-		Name:    filename,
+func (s *migrationState) checkDrift() error {
+	if len(s.drifted) == 0 {
+		return nil
 	}
-	_, err = m.db.NewInsert().Model(migration).Exec(ctx)
-	return err
+	return fmt.Errorf("migrations edited after they were applied: %s; restore them and add a new migration instead",
+		strings.Join(s.drifted, ", "))
 }
 
-// rollbackMigration rolls back a single migration
-func (m *Migrator) rollbackMigration(ctx context.Context, version string) error {
-	// Find migration file
-	filename := ""
-	files, err := m.getMigrationFiles()
+// pending returns the migrations Up runs, in order
+func (s *migrationState) pending(opts RunOptions) ([]*migration, error) {
+	to, err := parseTarget(opts.To)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opts.To != "" && !s.hasVersion(to) {
+		return nil, fmt.Errorf("no migration has version %s", opts.To)
 	}
 
-	for _, file := range files {
-		if m.extractVersion(file) == version {
-			filename = file
+	var pending []*migration
+	for _, mig := range s.migrations {
+		if mig.Skipped || s.applied[mig.Version] != nil {
+			continue
+		}
+		if opts.To != "" && versionNumber(mig.Version) > to {
 			break
 		}
+		pending = append(pending, mig)
 	}
-
-	if filename == "" {
-		return fmt.Errorf("migration file not found for version %s", version)
+	if opts.Steps > 0 && len(pending) > opts.Steps {
+		pending = pending[:opts.Steps]
 	}
+	return pending, nil
+}
 
-	content, err := os.ReadFile(filepath.Join("migrations", filename))
+// rollback returns the migrations Down rolls back, newest first
+func (s *migrationState) rollback(opts RunOptions) ([]*migration, error) {
+	to, err := parseTarget(opts.To)
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return nil, err
 	}
 
-	// Extract DOWN migration
-	sqlContent := string(content)
-	downSQL := m.extractDownSQL(sqlContent)
-
-	// Execute rollback
-	_, err = m.db.ExecContext(ctx, downSQL)
-	if err != nil {
-		return fmt.Errorf("failed to execute rollback: %w", err)
+	versions := make([]string, 0, len(s.applied))
+	for version := range s.applied {
+		versions = append(versions, version)
 	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versionNumber(versions[i]) > versionNumber(versions[j])
+	})
 
-	// Remove migration record
-	_, err = m.db.NewDelete().Model((*Migration)(nil)).Where("version = ?", version).Exec(ctx)
-	return err
-}
-
-// extractUpSQL extracts UP SQL from migration content
-func (m *Migrator) extractUpSQL(content string) string {
-	lines := strings.Split(content, "\n")
-	var upLines []string
-	inUp := false
+	steps := opts.Steps
+	if steps <= 0 && opts.To == "" {
+		steps = 1
+	}
 
-	for _, line := range lines {
-		if strings.Contains(line, "-- +goose Up") || strings.Contains(line, "-- +goose StatementBegin") {
-			inUp = true
-			continue
+	var rollback []*migration
+	for _, version := range versions {
+		if opts.To != "" && versionNumber(version) <= to {
+			break
 		}
-		if strings.Contains(line, "-- +goose Down") || strings.Contains(line, "-- +goose StatementEnd") {
+		if steps > 0 && len(rollback) == steps {
 			break
 		}
-		if inUp {
-			upLines = append(upLines, line)
+		mig := s.find(version)
+		if mig == nil {
+			return nil, fmt.Errorf("migration file not found for version %s", version)
 		}
+		if mig.Go != nil && mig.Go.Down == nil {
+			return nil, fmt.Errorf("go migration %s can't be rolled back", mig.Name)
+		}
+		rollback = append(rollback, mig)
 	}
+	return rollback, nil
+}
 
-	return strings.Join(upLines, "\n")
+func (m *Migrator) applyAll(ctx context.Context, pending []*migration) error {
+	if len(pending) == 0 {
+		fmt.Fprintln(m.out, "No pending migrations")
+		return nil
+	}
+
+	for _, mig := range pending {
+		if err := m.applyMigration(ctx, mig); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", mig.Name, err)
+		}
+		fmt.Fprintf(m.out, "Applied migration: %s\n", mig.Name)
+	}
+	return nil
 }
 
-// extractDownSQL extracts DOWN SQL from migration content
-func (m *Migrator) extractDownSQL(content string) string {
-	lines := strings.Split(content, "\n")
-	var downLines []string
-	inDown := false
+// applyMigration runs a migration and records it, in one transaction unless the
+// migration can't run in one
+func (m *Migrator) applyMigration(ctx context.Context, mig *migration) error {
+	return m.inTx(ctx, mig, func(ctx context.Context, db bun.IDB) error {
+		if mig.Go != nil {
+			if err := mig.Go.Up(ctx, db); err != nil {
+				return err
+			}
+		} else if mig.UpSQL != "" {
+			if _, err := db.ExecContext(ctx, mig.UpSQL); err != nil {
+				return fmt.Errorf("failed to execute migration: %w", err)
+			}
+		}
 
-	for _, line := range lines {
-		if strings.Contains(line, "-- +goose Down") {
-			inDown = true
-			continue
+		record := &Migration{
+			Version:  mig.Version,
+			Name:     mig.Name,
+			Checksum: mig.Checksum,
 		}
-		if strings.Contains(line, "-- +goose StatementBegin") && inDown {
-			continue
+		_, err := db.NewInsert().Model(record).Exec(ctx)
+		return err
+	})
+}
+
+// rollbackMigration rolls back a migration and forgets it
+func (m *Migrator) rollbackMigration(ctx context.Context, mig *migration) error {
+	return m.inTx(ctx, mig, func(ctx context.Context, db bun.IDB) error {
+		if mig.Go != nil {
+			if err := mig.Go.Down(ctx, db); err != nil {
+				return err
+			}
+		} else if mig.DownSQL != "" {
+			if _, err := db.ExecContext(ctx, mig.DownSQL); err != nil {
+				return fmt.Errorf("failed to execute rollback: %w", err)
+			}
 		}
-		if strings.Contains(line, "-- +goose StatementEnd") && inDown {
-			break
+
+		_, err := db.NewDelete().Model((*Migration)(nil)).Where("version = ?", mig.Version).Exec(ctx)
+		return err
+	})
+}
+
+func (m *Migrator) inTx(ctx context.Context, mig *migration, fn func(ctx context.Context, db bun.IDB) error) error {
+	if mig.NoTx {
+		return fn(ctx, m.db)
+	}
+	return m.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return fn(ctx, tx)
+	})
+}
+
+// printPlan prints what running the migrations would execute
+func (m *Migrator) printPlan(direction string, plan []*migration) {
+	if len(plan) == 0 {
+		fmt.Fprintf(m.out, "-- Nothing to migrate %s\n", direction)
+		return
+	}
+
+	for _, mig := range plan {
+		fmt.Fprintf(m.out, "-- %s: %s\n", direction, mig.Name)
+		if mig.NoTx {
+			fmt.Fprintln(m.out, "-- runs outside a transaction")
 		}
-		if inDown {
-			downLines = append(downLines, line)
+		switch {
+		case mig.Go != nil:
+			fmt.Fprintf(m.out, "-- Go migration %s %s\n\n", mig.Version, mig.Name)
+		case direction == "up":
+			fmt.Fprintf(m.out, "%s\n\n", mig.UpSQL)
+		default:
+			fmt.Fprintf(m.out, "%s\n\n", mig.DownSQL)
 		}
 	}
+}
+
+// ensureMigrationsTable creates the migrations table if it doesn't exist, and adds
+// the checksum column to tables created before checksums were kept
+func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
+	_, err := m.db.NewCreateTable().Model((*Migration)(nil)).IfNotExists().Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if m.hasChecksums(ctx) {
+		return nil
+	}
+	_, err = m.db.ExecContext(ctx, "ALTER TABLE schema_migrations ADD COLUMN checksum VARCHAR(64)")
+	return err
+}
 
-	return strings.Join(downLines, "\n")
+// getAppliedMigrations returns the applied migrations, or none before the
+// migrations table exists
+func (m *Migrator) getAppliedMigrations(ctx context.Context) ([]*Migration, error) {
+	if _, err := m.db.ExecContext(ctx, "SELECT version FROM schema_migrations LIMIT 0"); err != nil {
+		return nil, nil
+	}
+
+	var migrations []*Migration
+	query := m.db.NewSelect().Model(&migrations).Order("version ASC")
+	if !m.hasChecksums(ctx) {
+		query = query.Column("id", "version", "name", "applied_at")
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func (m *Migrator) hasChecksums(ctx context.Context) bool {
+	_, err := m.db.ExecContext(ctx, "SELECT checksum FROM schema_migrations LIMIT 0")
+	return err == nil
 }
 
 // dropAllTables drops all user tables
@@ -344,12 +488,19 @@ func (m *Migrator) dropAllTables(ctx context.Context) error {
 	return nil
 }
 
-// contains checks if slice contains string
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
+func parseTarget(to string) (int, error) {
+	if to == "" {
+		return 0, nil
 	}
-	return false
+	version, err := strconv.Atoi(to)
+	if err != nil {
+		return 0, fmt.Errorf("target version %q is not a number", to)
+	}
+	return version, nil
+}
+
+// versionNumber orders versions numerically, so 100 comes after 099
+func versionNumber(version string) int {
+	n, _ := strconv.Atoi(version)
+	return n
 }
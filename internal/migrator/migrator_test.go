@@ -0,0 +1,372 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+func setupMigratorTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	sqldb, err := sql.Open(sqliteshim.DriverName(), fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func goose(up, down string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(fmt.Sprintf(`-- +goose Up
+-- +goose StatementBegin
+%s
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+%s
+-- +goose StatementEnd
+`, up, down))}
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"001_create_stores.sql":     goose("CREATE TABLE stores (id INTEGER PRIMARY KEY, code TEXT);", "DROP TABLE stores;"),
+		"002_create_flyers.sql":     goose("CREATE TABLE flyers (id INTEGER PRIMARY KEY, store_id INTEGER);", "DROP TABLE flyers;"),
+		"002_old_flyers.sql.skip":   goose("CREATE TABLE old_flyers (id INTEGER);", "DROP TABLE old_flyers;"),
+		"003_add_flyer_title.sql":   goose("ALTER TABLE flyers ADD COLUMN title TEXT;", "ALTER TABLE flyers DROP COLUMN title;"),
+		"README.md":                 &fstest.MapFile{Data: []byte("not a migration")},
+		"010_create_products.sql":   goose("CREATE TABLE products (id INTEGER PRIMARY KEY);", "DROP TABLE products;"),
+		"004_seed_stores_later.sql": goose("INSERT INTO stores (code) VALUES ('iki');", "DELETE FROM stores;"),
+	}
+}
+
+func newTestMigrator(t *testing.T, db *bun.DB, fsys fstest.MapFS, goMigrations ...GoMigration) (*Migrator, *bytes.Buffer) {
+	t.Helper()
+	m := NewWithSource(db, fsys, goMigrations)
+	out := &bytes.Buffer{}
+	m.out = out
+	return m, out
+}
+
+func appliedVersions(t *testing.T, db *bun.DB) []string {
+	t.Helper()
+	var versions []string
+	if err := db.NewSelect().Model((*Migration)(nil)).Column("version").Order("version ASC").Scan(context.Background(), &versions); err != nil {
+		t.Fatalf("failed to read applied migrations: %v", err)
+	}
+	return versions
+}
+
+func tableExists(db *bun.DB, table string) bool {
+	_, err := db.ExecContext(context.Background(), "SELECT 1 FROM "+table+" LIMIT 0")
+	return err == nil
+}
+
+func TestMigrator_UpAppliesPendingInOrderAndSkipsSkipFiles(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratorTestDB(t)
+	m, _ := newTestMigrator(t, db, testMigrations())
+
+	if err := m.Up(ctx, RunOptions{}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if got := strings.Join(appliedVersions(t, db), ","); got != "001,002,003,004,010" {
+		t.Fatalf("applied = %s, want 001,002,003,004,010", got)
+	}
+	if tableExists(db, "old_flyers") {
+		t.Error("the .sql.skip migration ran")
+	}
+
+	var record Migration
+	if err := db.NewSelect().Model(&record).Where("version = ?", "003").Scan(ctx); err != nil {
+		t.Fatalf("failed to read migration 003: %v", err)
+	}
+	if len(record.Checksum) != 64 || record.Name != "003_add_flyer_title.sql" {
+		t.Errorf("migration 003 recorded as %+v, want its name and a sha256 checksum", record)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, want := range []string{"Applied migrations: 5", "Pending migrations: 0", "skipped  002_old_flyers.sql.skip"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("Status() = %q, want it to contain %q", status, want)
+		}
+	}
+}
+
+func TestMigrator_To(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratorTestDB(t)
+	m, _ := newTestMigrator(t, db, testMigrations())
+
+	if err := m.Up(ctx, RunOptions{To: "003"}); err != nil {
+		t.Fatalf("Up(to 003) error = %v", err)
+	}
+	if got := strings.Join(appliedVersions(t, db), ","); got != "001,002,003" {
+		t.Fatalf("applied after up to 003 = %s", got)
+	}
+
+	if err := m.Up(ctx, RunOptions{}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := m.Down(ctx, RunOptions{To: "002"}); err != nil {
+		t.Fatalf("Down(to 002) error = %v", err)
+	}
+	if got := strings.Join(appliedVersions(t, db), ","); got != "001,002" {
+		t.Fatalf("applied after down to 002 = %s", got)
+	}
+	if tableExists(db, "products") {
+		t.Error("products still exists after rolling back 010")
+	}
+
+	if err := m.Down(ctx, RunOptions{}); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if got := strings.Join(appliedVersions(t, db), ","); got != "001" {
+		t.Fatalf("applied after one step down = %s", got)
+	}
+
+	if err := m.Up(ctx, RunOptions{To: "005"}); err == nil {
+		t.Error("Up(to an unknown version) succeeded")
+	}
+	if err := m.Up(ctx, RunOptions{To: "latest"}); err == nil {
+		t.Error("Up(to a non-numeric version) succeeded")
+	}
+}
+
+func TestMigrator_DryRunPrintsThePlanWithoutRunningIt(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratorTestDB(t)
+	m, out := newTestMigrator(t, db, testMigrations())
+
+	if err := m.Up(ctx, RunOptions{To: "002", DryRun: true}); err != nil {
+		t.Fatalf("Up(dry run) error = %v", err)
+	}
+	plan := out.String()
+	for _, want := range []string{"-- up: 001_create_stores.sql", "CREATE TABLE stores", "-- up: 002_create_flyers.sql"} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("plan = %q, want it to contain %q", plan, want)
+		}
+	}
+	if strings.Contains(plan, "003_add_flyer_title") || strings.Contains(plan, "+goose") {
+		t.Errorf("plan = %q, want only migrations up to 002 without goose annotations", plan)
+	}
+	if tableExists(db, "schema_migrations") || tableExists(db, "stores") {
+		t.Error("dry run changed the database")
+	}
+
+	if err := m.Up(ctx, RunOptions{}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	out.Reset()
+	if err := m.Down(ctx, RunOptions{Steps: 2, DryRun: true}); err != nil {
+		t.Fatalf("Down(dry run) error = %v", err)
+	}
+	if plan := out.String(); !strings.Contains(plan, "-- down: 010_create_products.sql\nDROP TABLE products;") || !strings.Contains(plan, "-- down: 004_seed_stores_later.sql") {
+		t.Errorf("down plan = %q", plan)
+	}
+	if got := len(appliedVersions(t, db)); got != 5 {
+		t.Errorf("dry run rolled back migrations, %d applied", got)
+	}
+}
+
+func TestMigrator_RefusesEditedMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratorTestDB(t)
+	fsys := testMigrations()
+	m, _ := newTestMigrator(t, db, fsys)
+
+	if err := m.Up(ctx, RunOptions{To: "002"}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	fsys["002_create_flyers.sql"] = goose("CREATE TABLE flyers (id INTEGER PRIMARY KEY, title TEXT);", "DROP TABLE flyers;")
+	err := m.Up(ctx, RunOptions{})
+	if err == nil || !strings.Contains(err.Error(), "002_create_flyers.sql") {
+		t.Fatalf("Up() after editing an applied migration error = %v, want drift of 002", err)
+	}
+	if err := m.Down(ctx, RunOptions{}); err == nil {
+		t.Error("Down() after editing an applied migration succeeded")
+	}
+	if got := strings.Join(appliedVersions(t, db), ","); got != "001,002" {
+		t.Errorf("applied after refusing = %s, want 001,002", got)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil || !strings.Contains(status, "edited   002_create_flyers.sql") {
+		t.Errorf("Status() = %q, %v, want 002 listed as edited", status, err)
+	}
+}
+
+func TestMigrator_RecordsChecksumsOfMigrationsAppliedBeforeThem(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratorTestDB(t)
+
+	// The table as created before checksums were kept
+	_, err := db.ExecContext(ctx, `
+CREATE TABLE schema_migrations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE stores (id INTEGER PRIMARY KEY, code TEXT);
+INSERT INTO schema_migrations (version, name) VALUES ('001', '001_create_stores.sql');`)
+	if err != nil {
+		t.Fatalf("failed to create the legacy schema: %v", err)
+	}
+
+	m, _ := newTestMigrator(t, db, testMigrations())
+	if status, err := m.Status(ctx); err != nil || !strings.Contains(status, "Pending migrations: 4") {
+		t.Fatalf("Status() on the legacy table = %q, %v", status, err)
+	}
+	if err := m.Up(ctx, RunOptions{}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	var record Migration
+	if err := db.NewSelect().Model(&record).Where("version = ?", "001").Scan(ctx); err != nil {
+		t.Fatalf("failed to read migration 001: %v", err)
+	}
+	if len(record.Checksum) != 64 {
+		t.Errorf("checksum of the legacy migration = %q, want it recorded", record.Checksum)
+	}
+}
+
+func TestMigrator_GoMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := setupMigratorTestDB(t)
+
+	backfill := GoMigration{
+		Version: "005",
+		Name:    "backfill_store_codes",
+		Up: func(ctx context.Context, db bun.IDB) error {
+			_, err := db.NewUpdate().Table("stores").Set("code = UPPER(code)").Where("1 = 1").Exec(ctx)
+			return err
+		},
+		Down: func(ctx context.Context, db bun.IDB) error {
+			_, err := db.NewUpdate().Table("stores").Set("code = LOWER(code)").Where("1 = 1").Exec(ctx)
+			return err
+		},
+	}
+	m, _ := newTestMigrator(t, db, testMigrations(), backfill)
+
+	if err := m.Up(ctx, RunOptions{To: "005"}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	var code string
+	if err := db.NewSelect().Table("stores").Column("code").Scan(ctx, &code); err != nil || code != "IKI" {
+		t.Fatalf("store code after the backfill = %q, %v, want IKI", code, err)
+	}
+
+	if err := m.Down(ctx, RunOptions{}); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if err := db.NewSelect().Table("stores").Column("code").Scan(ctx, &code); err != nil || code != "iki" {
+		t.Fatalf("store code after rolling back the backfill = %q, %v, want iki", code, err)
+	}
+
+	// A failing Go migration leaves nothing behind
+	failing := GoMigration{Version: "011", Name: "broken_backfill", Up: func(ctx context.Context, db bun.IDB) error {
+		if _, err := db.ExecContext(ctx, "DELETE FROM stores"); err != nil {
+			return err
+		}
+		return fmt.Errorf("backfill failed")
+	}}
+	m, _ = newTestMigrator(t, db, testMigrations(), backfill, failing)
+	if err := m.Up(ctx, RunOptions{}); err == nil {
+		t.Fatal("Up() with a failing Go migration succeeded")
+	}
+	var stores int
+	if err := db.NewSelect().Table("stores").ColumnExpr("COUNT(*)").Scan(ctx, &stores); err != nil || stores != 1 {
+		t.Errorf("stores after the failed backfill = %d, %v, want the rollback to keep 1", stores, err)
+	}
+	if got := strings.Join(appliedVersions(t, db), ","); got != "001,002,003,004,005,010" {
+		t.Errorf("applied = %s, want everything but the failed 011", got)
+	}
+}
+
+func TestLoadMigrations_RejectsAmbiguousSources(t *testing.T) {
+	noop := func(ctx context.Context, db bun.IDB) error { return nil }
+
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		goMigrations []GoMigration
+	}{
+		{
+			name: "two files with one version",
+			fsys: fstest.MapFS{"001_a.sql": goose("", ""), "001_b.sql": goose("", "")},
+		},
+		{
+			name:         "go migration reusing a file's version",
+			fsys:         fstest.MapFS{"001_a.sql": goose("", "")},
+			goMigrations: []GoMigration{{Version: "001", Name: "backfill", Up: noop}},
+		},
+		{
+			name: "unnumbered file",
+			fsys: fstest.MapFS{"create_stores.sql": goose("", "")},
+		},
+		{
+			name:         "go migration without a version",
+			goMigrations: []GoMigration{{Name: "backfill", Up: noop}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fsys == nil {
+				tt.fsys = fstest.MapFS{}
+			}
+			if _, err := loadMigrations(tt.fsys, tt.goMigrations); err == nil {
+				t.Error("loadMigrations() succeeded, want an error")
+			}
+		})
+	}
+
+	// A skipped file may share its version with the migration that replaced it
+	migrations, err := loadMigrations(fstest.MapFS{"023_a.sql": goose("", ""), "023_b.sql.skip": goose("", "")}, nil)
+	if err != nil || len(migrations) != 2 {
+		t.Errorf("loadMigrations() with a skipped duplicate = %d migrations, %v", len(migrations), err)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(New(nil).fsys, nil)
+	if err != nil {
+		t.Fatalf("loadMigrations(embedded) error = %v", err)
+	}
+
+	skipped := 0
+	for _, mig := range migrations {
+		if mig.Skipped {
+			skipped++
+			continue
+		}
+		if mig.UpSQL == "" {
+			t.Errorf("migration %s has no up SQL", mig.Name)
+		}
+	}
+	files, err := filepath.Glob("../../migrations/*.sql*")
+	if err != nil {
+		t.Fatalf("failed to list the migrations directory: %v", err)
+	}
+	if len(migrations) != len(files) || skipped == 0 {
+		t.Errorf("embedded %d migrations with %d skipped, want all %d files of the directory", len(migrations), skipped, len(files))
+	}
+}
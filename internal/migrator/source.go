@@ -0,0 +1,145 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// migrationFilePattern matches numbered migrations: 001_create_stores.sql, or
+// 008_fts_config.sql.skip for one that is deliberately never run
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.sql(\.skip)?$`)
+
+// noTransactionAnnotation marks SQL migrations that can't run in a transaction,
+// such as ones creating indexes concurrently
+const noTransactionAnnotation = "-- +goose NO TRANSACTION"
+
+// GoMigration is a migration written in Go, for data backfills SQL can't express.
+// It runs in a transaction with its record, ordered by version among the SQL ones.
+type GoMigration struct {
+	Version string
+	Name    string
+	Up      func(ctx context.Context, db bun.IDB) error
+	// Down may be nil when the backfill needs no rollback
+	Down func(ctx context.Context, db bun.IDB) error
+}
+
+var registered []GoMigration
+
+// Register adds a Go migration to the ones New runs. Call it from an init function.
+func Register(migration GoMigration) {
+	registered = append(registered, migration)
+}
+
+// migration is one numbered migration of the source, SQL or Go
+type migration struct {
+	Version  string
+	Name     string // file name, or the Go migration's name
+	Checksum string // of the SQL file; Go migrations have none
+	Skipped  bool   // a .sql.skip file
+	UpSQL    string
+	DownSQL  string
+	NoTx     bool
+	Go       *GoMigration
+}
+
+func (mig *migration) kind() string {
+	if mig.Go != nil {
+		return "go"
+	}
+	return "sql"
+}
+
+// loadMigrations reads the migrations of fsys and the Go migrations, ordered by
+// version. Skipped files are returned too; two migrations run with the same
+// version are an error.
+func loadMigrations(fsys fs.FS, goMigrations []GoMigration) ([]*migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var migrations []*migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".sql.skip")) {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.sql", name)
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		sum := sha256.Sum256(content)
+		upSQL, downSQL := splitSQL(string(content))
+		migrations = append(migrations, &migration{
+			Version:  match[1],
+			Name:     name,
+			Checksum: hex.EncodeToString(sum[:]),
+			Skipped:  match[3] != "",
+			UpSQL:    upSQL,
+			DownSQL:  downSQL,
+			NoTx:     strings.Contains(string(content), noTransactionAnnotation),
+		})
+	}
+
+	for i := range goMigrations {
+		gm := &goMigrations[i]
+		if _, err := strconv.Atoi(gm.Version); err != nil || gm.Name == "" || gm.Up == nil {
+			return nil, fmt.Errorf("go migration %q (%s) needs a numeric version, a name and an Up function", gm.Version, gm.Name)
+		}
+		migrations = append(migrations, &migration{Version: gm.Version, Name: gm.Name, Go: gm})
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		vi, _ := strconv.Atoi(migrations[i].Version)
+		vj, _ := strconv.Atoi(migrations[j].Version)
+		if vi != vj {
+			return vi < vj
+		}
+		return migrations[i].Name < migrations[j].Name
+	})
+
+	byVersion := make(map[string]string)
+	for _, mig := range migrations {
+		if mig.Skipped {
+			continue
+		}
+		if other, ok := byVersion[mig.Version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %s", other, mig.Name, mig.Version)
+		}
+		byVersion[mig.Version] = mig.Name
+	}
+	return migrations, nil
+}
+
+// splitSQL returns the statements of a goose-style file before and after its
+// "-- +goose Down" line, without the goose annotations
+func splitSQL(content string) (up, down string) {
+	var upLines, downLines []string
+	section := &upLines
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "-- +goose Down"):
+			section = &downLines
+			continue
+		case strings.HasPrefix(trimmed, "-- +goose"):
+			continue
+		}
+		*section = append(*section, line)
+	}
+	return strings.TrimSpace(strings.Join(upLines, "\n")), strings.TrimSpace(strings.Join(downLines, "\n"))
+}
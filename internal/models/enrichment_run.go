@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// EnrichmentRun records a single invocation of the flyer enrichment pipeline
+type EnrichmentRun struct {
+	bun.BaseModel `bun:"table:enrichment_runs,alias:er"`
+
+	ID      int64                `bun:"id,pk,autoincrement" json:"id"`
+	Status  string               `bun:"status,notnull,default:'running'" json:"status"`
+	Options EnrichmentRunOptions `bun:"options,type:jsonb,notnull" json:"options"`
+
+	// Aggregated progress
+	PagesTotal        int `bun:"pages_total,notnull,default:0" json:"pages_total"`
+	PagesCompleted    int `bun:"pages_completed,notnull,default:0" json:"pages_completed"`
+	PagesFailed       int `bun:"pages_failed,notnull,default:0" json:"pages_failed"`
+	ProductsExtracted int `bun:"products_extracted,notnull,default:0" json:"products_extracted"`
+	TokensUsed        int `bun:"tokens_used,notnull,default:0" json:"tokens_used"`
+
+	LastError *string `bun:"last_error" json:"last_error,omitempty"`
+
+	StartedAt   time.Time  `bun:"started_at,nullzero,notnull,default:current_timestamp" json:"started_at"`
+	CompletedAt *time.Time `bun:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt   time.Time  `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updated_at"`
+
+	// Relations
+	Pages []*EnrichmentRunPage `bun:"rel:has-many,join:id=run_id" json:"pages,omitempty"`
+}
+
+// EnrichmentRunOptions captures the options a run was started with so it can be resumed
+type EnrichmentRunOptions struct {
+	StoreCode      string `json:"store_code,omitempty"`
+	Date           string `json:"date"` // YYYY-MM-DD
+	ForceReprocess bool   `json:"force_reprocess,omitempty"`
+	MaxPages       int    `json:"max_pages,omitempty"`
+	BatchSize      int    `json:"batch_size,omitempty"`
+}
+
+// EnrichmentRunPage records the processing outcome of a single flyer page within a run
+type EnrichmentRunPage struct {
+	bun.BaseModel `bun:"table:enrichment_run_pages,alias:erp"`
+
+	ID                int64      `bun:"id,pk,autoincrement" json:"id"`
+	RunID             int64      `bun:"run_id,notnull" json:"run_id"`
+	FlyerID           int        `bun:"flyer_id,notnull" json:"flyer_id"`
+	FlyerPageID       int        `bun:"flyer_page_id,notnull" json:"flyer_page_id"`
+	Status            string     `bun:"status,notnull,default:'pending'" json:"status"`
+	Attempts          int        `bun:"attempts,notnull,default:0" json:"attempts"`
+	ProductsExtracted int        `bun:"products_extracted,notnull,default:0" json:"products_extracted"`
+	TokensUsed        int        `bun:"tokens_used,notnull,default:0" json:"tokens_used"`
+	ErrorMessage      *string    `bun:"error_message" json:"error_message,omitempty"`
+	StartedAt         *time.Time `bun:"started_at" json:"started_at,omitempty"`
+	CompletedAt       *time.Time `bun:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt         time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt         time.Time  `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updated_at"`
+}
+
+// EnrichmentRunStatus represents the lifecycle state of an enrichment run
+type EnrichmentRunStatus string
+
+const (
+	EnrichmentRunStatusRunning     EnrichmentRunStatus = "running"
+	EnrichmentRunStatusCompleted   EnrichmentRunStatus = "completed"
+	EnrichmentRunStatusFailed      EnrichmentRunStatus = "failed"
+	EnrichmentRunStatusInterrupted EnrichmentRunStatus = "interrupted"
+)
+
+// EnrichmentRunPageStatus represents the processing state of a page within a run
+type EnrichmentRunPageStatus string
+
+const (
+	EnrichmentRunPageStatusPending    EnrichmentRunPageStatus = "pending"
+	EnrichmentRunPageStatusProcessing EnrichmentRunPageStatus = "processing"
+	EnrichmentRunPageStatusCompleted  EnrichmentRunPageStatus = "completed"
+	EnrichmentRunPageStatusFailed     EnrichmentRunPageStatus = "failed"
+)
+
+// CanResume checks if the run can be resumed
+func (r *EnrichmentRun) CanResume() bool {
+	return r.Status != string(EnrichmentRunStatusCompleted)
+}
+
+// IsCompleted checks if the page finished successfully within the run
+func (p *EnrichmentRunPage) IsCompleted() bool {
+	return p.Status == string(EnrichmentRunPageStatusCompleted)
+}
+
+// IsFailed checks if the page failed within the run
+func (p *EnrichmentRunPage) IsFailed() bool {
+	return p.Status == string(EnrichmentRunPageStatusFailed)
+}
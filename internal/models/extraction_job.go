@@ -34,6 +34,13 @@ type ExtractionJob struct {
 	ScheduledFor time.Time  `bun:"scheduled_for,default:current_timestamp" json:"scheduled_for"`
 	ExpiresAt    *time.Time `bun:"expires_at" json:"expires_at,omitempty"`
 
+	// Job queue: rows of the Postgres job queue backend carry the queue name,
+	// the job's ID as job_key, its retry delay and its lease
+	QueueName      *string    `bun:"queue_name" json:"queue_name,omitempty"`
+	JobKey         *string    `bun:"job_key" json:"job_key,omitempty"`
+	RetryDelayMs   int64      `bun:"retry_delay_ms,default:30000" json:"retry_delay_ms"`
+	LeaseExpiresAt *time.Time `bun:"lease_expires_at" json:"lease_expires_at,omitempty"`
+
 	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
 	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updated_at"`
 }
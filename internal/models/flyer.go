@@ -138,6 +138,39 @@ func (f *Flyer) FailProcessing() {
 	f.UpdatedAt = now
 }
 
+// RollupFlyerStatus derives a flyer's processing status from the extraction status of its pages.
+// The flyer stays pending until a page is picked up and is processing while any page is
+// outstanding. Once every page is done it is completed if at least one page succeeded
+// ("warning" counts as success) and failed otherwise.
+func RollupFlyerStatus(pages []*FlyerPage) FlyerStatus {
+	if len(pages) == 0 {
+		return FlyerStatusPending
+	}
+
+	var pending, processing, succeeded int
+	for _, page := range pages {
+		switch page.ExtractionStatus {
+		case string(FlyerPageStatusPending), "":
+			pending++
+		case string(FlyerPageStatusProcessing):
+			processing++
+		case string(FlyerPageStatusCompleted), "warning":
+			succeeded++
+		}
+	}
+
+	switch {
+	case pending == len(pages):
+		return FlyerStatusPending
+	case pending > 0 || processing > 0:
+		return FlyerStatusProcessing
+	case succeeded > 0:
+		return FlyerStatusCompleted
+	default:
+		return FlyerStatusFailed
+	}
+}
+
 // Archive marks the flyer as archived
 func (f *Flyer) Archive() {
 	now := time.Now()
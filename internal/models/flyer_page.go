@@ -43,6 +43,11 @@ const (
 	FlyerPageStatusFailed     FlyerPageStatus = "failed"
 )
 
+// FlyerPageProcessingLease is how long a page marked processing stays with the
+// job extracting it. A page still processing after that was left behind by a
+// worker that died and may be extracted again.
+const FlyerPageProcessingLease = 15 * time.Minute
+
 // IsProcessingComplete checks if extraction is complete
 func (fp *FlyerPage) IsProcessingComplete() bool {
 	return fp.ExtractionStatus == string(FlyerPageStatusCompleted)
@@ -55,6 +60,12 @@ func (fp *FlyerPage) CanBeProcessed() bool {
 		fp.ExtractionAttempts < 3 // Max 3 retry attempts
 }
 
+// IsProcessingLeased checks if another job is still extracting the page
+func (fp *FlyerPage) IsProcessingLeased(now time.Time) bool {
+	return fp.ExtractionStatus == string(FlyerPageStatusProcessing) &&
+		now.Before(fp.UpdatedAt.Add(FlyerPageProcessingLease))
+}
+
 // HasImage checks if the page has a valid image URL
 func (fp *FlyerPage) HasImage() bool {
 	return fp.ImageURL != nil && *fp.ImageURL != ""
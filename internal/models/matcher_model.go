@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// MatcherModel holds product matcher weights and thresholds learned from
+// reviewed matches. An empty Category is the global model.
+type MatcherModel struct {
+	bun.BaseModel `bun:"table:matcher_models,alias:mm"`
+
+	ID                 int64              `bun:"id,pk,autoincrement" json:"id"`
+	Category           string             `bun:"category,notnull" json:"category"`
+	Weights            map[string]float64 `bun:"weights,type:jsonb,notnull" json:"weights"`
+	Bias               float64            `bun:"bias,notnull" json:"bias"`
+	AutoMatchThreshold float64            `bun:"auto_match_threshold,notnull" json:"auto_match_threshold"`
+	ReviewThreshold    float64            `bun:"review_threshold,notnull" json:"review_threshold"`
+	TrainingSamples    int                `bun:"training_samples,notnull" json:"training_samples"`
+	Precision          *float64           `bun:"precision" json:"precision"`
+	Recall             *float64           `bun:"recall" json:"recall"`
+	TrainedAt          time.Time          `bun:"trained_at,notnull,default:now()" json:"trained_at"`
+}
@@ -22,6 +22,10 @@ type PriceHistory struct {
 	Currency      string   `bun:"currency,default:'EUR'" json:"currency"`
 	IsOnSale      bool     `bun:"is_on_sale,default:false" json:"is_on_sale"`
 
+	// Loyalty price tiers; Price is the regular shelf price
+	CardPrice *float64 `bun:"card_price" json:"card_price,omitempty"`
+	AppPrice  *float64 `bun:"app_price" json:"app_price,omitempty"`
+
 	// Timing information
 	RecordedAt    time.Time  `bun:"recorded_at,notnull" json:"recorded_at"`
 	ValidFrom     time.Time  `bun:"valid_from,notnull" json:"valid_from"`
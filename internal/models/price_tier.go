@@ -0,0 +1,132 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// PriceTier identifies who a published price is available to
+type PriceTier string
+
+const (
+	PriceTierRegular PriceTier = "regular" // shelf price for everyone
+	PriceTierCard    PriceTier = "card"    // loyalty-card holders only
+	PriceTierApp     PriceTier = "app"     // loyalty app users only
+)
+
+// LoyaltyProgram identifies a store loyalty programme
+type LoyaltyProgram string
+
+const (
+	LoyaltyProgramAitvaras LoyaltyProgram = "aitvaras"  // Maxima "Aitvaras"
+	LoyaltyProgramManoRimi LoyaltyProgram = "mano_rimi" // "Mano Rimi"
+	LoyaltyProgramIki      LoyaltyProgram = "iki"       // IKI card
+)
+
+// storeLoyaltyPrograms maps store codes to the loyalty programme they honour
+var storeLoyaltyPrograms = map[string]LoyaltyProgram{
+	"maxima": LoyaltyProgramAitvaras,
+	"rimi":   LoyaltyProgramManoRimi,
+	"iki":    LoyaltyProgramIki,
+}
+
+// LoyaltyProgramForStore returns the loyalty programme of the store with the given code
+func LoyaltyProgramForStore(storeCode string) (LoyaltyProgram, bool) {
+	program, ok := storeLoyaltyPrograms[strings.ToLower(storeCode)]
+	return program, ok
+}
+
+// IsValid checks if the loyalty programme is known
+func (lp LoyaltyProgram) IsValid() bool {
+	switch lp {
+	case LoyaltyProgramAitvaras, LoyaltyProgramManoRimi, LoyaltyProgramIki:
+		return true
+	default:
+		return false
+	}
+}
+
+// LoyaltyPrograms is the set of loyalty programmes a user belongs to
+type LoyaltyPrograms []string
+
+// Has checks if the set contains the programme
+func (lp LoyaltyPrograms) Has(program LoyaltyProgram) bool {
+	return slices.Contains(lp, string(program))
+}
+
+// CoversStore checks if one of the programmes gives member prices at the store
+func (lp LoyaltyPrograms) CoversStore(storeCode string) bool {
+	program, ok := LoyaltyProgramForStore(storeCode)
+	return ok && lp.Has(program)
+}
+
+// StoreCodes returns the codes of the stores where the programmes give member prices
+func (lp LoyaltyPrograms) StoreCodes() []string {
+	codes := make([]string, 0, len(lp))
+	for code, program := range storeLoyaltyPrograms {
+		if lp.Has(program) {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// TierPrice returns the price published for the tier, falling back to the
+// regular price when the store publishes no separate price for it
+func (p *Product) TierPrice(tier PriceTier) float64 {
+	switch tier {
+	case PriceTierCard:
+		if p.CardPrice != nil && *p.CardPrice > 0 {
+			return *p.CardPrice
+		}
+	case PriceTierApp:
+		if p.AppPrice != nil && *p.AppPrice > 0 {
+			return *p.AppPrice
+		}
+	}
+	return p.RegularPrice()
+}
+
+// RegularPrice returns the price a shopper without a loyalty card or app pays.
+// Flyers print the member price as the headline price, so the regular price is
+// the original price when CurrentPrice is only available to members.
+func (p *Product) RegularPrice() float64 {
+	if p.headlineIsMemberPrice() && p.OriginalPrice != nil && *p.OriginalPrice > 0 {
+		return *p.OriginalPrice
+	}
+	return p.CurrentPrice
+}
+
+// BestPrice returns the lowest per-item price before multi-item promotions,
+// across the tiers the shopper is eligible for
+func (p *Product) BestPrice(loyaltyMember bool) float64 {
+	if !loyaltyMember {
+		return p.RegularPrice()
+	}
+	best := p.CurrentPrice
+	for _, tierPrice := range []*float64{p.CardPrice, p.AppPrice} {
+		if tierPrice != nil && *tierPrice > 0 && *tierPrice < best {
+			best = *tierPrice
+		}
+	}
+	return best
+}
+
+// headlineIsMemberPrice reports whether CurrentPrice requires a loyalty card or app.
+// Products stored before price tiers existed only carry a loyalty-only promotion.
+func (p *Product) headlineIsMemberPrice() bool {
+	if samePrice(p.CardPrice, p.CurrentPrice) || samePrice(p.AppPrice, p.CurrentPrice) {
+		return true
+	}
+	if p.CardPrice == nil && p.AppPrice == nil {
+		promo := p.GetPromotion()
+		return promo != nil && promo.LoyaltyOnly
+	}
+	return false
+}
+
+func samePrice(tierPrice *float64, price float64) bool {
+	return tierPrice != nil && math.Abs(*tierPrice-price) < 0.005
+}
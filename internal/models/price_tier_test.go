@@ -0,0 +1,68 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProduct_TierPrices(t *testing.T) {
+	original, card, app := 3.00, 2.20, 1.90
+	tests := []struct {
+		name        string
+		product     Product
+		wantRegular float64
+		wantMember  float64
+	}{
+		{"no tiers", Product{CurrentPrice: 2.50}, 2.50, 2.50},
+		{"card price headline", Product{CurrentPrice: 2.20, OriginalPrice: &original, CardPrice: &card}, 3.00, 2.20},
+		{"card price below shelf price", Product{CurrentPrice: 2.50, CardPrice: &card}, 2.50, 2.20},
+		{"card and app prices", Product{CurrentPrice: 2.50, CardPrice: &card, AppPrice: &app}, 2.50, 1.90},
+		{"legacy loyalty promotion", Product{CurrentPrice: 2.00, OriginalPrice: &original, SpecialDiscount: strPtr("Su kortele")}, 3.00, 2.00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.product.RegularPrice(); math.Abs(got-tt.wantRegular) > 0.0001 {
+				t.Fatalf("expected regular price %.2f, got %.2f", tt.wantRegular, got)
+			}
+			if got := tt.product.BestPrice(true); math.Abs(got-tt.wantMember) > 0.0001 {
+				t.Fatalf("expected member price %.2f, got %.2f", tt.wantMember, got)
+			}
+		})
+	}
+
+	product := Product{CurrentPrice: 2.50, CardPrice: &card}
+	if got := product.TierPrice(PriceTierApp); got != 2.50 {
+		t.Fatalf("expected app tier to fall back to the regular price, got %.2f", got)
+	}
+}
+
+func TestProduct_ApplyPromotion_PriceTiers(t *testing.T) {
+	original := 4.00
+	product := Product{CurrentPrice: 3.00, OriginalPrice: &original, SpecialDiscount: strPtr("1+1")}
+	product.ApplyPromotion(true)
+
+	if product.CardPrice == nil || *product.CardPrice != 3.00 {
+		t.Fatalf("expected card price 3.00, got %v", product.CardPrice)
+	}
+	if product.EffectivePrice == nil || math.Abs(*product.EffectivePrice-1.50) > 0.0001 {
+		t.Fatalf("expected member effective price 1.50, got %v", product.EffectivePrice)
+	}
+	if product.EffectiveRegularPrice == nil || math.Abs(*product.EffectiveRegularPrice-4.00) > 0.0001 {
+		t.Fatalf("expected regular effective price 4.00, got %v", product.EffectiveRegularPrice)
+	}
+}
+
+func TestLoyaltyPrograms_CoversStore(t *testing.T) {
+	programs := LoyaltyPrograms{string(LoyaltyProgramAitvaras), string(LoyaltyProgramIki)}
+
+	if !programs.CoversStore("maxima") || !programs.CoversStore("IKI") {
+		t.Fatal("expected member prices at Maxima and IKI")
+	}
+	if programs.CoversStore("rimi") || programs.CoversStore("lidl") {
+		t.Fatal("expected no member prices at Rimi or unknown stores")
+	}
+	if codes := programs.StoreCodes(); len(codes) != 2 || codes[0] != "iki" || codes[1] != "maxima" {
+		t.Fatalf("expected store codes [iki maxima], got %v", codes)
+	}
+}
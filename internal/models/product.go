@@ -35,6 +35,25 @@ type Product struct {
 	SpecialDiscount *string  `bun:"special_discount" json:"special_discount,omitempty"`    // e.g., "1+1", "3 už 2"
 	Currency        string   `bun:"-" json:"currency"`                                     // Not stored in DB, always EUR
 
+	// Loyalty price tiers; nil when the store publishes no separate price
+	CardPrice *float64 `bun:"card_price" json:"card_price,omitempty"` // e.g., Aitvaras, Mano Rimi, IKI card
+	AppPrice  *float64 `bun:"app_price" json:"app_price,omitempty"`   // loyalty app only
+
+	// Structured promotion parsed from SpecialDiscount, and the per-item price it yields
+	// for loyalty members (EffectivePrice) and everyone else (EffectiveRegularPrice)
+	Promotion             *Promotion `bun:"promotion,type:jsonb" json:"promotion,omitempty"`
+	EffectivePrice        *float64   `bun:"effective_price" json:"effective_price,omitempty"`
+	EffectiveRegularPrice *float64   `bun:"effective_regular_price" json:"effective_regular_price,omitempty"`
+
+	// Package size in base units (kg, l or vnt.) and the effective regular price
+	// per base unit, comparable across package sizes; nil when the size is unknown
+	UnitQuantity        *float64 `bun:"unit_quantity" json:"unit_quantity,omitempty"`
+	UnitBase            *string  `bun:"unit_base" json:"unit_base,omitempty"`
+	NormalizedUnitPrice *float64 `bun:"normalized_unit_price" json:"normalized_unit_price,omitempty"`
+
+	// Product identifiers
+	Barcode *string `bun:"barcode" json:"barcode,omitempty"` // GTIN-14, see normalize.NormalizeGTIN
+
 	// Product specifications
 	UnitSize    *string `bun:"unit_size" json:"unit_size,omitempty"`
 	UnitType    *string `bun:"unit_type" json:"unit_type,omitempty"`
@@ -62,6 +81,7 @@ type Product struct {
 
 	// Search optimization
 	SearchVector string `bun:"search_vector" json:"-"`
+	StemmedName  string `bun:"stemmed_name" json:"-"` // NormalizedName reduced to Lithuanian stems
 
 	// Validity period (partitioning key)
 	ValidFrom time.Time `bun:"valid_from,notnull" json:"valid_from"`
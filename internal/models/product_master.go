@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"slices"
 	"strings"
 	"time"
 
@@ -29,7 +30,7 @@ type ProductMaster struct {
 	PackagingVariants []string `bun:"packaging_variants,array" json:"packaging_variants"`
 
 	// Product identifiers and matching
-	Barcode          *string  `bun:"barcode" json:"barcode"`
+	Barcode          *string  `bun:"barcode" json:"barcode"` // GTIN-14, see normalize.NormalizeGTIN
 	ManufacturerCode *string  `bun:"manufacturer_code" json:"manufacturer_code"`
 	AlternativeNames []string `bun:"alternative_names,array" json:"alternative_names"`
 
@@ -241,6 +242,43 @@ func (pm *ProductMaster) AddAlternativeName(name string) {
 	pm.UpdatedAt = time.Now()
 }
 
+// AbsorbMaster folds the names, keywords, tags and barcode of a duplicate
+// master into this one so that products matched by either still match here
+func (pm *ProductMaster) AbsorbMaster(source *ProductMaster) {
+	if source == nil || source.ID == pm.ID {
+		return
+	}
+
+	if source.Name != pm.Name {
+		pm.AddAlternativeName(source.Name)
+	}
+	for _, name := range source.AlternativeNames {
+		if name != pm.Name {
+			pm.AddAlternativeName(name)
+		}
+	}
+
+	for _, keyword := range source.MatchKeywords {
+		pm.AddMatchingKeyword(keyword)
+	}
+	if source.NormalizedName != pm.NormalizedName {
+		pm.AddMatchingKeyword(source.NormalizedName)
+	}
+
+	for _, tag := range source.Tags {
+		if !slices.Contains(pm.Tags, tag) {
+			pm.Tags = append(pm.Tags, tag)
+		}
+	}
+
+	if pm.Barcode == nil && source.Barcode != nil {
+		barcode := *source.Barcode
+		pm.Barcode = &barcode
+	}
+
+	pm.UpdatedAt = time.Now()
+}
+
 // GetAllMatchableTerms returns all terms that can be used for matching
 func (pm *ProductMaster) GetAllMatchableTerms() []string {
 	var terms []string
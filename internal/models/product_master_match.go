@@ -29,3 +29,38 @@ type ProductMasterMatch struct {
 func (pmm *ProductMasterMatch) TableName() string {
 	return "product_master_matches"
 }
+
+// MatchReviewStatus represents the manual review state of a product master match
+type MatchReviewStatus string
+
+const (
+	MatchReviewStatusPending  MatchReviewStatus = "pending"
+	MatchReviewStatusApproved MatchReviewStatus = "approved"
+	MatchReviewStatusRejected MatchReviewStatus = "rejected"
+)
+
+// IsPending checks if the match is still waiting for review
+func (pmm *ProductMasterMatch) IsPending() bool {
+	return pmm.ReviewStatus == "" || pmm.ReviewStatus == string(MatchReviewStatusPending)
+}
+
+// MarkReviewed records the review decision on the match
+func (pmm *ProductMasterMatch) MarkReviewed(status MatchReviewStatus, reviewerID string, reviewedAt time.Time) {
+	pmm.ReviewStatus = string(status)
+	pmm.ReviewedBy = &reviewerID
+	pmm.ReviewedAt = &reviewedAt
+	pmm.UpdatedAt = reviewedAt
+}
+
+// ProductMasterMatchRejection is a negative matching example: products with
+// this normalized name must never be matched to the master again
+type ProductMasterMatchRejection struct {
+	bun.BaseModel `bun:"table:product_master_match_rejections,alias:pmr"`
+
+	ID              int64     `bun:"id,pk,autoincrement" json:"id"`
+	ProductMasterID int64     `bun:"product_master_id,notnull" json:"product_master_id"`
+	NormalizedName  string    `bun:"normalized_name,notnull" json:"normalized_name"`
+	ProductID       *int64    `bun:"product_id" json:"product_id"`
+	RejectedBy      *string   `bun:"rejected_by,type:uuid" json:"rejected_by"`
+	CreatedAt       time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
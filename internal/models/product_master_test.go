@@ -0,0 +1,48 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProductMaster_AbsorbMaster(t *testing.T) {
+	barcode := "04771234567898"
+	target := &ProductMaster{
+		ID:               1,
+		Name:             "Pienas 2.5%",
+		NormalizedName:   "pienas 2 5%",
+		AlternativeNames: []string{"Pienas 2,5%"},
+		MatchKeywords:    []string{"pienas"},
+		Tags:             []string{"dairy"},
+	}
+	source := &ProductMaster{
+		ID:               2,
+		Name:             "Pienas 2,5 proc.",
+		NormalizedName:   "pienas 2 5 proc",
+		AlternativeNames: []string{"Pienas 2,5%", "Pienas 2.5%"},
+		MatchKeywords:    []string{"Pienas", "2.5%"},
+		Tags:             []string{"dairy", "milk"},
+		Barcode:          &barcode,
+	}
+
+	target.AbsorbMaster(source)
+
+	if want := []string{"Pienas 2,5%", "Pienas 2,5 proc."}; !reflect.DeepEqual(target.AlternativeNames, want) {
+		t.Fatalf("expected alternative names %v, got %v", want, target.AlternativeNames)
+	}
+	if want := []string{"pienas", "2.5%", "pienas 2 5 proc"}; !reflect.DeepEqual(target.MatchKeywords, want) {
+		t.Fatalf("expected keywords %v, got %v", want, target.MatchKeywords)
+	}
+	if want := []string{"dairy", "milk"}; !reflect.DeepEqual(target.Tags, want) {
+		t.Fatalf("expected tags %v, got %v", want, target.Tags)
+	}
+	if target.Barcode == nil || *target.Barcode != barcode {
+		t.Fatalf("expected barcode to be taken from the source, got %v", target.Barcode)
+	}
+
+	other := "00000096385074"
+	target.AbsorbMaster(&ProductMaster{ID: 3, Name: "Pienas", Barcode: &other})
+	if *target.Barcode != barcode {
+		t.Fatalf("expected existing barcode to be kept, got %s", *target.Barcode)
+	}
+}
@@ -0,0 +1,382 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
+)
+
+// PromotionType identifies the mechanic of a structured promotion
+type PromotionType string
+
+const (
+	PromotionTypeBuyGet          PromotionType = "buy_get"           // buy N get M free: "1+1", "3 už 2"
+	PromotionTypeMultiBuy        PromotionType = "multi_buy"         // N items for X €: "3 už 2 €"
+	PromotionTypeNthItemDiscount PromotionType = "nth_item_discount" // Nth item −P%: "Antra prekė -50%"
+	PromotionTypeLoyalty         PromotionType = "loyalty"           // loyalty-card-only price
+)
+
+// Promotion is the structured form of a product's special discount text
+type Promotion struct {
+	Type               PromotionType `json:"type"`
+	BuyQuantity        int           `json:"buy_quantity,omitempty"`
+	FreeQuantity       int           `json:"free_quantity,omitempty"`
+	BundleQuantity     int           `json:"bundle_quantity,omitempty"`
+	BundlePrice        float64       `json:"bundle_price,omitempty"`
+	NthItem            int           `json:"nth_item,omitempty"`
+	NthDiscountPercent float64       `json:"nth_discount_percent,omitempty"`
+	LoyaltyOnly        bool          `json:"loyalty_only,omitempty"`
+	Text               string        `json:"text,omitempty"`
+}
+
+var (
+	buyGetPattern    = regexp.MustCompile(`(?i)\b(\d+)\s*\+\s*(\d+)\b`)
+	multiBuyPattern  = regexp.MustCompile(`(?i)\b(\d+)\s*(?:vnt\.?\s*)?(?:už|uz|for)\s*(\d+(?:[.,]\d{1,2})?)\s*(€|eur)?`)
+	nthItemPattern   = regexp.MustCompile(`(?i)(pirm|antr|treči|trec|ketvirt|penkt|\d+\s*-?\s*(?:a|oji|as|ji)?)\pL*\s+(?:prek|vnt|pak)\pL*\.?\s*(?:[-–]\s*(\d+(?:[.,]\d+)?)\s*%|(nemokamai|už\s*0|uz\s*0))`)
+	loyaltyPattern   = regexp.MustCompile(`(?i)(kortel|ačiū|aciu|lojalum|mano rimi|rimi mano|loyalty|card)`)
+	ordinalPrefixes  = map[string]int{"pirm": 1, "antr": 2, "treči": 3, "trec": 3, "ketvirt": 4, "penkt": 5}
+	leadingDigitsExp = regexp.MustCompile(`^\d+`)
+)
+
+// packSizeExtractor parses package sizes for unit prices; it only reads its
+// patterns, so one is shared by every product
+var packSizeExtractor = normalize.NewUnitExtractor()
+
+// ParsePromotion parses free-text promotion labels such as "1+1", "3 už 2 €",
+// "Antra prekė -50%" or "Su Ačiū kortele". Several comma-separated labels may be
+// combined; the first recognised mechanic wins and loyalty labels set LoyaltyOnly.
+// It returns nil when the text describes no known promotion.
+func ParsePromotion(text string) *Promotion {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	promo := &Promotion{Text: text}
+	lower := strings.ToLower(text)
+
+	switch {
+	case parseNthItem(lower, promo):
+	case parseMultiBuy(lower, promo):
+	case parseBuyGet(lower, promo):
+	}
+
+	promo.LoyaltyOnly = loyaltyPattern.MatchString(lower)
+	if promo.Type == "" {
+		if !promo.LoyaltyOnly {
+			return nil
+		}
+		promo.Type = PromotionTypeLoyalty
+	}
+	return promo
+}
+
+func parseBuyGet(text string, promo *Promotion) bool {
+	m := buyGetPattern.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+	buy, _ := strconv.Atoi(m[1])
+	free, _ := strconv.Atoi(m[2])
+	if buy <= 0 || free <= 0 {
+		return false
+	}
+	promo.Type = PromotionTypeBuyGet
+	promo.BuyQuantity = buy
+	promo.FreeQuantity = free
+	return true
+}
+
+func parseMultiBuy(text string, promo *Promotion) bool {
+	m := multiBuyPattern.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+	quantity, _ := strconv.Atoi(m[1])
+	amount := m[2]
+	if quantity <= 1 {
+		return false
+	}
+
+	// "3 už 2" without a currency or decimals means three for the price of two
+	if m[3] == "" && !strings.ContainsAny(amount, ".,") {
+		paid, _ := strconv.Atoi(amount)
+		if paid <= 0 || paid >= quantity {
+			return false
+		}
+		promo.Type = PromotionTypeBuyGet
+		promo.BuyQuantity = paid
+		promo.FreeQuantity = quantity - paid
+		return true
+	}
+
+	price, err := strconv.ParseFloat(strings.ReplaceAll(amount, ",", "."), 64)
+	if err != nil || price <= 0 {
+		return false
+	}
+	promo.Type = PromotionTypeMultiBuy
+	promo.BundleQuantity = quantity
+	promo.BundlePrice = price
+	return true
+}
+
+func parseNthItem(text string, promo *Promotion) bool {
+	m := nthItemPattern.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+
+	nth := 0
+	if digits := leadingDigitsExp.FindString(m[1]); digits != "" {
+		nth, _ = strconv.Atoi(digits)
+	} else {
+		nth = ordinalPrefixes[m[1]]
+	}
+	if nth < 2 {
+		return false
+	}
+
+	percent := 100.0
+	if m[2] != "" {
+		p, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", "."), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return false
+		}
+		percent = p
+	}
+
+	promo.Type = PromotionTypeNthItemDiscount
+	promo.NthItem = nth
+	promo.NthDiscountPercent = percent
+	return true
+}
+
+// QualifyingQuantity returns the smallest quantity that unlocks the full promotion
+func (pr *Promotion) QualifyingQuantity() int {
+	if pr == nil {
+		return 1
+	}
+	switch pr.Type {
+	case PromotionTypeBuyGet:
+		return pr.BuyQuantity + pr.FreeQuantity
+	case PromotionTypeMultiBuy:
+		return pr.BundleQuantity
+	case PromotionTypeNthItemDiscount:
+		return pr.NthItem
+	default:
+		return 1
+	}
+}
+
+// TotalPrice returns what quantity items cost when each item regularly costs itemPrice
+func (pr *Promotion) TotalPrice(itemPrice float64, quantity int) float64 {
+	if quantity <= 0 {
+		return 0
+	}
+	regular := itemPrice * float64(quantity)
+	if pr == nil {
+		return regular
+	}
+
+	switch pr.Type {
+	case PromotionTypeBuyGet:
+		group := pr.BuyQuantity + pr.FreeQuantity
+		if pr.BuyQuantity <= 0 || group <= 0 {
+			return regular
+		}
+		groups, rest := quantity/group, quantity%group
+		paid := groups*pr.BuyQuantity + min(rest, pr.BuyQuantity)
+		return itemPrice * float64(paid)
+	case PromotionTypeMultiBuy:
+		if pr.BundleQuantity <= 0 {
+			return regular
+		}
+		groups, rest := quantity/pr.BundleQuantity, quantity%pr.BundleQuantity
+		return pr.BundlePrice*float64(groups) + itemPrice*float64(rest)
+	case PromotionTypeNthItemDiscount:
+		if pr.NthItem <= 0 {
+			return regular
+		}
+		discounted := quantity / pr.NthItem
+		return regular - itemPrice*float64(discounted)*pr.NthDiscountPercent/100
+	default:
+		return regular
+	}
+}
+
+// Implement SQL driver interfaces for the JSON column
+func (pr *Promotion) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("failed to scan Promotion")
+	}
+
+	return json.Unmarshal(bytes, pr)
+}
+
+func (pr Promotion) Value() (driver.Value, error) {
+	b, err := json.Marshal(pr)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// GetPromotion returns the structured promotion, parsing SpecialDiscount for
+// products stored before promotions were structured
+func (p *Product) GetPromotion() *Promotion {
+	if p.Promotion != nil {
+		return p.Promotion
+	}
+	if p.SpecialDiscount == nil {
+		return nil
+	}
+	return ParsePromotion(*p.SpecialDiscount)
+}
+
+// ApplyPromotion parses the special discount text into Promotion and refreshes the
+// per-item prices at the promotion's qualifying quantity. A loyalty-only headline
+// price is recorded as the card price.
+func (p *Product) ApplyPromotion(loyaltyRequired bool) {
+	p.Promotion = nil
+	if p.SpecialDiscount != nil {
+		p.Promotion = ParsePromotion(*p.SpecialDiscount)
+	}
+	if loyaltyRequired {
+		if p.Promotion == nil {
+			p.Promotion = &Promotion{Type: PromotionTypeLoyalty}
+		}
+		p.Promotion.LoyaltyOnly = true
+		if p.CardPrice == nil && p.AppPrice == nil {
+			cardPrice := p.CurrentPrice
+			p.CardPrice = &cardPrice
+		}
+	}
+
+	effective := p.DealPrice()
+	p.EffectivePrice = &effective
+	effectiveRegular := p.EffectiveItemPrice(p.GetPromotion().QualifyingQuantity(), false)
+	p.EffectiveRegularPrice = &effectiveRegular
+	p.ApplyUnitPrice()
+}
+
+// DealPrice returns the per-item price a loyalty member pays when buying the
+// promotion's qualifying quantity. Flyer deals are compared at this price.
+func (p *Product) DealPrice() float64 {
+	return p.EffectiveItemPrice(p.GetPromotion().QualifyingQuantity(), true)
+}
+
+// EligiblePrice returns the per-item price at the promotion's qualifying quantity
+// for a shopper who is or is not a member of the store's loyalty programme
+func (p *Product) EligiblePrice(loyaltyMember bool) float64 {
+	return p.EffectiveItemPrice(p.GetPromotion().QualifyingQuantity(), loyaltyMember)
+}
+
+// EffectiveItemPrice returns the per-item price when buying quantity items, with the
+// best eligible price tier and the promotion applied. Loyalty-card-only deals and
+// card or app prices apply only to loyalty members.
+func (p *Product) EffectiveItemPrice(quantity int, loyaltyMember bool) float64 {
+	if quantity <= 0 {
+		quantity = 1
+	}
+	itemPrice := p.BestPrice(loyaltyMember)
+	promo := p.GetPromotion()
+	if promo == nil {
+		return itemPrice
+	}
+
+	if promo.LoyaltyOnly && !loyaltyMember {
+		// Without the card the shopper pays the shelf price and gets no deal
+		return itemPrice
+	}
+
+	// Flyers often print the bundle price ("3 už 2 €") as the product price
+	if promo.Type == PromotionTypeMultiBuy && math.Abs(p.CurrentPrice-promo.BundlePrice) < 0.005 {
+		if p.OriginalPrice != nil && *p.OriginalPrice > 0 {
+			itemPrice = *p.OriginalPrice
+		} else {
+			itemPrice = promo.BundlePrice / float64(promo.BundleQuantity)
+		}
+	}
+
+	return roundPrice(promo.TotalPrice(itemPrice, quantity) / float64(quantity))
+}
+
+// EffectiveUnitPrice returns the effective price per kg, l or vnt. together with
+// that unit, or false when the package size is unknown.
+func (p *Product) EffectiveUnitPrice(quantity int, loyaltyMember bool) (float64, string, bool) {
+	size, base, ok := p.BaseQuantity()
+	if !ok {
+		return 0, "", false
+	}
+	return roundPrice(p.EffectiveItemPrice(quantity, loyaltyMember) / size), base, true
+}
+
+// BaseQuantity returns the package size in kg, l or vnt. together with that
+// unit, parsed from UnitSize or else the name, or false when it is unknown.
+// Multipacks count in full: "6 x 0,5 l" is 3 l.
+func (p *Product) BaseQuantity() (float64, string, bool) {
+	size := ""
+	if p.UnitSize != nil {
+		size = *p.UnitSize
+	}
+	if size == "" {
+		size = p.Name
+	}
+
+	unit := packSizeExtractor.GetPackSize(size)
+	if unit == nil || unit.BaseValue <= 0 {
+		return 0, "", false
+	}
+
+	switch unit.Type {
+	case normalize.UnitTypeWeight:
+		return unit.BaseValue / 1000, "kg", true
+	case normalize.UnitTypeVolume:
+		return unit.BaseValue / 1000, "l", true
+	case normalize.UnitTypeCount:
+		return unit.BaseValue, "vnt.", true
+	default:
+		return 0, "", false
+	}
+}
+
+// ApplyUnitPrice refreshes the package size in base units and the normalized
+// price per base unit from the effective regular price, so products can be
+// compared per kg, l or vnt. across package sizes and stores
+func (p *Product) ApplyUnitPrice() {
+	p.UnitQuantity, p.UnitBase, p.NormalizedUnitPrice = nil, nil, nil
+
+	size, base, ok := p.BaseQuantity()
+	if !ok {
+		return
+	}
+	price := p.EligiblePrice(false)
+	if p.EffectiveRegularPrice != nil {
+		price = *p.EffectiveRegularPrice
+	}
+	unitPrice := roundPrice(price / size)
+
+	p.UnitQuantity = &size
+	p.UnitBase = &base
+	p.NormalizedUnitPrice = &unitPrice
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*10000) / 10000
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePromotion(t *testing.T) {
+	tests := []struct {
+		text string
+		want *Promotion
+	}{
+		{"1+1", &Promotion{Type: PromotionTypeBuyGet, BuyQuantity: 1, FreeQuantity: 1}},
+		{"2 + 1", &Promotion{Type: PromotionTypeBuyGet, BuyQuantity: 2, FreeQuantity: 1}},
+		{"3 už 2", &Promotion{Type: PromotionTypeBuyGet, BuyQuantity: 2, FreeQuantity: 1}},
+		{"3 už 2 €", &Promotion{Type: PromotionTypeMultiBuy, BundleQuantity: 3, BundlePrice: 2}},
+		{"2 vnt. už 3,99", &Promotion{Type: PromotionTypeMultiBuy, BundleQuantity: 2, BundlePrice: 3.99}},
+		{"Antra prekė -50%", &Promotion{Type: PromotionTypeNthItemDiscount, NthItem: 2, NthDiscountPercent: 50}},
+		{"Trečia prekė nemokamai", &Promotion{Type: PromotionTypeNthItemDiscount, NthItem: 3, NthDiscountPercent: 100}},
+		{"2-a prekė -30%", &Promotion{Type: PromotionTypeNthItemDiscount, NthItem: 2, NthDiscountPercent: 30}},
+		{"Su Ačiū kortele", &Promotion{Type: PromotionTypeLoyalty, LoyaltyOnly: true}},
+		{"1+1, Su Ačiū kortele", &Promotion{Type: PromotionTypeBuyGet, BuyQuantity: 1, FreeQuantity: 1, LoyaltyOnly: true}},
+		{"Naujiena", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := ParsePromotion(tt.text)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("expected no promotion, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected %+v, got nil", tt.want)
+			}
+			tt.want.Text = tt.text
+			if *got != *tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProduct_EffectiveItemPrice(t *testing.T) {
+	original := 3.00
+	tests := []struct {
+		name     string
+		product  Product
+		quantity int
+		loyalty  bool
+		want     float64
+	}{
+		{"no promotion", Product{CurrentPrice: 2.00}, 2, true, 2.00},
+		{"1+1 for two", Product{CurrentPrice: 2.00, SpecialDiscount: strPtr("1+1")}, 2, true, 1.00},
+		{"1+1 for three", Product{CurrentPrice: 3.00, SpecialDiscount: strPtr("1+1")}, 3, true, 2.00},
+		{"3 for 5 eur", Product{CurrentPrice: 5.00, OriginalPrice: &original, SpecialDiscount: strPtr("3 už 5 €")}, 3, true, 1.6667},
+		{"3 for 5 eur single", Product{CurrentPrice: 5.00, OriginalPrice: &original, SpecialDiscount: strPtr("3 už 5 €")}, 1, true, 3.00},
+		{"second item half price", Product{CurrentPrice: 2.00, SpecialDiscount: strPtr("Antra prekė -50%")}, 2, true, 1.50},
+		{"loyalty member", Product{CurrentPrice: 2.00, OriginalPrice: &original, SpecialDiscount: strPtr("Su kortele")}, 1, true, 2.00},
+		{"loyalty non member", Product{CurrentPrice: 2.00, OriginalPrice: &original, SpecialDiscount: strPtr("1+1, su kortele")}, 2, false, 3.00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.product.EffectiveItemPrice(tt.quantity, tt.loyalty); math.Abs(got-tt.want) > 0.0001 {
+				t.Fatalf("expected %.4f, got %.4f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProduct_EffectiveUnitPrice(t *testing.T) {
+	product := Product{CurrentPrice: 2.00, UnitSize: strPtr("500 g"), SpecialDiscount: strPtr("1+1")}
+
+	price, unit, ok := product.EffectiveUnitPrice(2, true)
+	if !ok || unit != "kg" || math.Abs(price-2.00) > 0.0001 {
+		t.Fatalf("expected 2.00 per kg, got %.4f %q (ok=%v)", price, unit, ok)
+	}
+
+	if _, _, ok := (&Product{CurrentPrice: 1, Name: "Duona"}).EffectiveUnitPrice(1, true); ok {
+		t.Fatal("expected no unit price without a package size")
+	}
+}
+
+func TestProduct_ApplyUnitPrice(t *testing.T) {
+	milk := Product{Name: "Pienas 2,5%", CurrentPrice: 1.20, UnitSize: strPtr("1,5 l")}
+	milk.ApplyPromotion(false)
+
+	if milk.UnitBase == nil || *milk.UnitBase != "l" || milk.UnitQuantity == nil || *milk.UnitQuantity != 1.5 {
+		t.Fatalf("expected 1.5 l, got %v %v", milk.UnitQuantity, milk.UnitBase)
+	}
+	if milk.NormalizedUnitPrice == nil || math.Abs(*milk.NormalizedUnitPrice-0.80) > 0.0001 {
+		t.Fatalf("expected 0.80 per l, got %v", milk.NormalizedUnitPrice)
+	}
+
+	bread := Product{Name: "Duona", CurrentPrice: 1, NormalizedUnitPrice: milk.NormalizedUnitPrice}
+	bread.ApplyUnitPrice()
+	if bread.NormalizedUnitPrice != nil || bread.UnitBase != nil {
+		t.Fatalf("expected no unit price without a package size, got %v", bread.NormalizedUnitPrice)
+	}
+}
+
+func TestProduct_ApplyPromotion(t *testing.T) {
+	product := Product{CurrentPrice: 4.00, SpecialDiscount: strPtr("2+1")}
+	product.ApplyPromotion(false)
+
+	if product.Promotion == nil || product.Promotion.Type != PromotionTypeBuyGet {
+		t.Fatalf("expected buy-get promotion, got %+v", product.Promotion)
+	}
+	if product.EffectivePrice == nil || math.Abs(*product.EffectivePrice-2.6667) > 0.0001 {
+		t.Fatalf("expected effective price 2.6667, got %v", product.EffectivePrice)
+	}
+
+	plain := Product{CurrentPrice: 1.99}
+	plain.ApplyPromotion(true)
+	if plain.Promotion == nil || !plain.Promotion.LoyaltyOnly {
+		t.Fatalf("expected loyalty-only promotion, got %+v", plain.Promotion)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SearchEvent is a product search recorded for search analytics
+type SearchEvent struct {
+	bun.BaseModel `bun:"table:search_events,alias:se"`
+
+	ID              int64      `bun:"id,pk,autoincrement" json:"id"`
+	SearchID        string     `bun:"search_id,type:uuid,notnull" json:"search_id"`
+	QueryText       string     `bun:"query_text,notnull" json:"query_text"`
+	NormalizedQuery string     `bun:"normalized_query,notnull" json:"normalized_query"`
+	ResultCount     int        `bun:"result_count,notnull" json:"result_count"`
+	ExecutionMs     int        `bun:"execution_ms,notnull" json:"execution_ms"`
+	MethodUsed      string     `bun:"method_used,notnull" json:"method_used"`
+	Failed          bool       `bun:"failed,notnull" json:"failed"`
+	UserID          *string    `bun:"user_id,type:uuid" json:"user_id,omitempty"`
+	IPAddress       *string    `bun:"ip_address" json:"ip_address,omitempty"`
+	UserAgent       *string    `bun:"user_agent" json:"user_agent,omitempty"`
+	FirstClickedAt  *time.Time `bun:"first_clicked_at" json:"first_clicked_at,omitempty"`
+	CreatedAt       time.Time  `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+func (se *SearchEvent) TableName() string {
+	return "search_events"
+}
+
+// SearchClick is a product opened from the results of a search
+type SearchClick struct {
+	bun.BaseModel `bun:"table:search_clicks,alias:sc"`
+
+	ID        int64     `bun:"id,pk,autoincrement" json:"id"`
+	SearchID  string    `bun:"search_id,type:uuid,notnull" json:"search_id"`
+	ProductID int64     `bun:"product_id,notnull" json:"product_id"`
+	Position  *int      `bun:"position" json:"position,omitempty"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+func (sc *SearchClick) TableName() string {
+	return "search_clicks"
+}
+
+// SearchDailyStat aggregates the searches of one normalized query on one day (UTC)
+type SearchDailyStat struct {
+	bun.BaseModel `bun:"table:search_daily_stats,alias:sds"`
+
+	Day                time.Time `bun:"day,pk,type:date" json:"day"`
+	NormalizedQuery    string    `bun:"normalized_query,pk" json:"normalized_query"`
+	SearchCount        int       `bun:"search_count,notnull" json:"search_count"`
+	ZeroResultCount    int       `bun:"zero_result_count,notnull" json:"zero_result_count"`
+	ErrorCount         int       `bun:"error_count,notnull" json:"error_count"`
+	TotalExecutionMs   int64     `bun:"total_execution_ms,notnull" json:"total_execution_ms"`
+	ClickCount         int       `bun:"click_count,notnull" json:"click_count"`
+	ClickedSearchCount int       `bun:"clicked_search_count,notnull" json:"clicked_search_count"`
+	UpdatedAt          time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+func (sds *SearchDailyStat) TableName() string {
+	return "search_daily_stats"
+}
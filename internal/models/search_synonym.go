@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SearchSynonym is a curated search dictionary entry applied to queries before searching
+type SearchSynonym struct {
+	bun.BaseModel `bun:"table:search_synonyms,alias:ss"`
+
+	ID           int64     `bun:"id,pk,autoincrement" json:"id"`
+	Type         string    `bun:"type,notnull" json:"type"`
+	Term         string    `bun:"term,notnull" json:"term"`
+	Replacements []string  `bun:"replacements,array" json:"replacements"`
+	CreatedBy    *string   `bun:"created_by,type:uuid" json:"created_by,omitempty"`
+	CreatedAt    time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	UpdatedAt    time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+func (ss *SearchSynonym) TableName() string {
+	return "search_synonyms"
+}
+
+// SearchSynonymType is the kind of rewrite a search dictionary entry applies
+type SearchSynonymType string
+
+const (
+	// SearchSynonymTypeSynonym makes the term and its replacements interchangeable
+	SearchSynonymTypeSynonym SearchSynonymType = "synonym"
+	// SearchSynonymTypeExpansion also searches the replacements when the term is queried, but not the reverse
+	SearchSynonymTypeExpansion SearchSynonymType = "expansion"
+	// SearchSynonymTypeBrandAlias rewrites the term to the canonical brand in Replacements[0]
+	SearchSynonymTypeBrandAlias SearchSynonymType = "brand_alias"
+	// SearchSynonymTypeStopPhrase removes the term from queries
+	SearchSynonymTypeStopPhrase SearchSynonymType = "stop_phrase"
+)
+
+// IsValid checks if the type is a known search dictionary entry type
+func (t SearchSynonymType) IsValid() bool {
+	switch t {
+	case SearchSynonymTypeSynonym, SearchSynonymTypeExpansion, SearchSynonymTypeBrandAlias, SearchSynonymTypeStopPhrase:
+		return true
+	}
+	return false
+}
@@ -20,6 +20,9 @@ type User struct {
 	PreferredLanguage string    `bun:"preferred_language,default:'lt'" json:"preferredLanguage"`
 	IsActive          bool      `bun:"is_active,default:true" json:"isActive"`
 
+	// Loyalty programmes the user belongs to, e.g. "aitvaras", "mano_rimi", "iki"
+	LoyaltyPrograms LoyaltyPrograms `bun:"loyalty_programs,array" json:"loyaltyPrograms"`
+
 	// OAuth fields for future expansion
 	OAuthProvider *string `bun:"oauth_provider" json:"oauthProvider"`
 	OAuthID       *string `bun:"oauth_id" json:"oauthId"`
@@ -214,6 +217,7 @@ type UserInput struct {
 type UserUpdateInput struct {
 	FullName          *string       `json:"fullName" validate:"omitempty,min=2,max=255"`
 	PreferredLanguage *string       `json:"preferredLanguage" validate:"omitempty,oneof=lt en ru"`
+	LoyaltyPrograms   *[]string     `json:"loyaltyPrograms" validate:"omitempty,dive,oneof=aitvaras mano_rimi iki"`
 	Metadata          *UserMetadata `json:"metadata"`
 }
 
@@ -86,9 +86,12 @@ type Suggestion struct {
 	StoreID         int            `json:"store_id"`
 	StoreName       string         `json:"store_name"`
 	Price           float64        `json:"price"`
+	EffectivePrice  float64        `json:"effective_price"` // Per-item price with the promotion applied, 0 when unknown
+	Promotion       *Promotion     `json:"promotion,omitempty"`
 	Unit            *string        `json:"unit,omitempty"`
-	SizeValue       *float64       `json:"size_value,omitempty"`
-	SizeUnit        *string        `json:"size_unit,omitempty"`
+	SizeValue       *float64       `json:"size_value,omitempty"` // Total package size in SizeUnit, multipacks included
+	SizeUnit        *string        `json:"size_unit,omitempty"`  // Base unit: g, ml or vnt.
+	SizeComparison  string         `json:"size_comparison,omitempty"`
 	Score           float64        `json:"score"`
 	Confidence      float64        `json:"confidence"` // 0.0-1.0 scale per constitution
 	Explanation     string         `json:"explanation"`
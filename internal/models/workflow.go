@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Workflow is the persisted state of a worker workflow: jobs that wait for their
+// parent jobs, fan out into child jobs and fan back in
+type Workflow struct {
+	bun.BaseModel `bun:"table:workflows,alias:wf"`
+
+	ID          string          `bun:"id,pk" json:"id"`
+	Name        string          `bun:"name,notnull" json:"name"`
+	Status      string          `bun:"status,notnull" json:"status"`
+	Params      json.RawMessage `bun:"params,type:jsonb,notnull" json:"params"`
+	Error       *string         `bun:"error" json:"error,omitempty"`
+	CreatedAt   time.Time       `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt   time.Time       `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updated_at"`
+	CompletedAt *time.Time      `bun:"completed_at" json:"completed_at,omitempty"`
+}
+
+// WorkflowStep is one job of a workflow. Declared steps depend on earlier steps by
+// name; steps a job fanned out into name the step that created them as parent.
+type WorkflowStep struct {
+	bun.BaseModel `bun:"table:workflow_steps,alias:ws"`
+
+	WorkflowID  string          `bun:"workflow_id,pk" json:"workflow_id"`
+	Name        string          `bun:"name,pk" json:"name"`
+	Position    int             `bun:"position,notnull" json:"position"`
+	Parent      *string         `bun:"parent" json:"parent,omitempty"`
+	DependsOn   json.RawMessage `bun:"depends_on,type:jsonb,notnull" json:"depends_on"`
+	JobID       string          `bun:"job_id,notnull" json:"job_id"`
+	JobType     string          `bun:"job_type,notnull" json:"job_type"`
+	Job         json.RawMessage `bun:"job,type:jsonb,notnull" json:"job"`
+	Status      string          `bun:"status,notnull" json:"status"`
+	Error       *string         `bun:"error" json:"error,omitempty"`
+	CreatedAt   time.Time       `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt   time.Time       `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updated_at"`
+	CompletedAt *time.Time      `bun:"completed_at" json:"completed_at,omitempty"`
+}
@@ -36,11 +36,15 @@ type CheckResult struct {
 	Error   string       `json:"error,omitempty"`
 }
 
+// CheckFunc is an additional named health check
+type CheckFunc func(ctx context.Context) CheckResult
+
 // HealthChecker performs health checks
 type HealthChecker struct {
 	db        *bun.DB
 	startTime time.Time
 	version   string
+	checks    map[string]CheckFunc
 }
 
 // NewHealthChecker creates a new health checker
@@ -49,15 +53,25 @@ func NewHealthChecker(db *bun.DB, version string) *HealthChecker {
 		db:        db,
 		startTime: time.Now(),
 		version:   version,
+		checks:    make(map[string]CheckFunc),
 	}
 }
 
+// AddCheck registers an additional check reported next to the database check.
+// Checks must be added before the checker serves requests.
+func (h *HealthChecker) AddCheck(name string, check CheckFunc) {
+	h.checks[name] = check
+}
+
 // Check performs all health checks
 func (h *HealthChecker) Check(ctx context.Context) HealthCheck {
 	checks := make(map[string]CheckResult)
 
 	// Database check
 	checks["database"] = h.checkDatabase(ctx)
+	for name, check := range h.checks {
+		checks[name] = check(ctx)
+	}
 
 	// Determine overall status
 	overallStatus := HealthStatusHealthy
@@ -107,4 +107,36 @@ var (
 		},
 		[]string{"worker"},
 	)
+
+	// WorkerJobsTotal tracks queue jobs processed by the worker daemon
+	// Labels:
+	//   - job_type: "scrape_flyer", "extract_products", ...
+	//   - status: "completed", "failed", "unhandled"
+	WorkerJobsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_jobs_total",
+			Help: "Total queue jobs processed by job type and status",
+		},
+		[]string{"job_type", "status"},
+	)
+
+	// WorkerJobDurationSeconds tracks how long job handlers run
+	// Labels:
+	//   - job_type: "scrape_flyer", "extract_products", ...
+	WorkerJobDurationSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_job_duration_seconds",
+			Help:    "Queue job handler duration in seconds",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600, 1800},
+		},
+		[]string{"job_type"},
+	)
+
+	// WorkerSchedulerLeader is 1 while this replica holds the scheduler leadership
+	WorkerSchedulerLeader = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "worker_scheduler_leader",
+			Help: "Whether this worker replica runs the job schedules (1) or not (0)",
+		},
+	)
 )
@@ -0,0 +1,107 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing exporters selectable with tracing.exporter
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterNone   = "none"
+)
+
+// InitTracing installs the global OpenTelemetry tracer provider for a service
+// and W3C trace context propagation. The returned function flushes buffered
+// spans and must run before the process exits.
+//
+// With no exporter the tracer provider stays the no-op one: spans cost nothing,
+// but incoming trace context is still passed on to jobs and outgoing calls.
+func InitTracing(ctx context.Context, cfg config.TracingConfig, service, environment string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporterName := cfg.Exporter
+	if exporterName == "" {
+		exporterName = TracingExporterNone
+		if cfg.Endpoint != "" {
+			exporterName = TracingExporterOTLP
+		}
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporterName, err)
+	}
+
+	if cfg.ServiceName != "" {
+		service = cfg.ServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(service),
+		semconv.DeploymentEnvironmentName(environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(rootSampler{sdktrace.TraceIDRatioBased(cfg.SampleRatio)})),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// rootSampler samples new traces by ratio but drops client spans starting a
+// trace: database and Redis calls made outside a request or job, like the job
+// queue's polling, would otherwise each be a trace of their own
+type rootSampler struct {
+	ratio sdktrace.Sampler
+}
+
+func (s rootSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if p.Kind == trace.SpanKindClient {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.Drop,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.ratio.ShouldSample(p)
+}
+
+func (s rootSampler) Description() string {
+	return "RootSampler{" + s.ratio.Description() + ",DropClientRoots}"
+}
@@ -18,5 +18,6 @@ type Repository interface {
 	GetValidProducts(ctx context.Context, storeIDs []int, filters *Filters) ([]*models.Product, error)
 	GetProductsOnSale(ctx context.Context, storeIDs []int, filters *Filters) ([]*models.Product, error)
 	CreateBatch(ctx context.Context, products []*models.Product) error
+	ReplaceByFlyerPage(ctx context.Context, flyerPageID int, products []*models.Product) error
 	Update(ctx context.Context, product *models.Product) error
 }
@@ -0,0 +1,12 @@
+package productmaster
+
+import "errors"
+
+var (
+	// ErrMasterNotActive is returned when a merge or split touches a master that is not active.
+	ErrMasterNotActive = errors.New("product master is not active")
+	// ErrProductsNotInMaster is returned when a split names products that do not belong to the master.
+	ErrProductsNotInMaster = errors.New("products do not belong to product master")
+	// ErrMatchAlreadyReviewed is returned when a review decision is made on a match that is no longer pending.
+	ErrMatchAlreadyReviewed = errors.New("product master match already reviewed")
+)
@@ -16,6 +16,7 @@ type Repository interface {
 	Update(ctx context.Context, master *models.ProductMaster) (int64, error)
 	SoftDelete(ctx context.Context, id int64) (int64, error)
 	GetByCanonicalName(ctx context.Context, normalizedName string) (*models.ProductMaster, error)
+	GetByBarcode(ctx context.Context, gtin string) (*models.ProductMaster, error)
 	GetActive(ctx context.Context) ([]*models.ProductMaster, error)
 	GetVerified(ctx context.Context) ([]*models.ProductMaster, error)
 	GetForReview(ctx context.Context) ([]*models.ProductMaster, error)
@@ -23,6 +24,8 @@ type Repository interface {
 	VerifyMaster(ctx context.Context, masterID int64, confidence float64, verifiedAt time.Time) error
 	DeactivateMaster(ctx context.Context, masterID int64, deactivatedAt time.Time) (int64, error)
 	MarkAsDuplicate(ctx context.Context, masterID, duplicateOfID int64) error
+	MergeMasters(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error)
+	SplitMaster(ctx context.Context, masterID int64, productIDs []int, newMaster *models.ProductMaster) error
 	GetMatchingStatistics(ctx context.Context, masterID int64) (*ProductMasterStats, error)
 	GetOverallStatistics(ctx context.Context) (*OverallStats, error)
 	GetProduct(ctx context.Context, productID int) (*models.Product, error)
@@ -30,6 +33,12 @@ type Repository interface {
 	CreateMasterAndLinkProduct(ctx context.Context, product *models.Product, master *models.ProductMaster) error
 	GetUnmatchedProducts(ctx context.Context, limit int) ([]*models.Product, error)
 	MarkProductForReview(ctx context.Context, productID int) error
+	CreatePendingMatch(ctx context.Context, match *models.ProductMasterMatch) error
+	GetMatch(ctx context.Context, matchID int64) (*models.ProductMasterMatch, error)
+	GetPendingMatches(ctx context.Context, limit, offset int) ([]*models.ProductMasterMatch, error)
+	AcceptMatch(ctx context.Context, matchID int64, reviewerID string, reviewedAt time.Time) error
+	RejectMatch(ctx context.Context, matchID int64, rejectionKey, reviewerID string, reviewedAt time.Time) error
+	ReassignMatch(ctx context.Context, matchID, masterID int64, rejectionKey, reviewerID string, reviewedAt time.Time) (*models.ProductMasterMatch, error)
 	GetMasterProductCounts(ctx context.Context) ([]MasterProductCount, error)
 	UpdateMasterStatistics(ctx context.Context, masterID int64, confidence float64, matchCount int, updatedAt time.Time) (int64, error)
 }
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kainuguru/kainuguru-api/internal/enrichmentrun"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/repositories/base"
+	"github.com/uptrace/bun"
+)
+
+type enrichmentRunRepository struct {
+	db   *bun.DB
+	base *base.Repository[models.EnrichmentRun]
+}
+
+// NewEnrichmentRunRepository returns a Bun-backed enrichment run repository.
+func NewEnrichmentRunRepository(db *bun.DB) enrichmentrun.Repository {
+	return &enrichmentRunRepository{
+		db:   db,
+		base: base.NewRepository[models.EnrichmentRun](db, "er.id"),
+	}
+}
+
+func (r *enrichmentRunRepository) GetByID(ctx context.Context, id int64) (*models.EnrichmentRun, error) {
+	return r.base.GetByID(ctx, id)
+}
+
+func (r *enrichmentRunRepository) GetAll(ctx context.Context, filters *enrichmentrun.Filters) ([]*models.EnrichmentRun, error) {
+	return r.base.GetAll(ctx, base.WithQuery[models.EnrichmentRun](func(q *bun.SelectQuery) *bun.SelectQuery {
+		q = applyEnrichmentRunFilters(q, filters)
+		return applyEnrichmentRunPagination(q, filters)
+	}))
+}
+
+func (r *enrichmentRunRepository) Create(ctx context.Context, run *models.EnrichmentRun) error {
+	return r.base.Create(ctx, run)
+}
+
+func (r *enrichmentRunRepository) Update(ctx context.Context, run *models.EnrichmentRun) error {
+	return r.base.Update(ctx, run)
+}
+
+// RefreshTotals recomputes the aggregated counters of a run from its page rows.
+func (r *enrichmentRunRepository) RefreshTotals(ctx context.Context, runID int64) error {
+	_, err := r.db.NewUpdate().
+		Model((*models.EnrichmentRun)(nil)).
+		Set("pages_total = (SELECT COUNT(*) FROM enrichment_run_pages WHERE run_id = ?)", runID).
+		Set("pages_completed = (SELECT COUNT(*) FROM enrichment_run_pages WHERE run_id = ? AND status = ?)", runID, string(models.EnrichmentRunPageStatusCompleted)).
+		Set("pages_failed = (SELECT COUNT(*) FROM enrichment_run_pages WHERE run_id = ? AND status = ?)", runID, string(models.EnrichmentRunPageStatusFailed)).
+		Set("products_extracted = (SELECT COALESCE(SUM(products_extracted), 0) FROM enrichment_run_pages WHERE run_id = ?)", runID).
+		Set("tokens_used = (SELECT COALESCE(SUM(tokens_used), 0) FROM enrichment_run_pages WHERE run_id = ?)", runID).
+		Where("id = ?", runID).
+		Exec(ctx)
+	return err
+}
+
+func (r *enrichmentRunRepository) GetPage(ctx context.Context, runID int64, flyerPageID int) (*models.EnrichmentRunPage, error) {
+	page := new(models.EnrichmentRunPage)
+	err := r.db.NewSelect().
+		Model(page).
+		Where("erp.run_id = ?", runID).
+		Where("erp.flyer_page_id = ?", flyerPageID).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (r *enrichmentRunRepository) GetPages(ctx context.Context, runID int64, filters *enrichmentrun.PageFilters) ([]*models.EnrichmentRunPage, error) {
+	var pages []*models.EnrichmentRunPage
+	query := r.db.NewSelect().
+		Model(&pages).
+		Where("erp.run_id = ?", runID)
+
+	if filters != nil {
+		if len(filters.Status) > 0 {
+			query.Where("erp.status IN (?)", bun.In(filters.Status))
+		}
+		if len(filters.FlyerIDs) > 0 {
+			query.Where("erp.flyer_id IN (?)", bun.In(filters.FlyerIDs))
+		}
+	}
+
+	err := query.
+		Order("erp.flyer_id ASC").
+		Order("erp.flyer_page_id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// UpsertPage inserts the page row or overwrites the existing row for the same run and flyer page.
+func (r *enrichmentRunRepository) UpsertPage(ctx context.Context, page *models.EnrichmentRunPage) error {
+	_, err := r.db.NewInsert().
+		Model(page).
+		On("CONFLICT (run_id, flyer_page_id) DO UPDATE").
+		Set("status = EXCLUDED.status").
+		Set("attempts = EXCLUDED.attempts").
+		Set("products_extracted = EXCLUDED.products_extracted").
+		Set("tokens_used = EXCLUDED.tokens_used").
+		Set("error_message = EXCLUDED.error_message").
+		Set("started_at = EXCLUDED.started_at").
+		Set("completed_at = EXCLUDED.completed_at").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("id").
+		Exec(ctx)
+	return err
+}
+
+func applyEnrichmentRunFilters(query *bun.SelectQuery, filters *enrichmentrun.Filters) *bun.SelectQuery {
+	if filters == nil {
+		return query
+	}
+	if len(filters.Status) > 0 {
+		query.Where("er.status IN (?)", bun.In(filters.Status))
+	}
+	return query
+}
+
+func applyEnrichmentRunPagination(query *bun.SelectQuery, filters *enrichmentrun.Filters) *bun.SelectQuery {
+	if filters == nil {
+		return query.Order("er.id DESC")
+	}
+	if filters.Limit > 0 {
+		query.Limit(filters.Limit)
+	}
+	if filters.Offset > 0 {
+		query.Offset(filters.Offset)
+	}
+
+	orderBy := "id"
+	if filters.OrderBy != "" {
+		orderBy = filters.OrderBy
+	}
+	orderDir := "DESC"
+	if filters.OrderDir == "ASC" {
+		orderDir = "ASC"
+	}
+	return query.Order(fmt.Sprintf("er.%s %s", orderBy, orderDir))
+}
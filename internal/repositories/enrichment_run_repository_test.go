@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/enrichmentrun"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+func TestEnrichmentRunRepository_UpsertPageOverwritesExistingRow(t *testing.T) {
+	ctx := context.Background()
+	_, repo, cleanup := setupEnrichmentRunTestDB(t)
+	defer cleanup()
+
+	run := createTestRun(t, repo)
+	now := time.Now()
+
+	first := &models.EnrichmentRunPage{
+		RunID:       run.ID,
+		FlyerID:     1,
+		FlyerPageID: 10,
+		Status:      string(models.EnrichmentRunPageStatusProcessing),
+		Attempts:    1,
+		StartedAt:   &now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := repo.UpsertPage(ctx, first); err != nil {
+		t.Fatalf("UpsertPage returned error: %v", err)
+	}
+
+	second := &models.EnrichmentRunPage{
+		RunID:             run.ID,
+		FlyerID:           1,
+		FlyerPageID:       10,
+		Status:            string(models.EnrichmentRunPageStatusCompleted),
+		Attempts:          1,
+		ProductsExtracted: 12,
+		TokensUsed:        900,
+		StartedAt:         &now,
+		CompletedAt:       &now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := repo.UpsertPage(ctx, second); err != nil {
+		t.Fatalf("second UpsertPage returned error: %v", err)
+	}
+
+	pages, err := repo.GetPages(ctx, run.ID, nil)
+	if err != nil {
+		t.Fatalf("GetPages returned error: %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("expected a single page row, got %d", len(pages))
+	}
+	if !pages[0].IsCompleted() || pages[0].ProductsExtracted != 12 || pages[0].TokensUsed != 900 {
+		t.Fatalf("page row not overwritten: %+v", pages[0])
+	}
+}
+
+func TestEnrichmentRunRepository_RefreshTotalsAggregatesPages(t *testing.T) {
+	ctx := context.Background()
+	_, repo, cleanup := setupEnrichmentRunTestDB(t)
+	defer cleanup()
+
+	run := createTestRun(t, repo)
+	now := time.Now()
+	errMsg := "AI extraction failed"
+
+	rows := []*models.EnrichmentRunPage{
+		{RunID: run.ID, FlyerID: 1, FlyerPageID: 1, Status: string(models.EnrichmentRunPageStatusCompleted), ProductsExtracted: 5, TokensUsed: 100},
+		{RunID: run.ID, FlyerID: 1, FlyerPageID: 2, Status: string(models.EnrichmentRunPageStatusCompleted), ProductsExtracted: 7, TokensUsed: 150},
+		{RunID: run.ID, FlyerID: 2, FlyerPageID: 3, Status: string(models.EnrichmentRunPageStatusFailed), TokensUsed: 50, ErrorMessage: &errMsg},
+		{RunID: run.ID, FlyerID: 2, FlyerPageID: 4, Status: string(models.EnrichmentRunPageStatusProcessing)},
+	}
+	for _, row := range rows {
+		row.CreatedAt = now
+		row.UpdatedAt = now
+		if err := repo.UpsertPage(ctx, row); err != nil {
+			t.Fatalf("UpsertPage returned error: %v", err)
+		}
+	}
+
+	if err := repo.RefreshTotals(ctx, run.ID); err != nil {
+		t.Fatalf("RefreshTotals returned error: %v", err)
+	}
+
+	reloaded, err := repo.GetByID(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+	if reloaded.PagesTotal != 4 || reloaded.PagesCompleted != 2 || reloaded.PagesFailed != 1 {
+		t.Fatalf("unexpected page counters: %+v", reloaded)
+	}
+	if reloaded.ProductsExtracted != 12 || reloaded.TokensUsed != 300 {
+		t.Fatalf("unexpected product/token totals: %+v", reloaded)
+	}
+	if reloaded.Options.StoreCode != "iki" {
+		t.Fatalf("options not round-tripped: %+v", reloaded.Options)
+	}
+
+	failed, err := repo.GetPages(ctx, run.ID, &enrichmentrun.PageFilters{
+		Status: []string{string(models.EnrichmentRunPageStatusFailed)},
+	})
+	if err != nil {
+		t.Fatalf("filtered GetPages returned error: %v", err)
+	}
+	if len(failed) != 1 || failed[0].FlyerPageID != 3 || failed[0].ErrorMessage == nil {
+		t.Fatalf("expected only the failed page, got %+v", failed)
+	}
+}
+
+func setupEnrichmentRunTestDB(t *testing.T) (*bun.DB, enrichmentrun.Repository, func()) {
+	t.Helper()
+	sqldb, err := sql.Open(sqliteshim.DriverName(), "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+
+	ctx := context.Background()
+	schema := []string{`
+CREATE TABLE enrichment_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    status TEXT NOT NULL DEFAULT 'running',
+    options TEXT NOT NULL,
+    pages_total INTEGER NOT NULL DEFAULT 0,
+    pages_completed INTEGER NOT NULL DEFAULT 0,
+    pages_failed INTEGER NOT NULL DEFAULT 0,
+    products_extracted INTEGER NOT NULL DEFAULT 0,
+    tokens_used INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    started_at DATETIME NOT NULL,
+    completed_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);`, `
+CREATE TABLE enrichment_run_pages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    flyer_id INTEGER NOT NULL,
+    flyer_page_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    products_extracted INTEGER NOT NULL DEFAULT 0,
+    tokens_used INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at DATETIME,
+    completed_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE(run_id, flyer_page_id)
+);`}
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+	}
+
+	repo := NewEnrichmentRunRepository(db)
+	cleanup := func() {
+		_ = db.Close()
+	}
+	return db, repo, cleanup
+}
+
+func createTestRun(t *testing.T, repo enrichmentrun.Repository) *models.EnrichmentRun {
+	t.Helper()
+	now := time.Now()
+	run := &models.EnrichmentRun{
+		Status:    string(models.EnrichmentRunStatusRunning),
+		Options:   models.EnrichmentRunOptions{StoreCode: "iki", Date: "2025-11-03", BatchSize: 10},
+		StartedAt: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := repo.Create(context.Background(), run); err != nil {
+		t.Fatalf("failed to create run: %v", err)
+	}
+	return run
+}
@@ -60,6 +60,7 @@ func (r *extractionJobRepository) GetNextJob(ctx context.Context, jobTypes []str
 		query := tx.NewSelect().
 			Model(job).
 			Where("ej.status = ?", models.JobStatusPending).
+			Where("ej.queue_name IS NULL").
 			Where("ej.scheduled_for <= ?", time.Now()).
 			Where("ej.attempts < ej.max_attempts").
 			Order("ej.priority DESC").
@@ -115,6 +116,7 @@ func (r *extractionJobRepository) GetPendingJobs(ctx context.Context, jobTypes [
 	query := r.db.NewSelect().
 		Model(&jobs).
 		Where("ej.status = ?", models.JobStatusPending).
+		Where("ej.queue_name IS NULL").
 		Where("ej.scheduled_for <= ?", time.Now()).
 		Order("ej.priority DESC").
 		Order("ej.created_at ASC")
@@ -199,6 +199,10 @@ CREATE TABLE extraction_jobs (
     error_count INTEGER NOT NULL DEFAULT 0,
     scheduled_for DATETIME NOT NULL,
     expires_at DATETIME,
+    queue_name TEXT,
+    job_key TEXT,
+    retry_delay_ms INTEGER NOT NULL DEFAULT 30000,
+    lease_expires_at DATETIME,
     created_at DATETIME NOT NULL,
     updated_at DATETIME NOT NULL
 );`
@@ -101,7 +101,14 @@ func applyFlyerPageFilters(query *bun.SelectQuery, filters *flyerpage.Filters) *
 		query.Where("fp.flyer_id IN (?)", bun.In(filters.FlyerIDs))
 	}
 
-	if len(filters.Status) > 0 {
+	if filters.ProcessingBefore != nil {
+		query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			if len(filters.Status) > 0 {
+				q.WhereOr("fp.extraction_status IN (?)", bun.In(filters.Status))
+			}
+			return q.WhereOr("fp.extraction_status = ? AND fp.updated_at < ?", string(models.FlyerPageStatusProcessing), *filters.ProcessingBefore)
+		})
+	} else if len(filters.Status) > 0 {
 		query.Where("fp.extraction_status IN (?)", bun.In(filters.Status))
 	}
 
@@ -117,6 +124,10 @@ func applyFlyerPageFilters(query *bun.SelectQuery, filters *flyerpage.Filters) *
 		query.Where("fp.page_number IN (?)", bun.In(filters.PageNumbers))
 	}
 
+	if filters.MaxAttempts > 0 {
+		query.Where("fp.extraction_attempts < ?", filters.MaxAttempts)
+	}
+
 	return query
 }
 
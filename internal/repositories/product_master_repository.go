@@ -85,6 +85,21 @@ func (r *productMasterRepository) GetByCanonicalName(ctx context.Context, normal
 	return master, nil
 }
 
+func (r *productMasterRepository) GetByBarcode(ctx context.Context, gtin string) (*models.ProductMaster, error) {
+	master := new(models.ProductMaster)
+	err := r.db.NewSelect().
+		Model(master).
+		Where("pm.barcode = ?", gtin).
+		Where("pm.status = ?", models.ProductMasterStatusActive).
+		Order("pm.confidence_score DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return master, nil
+}
+
 func (r *productMasterRepository) GetActive(ctx context.Context) ([]*models.ProductMaster, error) {
 	return r.querySimpleList(ctx, "pm.status = ?", models.ProductMasterStatusActive)
 }
@@ -194,6 +209,129 @@ func (r *productMasterRepository) DeactivateMaster(ctx context.Context, masterID
 }
 
 func (r *productMasterRepository) MarkAsDuplicate(ctx context.Context, masterID, duplicateOfID int64) error {
+	_, err := r.MergeMasters(ctx, []int64{masterID}, duplicateOfID)
+	return err
+}
+
+// masterReferenceTables lists the tables whose product_master_id follows a
+// master when it is merged into another one
+var masterReferenceTables = []string{
+	"products",
+	"shopping_list_items",
+	"price_history",
+	"price_alerts",
+	"offer_snapshots",
+}
+
+func (r *productMasterRepository) MergeMasters(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error) {
+	target := new(models.ProductMaster)
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var masters []*models.ProductMaster
+		if err := tx.NewSelect().
+			Model(&masters).
+			Where("pm.id IN (?)", bun.In(append([]int64{targetID}, sourceIDs...))).
+			OrderExpr("pm.id ASC").
+			For("UPDATE").
+			Scan(ctx); err != nil {
+			return err
+		}
+		if len(masters) != len(sourceIDs)+1 {
+			return sql.ErrNoRows
+		}
+
+		var sources []*models.ProductMaster
+		for _, master := range masters {
+			if !master.IsActive() {
+				return fmt.Errorf("%w: %d", productmaster.ErrMasterNotActive, master.ID)
+			}
+			if master.ID == targetID {
+				*target = *master
+				continue
+			}
+			sources = append(sources, master)
+		}
+
+		for _, source := range sources {
+			target.AbsorbMaster(source)
+		}
+		if _, err := tx.NewUpdate().
+			Model(target).
+			Column("alternative_names", "match_keywords", "tags", "barcode", "updated_at").
+			WherePK().
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to update target master: %w", err)
+		}
+
+		for _, table := range masterReferenceTables {
+			if _, err := tx.NewRaw(
+				"UPDATE ? SET product_master_id = ? WHERE product_master_id IN (?)",
+				bun.Ident(table), targetID, bun.In(sourceIDs),
+			).Exec(ctx); err != nil {
+				return fmt.Errorf("failed to re-link %s: %w", table, err)
+			}
+		}
+
+		// A product can only be matched to a master once, so drop the match
+		// rows that would collide with an existing target (or sibling) match
+		if _, err := tx.NewRaw(`
+			DELETE FROM product_master_matches a
+			USING product_master_matches b
+			WHERE a.product_master_id IN (?)
+				AND a.product_id = b.product_id
+				AND a.id <> b.id
+				AND (b.product_master_id = ? OR (b.product_master_id IN (?) AND b.id < a.id))`,
+			bun.In(sourceIDs), targetID, bun.In(sourceIDs),
+		).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to remove duplicate matches: %w", err)
+		}
+		if _, err := tx.NewRaw(
+			"UPDATE product_master_matches SET product_master_id = ? WHERE product_master_id IN (?)",
+			targetID, bun.In(sourceIDs),
+		).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to re-link matches: %w", err)
+		}
+
+		// Trends are derived from price_history and get recalculated for the target
+		if _, err := tx.NewRaw(
+			"DELETE FROM price_trends WHERE product_master_id IN (?)",
+			bun.In(sourceIDs),
+		).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to remove merged price trends: %w", err)
+		}
+
+		// Keep redirects one hop deep for masters merged into a source earlier
+		if _, err := tx.NewUpdate().
+			Model((*models.ProductMaster)(nil)).
+			Set("merged_into_id = ?", targetID).
+			Where("merged_into_id IN (?)", bun.In(sourceIDs)).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to update redirects: %w", err)
+		}
+
+		for _, source := range sources {
+			source.MarkAsMerged(targetID)
+			if _, err := tx.NewUpdate().
+				Model(source).
+				Column("status", "merged_into_id", "updated_at").
+				WherePK().
+				Exec(ctx); err != nil {
+				return fmt.Errorf("failed to mark master %d as merged: %w", source.ID, err)
+			}
+		}
+
+		if err := recomputeMasterStatistics(ctx, tx, targetID); err != nil {
+			return err
+		}
+
+		return tx.NewSelect().Model(target).WherePK().Scan(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *productMasterRepository) SplitMaster(ctx context.Context, masterID int64, productIDs []int, newMaster *models.ProductMaster) error {
 	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
 		master := new(models.ProductMaster)
 		if err := tx.NewSelect().
@@ -203,26 +341,110 @@ func (r *productMasterRepository) MarkAsDuplicate(ctx context.Context, masterID,
 			Scan(ctx); err != nil {
 			return err
 		}
+		if !master.IsActive() {
+			return fmt.Errorf("%w: %d", productmaster.ErrMasterNotActive, master.ID)
+		}
+
+		count, err := tx.NewSelect().
+			Model((*models.Product)(nil)).
+			Where("p.id IN (?)", bun.In(productIDs)).
+			Where("p.product_master_id = ?", masterID).
+			Count(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check products: %w", err)
+		}
+		if count != len(productIDs) {
+			return productmaster.ErrProductsNotInMaster
+		}
+
+		if _, err := tx.NewInsert().Model(newMaster).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create split master: %w", err)
+		}
 
-		master.MarkAsMerged(duplicateOfID)
 		if _, err := tx.NewUpdate().
-			Model(master).
-			Column("status", "merged_into_id", "updated_at").
-			WherePK().
+			Model((*models.Product)(nil)).
+			Set("product_master_id = ?", newMaster.ID).
+			Set("updated_at = ?", time.Now()).
+			Where("id IN (?)", bun.In(productIDs)).
 			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to move products: %w", err)
+		}
+
+		if _, err := tx.NewRaw(
+			"UPDATE product_master_matches SET product_master_id = ? WHERE product_master_id = ? AND product_id IN (?)",
+			newMaster.ID, masterID, bun.In(productIDs),
+		).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to move matches: %w", err)
+		}
+
+		// Price points recorded for a store and flyer that only the moved
+		// products appeared in belong to the new master
+		if _, err := tx.NewRaw(`
+			UPDATE price_history ph SET product_master_id = ?
+			WHERE ph.product_master_id = ?
+				AND EXISTS (
+					SELECT 1 FROM products p
+					WHERE p.product_master_id = ? AND p.store_id = ph.store_id AND p.flyer_id = ph.flyer_id
+				)
+				AND NOT EXISTS (
+					SELECT 1 FROM products p
+					WHERE p.product_master_id = ? AND p.store_id = ph.store_id AND p.flyer_id = ph.flyer_id
+				)`,
+			newMaster.ID, masterID, newMaster.ID, masterID,
+		).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to move price history: %w", err)
+		}
+
+		if _, err := tx.NewRaw(
+			"UPDATE offer_snapshots SET product_master_id = ? WHERE product_master_id = ? AND flyer_product_id IN (?)",
+			newMaster.ID, masterID, bun.In(productIDs),
+		).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to move offer snapshots: %w", err)
+		}
+
+		// Shopping list items and price alerts stay with the original master:
+		// they were created for it and the user can re-target them
+		if err := recomputeMasterStatistics(ctx, tx, masterID); err != nil {
+			return err
+		}
+		if err := recomputeMasterStatistics(ctx, tx, newMaster.ID); err != nil {
 			return err
 		}
 
-		_, err := tx.NewUpdate().
-			Model((*models.Product)(nil)).
-			Set("product_master_id = ?", duplicateOfID).
-			Set("updated_at = ?", time.Now()).
-			Where("product_master_id = ?", masterID).
-			Exec(ctx)
-		return err
+		return tx.NewSelect().Model(newMaster).WherePK().Scan(ctx)
 	})
 }
 
+// recomputeMasterStatistics derives match count, price range and last seen
+// date of a master from the products currently linked to it
+func recomputeMasterStatistics(ctx context.Context, db bun.IDB, masterID int64) error {
+	_, err := db.NewRaw(`
+		UPDATE product_masters pm SET
+			match_count = stats.match_count,
+			avg_price = stats.avg_price,
+			min_price = stats.min_price,
+			max_price = stats.max_price,
+			last_seen_date = COALESCE(stats.last_seen_date, pm.last_seen_date),
+			updated_at = ?
+		FROM (
+			SELECT
+				COUNT(*) AS match_count,
+				AVG(p.current_price) AS avg_price,
+				MIN(p.current_price) AS min_price,
+				MAX(p.current_price) AS max_price,
+				MAX(p.created_at) AS last_seen_date
+			FROM products p
+			WHERE p.product_master_id = ?
+		) stats
+		WHERE pm.id = ?`,
+		time.Now(), masterID, masterID,
+	).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to recompute statistics for master %d: %w", masterID, err)
+	}
+	return nil
+}
+
 func (r *productMasterRepository) GetProduct(ctx context.Context, productID int) (*models.Product, error) {
 	product := new(models.Product)
 	err := r.db.NewSelect().
@@ -409,6 +631,226 @@ func (r *productMasterRepository) MarkProductForReview(ctx context.Context, prod
 	return err
 }
 
+func (r *productMasterRepository) CreatePendingMatch(ctx context.Context, match *models.ProductMasterMatch) error {
+	match.ReviewStatus = string(models.MatchReviewStatusPending)
+	_, err := r.db.NewInsert().
+		Model(match).
+		On("CONFLICT (product_id, product_master_id) DO NOTHING").
+		Exec(ctx)
+	return err
+}
+
+func (r *productMasterRepository) GetMatch(ctx context.Context, matchID int64) (*models.ProductMasterMatch, error) {
+	match := new(models.ProductMasterMatch)
+	err := r.db.NewSelect().
+		Model(match).
+		Relation("Product").
+		Relation("Master").
+		Where("pmm.id = ?", matchID).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+func (r *productMasterRepository) GetPendingMatches(ctx context.Context, limit, offset int) ([]*models.ProductMasterMatch, error) {
+	var matches []*models.ProductMasterMatch
+	query := r.db.NewSelect().
+		Model(&matches).
+		Relation("Product").
+		Relation("Master").
+		Where("pmm.review_status = ?", models.MatchReviewStatusPending).
+		Order("pmm.confidence DESC", "pmm.created_at ASC", "pmm.id ASC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (r *productMasterRepository) AcceptMatch(ctx context.Context, matchID int64, reviewerID string, reviewedAt time.Time) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		match, err := lockPendingMatch(ctx, tx, matchID)
+		if err != nil {
+			return err
+		}
+
+		match.MarkReviewed(models.MatchReviewStatusApproved, reviewerID, reviewedAt)
+		if err := updateMatchReview(ctx, tx, match); err != nil {
+			return err
+		}
+
+		return linkReviewedProduct(ctx, tx, match.ProductID, match.ProductMasterID, reviewedAt)
+	})
+}
+
+func (r *productMasterRepository) RejectMatch(ctx context.Context, matchID int64, rejectionKey, reviewerID string, reviewedAt time.Time) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := rejectPendingMatch(ctx, tx, matchID, rejectionKey, reviewerID, reviewedAt)
+		return err
+	})
+}
+
+func (r *productMasterRepository) ReassignMatch(ctx context.Context, matchID, masterID int64, rejectionKey, reviewerID string, reviewedAt time.Time) (*models.ProductMasterMatch, error) {
+	reassigned := new(models.ProductMasterMatch)
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		master := new(models.ProductMaster)
+		if err := tx.NewSelect().
+			Model(master).
+			Where("pm.id = ?", masterID).
+			Scan(ctx); err != nil {
+			return err
+		}
+		if !master.IsActive() {
+			return fmt.Errorf("%w: %d", productmaster.ErrMasterNotActive, master.ID)
+		}
+
+		rejected, err := rejectPendingMatch(ctx, tx, matchID, rejectionKey, reviewerID, reviewedAt)
+		if err != nil {
+			return err
+		}
+
+		*reassigned = models.ProductMasterMatch{
+			ProductID:       rejected.ProductID,
+			ProductMasterID: masterID,
+			Confidence:      1.0,
+			MatchType:       "manual",
+		}
+		reassigned.MarkReviewed(models.MatchReviewStatusApproved, reviewerID, reviewedAt)
+		reassigned.CreatedAt = reviewedAt
+		if _, err := tx.NewInsert().
+			Model(reassigned).
+			On("CONFLICT (product_id, product_master_id) DO UPDATE").
+			Set("confidence = EXCLUDED.confidence").
+			Set("match_type = EXCLUDED.match_type").
+			Set("review_status = EXCLUDED.review_status").
+			Set("reviewed_by = EXCLUDED.reviewed_by").
+			Set("reviewed_at = EXCLUDED.reviewed_at").
+			Set("updated_at = EXCLUDED.updated_at").
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create reassigned match: %w", err)
+		}
+
+		return linkReviewedProduct(ctx, tx, rejected.ProductID, masterID, reviewedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reassigned, nil
+}
+
+// lockPendingMatch selects a match for update and checks it still awaits review
+func lockPendingMatch(ctx context.Context, tx bun.Tx, matchID int64) (*models.ProductMasterMatch, error) {
+	match := new(models.ProductMasterMatch)
+	if err := tx.NewSelect().
+		Model(match).
+		Where("pmm.id = ?", matchID).
+		For("UPDATE").
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	if !match.IsPending() {
+		return nil, productmaster.ErrMatchAlreadyReviewed
+	}
+	return match, nil
+}
+
+func updateMatchReview(ctx context.Context, tx bun.Tx, match *models.ProductMasterMatch) error {
+	if _, err := tx.NewUpdate().
+		Model(match).
+		Column("review_status", "reviewed_by", "reviewed_at", "updated_at").
+		WherePK().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update match review: %w", err)
+	}
+	return nil
+}
+
+// rejectPendingMatch marks the match as rejected, remembers the pair as a
+// negative example and detaches the product if it was already linked to the
+// rejected master
+func rejectPendingMatch(ctx context.Context, tx bun.Tx, matchID int64, rejectionKey, reviewerID string, reviewedAt time.Time) (*models.ProductMasterMatch, error) {
+	match, err := lockPendingMatch(ctx, tx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	match.MarkReviewed(models.MatchReviewStatusRejected, reviewerID, reviewedAt)
+	if err := updateMatchReview(ctx, tx, match); err != nil {
+		return nil, err
+	}
+
+	if rejectionKey != "" {
+		productID := match.ProductID
+		if _, err := tx.NewInsert().
+			Model(&models.ProductMasterMatchRejection{
+				ProductMasterID: match.ProductMasterID,
+				NormalizedName:  rejectionKey,
+				ProductID:       &productID,
+				RejectedBy:      &reviewerID,
+				CreatedAt:       reviewedAt,
+			}).
+			On("CONFLICT (product_master_id, normalized_name) DO NOTHING").
+			Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to record match rejection: %w", err)
+		}
+	}
+
+	// Release the product from the review queue so the worker matches it again
+	if _, err := tx.NewUpdate().
+		Model((*models.Product)(nil)).
+		Set("product_master_id = CASE WHEN product_master_id = ? THEN NULL ELSE product_master_id END", match.ProductMasterID).
+		Set("requires_review = ?", false).
+		Set("updated_at = ?", reviewedAt).
+		Where("id = ?", match.ProductID).
+		Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to release product: %w", err)
+	}
+
+	if err := recomputeMasterStatistics(ctx, tx, match.ProductMasterID); err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+// linkReviewedProduct links the product to the master a reviewer confirmed
+func linkReviewedProduct(ctx context.Context, tx bun.Tx, productID, masterID int64, reviewedAt time.Time) error {
+	var previousMasterID sql.NullInt64
+	if err := tx.NewSelect().
+		Model((*models.Product)(nil)).
+		Column("p.product_master_id").
+		Where("p.id = ?", productID).
+		Scan(ctx, &previousMasterID); err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+
+	if _, err := tx.NewUpdate().
+		Model((*models.Product)(nil)).
+		Set("product_master_id = ?", masterID).
+		Set("requires_review = ?", false).
+		Set("updated_at = ?", reviewedAt).
+		Where("id = ?", productID).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to link product: %w", err)
+	}
+
+	if previousMasterID.Valid && previousMasterID.Int64 != masterID {
+		if err := recomputeMasterStatistics(ctx, tx, previousMasterID.Int64); err != nil {
+			return err
+		}
+	}
+	return recomputeMasterStatistics(ctx, tx, masterID)
+}
+
 func (r *productMasterRepository) GetMasterProductCounts(ctx context.Context) ([]productmaster.MasterProductCount, error) {
 	var counts []productmaster.MasterProductCount
 	query := r.db.NewSelect().
@@ -130,6 +130,34 @@ func (r *productRepository) CreateBatch(ctx context.Context, products []*models.
 	return r.base.CreateMany(ctx, products)
 }
 
+// ReplaceByFlyerPage atomically swaps the products extracted from a flyer page,
+// so that re-processing a page never leaves duplicates behind.
+func (r *productRepository) ReplaceByFlyerPage(ctx context.Context, flyerPageID int, products []*models.Product) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			TableExpr("product_master_matches").
+			Where("product_id IN (SELECT id FROM products WHERE flyer_page_id = ?)", flyerPageID).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to delete matches for page %d: %w", flyerPageID, err)
+		}
+
+		if _, err := tx.NewDelete().
+			Model((*models.Product)(nil)).
+			Where("flyer_page_id = ?", flyerPageID).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to delete products for page %d: %w", flyerPageID, err)
+		}
+
+		if len(products) == 0 {
+			return nil
+		}
+		if _, err := tx.NewInsert().Model(&products).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to insert products for page %d: %w", flyerPageID, err)
+		}
+		return nil
+	})
+}
+
 func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
 	return r.base.Update(ctx, product)
 }
@@ -202,7 +230,19 @@ func applyProductPagination(q *bun.SelectQuery, filters *product.Filters) *bun.S
 	if orderDir == "" {
 		orderDir = "DESC"
 	}
-	return q.Order(fmt.Sprintf("p.%s %s", orderBy, orderDir))
+	if orderBy == "normalized_unit_price" {
+		// Prices per kg, l and vnt. do not compare, so products are grouped by unit
+		// base, the base most of them share first, with unknown sizes last
+		q.OrderExpr("p.normalized_unit_price IS NULL").
+			OrderExpr("COUNT(*) OVER (PARTITION BY p.unit_base) DESC").
+			OrderExpr("p.unit_base")
+	}
+	q.Order(fmt.Sprintf("p.%s %s", orderBy, orderDir))
+	if orderBy != "id" {
+		// Keep offset pagination stable when the sort column has ties
+		q.Order("p.id ASC")
+	}
+	return q
 }
 
 func applyStoreFilter(q *bun.SelectQuery, storeIDs []int) *bun.SelectQuery {
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/kainuguru/kainuguru-api/internal/product"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+func TestApplyProductPagination_UnitPriceGroupsUnitBases(t *testing.T) {
+	ctx := context.Background()
+	sqldb, err := sql.Open(sqliteshim.DriverName(), "file::memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE products (id INTEGER PRIMARY KEY, unit_base TEXT, normalized_unit_price REAL)`); err != nil {
+		t.Fatalf("failed to create products: %v", err)
+	}
+	// The kilogram is the cheapest unit price, but most products are sold by the litre
+	if _, err := db.ExecContext(ctx, `INSERT INTO products (id, unit_base, normalized_unit_price) VALUES
+		(1, 'l', 1.50), (2, 'kg', 0.50), (3, NULL, NULL), (4, 'l', 0.90), (5, 'vnt', 0.10), (6, 'kg', 0.70), (7, 'l', 1.20)`); err != nil {
+		t.Fatalf("failed to insert products: %v", err)
+	}
+
+	var ids []int
+	q := db.NewSelect().TableExpr("products AS p").Column("p.id")
+	q = applyProductPagination(q, &product.Filters{OrderBy: "normalized_unit_price", OrderDir: "ASC", Limit: 10})
+	if err := q.Scan(ctx, &ids); err != nil {
+		t.Fatalf("failed to select products: %v", err)
+	}
+
+	want := []int{4, 7, 1, 2, 6, 5, 3}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("order = %v, want %v: litres, then kilograms, then pieces, then unknown sizes", ids, want)
+	}
+}
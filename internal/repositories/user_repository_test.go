@@ -103,6 +103,7 @@ CREATE TABLE users (
 	full_name TEXT,
 	preferred_language TEXT,
 	is_active BOOLEAN NOT NULL DEFAULT 1,
+	loyalty_programs TEXT,
 	oauth_provider TEXT,
 	oauth_id TEXT,
 	avatar_url TEXT,
@@ -51,6 +51,7 @@ type ExtractedProduct struct {
 	Discount        string                     `json:"discount,omitempty"`
 	DiscountType    string                     `json:"discount_type,omitempty"` // percentage | absolute | bundle | loyalty
 	SpecialDiscount string                     `json:"special_discount,omitempty"`
+	Barcode         string                     `json:"barcode,omitempty"` // EAN/GTIN digits when printed
 	Brand           string                     `json:"brand,omitempty"`
 	Category        string                     `json:"category,omitempty"`
 	Confidence      float64                    `json:"confidence,omitempty"`
@@ -67,6 +68,7 @@ type Promotion struct {
 	CategoryGuessLT  string                     `json:"category_guess_lt,omitempty"`
 	Unit             string                     `json:"unit,omitempty"`
 	UnitSize         string                     `json:"unit_size,omitempty"`
+	Barcode          string                     `json:"barcode,omitempty"` // EAN/GTIN digits when printed
 	PriceEUR         *string                    `json:"price_eur,omitempty"`
 	OriginalPriceEUR *string                    `json:"original_price_eur,omitempty"`
 	PricePerUnitEUR  *string                    `json:"price_per_unit_eur,omitempty"`
@@ -75,6 +77,7 @@ type Promotion struct {
 	DiscountType     string                     `json:"discount_type,omitempty"` // percentage | absolute | bundle | loyalty
 	SpecialTags      []string                   `json:"special_tags,omitempty"`
 	LoyaltyRequired  bool                       `json:"loyalty_required"`
+	PriceTier        string                     `json:"price_tier,omitempty"` // regular | card | app
 	BundleDetails    string                     `json:"bundle_details,omitempty"`
 	BoundingBox      *models.ProductBoundingBox `json:"bounding_box,omitempty"`
 	PagePosition     *models.ProductPosition    `json:"page_position,omitempty"`
@@ -111,14 +114,34 @@ type ExtractionResult struct {
 	RawResponse    string        `json:"raw_response,omitempty"`
 }
 
+// VisionClient is the subset of the OpenAI client used for extraction (satisfied by *openai.Client).
+type VisionClient interface {
+	AnalyzeImage(ctx context.Context, imageURL, prompt string) (*openai.VisionResponse, error)
+	AnalyzeImageWithBase64(ctx context.Context, base64Image, prompt string) (*openai.VisionResponse, error)
+}
+
 // ProductExtractor handles AI-powered promotion extraction from flyer images.
 type ProductExtractor struct {
 	config        ExtractorConfig
-	openaiClient  *openai.Client
+	openaiClient  VisionClient
 	promptBuilder *PromptBuilder
 }
 
 func NewProductExtractor(config ExtractorConfig) *ProductExtractor {
+	return NewProductExtractorWithClient(config, NewOpenAIClient(config))
+}
+
+// NewProductExtractorWithClient allows injecting a custom vision client (useful for tests).
+func NewProductExtractorWithClient(config ExtractorConfig, client VisionClient) *ProductExtractor {
+	return &ProductExtractor{
+		config:        config,
+		openaiClient:  client,
+		promptBuilder: NewPromptBuilder(),
+	}
+}
+
+// NewOpenAIClient builds the OpenAI client used by the extractor.
+func NewOpenAIClient(config ExtractorConfig) *openai.Client {
 	openaiConfig := openai.DefaultClientConfig(config.OpenAIAPIKey)
 	openaiConfig.Model = config.Model
 	openaiConfig.MaxTokens = config.MaxTokens
@@ -126,12 +149,7 @@ func NewProductExtractor(config ExtractorConfig) *ProductExtractor {
 	openaiConfig.Timeout = config.Timeout
 	openaiConfig.MaxRetries = config.MaxRetries
 	openaiConfig.RetryDelay = config.RetryDelay
-
-	return &ProductExtractor{
-		config:        config,
-		openaiClient:  openai.NewClient(openaiConfig),
-		promptBuilder: NewPromptBuilder(),
-	}
+	return openai.NewClient(openaiConfig)
 }
 
 // -------------------- Public entrypoints ------------------------------------
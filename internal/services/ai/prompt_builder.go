@@ -50,6 +50,7 @@ func (pb *PromptBuilder) Schema() string {
       "category_guess_lt": "one from fixed list|null",
       "unit": "kg|g|l|ml|vnt.|pak.|null",
       "unit_size": "e.g., '125 g'|'1 kg'|null",
+      "barcode": "EAN/GTIN digits printed in the module (8, 12, 13 or 14 digits)|null",
       "price_eur": "X,XX €|null",
       "original_price_eur": "X,XX €|null",
       "price_per_unit_eur": "X,XX €|null",
@@ -58,6 +59,7 @@ func (pb *PromptBuilder) Schema() string {
       "discount_type": "percentage|absolute|bundle|loyalty|null",
       "special_tags": ["SUPER KAINA","TIK","MEILĖ IKI","IKI EXPRESS","1+1","2+1","3+1","..."],
       "loyalty_required": "true|false",
+      "price_tier": "regular|card|app (card = Aitvaras/Mano Rimi/IKI card price, app = price only in the store app)",
       "bundle_details": "e.g., '1+1','2+1','3 už 2'|null",
       "bounding_box": {"x": 0.0, "y": 0.0, "width": 0.0, "height": 0.0},
       "page_position": {"row": 0, "column": 0, "zone": "main|header|footer|sidebar"},
@@ -129,6 +131,7 @@ For each module, return:
 - price_eur (formatted "X,XX €", or null if not visible)
 - discount_text (as printed if percent, e.g., "-30 %%")
 - loyalty_required (true if loyalty heart/MEILĖ IKI visible)
+- price_tier ("card" if price_eur needs a loyalty card, "app" if only in the store app, otherwise "regular")
 - special_tags (array: ["SUPER KAINA","TIK","MEILĖ IKI",etc.])
 - bounding_box (normalized 0..1)
 - page_position: {"row": 1-based row from top, "column": 1-based column from left, "zone": "main|header|footer|sidebar"}
@@ -148,6 +151,7 @@ func (pb *PromptBuilder) FillDetailsPrompt(storeCode string, pageNumber int) str
 You are given the page image and a JSON list named PROMOTION_BOXES that contains bounding boxes and coarse data found in pass 1.
 For each box, read ONLY inside that rectangle and fill or correct fields:
 - brand, unit, unit_size
+- barcode (EAN/GTIN digits, only if a barcode number is printed inside the box)
 - price_eur, original_price_eur, price_per_unit_eur (IF AND ONLY IF these are printed inside the box)
 - discount_pct and discount_text
 - discount_type: percentage|absolute|bundle|loyalty
@@ -1,12 +1,19 @@
 package archive
 
 import (
+	"compress/gzip"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"io"
+	"path"
 	"time"
 
-	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
 )
 
 // ArchiverService handles data archival operations
@@ -23,7 +30,7 @@ type ArchiverService interface {
 	// ArchiveCompletedExtractionJobs archives completed extraction jobs
 	ArchiveCompletedExtractionJobs(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error)
 
-	// RestoreArchivedData restores archived data by ID or date range
+	// RestoreArchivedData verifies an archive and inserts its rows back into their tables
 	RestoreArchivedData(ctx context.Context, archiveID string, dataType ArchiveDataType) error
 
 	// ListArchives returns a list of available archives
@@ -35,8 +42,8 @@ type ArchiverService interface {
 	// DeleteArchive permanently deletes an archive
 	DeleteArchive(ctx context.Context, archiveID string) error
 
-	// ScheduleRegularArchival sets up regular archival jobs
-	ScheduleRegularArchival(ctx context.Context, config *ArchivalConfig) error
+	// DeleteExpiredArchives deletes the archives past their retention
+	DeleteExpiredArchives(ctx context.Context) ([]*ArchiveMetadata, error)
 
 	// GetArchivalStatistics returns statistics about archived data
 	GetArchivalStatistics(ctx context.Context) (*ArchivalStatistics, error)
@@ -80,39 +87,30 @@ const (
 	ArchiveStatusFailed     ArchiveStatus = "FAILED"
 )
 
-// ArchiveMetadata contains metadata about an archived dataset
+// ArchiveMetadata is the manifest of an archive, kept in the archives table
 type ArchiveMetadata struct {
-	ID               string          `json:"id"`
-	DataType         ArchiveDataType `json:"data_type"`
-	RecordCount      int             `json:"record_count"`
-	StartDate        time.Time       `json:"start_date"`
-	EndDate          time.Time       `json:"end_date"`
-	ArchiveSize      int64           `json:"archive_size_bytes"`
-	StoragePath      string          `json:"storage_path"`
-	CompressionType  string          `json:"compression_type"`
-	CompressionRatio float64         `json:"compression_ratio"`
-	Checksum         string          `json:"checksum"`
-	IsEncrypted      bool            `json:"is_encrypted"`
-	RetentionUntil   time.Time       `json:"retention_until"`
-	CreatedAt        time.Time       `json:"created_at"`
-	CreatedBy        string          `json:"created_by"`
-	Status           ArchiveStatus   `json:"status"`
-	Restorable       bool            `json:"restorable"`
-	Tags             []string        `json:"tags"`
-}
-
-// ArchivalConfig contains configuration for regular archival operations
-type ArchivalConfig struct {
-	DataType        ArchiveDataType `json:"data_type"`
-	RetentionPeriod time.Duration   `json:"retention_period"`
-	Schedule        string          `json:"schedule"` // Cron expression
-	Enabled         bool            `json:"enabled"`
-	CompressionType string          `json:"compression_type"`
-	EncryptData     bool            `json:"encrypt_data"`
-	StorageLocation string          `json:"storage_location"`
-	MaxArchiveSize  int64           `json:"max_archive_size_bytes"`
-	NotifyOnError   bool            `json:"notify_on_error"`
-	CleanupAfter    time.Duration   `json:"cleanup_after"`
+	bun.BaseModel `bun:"table:archives,alias:a"`
+
+	ID               string          `bun:"id,pk" json:"id"`
+	DataType         ArchiveDataType `bun:"data_type,notnull" json:"data_type"`
+	RecordCount      int             `bun:"record_count,notnull" json:"record_count"`
+	StartDate        time.Time       `bun:"start_date,nullzero" json:"start_date"`
+	EndDate          time.Time       `bun:"end_date,nullzero" json:"end_date"`
+	ArchiveSize      int64           `bun:"archive_size,notnull" json:"archive_size_bytes"`
+	UncompressedSize int64           `bun:"uncompressed_size,notnull" json:"uncompressed_size_bytes"`
+	StoragePath      string          `bun:"storage_path,notnull" json:"storage_path"`
+	CompressionType  string          `bun:"compression_type,notnull" json:"compression_type"`
+	CompressionRatio float64         `bun:"compression_ratio,notnull" json:"compression_ratio"`
+	Checksum         string          `bun:"checksum,nullzero" json:"checksum"`
+	IsEncrypted      bool            `bun:"is_encrypted,notnull" json:"is_encrypted"`
+	RetentionUntil   time.Time       `bun:"retention_until,notnull" json:"retention_until"`
+	CreatedAt        time.Time       `bun:"created_at,notnull" json:"created_at"`
+	CreatedBy        string          `bun:"created_by,notnull" json:"created_by"`
+	Status           ArchiveStatus   `bun:"status,notnull" json:"status"`
+	Restorable       bool            `bun:"restorable,notnull" json:"restorable"`
+	Tags             []string        `bun:"tags,type:jsonb" json:"tags"`
+	Error            string          `bun:"error,nullzero" json:"error,omitempty"`
+	RestoredAt       *time.Time      `bun:"restored_at" json:"restored_at,omitempty"`
 }
 
 // ArchivalStatistics contains statistics about archival operations
@@ -165,177 +163,160 @@ type ArchiveFilter struct {
 	Restorable *bool
 }
 
+// ProductPartitions manages the partitions of products; partition.Manager
+// implements it
+type ProductPartitions interface {
+	// Partitioned reports whether products is partitioned
+	Partitioned() bool
+	// EnsureForDates creates the partitions products valid from dates go into
+	EnsureForDates(ctx context.Context, dates ...time.Time) error
+	// Detached lists the partitions detached from products past retention
+	Detached(ctx context.Context) ([]string, error)
+	// DropDetached deletes a detached partition
+	DropDetached(ctx context.Context, name string) error
+}
+
+var _ ProductPartitions = (*partition.Manager)(nil)
+
 // archiverService implements ArchiverService
 type archiverService struct {
-	priceHistoryRepo PriceHistoryRepository
-	flyerRepo        FlyerRepository
-	productRepo      ProductRepository
-	extractionRepo   ExtractionJobRepository
-	storage          ArchiveStorage
-	config           *ArchivalServiceConfig
+	db         *bun.DB
+	storage    ArchiveStorage
+	partitions ProductPartitions
+	config     *ArchivalServiceConfig
+	now        func() time.Time
 }
 
 // ArchivalServiceConfig contains service configuration
 type ArchivalServiceConfig struct {
-	StorageBasePath  string
+	// CompressionLevel is the gzip level; zero means gzip's default
 	CompressionLevel int
-	EncryptionKey    string
-	MaxFileSize      int64
-	ChunkSize        int
-	RetentionPeriod  time.Duration
-}
-
-// Repository interfaces needed by the archiver
-type PriceHistoryRepository interface {
-	GetOldPriceHistory(ctx context.Context, olderThan time.Time) ([]*models.PriceHistory, error)
-	DeletePriceHistory(ctx context.Context, ids []int64) error
-	BulkArchivePriceHistory(ctx context.Context, entries []*models.PriceHistory) error
-}
-
-type FlyerRepository interface {
-	GetOldFlyers(ctx context.Context, olderThan time.Time) ([]*models.Flyer, error)
-	DeleteFlyers(ctx context.Context, ids []int) error
-	BulkArchiveFlyers(ctx context.Context, flyers []*models.Flyer) error
-}
-
-type ProductRepository interface {
-	GetOldProducts(ctx context.Context, olderThan time.Time) ([]*models.Product, error)
-	DeleteProducts(ctx context.Context, ids []int) error
-	BulkArchiveProducts(ctx context.Context, products []*models.Product) error
-}
-
-type ExtractionJobRepository interface {
-	GetCompletedJobs(ctx context.Context, olderThan time.Time) ([]*models.ExtractionJob, error)
-	DeleteExtractionJobs(ctx context.Context, ids []int64) error
-	BulkArchiveJobs(ctx context.Context, jobs []*models.ExtractionJob) error
+	// ChunkSize is how many rows are read, deleted and restored at a time
+	ChunkSize int
+	// RetentionPeriod is how long archives are kept before they expire
+	RetentionPeriod time.Duration
+	// CreatedBy is recorded on the archives this service writes
+	CreatedBy string
 }
 
-// ArchiveStorage handles the physical storage of archives
+// ArchiveStorage handles the physical storage of archives. Paths are relative
+// and slash separated.
 type ArchiveStorage interface {
-	Store(ctx context.Context, data []byte, path string) error
-	Retrieve(ctx context.Context, path string) ([]byte, error)
+	// Create returns a writer for a new archive; the archive exists once the
+	// writer is closed
+	Create(ctx context.Context, path string) (io.WriteCloser, error)
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
 	Delete(ctx context.Context, path string) error
 	List(ctx context.Context, prefix string) ([]string, error)
 	GetSize(ctx context.Context, path string) (int64, error)
 	GetHealth(ctx context.Context) (*StorageHealthStatus, error)
 }
 
-// NewArchiverService creates a new archiver service
+const (
+	defaultChunkSize       = 1000
+	defaultRetentionPeriod = 365 * 24 * time.Hour
+	defaultCreatedBy       = "system"
+	recentOperationsLimit  = 10
+)
+
+// NewArchiverService creates a new archiver service. Partitioned products are
+// archived from their detached partitions, and restored products get their
+// partitions from partitions; it may be nil when products aren't partitioned.
 func NewArchiverService(
-	priceHistoryRepo PriceHistoryRepository,
-	flyerRepo FlyerRepository,
-	productRepo ProductRepository,
-	extractionRepo ExtractionJobRepository,
+	db *bun.DB,
 	storage ArchiveStorage,
+	partitions ProductPartitions,
 	config *ArchivalServiceConfig,
 ) ArchiverService {
+	cfg := ArchivalServiceConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.CompressionLevel == 0 {
+		cfg.CompressionLevel = gzip.DefaultCompression
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = defaultChunkSize
+	}
+	if cfg.RetentionPeriod <= 0 {
+		cfg.RetentionPeriod = defaultRetentionPeriod
+	}
+	if cfg.CreatedBy == "" {
+		cfg.CreatedBy = defaultCreatedBy
+	}
+
 	return &archiverService{
-		priceHistoryRepo: priceHistoryRepo,
-		flyerRepo:        flyerRepo,
-		productRepo:      productRepo,
-		extractionRepo:   extractionRepo,
-		storage:          storage,
-		config:           config,
+		db:         db,
+		storage:    storage,
+		partitions: partitions,
+		config:     &cfg,
+		now:        time.Now,
 	}
 }
 
 func (s *archiverService) ArchiveOldPrices(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
-	startTime := time.Now()
-	cutoffDate := time.Now().Add(-olderThan)
+	return s.archive(ctx, ArchiveTypePriceHistory, olderThan)
+}
 
-	// Get old price history records
-	oldPrices, err := s.priceHistoryRepo.GetOldPriceHistory(ctx, cutoffDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get old price history: %w", err)
-	}
+// ArchiveOldFlyers archives flyers already marked archived, with their pages,
+// once none of their products or prices remain
+func (s *archiverService) ArchiveOldFlyers(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
+	return s.archive(ctx, ArchiveTypeFlyers, olderThan)
+}
 
-	if len(oldPrices) == 0 {
-		return &ArchivalResult{
-			DataType:        ArchiveTypePriceHistory,
-			RecordsArchived: 0,
-			Status:          ArchiveStatusCompleted,
-			Duration:        time.Since(startTime),
-			CreatedAt:       startTime,
-		}, nil
+// ArchiveOldProducts archives products whose offers ended before olderThan.
+// Partitioned products leave products a week at a time instead, once the
+// partition manager detaches the week past its retention: then the detached
+// partitions are archived and dropped, and olderThan doesn't apply.
+func (s *archiverService) ArchiveOldProducts(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
+	if s.partitions == nil || !s.partitions.Partitioned() {
+		return s.archive(ctx, ArchiveTypeProducts, olderThan)
 	}
 
-	// Create archive
-	archiveID := s.generateArchiveID(ArchiveTypePriceHistory, startTime)
-	archivePath := s.getArchivePath(archiveID, ArchiveTypePriceHistory)
-
-	// Serialize and compress data
-	archiveData, err := s.serializeAndCompress(oldPrices)
+	detached, err := s.partitions.Detached(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize price data: %w", err)
-	}
-
-	// Store archive
-	if err := s.storage.Store(ctx, archiveData, archivePath); err != nil {
-		return nil, fmt.Errorf("failed to store archive: %w", err)
+		return nil, err
 	}
-
-	// Delete original records
-	priceIDs := make([]int64, len(oldPrices))
-	for i, price := range oldPrices {
-		priceIDs[i] = price.ID
-	}
-
-	if err := s.priceHistoryRepo.DeletePriceHistory(ctx, priceIDs); err != nil {
-		// Archive was created but deletion failed - this is recoverable
+	startTime := s.now()
+	if len(detached) == 0 {
 		return &ArchivalResult{
-			ArchiveID:       archiveID,
-			DataType:        ArchiveTypePriceHistory,
-			RecordsArchived: len(oldPrices),
-			ArchiveSize:     int64(len(archiveData)),
-			Duration:        time.Since(startTime),
-			Status:          ArchiveStatusCompleted,
-			Error:           fmt.Sprintf("Archive created but deletion failed: %v", err),
-			CreatedAt:       startTime,
+			DataType:  ArchiveTypeProducts,
+			Status:    ArchiveStatusCompleted,
+			Duration:  time.Since(startTime),
+			CreatedAt: startTime,
 		}, nil
 	}
 
-	// Calculate dates
-	var startDate, endDate time.Time
-	if len(oldPrices) > 0 {
-		startDate = oldPrices[0].RecordedAt
-		endDate = oldPrices[0].RecordedAt
-		for _, price := range oldPrices {
-			if price.RecordedAt.Before(startDate) {
-				startDate = price.RecordedAt
-			}
-			if price.RecordedAt.After(endDate) {
-				endDate = price.RecordedAt
-			}
-		}
-	}
-
-	return &ArchivalResult{
-		ArchiveID:        archiveID,
-		DataType:         ArchiveTypePriceHistory,
-		RecordsArchived:  len(oldPrices),
-		StartDate:        startDate,
-		EndDate:          endDate,
-		ArchiveSize:      int64(len(archiveData)),
-		CompressionRatio: s.calculateCompressionRatio(len(oldPrices), len(archiveData)),
-		Duration:         time.Since(startTime),
-		StoragePath:      archivePath,
-		Status:           ArchiveStatusCompleted,
-		CreatedAt:        startTime,
-	}, nil
+	ds := *datasets[ArchiveTypeProducts]
+	ds.detached = detached
+	return s.archiveDataset(ctx, ArchiveTypeProducts, &ds, startTime, startTime)
 }
 
-func (s *archiverService) ArchiveOldFlyers(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
-	startTime := time.Now()
-	cutoffDate := time.Now().Add(-olderThan)
+func (s *archiverService) ArchiveCompletedExtractionJobs(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
+	return s.archive(ctx, ArchiveTypeExtractionJobs, olderThan)
+}
 
-	oldFlyers, err := s.flyerRepo.GetOldFlyers(ctx, cutoffDate)
+// archive writes the rows of a data type older than olderThan to a new archive,
+// verifies it against its manifest and then deletes the rows
+func (s *archiverService) archive(ctx context.Context, dataType ArchiveDataType, olderThan time.Duration) (*ArchivalResult, error) {
+	ds, err := datasetFor(dataType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get old flyers: %w", err)
+		return nil, apperrors.Validation(err.Error())
 	}
 
-	if len(oldFlyers) == 0 {
+	startTime := s.now()
+	return s.archiveDataset(ctx, dataType, ds, startTime, startTime.Add(-olderThan))
+}
+
+// archiveDataset archives the rows of ds older than cutoff
+func (s *archiverService) archiveDataset(ctx context.Context, dataType ArchiveDataType, ds *dataset, startTime, cutoff time.Time) (*ArchivalResult, error) {
+	count, err := ds.countOld(ctx, s.db, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
 		return &ArchivalResult{
-			DataType:        ArchiveTypeFlyers,
+			DataType:        dataType,
 			RecordsArchived: 0,
 			Status:          ArchiveStatusCompleted,
 			Duration:        time.Since(startTime),
@@ -343,231 +324,420 @@ func (s *archiverService) ArchiveOldFlyers(ctx context.Context, olderThan time.D
 		}, nil
 	}
 
-	archiveID := s.generateArchiveID(ArchiveTypeFlyers, startTime)
-	archivePath := s.getArchivePath(archiveID, ArchiveTypeFlyers)
+	archiveID := s.generateArchiveID(dataType, startTime)
+	meta := &ArchiveMetadata{
+		ID:              archiveID,
+		DataType:        dataType,
+		StoragePath:     s.getArchivePath(archiveID, dataType),
+		CompressionType: "gzip",
+		RetentionUntil:  startTime.Add(s.config.RetentionPeriod),
+		CreatedAt:       startTime,
+		CreatedBy:       s.config.CreatedBy,
+		Status:          ArchiveStatusInProgress,
+		Restorable:      true,
+		Tags:            []string{string(dataType)},
+	}
+	if _, err := s.db.NewInsert().Model(meta).Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to record archive %s: %w", archiveID, err)
+	}
 
-	archiveData, err := s.serializeAndCompress(oldFlyers)
+	ids, err := s.write(ctx, ds, cutoff, meta)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize flyer data: %w", err)
+		return nil, s.fail(ctx, meta, err)
+	}
+	if err := verifyArchive(s.opener(ctx, meta), meta, nil); err != nil {
+		return nil, s.fail(ctx, meta, err)
 	}
 
-	if err := s.storage.Store(ctx, archiveData, archivePath); err != nil {
-		return nil, fmt.Errorf("failed to store archive: %w", err)
+	result := &ArchivalResult{
+		ArchiveID:        archiveID,
+		DataType:         dataType,
+		RecordsArchived:  meta.RecordCount,
+		StartDate:        meta.StartDate,
+		EndDate:          meta.EndDate,
+		ArchiveSize:      meta.ArchiveSize,
+		CompressionRatio: meta.CompressionRatio,
+		StoragePath:      meta.StoragePath,
+		Status:           ArchiveStatusCompleted,
+		CreatedAt:        startTime,
 	}
 
-	flyerIDs := make([]int, len(oldFlyers))
-	for i, flyer := range oldFlyers {
-		flyerIDs[i] = flyer.ID
+	if err := s.deleteArchived(ctx, ds, ids); err != nil {
+		// Archive was created but deletion failed - this is recoverable, a
+		// restore skips the rows still present
+		meta.Error = fmt.Sprintf("Archive created but deletion failed: %v", err)
+		result.Error = meta.Error
 	}
 
-	if err := s.flyerRepo.DeleteFlyers(ctx, flyerIDs); err != nil {
-		return &ArchivalResult{
-			ArchiveID:       archiveID,
-			DataType:        ArchiveTypeFlyers,
-			RecordsArchived: len(oldFlyers),
-			ArchiveSize:     int64(len(archiveData)),
-			Duration:        time.Since(startTime),
-			Status:          ArchiveStatusCompleted,
-			Error:           fmt.Sprintf("Archive created but deletion failed: %v", err),
-			CreatedAt:       startTime,
-		}, nil
+	meta.Status = ArchiveStatusCompleted
+	if _, err := s.db.NewUpdate().Model(meta).WherePK().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to complete archive %s: %w", archiveID, err)
 	}
 
-	return &ArchivalResult{
-		ArchiveID:        archiveID,
-		DataType:         ArchiveTypeFlyers,
-		RecordsArchived:  len(oldFlyers),
-		ArchiveSize:      int64(len(archiveData)),
-		CompressionRatio: s.calculateCompressionRatio(len(oldFlyers), len(archiveData)),
-		Duration:         time.Since(startTime),
-		StoragePath:      archivePath,
-		Status:           ArchiveStatusCompleted,
-		CreatedAt:        startTime,
-	}, nil
+	result.Duration = time.Since(startTime)
+	return result, nil
 }
 
-func (s *archiverService) ArchiveOldProducts(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
-	startTime := time.Now()
-	cutoffDate := time.Now().Add(-olderThan)
-
-	oldProducts, err := s.productRepo.GetOldProducts(ctx, cutoffDate)
+// write streams the rows to archive into the archive file, filling in the
+// manifest, and returns the ids of the rows written
+func (s *archiverService) write(ctx context.Context, ds *dataset, cutoff time.Time, meta *ArchiveMetadata) ([]int64, error) {
+	file, err := s.storage.Create(ctx, meta.StoragePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get old products: %w", err)
+		return nil, err
 	}
-
-	if len(oldProducts) == 0 {
-		return &ArchivalResult{
-			DataType:        ArchiveTypeProducts,
-			RecordsArchived: 0,
-			Status:          ArchiveStatusCompleted,
-			Duration:        time.Since(startTime),
-			CreatedAt:       startTime,
-		}, nil
+	writer, err := newArchiveWriter(file, s.config.CompressionLevel)
+	if err != nil {
+		file.Close()
+		return nil, err
 	}
 
-	archiveID := s.generateArchiveID(ArchiveTypeProducts, startTime)
-	archivePath := s.getArchivePath(archiveID, ArchiveTypeProducts)
+	var ids []int64
+	for _, source := range ds.sources() {
+		sourceIDs, err := s.writeSource(ctx, writer, ds, source, cutoff, meta)
+		if err != nil {
+			writer.close()
+			return nil, err
+		}
+		ids = append(ids, sourceIDs...)
+	}
 
-	archiveData, err := s.serializeAndCompress(oldProducts)
+	size, checksum, err := writer.close()
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize product data: %w", err)
+		return nil, err
 	}
+	meta.RecordCount = writer.records
+	meta.ArchiveSize = size
+	meta.UncompressedSize = writer.uncompressed.n
+	meta.Checksum = checksum
+	meta.CompressionRatio = s.calculateCompressionRatio(meta.UncompressedSize, size)
+	return ids, nil
+}
 
-	if err := s.storage.Store(ctx, archiveData, archivePath); err != nil {
-		return nil, fmt.Errorf("failed to store archive: %w", err)
-	}
+// writeSource writes the rows of one source table, and their children, to the
+// archive, widening the manifest's date range, and returns their ids
+func (s *archiverService) writeSource(ctx context.Context, writer *archiveWriter, ds *dataset, source string, cutoff time.Time, meta *ArchiveMetadata) ([]int64, error) {
+	var ids []int64
+	var afterID int64
+	for {
+		rows, err := ds.nextBatch(ctx, s.db, source, cutoff, afterID, s.config.ChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return ids, nil
+		}
 
-	productIDs := make([]int, len(oldProducts))
-	for i, product := range oldProducts {
-		productIDs[i] = product.ID
+		batchIDs := make([]int64, 0, len(rows))
+		for _, row := range rows {
+			id, ok := rowID(row)
+			if !ok {
+				return nil, fmt.Errorf("%s row without an integer id", source)
+			}
+			batchIDs = append(batchIDs, id)
+			if date, ok := rowTime(row, ds.dateColumn); ok {
+				if meta.StartDate.IsZero() || date.Before(meta.StartDate) {
+					meta.StartDate = date
+				}
+				if date.After(meta.EndDate) {
+					meta.EndDate = date
+				}
+			}
+		}
+		afterID = batchIDs[len(batchIDs)-1]
+		ids = append(ids, batchIDs...)
+
+		// Parents go first so a restore inserts them before the rows referencing
+		// them. Rows of detached partitions are restored into their table.
+		if err := writer.write(ds.table, rows); err != nil {
+			return nil, err
+		}
+		for _, child := range ds.children {
+			childRows, err := child.rows(ctx, s.db, batchIDs)
+			if err != nil {
+				return nil, err
+			}
+			if err := writer.write(child.table, childRows); err != nil {
+				return nil, err
+			}
+		}
 	}
+}
 
-	if err := s.productRepo.DeleteProducts(ctx, productIDs); err != nil {
-		return &ArchivalResult{
-			ArchiveID:       archiveID,
-			DataType:        ArchiveTypeProducts,
-			RecordsArchived: len(oldProducts),
-			ArchiveSize:     int64(len(archiveData)),
-			Duration:        time.Since(startTime),
-			Status:          ArchiveStatusCompleted,
-			Error:           fmt.Sprintf("Archive created but deletion failed: %v", err),
-			CreatedAt:       startTime,
-		}, nil
+// deleteArchived deletes archived rows, and their children, in one transaction,
+// or drops the archived detached partitions
+func (s *archiverService) deleteArchived(ctx context.Context, ds *dataset, ids []int64) error {
+	if len(ds.detached) > 0 {
+		for _, name := range ds.detached {
+			if err := s.partitions.DropDetached(ctx, name); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
-	return &ArchivalResult{
-		ArchiveID:        archiveID,
-		DataType:         ArchiveTypeProducts,
-		RecordsArchived:  len(oldProducts),
-		ArchiveSize:      int64(len(archiveData)),
-		CompressionRatio: s.calculateCompressionRatio(len(oldProducts), len(archiveData)),
-		Duration:         time.Since(startTime),
-		StoragePath:      archivePath,
-		Status:           ArchiveStatusCompleted,
-		CreatedAt:        startTime,
-	}, nil
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for start := 0; start < len(ids); start += s.config.ChunkSize {
+			end := start + s.config.ChunkSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if err := ds.deleteRows(ctx, tx, ids[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func (s *archiverService) ArchiveCompletedExtractionJobs(ctx context.Context, olderThan time.Duration) (*ArchivalResult, error) {
-	startTime := time.Now()
-	cutoffDate := time.Now().Add(-olderThan)
+// fail marks an archive failed and removes its file, returning err
+func (s *archiverService) fail(ctx context.Context, meta *ArchiveMetadata, err error) error {
+	meta.Status = ArchiveStatusFailed
+	meta.Restorable = false
+	meta.Error = err.Error()
+	if _, updateErr := s.db.NewUpdate().Model(meta).WherePK().Exec(ctx); updateErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to mark archive %s failed: %w", meta.ID, updateErr))
+	}
+	if deleteErr := s.storage.Delete(ctx, meta.StoragePath); deleteErr != nil {
+		err = errors.Join(err, deleteErr)
+	}
+	return fmt.Errorf("failed to archive %s: %w", meta.DataType, err)
+}
 
-	oldJobs, err := s.extractionRepo.GetCompletedJobs(ctx, cutoffDate)
+// RestoreArchivedData verifies an archive against its manifest and inserts its
+// rows back, skipping rows that are still present. The rows are inserted in one
+// transaction, so a failed restore leaves the tables as they were.
+func (s *archiverService) RestoreArchivedData(ctx context.Context, archiveID string, dataType ArchiveDataType) error {
+	meta, err := s.GetArchiveMetadata(ctx, archiveID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get completed jobs: %w", err)
+		return err
 	}
-
-	if len(oldJobs) == 0 {
-		return &ArchivalResult{
-			DataType:        ArchiveTypeExtractionJobs,
-			RecordsArchived: 0,
-			Status:          ArchiveStatusCompleted,
-			Duration:        time.Since(startTime),
-			CreatedAt:       startTime,
-		}, nil
+	if meta.DataType != dataType {
+		return apperrors.ValidationF("archive %s holds %s, not %s", archiveID, meta.DataType, dataType)
 	}
-
-	archiveID := s.generateArchiveID(ArchiveTypeExtractionJobs, startTime)
-	archivePath := s.getArchivePath(archiveID, ArchiveTypeExtractionJobs)
-
-	archiveData, err := s.serializeAndCompress(oldJobs)
+	if meta.Status != ArchiveStatusCompleted || !meta.Restorable {
+		return apperrors.ValidationF("archive %s is %s and can't be restored", archiveID, meta.Status)
+	}
+	ds, err := datasetFor(dataType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize job data: %w", err)
+		return apperrors.Validation(err.Error())
 	}
-
-	if err := s.storage.Store(ctx, archiveData, archivePath); err != nil {
-		return nil, fmt.Errorf("failed to store archive: %w", err)
+	tables := ds.restoreTables()
+
+	// Check the whole archive before inserting anything, and collect the weeks
+	// restored products need partitions for
+	var validFrom []time.Time
+	err = verifyArchive(s.opener(ctx, meta), meta, func(rec record) error {
+		if !tables[rec.Table] {
+			return fmt.Errorf("unexpected %s record", rec.Table)
+		}
+		if rec.Table == "products" {
+			if date, ok := rowTime(rec.Row, "valid_from"); ok {
+				validFrom = append(validFrom, date)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return apperrors.Validation(err.Error())
 	}
-
-	jobIDs := make([]int64, len(oldJobs))
-	for i, job := range oldJobs {
-		jobIDs[i] = job.ID
+	if s.partitions != nil && len(validFrom) > 0 {
+		if err := s.partitions.EnsureForDates(ctx, validFrom...); err != nil {
+			return fmt.Errorf("failed to prepare product partitions: %w", err)
+		}
 	}
 
-	if err := s.extractionRepo.DeleteExtractionJobs(ctx, jobIDs); err != nil {
-		return &ArchivalResult{
-			ArchiveID:       archiveID,
-			DataType:        ArchiveTypeExtractionJobs,
-			RecordsArchived: len(oldJobs),
-			ArchiveSize:     int64(len(archiveData)),
-			Duration:        time.Since(startTime),
-			Status:          ArchiveStatusCompleted,
-			Error:           fmt.Sprintf("Archive created but deletion failed: %v", err),
-			CreatedAt:       startTime,
-		}, nil
+	file, err := s.storage.Open(ctx, meta.StoragePath)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+
+	err = s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var table string
+		var batch []map[string]interface{}
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			err := insertRows(ctx, tx, table, batch)
+			batch = batch[:0]
+			return err
+		}
 
-	return &ArchivalResult{
-		ArchiveID:        archiveID,
-		DataType:         ArchiveTypeExtractionJobs,
-		RecordsArchived:  len(oldJobs),
-		ArchiveSize:      int64(len(archiveData)),
-		CompressionRatio: s.calculateCompressionRatio(len(oldJobs), len(archiveData)),
-		Duration:         time.Since(startTime),
-		StoragePath:      archivePath,
-		Status:           ArchiveStatusCompleted,
-		CreatedAt:        startTime,
-	}, nil
-}
+		err := decodeRecords(file, func(rec record) error {
+			if rec.Table != table || len(batch) >= s.config.ChunkSize {
+				if err := flush(); err != nil {
+					return err
+				}
+				table = rec.Table
+			}
+			batch = append(batch, rec.Row)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return flush()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore archive %s: %w", archiveID, err)
+	}
 
-func (s *archiverService) RestoreArchivedData(ctx context.Context, archiveID string, dataType ArchiveDataType) error {
-	// This would implement data restoration logic
-	// For now, return a not implemented error
-	return fmt.Errorf("restore functionality not yet implemented")
+	restoredAt := s.now()
+	meta.RestoredAt = &restoredAt
+	if _, err := s.db.NewUpdate().Model(meta).Column("restored_at").WherePK().Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record restore of archive %s: %w", archiveID, err)
+	}
+	return nil
 }
 
+// ListArchives returns the archives of a data type, or of all types when
+// dataType is empty, newest first
 func (s *archiverService) ListArchives(ctx context.Context, dataType ArchiveDataType, limit int, offset int) ([]*ArchiveMetadata, error) {
-	// This would implement archive listing logic
-	// For now, return empty list
-	return []*ArchiveMetadata{}, nil
+	archives := []*ArchiveMetadata{}
+	q := s.db.NewSelect().Model(&archives).Order("created_at DESC", "id DESC")
+	if dataType != "" {
+		q = q.Where("data_type = ?", dataType)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if offset > 0 {
+		q = q.Offset(offset)
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+	return archives, nil
 }
 
 func (s *archiverService) GetArchiveMetadata(ctx context.Context, archiveID string) (*ArchiveMetadata, error) {
-	// This would implement metadata retrieval
-	// For now, return not found error
-	return nil, fmt.Errorf("archive not found: %s", archiveID)
+	meta := new(ArchiveMetadata)
+	err := s.db.NewSelect().Model(meta).Where("id = ?", archiveID).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("archive not found: %s", archiveID))
+		}
+		return nil, fmt.Errorf("failed to get archive %s: %w", archiveID, err)
+	}
+	return meta, nil
 }
 
+// DeleteArchive deletes an archive's file and then its manifest
 func (s *archiverService) DeleteArchive(ctx context.Context, archiveID string) error {
-	// This would implement archive deletion
-	// For now, return not implemented error
-	return fmt.Errorf("delete functionality not yet implemented")
+	meta, err := s.GetArchiveMetadata(ctx, archiveID)
+	if err != nil {
+		return err
+	}
+	return s.deleteArchive(ctx, meta)
+}
+
+func (s *archiverService) deleteArchive(ctx context.Context, meta *ArchiveMetadata) error {
+	if err := s.storage.Delete(ctx, meta.StoragePath); err != nil {
+		return err
+	}
+	if _, err := s.db.NewDelete().Model(meta).WherePK().Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete archive %s: %w", meta.ID, err)
+	}
+	return nil
 }
 
-func (s *archiverService) ScheduleRegularArchival(ctx context.Context, config *ArchivalConfig) error {
-	// This would implement scheduling logic
-	// For now, return not implemented error
-	return fmt.Errorf("scheduling functionality not yet implemented")
+// DeleteExpiredArchives deletes the archives past their retention and returns them
+func (s *archiverService) DeleteExpiredArchives(ctx context.Context) ([]*ArchiveMetadata, error) {
+	var expired []*ArchiveMetadata
+	err := s.db.NewSelect().
+		Model(&expired).
+		Where("retention_until < ?", s.now()).
+		Order("retention_until ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired archives: %w", err)
+	}
+
+	deleted := make([]*ArchiveMetadata, 0, len(expired))
+	for _, meta := range expired {
+		if err := s.deleteArchive(ctx, meta); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, meta)
+	}
+	return deleted, nil
 }
 
 func (s *archiverService) GetArchivalStatistics(ctx context.Context) (*ArchivalStatistics, error) {
-	// This would implement statistics gathering
-	// For now, return empty statistics
-	return &ArchivalStatistics{
-		TotalArchives:      0,
-		TotalArchivedBytes: 0,
-		ArchivesByType:     make(map[ArchiveDataType]int),
-		SizeByType:         make(map[ArchiveDataType]int64),
-	}, nil
+	var archives []*ArchiveMetadata
+	if err := s.db.NewSelect().Model(&archives).Order("created_at DESC", "id DESC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load archives: %w", err)
+	}
+
+	stats := &ArchivalStatistics{
+		ArchivesByType:      make(map[ArchiveDataType]int),
+		SizeByType:          make(map[ArchiveDataType]int64),
+		ArchivesSizeByMonth: make(map[string]int64),
+		RecentOperations:    []*RecentArchivalOperation{},
+	}
+
+	var compressionSum float64
+	for _, meta := range archives {
+		if len(stats.RecentOperations) < recentOperationsLimit {
+			stats.RecentOperations = append(stats.RecentOperations, &RecentArchivalOperation{
+				ID:          meta.ID,
+				DataType:    meta.DataType,
+				Status:      meta.Status,
+				RecordCount: meta.RecordCount,
+				Size:        meta.ArchiveSize,
+				CreatedAt:   meta.CreatedAt,
+				Error:       meta.Error,
+			})
+		}
+		if meta.Status != ArchiveStatusCompleted {
+			continue
+		}
+
+		stats.TotalArchives++
+		stats.TotalArchivedBytes += meta.ArchiveSize
+		stats.ArchivesByType[meta.DataType]++
+		stats.SizeByType[meta.DataType] += meta.ArchiveSize
+		stats.ArchivesSizeByMonth[meta.CreatedAt.Format("2006-01")] += meta.ArchiveSize
+		compressionSum += meta.CompressionRatio
+
+		createdAt := meta.CreatedAt
+		if stats.OldestArchive == nil || createdAt.Before(*stats.OldestArchive) {
+			stats.OldestArchive = &createdAt
+		}
+		if stats.NewestArchive == nil || createdAt.After(*stats.NewestArchive) {
+			stats.NewestArchive = &createdAt
+		}
+	}
+	if stats.TotalArchives > 0 {
+		stats.AverageCompression = compressionSum / float64(stats.TotalArchives)
+	}
+
+	health, err := s.storage.GetHealth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check archive storage: %w", err)
+	}
+	stats.StorageHealth = health
+	return stats, nil
 }
 
 // Helper methods
 
 func (s *archiverService) generateArchiveID(dataType ArchiveDataType, timestamp time.Time) string {
-	return fmt.Sprintf("%s_%s", string(dataType), timestamp.Format("20060102_150405"))
+	return fmt.Sprintf("%s_%s_%s", string(dataType), timestamp.UTC().Format("20060102_150405"), uuid.NewString()[:8])
 }
 
 func (s *archiverService) getArchivePath(archiveID string, dataType ArchiveDataType) string {
-	return filepath.Join(s.config.StorageBasePath, string(dataType), archiveID+".archive")
+	return path.Join(string(dataType), archiveID+".jsonl.gz")
 }
 
-func (s *archiverService) serializeAndCompress(data interface{}) ([]byte, error) {
-	// This would implement serialization and compression
-	// For now, return a simple byte representation
-	return []byte(fmt.Sprintf("compressed_%v", data)), nil
+func (s *archiverService) opener(ctx context.Context, meta *ArchiveMetadata) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return s.storage.Open(ctx, meta.StoragePath)
+	}
 }
 
-func (s *archiverService) calculateCompressionRatio(originalSize int, compressedSize int) float64 {
+func (s *archiverService) calculateCompressionRatio(originalSize int64, compressedSize int64) float64 {
 	if originalSize == 0 {
 		return 0
 	}
@@ -0,0 +1,346 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestArchiver(t *testing.T) (*archiverService, *bun.DB, string) {
+	t.Helper()
+
+	sqldb, err := sql.Open(sqliteshim.DriverName(), "file::memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE price_history (id INTEGER PRIMARY KEY, product_master_id INTEGER, flyer_id INTEGER, price NUMERIC, recorded_at TIMESTAMP)`,
+		`CREATE TABLE flyers (id INTEGER PRIMARY KEY, title TEXT, valid_to DATE, is_archived BOOLEAN)`,
+		`CREATE TABLE flyer_pages (id INTEGER PRIMARY KEY, flyer_id INTEGER REFERENCES flyers(id), page_number INTEGER)`,
+		`CREATE TABLE enrichment_run_pages (id INTEGER PRIMARY KEY, flyer_id INTEGER REFERENCES flyers(id))`,
+		`CREATE TABLE products (id INTEGER PRIMARY KEY, flyer_id INTEGER, name TEXT, valid_from DATE, valid_to DATE)`,
+		`CREATE TABLE extraction_jobs (id INTEGER PRIMARY KEY, status TEXT, completed_at TIMESTAMP)`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to create test schema: %v", err)
+		}
+	}
+	if _, err := db.NewCreateTable().Model((*ArchiveMetadata)(nil)).Exec(ctx); err != nil {
+		t.Fatalf("failed to create archives: %v", err)
+	}
+
+	dir := t.TempDir()
+	s := NewArchiverService(db, NewFileSystemStorage(dir), nil, &ArchivalServiceConfig{
+		ChunkSize:       2,
+		RetentionPeriod: 30 * 24 * time.Hour,
+	}).(*archiverService)
+	s.now = func() time.Time { return testNow }
+	return s, db, dir
+}
+
+func exec(t *testing.T, db *bun.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func count(t *testing.T, db *bun.DB, table string) int {
+	t.Helper()
+	n, err := db.NewSelect().TableExpr(table).Count(context.Background())
+	if err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	return n
+}
+
+func TestArchiver_ArchiveAndRestorePrices(t *testing.T) {
+	s, db, _ := newTestArchiver(t)
+	ctx := context.Background()
+
+	old := testNow.AddDate(0, 0, -100)
+	for i, recordedAt := range []time.Time{old, old.AddDate(0, 0, 1), old.AddDate(0, 0, 2), testNow.AddDate(0, 0, -1)} {
+		exec(t, db, "INSERT INTO price_history (id, product_master_id, price, recorded_at) VALUES (?, 7, ?, ?)", i+1, "1.99", recordedAt)
+	}
+
+	result, err := s.ArchiveOldPrices(ctx, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveOldPrices() error = %v", err)
+	}
+	if result.RecordsArchived != 3 || result.Status != ArchiveStatusCompleted || result.Error != "" {
+		t.Fatalf("ArchiveOldPrices() = %+v, want 3 records completed", result)
+	}
+	if !result.StartDate.Equal(old) || !result.EndDate.Equal(old.AddDate(0, 0, 2)) {
+		t.Errorf("date range = %s - %s", result.StartDate, result.EndDate)
+	}
+	if got := count(t, db, "price_history"); got != 1 {
+		t.Errorf("price_history has %d rows after archiving, want 1", got)
+	}
+
+	meta, err := s.GetArchiveMetadata(ctx, result.ArchiveID)
+	if err != nil {
+		t.Fatalf("GetArchiveMetadata() error = %v", err)
+	}
+	if meta.Status != ArchiveStatusCompleted || meta.RecordCount != 3 || len(meta.Checksum) != 64 ||
+		meta.ArchiveSize == 0 || meta.UncompressedSize == 0 || !meta.RetentionUntil.Equal(testNow.Add(30*24*time.Hour)) {
+		t.Errorf("manifest = %+v", meta)
+	}
+
+	if err := s.RestoreArchivedData(ctx, result.ArchiveID, ArchiveTypePriceHistory); err != nil {
+		t.Fatalf("RestoreArchivedData() error = %v", err)
+	}
+	if got := count(t, db, "price_history"); got != 4 {
+		t.Errorf("price_history has %d rows after restore, want 4", got)
+	}
+	var price string
+	if err := db.NewSelect().TableExpr("price_history").Column("price").Where("id = 2").Scan(ctx, &price); err != nil || price != "1.99" {
+		t.Errorf("restored price = %q, %v", price, err)
+	}
+
+	// Restoring again skips the rows already present
+	if err := s.RestoreArchivedData(ctx, result.ArchiveID, ArchiveTypePriceHistory); err != nil {
+		t.Errorf("second RestoreArchivedData() error = %v", err)
+	}
+	if meta, _ := s.GetArchiveMetadata(ctx, result.ArchiveID); meta.RestoredAt == nil {
+		t.Error("RestoredAt not recorded")
+	}
+}
+
+func TestArchiver_NothingToArchive(t *testing.T) {
+	s, _, _ := newTestArchiver(t)
+
+	result, err := s.ArchiveCompletedExtractionJobs(context.Background(), 24*time.Hour)
+	if err != nil || result.RecordsArchived != 0 || result.ArchiveID != "" {
+		t.Errorf("ArchiveCompletedExtractionJobs() = %+v, %v, want nothing archived", result, err)
+	}
+	archives, err := s.ListArchives(context.Background(), "", 0, 0)
+	if err != nil || len(archives) != 0 {
+		t.Errorf("ListArchives() = %d archives, %v, want none recorded", len(archives), err)
+	}
+}
+
+func TestArchiver_FlyersWithPages(t *testing.T) {
+	s, db, _ := newTestArchiver(t)
+	ctx := context.Background()
+
+	exec(t, db, "INSERT INTO flyers (id, title, valid_to, is_archived) VALUES (1, 'old', '2025-01-05', TRUE), (2, 'with products', '2025-01-05', TRUE), (3, 'current', '2025-06-08', FALSE)")
+	exec(t, db, "INSERT INTO flyer_pages (id, flyer_id, page_number) VALUES (10, 1, 1), (11, 1, 2), (12, 2, 1)")
+	exec(t, db, "INSERT INTO enrichment_run_pages (id, flyer_id) VALUES (20, 1)")
+	exec(t, db, "INSERT INTO products (id, flyer_id, name, valid_from, valid_to) VALUES (30, 2, 'milk', '2024-12-30', '2025-01-05')")
+
+	result, err := s.ArchiveOldFlyers(ctx, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveOldFlyers() error = %v", err)
+	}
+	// The flyer, its two pages and its run page
+	if result.RecordsArchived != 4 {
+		t.Errorf("RecordsArchived = %d, want 4", result.RecordsArchived)
+	}
+	if flyers, pages, runPages := count(t, db, "flyers"), count(t, db, "flyer_pages"), count(t, db, "enrichment_run_pages"); flyers != 2 || pages != 1 || runPages != 0 {
+		t.Errorf("after archiving: %d flyers, %d pages, %d run pages, want 2, 1, 0", flyers, pages, runPages)
+	}
+
+	if err := s.RestoreArchivedData(ctx, result.ArchiveID, ArchiveTypeFlyers); err != nil {
+		t.Fatalf("RestoreArchivedData() error = %v", err)
+	}
+	if flyers, pages, runPages := count(t, db, "flyers"), count(t, db, "flyer_pages"), count(t, db, "enrichment_run_pages"); flyers != 3 || pages != 3 || runPages != 1 {
+		t.Errorf("after restore: %d flyers, %d pages, %d run pages, want 3, 3, 1", flyers, pages, runPages)
+	}
+}
+
+// detachedPartitions stands in for the partition manager, keeping detached
+// partitions in a database attached as product_archive
+type detachedPartitions struct {
+	db      *bun.DB
+	names   []string
+	ensured []time.Time
+}
+
+func (p *detachedPartitions) Partitioned() bool { return true }
+
+func (p *detachedPartitions) EnsureForDates(ctx context.Context, dates ...time.Time) error {
+	p.ensured = append(p.ensured, dates...)
+	return nil
+}
+
+func (p *detachedPartitions) Detached(ctx context.Context) ([]string, error) {
+	return p.names, nil
+}
+
+func (p *detachedPartitions) DropDetached(ctx context.Context, name string) error {
+	if _, err := p.db.ExecContext(ctx, "DROP TABLE ?", bun.Ident("product_archive."+name)); err != nil {
+		return err
+	}
+	for i, detached := range p.names {
+		if detached == name {
+			p.names = append(p.names[:i], p.names[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func TestArchiver_ProductsFromDetachedPartitions(t *testing.T) {
+	s, db, _ := newTestArchiver(t)
+	ctx := context.Background()
+
+	exec(t, db, "ATTACH DATABASE ':memory:' AS product_archive")
+	partitions := &detachedPartitions{db: db, names: []string{"products_2025_01", "products_2025_02"}}
+	for _, name := range partitions.names {
+		exec(t, db, "CREATE TABLE ? (id INTEGER PRIMARY KEY, flyer_id INTEGER, name TEXT, valid_from DATE, valid_to DATE)", bun.Ident("product_archive."+name))
+	}
+	s.partitions = partitions
+
+	// The partition manager detached these weeks after its retention, well
+	// before archive_older_than_days: they must not wait for the cutoff
+	exec(t, db, "INSERT INTO product_archive.products_2025_01 (id, flyer_id, name, valid_from, valid_to) VALUES (1, 1, 'milk', '2025-01-06', '2025-01-12'), (2, 1, 'bread', '2025-01-06', '2025-01-12'), (3, 1, 'eggs', '2025-01-07', '2025-01-12')")
+	exec(t, db, "INSERT INTO product_archive.products_2025_02 (id, flyer_id, name, valid_from, valid_to) VALUES (4, 2, 'butter', '2025-01-13', '2025-01-19')")
+	// Attached rows leave with their partition, not by age
+	exec(t, db, "INSERT INTO products (id, flyer_id, name, valid_from, valid_to) VALUES (5, 3, 'cheese', '2025-01-20', '2025-01-26')")
+
+	result, err := s.ArchiveOldProducts(ctx, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveOldProducts() error = %v", err)
+	}
+	if result.RecordsArchived != 4 || result.Error != "" {
+		t.Errorf("ArchiveOldProducts() = %+v, want the 4 detached products", result)
+	}
+	if len(partitions.names) != 0 {
+		t.Errorf("detached partitions %v left after archiving", partitions.names)
+	}
+	if got := count(t, db, "products"); got != 1 {
+		t.Errorf("products has %d rows, want the attached one left alone", got)
+	}
+
+	again, err := s.ArchiveOldProducts(ctx, 90*24*time.Hour)
+	if err != nil || again.RecordsArchived != 0 || again.ArchiveID != "" {
+		t.Errorf("second ArchiveOldProducts() = %+v, %v, want nothing left", again, err)
+	}
+
+	// Restoring puts the rows back into products, in partitions for their weeks
+	if err := s.RestoreArchivedData(ctx, result.ArchiveID, ArchiveTypeProducts); err != nil {
+		t.Fatalf("RestoreArchivedData() error = %v", err)
+	}
+	if got := count(t, db, "products"); got != 5 {
+		t.Errorf("products has %d rows after restore, want 5", got)
+	}
+	if len(partitions.ensured) != 4 {
+		t.Errorf("restore ensured partitions for %d products, want 4", len(partitions.ensured))
+	}
+}
+
+func TestArchiver_RestoreRefusesCorruptArchive(t *testing.T) {
+	s, db, dir := newTestArchiver(t)
+	ctx := context.Background()
+
+	exec(t, db, "INSERT INTO extraction_jobs (id, status, completed_at) VALUES (1, 'completed', ?), (2, 'pending', ?)", testNow.AddDate(0, -1, 0), testNow.AddDate(0, -1, 0))
+	result, err := s.ArchiveCompletedExtractionJobs(ctx, 24*time.Hour)
+	if err != nil || result.RecordsArchived != 1 {
+		t.Fatalf("ArchiveCompletedExtractionJobs() = %+v, %v", result, err)
+	}
+
+	file := filepath.Join(dir, filepath.FromSlash(result.StoragePath))
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatalf("failed to corrupt archive: %v", err)
+	}
+
+	err = s.RestoreArchivedData(ctx, result.ArchiveID, ArchiveTypeExtractionJobs)
+	if !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("RestoreArchivedData() error = %v, want a validation error", err)
+	}
+	if got := count(t, db, "extraction_jobs"); got != 1 {
+		t.Errorf("extraction_jobs has %d rows, want only the pending job", got)
+	}
+
+	err = s.RestoreArchivedData(ctx, result.ArchiveID, ArchiveTypeProducts)
+	if !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("RestoreArchivedData(wrong type) error = %v, want a validation error", err)
+	}
+}
+
+func TestArchiver_ListDeleteAndExpire(t *testing.T) {
+	s, db, dir := newTestArchiver(t)
+	ctx := context.Background()
+
+	exec(t, db, "INSERT INTO products (id, name, valid_from, valid_to) VALUES (1, 'bread', '2025-01-06', '2025-01-12')")
+	exec(t, db, "INSERT INTO extraction_jobs (id, status, completed_at) VALUES (1, 'failed', ?)", testNow.AddDate(0, -2, 0))
+
+	products, err := s.ArchiveOldProducts(ctx, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveOldProducts() error = %v", err)
+	}
+	s.now = func() time.Time { return testNow.Add(time.Hour) }
+	jobs, err := s.ArchiveCompletedExtractionJobs(ctx, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("ArchiveCompletedExtractionJobs() error = %v", err)
+	}
+
+	archives, err := s.ListArchives(ctx, "", 10, 0)
+	if err != nil || len(archives) != 2 || archives[0].ID != jobs.ArchiveID {
+		t.Fatalf("ListArchives() = %v, %v, want the jobs archive first", archives, err)
+	}
+	archives, err = s.ListArchives(ctx, ArchiveTypeProducts, 10, 0)
+	if err != nil || len(archives) != 1 || archives[0].ID != products.ArchiveID {
+		t.Errorf("ListArchives(products) = %v, %v", archives, err)
+	}
+
+	stats, err := s.GetArchivalStatistics(ctx)
+	if err != nil {
+		t.Fatalf("GetArchivalStatistics() error = %v", err)
+	}
+	if stats.TotalArchives != 2 || stats.ArchivesByType[ArchiveTypeProducts] != 1 || stats.TotalArchivedBytes != products.ArchiveSize+jobs.ArchiveSize ||
+		len(stats.RecentOperations) != 2 || stats.StorageHealth == nil || !stats.StorageHealth.Available {
+		t.Errorf("GetArchivalStatistics() = %+v", stats)
+	}
+
+	if err := s.DeleteArchive(ctx, jobs.ArchiveID); err != nil {
+		t.Fatalf("DeleteArchive() error = %v", err)
+	}
+	if _, err := s.GetArchiveMetadata(ctx, jobs.ArchiveID); !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Errorf("GetArchiveMetadata(deleted) error = %v, want not found", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(jobs.StoragePath))); !os.IsNotExist(err) {
+		t.Errorf("deleted archive file still exists: %v", err)
+	}
+
+	s.now = func() time.Time { return testNow.AddDate(0, 0, 29) }
+	if expired, err := s.DeleteExpiredArchives(ctx); err != nil || len(expired) != 0 {
+		t.Errorf("DeleteExpiredArchives() before retention = %d, %v", len(expired), err)
+	}
+	s.now = func() time.Time { return testNow.AddDate(0, 0, 31) }
+	expired, err := s.DeleteExpiredArchives(ctx)
+	if err != nil || len(expired) != 1 || expired[0].ID != products.ArchiveID {
+		t.Errorf("DeleteExpiredArchives() = %v, %v, want the products archive", expired, err)
+	}
+}
+
+func TestFileSystemStorage_RefusesEscapingPaths(t *testing.T) {
+	storage := NewFileSystemStorage(t.TempDir())
+
+	for _, path := range []string{"../outside.jsonl.gz", "/etc/passwd", "flyers/../../outside"} {
+		if _, err := storage.Open(context.Background(), path); err == nil {
+			t.Errorf("Open(%q) succeeded", path)
+		}
+	}
+}
@@ -2,11 +2,19 @@ package archive
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
+
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	image "github.com/kainuguru/kainuguru-api/pkg/image"
+	"github.com/robfig/cron/v3"
+	"github.com/uptrace/bun"
 )
 
 // CleanerService handles cleanup of archived data and orphaned files
@@ -34,8 +42,19 @@ type CleanerService interface {
 
 	// ScheduleRegularCleanup sets up regular cleanup jobs
 	ScheduleRegularCleanup(ctx context.Context, config *CleanupConfig) error
+
+	// RunDueCleanups runs the scheduled cleanups whose next run has come
+	RunDueCleanups(ctx context.Context) ([]*CleanupResult, error)
 }
 
+// Cleanup types that can be scheduled
+const (
+	CleanupTypeOrphanedImages  = "orphaned_images"
+	CleanupTypeExpiredArchives = "expired_archives"
+	CleanupTypeTempFiles       = "temp_files"
+	CleanupTypeOptimizeStorage = "optimize_storage"
+)
+
 // CleanupResult contains results of a cleanup operation
 type CleanupResult struct {
 	OperationType  string                 `json:"operation_type"`
@@ -79,17 +98,18 @@ type OrphanedFile struct {
 	Suggestions []string  `json:"suggestions"`
 }
 
-// OptimizationOptions contains options for storage optimization
+// OptimizationOptions contains options for storage optimization. Images are
+// compressed by recompressing them to WebP, and resized while at it.
 type OptimizationOptions struct {
-	CompressImages   bool    `json:"compress_images"`
-	ResizeImages     bool    `json:"resize_images"`
-	MaxImageWidth    int     `json:"max_image_width"`
-	MaxImageHeight   int     `json:"max_image_height"`
-	ImageQuality     int     `json:"image_quality"`
-	ConvertToWebP    bool    `json:"convert_to_webp"`
-	RemoveDuplicates bool    `json:"remove_duplicates"`
-	DedupeThreshold  float64 `json:"dedupe_threshold"`
-	DryRun           bool    `json:"dry_run"`
+	CompressImages   bool          `json:"compress_images"`
+	ResizeImages     bool          `json:"resize_images"`
+	MaxImageWidth    int           `json:"max_image_width"`
+	MaxImageHeight   int           `json:"max_image_height"`
+	ImageQuality     int           `json:"image_quality"`
+	ConvertToWebP    bool          `json:"convert_to_webp"`
+	RemoveDuplicates bool          `json:"remove_duplicates"`
+	OlderThan        time.Duration `json:"older_than"` // Only recompress pages of flyers ended this long ago
+	DryRun           bool          `json:"dry_run"`
 }
 
 // OptimizationResult contains results of storage optimization
@@ -131,15 +151,20 @@ type StorageUtilization struct {
 	LastUpdated    time.Time `json:"last_updated"`
 }
 
-// ScheduledCleanup represents a scheduled cleanup operation
+// ScheduledCleanup represents a scheduled cleanup operation, kept in the
+// cleanup_schedules table with one schedule per type
 type ScheduledCleanup struct {
-	ID       string         `json:"id"`
-	Type     string         `json:"type"`
-	Schedule string         `json:"schedule"`
-	Enabled  bool           `json:"enabled"`
-	LastRun  *time.Time     `json:"last_run"`
-	NextRun  time.Time      `json:"next_run"`
-	Config   *CleanupConfig `json:"config"`
+	bun.BaseModel `bun:"table:cleanup_schedules,alias:cs"`
+
+	ID        string         `bun:"-" json:"id"`
+	Type      string         `bun:"type,pk" json:"type"`
+	Schedule  string         `bun:"schedule,notnull" json:"schedule"`
+	Enabled   bool           `bun:"enabled,notnull" json:"enabled"`
+	LastRun   *time.Time     `bun:"last_run" json:"last_run"`
+	NextRun   time.Time      `bun:"next_run,notnull" json:"next_run"`
+	Config    *CleanupConfig `bun:"config,type:jsonb" json:"config"`
+	CreatedAt time.Time      `bun:"created_at,notnull" json:"created_at"`
+	UpdatedAt time.Time      `bun:"updated_at,notnull" json:"updated_at"`
 }
 
 // CleanupConfig contains configuration for cleanup operations
@@ -160,16 +185,27 @@ type CleanupConfig struct {
 // FileStorage interface for file operations
 type FileStorage interface {
 	Exists(path string) bool
+	Open(path string) (io.ReadCloser, error)
+	// Create returns a writer for a file that exists once the writer is closed
+	Create(path string) (io.WriteCloser, error)
 	Delete(path string) error
 	GetSize(path string) (int64, error)
 	GetModTime(path string) (time.Time, error)
 	List(dir string) ([]string, error)
 	GetStats(path string) (os.FileInfo, error)
+	// Capacity returns the total and free bytes of the storage
+	Capacity() (total, free int64, err error)
+}
+
+// ImageEncoder recompresses images to WebP
+type ImageEncoder interface {
+	EncodeWebP(ctx context.Context, r io.Reader, w io.Writer, options image.WebPOptions) error
 }
 
 // Repository interfaces for database operations
 type ImageRepository interface {
 	GetAllImageReferences(ctx context.Context) ([]*ImageReference, error)
+	GetPageImagesEndedBefore(ctx context.Context, cutoff time.Time) ([]*ImageReference, error)
 	GetOrphanedImagePaths(ctx context.Context, existingPaths []string) ([]string, error)
 	UpdateImagePath(ctx context.Context, oldPath, newPath string) error
 	DeleteImageReference(ctx context.Context, path string) error
@@ -186,39 +222,77 @@ type ImageReference struct {
 
 // cleanerService implements CleanerService
 type cleanerService struct {
-	storage     FileStorage
-	imageRepo   ImageRepository
-	flyerRepo   FlyerRepository
-	productRepo ProductRepository
-	config      *CleanerServiceConfig
+	db        *bun.DB
+	storage   FileStorage
+	imageRepo ImageRepository
+	encoder   ImageEncoder
+	config    *CleanerServiceConfig
+	now       func() time.Time
 }
 
-// CleanerServiceConfig contains service configuration
+// CleanerServiceConfig contains service configuration. Paths are relative to
+// the file storage.
 type CleanerServiceConfig struct {
 	ImageBasePath     string
 	TempBasePath      string
 	ArchiveBasePath   string
+	SharedImagePath   string // Where images shared by several pages are kept after deduplication
 	MaxFileAge        time.Duration
 	MaxTempFileAge    time.Duration
+	OptimizeAfter     time.Duration // How long after a flyer ends its page images are recompressed
+	WebPQuality       int
 	ChunkSize         int
 	ConcurrentWorkers int
 	ValidationEnabled bool
 }
 
-// NewCleanerService creates a new cleaner service
+const (
+	defaultTempFileAge   = 24 * time.Hour
+	defaultOptimizeAfter = 30 * 24 * time.Hour
+	defaultWebPQuality   = 80
+	recentCleanupsLimit  = 10
+)
+
+// cronParser parses cleanup schedules like the worker's: with or without seconds
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// NewCleanerService creates a new cleaner service. Cleanup history, shared
+// images and schedules are kept in db.
 func NewCleanerService(
+	db *bun.DB,
 	storage FileStorage,
 	imageRepo ImageRepository,
-	flyerRepo FlyerRepository,
-	productRepo ProductRepository,
+	encoder ImageEncoder,
 	config *CleanerServiceConfig,
 ) CleanerService {
+	cfg := CleanerServiceConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.SharedImagePath == "" {
+		cfg.SharedImagePath = path.Join(cfg.ImageBasePath, "shared")
+	}
+	// Archives expire with the archiver's retention unless told otherwise
+	if cfg.MaxFileAge <= 0 {
+		cfg.MaxFileAge = defaultRetentionPeriod
+	}
+	if cfg.MaxTempFileAge <= 0 {
+		cfg.MaxTempFileAge = defaultTempFileAge
+	}
+	if cfg.OptimizeAfter <= 0 {
+		cfg.OptimizeAfter = defaultOptimizeAfter
+	}
+	if cfg.WebPQuality <= 0 {
+		cfg.WebPQuality = defaultWebPQuality
+	}
+
 	return &cleanerService{
-		storage:     storage,
-		imageRepo:   imageRepo,
-		flyerRepo:   flyerRepo,
-		productRepo: productRepo,
-		config:      config,
+		db:        db,
+		storage:   storage,
+		imageRepo: imageRepo,
+		encoder:   encoder,
+		config:    &cfg,
+		now:       time.Now,
 	}
 }
 
@@ -231,6 +305,18 @@ func (s *cleanerService) CleanOrphanedImages(ctx context.Context, dryRun bool) (
 		CreatedAt:     startTime,
 	}
 
+	// Shared images no page uses anymore go first, with their records
+	released, err := s.releaseUnreferencedBlobs(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	releasedPaths := make(map[string]bool, len(released))
+	var releasedSize int64
+	for _, blob := range released {
+		releasedPaths[blob.Path] = true
+		releasedSize += blob.Size
+	}
+
 	// Get all image references from database
 	references, err := s.imageRepo.GetAllImageReferences(ctx)
 	if err != nil {
@@ -256,7 +342,7 @@ func (s *cleanerService) CleanOrphanedImages(ctx context.Context, dryRun bool) (
 	var totalSize int64
 
 	for _, imagePath := range imageFiles {
-		if !referencedPaths[imagePath] {
+		if !referencedPaths[imagePath] && !releasedPaths[imagePath] {
 			orphanedFiles = append(orphanedFiles, imagePath)
 			if size, err := s.storage.GetSize(imagePath); err == nil {
 				totalSize += size
@@ -267,6 +353,7 @@ func (s *cleanerService) CleanOrphanedImages(ctx context.Context, dryRun bool) (
 	result.FilesProcessed = len(imageFiles)
 	result.Details["orphaned_files"] = orphanedFiles
 	result.Details["referenced_files"] = len(referencedPaths)
+	result.Details["released_shared_images"] = len(released)
 
 	if !dryRun {
 		// Delete orphaned files
@@ -291,8 +378,11 @@ func (s *cleanerService) CleanOrphanedImages(ctx context.Context, dryRun bool) (
 		result.FilesDeleted = len(orphanedFiles)
 		result.BytesFreed = totalSize
 	}
+	result.FilesDeleted += len(released)
+	result.BytesFreed += releasedSize
 
 	result.Duration = time.Since(startTime)
+	s.record(ctx, result)
 	return result, nil
 }
 
@@ -373,6 +463,7 @@ func (s *cleanerService) CleanExpiredArchives(ctx context.Context, dryRun bool)
 	}
 
 	result.Duration = time.Since(startTime)
+	s.record(ctx, result)
 	return result, nil
 }
 
@@ -435,6 +526,7 @@ func (s *cleanerService) CleanTempFiles(ctx context.Context, olderThan time.Dura
 	}
 
 	result.Duration = time.Since(startTime)
+	s.record(ctx, result)
 	return result, nil
 }
 
@@ -521,37 +613,326 @@ func (s *cleanerService) ValidateImageReferences(ctx context.Context) (*Validati
 	return result, nil
 }
 
+// OptimizeStorage recompresses the page images of ended flyers to WebP and
+// then keeps one copy of identical images, recording each step in the cleanup
+// history
 func (s *cleanerService) OptimizeStorage(ctx context.Context, options *OptimizationOptions) (*OptimizationResult, error) {
+	if options == nil {
+		options = &OptimizationOptions{ConvertToWebP: true, RemoveDuplicates: true}
+	}
+
 	startTime := time.Now()
 	result := &OptimizationResult{
 		Details:   make(map[string]interface{}),
+		Errors:    []string{},
 		CreatedAt: startTime,
 	}
 
-	// This would implement storage optimization logic
-	// For now, return minimal implementation
-	result.Duration = time.Since(startTime)
-	result.Details["options"] = options
+	if options.ConvertToWebP || options.CompressImages {
+		if s.encoder == nil {
+			return nil, apperrors.Validation("recompressing images needs an image encoder")
+		}
+		if err := s.convertToWebP(ctx, options, result); err != nil {
+			return nil, err
+		}
+	}
+	if options.RemoveDuplicates {
+		if err := s.deduplicate(ctx, options.DryRun, result); err != nil {
+			return nil, err
+		}
+	}
 
+	result.Details["dry_run"] = options.DryRun
+	result.Duration = time.Since(startTime)
 	return result, nil
 }
 
+// cleanupOperation is a cleanup operation in the cleanup history
+type cleanupOperation struct {
+	bun.BaseModel `bun:"table:cleanup_operations,alias:co"`
+
+	ID             int64     `bun:"id,pk,autoincrement"`
+	OperationType  string    `bun:"operation_type,notnull"`
+	FilesProcessed int       `bun:"files_processed,notnull"`
+	FilesDeleted   int       `bun:"files_deleted,notnull"`
+	BytesFreed     int64     `bun:"bytes_freed,notnull"`
+	DurationMS     int64     `bun:"duration_ms,notnull"`
+	DryRun         bool      `bun:"dry_run,notnull"`
+	Errors         []string  `bun:"errors,type:jsonb"`
+	CreatedAt      time.Time `bun:"created_at,notnull"`
+}
+
+// record adds an operation to the cleanup history. The history only feeds
+// statistics, so failing to record is reported on the result rather than failing it.
+func (s *cleanerService) record(ctx context.Context, result *CleanupResult) {
+	if result.Errors == nil {
+		result.Errors = []string{}
+	}
+	operation := &cleanupOperation{
+		OperationType:  result.OperationType,
+		FilesProcessed: result.FilesProcessed,
+		FilesDeleted:   result.FilesDeleted,
+		BytesFreed:     result.BytesFreed,
+		DurationMS:     result.Duration.Milliseconds(),
+		DryRun:         result.DryRun,
+		Errors:         result.Errors,
+		CreatedAt:      result.CreatedAt,
+	}
+	if _, err := s.db.NewInsert().Model(operation).Exec(ctx); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to record cleanup history: %v", err))
+	}
+}
+
+// GetCleanupStatistics sums the cleanup history, leaving dry runs out of the
+// totals, and measures the storage
 func (s *cleanerService) GetCleanupStatistics(ctx context.Context) (*CleanupStatistics, error) {
-	return &CleanupStatistics{
-		TotalCleanupOperations: 0,
-		TotalFilesDeleted:      0,
-		TotalBytesFreed:        0,
-		CleanupsByType:         make(map[string]int),
-		BytesFreedByType:       make(map[string]int64),
-		RecentOperations:       []*CleanupResult{},
-		StorageUtilization:     &StorageUtilization{},
-		ScheduledCleanups:      []*ScheduledCleanup{},
-	}, nil
+	var byType []struct {
+		OperationType string `bun:"operation_type"`
+		Operations    int    `bun:"operations"`
+		FilesDeleted  int    `bun:"files_deleted"`
+		BytesFreed    int64  `bun:"bytes_freed"`
+		DurationMS    int64  `bun:"duration_ms"`
+	}
+	err := s.db.NewSelect().
+		Model((*cleanupOperation)(nil)).
+		ColumnExpr("co.operation_type").
+		ColumnExpr("COUNT(*) AS operations").
+		ColumnExpr("CAST(COALESCE(SUM(co.files_deleted), 0) AS BIGINT) AS files_deleted").
+		ColumnExpr("CAST(COALESCE(SUM(co.bytes_freed), 0) AS BIGINT) AS bytes_freed").
+		ColumnExpr("CAST(COALESCE(SUM(co.duration_ms), 0) AS BIGINT) AS duration_ms").
+		Where("co.dry_run = FALSE").
+		Group("co.operation_type").
+		Scan(ctx, &byType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum cleanup history: %w", err)
+	}
+
+	stats := &CleanupStatistics{
+		CleanupsByType:     make(map[string]int),
+		BytesFreedByType:   make(map[string]int64),
+		RecentOperations:   []*CleanupResult{},
+		StorageUtilization: s.storageUtilization(),
+		ScheduledCleanups:  []*ScheduledCleanup{},
+	}
+	var totalDurationMS int64
+	for _, row := range byType {
+		stats.TotalCleanupOperations += row.Operations
+		stats.TotalFilesDeleted += row.FilesDeleted
+		stats.TotalBytesFreed += row.BytesFreed
+		stats.CleanupsByType[row.OperationType] = row.Operations
+		stats.BytesFreedByType[row.OperationType] = row.BytesFreed
+		totalDurationMS += row.DurationMS
+	}
+	if stats.TotalCleanupOperations > 0 {
+		stats.AverageCleanupDuration = time.Duration(totalDurationMS/int64(stats.TotalCleanupOperations)) * time.Millisecond
+	}
+
+	var recent []*cleanupOperation
+	err = s.db.NewSelect().
+		Model(&recent).
+		Order("created_at DESC", "id DESC").
+		Limit(recentCleanupsLimit).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recent cleanups: %w", err)
+	}
+	for _, operation := range recent {
+		if !operation.DryRun && stats.LastCleanupTime == nil {
+			lastCleanup := operation.CreatedAt
+			stats.LastCleanupTime = &lastCleanup
+		}
+		stats.RecentOperations = append(stats.RecentOperations, &CleanupResult{
+			OperationType:  operation.OperationType,
+			FilesProcessed: operation.FilesProcessed,
+			FilesDeleted:   operation.FilesDeleted,
+			BytesFreed:     operation.BytesFreed,
+			Duration:       time.Duration(operation.DurationMS) * time.Millisecond,
+			DryRun:         operation.DryRun,
+			Errors:         operation.Errors,
+			CreatedAt:      operation.CreatedAt,
+		})
+	}
+
+	err = s.db.NewSelect().Model(&stats.ScheduledCleanups).Order("type ASC").Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cleanup schedules: %w", err)
+	}
+	for _, scheduled := range stats.ScheduledCleanups {
+		scheduled.ID = scheduled.Type
+	}
+
+	return stats, nil
 }
 
+// storageUtilization measures the image, archive and temporary directories and
+// the filesystem holding them
+func (s *cleanerService) storageUtilization() *StorageUtilization {
+	utilization := &StorageUtilization{
+		ImageStorage:   s.directorySize(s.config.ImageBasePath),
+		ArchiveStorage: s.directorySize(s.config.ArchiveBasePath),
+		TempStorage:    s.directorySize(s.config.TempBasePath),
+		LastUpdated:    s.now(),
+	}
+
+	total, free, err := s.storage.Capacity()
+	if err != nil || total <= 0 {
+		utilization.UsedSpace = utilization.ImageStorage + utilization.ArchiveStorage + utilization.TempStorage
+		return utilization
+	}
+	utilization.TotalSpace = total
+	utilization.FreeSpace = free
+	utilization.UsedSpace = total - free
+	utilization.UsagePercent = float64(utilization.UsedSpace) / float64(total) * 100
+	return utilization
+}
+
+func (s *cleanerService) directorySize(dir string) int64 {
+	if dir == "" {
+		return 0
+	}
+	files, err := s.storage.List(dir)
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, file := range files {
+		if fileSize, err := s.storage.GetSize(file); err == nil {
+			size += fileSize
+		}
+	}
+	return size
+}
+
+// ScheduleRegularCleanup saves the schedule of a cleanup type, replacing its
+// previous one. The worker runs due cleanups through RunDueCleanups.
 func (s *cleanerService) ScheduleRegularCleanup(ctx context.Context, config *CleanupConfig) error {
-	// This would implement cleanup scheduling
-	return fmt.Errorf("scheduling not yet implemented")
+	if config == nil {
+		return apperrors.Validation("cleanup config is required")
+	}
+	switch config.Type {
+	case CleanupTypeOrphanedImages, CleanupTypeExpiredArchives, CleanupTypeTempFiles, CleanupTypeOptimizeStorage:
+	default:
+		return apperrors.ValidationF("unknown cleanup type %q", config.Type)
+	}
+	schedule, err := cronParser.Parse(config.Schedule)
+	if err != nil {
+		return apperrors.ValidationF("invalid schedule %q: %v", config.Schedule, err)
+	}
+
+	now := s.now()
+	scheduled := &ScheduledCleanup{
+		Type:      config.Type,
+		Schedule:  config.Schedule,
+		Enabled:   config.Enabled,
+		NextRun:   schedule.Next(now),
+		Config:    config,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = s.db.NewInsert().
+		Model(scheduled).
+		On("CONFLICT (type) DO UPDATE").
+		Set("schedule = EXCLUDED.schedule").
+		Set("enabled = EXCLUDED.enabled").
+		Set("next_run = EXCLUDED.next_run").
+		Set("config = EXCLUDED.config").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save %s cleanup schedule: %w", config.Type, err)
+	}
+	return nil
+}
+
+// RunDueCleanups runs the enabled cleanups whose next run has come and moves
+// them to their next run, also when they fail
+func (s *cleanerService) RunDueCleanups(ctx context.Context) ([]*CleanupResult, error) {
+	now := s.now()
+	var due []*ScheduledCleanup
+	err := s.db.NewSelect().
+		Model(&due).
+		Where("enabled = TRUE").
+		Where("next_run <= ?", now).
+		Order("next_run ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load due cleanups: %w", err)
+	}
+
+	results := []*CleanupResult{}
+	var errs []error
+	for _, scheduled := range due {
+		config := &CleanupConfig{}
+		if scheduled.Config != nil {
+			config = scheduled.Config
+		}
+		config.Type = scheduled.Type
+
+		result, err := s.runCleanup(ctx, config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cleanup failed: %w", scheduled.Type, err))
+		} else {
+			results = append(results, result)
+		}
+
+		schedule, err := cronParser.Parse(scheduled.Schedule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s cleanup has an invalid schedule: %w", scheduled.Type, err))
+			continue
+		}
+		lastRun := now
+		scheduled.LastRun = &lastRun
+		scheduled.NextRun = schedule.Next(now)
+		scheduled.UpdatedAt = now
+		_, err = s.db.NewUpdate().
+			Model(scheduled).
+			Column("last_run", "next_run", "updated_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to update %s cleanup schedule: %w", scheduled.Type, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// runCleanup runs one cleanup of a scheduled type
+func (s *cleanerService) runCleanup(ctx context.Context, config *CleanupConfig) (*CleanupResult, error) {
+	switch config.Type {
+	case CleanupTypeOrphanedImages:
+		return s.CleanOrphanedImages(ctx, config.DryRun)
+	case CleanupTypeExpiredArchives:
+		return s.CleanExpiredArchives(ctx, config.DryRun)
+	case CleanupTypeTempFiles:
+		maxAge := config.MaxFileAge
+		if maxAge <= 0 {
+			maxAge = s.config.MaxTempFileAge
+		}
+		return s.CleanTempFiles(ctx, maxAge, config.DryRun)
+	case CleanupTypeOptimizeStorage:
+		optimized, err := s.OptimizeStorage(ctx, &OptimizationOptions{
+			ConvertToWebP:    true,
+			RemoveDuplicates: true,
+			OlderThan:        config.MaxFileAge,
+			DryRun:           config.DryRun,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &CleanupResult{
+			OperationType:  CleanupTypeOptimizeStorage,
+			FilesProcessed: optimized.ImagesProcessed,
+			FilesDeleted:   optimized.ImagesCompressed + optimized.DuplicatesRemoved,
+			BytesFreed:     optimized.SpaceSaved,
+			Duration:       optimized.Duration,
+			DryRun:         config.DryRun,
+			Errors:         optimized.Errors,
+			Details:        optimized.Details,
+			CreatedAt:      optimized.CreatedAt,
+		}, nil
+	default:
+		return nil, apperrors.ValidationF("unknown cleanup type %q", config.Type)
+	}
 }
 
 // Helper methods
@@ -0,0 +1,354 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	image "github.com/kainuguru/kainuguru-api/pkg/image"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// halvingEncoder stands in for ImageMagick, writing the first half of the image
+type halvingEncoder struct {
+	qualities []int
+}
+
+func (e *halvingEncoder) EncodeWebP(ctx context.Context, r io.Reader, w io.Writer, options image.WebPOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	e.qualities = append(e.qualities, options.Quality)
+	_, err = w.Write(data[:len(data)/2])
+	return err
+}
+
+func newTestCleaner(t *testing.T) (*cleanerService, *bun.DB, *halvingEncoder, string) {
+	t.Helper()
+
+	sqldb, err := sql.Open(sqliteshim.DriverName(), "file::memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE flyers (id INTEGER PRIMARY KEY, valid_to DATE)`,
+		`CREATE TABLE flyer_pages (id INTEGER PRIMARY KEY, flyer_id INTEGER, image_url TEXT)`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to create test schema: %v", err)
+		}
+	}
+	for _, model := range []interface{}{(*imageBlob)(nil), (*cleanupOperation)(nil), (*ScheduledCleanup)(nil)} {
+		if _, err := db.NewCreateTable().Model(model).Exec(ctx); err != nil {
+			t.Fatalf("failed to create table: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	encoder := &halvingEncoder{}
+	s := NewCleanerService(db, NewLocalFileStorage(dir), NewImageRepository(db, "https://cdn.example.com"), encoder, &CleanerServiceConfig{
+		ImageBasePath:   "flyers",
+		TempBasePath:    "tmp",
+		ArchiveBasePath: "archives",
+	}).(*cleanerService)
+	s.now = func() time.Time { return testNow }
+	return s, db, encoder, dir
+}
+
+func writeFile(t *testing.T, dir, name string, content []byte) {
+	t.Helper()
+	fullPath := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fileExists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+	return err == nil
+}
+
+func pageImage(t *testing.T, db *bun.DB, id int) string {
+	t.Helper()
+	var imageURL sql.NullString
+	if err := db.NewSelect().TableExpr("flyer_pages").Column("image_url").Where("id = ?", id).Scan(context.Background(), &imageURL); err != nil {
+		t.Fatalf("failed to load page %d: %v", id, err)
+	}
+	return imageURL.String
+}
+
+func TestCleaner_ConvertToWebP(t *testing.T) {
+	s, db, encoder, dir := newTestCleaner(t)
+	ctx := context.Background()
+
+	exec(t, db, "INSERT INTO flyers (id, valid_to) VALUES (1, ?), (2, ?)",
+		testNow.AddDate(0, 0, -60).Format(time.DateOnly), testNow.AddDate(0, 0, -1).Format(time.DateOnly))
+	exec(t, db, "INSERT INTO flyer_pages (id, flyer_id, image_url) VALUES (1, 1, ?), (2, 2, ?)",
+		"https://cdn.example.com/flyers/1/page-1.jpg", "flyers/2/page-1.jpg")
+	writeFile(t, dir, "flyers/1/page-1.jpg", bytes.Repeat([]byte("a"), 1000))
+	writeFile(t, dir, "flyers/2/page-1.jpg", bytes.Repeat([]byte("b"), 1000))
+
+	preview, err := s.OptimizeStorage(ctx, &OptimizationOptions{ConvertToWebP: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("OptimizeStorage(dry run) error = %v", err)
+	}
+	if preview.ImagesCompressed != 1 || preview.SpaceSaved != 500 {
+		t.Errorf("dry run = %+v, want 1 image saving 500 bytes", preview)
+	}
+	if !fileExists(dir, "flyers/1/page-1.jpg") || fileExists(dir, "flyers/1/page-1.webp") {
+		t.Error("dry run changed files")
+	}
+
+	result, err := s.OptimizeStorage(ctx, &OptimizationOptions{ConvertToWebP: true})
+	if err != nil {
+		t.Fatalf("OptimizeStorage() error = %v", err)
+	}
+	if result.ImagesCompressed != 1 || result.SpaceSaved != 500 || len(result.Errors) != 0 {
+		t.Errorf("OptimizeStorage() = %+v", result)
+	}
+	if got := pageImage(t, db, 1); got != "https://cdn.example.com/flyers/1/page-1.webp" {
+		t.Errorf("page 1 image = %q", got)
+	}
+	if fileExists(dir, "flyers/1/page-1.jpg") || !fileExists(dir, "flyers/1/page-1.webp") {
+		t.Error("original not replaced by the WebP file")
+	}
+	if got := pageImage(t, db, 2); got != "flyers/2/page-1.jpg" {
+		t.Errorf("page of a recent flyer converted: %q", got)
+	}
+	if len(encoder.qualities) != 2 || encoder.qualities[1] != defaultWebPQuality {
+		t.Errorf("encoder qualities = %v, want the default quality", encoder.qualities)
+	}
+
+	s.encoder = nil
+	if _, err := s.OptimizeStorage(ctx, &OptimizationOptions{ConvertToWebP: true}); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("OptimizeStorage() without encoder error = %v, want validation error", err)
+	}
+}
+
+func TestCleaner_DeduplicateAndRelease(t *testing.T) {
+	s, db, _, dir := newTestCleaner(t)
+	ctx := context.Background()
+
+	same := bytes.Repeat([]byte("x"), 300)
+	exec(t, db, "INSERT INTO flyer_pages (id, flyer_id, image_url) VALUES (1, 1, 'flyers/1/a.jpg'), (2, 2, 'flyers/2/b.jpg'), (3, 2, 'flyers/2/c.jpg')")
+	writeFile(t, dir, "flyers/1/a.jpg", same)
+	writeFile(t, dir, "flyers/2/b.jpg", same)
+	writeFile(t, dir, "flyers/2/c.jpg", []byte("different"))
+
+	result, err := s.OptimizeStorage(ctx, &OptimizationOptions{RemoveDuplicates: true})
+	if err != nil {
+		t.Fatalf("OptimizeStorage() error = %v", err)
+	}
+	if result.DuplicatesRemoved != 1 || result.SpaceSaved != 300 || len(result.Errors) != 0 {
+		t.Errorf("OptimizeStorage() = %+v, want 1 duplicate saving 300 bytes", result)
+	}
+
+	shared := pageImage(t, db, 1)
+	if !strings.HasPrefix(shared, "flyers/shared/") || pageImage(t, db, 2) != shared {
+		t.Fatalf("pages point at %q and %q, want one shared image", shared, pageImage(t, db, 2))
+	}
+	if !fileExists(dir, shared) || fileExists(dir, "flyers/1/a.jpg") || fileExists(dir, "flyers/2/b.jpg") {
+		t.Error("duplicates not replaced by the shared image")
+	}
+	if got := pageImage(t, db, 3); got != "flyers/2/c.jpg" {
+		t.Errorf("unique image moved to %q", got)
+	}
+
+	var blob imageBlob
+	if err := db.NewSelect().Model(&blob).Scan(ctx); err != nil {
+		t.Fatalf("failed to load shared image: %v", err)
+	}
+	if blob.Path != shared || blob.ReferenceCount != 2 || blob.Size != 300 {
+		t.Errorf("shared image = %+v", blob)
+	}
+
+	// One page still uses the shared image, so orphan cleanup keeps it
+	exec(t, db, "UPDATE flyer_pages SET image_url = NULL WHERE id = 1")
+	if _, err := s.CleanOrphanedImages(ctx, false); err != nil {
+		t.Fatalf("CleanOrphanedImages() error = %v", err)
+	}
+	if err := db.NewSelect().Model(&blob).WherePK().Scan(ctx); err != nil || blob.ReferenceCount != 1 || !fileExists(dir, shared) {
+		t.Errorf("shared image after one page dropped it = %+v, %v", blob, err)
+	}
+
+	exec(t, db, "UPDATE flyer_pages SET image_url = NULL WHERE id = 2")
+	cleanup, err := s.CleanOrphanedImages(ctx, false)
+	if err != nil {
+		t.Fatalf("CleanOrphanedImages() error = %v", err)
+	}
+	if cleanup.FilesDeleted != 1 || cleanup.BytesFreed != 300 {
+		t.Errorf("CleanOrphanedImages() = %+v, want the shared image released", cleanup)
+	}
+	if fileExists(dir, shared) || count(t, db, "image_blobs") != 0 {
+		t.Error("unreferenced shared image not deleted")
+	}
+}
+
+func TestImageRepository_MatchesExactPaths(t *testing.T) {
+	s, db, _, _ := newTestCleaner(t)
+	ctx := context.Background()
+
+	// The underscore would match any character in a LIKE pattern, and page 4 only
+	// ends in the path
+	exec(t, db, "INSERT INTO flyer_pages (id, flyer_id, image_url) VALUES (1, 1, ?), (2, 1, ?), (3, 1, ?), (4, 1, ?), (5, 1, ?)",
+		"flyers/1/page_1.jpg", "https://cdn.example.com/flyers/1/pageX1.jpg", "https://cdn.example.com/flyers/1/page_1.jpg",
+		"https://mirror.example.com/old/flyers/1/page_1.jpg", "https://cdn.example.com/flyers/1/page_1.jpg/flyers/1/page_1.jpg")
+
+	refs, err := s.imageRepo.GetAllImageReferences(ctx)
+	if err != nil {
+		t.Fatalf("GetAllImageReferences() error = %v", err)
+	}
+	if refs[2].ImagePath != "flyers/1/page_1.jpg" || refs[3].ImagePath != "https://mirror.example.com/old/flyers/1/page_1.jpg" {
+		t.Errorf("image paths = %q, %q, want the public URL stripped and other hosts kept", refs[2].ImagePath, refs[3].ImagePath)
+	}
+
+	if err := s.imageRepo.UpdateImagePath(ctx, "flyers/1/page_1.jpg", "flyers/shared/a.webp"); err != nil {
+		t.Fatalf("UpdateImagePath() error = %v", err)
+	}
+	want := []string{
+		"flyers/shared/a.webp",
+		"https://cdn.example.com/flyers/1/pageX1.jpg",
+		"https://cdn.example.com/flyers/shared/a.webp",
+		"https://mirror.example.com/old/flyers/1/page_1.jpg",
+		"https://cdn.example.com/flyers/1/page_1.jpg/flyers/1/page_1.jpg",
+	}
+	for i, url := range want {
+		if got := pageImage(t, db, i+1); got != url {
+			t.Errorf("page %d image after update = %q, want %q", i+1, got, url)
+		}
+	}
+
+	if err := s.imageRepo.DeleteImageReference(ctx, "flyers/shared/a.webp"); err != nil {
+		t.Fatalf("DeleteImageReference() error = %v", err)
+	}
+	want[0], want[2] = "", ""
+	for i, url := range want {
+		if got := pageImage(t, db, i+1); got != url {
+			t.Errorf("page %d image after delete = %q, want %q", i+1, got, url)
+		}
+	}
+}
+
+func TestCleaner_StatisticsAndHistory(t *testing.T) {
+	s, _, _, dir := newTestCleaner(t)
+	ctx := context.Background()
+
+	writeFile(t, dir, "tmp/old.pdf", bytes.Repeat([]byte("t"), 2048))
+	writeFile(t, dir, "tmp/new.pdf", []byte("new"))
+	writeFile(t, dir, "flyers/1/page-1.jpg", []byte("image"))
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "tmp", "old.pdf"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.CleanTempFiles(ctx, 24*time.Hour, true); err != nil {
+		t.Fatalf("CleanTempFiles(dry run) error = %v", err)
+	}
+	result, err := s.CleanTempFiles(ctx, 24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("CleanTempFiles() error = %v", err)
+	}
+	if result.FilesDeleted != 1 || result.BytesFreed != 2048 || fileExists(dir, "tmp/old.pdf") {
+		t.Errorf("CleanTempFiles() = %+v", result)
+	}
+
+	stats, err := s.GetCleanupStatistics(ctx)
+	if err != nil {
+		t.Fatalf("GetCleanupStatistics() error = %v", err)
+	}
+	if stats.TotalCleanupOperations != 1 || stats.TotalBytesFreed != 2048 || stats.BytesFreedByType[CleanupTypeTempFiles] != 2048 {
+		t.Errorf("statistics = %+v, want the dry run left out", stats)
+	}
+	if len(stats.RecentOperations) != 2 || stats.LastCleanupTime == nil {
+		t.Errorf("recent operations = %d, last cleanup = %v", len(stats.RecentOperations), stats.LastCleanupTime)
+	}
+	if u := stats.StorageUtilization; u.ImageStorage != 5 || u.TempStorage != 3 || u.TotalSpace <= 0 {
+		t.Errorf("storage utilization = %+v", u)
+	}
+}
+
+func TestCleaner_ScheduledCleanups(t *testing.T) {
+	s, _, _, dir := newTestCleaner(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		config *CleanupConfig
+	}{
+		{"unknown type", &CleanupConfig{Type: "everything", Schedule: "@daily"}},
+		{"invalid schedule", &CleanupConfig{Type: CleanupTypeTempFiles, Schedule: "every day"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.ScheduleRegularCleanup(ctx, tt.config); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+				t.Errorf("ScheduleRegularCleanup() error = %v, want validation error", err)
+			}
+		})
+	}
+
+	if err := s.ScheduleRegularCleanup(ctx, &CleanupConfig{Type: CleanupTypeTempFiles, Schedule: "0 * * * *", Enabled: true, MaxFileAge: time.Hour}); err != nil {
+		t.Fatalf("ScheduleRegularCleanup() error = %v", err)
+	}
+	if err := s.ScheduleRegularCleanup(ctx, &CleanupConfig{Type: CleanupTypeOrphanedImages, Schedule: "0 * * * *"}); err != nil {
+		t.Fatalf("ScheduleRegularCleanup() error = %v", err)
+	}
+
+	results, err := s.RunDueCleanups(ctx)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("RunDueCleanups() before the schedule = %d results, %v", len(results), err)
+	}
+
+	writeFile(t, dir, "tmp/old.pdf", []byte("old"))
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "tmp", "old.pdf"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	s.now = func() time.Time { return testNow.Add(90 * time.Minute) }
+	results, err = s.RunDueCleanups(ctx)
+	if err != nil {
+		t.Fatalf("RunDueCleanups() error = %v", err)
+	}
+	if len(results) != 1 || results[0].OperationType != CleanupTypeTempFiles || results[0].FilesDeleted != 1 {
+		t.Fatalf("RunDueCleanups() = %+v, want the enabled temp file cleanup", results)
+	}
+
+	stats, err := s.GetCleanupStatistics(ctx)
+	if err != nil {
+		t.Fatalf("GetCleanupStatistics() error = %v", err)
+	}
+	if len(stats.ScheduledCleanups) != 2 {
+		t.Fatalf("scheduled cleanups = %d, want 2", len(stats.ScheduledCleanups))
+	}
+	for _, scheduled := range stats.ScheduledCleanups {
+		if scheduled.Type != CleanupTypeTempFiles {
+			continue
+		}
+		if scheduled.LastRun == nil || !scheduled.NextRun.Equal(testNow.Add(2*time.Hour)) {
+			t.Errorf("temp file schedule after running = last %v, next %s", scheduled.LastRun, scheduled.NextRun)
+		}
+	}
+
+	results, err = s.RunDueCleanups(ctx)
+	if err != nil || len(results) != 0 {
+		t.Errorf("RunDueCleanups() again = %d results, %v", len(results), err)
+	}
+}
@@ -0,0 +1,385 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
+	"github.com/uptrace/bun"
+)
+
+// dataset describes the rows of one table an archive of its data type moves out
+type dataset struct {
+	table string
+	// dateColumn is compared with the cutoff and gives the archive's date range
+	dateColumn string
+	// where narrows the rows old enough to the ones safe to move; t aliases the table
+	where string
+	// children are archived and deleted with their parent rows, and restored after them
+	children []childTable
+	// dateOnly formats the cutoff as a date for DATE columns
+	dateOnly bool
+	// detached lists partitions detached from table into partition.ArchiveSchema.
+	// When set they are archived whole in place of table's old rows, and dropped
+	// afterwards.
+	detached []string
+}
+
+// childTable holds rows referencing the dataset's rows through foreignKey
+type childTable struct {
+	table      string
+	foreignKey string
+}
+
+var datasets = map[ArchiveDataType]*dataset{
+	ArchiveTypePriceHistory: {
+		table:      "price_history",
+		dateColumn: "recorded_at",
+	},
+	ArchiveTypeProducts: {
+		table:      "products",
+		dateColumn: "valid_to",
+		dateOnly:   true,
+	},
+	// Flyers are archived once marked archived and nothing but their pages and
+	// run bookkeeping references them
+	ArchiveTypeFlyers: {
+		table:      "flyers",
+		dateColumn: "valid_to",
+		dateOnly:   true,
+		where: "t.is_archived = TRUE" +
+			" AND NOT EXISTS (SELECT 1 FROM products p WHERE p.flyer_id = t.id)" +
+			" AND NOT EXISTS (SELECT 1 FROM price_history ph WHERE ph.flyer_id = t.id)",
+		children: []childTable{
+			{table: "flyer_pages", foreignKey: "flyer_id"},
+			{table: "enrichment_run_pages", foreignKey: "flyer_id"},
+		},
+	},
+	ArchiveTypeExtractionJobs: {
+		table:      "extraction_jobs",
+		dateColumn: "completed_at",
+		where:      "t.status IN ('completed', 'failed')",
+	},
+}
+
+// datasetFor returns the dataset of an archivable data type
+func datasetFor(dataType ArchiveDataType) (*dataset, error) {
+	ds, ok := datasets[dataType]
+	if !ok {
+		return nil, fmt.Errorf("data type %q can't be archived", dataType)
+	}
+	return ds, nil
+}
+
+// record is one line of an archive: a row and the table it came from
+type record struct {
+	Table string                 `json:"table"`
+	Row   map[string]interface{} `json:"row"`
+}
+
+// sources returns the tables the rows to archive are read from
+func (ds *dataset) sources() []string {
+	if len(ds.detached) == 0 {
+		return []string{ds.table}
+	}
+	sources := make([]string, len(ds.detached))
+	for i, name := range ds.detached {
+		sources[i] = partition.ArchiveSchema + "." + name
+	}
+	return sources
+}
+
+// selectOld returns the query of the rows of source older than cutoff. Every
+// row of a detached partition is past retention.
+func (ds *dataset) selectOld(db bun.IDB, source string, cutoff time.Time) *bun.SelectQuery {
+	q := db.NewSelect().TableExpr("? AS t", bun.Ident(source))
+	if len(ds.detached) > 0 {
+		return q
+	}
+	q = q.Where("t.? < ?", bun.Ident(ds.dateColumn), ds.cutoffValue(cutoff))
+	if ds.where != "" {
+		q = q.Where(ds.where)
+	}
+	return q
+}
+
+// countOld counts the rows to archive across the dataset's sources
+func (ds *dataset) countOld(ctx context.Context, db bun.IDB, cutoff time.Time) (int, error) {
+	total := 0
+	for _, source := range ds.sources() {
+		n, err := ds.selectOld(db, source, cutoff).Count(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count %s to archive: %w", source, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (ds *dataset) cutoffValue(cutoff time.Time) interface{} {
+	if ds.dateOnly {
+		return cutoff.Format("2006-01-02")
+	}
+	return cutoff
+}
+
+// nextBatch returns up to limit rows of source older than cutoff with an id above afterID
+func (ds *dataset) nextBatch(ctx context.Context, db bun.IDB, source string, cutoff time.Time, afterID int64, limit int) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := ds.selectOld(db, source, cutoff).
+		ColumnExpr("t.*").
+		Where("t.id > ?", afterID).
+		Order("t.id ASC").
+		Limit(limit).
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	return rows, nil
+}
+
+// childRows returns the rows of a child table referencing the given parents
+func (c childTable) rows(ctx context.Context, db bun.IDB, parentIDs []int64) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := db.NewSelect().
+		TableExpr("?", bun.Ident(c.table)).
+		ColumnExpr("*").
+		Where("? IN (?)", bun.Ident(c.foreignKey), bun.In(parentIDs)).
+		Order("id ASC").
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.table, err)
+	}
+	return rows, nil
+}
+
+// deleteRows deletes the given rows with their children
+func (ds *dataset) deleteRows(ctx context.Context, tx bun.Tx, ids []int64) error {
+	for _, child := range ds.children {
+		if _, err := tx.NewDelete().
+			TableExpr("?", bun.Ident(child.table)).
+			Where("? IN (?)", bun.Ident(child.foreignKey), bun.In(ids)).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", child.table, err)
+		}
+	}
+	if _, err := tx.NewDelete().
+		TableExpr("?", bun.Ident(ds.table)).
+		Where("id IN (?)", bun.In(ids)).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", ds.table, err)
+	}
+	return nil
+}
+
+// restoreTables lists the tables an archive of the dataset may hold rows of
+func (ds *dataset) restoreTables() map[string]bool {
+	tables := map[string]bool{ds.table: true}
+	for _, child := range ds.children {
+		tables[child.table] = true
+	}
+	return tables
+}
+
+// insertRows inserts restored rows into table, skipping rows already present.
+// Values are sent as the text they were archived as and converted by the
+// columns they go into.
+func insertRows(ctx context.Context, tx bun.Tx, table string, rows []map[string]interface{}) error {
+	columnSet := make(map[string]bool)
+	for _, row := range rows {
+		for column := range row {
+			columnSet[column] = true
+		}
+	}
+	columns := make([]bun.Ident, 0, len(columnSet))
+	for column := range columnSet {
+		columns = append(columns, bun.Ident(column))
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i] < columns[j] })
+
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	values := make([]string, 0, len(rows))
+	args := []interface{}{bun.Ident(table), bun.In(columns)}
+	for _, row := range rows {
+		values = append(values, placeholders)
+		for _, column := range columns {
+			args = append(args, row[string(column)])
+		}
+	}
+
+	query := "INSERT INTO ? (?) VALUES " + strings.Join(values, ", ") + " ON CONFLICT DO NOTHING"
+	if _, err := tx.NewRaw(query, args...).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", table, err)
+	}
+	return nil
+}
+
+// archiveWriter streams records as gzip-compressed JSONL, measuring the
+// uncompressed size and hashing the compressed bytes as they're written
+type archiveWriter struct {
+	file         io.WriteCloser
+	hash         hash.Hash
+	counter      *countingWriter
+	gzip         *gzip.Writer
+	encoder      *json.Encoder
+	uncompressed *countingWriter
+	records      int
+}
+
+func newArchiveWriter(file io.WriteCloser, level int) (*archiveWriter, error) {
+	w := &archiveWriter{file: file, hash: sha256.New()}
+	w.counter = &countingWriter{w: io.MultiWriter(file, w.hash)}
+
+	gz, err := gzip.NewWriterLevel(w.counter, level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compression level %d: %w", level, err)
+	}
+	w.gzip = gz
+	w.uncompressed = &countingWriter{w: gz}
+	w.encoder = json.NewEncoder(w.uncompressed)
+	return w, nil
+}
+
+// write adds the rows of table to the archive
+func (w *archiveWriter) write(table string, rows []map[string]interface{}) error {
+	for _, row := range rows {
+		if err := w.encoder.Encode(record{Table: table, Row: normalizeRow(row)}); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		w.records++
+	}
+	return nil
+}
+
+// close finishes the archive file, returning its size and checksum
+func (w *archiveWriter) close() (size int64, checksum string, err error) {
+	if err := w.gzip.Close(); err != nil {
+		w.file.Close()
+		return 0, "", fmt.Errorf("failed to compress archive: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return 0, "", err
+	}
+	return w.counter.n, hex.EncodeToString(w.hash.Sum(nil)), nil
+}
+
+// verifyArchive checks an archive's file against the checksum and record count
+// of its manifest, passing each record to fn when given. The records seen by fn
+// aren't verified until verifyArchive returns nil.
+func verifyArchive(open func() (io.ReadCloser, error), meta *ArchiveMetadata, fn func(record) error) error {
+	file, err := open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	records := 0
+	err = decodeRecords(io.TeeReader(file, hasher), func(rec record) error {
+		records++
+		if fn != nil {
+			return fn(rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("archive %s is corrupt: %w", meta.ID, err)
+	}
+	// Drain what gzip didn't read so the whole file is hashed
+	if _, err := io.Copy(hasher, file); err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", meta.ID, err)
+	}
+
+	if checksum := hex.EncodeToString(hasher.Sum(nil)); checksum != meta.Checksum {
+		return fmt.Errorf("archive %s checksum mismatch: manifest has %s, file has %s", meta.ID, meta.Checksum, checksum)
+	}
+	if records != meta.RecordCount {
+		return fmt.Errorf("archive %s holds %d records, manifest says %d", meta.ID, records, meta.RecordCount)
+	}
+	return nil
+}
+
+func decodeRecords(r io.Reader, fn func(record) error) error {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	// Keep numbers as written so ids and amounts aren't rounded through float64
+	decoder.UseNumber()
+	for {
+		var rec record
+		if err := decoder.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// normalizeRow makes a scanned row JSON friendly: the driver returns the text
+// of numeric, JSON, array and similar columns as bytes
+func normalizeRow(row map[string]interface{}) map[string]interface{} {
+	for column, value := range row {
+		if b, ok := value.([]byte); ok {
+			row[column] = string(b)
+		}
+	}
+	return row
+}
+
+// rowID returns the id column of a scanned row
+func rowID(row map[string]interface{}) (int64, bool) {
+	switch id := row["id"].(type) {
+	case int64:
+		return id, true
+	case int32:
+		return int64(id), true
+	case int:
+		return int64(id), true
+	}
+	return 0, false
+}
+
+// rowTime returns a timestamp or date column of a scanned or restored row
+func rowTime(row map[string]interface{}, column string) (time.Time, bool) {
+	switch value := row[column].(type) {
+	case time.Time:
+		return value, true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, true
+			}
+		}
+	case []byte:
+		return rowTime(map[string]interface{}{column: string(value)}, column)
+	}
+	return time.Time{}, false
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// localFileStorage serves the files below a base directory, such as the
+// public directory flyer page images are saved to. Paths are relative and
+// slash separated, like the image paths stored for flyer pages.
+type localFileStorage struct {
+	basePath string
+}
+
+// NewLocalFileStorage creates file storage for the files below basePath
+func NewLocalFileStorage(basePath string) FileStorage {
+	return &localFileStorage{basePath: basePath}
+}
+
+func (s *localFileStorage) Exists(path string) bool {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(fullPath)
+	return err == nil && !info.IsDir()
+}
+
+func (s *localFileStorage) Open(path string) (io.ReadCloser, error) {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(fullPath)
+}
+
+// Create writes a file that replaces path once closed
+func (s *localFileStorage) Create(path string) (io.WriteCloser, error) {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	return createAtomic(fullPath)
+}
+
+func (s *localFileStorage) Delete(path string) error {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *localFileStorage) GetSize(path string) (int64, error) {
+	info, err := s.GetStats(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *localFileStorage) GetModTime(path string) (time.Time, error) {
+	info, err := s.GetStats(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// List returns the files below dir, sorted; a missing dir has none
+func (s *localFileStorage) List(dir string) ([]string, error) {
+	root, err := resolvePath(s.basePath, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.basePath, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (s *localFileStorage) GetStats(path string) (os.FileInfo, error) {
+	fullPath, err := resolvePath(s.basePath, path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(fullPath)
+}
+
+// Capacity returns the size and free space of the filesystem holding the base directory
+func (s *localFileStorage) Capacity() (total, free int64, err error) {
+	return diskCapacity(s.basePath)
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package archive
+
+import "errors"
+
+func diskCapacity(path string) (total, free int64, err error) {
+	return 0, 0, errors.New("filesystem capacity is not available on this platform")
+}
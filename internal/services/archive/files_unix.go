@@ -0,0 +1,17 @@
+//go:build linux || darwin
+
+package archive
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func diskCapacity(path string) (total, free int64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, fmt.Errorf("failed to stat filesystem of %s: %w", path, err)
+	}
+	blockSize := int64(stat.Bsize)
+	return int64(stat.Blocks) * blockSize, int64(stat.Bavail) * blockSize, nil
+}
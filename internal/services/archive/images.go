@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// imageRepository reads and rewrites the image paths of flyer pages
+type imageRepository struct {
+	db        *bun.DB
+	urlPrefix string // Public URL followed by a slash; empty when pages store bare paths
+}
+
+// NewImageRepository creates an image repository over the flyer pages. Pages
+// store either a path relative to the file storage or publicURL followed by
+// that path.
+func NewImageRepository(db *bun.DB, publicURL string) ImageRepository {
+	r := &imageRepository{db: db}
+	if publicURL != "" {
+		r.urlPrefix = strings.TrimSuffix(publicURL, "/") + "/"
+	}
+	return r
+}
+
+// flyerPageImage is the image column of a flyer page
+type flyerPageImage struct {
+	ID       int64  `bun:"id"`
+	FlyerID  int    `bun:"flyer_id"`
+	ImageURL string `bun:"image_url"`
+}
+
+func (r *imageRepository) GetAllImageReferences(ctx context.Context) ([]*ImageReference, error) {
+	return r.pageImages(ctx, r.db.NewSelect())
+}
+
+// GetPageImagesEndedBefore returns the page images of flyers that ended before cutoff
+func (r *imageRepository) GetPageImagesEndedBefore(ctx context.Context, cutoff time.Time) ([]*ImageReference, error) {
+	q := r.db.NewSelect().
+		Join("JOIN flyers AS f ON f.id = fp.flyer_id").
+		Where("f.valid_to < ?", cutoff.Format("2006-01-02"))
+	return r.pageImages(ctx, q)
+}
+
+func (r *imageRepository) pageImages(ctx context.Context, q *bun.SelectQuery) ([]*ImageReference, error) {
+	var pages []flyerPageImage
+	err := q.
+		TableExpr("flyer_pages AS fp").
+		ColumnExpr("fp.id, fp.flyer_id, fp.image_url").
+		Where("fp.image_url IS NOT NULL").
+		Where("fp.image_url <> ''").
+		Order("fp.id ASC").
+		Scan(ctx, &pages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load flyer page images: %w", err)
+	}
+
+	references := make([]*ImageReference, len(pages))
+	for i, page := range pages {
+		references[i] = &ImageReference{
+			ID:         page.ID,
+			EntityType: "flyer_page",
+			EntityID:   page.FlyerID,
+			ImageURL:   page.ImageURL,
+			ImagePath:  r.imagePath(page.ImageURL),
+		}
+	}
+	return references, nil
+}
+
+// GetOrphanedImagePaths returns the paths no flyer page uses
+func (r *imageRepository) GetOrphanedImagePaths(ctx context.Context, existingPaths []string) ([]string, error) {
+	references, err := r.GetAllImageReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(references))
+	for _, ref := range references {
+		referenced[ref.ImagePath] = true
+	}
+
+	orphaned := []string{}
+	for _, path := range existingPaths {
+		if !referenced[path] {
+			orphaned = append(orphaned, path)
+		}
+	}
+	return orphaned, nil
+}
+
+// imagePath strips the public URL from an image URL. URLs saved under another
+// host are returned whole, so they never match a file in the storage.
+func (r *imageRepository) imagePath(imageURL string) string {
+	if r.urlPrefix != "" {
+		return strings.TrimPrefix(imageURL, r.urlPrefix)
+	}
+	return imageURL
+}
+
+// imageURLs returns the values image_url holds for pages using path
+func (r *imageRepository) imageURLs(path string) []string {
+	if r.urlPrefix == "" {
+		return []string{path}
+	}
+	return []string{path, r.urlPrefix + path}
+}
+
+// UpdateImagePath points the pages using oldPath at newPath, keeping the
+// public URL of pages saved with one
+func (r *imageRepository) UpdateImagePath(ctx context.Context, oldPath, newPath string) error {
+	_, err := r.db.NewUpdate().
+		Table("flyer_pages").
+		Set("image_url = CASE WHEN image_url = ? THEN ? ELSE ? END", oldPath, newPath, r.urlPrefix+newPath).
+		Where("image_url IN (?)", bun.In(r.imageURLs(oldPath))).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update image path %s: %w", oldPath, err)
+	}
+	return nil
+}
+
+// DeleteImageReference clears the image of the pages using path
+func (r *imageRepository) DeleteImageReference(ctx context.Context, path string) error {
+	_, err := r.db.NewUpdate().
+		Table("flyer_pages").
+		Set("image_url = NULL").
+		Where("image_url IN (?)", bun.In(r.imageURLs(path))).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete image reference %s: %w", path, err)
+	}
+	return nil
+}
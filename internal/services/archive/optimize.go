@@ -0,0 +1,443 @@
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	image "github.com/kainuguru/kainuguru-api/pkg/image"
+	"github.com/uptrace/bun"
+)
+
+// Operation types of the storage optimization steps
+const (
+	OperationWebPConversion = "webp_conversion"
+	OperationDeduplication  = "image_deduplication"
+)
+
+// imageBlob is an image file shared by the flyer pages with identical images.
+// The file is deleted once no page references it.
+type imageBlob struct {
+	bun.BaseModel `bun:"table:image_blobs,alias:ib"`
+
+	ContentHash    string    `bun:"content_hash,pk"`
+	Path           string    `bun:"path,notnull"`
+	Size           int64     `bun:"size,notnull"`
+	ReferenceCount int       `bun:"reference_count,notnull"`
+	CreatedAt      time.Time `bun:"created_at,notnull"`
+	UpdatedAt      time.Time `bun:"updated_at,notnull"`
+}
+
+// errNotSmaller skips images WebP wouldn't make smaller
+var errNotSmaller = errors.New("webp is not smaller")
+
+// convertToWebP recompresses the page images of flyers ended before the
+// options' age to WebP, repointing the pages at the new files
+func (s *cleanerService) convertToWebP(ctx context.Context, options *OptimizationOptions, result *OptimizationResult) error {
+	startTime := time.Now()
+	history := &CleanupResult{
+		OperationType: OperationWebPConversion,
+		DryRun:        options.DryRun,
+		Errors:        []string{},
+		Details:       make(map[string]interface{}),
+		CreatedAt:     startTime,
+	}
+
+	olderThan := options.OlderThan
+	if olderThan <= 0 {
+		olderThan = s.config.OptimizeAfter
+	}
+	webpOptions := image.WebPOptions{Quality: options.ImageQuality}
+	if webpOptions.Quality <= 0 {
+		webpOptions.Quality = s.config.WebPQuality
+	}
+	if options.ResizeImages {
+		webpOptions.MaxWidth = options.MaxImageWidth
+		webpOptions.MaxHeight = options.MaxImageHeight
+	}
+
+	references, err := s.imageRepo.GetPageImagesEndedBefore(ctx, s.now().Add(-olderThan))
+	if err != nil {
+		return fmt.Errorf("failed to get old page images: %w", err)
+	}
+
+	skipped := 0
+	for _, imagePath := range distinctPaths(references) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Shared images keep the name of their content hash
+		if strings.EqualFold(path.Ext(imagePath), ".webp") || s.isShared(imagePath) || !s.storage.Exists(imagePath) {
+			continue
+		}
+		history.FilesProcessed++
+
+		webpPath := strings.TrimSuffix(imagePath, path.Ext(imagePath)) + ".webp"
+		oldSize, newSize, err := s.encodeWebP(ctx, imagePath, webpPath, webpOptions, options.DryRun)
+		if errors.Is(err, errNotSmaller) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			history.Errors = append(history.Errors, fmt.Sprintf("Failed to convert %s: %v", imagePath, err))
+			continue
+		}
+
+		if !options.DryRun {
+			if err := s.imageRepo.UpdateImagePath(ctx, imagePath, webpPath); err != nil {
+				s.storage.Delete(webpPath)
+				history.Errors = append(history.Errors, err.Error())
+				continue
+			}
+			if err := s.storage.Delete(imagePath); err != nil {
+				history.Errors = append(history.Errors, fmt.Sprintf("Failed to delete %s: %v", imagePath, err))
+			}
+		}
+		history.FilesDeleted++
+		history.BytesFreed += oldSize - newSize
+	}
+
+	history.Details["quality"] = webpOptions.Quality
+	history.Details["skipped_not_smaller"] = skipped
+	history.Duration = time.Since(startTime)
+	s.record(ctx, history)
+
+	result.ImagesProcessed += history.FilesProcessed
+	result.ImagesCompressed += history.FilesDeleted
+	if options.ResizeImages {
+		result.ImagesResized += history.FilesDeleted
+	}
+	result.SpaceSaved += history.BytesFreed
+	result.Errors = append(result.Errors, history.Errors...)
+	result.Details[OperationWebPConversion] = history
+	return nil
+}
+
+// encodeWebP writes imagePath as WebP to webpPath, or only measures the WebP
+// size on a dry run
+func (s *cleanerService) encodeWebP(ctx context.Context, imagePath, webpPath string, options image.WebPOptions, dryRun bool) (oldSize, newSize int64, err error) {
+	src, err := s.storage.Open(imagePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer src.Close()
+
+	source := &countingReader{r: src}
+	if dryRun {
+		out := &countingWriter{w: io.Discard}
+		if err := s.encoder.EncodeWebP(ctx, source, out, options); err != nil {
+			return 0, 0, err
+		}
+		if out.n >= source.n {
+			return source.n, out.n, errNotSmaller
+		}
+		return source.n, out.n, nil
+	}
+
+	dst, err := s.storage.Create(webpPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	out := &countingWriter{w: dst}
+	if err := s.encoder.EncodeWebP(ctx, source, out, options); err != nil {
+		dst.Close()
+		s.storage.Delete(webpPath)
+		return 0, 0, err
+	}
+	if err := dst.Close(); err != nil {
+		return 0, 0, err
+	}
+	if out.n >= source.n {
+		s.storage.Delete(webpPath)
+		return source.n, out.n, errNotSmaller
+	}
+	return source.n, out.n, nil
+}
+
+// deduplicate keeps one copy of identical page images, found by content hash,
+// in the shared image directory and points every page using it there
+func (s *cleanerService) deduplicate(ctx context.Context, dryRun bool, result *OptimizationResult) error {
+	startTime := time.Now()
+	history := &CleanupResult{
+		OperationType: OperationDeduplication,
+		DryRun:        dryRun,
+		Errors:        []string{},
+		Details:       make(map[string]interface{}),
+		CreatedAt:     startTime,
+	}
+
+	references, err := s.imageRepo.GetAllImageReferences(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get image references: %w", err)
+	}
+	referenceCounts := countReferences(references)
+
+	byHash := make(map[string][]string)
+	sizes := make(map[string]int64)
+	for _, imagePath := range distinctPaths(references) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !s.storage.Exists(imagePath) {
+			continue
+		}
+		history.FilesProcessed++
+		hash, size, err := s.hashFile(imagePath)
+		if err != nil {
+			history.Errors = append(history.Errors, fmt.Sprintf("Failed to hash %s: %v", imagePath, err))
+			continue
+		}
+		byHash[hash] = append(byHash[hash], imagePath)
+		sizes[imagePath] = size
+	}
+
+	blobs, err := s.loadBlobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	hashes := make([]string, 0, len(byHash))
+	for hash := range byHash {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	shared := 0
+	for _, hash := range hashes {
+		paths := byHash[hash]
+		blob := blobs[hash]
+		if len(paths) == 1 && blob == nil {
+			continue
+		}
+
+		canonical := s.sharedPath(hash, path.Ext(paths[0]))
+		if blob != nil {
+			canonical = blob.Path
+		}
+		// A new shared copy takes the place of one of the files deleted below
+		copied := !contains(paths, canonical) && !s.storage.Exists(canonical)
+		if copied && !dryRun {
+			if err := s.copyFile(paths[0], canonical); err != nil {
+				history.Errors = append(history.Errors, fmt.Sprintf("Failed to share %s: %v", paths[0], err))
+				continue
+			}
+		}
+
+		pages := 0
+		for _, imagePath := range paths {
+			pages += referenceCounts[imagePath]
+			if imagePath == canonical {
+				continue
+			}
+			if !dryRun {
+				if err := s.imageRepo.UpdateImagePath(ctx, imagePath, canonical); err != nil {
+					history.Errors = append(history.Errors, err.Error())
+					continue
+				}
+				if err := s.storage.Delete(imagePath); err != nil {
+					history.Errors = append(history.Errors, fmt.Sprintf("Failed to delete %s: %v", imagePath, err))
+				}
+			}
+			history.FilesDeleted++
+			history.BytesFreed += sizes[imagePath]
+		}
+		if copied {
+			history.FilesDeleted--
+			history.BytesFreed -= sizes[paths[0]]
+		}
+		shared++
+
+		if !dryRun {
+			if err := s.saveBlob(ctx, &imageBlob{
+				ContentHash:    hash,
+				Path:           canonical,
+				Size:           sizes[paths[0]],
+				ReferenceCount: pages,
+			}); err != nil {
+				history.Errors = append(history.Errors, err.Error())
+			}
+		}
+	}
+
+	if !dryRun {
+		if _, err := s.releaseUnreferencedBlobs(ctx, false); err != nil {
+			history.Errors = append(history.Errors, err.Error())
+		}
+	}
+
+	history.Details["shared_images"] = shared
+	history.Duration = time.Since(startTime)
+	s.record(ctx, history)
+
+	result.ImagesProcessed += history.FilesProcessed
+	result.DuplicatesRemoved += history.FilesDeleted
+	result.SpaceSaved += history.BytesFreed
+	result.Errors = append(result.Errors, history.Errors...)
+	result.Details[OperationDeduplication] = history
+	return nil
+}
+
+// releaseUnreferencedBlobs recounts the pages referencing each shared image and
+// deletes the images no page references anymore, returning them
+func (s *cleanerService) releaseUnreferencedBlobs(ctx context.Context, dryRun bool) ([]*imageBlob, error) {
+	references, err := s.imageRepo.GetAllImageReferences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image references: %w", err)
+	}
+	referenceCounts := countReferences(references)
+
+	blobs, err := s.loadBlobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var released []*imageBlob
+	for _, blob := range blobs {
+		count := referenceCounts[blob.Path]
+		if count > 0 {
+			if count != blob.ReferenceCount && !dryRun {
+				blob.ReferenceCount = count
+				if err := s.saveBlob(ctx, blob); err != nil {
+					return released, err
+				}
+			}
+			continue
+		}
+
+		released = append(released, blob)
+		if dryRun {
+			continue
+		}
+		if err := s.storage.Delete(blob.Path); err != nil {
+			return released, fmt.Errorf("failed to delete shared image %s: %w", blob.Path, err)
+		}
+		if _, err := s.db.NewDelete().Model(blob).WherePK().Exec(ctx); err != nil {
+			return released, fmt.Errorf("failed to delete shared image %s: %w", blob.Path, err)
+		}
+	}
+	return released, nil
+}
+
+func (s *cleanerService) loadBlobs(ctx context.Context) (map[string]*imageBlob, error) {
+	var blobs []*imageBlob
+	if err := s.db.NewSelect().Model(&blobs).Order("content_hash ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load shared images: %w", err)
+	}
+	byHash := make(map[string]*imageBlob, len(blobs))
+	for _, blob := range blobs {
+		byHash[blob.ContentHash] = blob
+	}
+	return byHash, nil
+}
+
+func (s *cleanerService) saveBlob(ctx context.Context, blob *imageBlob) error {
+	now := time.Now()
+	if blob.CreatedAt.IsZero() {
+		blob.CreatedAt = now
+	}
+	blob.UpdatedAt = now
+	_, err := s.db.NewInsert().
+		Model(blob).
+		On("CONFLICT (content_hash) DO UPDATE").
+		Set("path = EXCLUDED.path").
+		Set("reference_count = EXCLUDED.reference_count").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save shared image %s: %w", blob.Path, err)
+	}
+	return nil
+}
+
+func (s *cleanerService) hashFile(imagePath string) (string, int64, error) {
+	file, err := s.storage.Open(imagePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+func (s *cleanerService) copyFile(src, dst string) error {
+	in, err := s.storage.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := s.storage.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		s.storage.Delete(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// sharedPath is where an image shared by several pages is kept, named by its content
+func (s *cleanerService) sharedPath(hash, ext string) string {
+	return path.Join(s.config.SharedImagePath, hash[:2], hash+strings.ToLower(ext))
+}
+
+func (s *cleanerService) isShared(imagePath string) bool {
+	return strings.HasPrefix(imagePath, strings.TrimSuffix(s.config.SharedImagePath, "/")+"/")
+}
+
+func countReferences(references []*ImageReference) map[string]int {
+	counts := make(map[string]int)
+	for _, ref := range references {
+		if ref.ImagePath != "" {
+			counts[ref.ImagePath]++
+		}
+	}
+	return counts
+}
+
+func distinctPaths(references []*ImageReference) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, ref := range references {
+		if ref.ImagePath != "" && !seen[ref.ImagePath] {
+			seen[ref.ImagePath] = true
+			paths = append(paths, ref.ImagePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
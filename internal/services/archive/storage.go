@@ -0,0 +1,181 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileSystemStorage keeps archives as files below a base directory
+type fileSystemStorage struct {
+	basePath string
+}
+
+// NewFileSystemStorage creates archive storage writing below basePath
+func NewFileSystemStorage(basePath string) ArchiveStorage {
+	return &fileSystemStorage{basePath: basePath}
+}
+
+// Create writes to a temporary file that replaces path only when closed, so a
+// failed archive never leaves a partial file under its name
+func (s *fileSystemStorage) Create(ctx context.Context, path string) (io.WriteCloser, error) {
+	fullPath, err := s.fullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	return createAtomic(fullPath)
+}
+
+// createAtomic writes to a temporary file beside path that's renamed to path on Close
+func createAtomic(path string) (io.WriteCloser, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	return &atomicFile{File: file, path: path}, nil
+}
+
+func (s *fileSystemStorage) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	fullPath, err := s.fullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	return file, nil
+}
+
+func (s *fileSystemStorage) Delete(ctx context.Context, path string) error {
+	fullPath, err := s.fullPath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete archive %s: %w", path, err)
+	}
+	return nil
+}
+
+// List returns the paths of the archives below prefix, sorted
+func (s *fileSystemStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	root, err := s.fullPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.basePath, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (s *fileSystemStorage) GetSize(ctx context.Context, path string) (int64, error) {
+	fullPath, err := s.fullPath(path)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat archive %s: %w", path, err)
+	}
+	return info.Size(), nil
+}
+
+// GetHealth checks the base directory can be written to and sums the archives in it
+func (s *fileSystemStorage) GetHealth(ctx context.Context) (*StorageHealthStatus, error) {
+	health := &StorageHealthStatus{LastChecked: time.Now(), Issues: []string{}}
+
+	if err := os.MkdirAll(s.basePath, 0o755); err != nil {
+		health.Issues = append(health.Issues, fmt.Sprintf("archive directory unavailable: %v", err))
+		return health, nil
+	}
+	probe, err := os.CreateTemp(s.basePath, ".health.*.tmp")
+	if err != nil {
+		health.Issues = append(health.Issues, fmt.Sprintf("archive directory not writable: %v", err))
+		return health, nil
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	health.Available = true
+
+	paths, err := s.List(ctx, "")
+	if err != nil {
+		health.Issues = append(health.Issues, err.Error())
+		return health, nil
+	}
+	for _, path := range paths {
+		if size, err := s.GetSize(ctx, path); err == nil {
+			health.UsedSpace += size
+		}
+	}
+	return health, nil
+}
+
+func (s *fileSystemStorage) fullPath(path string) (string, error) {
+	return resolvePath(s.basePath, path)
+}
+
+// resolvePath resolves a slash separated path below basePath, refusing paths escaping it
+func resolvePath(basePath, path string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside %s", path, basePath)
+	}
+	return filepath.Join(basePath, clean), nil
+}
+
+// atomicFile renames its temporary file to path once fully written, so a
+// failed write never leaves a partial file under its name
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+func (f *atomicFile) Close() error {
+	if err := f.File.Sync(); err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(f.path), err)
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(f.path), err)
+	}
+	if err := os.Rename(f.File.Name(), f.path); err != nil {
+		os.Remove(f.File.Name())
+		return fmt.Errorf("failed to move %s into place: %w", filepath.Base(f.path), err)
+	}
+	return nil
+}
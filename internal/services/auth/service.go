@@ -437,6 +437,18 @@ func (a *authServiceImpl) UpdateUser(ctx context.Context, userID uuid.UUID, inpu
 	if input.PreferredLanguage != nil {
 		user.PreferredLanguage = *input.PreferredLanguage
 	}
+	if input.LoyaltyPrograms != nil {
+		programs := models.LoyaltyPrograms{}
+		for _, program := range *input.LoyaltyPrograms {
+			if !models.LoyaltyProgram(program).IsValid() {
+				return nil, apperrors.ValidationF("unknown loyalty program %q", program)
+			}
+			if !programs.Has(models.LoyaltyProgram(program)) {
+				programs = append(programs, program)
+			}
+		}
+		user.LoyaltyPrograms = programs
+	}
 	user.UpdatedAt = time.Now()
 
 	// Save to database
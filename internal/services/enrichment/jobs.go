@@ -0,0 +1,152 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultSweepBatchSize bounds how many pending pages one sweep job enqueues
+const defaultSweepBatchSize = 50
+
+// JobEnqueuer is the part of the job queue used to fan out per-page jobs
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, job *worker.Job) error
+}
+
+// PageJobHandler runs extract_products jobs from the job queue.
+// Each job covers a single flyer page; jobs without a page (the scheduled sweep)
+// enqueue one job per pending page instead.
+type PageJobHandler struct {
+	enrichmentSvc services.EnrichmentService
+	flyerSvc      services.FlyerService
+	pageSvc       services.FlyerPageService
+	queue         JobEnqueuer
+}
+
+// NewPageJobHandler creates a handler for extract_products jobs
+func NewPageJobHandler(enrichmentSvc services.EnrichmentService, flyerSvc services.FlyerService, pageSvc services.FlyerPageService, queue JobEnqueuer) *PageJobHandler {
+	return &PageJobHandler{
+		enrichmentSvc: enrichmentSvc,
+		flyerSvc:      flyerSvc,
+		pageSvc:       pageSvc,
+		queue:         queue,
+	}
+}
+
+// Register installs the handler on a worker processor
+func (h *PageJobHandler) Register(processor *worker.WorkerProcessor) {
+	processor.RegisterHandler(worker.JobTypeExtractProducts, h.Handle)
+}
+
+// Handle processes one extract_products job. A returned error makes the queue retry the job.
+func (h *PageJobHandler) Handle(ctx context.Context, job *worker.Job) error {
+	pageID, ok := job.PayloadInt(worker.PayloadFlyerPageID)
+	if !ok {
+		return h.enqueuePendingPages(ctx, job)
+	}
+
+	progress := worker.ProgressFromContext(ctx)
+	progress.Step(ctx, "loading page", 0)
+	page, err := h.pageSvc.GetByID(ctx, pageID)
+	if err != nil {
+		if apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+			log.Warn().Str("job_id", job.ID).Int("page_id", pageID).Msg("Flyer page no longer exists, dropping job")
+			return nil
+		}
+		return fmt.Errorf("failed to load flyer page %d: %w", pageID, err)
+	}
+
+	// Duplicate or late jobs for pages that already succeeded are no-ops
+	if page.ExtractionStatus == string(models.FlyerPageStatusCompleted) || page.ExtractionStatus == "warning" {
+		log.Debug().Int("page_id", page.ID).Str("status", page.ExtractionStatus).Msg("Page already extracted, skipping job")
+		return nil
+	}
+	// A job enqueued again after the first one was leased must not extract the
+	// page twice; once the lease runs out the page is free to take over
+	if page.IsProcessingLeased(time.Now()) {
+		log.Debug().Int("page_id", page.ID).Time("updated_at", page.UpdatedAt).Msg("Page is being extracted by another job, skipping job")
+		return nil
+	}
+
+	flyer, err := h.flyerSvc.GetWithStore(ctx, page.FlyerID)
+	if err != nil {
+		return fmt.Errorf("failed to load flyer %d: %w", page.FlyerID, err)
+	}
+	if flyer.IsArchived {
+		log.Info().Int("flyer_id", flyer.ID).Int("page_id", page.ID).Msg("Flyer is archived, skipping page")
+		return nil
+	}
+
+	// The flyer is processing as soon as its first page is picked up
+	if flyer.Status == string(models.FlyerStatusPending) {
+		if err := h.flyerSvc.StartProcessing(ctx, flyer.ID); err != nil {
+			log.Warn().Err(err).Int("flyer_id", flyer.ID).Msg("Failed to mark flyer as processing")
+		}
+	}
+
+	progress.Step(ctx, fmt.Sprintf("extracting page %d of flyer %d", page.PageNumber, flyer.ID), 10)
+	stats, procErr := h.enrichmentSvc.ProcessPage(ctx, flyer, page)
+
+	if _, err := h.enrichmentSvc.RefreshFlyerStatus(context.WithoutCancel(ctx), flyer.ID); err != nil {
+		log.Warn().Err(err).Int("flyer_id", flyer.ID).Msg("Failed to refresh flyer status")
+	}
+
+	if procErr != nil {
+		return fmt.Errorf("page %d extraction failed: %w", page.ID, procErr)
+	}
+	progress.Add(ctx, "products", int64(stats.ProductsExtracted))
+	progress.Add(ctx, "tokens", int64(stats.TokensUsed))
+	progress.Step(ctx, "done", 100)
+
+	log.Info().
+		Str("job_id", job.ID).
+		Int("flyer_id", flyer.ID).
+		Int("page_id", page.ID).
+		Int("products", stats.ProductsExtracted).
+		Int("tokens", stats.TokensUsed).
+		Dur("duration", stats.Duration).
+		Msg("Page extraction job completed")
+	return nil
+}
+
+// enqueuePendingPages fans a sweep job out into one job per pending page, and
+// per page whose processing lease expired
+func (h *PageJobHandler) enqueuePendingPages(ctx context.Context, job *worker.Job) error {
+	batchSize, ok := job.PayloadInt(worker.PayloadBatchSize)
+	if !ok || batchSize <= 0 {
+		batchSize = defaultSweepBatchSize
+	}
+
+	pages, err := h.pageSvc.GetPagesForProcessing(ctx, batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get pending pages: %w", err)
+	}
+
+	progress := worker.ProgressFromContext(ctx)
+	progress.Step(ctx, "enqueueing pending pages", 0)
+	if err := EnqueuePageJobs(ctx, h.queue, pages); err != nil {
+		return err
+	}
+	progress.Add(ctx, "pages", int64(len(pages)))
+	progress.Step(ctx, "done", 100)
+
+	log.Info().Str("job_id", job.ID).Int("pages", len(pages)).Msg("Enqueued pending pages for extraction")
+	return nil
+}
+
+// EnqueuePageJobs enqueues one extract_products job per page
+func EnqueuePageJobs(ctx context.Context, queue JobEnqueuer, pages []*models.FlyerPage) error {
+	for _, page := range pages {
+		if err := queue.Enqueue(ctx, worker.NewExtractProductsJob(page.FlyerID, page.ID)); err != nil {
+			return fmt.Errorf("failed to enqueue extraction of page %d: %w", page.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+type jobEnrichmentStub struct {
+	services.EnrichmentService
+
+	processErr error
+	processed  []int
+	refreshed  []int
+}
+
+func (s *jobEnrichmentStub) ProcessPage(ctx context.Context, flyer *models.Flyer, page *models.FlyerPage) (*services.EnrichmentStats, error) {
+	s.processed = append(s.processed, page.ID)
+	return &services.EnrichmentStats{PagesProcessed: 1}, s.processErr
+}
+
+func (s *jobEnrichmentStub) RefreshFlyerStatus(ctx context.Context, flyerID int) (models.FlyerStatus, error) {
+	s.refreshed = append(s.refreshed, flyerID)
+	return models.FlyerStatusProcessing, nil
+}
+
+type jobFlyerStub struct {
+	services.FlyerService
+
+	flyer   *models.Flyer
+	started []int
+}
+
+func (s *jobFlyerStub) GetWithStore(ctx context.Context, id int) (*models.Flyer, error) {
+	return s.flyer, nil
+}
+
+func (s *jobFlyerStub) StartProcessing(ctx context.Context, id int) error {
+	s.started = append(s.started, id)
+	return nil
+}
+
+type jobPageStub struct {
+	services.FlyerPageService
+
+	pages   map[int]*models.FlyerPage
+	pending []*models.FlyerPage
+	limit   int
+}
+
+func (s *jobPageStub) GetByID(ctx context.Context, id int) (*models.FlyerPage, error) {
+	page, ok := s.pages[id]
+	if !ok {
+		return nil, apperrors.NotFound("flyer page not found")
+	}
+	return page, nil
+}
+
+func (s *jobPageStub) GetPagesForProcessing(ctx context.Context, limit int) ([]*models.FlyerPage, error) {
+	s.limit = limit
+	return s.pending, nil
+}
+
+type recordingQueue struct {
+	jobs []*worker.Job
+}
+
+func (q *recordingQueue) Enqueue(ctx context.Context, job *worker.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func newJobHandlerFixture(pageStatus string) (*PageJobHandler, *jobEnrichmentStub, *jobFlyerStub, *recordingQueue) {
+	enrichmentSvc := &jobEnrichmentStub{}
+	flyerSvc := &jobFlyerStub{flyer: &models.Flyer{ID: 7, Status: string(models.FlyerStatusPending)}}
+	pageSvc := &jobPageStub{
+		pages: map[int]*models.FlyerPage{
+			70: {ID: 70, FlyerID: 7, PageNumber: 1, ExtractionStatus: pageStatus},
+		},
+		pending: []*models.FlyerPage{
+			{ID: 81, FlyerID: 8},
+			{ID: 82, FlyerID: 8},
+		},
+	}
+	queue := &recordingQueue{}
+	return NewPageJobHandler(enrichmentSvc, flyerSvc, pageSvc, queue), enrichmentSvc, flyerSvc, queue
+}
+
+func TestPageJobHandler_ProcessesPageAndRefreshesFlyer(t *testing.T) {
+	handler, enrichmentSvc, flyerSvc, _ := newJobHandlerFixture(string(models.FlyerPageStatusPending))
+
+	if err := handler.Handle(context.Background(), worker.NewExtractProductsJob(7, 70)); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+
+	if len(enrichmentSvc.processed) != 1 || enrichmentSvc.processed[0] != 70 {
+		t.Fatalf("expected page 70 processed, got %v", enrichmentSvc.processed)
+	}
+	if len(flyerSvc.started) != 1 || flyerSvc.started[0] != 7 {
+		t.Fatalf("expected pending flyer to be marked processing, got %v", flyerSvc.started)
+	}
+	if len(enrichmentSvc.refreshed) != 1 || enrichmentSvc.refreshed[0] != 7 {
+		t.Fatalf("expected flyer status refresh, got %v", enrichmentSvc.refreshed)
+	}
+}
+
+func TestPageJobHandler_ReturnsErrorSoJobRetries(t *testing.T) {
+	handler, enrichmentSvc, _, _ := newJobHandlerFixture(string(models.FlyerPageStatusPending))
+	enrichmentSvc.processErr = errors.New("vision request failed")
+
+	if err := handler.Handle(context.Background(), worker.NewExtractProductsJob(7, 70)); err == nil {
+		t.Fatal("expected extraction error to be returned")
+	}
+	if len(enrichmentSvc.refreshed) != 1 {
+		t.Fatalf("expected flyer status refresh after failure, got %v", enrichmentSvc.refreshed)
+	}
+}
+
+func TestPageJobHandler_SkipsCompletedAndMissingPages(t *testing.T) {
+	handler, enrichmentSvc, _, _ := newJobHandlerFixture(string(models.FlyerPageStatusCompleted))
+
+	if err := handler.Handle(context.Background(), worker.NewExtractProductsJob(7, 70)); err != nil {
+		t.Fatalf("Handle returned error for completed page: %v", err)
+	}
+	if err := handler.Handle(context.Background(), worker.NewExtractProductsJob(7, 99)); err != nil {
+		t.Fatalf("Handle returned error for missing page: %v", err)
+	}
+	if len(enrichmentSvc.processed) != 0 {
+		t.Fatalf("expected no pages processed, got %v", enrichmentSvc.processed)
+	}
+}
+
+func TestPageJobHandler_SkipsPagesLeasedByAnotherJob(t *testing.T) {
+	handler, enrichmentSvc, _, _ := newJobHandlerFixture(string(models.FlyerPageStatusProcessing))
+	page := handler.pageSvc.(*jobPageStub).pages[70]
+
+	page.UpdatedAt = time.Now().Add(-time.Minute)
+	if err := handler.Handle(context.Background(), worker.NewExtractProductsJob(7, 70)); err != nil {
+		t.Fatalf("Handle returned error for leased page: %v", err)
+	}
+	if len(enrichmentSvc.processed) != 0 {
+		t.Fatalf("expected leased page to be skipped, got %v", enrichmentSvc.processed)
+	}
+
+	page.UpdatedAt = time.Now().Add(-models.FlyerPageProcessingLease - time.Minute)
+	if err := handler.Handle(context.Background(), worker.NewExtractProductsJob(7, 70)); err != nil {
+		t.Fatalf("Handle returned error for page past its lease: %v", err)
+	}
+	if len(enrichmentSvc.processed) != 1 || enrichmentSvc.processed[0] != 70 {
+		t.Fatalf("expected page past its lease to be processed, got %v", enrichmentSvc.processed)
+	}
+}
+
+func TestPageJobHandler_SweepEnqueuesPendingPages(t *testing.T) {
+	handler, enrichmentSvc, _, queue := newJobHandlerFixture(string(models.FlyerPageStatusPending))
+	sweep := &worker.Job{
+		ID:      "sweep",
+		Type:    worker.JobTypeExtractProducts,
+		Payload: map[string]interface{}{worker.PayloadBatchSize: float64(20)},
+	}
+
+	if err := handler.Handle(context.Background(), sweep); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+
+	if got := handler.pageSvc.(*jobPageStub).limit; got != 20 {
+		t.Fatalf("expected batch size 20, got %d", got)
+	}
+	if len(queue.jobs) != 2 || queue.jobs[0].ID != "extract_products:81" || queue.jobs[1].ID != "extract_products:82" {
+		t.Fatalf("unexpected enqueued jobs: %+v", queue.jobs)
+	}
+	if pageID, _ := queue.jobs[0].PayloadInt(worker.PayloadFlyerPageID); pageID != 81 {
+		t.Fatalf("expected page payload 81, got %d", pageID)
+	}
+	if len(enrichmentSvc.processed) != 0 {
+		t.Fatalf("sweep must not process pages itself, got %v", enrichmentSvc.processed)
+	}
+}
@@ -3,13 +3,16 @@ package enrichment
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/kainuguru/kainuguru-api/internal/cache"
 	"github.com/kainuguru/kainuguru-api/internal/config"
 	"github.com/kainuguru/kainuguru-api/internal/database"
 	"github.com/kainuguru/kainuguru-api/internal/models"
 	"github.com/kainuguru/kainuguru-api/internal/services"
 	"github.com/kainuguru/kainuguru-api/internal/services/ai"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
 	"github.com/rs/zerolog/log"
 )
 
@@ -21,6 +24,31 @@ type ProcessOptions struct {
 	MaxPages       int
 	BatchSize      int
 	DryRun         bool
+	// Concurrency overrides the configured per-provider worker count (0 = use config)
+	Concurrency int
+
+	// ResumeRunID continues a previously started run instead of starting a new one
+	ResumeRunID int64
+	// RetryFailed re-processes only the pages that failed in the resumed run
+	RetryFailed bool
+}
+
+// runOptions converts the options into their persisted form
+func (o ProcessOptions) runOptions() models.EnrichmentRunOptions {
+	return models.EnrichmentRunOptions{
+		StoreCode:      o.StoreCode,
+		Date:           o.Date.Format("2006-01-02"),
+		ForceReprocess: o.ForceReprocess,
+		MaxPages:       o.MaxPages,
+		BatchSize:      o.BatchSize,
+	}
+}
+
+// runScope narrows which pages of each flyer a resumed run processes
+type runScope struct {
+	onlyPages  map[int][]int
+	skipPages  map[int][]int
+	retryPages map[int][]int
 }
 
 // Orchestrator orchestrates the flyer enrichment process
@@ -28,6 +56,10 @@ type Orchestrator struct {
 	db            *database.BunDB
 	cfg           *config.Config
 	enrichmentSvc services.EnrichmentService
+	flyerSvc      services.FlyerService
+	pageSvc       services.FlyerPageService
+	runSvc        services.EnrichmentRunService
+	concurrency   int
 }
 
 // NewOrchestrator creates a new enrichment orchestrator instance
@@ -45,10 +77,16 @@ func NewOrchestrator(ctx context.Context, db *database.BunDB, cfg *config.Config
 		CacheExpiry:   24 * time.Hour,
 		BatchSize:     5,
 	}
-	aiExtractor := ai.NewProductExtractor(extractorConfig)
+	limits := cfg.OpenAI.ProviderLimits()
+	openaiClient := ai.NewOpenAIClient(extractorConfig)
+	if limiter := newProviderRateLimiter(cfg, limits); limiter != nil {
+		openaiClient.SetRateLimiter(limiter)
+	}
+	aiExtractor := ai.NewProductExtractorWithClient(extractorConfig, openaiClient)
 
 	// Create service factory
 	serviceFactory := services.NewServiceFactory(db.DB)
+	runSvc := serviceFactory.EnrichmentRunService()
 
 	// Create enrichment service
 	enrichmentSvc := NewService(
@@ -57,18 +95,60 @@ func NewOrchestrator(ctx context.Context, db *database.BunDB, cfg *config.Config
 		serviceFactory.FlyerPageService(),
 		serviceFactory.ProductService(),
 		serviceFactory.ProductMasterService(),
+		runSvc,
 		aiExtractor,
+		partition.NewManager(db.DB, cfg.Partitions),
 	)
 
 	return &Orchestrator{
 		db:            db,
 		cfg:           cfg,
 		enrichmentSvc: enrichmentSvc,
+		flyerSvc:      serviceFactory.FlyerService(),
+		pageSvc:       serviceFactory.FlyerPageService(),
+		runSvc:        runSvc,
+		concurrency:   limits.Concurrency,
 	}, nil
 }
 
+// newProviderRateLimiter connects the provider's RPM/TPM budgets to Redis so that they
+// are shared with every other process using the same account. Without Redis the
+// client still backs off on 429 responses.
+func newProviderRateLimiter(cfg *config.Config, limits config.LLMProviderLimits) *cache.LLMRateLimiter {
+	if limits.RequestsPerMinute <= 0 && limits.TokensPerMinute <= 0 {
+		return nil
+	}
+
+	redisClient, err := cache.NewRedis(cache.Config{
+		Host:       cfg.Redis.Host,
+		Port:       cfg.Redis.Port,
+		Password:   cfg.Redis.Password,
+		DB:         cfg.Redis.DB,
+		MaxRetries: cfg.Redis.MaxRetries,
+		PoolSize:   cfg.Redis.PoolSize,
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Redis unavailable, LLM rate limits will not be shared across processes")
+		return nil
+	}
+
+	provider := cfg.OpenAI.ProviderName()
+	log.Info().
+		Str("provider", provider).
+		Int("concurrency", limits.Concurrency).
+		Int("requests_per_minute", limits.RequestsPerMinute).
+		Int("tokens_per_minute", limits.TokensPerMinute).
+		Msg("LLM rate limiter enabled")
+
+	return cache.NewLLMRateLimiter(redisClient.Client(), provider, limits.RequestsPerMinute, limits.TokensPerMinute)
+}
+
 // ProcessFlyers processes flyers based on provided options
 func (o *Orchestrator) ProcessFlyers(ctx context.Context, opts ProcessOptions) error {
+	if opts.ResumeRunID != 0 {
+		return o.resumeRun(ctx, opts)
+	}
+
 	log.Info().Msg("Starting flyer processing")
 
 	// Get eligible flyers
@@ -88,7 +168,157 @@ func (o *Orchestrator) ProcessFlyers(ctx context.Context, opts ProcessOptions) e
 		return o.dryRun(flyers)
 	}
 
-	return o.processAllFlyers(ctx, flyers, opts)
+	run, err := o.runSvc.StartRun(ctx, opts.runOptions())
+	if err != nil {
+		return fmt.Errorf("failed to start enrichment run: %w", err)
+	}
+	log.Info().Int64("run_id", run.ID).Msg("Enrichment run started (use --resume to continue it if interrupted)")
+
+	return o.finishRun(ctx, run.ID, o.processAllFlyers(ctx, run.ID, flyers, opts, runScope{}))
+}
+
+// resumeRun continues an earlier run using the options it was started with
+func (o *Orchestrator) resumeRun(ctx context.Context, opts ProcessOptions) error {
+	run, err := o.runSvc.GetByID(ctx, opts.ResumeRunID)
+	if err != nil {
+		return fmt.Errorf("failed to load enrichment run %d: %w", opts.ResumeRunID, err)
+	}
+	if !opts.RetryFailed && !run.CanResume() {
+		return fmt.Errorf("enrichment run %d is already completed, use --retry-failed to retry its failed pages", run.ID)
+	}
+
+	date, err := time.Parse("2006-01-02", run.Options.Date)
+	if err != nil {
+		return fmt.Errorf("enrichment run %d has invalid date %q: %w", run.ID, run.Options.Date, err)
+	}
+	opts.StoreCode = run.Options.StoreCode
+	opts.Date = date
+	opts.ForceReprocess = run.Options.ForceReprocess
+	opts.MaxPages = run.Options.MaxPages
+	opts.BatchSize = run.Options.BatchSize
+
+	pages, err := o.runSvc.GetPages(ctx, run.ID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load pages of enrichment run %d: %w", run.ID, err)
+	}
+
+	scope := runScope{
+		onlyPages:  map[int][]int{},
+		skipPages:  map[int][]int{},
+		retryPages: map[int][]int{},
+	}
+	completed := 0
+	for _, page := range pages {
+		switch {
+		case page.IsCompleted():
+			scope.skipPages[page.FlyerID] = append(scope.skipPages[page.FlyerID], page.FlyerPageID)
+			completed++
+		case page.IsFailed():
+			scope.onlyPages[page.FlyerID] = append(scope.onlyPages[page.FlyerID], page.FlyerPageID)
+		case page.Status == string(models.EnrichmentRunPageStatusProcessing):
+			scope.retryPages[page.FlyerID] = append(scope.retryPages[page.FlyerID], page.FlyerPageID)
+		}
+	}
+
+	var flyers []*models.Flyer
+	if opts.RetryFailed {
+		// Only the flyers owning failed pages are revisited
+		scope.skipPages = nil
+		scope.retryPages = nil
+		for flyerID := range scope.onlyPages {
+			flyer, err := o.flyerSvc.GetWithStore(ctx, flyerID)
+			if err != nil {
+				return fmt.Errorf("failed to load flyer %d: %w", flyerID, err)
+			}
+			flyers = append(flyers, flyer)
+		}
+		sort.Slice(flyers, func(i, j int) bool { return flyers[i].ID < flyers[j].ID })
+		opts.MaxPages = 0
+	} else {
+		// Failed pages are left for --retry-failed
+		scope.onlyPages = nil
+		flyers, err = o.enrichmentSvc.GetEligibleFlyers(ctx, opts.Date, opts.StoreCode)
+		if err != nil {
+			return fmt.Errorf("failed to get eligible flyers: %w", err)
+		}
+		if opts.MaxPages > 0 {
+			opts.MaxPages -= completed
+			if opts.MaxPages <= 0 {
+				log.Info().Int64("run_id", run.ID).Msg("Run already reached its maximum pages limit")
+				_, err := o.runSvc.FinishRun(ctx, run.ID, nil)
+				return err
+			}
+		}
+	}
+
+	log.Info().
+		Int64("run_id", run.ID).
+		Bool("retry_failed", opts.RetryFailed).
+		Int("pages_completed", completed).
+		Int("flyers", len(flyers)).
+		Msg("Resuming enrichment run")
+
+	if opts.DryRun {
+		return o.dryRun(flyers)
+	}
+
+	if len(flyers) == 0 {
+		log.Info().Int64("run_id", run.ID).Msg("Nothing left to process in run")
+		return nil
+	}
+
+	if _, err := o.runSvc.ResumeRun(ctx, run.ID, opts.RetryFailed); err != nil {
+		return fmt.Errorf("failed to resume enrichment run %d: %w", run.ID, err)
+	}
+
+	return o.finishRun(ctx, run.ID, o.processAllFlyers(ctx, run.ID, flyers, opts, scope))
+}
+
+// PageJobHandler returns a handler that runs per-page extraction jobs with this orchestrator's services
+func (o *Orchestrator) PageJobHandler(queue JobEnqueuer) *PageJobHandler {
+	return NewPageJobHandler(o.enrichmentSvc, o.flyerSvc, o.pageSvc, queue)
+}
+
+// Concurrency returns the number of pages the configured provider may extract in parallel
+func (o *Orchestrator) Concurrency() int {
+	return o.concurrency
+}
+
+// workerCount returns the number of pages extracted in parallel
+func (o *Orchestrator) workerCount(opts ProcessOptions) int {
+	if opts.Concurrency > 0 {
+		return opts.Concurrency
+	}
+	return o.concurrency
+}
+
+// finishRun persists the final run state, even when the context was cancelled
+func (o *Orchestrator) finishRun(ctx context.Context, runID int64, runErr error) error {
+	run, err := o.runSvc.FinishRun(context.WithoutCancel(ctx), runID, runErr)
+	if err != nil {
+		log.Error().Err(err).Int64("run_id", runID).Msg("Failed to record enrichment run result")
+		if runErr != nil {
+			return runErr
+		}
+		return err
+	}
+
+	log.Info().
+		Int64("run_id", run.ID).
+		Str("status", run.Status).
+		Int("pages_completed", run.PagesCompleted).
+		Int("pages_failed", run.PagesFailed).
+		Int("products_extracted", run.ProductsExtracted).
+		Int("tokens_used", run.TokensUsed).
+		Msg("Enrichment run finished")
+
+	if run.PagesFailed > 0 {
+		log.Info().
+			Int64("run_id", run.ID).
+			Msgf("Some pages failed, re-run with --resume %d --retry-failed to retry them", run.ID)
+	}
+
+	return runErr
 }
 
 func (o *Orchestrator) dryRun(flyers []*models.Flyer) error {
@@ -111,7 +341,7 @@ func (o *Orchestrator) dryRun(flyers []*models.Flyer) error {
 	return nil
 }
 
-func (o *Orchestrator) processAllFlyers(ctx context.Context, flyers []*models.Flyer, opts ProcessOptions) error {
+func (o *Orchestrator) processAllFlyers(ctx context.Context, runID int64, flyers []*models.Flyer, opts ProcessOptions, scope runScope) error {
 	totalProcessed := 0
 	totalProducts := 0
 	flyersProcessedCount := 0
@@ -157,9 +387,17 @@ func (o *Orchestrator) processAllFlyers(ctx context.Context, flyers []*models.Fl
 			ForceReprocess: opts.ForceReprocess,
 			MaxPages:       remainingPages,
 			BatchSize:      opts.BatchSize,
+			Concurrency:    o.workerCount(opts),
+			RunID:          runID,
+			OnlyPageIDs:    scope.onlyPages[flyer.ID],
+			SkipPageIDs:    scope.skipPages[flyer.ID],
+			RetryPageIDs:   scope.retryPages[flyer.ID],
 		})
 
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			log.Error().
 				Err(err).
 				Int("flyer_id", flyer.ID).
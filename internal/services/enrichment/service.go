@@ -7,11 +7,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kainuguru/kainuguru-api/internal/models"
 	"github.com/kainuguru/kainuguru-api/internal/services"
 	"github.com/kainuguru/kainuguru-api/internal/services/ai"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
 	"github.com/rs/zerolog/log"
 	"github.com/uptrace/bun"
 )
@@ -22,7 +24,9 @@ type service struct {
 	pageService    services.FlyerPageService
 	productService services.ProductService
 	masterService  services.ProductMasterService
+	runService     services.EnrichmentRunService
 	aiExtractor    *ai.ProductExtractor
+	partitions     *partition.Manager
 }
 
 // NewService creates a new enrichment service
@@ -32,7 +36,9 @@ func NewService(
 	pageService services.FlyerPageService,
 	productService services.ProductService,
 	masterService services.ProductMasterService,
+	runService services.EnrichmentRunService,
 	aiExtractor *ai.ProductExtractor,
+	partitions *partition.Manager,
 ) services.EnrichmentService {
 	return &service{
 		db:             db,
@@ -40,7 +46,9 @@ func NewService(
 		pageService:    pageService,
 		productService: productService,
 		masterService:  masterService,
+		runService:     runService,
 		aiExtractor:    aiExtractor,
+		partitions:     partitions,
 	}
 }
 
@@ -123,7 +131,7 @@ func (s *service) ProcessFlyer(ctx context.Context, flyer *models.Flyer, opts se
 
 		batch := pages[i:end]
 
-		batchStats, err := s.processBatch(ctx, flyer, batch)
+		batchStats, err := s.processBatch(ctx, flyer, batch, opts)
 		if err != nil {
 			log.Error().Err(err).Msg("Batch processing failed")
 			stats.PagesFailed += len(batch)
@@ -133,6 +141,7 @@ func (s *service) ProcessFlyer(ctx context.Context, flyer *models.Flyer, opts se
 		stats.PagesProcessed += batchStats.PagesProcessed
 		stats.PagesFailed += batchStats.PagesFailed
 		stats.ProductsExtracted += batchStats.ProductsExtracted
+		stats.TokensUsed += batchStats.TokensUsed
 	}
 
 	// Calculate average confidence
@@ -140,11 +149,91 @@ func (s *service) ProcessFlyer(ctx context.Context, flyer *models.Flyer, opts se
 		stats.AvgConfidence = stats.AvgConfidence / float64(stats.PagesProcessed)
 	}
 
+	if _, err := s.RefreshFlyerStatus(context.WithoutCancel(ctx), flyer.ID); err != nil {
+		log.Warn().Err(err).Int("flyer_id", flyer.ID).Msg("Failed to refresh flyer status")
+	}
+
 	stats.Duration = time.Since(startTime)
 
 	return stats, nil
 }
 
+// ProcessPage processes a single flyer page, as done by extract_products queue jobs
+func (s *service) ProcessPage(ctx context.Context, flyer *models.Flyer, page *models.FlyerPage) (*services.EnrichmentStats, error) {
+	startTime := time.Now()
+	stats := &services.EnrichmentStats{}
+
+	result, err := s.extractPage(ctx, flyer, page)
+	if err != nil {
+		stats.PagesFailed++
+		if result != nil {
+			stats.TokensUsed = result.TokensUsed
+		}
+		stats.Duration = time.Since(startTime)
+		return stats, err
+	}
+
+	pageStats, err := s.storePageResult(ctx, flyer, page, result)
+	if pageStats != nil {
+		stats.TokensUsed = pageStats.TokensUsed
+	}
+	if err != nil {
+		stats.PagesFailed++
+		stats.Duration = time.Since(startTime)
+		return stats, err
+	}
+
+	stats.PagesProcessed = 1
+	stats.ProductsExtracted = pageStats.ProductCount
+	stats.AvgConfidence = pageStats.AvgConfidence
+	stats.Duration = time.Since(startTime)
+	return stats, nil
+}
+
+// RefreshFlyerStatus rolls the flyer status up from the extraction status of its pages
+func (s *service) RefreshFlyerStatus(ctx context.Context, flyerID int) (models.FlyerStatus, error) {
+	pages, err := s.pageService.GetByFlyerID(ctx, flyerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get pages of flyer %d: %w", flyerID, err)
+	}
+	status := models.RollupFlyerStatus(pages)
+
+	flyer, err := s.flyerService.GetByID(ctx, flyerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get flyer %d: %w", flyerID, err)
+	}
+
+	switch status {
+	case models.FlyerStatusProcessing:
+		if flyer.Status != string(status) {
+			err = s.flyerService.StartProcessing(ctx, flyerID)
+		}
+	case models.FlyerStatusCompleted:
+		// Always refreshed: a retried page can change the product count
+		count, countErr := s.productService.Count(ctx, services.ProductFilters{FlyerIDs: []int{flyerID}})
+		if countErr != nil {
+			return "", fmt.Errorf("failed to count products of flyer %d: %w", flyerID, countErr)
+		}
+		err = s.flyerService.CompleteProcessing(ctx, flyerID, count)
+	case models.FlyerStatusFailed:
+		if flyer.Status != string(status) {
+			err = s.flyerService.FailProcessing(ctx, flyerID)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to update status of flyer %d: %w", flyerID, err)
+	}
+
+	if flyer.Status != string(status) {
+		log.Info().
+			Int("flyer_id", flyerID).
+			Str("from", flyer.Status).
+			Str("to", string(status)).
+			Msg("Flyer status updated")
+	}
+	return status, nil
+}
+
 // getPagesToProcess retrieves pages that need processing
 func (s *service) getPagesToProcess(ctx context.Context, flyerID int, opts services.EnrichmentOptions) ([]*models.FlyerPage, error) {
 	pages, err := s.pageService.GetByFlyerID(ctx, flyerID)
@@ -152,6 +241,10 @@ func (s *service) getPagesToProcess(ctx context.Context, flyerID int, opts servi
 		return nil, err
 	}
 
+	only := intSet(opts.OnlyPageIDs)
+	skip := intSet(opts.SkipPageIDs)
+	retry := intSet(opts.RetryPageIDs)
+
 	var toProcess []*models.FlyerPage
 
 	for _, page := range pages {
@@ -160,6 +253,27 @@ func (s *service) getPagesToProcess(ctx context.Context, flyerID int, opts servi
 			break
 		}
 
+		// Pages already completed by the run being resumed
+		if skip[page.ID] {
+			continue
+		}
+
+		// Explicitly requested pages (failed or interrupted in an earlier run) are
+		// retried regardless of the status left behind by that run
+		if len(only) > 0 || retry[page.ID] {
+			if len(only) > 0 && !only[page.ID] {
+				continue
+			}
+			if !page.HasImage() {
+				log.Warn().
+					Int("page_id", page.ID).
+					Msg("Page has no image URL")
+				continue
+			}
+			toProcess = append(toProcess, page)
+			continue
+		}
+
 		// Skip if already completed and not forcing reprocess
 		if page.ExtractionStatus == "completed" && !opts.ForceReprocess {
 			continue
@@ -213,43 +327,149 @@ func (s *service) getPagesToProcess(ctx context.Context, flyerID int, opts servi
 	return toProcess, nil
 }
 
-// processBatch processes a batch of pages
-func (s *service) processBatch(ctx context.Context, flyer *models.Flyer, pages []*models.FlyerPage) (*services.EnrichmentStats, error) {
+// pageExtraction holds the outcome of the AI extraction phase for one page
+type pageExtraction struct {
+	result *ai.ExtractionResult
+	err    error
+}
+
+// processBatch processes a batch of pages.
+// AI extraction runs on up to opts.Concurrency workers, then results are stored in
+// page order so that product insertion and master matching stay deterministic.
+func (s *service) processBatch(ctx context.Context, flyer *models.Flyer, pages []*models.FlyerPage, opts services.EnrichmentOptions) (*services.EnrichmentStats, error) {
 	stats := &services.EnrichmentStats{}
 
-	for _, page := range pages {
-		select {
-		case <-ctx.Done():
+	extractions := s.extractPages(ctx, flyer, pages, opts)
+
+	for i, page := range pages {
+		extraction := extractions[i]
+		if extraction == nil || ctx.Err() != nil {
+			// Interrupted: pages stay "processing" in the run and are retried on resume
 			return stats, ctx.Err()
-		default:
 		}
 
-		pageStats, err := s.processPage(ctx, flyer, page)
+		var pageStats *PageProcessingStats
+		err := extraction.err
+		if err == nil {
+			pageStats, err = s.storePageResult(ctx, flyer, page, extraction.result)
+		} else if extraction.result != nil {
+			// Tokens spent on a failed extraction are still billed
+			pageStats = &PageProcessingStats{TokensUsed: extraction.result.TokensUsed}
+		}
+		if err != nil && ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
 		if err != nil {
 			log.Error().
 				Err(err).
 				Int("page_id", page.ID).
 				Msg("Failed to process page")
 			stats.PagesFailed++
+			tokens := 0
+			if pageStats != nil {
+				tokens = pageStats.TokensUsed
+			}
+			stats.TokensUsed += tokens
+			s.recordPageFailure(ctx, opts.RunID, page, tokens, err)
 			continue
 		}
 
 		stats.PagesProcessed++
 		stats.ProductsExtracted += pageStats.ProductCount
+		stats.TokensUsed += pageStats.TokensUsed
 		stats.AvgConfidence += pageStats.AvgConfidence
+		s.recordPageSuccess(ctx, opts.RunID, page, pageStats)
 	}
 
 	return stats, nil
 }
 
+// extractPages runs AI extraction for the pages on a bounded worker pool.
+// Results are indexed like pages; a nil entry means the page was never started
+// because the context was cancelled.
+func (s *service) extractPages(ctx context.Context, flyer *models.Flyer, pages []*models.FlyerPage, opts services.EnrichmentOptions) []*pageExtraction {
+	results := make([]*pageExtraction, len(pages))
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(pages) {
+		workers = len(pages)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				page := pages[i]
+				s.recordPageStart(ctx, opts.RunID, page)
+				result, err := s.extractPage(ctx, flyer, page)
+				results[i] = &pageExtraction{result: result, err: err}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range pages {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// recordPageStart marks the page as processing in the current run, if any
+func (s *service) recordPageStart(ctx context.Context, runID int64, page *models.FlyerPage) {
+	if runID == 0 || s.runService == nil {
+		return
+	}
+	if err := s.runService.StartPage(ctx, runID, page); err != nil {
+		log.Warn().Err(err).Int64("run_id", runID).Int("page_id", page.ID).Msg("Failed to record page start")
+	}
+}
+
+// recordPageSuccess stores the page outcome in the current run, if any
+func (s *service) recordPageSuccess(ctx context.Context, runID int64, page *models.FlyerPage, pageStats *PageProcessingStats) {
+	if runID == 0 || s.runService == nil {
+		return
+	}
+	if err := s.runService.CompletePage(ctx, runID, page, pageStats.ProductCount, pageStats.TokensUsed); err != nil {
+		log.Warn().Err(err).Int64("run_id", runID).Int("page_id", page.ID).Msg("Failed to record page completion")
+	}
+}
+
+// recordPageFailure stores the page error in the current run, if any
+func (s *service) recordPageFailure(ctx context.Context, runID int64, page *models.FlyerPage, tokensUsed int, pageErr error) {
+	if runID == 0 || s.runService == nil {
+		return
+	}
+	if err := s.runService.FailPage(ctx, runID, page, tokensUsed, pageErr); err != nil {
+		log.Warn().Err(err).Int64("run_id", runID).Int("page_id", page.ID).Msg("Failed to record page failure")
+	}
+}
+
 // PageProcessingStats contains statistics for a single page
 type PageProcessingStats struct {
 	ProductCount  int
 	AvgConfidence float64
+	TokensUsed    int
 }
 
-// processPage processes a single flyer page
-func (s *service) processPage(ctx context.Context, flyer *models.Flyer, page *models.FlyerPage) (*PageProcessingStats, error) {
+// extractPage marks the page as processing and runs AI extraction on its image.
+// It is safe to call concurrently for different pages.
+func (s *service) extractPage(ctx context.Context, flyer *models.Flyer, page *models.FlyerPage) (*ai.ExtractionResult, error) {
 	log.Info().
 		Int("page_id", page.ID).
 		Int("page_number", page.PageNumber).
@@ -294,9 +514,17 @@ func (s *service) processPage(ctx context.Context, flyer *models.Flyer, page *mo
 		errMsg := err.Error()
 		page.ExtractionError = &errMsg
 		s.pageService.Update(ctx, page)
-		return nil, fmt.Errorf("AI extraction failed: %w", err)
+		return result, fmt.Errorf("AI extraction failed: %w", err)
 	}
 
+	return result, nil
+}
+
+// storePageResult persists the extracted products of a page and updates its status
+func (s *service) storePageResult(ctx context.Context, flyer *models.Flyer, page *models.FlyerPage, result *ai.ExtractionResult) (*PageProcessingStats, error) {
+	// Tokens are spent from here on, even if the page ends up failing
+	usage := &PageProcessingStats{TokensUsed: result.TokensUsed}
+
 	// Store raw extraction data
 	page.RawExtractionData = map[string]interface{}{
 		"extracted_at":    result.ExtractedAt,
@@ -320,32 +548,39 @@ func (s *service) processPage(ctx context.Context, flyer *models.Flyer, page *mo
 		if extractionCount == 0 {
 			extractionCount = len(result.Products)
 		}
-		return nil, fmt.Errorf("extraction quality failed: %d promotions", extractionCount)
+		return usage, fmt.Errorf("extraction quality failed: %d promotions", extractionCount)
 	}
 
-	// Create products from promotions
+	// Create products from promotions, replacing any left over from an earlier
+	// attempt so that re-processing a page never duplicates its products. A page
+	// without products still drops what an earlier attempt stored, with its matches.
 	products := s.convertToProducts(result, flyer, page)
-	if len(products) > 0 {
-		if err := s.productService.CreateBatch(ctx, products); err != nil {
-			page.ExtractionStatus = "failed"
-			errMsg := fmt.Sprintf("Failed to create products: %v", err)
-			page.ExtractionError = &errMsg
-			s.pageService.Update(ctx, page)
-			return nil, fmt.Errorf("failed to create products: %w", err)
-		}
+	if err := s.ensurePartitions(ctx, products); err != nil {
+		page.ExtractionStatus = "failed"
+		errMsg := fmt.Sprintf("Failed to prepare products partition: %v", err)
+		page.ExtractionError = &errMsg
+		s.pageService.Update(ctx, page)
+		return usage, err
+	}
+	if err := s.productService.ReplacePageProducts(ctx, page.ID, products); err != nil {
+		page.ExtractionStatus = "failed"
+		errMsg := fmt.Sprintf("Failed to create products: %v", err)
+		page.ExtractionError = &errMsg
+		s.pageService.Update(ctx, page)
+		return usage, fmt.Errorf("failed to create products: %w", err)
+	}
 
-		// Match products to masters or create new masters
-		if err := s.matchProductsToMasters(ctx, products); err != nil {
-			log.Warn().Err(err).Msg("Failed to match products to masters")
-			// Don't fail the entire batch for matching errors
-		}
+	// Match products to masters or create new masters
+	if err := s.matchProductsToMasters(ctx, products); err != nil {
+		log.Warn().Err(err).Msg("Failed to match products to masters")
+		// Don't fail the entire batch for matching errors
 	}
 
 	// Update page status
 	page.ExtractionStatus = quality.State
 	page.NeedsManualReview = quality.RequiresReview
 	if err := s.pageService.Update(ctx, page); err != nil {
-		return nil, fmt.Errorf("failed to update page: %w", err)
+		return usage, fmt.Errorf("failed to update page: %w", err)
 	}
 
 	// Calculate stats - use Promotions if available
@@ -368,10 +603,9 @@ func (s *service) processPage(ctx context.Context, flyer *models.Flyer, page *mo
 		avgConfidence = totalConfidence / float64(productCount)
 	}
 
-	return &PageProcessingStats{
-		ProductCount:  productCount,
-		AvgConfidence: avgConfidence,
-	}, nil
+	usage.ProductCount = productCount
+	usage.AvgConfidence = avgConfidence
+	return usage, nil
 }
 
 // QualityAssessment represents quality assessment results
@@ -435,6 +669,18 @@ func (s *service) assessQuality(result *ai.ExtractionResult) *QualityAssessment
 	return assessment
 }
 
+// ensurePartitions creates the products partitions the products' valid_from
+// fall in, so flyers valid weeks ahead don't fail to insert
+func (s *service) ensurePartitions(ctx context.Context, products []*models.Product) error {
+	if s.partitions == nil {
+		return nil
+	}
+	if err := s.partitions.EnsureForProducts(ctx, products); err != nil {
+		return fmt.Errorf("failed to ensure products partitions: %w", err)
+	}
+	return nil
+}
+
 // convertToProducts converts AI extracted promotions to Product models
 // Now uses result.Promotions to capture ALL modules (including percent-only ones)
 func (s *service) convertToProducts(result *ai.ExtractionResult, flyer *models.Flyer, page *models.FlyerPage) []*models.Product {
@@ -496,6 +742,7 @@ func (s *service) convertToProducts(result *ai.ExtractionResult, flyer *models.F
 		if promo.UnitSize != "" {
 			product.UnitSize = &promo.UnitSize
 		}
+		product.Barcode = parseBarcode(promo.Barcode)
 
 		if promo.Brand != "" {
 			product.Brand = &promo.Brand
@@ -505,12 +752,13 @@ func (s *service) convertToProducts(result *ai.ExtractionResult, flyer *models.F
 			product.Category = &promo.CategoryGuessLT
 		}
 
-		// Set special discount/tags
-		if len(promo.SpecialTags) > 0 {
-			specialDiscount := strings.Join(promo.SpecialTags, ", ")
+		// Set special discount/tags; bundle mechanics may only be described in the bundle or discount text
+		if specialDiscount := promotionText(promo); specialDiscount != "" {
 			product.SpecialDiscount = &specialDiscount
 			product.IsOnSale = true
 		}
+		memberPrice := applyPriceTier(product, promo.PriceTier)
+		product.ApplyPromotion(memberPrice || promo.LoyaltyRequired || promo.PromotionType == "loyalty")
 
 		// Set bounding box if valid
 		if promo.BoundingBox != nil && promo.BoundingBox.Width > 0 && promo.BoundingBox.Height > 0 {
@@ -563,6 +811,7 @@ func (s *service) convertToProducts(result *ai.ExtractionResult, flyer *models.F
 			if extracted.Unit != "" {
 				product.UnitSize = &extracted.Unit
 			}
+			product.Barcode = parseBarcode(extracted.Barcode)
 			if extracted.Brand != "" {
 				product.Brand = &extracted.Brand
 			}
@@ -573,6 +822,7 @@ func (s *service) convertToProducts(result *ai.ExtractionResult, flyer *models.F
 				product.SpecialDiscount = &extracted.SpecialDiscount
 				product.IsOnSale = true
 			}
+			product.ApplyPromotion(extracted.DiscountType == "loyalty")
 			if extracted.BoundingBox != nil && extracted.BoundingBox.Width > 0 && extracted.BoundingBox.Height > 0 {
 				product.BoundingBox = extracted.BoundingBox
 			}
@@ -597,8 +847,10 @@ func (s *service) matchProductsToMasters(ctx context.Context, products []*models
 			continue
 		}
 
-		// Auto-link if confidence is high enough (>= 0.85)
-		if len(matches) > 0 && matches[0].Confidence >= 0.85 {
+		thresholds := s.masterService.GetMatchThresholds(ctx, product.Category)
+
+		// Auto-link if confidence is high enough
+		if len(matches) > 0 && matches[0].Confidence >= thresholds.AutoMatch {
 			masterIDInt := int(matches[0].Master.ID)
 			product.ProductMasterID = &masterIDInt
 			if err := s.productService.Update(ctx, product); err != nil {
@@ -612,8 +864,8 @@ func (s *service) matchProductsToMasters(ctx context.Context, products []*models
 			continue
 		}
 
-		// Create new master if no good match found (confidence < 0.65)
-		if len(matches) == 0 || matches[0].Confidence < 0.65 {
+		// Create new master if no good match found
+		if len(matches) == 0 || matches[0].Confidence < thresholds.Review {
 			master, err := s.masterService.CreateFromProduct(ctx, product)
 			if err != nil {
 				log.Warn().Err(err).Int("product_id", product.ID).Msg("Failed to create product master")
@@ -633,7 +885,7 @@ func (s *service) matchProductsToMasters(ctx context.Context, products []*models
 			continue
 		}
 
-		// Medium confidence (0.65 - 0.85): flag for manual review
+		// Medium confidence: flag for manual review
 		if len(matches) > 0 {
 			// Mark product as requiring review
 			product.RequiresReview = true
@@ -697,3 +949,12 @@ func (s *service) convertImageToBase64(imageURL string) (string, error) {
 
 	return dataURI, nil
 }
+
+// intSet builds a lookup set from a slice of IDs
+func intSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,242 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/ai"
+	"github.com/kainuguru/kainuguru-api/internal/services/matching"
+	"github.com/kainuguru/kainuguru-api/pkg/openai"
+)
+
+// fakeVisionClient answers every extraction pass with a fixed set of promotions for the
+// page encoded in the image, simulating provider latency and tracking parallelism.
+type fakeVisionClient struct {
+	latency     func(page string) time.Duration
+	inFlight    int32
+	maxInFlight int32
+	calls       int32
+}
+
+func (f *fakeVisionClient) AnalyzeImage(ctx context.Context, imageURL, prompt string) (*openai.VisionResponse, error) {
+	return f.AnalyzeImageWithBase64(ctx, imageURL, prompt)
+}
+
+func (f *fakeVisionClient) AnalyzeImageWithBase64(ctx context.Context, base64Image, prompt string) (*openai.VisionResponse, error) {
+	atomic.AddInt32(&f.calls, 1)
+	current := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&f.maxInFlight)
+		if current <= max || atomic.CompareAndSwapInt32(&f.maxInFlight, max, current) {
+			break
+		}
+	}
+
+	encoded := base64Image[strings.Index(base64Image, ",")+1:]
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	page := string(raw)
+
+	if f.latency != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.latency(page)):
+		}
+	}
+
+	promotions := make([]string, 0, 5)
+	for i := 1; i <= 5; i++ {
+		promotions = append(promotions, fmt.Sprintf(
+			`{"promotion_type":"price","name_lt":"Prekė %s-%d","price_eur":"1,%02d €","original_price_eur":"2,49 €"}`, page, i, i))
+	}
+	content := fmt.Sprintf(`{"page_meta":{"store_code":"iki"},"promotions":[%s]}`, strings.Join(promotions, ","))
+
+	return &openai.VisionResponse{
+		Choices: []openai.Choice{{Message: openai.ResponseMessage{Content: content}, FinishReason: "stop"}},
+		Usage:   openai.Usage{TotalTokens: 100},
+	}, nil
+}
+
+type pageServiceStub struct {
+	services.FlyerPageService
+}
+
+func (pageServiceStub) Update(ctx context.Context, page *models.FlyerPage) error { return nil }
+
+type productServiceStub struct {
+	services.ProductService
+
+	mu       sync.Mutex
+	replaced []int
+	nextID   int
+}
+
+func (s *productServiceStub) ReplacePageProducts(ctx context.Context, flyerPageID int, products []*models.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replaced = append(s.replaced, flyerPageID)
+	for _, p := range products {
+		s.nextID++
+		p.ID = s.nextID
+	}
+	return nil
+}
+
+func (s *productServiceStub) Update(ctx context.Context, product *models.Product) error { return nil }
+
+type masterServiceStub struct {
+	services.ProductMasterService
+}
+
+func (masterServiceStub) FindBestMatch(ctx context.Context, product *models.Product, limit int) ([]*services.ProductMasterMatch, error) {
+	return nil, nil
+}
+
+func (masterServiceStub) GetMatchThresholds(ctx context.Context, category *string) matching.Thresholds {
+	return matching.DefaultThresholds
+}
+
+func (masterServiceStub) CreateFromProduct(ctx context.Context, product *models.Product) (*models.ProductMaster, error) {
+	return &models.ProductMaster{ID: int64(product.ID)}, nil
+}
+
+func newTestEnrichmentService(t testing.TB, client ai.VisionClient, pageCount int) (*service, *productServiceStub, *models.Flyer, []*models.FlyerPage) {
+	t.Helper()
+
+	storageDir := t.TempDir()
+	t.Setenv("STORAGE_BASE_PATH", storageDir)
+
+	flyer := &models.Flyer{ID: 1, StoreID: 1, Store: &models.Store{Code: "iki"}, ValidFrom: time.Now(), ValidTo: time.Now().Add(7 * 24 * time.Hour)}
+	pages := make([]*models.FlyerPage, 0, pageCount)
+	for i := 1; i <= pageCount; i++ {
+		name := fmt.Sprintf("page-%d.jpg", i)
+		if err := os.WriteFile(filepath.Join(storageDir, name), []byte(fmt.Sprintf("p%d", i)), 0o644); err != nil {
+			t.Fatalf("failed to write page image: %v", err)
+		}
+		imageURL := name
+		pages = append(pages, &models.FlyerPage{ID: i, FlyerID: flyer.ID, PageNumber: i, ImageURL: &imageURL})
+	}
+
+	products := &productServiceStub{}
+	svc := &service{
+		pageService:    pageServiceStub{},
+		productService: products,
+		masterService:  masterServiceStub{},
+		aiExtractor:    ai.NewProductExtractorWithClient(ai.DefaultExtractorConfig("sk-test"), client),
+	}
+	return svc, products, flyer, pages
+}
+
+func TestProcessBatch_ParallelExtractionKeepsPageOrder(t *testing.T) {
+	// Earlier pages are slower, so extraction finishes in reverse order
+	client := &fakeVisionClient{latency: func(page string) time.Duration {
+		var n int
+		fmt.Sscanf(page, "p%d", &n)
+		return time.Duration(10-n) * 5 * time.Millisecond
+	}}
+	svc, products, flyer, pages := newTestEnrichmentService(t, client, 8)
+
+	stats, err := svc.processBatch(context.Background(), flyer, pages, services.EnrichmentOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("processBatch returned error: %v", err)
+	}
+
+	if stats.PagesProcessed != 8 || stats.PagesFailed != 0 {
+		t.Fatalf("unexpected page stats: %+v", stats)
+	}
+	if stats.ProductsExtracted != 40 {
+		t.Fatalf("expected 40 products, got %d", stats.ProductsExtracted)
+	}
+	if stats.TokensUsed != 8*2*100 {
+		t.Fatalf("expected tokens from both passes of every page, got %d", stats.TokensUsed)
+	}
+	if client.maxInFlight < 2 || client.maxInFlight > 4 {
+		t.Fatalf("expected between 2 and 4 concurrent requests, got %d", client.maxInFlight)
+	}
+	for i, pageID := range products.replaced {
+		if pageID != i+1 {
+			t.Fatalf("products stored out of page order: %v", products.replaced)
+		}
+	}
+}
+
+func TestProcessBatch_SequentialByDefault(t *testing.T) {
+	client := &fakeVisionClient{latency: func(string) time.Duration { return time.Millisecond }}
+	svc, _, flyer, pages := newTestEnrichmentService(t, client, 3)
+
+	if _, err := svc.processBatch(context.Background(), flyer, pages, services.EnrichmentOptions{}); err != nil {
+		t.Fatalf("processBatch returned error: %v", err)
+	}
+	if client.maxInFlight != 1 {
+		t.Fatalf("expected sequential extraction, got %d concurrent requests", client.maxInFlight)
+	}
+}
+
+func TestProcessBatch_StopsOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &fakeVisionClient{latency: func(string) time.Duration {
+		cancel()
+		return time.Second
+	}}
+	svc, products, flyer, pages := newTestEnrichmentService(t, client, 6)
+
+	_, err := svc.processBatch(ctx, flyer, pages, services.EnrichmentOptions{Concurrency: 2})
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(products.replaced) != 0 {
+		t.Fatalf("expected no products stored after cancellation, got pages %v", products.replaced)
+	}
+	if calls := atomic.LoadInt32(&client.calls); calls > 2 {
+		t.Fatalf("expected no new extractions after cancellation, got %d calls", calls)
+	}
+}
+
+func TestStorePageResult_EmptyPageDropsEarlierProducts(t *testing.T) {
+	svc, products, flyer, pages := newTestEnrichmentService(t, &fakeVisionClient{}, 1)
+
+	stats, err := svc.storePageResult(context.Background(), flyer, pages[0], &ai.ExtractionResult{TokensUsed: 100})
+	if err != nil {
+		t.Fatalf("storePageResult returned error: %v", err)
+	}
+	if stats.ProductCount != 0 {
+		t.Fatalf("expected no products, got %d", stats.ProductCount)
+	}
+	if len(products.replaced) != 1 || products.replaced[0] != pages[0].ID {
+		t.Fatalf("expected the products of page %d replaced with none, got pages %v", pages[0].ID, products.replaced)
+	}
+	if pages[0].ExtractionStatus != "warning" {
+		t.Fatalf("expected an empty page to need review, got status %q", pages[0].ExtractionStatus)
+	}
+}
+
+func BenchmarkProcessBatch(b *testing.B) {
+	for _, concurrency := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			client := &fakeVisionClient{latency: func(string) time.Duration { return 2 * time.Millisecond }}
+			svc, _, flyer, pages := newTestEnrichmentService(b, client, 16)
+			opts := services.EnrichmentOptions{Concurrency: concurrency}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := svc.processBatch(context.Background(), flyer, pages, opts); err != nil {
+					b.Fatalf("processBatch returned error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -3,11 +3,14 @@ package enrichment
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
 
+	"github.com/kainuguru/kainuguru-api/internal/models"
 	"github.com/kainuguru/kainuguru-api/internal/services/ai"
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -408,3 +411,46 @@ func normalizeProductNameForMaster(name string, brand *string) string {
 
 	return normalized
 }
+
+// promotionText builds the special discount label of a promotion. Bundle deals
+// ("3 už 2 €") are often described only in the bundle details or discount text.
+func promotionText(promo ai.Promotion) string {
+	parts := make([]string, 0, len(promo.SpecialTags)+2)
+	parts = append(parts, promo.SpecialTags...)
+	if promo.PromotionType == "bundle" || promo.DiscountType == "bundle" {
+		for _, text := range []string{promo.BundleDetails, promo.DiscountText} {
+			if text = strings.TrimSpace(text); text != "" && !slices.Contains(parts, text) {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// applyPriceTier records the extracted price as a card or app price when the flyer
+// marks it as member-only, and reports whether it did
+func applyPriceTier(product *models.Product, tier string) bool {
+	price := product.CurrentPrice
+	switch models.PriceTier(strings.ToLower(strings.TrimSpace(tier))) {
+	case models.PriceTierCard:
+		product.CardPrice = &price
+	case models.PriceTierApp:
+		product.AppPrice = &price
+	default:
+		return false
+	}
+	return true
+}
+
+// parseBarcode returns the extracted barcode in GTIN-14 form, or nil when it is
+// missing or fails check-digit validation (misread digits must not match a master)
+func parseBarcode(code string) *string {
+	if strings.TrimSpace(code) == "" {
+		return nil
+	}
+	gtin, err := normalize.NormalizeGTIN(code)
+	if err != nil {
+		return nil
+	}
+	return &gtin
+}
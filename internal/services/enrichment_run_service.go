@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+
+	"github.com/kainuguru/kainuguru-api/internal/enrichmentrun"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type enrichmentRunService struct {
+	repo   enrichmentrun.Repository
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewEnrichmentRunService creates a new enrichment run service instance using the registered repository factory.
+func NewEnrichmentRunService(db *bun.DB) EnrichmentRunService {
+	return NewEnrichmentRunServiceWithRepository(newEnrichmentRunRepository(db))
+}
+
+// NewEnrichmentRunServiceWithRepository allows injecting a custom repository (useful for tests).
+func NewEnrichmentRunServiceWithRepository(repo enrichmentrun.Repository) EnrichmentRunService {
+	if repo == nil {
+		panic("enrichment run repository cannot be nil")
+	}
+	return &enrichmentRunService{
+		repo:   repo,
+		logger: slog.Default().With("service", "enrichment_run"),
+		now:    time.Now,
+	}
+}
+
+func (s *enrichmentRunService) GetByID(ctx context.Context, id int64) (*models.EnrichmentRun, error) {
+	run, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("enrichment run not found with ID %d", id))
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get enrichment run %d", id)
+	}
+	return run, nil
+}
+
+func (s *enrichmentRunService) GetRecent(ctx context.Context, limit int) ([]*models.EnrichmentRun, error) {
+	runs, err := s.repo.GetAll(ctx, &enrichmentrun.Filters{Limit: limit})
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list enrichment runs")
+	}
+	return runs, nil
+}
+
+// StartRun persists a new run in the running state
+func (s *enrichmentRunService) StartRun(ctx context.Context, opts models.EnrichmentRunOptions) (*models.EnrichmentRun, error) {
+	now := s.now()
+	run := &models.EnrichmentRun{
+		Status:    string(models.EnrichmentRunStatusRunning),
+		Options:   opts,
+		StartedAt: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, run); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to create enrichment run")
+	}
+	s.logger.Info("enrichment run started", slog.Int64("run_id", run.ID))
+	return run, nil
+}
+
+// ResumeRun moves an unfinished run back into the running state
+func (s *enrichmentRunService) ResumeRun(ctx context.Context, id int64, retryFailed bool) (*models.EnrichmentRun, error) {
+	run, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !retryFailed && !run.CanResume() {
+		return nil, apperrors.Validation(fmt.Sprintf("enrichment run %d is already completed", id))
+	}
+
+	run.Status = string(models.EnrichmentRunStatusRunning)
+	run.CompletedAt = nil
+	run.LastError = nil
+	run.UpdatedAt = s.now()
+	if err := s.repo.Update(ctx, run); err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to resume enrichment run %d", id)
+	}
+	s.logger.Info("enrichment run resumed", slog.Int64("run_id", run.ID), slog.Bool("retry_failed", retryFailed))
+	return run, nil
+}
+
+// FinishRun recomputes the run totals and records its final state.
+// A cancelled context marks the run as interrupted so that it can be resumed later.
+func (s *enrichmentRunService) FinishRun(ctx context.Context, runID int64, runErr error) (*models.EnrichmentRun, error) {
+	if err := s.repo.RefreshTotals(ctx, runID); err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to refresh totals for enrichment run %d", runID)
+	}
+
+	run, err := s.GetByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	switch {
+	case runErr == nil:
+		run.Status = string(models.EnrichmentRunStatusCompleted)
+		run.CompletedAt = &now
+	case errors.Is(runErr, context.Canceled):
+		run.Status = string(models.EnrichmentRunStatusInterrupted)
+	default:
+		run.Status = string(models.EnrichmentRunStatusFailed)
+		run.CompletedAt = &now
+	}
+	if runErr != nil {
+		msg := runErr.Error()
+		run.LastError = &msg
+	}
+	run.UpdatedAt = now
+
+	if err := s.repo.Update(ctx, run); err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to finish enrichment run %d", runID)
+	}
+	s.logger.Info("enrichment run finished",
+		slog.Int64("run_id", run.ID),
+		slog.String("status", run.Status),
+		slog.Int("pages_completed", run.PagesCompleted),
+		slog.Int("pages_failed", run.PagesFailed),
+		slog.Int("tokens_used", run.TokensUsed),
+	)
+	return run, nil
+}
+
+func (s *enrichmentRunService) GetPages(ctx context.Context, runID int64, statuses []string) ([]*models.EnrichmentRunPage, error) {
+	pages, err := s.repo.GetPages(ctx, runID, &enrichmentrun.PageFilters{Status: statuses})
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get pages for enrichment run %d", runID)
+	}
+	return pages, nil
+}
+
+// StartPage records that a page is being processed as part of the run
+func (s *enrichmentRunService) StartPage(ctx context.Context, runID int64, page *models.FlyerPage) error {
+	existing, err := s.repo.GetPage(ctx, runID, page.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to load page %d of enrichment run %d", page.ID, runID)
+	}
+
+	now := s.now()
+	row := &models.EnrichmentRunPage{
+		RunID:       runID,
+		FlyerID:     page.FlyerID,
+		FlyerPageID: page.ID,
+		Status:      string(models.EnrichmentRunPageStatusProcessing),
+		Attempts:    1,
+		StartedAt:   &now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if existing != nil {
+		row.Attempts = existing.Attempts + 1
+		// The row is overwritten, so tokens of earlier attempts are carried over
+		row.TokensUsed = existing.TokensUsed
+	}
+	return s.upsertPage(ctx, row)
+}
+
+// CompletePage records a successfully processed page with its product count and token usage
+func (s *enrichmentRunService) CompletePage(ctx context.Context, runID int64, page *models.FlyerPage, productsExtracted, tokensUsed int) error {
+	return s.finishPage(ctx, runID, page, models.EnrichmentRunPageStatusCompleted, productsExtracted, tokensUsed, nil)
+}
+
+// FailPage records a page failure and its error
+func (s *enrichmentRunService) FailPage(ctx context.Context, runID int64, page *models.FlyerPage, tokensUsed int, pageErr error) error {
+	return s.finishPage(ctx, runID, page, models.EnrichmentRunPageStatusFailed, 0, tokensUsed, pageErr)
+}
+
+func (s *enrichmentRunService) finishPage(ctx context.Context, runID int64, page *models.FlyerPage, status models.EnrichmentRunPageStatus, productsExtracted, tokensUsed int, pageErr error) error {
+	existing, err := s.repo.GetPage(ctx, runID, page.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to load page %d of enrichment run %d", page.ID, runID)
+	}
+
+	now := s.now()
+	row := &models.EnrichmentRunPage{
+		RunID:             runID,
+		FlyerID:           page.FlyerID,
+		FlyerPageID:       page.ID,
+		Status:            string(status),
+		Attempts:          1,
+		ProductsExtracted: productsExtracted,
+		TokensUsed:        tokensUsed,
+		StartedAt:         &now,
+		CompletedAt:       &now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if existing != nil {
+		row.Attempts = existing.Attempts
+		row.StartedAt = existing.StartedAt
+		// Tokens spent on earlier attempts are still billed
+		row.TokensUsed += existing.TokensUsed
+	}
+	if pageErr != nil {
+		msg := pageErr.Error()
+		row.ErrorMessage = &msg
+	}
+	return s.upsertPage(ctx, row)
+}
+
+func (s *enrichmentRunService) upsertPage(ctx context.Context, row *models.EnrichmentRunPage) error {
+	if err := s.repo.UpsertPage(ctx, row); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to record page %d of enrichment run %d", row.FlyerPageID, row.RunID)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/kainuguru/kainuguru-api/internal/enrichmentrun"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+// enrichmentRunRepoStub keeps run pages in memory; UpsertPage overwrites the
+// whole row like the repository's ON CONFLICT update
+type enrichmentRunRepoStub struct {
+	enrichmentrun.Repository
+
+	pages map[int]*models.EnrichmentRunPage
+}
+
+func (s *enrichmentRunRepoStub) GetPage(ctx context.Context, runID int64, flyerPageID int) (*models.EnrichmentRunPage, error) {
+	page, ok := s.pages[flyerPageID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *page
+	return &copied, nil
+}
+
+func (s *enrichmentRunRepoStub) UpsertPage(ctx context.Context, page *models.EnrichmentRunPage) error {
+	copied := *page
+	s.pages[page.FlyerPageID] = &copied
+	return nil
+}
+
+func TestEnrichmentRunService_RetriedPageKeepsEarlierTokens(t *testing.T) {
+	ctx := context.Background()
+	repo := &enrichmentRunRepoStub{pages: map[int]*models.EnrichmentRunPage{}}
+	svc := NewEnrichmentRunServiceWithRepository(repo)
+	page := &models.FlyerPage{ID: 10, FlyerID: 1}
+
+	if err := svc.StartPage(ctx, 1, page); err != nil {
+		t.Fatalf("StartPage returned error: %v", err)
+	}
+	if err := svc.FailPage(ctx, 1, page, 300, errors.New("vision request failed")); err != nil {
+		t.Fatalf("FailPage returned error: %v", err)
+	}
+
+	if err := svc.StartPage(ctx, 1, page); err != nil {
+		t.Fatalf("StartPage of the retry returned error: %v", err)
+	}
+	if got := repo.pages[10].TokensUsed; got != 300 {
+		t.Fatalf("expected the retry to keep the 300 tokens of the first attempt, got %d", got)
+	}
+	if err := svc.CompletePage(ctx, 1, page, 12, 500); err != nil {
+		t.Fatalf("CompletePage returned error: %v", err)
+	}
+
+	row := repo.pages[10]
+	if row.TokensUsed != 800 {
+		t.Fatalf("expected tokens of both attempts, got %d", row.TokensUsed)
+	}
+	if row.Attempts != 2 || row.ProductsExtracted != 12 || row.Status != string(models.EnrichmentRunPageStatusCompleted) {
+		t.Fatalf("unexpected page row: %+v", row)
+	}
+}
@@ -2,23 +2,33 @@ package services
 
 import (
 	"log/slog"
+	"path/filepath"
 	"time"
 
+	"github.com/kainuguru/kainuguru-api/internal/cache"
 	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/services/archive"
 	"github.com/kainuguru/kainuguru-api/internal/services/auth"
 	"github.com/kainuguru/kainuguru-api/internal/services/email"
+	"github.com/kainuguru/kainuguru-api/internal/services/partition"
 	"github.com/kainuguru/kainuguru-api/internal/services/recommendation"
 	"github.com/kainuguru/kainuguru-api/internal/services/search"
 	"github.com/kainuguru/kainuguru-api/internal/services/storage"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	image "github.com/kainuguru/kainuguru-api/pkg/image"
 	"github.com/uptrace/bun"
 )
 
 // ServiceFactory creates and manages all service instances
 type ServiceFactory struct {
-	db     *bun.DB
-	config *config.Config
+	db          *bun.DB
+	config      *config.Config
+	redis       *cache.RedisClient
+	queue       worker.Queue
+	jobProgress *worker.ProgressStore
 	// memoized services
-	authService auth.AuthService
+	authService   auth.AuthService
+	searchService search.Service
 }
 
 // NewServiceFactory creates a new service factory
@@ -36,6 +46,24 @@ func NewServiceFactoryWithConfig(db *bun.DB, cfg *config.Config) *ServiceFactory
 	}
 }
 
+// WithRedis lets services that cache shared state in Redis use the client
+func (f *ServiceFactory) WithRedis(redis *cache.RedisClient) *ServiceFactory {
+	f.redis = redis
+	return f
+}
+
+// WithJobQueue lets the job admin service reach the worker job queue
+func (f *ServiceFactory) WithJobQueue(queue worker.Queue) *ServiceFactory {
+	f.queue = queue
+	return f
+}
+
+// WithJobProgress lets the job admin service show, watch and cancel running jobs
+func (f *ServiceFactory) WithJobProgress(progress *worker.ProgressStore) *ServiceFactory {
+	f.jobProgress = progress
+	return f
+}
+
 // StoreService returns a store service instance
 func (f *ServiceFactory) StoreService() StoreService {
 	return NewStoreService(f.db)
@@ -66,10 +94,34 @@ func (f *ServiceFactory) ExtractionJobService() ExtractionJobService {
 	return NewExtractionJobService(f.db)
 }
 
-// SearchService returns a search service instance
+// JobAdminService returns a job admin service for the factory's job queue, running
+// workflows on it
+func (f *ServiceFactory) JobAdminService() JobAdminService {
+	var workflows *worker.WorkflowEngine
+	if f.queue != nil {
+		workflows = worker.NewWorkflowEngine(f.db, f.queue)
+	}
+	return NewJobAdminService(f.queue, f.jobProgress, workflows)
+}
+
+// EnrichmentRunService returns an enrichment run service instance
+func (f *ServiceFactory) EnrichmentRunService() EnrichmentRunService {
+	return NewEnrichmentRunService(f.db)
+}
+
+// SearchService returns the search service instance, sharing its synonym
+// dictionary through Redis when the factory has a Redis client
 func (f *ServiceFactory) SearchService() search.Service {
+	if f.searchService != nil {
+		return f.searchService
+	}
 	logger := slog.Default()
-	return search.NewSearchService(f.db, logger)
+	if f.redis != nil {
+		f.searchService = search.NewSearchServiceWithCache(f.db, logger, cache.NewSynonymCache(f.redis))
+	} else {
+		f.searchService = search.NewSearchService(f.db, logger)
+	}
+	return f.searchService
 }
 
 // AuthService returns an auth service instance
@@ -153,6 +205,50 @@ func (f *ServiceFactory) FlyerStorageService() storage.FlyerStorageService {
 	)
 }
 
+// ArchiverService returns an archiver writing archives below the storage base path
+func (f *ServiceFactory) ArchiverService() archive.ArchiverService {
+	basePath := "../kainuguru-public"
+	var partitions config.PartitionConfig
+	if f.config != nil {
+		basePath = f.config.Storage.BasePath
+		partitions = f.config.Partitions
+	}
+	return archive.NewArchiverService(
+		f.db,
+		archive.NewFileSystemStorage(filepath.Join(basePath, "archives")),
+		partition.NewManager(f.db, partitions),
+		nil,
+	)
+}
+
+// CleanerService returns a cleaner over the files below the storage base path.
+// Images are only recompressed when ImageMagick is installed.
+func (f *ServiceFactory) CleanerService() archive.CleanerService {
+	basePath, publicURL := "../kainuguru-public", "http://localhost:8080"
+	cleanerConfig := &archive.CleanerServiceConfig{
+		ImageBasePath:   "flyers",
+		TempBasePath:    "tmp",
+		ArchiveBasePath: "archives",
+	}
+	if f.config != nil {
+		basePath, publicURL = f.config.Storage.BasePath, f.config.Storage.PublicURL
+		cleanerConfig.WebPQuality = f.config.Cleanup.WebPQuality
+		cleanerConfig.OptimizeAfter = time.Duration(f.config.Cleanup.OptimizeAfterDays) * 24 * time.Hour
+	}
+
+	var encoder archive.ImageEncoder
+	if webp := image.NewWebPEncoder(); webp.Available() {
+		encoder = webp
+	}
+	return archive.NewCleanerService(
+		f.db,
+		archive.NewLocalFileStorage(basePath),
+		archive.NewImageRepository(f.db, publicURL),
+		encoder,
+		cleanerConfig,
+	)
+}
+
 // Close closes all connections and resources
 func (f *ServiceFactory) Close() error {
 	// Close database connection if needed
@@ -143,8 +143,22 @@ func (s *flyerPageService) GetProcessablePages(ctx context.Context) ([]*models.F
 	return nil, fmt.Errorf("flyerPageService.GetProcessablePages not implemented")
 }
 
+// GetPagesForProcessing returns pending pages with an image that have not exhausted their extraction attempts,
+// along with pages whose processing lease expired
 func (s *flyerPageService) GetPagesForProcessing(ctx context.Context, limit int) ([]*models.FlyerPage, error) {
-	return nil, fmt.Errorf("flyerPageService.GetPagesForProcessing not implemented")
+	hasImage := true
+	leaseExpired := time.Now().Add(-models.FlyerPageProcessingLease)
+	pages, err := s.repo.GetAll(ctx, &flyerpage.Filters{
+		Status:           []string{string(models.FlyerPageStatusPending)},
+		ProcessingBefore: &leaseExpired,
+		HasImage:         &hasImage,
+		MaxAttempts:      3,
+		Limit:            limit,
+	})
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get flyer pages for processing")
+	}
+	return pages, nil
 }
 
 func (s *flyerPageService) StartProcessing(ctx context.Context, pageID int) error {
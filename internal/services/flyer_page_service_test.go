@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/kainuguru/kainuguru-api/internal/flyerpage"
 	"github.com/kainuguru/kainuguru-api/internal/models"
@@ -309,6 +310,33 @@ func TestFlyerPageService_GetPagesByFlyerIDsDelegates(t *testing.T) {
 	}
 }
 
+func TestFlyerPageService_GetPagesForProcessingFiltersPendingPages(t *testing.T) {
+	ctx := context.Background()
+	repo := &flyerPageRepoStub{
+		getAllFunc: func(ctx context.Context, filters *flyerpage.Filters) ([]*models.FlyerPage, error) {
+			if filters == nil || len(filters.Status) != 1 || filters.Status[0] != string(models.FlyerPageStatusPending) {
+				t.Fatalf("expected pending status filter, got %+v", filters)
+			}
+			if filters.ProcessingBefore == nil || time.Since(*filters.ProcessingBefore) < models.FlyerPageProcessingLease {
+				t.Fatalf("expected pages past their processing lease, got %+v", filters.ProcessingBefore)
+			}
+			if filters.HasImage == nil || !*filters.HasImage || filters.MaxAttempts != 3 || filters.Limit != 50 {
+				t.Fatalf("unexpected filters: %+v", filters)
+			}
+			return []*models.FlyerPage{{ID: 7}}, nil
+		},
+	}
+	svc := &flyerPageService{repo: repo}
+
+	pages, err := svc.GetPagesForProcessing(ctx, 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pages) != 1 || pages[0].ID != 7 {
+		t.Fatalf("unexpected pages: %+v", pages)
+	}
+}
+
 type flyerPageRepoStub struct {
 	getByIDFunc            func(ctx context.Context, id int) (*models.FlyerPage, error)
 	getByIDsFunc           func(ctx context.Context, ids []int) ([]*models.FlyerPage, error)
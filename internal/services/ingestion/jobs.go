@@ -0,0 +1,133 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/scraper"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	"github.com/rs/zerolog/log"
+)
+
+// ScrapeJobHandler runs scrape_flyer jobs from the job queue. A job covers
+// the stores listed in its payload, or every store with a scraper when it lists none.
+// It fans out into one rasterize_flyer job per new flyer, which the handler runs too.
+type ScrapeJobHandler struct {
+	pipeline *Pipeline
+	scrapers map[string]scraper.Scraper
+}
+
+// NewScrapeJobHandler creates a handler for scrape_flyer jobs
+func NewScrapeJobHandler(pipeline *Pipeline, scrapers []scraper.Scraper) *ScrapeJobHandler {
+	byCode := make(map[string]scraper.Scraper, len(scrapers))
+	for _, s := range scrapers {
+		byCode[s.GetStoreInfo().Code] = s
+	}
+	return &ScrapeJobHandler{
+		pipeline: pipeline,
+		scrapers: byCode,
+	}
+}
+
+// Register installs the handler on a worker processor
+func (h *ScrapeJobHandler) Register(processor *worker.WorkerProcessor) {
+	processor.RegisterHandler(worker.JobTypeScrapeFlyer, h.Handle)
+	processor.RegisterHandler(worker.JobTypeRasterizeFlyer, h.HandleRasterize)
+}
+
+// Handle processes one scrape_flyer job. A returned error makes the queue retry the
+// job; flyers ingested by the failed attempt are skipped on retry by source URL.
+func (h *ScrapeJobHandler) Handle(ctx context.Context, job *worker.Job) error {
+	storeCodes, ok := job.PayloadStrings(worker.PayloadStores)
+	if !ok || len(storeCodes) == 0 {
+		storeCodes = make([]string, 0, len(h.scrapers))
+		for code := range h.scrapers {
+			storeCodes = append(storeCodes, code)
+		}
+	}
+
+	progress := worker.ProgressFromContext(ctx)
+	var errs []error
+	for i, code := range storeCodes {
+		// Stop between stores once the job is cancelled or times out
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		s, exists := h.scrapers[code]
+		if !exists {
+			log.Warn().Str("job_id", job.ID).Str("store", code).Msg("No scraper for store, skipping")
+			continue
+		}
+
+		progress.Step(ctx, "scraping "+code, float64(i)*100/float64(len(storeCodes)))
+		queued, err := h.pipeline.QueueStore(ctx, s)
+		progress.Add(ctx, "flyers_queued", int64(queued))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("store %s: %w", code, err))
+			progress.Add(ctx, "stores_failed", 1)
+			continue
+		}
+		progress.Add(ctx, "stores_scraped", 1)
+	}
+
+	progress.Step(ctx, "done", 100)
+	return errors.Join(errs...)
+}
+
+// HandleRasterize processes one rasterize_flyer job: it stores the pages of the
+// scraped flyer in its payload and queues their extraction
+func (h *ScrapeJobHandler) HandleRasterize(ctx context.Context, job *worker.Job) error {
+	flyerInfo, err := flyerFromJob(job)
+	if err != nil {
+		return err
+	}
+
+	s, exists := h.scrapers[flyerInfo.StoreCode]
+	if !exists {
+		return fmt.Errorf("no scraper for store %s", flyerInfo.StoreCode)
+	}
+	return h.pipeline.ProcessFlyer(ctx, flyerInfo, s)
+}
+
+// NewRasterizeFlyerJob creates the job that stores the pages of a scraped flyer.
+// The ID is derived from the flyer URL so a flyer scraped twice is queued once.
+func NewRasterizeFlyerJob(flyerInfo scraper.FlyerInfo) *worker.Job {
+	// Round-trip through JSON so the payload looks the same before and after queueing
+	var payload map[string]interface{}
+	data, _ := json.Marshal(flyerInfo)
+	_ = json.Unmarshal(data, &payload)
+
+	sum := sha256.Sum256([]byte(flyerInfo.FlyerURL))
+	return &worker.Job{
+		ID:       fmt.Sprintf("%s:%s", worker.JobTypeRasterizeFlyer, hex.EncodeToString(sum[:8])),
+		Type:     worker.JobTypeRasterizeFlyer,
+		Priority: 6,
+		Payload: map[string]interface{}{
+			worker.PayloadFlyer: payload,
+		},
+		MaxAttempts: 3,
+		RetryDelay:  2 * time.Minute,
+	}
+}
+
+func flyerFromJob(job *worker.Job) (scraper.FlyerInfo, error) {
+	var flyerInfo scraper.FlyerInfo
+	payload, ok := job.PayloadMap(worker.PayloadFlyer)
+	if !ok {
+		return flyerInfo, fmt.Errorf("rasterize_flyer job %s has no flyer", job.ID)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return flyerInfo, fmt.Errorf("failed to read flyer of job %s: %w", job.ID, err)
+	}
+	if err := json.Unmarshal(data, &flyerInfo); err != nil {
+		return flyerInfo, fmt.Errorf("failed to read flyer of job %s: %w", job.ID, err)
+	}
+	return flyerInfo, nil
+}
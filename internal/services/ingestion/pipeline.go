@@ -0,0 +1,474 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/enrichment"
+	"github.com/kainuguru/kainuguru-api/internal/services/scraper"
+	"github.com/kainuguru/kainuguru-api/pkg/pdf"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/kainuguru/kainuguru-api/internal/services/ingestion")
+
+// Pipeline turns scraped flyers into flyer and page records with stored page
+// images, and queues the pages for product extraction
+type Pipeline struct {
+	factory      *services.ServiceFactory
+	pdfProcessor *pdf.Processor
+	jobQueue     enrichment.JobEnqueuer
+}
+
+// NewPipeline creates an ingestion pipeline. Without a job queue new pages stay
+// pending for the enrich-flyers CLI.
+func NewPipeline(factory *services.ServiceFactory, pdfProcessor *pdf.Processor, jobQueue enrichment.JobEnqueuer) *Pipeline {
+	return &Pipeline{
+		factory:      factory,
+		pdfProcessor: pdfProcessor,
+		jobQueue:     jobQueue,
+	}
+}
+
+// ScrapeStore scrapes the current flyers of one store and ingests the ones not seen before
+func (p *Pipeline) ScrapeStore(ctx context.Context, s scraper.Scraper) error {
+	ctx, span := startScrapeSpan(ctx, s)
+	defer span.End()
+
+	flyerInfos, err := p.NewFlyers(ctx, s)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetAttributes(attribute.Int("scraper.new_flyers", len(flyerInfos)))
+	p.processFlyers(ctx, s, flyerInfos)
+	return nil
+}
+
+// QueueStore scrapes the current flyers of one store and queues a rasterize_flyer
+// job for each one not seen before, returning how many were queued. Without a
+// job queue the flyers are ingested inline, like ScrapeStore.
+func (p *Pipeline) QueueStore(ctx context.Context, s scraper.Scraper) (int, error) {
+	ctx, span := startScrapeSpan(ctx, s)
+	defer span.End()
+
+	flyerInfos, err := p.NewFlyers(ctx, s)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	span.SetAttributes(attribute.Int("scraper.new_flyers", len(flyerInfos)))
+	if p.jobQueue == nil {
+		p.processFlyers(ctx, s, flyerInfos)
+		return 0, nil
+	}
+
+	for i, flyerInfo := range flyerInfos {
+		if err := p.jobQueue.Enqueue(ctx, NewRasterizeFlyerJob(flyerInfo)); err != nil {
+			return i, fmt.Errorf("failed to queue flyer %q: %w", flyerInfo.Title, err)
+		}
+	}
+	return len(flyerInfos), nil
+}
+
+// startScrapeSpan starts the span of one store's scrape, the root of the trace
+// when the scraper runs on its own schedule
+func startScrapeSpan(ctx context.Context, s scraper.Scraper) (context.Context, trace.Span) {
+	store := s.GetStoreInfo()
+	return tracer.Start(ctx, "scrape "+store.Code, trace.WithAttributes(
+		attribute.String("store.code", store.Code),
+	))
+}
+
+// NewFlyers scrapes the current flyers of one store and returns the ones not seen
+// before, by source URL
+func (p *Pipeline) NewFlyers(ctx context.Context, s scraper.Scraper) ([]scraper.FlyerInfo, error) {
+	storeInfo := s.GetStoreInfo()
+
+	log.Info().Str("store", storeInfo.Name).Msg("Processing store")
+
+	flyerInfos, err := s.ScrapeCurrentFlyers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape: %w", err)
+	}
+
+	flyerService := p.factory.FlyerService()
+	newFlyers := make([]scraper.FlyerInfo, 0, len(flyerInfos))
+	for _, flyerInfo := range flyerInfos {
+		existingFlyer, err := flyerService.GetBySourceURL(ctx, flyerInfo.FlyerURL)
+		if err == nil && existingFlyer != nil {
+			continue
+		}
+		newFlyers = append(newFlyers, flyerInfo)
+	}
+
+	log.Info().
+		Str("store", storeInfo.Name).
+		Int("count", len(flyerInfos)).
+		Int("new", len(newFlyers)).
+		Msg("Found flyers")
+
+	return newFlyers, nil
+}
+
+func (p *Pipeline) processFlyers(ctx context.Context, s scraper.Scraper, flyerInfos []scraper.FlyerInfo) {
+	for _, flyerInfo := range flyerInfos {
+		if err := p.ProcessFlyer(ctx, flyerInfo, s); err != nil {
+			log.Error().
+				Err(err).
+				Str("store", s.GetStoreInfo().Name).
+				Str("flyer", flyerInfo.Title).
+				Msg("Failed to process flyer")
+		}
+	}
+}
+
+// ProcessFlyer creates the flyer, stores its page images and queues their extraction
+func (p *Pipeline) ProcessFlyer(ctx context.Context, flyerInfo scraper.FlyerInfo, s scraper.Scraper) error {
+	flyerService := p.factory.FlyerService()
+	storeService := p.factory.StoreService()
+
+	// 1. Get store from database by code
+	store, err := storeService.GetByCode(ctx, flyerInfo.StoreCode)
+	if err != nil {
+		return fmt.Errorf("failed to get store by code %q: %w", flyerInfo.StoreCode, err)
+	}
+
+	// 2. Check if this exact flyer already exists (by source URL)
+	existingFlyer, err := flyerService.GetBySourceURL(ctx, flyerInfo.FlyerURL)
+	if err == nil && existingFlyer != nil {
+		log.Info().
+			Int("flyerId", existingFlyer.ID).
+			Str("store", store.Code).
+			Str("url", flyerInfo.FlyerURL).
+			Msg("Flyer already exists (same source URL), skipping")
+		return nil
+	}
+
+	// 3. Create flyer record FIRST (we need the ID for folder paths)
+	title := flyerInfo.Title
+	flyer := &models.Flyer{
+		StoreID:   store.ID,
+		Title:     &title,
+		ValidFrom: flyerInfo.ValidFrom,
+		ValidTo:   flyerInfo.ValidTo,
+		SourceURL: &flyerInfo.FlyerURL,
+		Status:    string(models.FlyerStatusPending),
+		Store:     store, // IMPORTANT: Set for path generation
+	}
+
+	if err := flyerService.Create(ctx, flyer); err != nil {
+		return fmt.Errorf("failed to create flyer: %w", err)
+	}
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Str("store", store.Code).
+		Str("title", title).
+		Msg("Created flyer record")
+
+	// 4. Try to get page images directly via scraper (works for iPaper-based stores like Maxima)
+	pageInfos, err := s.ScrapeFlyer(ctx, flyerInfo)
+	if err == nil && len(pageInfos) > 0 {
+		// Check if the first page is a PDF (IKI returns PDF URL, not images)
+		// If so, fall back to PDF processing
+		if len(pageInfos) == 1 && pageInfos[0].FileType == "pdf" {
+			log.Debug().
+				Int("flyerId", flyer.ID).
+				Msg("ScrapeFlyer returned PDF, falling back to PDF processing")
+		} else {
+			// Store provides direct image URLs (e.g., Maxima via iPaper)
+			return p.processDirectImagePages(ctx, flyer, pageInfos)
+		}
+	}
+
+	// 5. Fall back to PDF download and conversion (e.g., IKI)
+	return p.processPDFFlyer(ctx, flyer, flyerInfo)
+}
+
+// processDirectImagePages handles stores that provide direct image URLs (like Maxima via iPaper)
+func (p *Pipeline) processDirectImagePages(ctx context.Context, flyer *models.Flyer, pageInfos []scraper.PageInfo) error {
+	flyerService := p.factory.FlyerService()
+	flyerPageService := p.factory.FlyerPageService()
+	storageService := p.factory.FlyerStorageService()
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Int("pages", len(pageInfos)).
+		Msg("Processing direct image pages")
+
+	var flyerPages []*models.FlyerPage
+
+	for _, pageInfo := range pageInfos {
+		// Download image from CDN URL
+		imageData, err := downloadFile(ctx, pageInfo.ImageURL)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int("page", pageInfo.PageNumber).
+				Str("url", pageInfo.ImageURL).
+				Msg("Failed to download page image")
+			continue
+		}
+
+		log.Debug().
+			Int("flyerId", flyer.ID).
+			Int("page", pageInfo.PageNumber).
+			Int("bytes", len(imageData)).
+			Msg("Downloaded page image")
+
+		// Save to storage and get public URL
+		publicURL, err := storageService.SaveFlyerPage(ctx, flyer, pageInfo.PageNumber, bytes.NewReader(imageData))
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int("page", pageInfo.PageNumber).
+				Msg("Failed to save page to storage")
+			continue
+		}
+
+		// Create flyer page record
+		flyerPage := &models.FlyerPage{
+			FlyerID:          flyer.ID,
+			PageNumber:       pageInfo.PageNumber,
+			ImageURL:         &publicURL,
+			ExtractionStatus: string(models.FlyerPageStatusPending),
+		}
+
+		flyerPages = append(flyerPages, flyerPage)
+
+		log.Debug().
+			Int("flyerId", flyer.ID).
+			Int("page", pageInfo.PageNumber).
+			Str("url", publicURL).
+			Msg("Saved flyer page")
+	}
+
+	// Update page count
+	pageCount := len(flyerPages)
+	flyer.PageCount = &pageCount
+	flyerService.Update(ctx, flyer)
+
+	// Batch create flyer pages
+	if len(flyerPages) > 0 {
+		if err := flyerPageService.CreateBatch(ctx, flyerPages); err != nil {
+			flyerService.FailProcessing(ctx, flyer.ID)
+			return fmt.Errorf("failed to create flyer pages: %w", err)
+		}
+
+		log.Info().
+			Int("flyerId", flyer.ID).
+			Int("pages", len(flyerPages)).
+			Msg("Created flyer page records")
+	}
+
+	// The flyer stays pending; its status rolls up from the page extraction jobs
+	p.enqueuePageJobs(ctx, flyer, flyerPages)
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Str("store", flyer.Store.Code).
+		Int("pages", len(flyerPages)).
+		Str("folder", flyer.GetFolderName()).
+		Msg("✅ Flyer processed successfully (direct images)")
+
+	return nil
+}
+
+// processPDFFlyer handles stores that provide PDF URLs (like IKI)
+func (p *Pipeline) processPDFFlyer(ctx context.Context, flyer *models.Flyer, flyerInfo scraper.FlyerInfo) error {
+	flyerService := p.factory.FlyerService()
+	flyerPageService := p.factory.FlyerPageService()
+	storageService := p.factory.FlyerStorageService()
+
+	// Download PDF
+	pdfData, err := downloadFile(ctx, flyerInfo.FlyerURL)
+	if err != nil {
+		flyerService.FailProcessing(ctx, flyer.ID)
+		return fmt.Errorf("failed to download PDF: %w", err)
+	}
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Int("bytes", len(pdfData)).
+		Msg("Downloaded PDF")
+
+	// Save PDF to temp file
+	tempDir := "/tmp/kainuguru/pdf"
+	os.MkdirAll(tempDir, 0755) // Ensure temp dir exists
+
+	tempPDFPath := filepath.Join(tempDir, fmt.Sprintf("flyer-%d.pdf", flyer.ID))
+	if err := os.MkdirAll(filepath.Dir(tempPDFPath), 0755); err != nil {
+		flyerService.FailProcessing(ctx, flyer.ID)
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	if err := os.WriteFile(tempPDFPath, pdfData, 0644); err != nil {
+		flyerService.FailProcessing(ctx, flyer.ID)
+		return fmt.Errorf("failed to save temp PDF: %w", err)
+	}
+	defer os.Remove(tempPDFPath)
+
+	// Convert PDF to images
+	result, err := p.pdfProcessor.ProcessPDF(ctx, tempPDFPath)
+	if err != nil || !result.Success {
+		flyerService.FailProcessing(ctx, flyer.ID)
+		return fmt.Errorf("failed to convert PDF to images: %w", err)
+	}
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Int("pages", result.PageCount).
+		Msg("Converted PDF to images")
+
+	// Update flyer page count
+	pageCount := result.PageCount
+	flyer.PageCount = &pageCount
+	flyerService.Update(ctx, flyer)
+
+	// Save each page image to storage
+	var flyerPages []*models.FlyerPage
+
+	for i, imagePath := range result.OutputFiles {
+		pageNumber := i + 1
+
+		// Read image data
+		imageData, err := os.ReadFile(imagePath)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int("page", pageNumber).
+				Msg("Failed to read image file")
+			continue
+		}
+
+		// Save to storage and get public URL
+		publicURL, err := storageService.SaveFlyerPage(ctx, flyer, pageNumber, bytes.NewReader(imageData))
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int("page", pageNumber).
+				Msg("Failed to save page to storage")
+			continue
+		}
+
+		// Create flyer page record
+		flyerPage := &models.FlyerPage{
+			FlyerID:          flyer.ID,
+			PageNumber:       pageNumber,
+			ImageURL:         &publicURL,
+			ExtractionStatus: string(models.FlyerPageStatusPending),
+		}
+
+		flyerPages = append(flyerPages, flyerPage)
+
+		log.Debug().
+			Int("flyerId", flyer.ID).
+			Int("page", pageNumber).
+			Str("url", publicURL).
+			Msg("Saved flyer page")
+
+		// Clean up temp image file
+		os.Remove(imagePath)
+	}
+
+	// 9. Batch create flyer pages
+	if len(flyerPages) > 0 {
+		if err := flyerPageService.CreateBatch(ctx, flyerPages); err != nil {
+			flyerService.FailProcessing(ctx, flyer.ID)
+			return fmt.Errorf("failed to create flyer pages: %w", err)
+		}
+
+		log.Info().
+			Int("flyerId", flyer.ID).
+			Int("pages", len(flyerPages)).
+			Msg("Created flyer page records")
+	}
+
+	// 10. Enforce storage limit (keep only 2 flyers per store)
+	if err := storageService.EnforceStorageLimit(ctx, flyer.Store.Code); err != nil {
+		log.Warn().
+			Err(err).
+			Str("store", flyer.Store.Code).
+			Msg("Failed to enforce storage limit (non-critical)")
+	}
+
+	// 11. Mark old flyers from this store as archived (manual update for now)
+	// Note: This will be handled better when we implement the archive service properly
+	oldFlyers, _ := flyerService.GetFlyersByStore(ctx, flyer.StoreID, services.FlyerFilters{
+		IsArchived: &[]bool{false}[0],
+	})
+	for _, oldFlyer := range oldFlyers {
+		if oldFlyer.ID != flyer.ID && oldFlyer.ValidTo.Before(flyer.ValidFrom) {
+			flyerService.ArchiveFlyer(ctx, oldFlyer.ID)
+		}
+	}
+
+	// 12. Queue page extraction; the flyer status rolls up from the page jobs
+	p.enqueuePageJobs(ctx, flyer, flyerPages)
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Str("store", flyer.Store.Code).
+		Int("pages", len(flyerPages)).
+		Str("folder", flyer.GetFolderName()).
+		Msg("✅ Flyer processed successfully (PDF)")
+
+	return nil
+}
+
+// enqueuePageJobs queues one extraction job per created page
+func (p *Pipeline) enqueuePageJobs(ctx context.Context, flyer *models.Flyer, flyerPages []*models.FlyerPage) {
+	if p.jobQueue == nil || len(flyerPages) == 0 {
+		return
+	}
+
+	if err := enrichment.EnqueuePageJobs(ctx, p.jobQueue, flyerPages); err != nil {
+		log.Error().
+			Err(err).
+			Int("flyerId", flyer.ID).
+			Msg("Failed to enqueue page extraction jobs, pages stay pending for the next sweep")
+		return
+	}
+
+	log.Info().
+		Int("flyerId", flyer.ID).
+		Int("pages", len(flyerPages)).
+		Msg("Enqueued page extraction jobs")
+}
+
+func downloadFile(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; KainuguruBot/1.0)")
+
+	client := scraper.NewHTTPClient(60 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/kainuguru/kainuguru-api/internal/pricehistory"
 	"github.com/kainuguru/kainuguru-api/internal/product"
 	"github.com/kainuguru/kainuguru-api/internal/productmaster"
+	"github.com/kainuguru/kainuguru-api/internal/services/matching"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
 	"github.com/kainuguru/kainuguru-api/internal/shoppinglist"
 	"github.com/kainuguru/kainuguru-api/internal/shoppinglistitem"
 	"github.com/kainuguru/kainuguru-api/internal/store"
@@ -113,6 +115,7 @@ type ProductService interface {
 	Count(ctx context.Context, filters ProductFilters) (int, error)
 	Create(ctx context.Context, product *models.Product) error
 	CreateBatch(ctx context.Context, products []*models.Product) error
+	ReplacePageProducts(ctx context.Context, flyerPageID int, products []*models.Product) error
 	Update(ctx context.Context, product *models.Product) error
 	Delete(ctx context.Context, id int) error
 
@@ -154,6 +157,8 @@ type ProductMasterService interface {
 
 	// Product master operations
 	GetByCanonicalName(ctx context.Context, name string) (*models.ProductMaster, error)
+	GetByBarcode(ctx context.Context, barcode string) (*models.ProductMaster, error)
+	AssignBarcode(ctx context.Context, masterID int64, barcode string) (*models.ProductMaster, error)
 	GetActiveProductMasters(ctx context.Context) ([]*models.ProductMaster, error)
 	GetVerifiedProductMasters(ctx context.Context) ([]*models.ProductMaster, error)
 	GetProductMastersForReview(ctx context.Context) ([]*models.ProductMaster, error)
@@ -165,11 +170,20 @@ type ProductMasterService interface {
 	CreateFromProduct(ctx context.Context, product *models.Product) (*models.ProductMaster, error)
 	MatchProduct(ctx context.Context, productID int, masterID int64) error
 	CreateMasterFromProduct(ctx context.Context, productID int) (*models.ProductMaster, error)
+	GetMatchThresholds(ctx context.Context, category *string) matching.Thresholds
 
 	// Verification operations
 	VerifyProductMaster(ctx context.Context, masterID int64, verifierID string) error
 	DeactivateProductMaster(ctx context.Context, masterID int64) error
 	MarkAsDuplicate(ctx context.Context, masterID int64, duplicateOfID int64) error
+	MergeProductMasters(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error)
+	SplitProductMaster(ctx context.Context, masterID int64, productIDs []int) (*models.ProductMaster, error)
+
+	// Match review operations
+	GetPendingMatches(ctx context.Context, limit, offset int) ([]*models.ProductMasterMatch, error)
+	AcceptMatch(ctx context.Context, matchID int64, reviewerID string) (*models.ProductMasterMatch, error)
+	RejectMatch(ctx context.Context, matchID int64, reviewerID string) (*models.ProductMasterMatch, error)
+	ReassignMatch(ctx context.Context, matchID, masterID int64, reviewerID string) (*models.ProductMasterMatch, error)
 
 	// Statistics
 	GetMatchingStatistics(ctx context.Context, masterID int64) (*ProductMasterStats, error)
@@ -181,6 +195,13 @@ type EnrichmentOptions struct {
 	ForceReprocess bool
 	MaxPages       int
 	BatchSize      int
+	Concurrency    int // Pages extracted in parallel within a batch (<= 1 = sequential)
+
+	// Run bookkeeping (RunID 0 disables per-page tracking)
+	RunID        int64
+	OnlyPageIDs  []int // Process only these pages, ignoring their previous status (retry of failed pages)
+	SkipPageIDs  []int // Pages already completed in the run being resumed
+	RetryPageIDs []int // Pages interrupted mid-processing in the run being resumed
 }
 
 // EnrichmentStats contains statistics about the enrichment process
@@ -188,6 +209,7 @@ type EnrichmentStats struct {
 	PagesProcessed    int
 	PagesFailed       int
 	ProductsExtracted int
+	TokensUsed        int
 	AvgConfidence     float64
 	Duration          time.Duration
 }
@@ -198,6 +220,27 @@ type EnrichmentService interface {
 	GetEligibleFlyers(ctx context.Context, date time.Time, storeCode string) ([]*models.Flyer, error)
 	// Process a single flyer and all its pages
 	ProcessFlyer(ctx context.Context, flyer *models.Flyer, opts EnrichmentOptions) (*EnrichmentStats, error)
+	// Process a single page: extraction, product storage and master matching
+	ProcessPage(ctx context.Context, flyer *models.Flyer, page *models.FlyerPage) (*EnrichmentStats, error)
+	// Roll the flyer status up from the extraction status of its pages
+	RefreshFlyerStatus(ctx context.Context, flyerID int) (models.FlyerStatus, error)
+}
+
+// EnrichmentRunService defines the interface for persisted enrichment run bookkeeping
+type EnrichmentRunService interface {
+	GetByID(ctx context.Context, id int64) (*models.EnrichmentRun, error)
+	GetRecent(ctx context.Context, limit int) ([]*models.EnrichmentRun, error)
+
+	// Run lifecycle
+	StartRun(ctx context.Context, opts models.EnrichmentRunOptions) (*models.EnrichmentRun, error)
+	ResumeRun(ctx context.Context, id int64, retryFailed bool) (*models.EnrichmentRun, error)
+	FinishRun(ctx context.Context, runID int64, runErr error) (*models.EnrichmentRun, error)
+
+	// Page bookkeeping
+	GetPages(ctx context.Context, runID int64, statuses []string) ([]*models.EnrichmentRunPage, error)
+	StartPage(ctx context.Context, runID int64, page *models.FlyerPage) error
+	CompletePage(ctx context.Context, runID int64, page *models.FlyerPage, productsExtracted, tokensUsed int) error
+	FailPage(ctx context.Context, runID int64, page *models.FlyerPage, tokensUsed int, pageErr error) error
 }
 
 // ExtractionJobService defines the interface for extraction job operations
@@ -231,6 +274,34 @@ type ExtractionJobService interface {
 	CleanupCompletedJobs(ctx context.Context, olderThan time.Duration) (int64, error)
 }
 
+// JobAdminService defines the interface for inspecting and repairing the worker job queue
+type JobAdminService interface {
+	// Inspection
+	ListJobs(ctx context.Context, filter worker.JobFilter) ([]*worker.Job, error)
+	GetJob(ctx context.Context, id string) (*worker.Job, error)
+	Stats(ctx context.Context) (*worker.QueueStats, error)
+	PausedJobTypes(ctx context.Context) ([]worker.JobType, error)
+	WatchJob(ctx context.Context, id string) (<-chan *worker.Job, error)
+
+	// Repair
+	RetryJob(ctx context.Context, id string) (*worker.Job, error)
+	RetryDeadLetters(ctx context.Context, filter DeadLetterFilter) (int, error)
+	CancelJob(ctx context.Context, id string) (*worker.Job, error)
+	PauseJobType(ctx context.Context, jobType worker.JobType) error
+	ResumeJobType(ctx context.Context, jobType worker.JobType) error
+
+	// Workflows
+	ListWorkflows(ctx context.Context, filter worker.WorkflowFilter) ([]*worker.Workflow, error)
+	GetWorkflow(ctx context.Context, id string) (*worker.Workflow, error)
+	StartWorkflow(ctx context.Context, name string, params map[string]interface{}) (*worker.Workflow, error)
+}
+
+// DeadLetterFilter selects the dead letters retried together. Empty fields match any job.
+type DeadLetterFilter struct {
+	Type          worker.JobType
+	ErrorContains string
+}
+
 // Filter structures for service operations
 type StoreFilters = store.Filters
 
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// deadLetterPageSize is how many dead letters RetryDeadLetters reads at a time
+const deadLetterPageSize = 500
+
+// jobProgressStore is the part of worker.ProgressStore the admin service uses
+type jobProgressStore interface {
+	GetMany(ctx context.Context, ids []string) (map[string]*worker.Progress, error)
+	Publish(ctx context.Context, job *worker.Job) error
+	Watch(ctx context.Context, id string) (<-chan *worker.Job, error)
+	RequestCancel(ctx context.Context, id string) error
+	CancelRequested(ctx context.Context, id string) (bool, error)
+}
+
+// workflowEngine is the part of worker.WorkflowEngine the admin service uses
+type workflowEngine interface {
+	Start(ctx context.Context, name string, params map[string]interface{}) (*worker.Workflow, error)
+	Get(ctx context.Context, id string) (*worker.Workflow, error)
+	List(ctx context.Context, filter worker.WorkflowFilter) ([]*worker.Workflow, error)
+}
+
+type jobAdminService struct {
+	queue     worker.Queue
+	progress  jobProgressStore
+	workflows workflowEngine
+	logger    *slog.Logger
+}
+
+// NewJobAdminService creates a job admin service on top of the worker job queue.
+// A nil queue leaves every call failing, for processes started without one. Without
+// a progress store jobs show no progress, and running jobs can't be cancelled or watched.
+// Without a workflow engine workflows can't be listed or started.
+func NewJobAdminService(queue worker.Queue, progress *worker.ProgressStore, workflows *worker.WorkflowEngine) JobAdminService {
+	s := &jobAdminService{
+		queue:  queue,
+		logger: slog.Default().With("service", "job_admin"),
+	}
+	if progress != nil {
+		s.progress = progress
+	}
+	if workflows != nil {
+		s.workflows = workflows
+	}
+	return s
+}
+
+func (s *jobAdminService) ListJobs(ctx context.Context, filter worker.JobFilter) ([]*worker.Job, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	if err := validateJobStatus(filter.Status); err != nil {
+		return nil, err
+	}
+	if err := validateJobType(filter.Type, true); err != nil {
+		return nil, err
+	}
+	jobs, err := s.queue.ListJobs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachProgress(ctx, jobs...); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *jobAdminService) GetJob(ctx context.Context, id string) (*worker.Job, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	job, err := s.queue.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachProgress(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// WatchJob sends the job as it is now, then every update until it completes, fails
+// for good or is cancelled. The channel is closed after that or once ctx ends.
+func (s *jobAdminService) WatchJob(ctx context.Context, id string) (<-chan *worker.Job, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	if s.progress == nil {
+		return nil, apperrors.Internal("job progress is not configured")
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+	// Subscribe before reading the job so no update in between is missed
+	updates, err := s.progress.Watch(watchCtx, id)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	jobs := make(chan *worker.Job)
+	go func() {
+		defer close(jobs)
+		defer stop()
+
+		for {
+			select {
+			case jobs <- job:
+			case <-watchCtx.Done():
+				return
+			}
+			if jobFinished(job) {
+				return
+			}
+
+			var ok bool
+			if job, ok = <-updates; !ok {
+				return
+			}
+		}
+	}()
+	return jobs, nil
+}
+
+func (s *jobAdminService) Stats(ctx context.Context) (*worker.QueueStats, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	return s.queue.Stats(ctx)
+}
+
+func (s *jobAdminService) PausedJobTypes(ctx context.Context) ([]worker.JobType, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	return s.queue.PausedJobTypes(ctx)
+}
+
+func (s *jobAdminService) RetryJob(ctx context.Context, id string) (*worker.Job, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	job, err := s.queue.RetryJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("job retried", "job_id", id, "job_type", job.Type)
+	return job, nil
+}
+
+// RetryDeadLetters retries every dead letter matching filter and returns how many were retried
+func (s *jobAdminService) RetryDeadLetters(ctx context.Context, filter DeadLetterFilter) (int, error) {
+	if err := s.requireQueue(); err != nil {
+		return 0, err
+	}
+	if err := validateJobType(filter.Type, true); err != nil {
+		return 0, err
+	}
+
+	// Collect the matches first so retried jobs don't shift the pages being read
+	var ids []string
+	for offset := 0; ; offset += deadLetterPageSize {
+		jobs, err := s.queue.ListJobs(ctx, worker.JobFilter{
+			Status: worker.JobStatusFailed,
+			Type:   filter.Type,
+			Limit:  deadLetterPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, job := range jobs {
+			if filter.ErrorContains == "" || strings.Contains(job.Error, filter.ErrorContains) {
+				ids = append(ids, job.ID)
+			}
+		}
+		if len(jobs) < deadLetterPageSize {
+			break
+		}
+	}
+
+	retried := 0
+	for _, id := range ids {
+		if _, err := s.queue.RetryJob(ctx, id); err != nil {
+			// Re-enqueued or retried elsewhere since it was listed
+			if apperrors.IsType(err, apperrors.ErrorTypeNotFound) || apperrors.IsType(err, apperrors.ErrorTypeConflict) {
+				continue
+			}
+			return retried, err
+		}
+		retried++
+	}
+
+	s.logger.Info("dead letters retried", "job_type", filter.Type, "error_contains", filter.ErrorContains, "retried", retried)
+	return retried, nil
+}
+
+func (s *jobAdminService) CancelJob(ctx context.Context, id string) (*worker.Job, error) {
+	if err := s.requireQueue(); err != nil {
+		return nil, err
+	}
+	job, err := s.queue.CancelJob(ctx, id)
+	if apperrors.IsType(err, apperrors.ErrorTypeConflict) && s.progress != nil {
+		return s.requestCancel(ctx, id, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Let watchers know the job is gone
+	if s.progress != nil {
+		if err := s.progress.Publish(ctx, job); err != nil {
+			s.logger.Warn("failed to publish cancelled job", "job_id", id, "error", err)
+		}
+	}
+	s.logger.Info("job cancelled", "job_id", id, "job_type", job.Type)
+	return job, nil
+}
+
+// requestCancel asks the worker running the job to stop it. The job stays
+// processing until its handler notices; conflictErr is returned for other jobs.
+func (s *jobAdminService) requestCancel(ctx context.Context, id string, conflictErr error) (*worker.Job, error) {
+	job, err := s.queue.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != worker.JobStatusProcessing {
+		return nil, conflictErr
+	}
+
+	if err := s.progress.RequestCancel(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.attachProgress(ctx, job); err != nil {
+		return nil, err
+	}
+	s.logger.Info("job cancellation requested", "job_id", id, "job_type", job.Type)
+	return job, nil
+}
+
+func (s *jobAdminService) PauseJobType(ctx context.Context, jobType worker.JobType) error {
+	if err := s.requireQueue(); err != nil {
+		return err
+	}
+	if err := validateJobType(jobType, false); err != nil {
+		return err
+	}
+	if err := s.queue.PauseJobType(ctx, jobType); err != nil {
+		return err
+	}
+	s.logger.Info("job type paused", "job_type", jobType)
+	return nil
+}
+
+func (s *jobAdminService) ResumeJobType(ctx context.Context, jobType worker.JobType) error {
+	if err := s.requireQueue(); err != nil {
+		return err
+	}
+	if err := validateJobType(jobType, false); err != nil {
+		return err
+	}
+	if err := s.queue.ResumeJobType(ctx, jobType); err != nil {
+		return err
+	}
+	s.logger.Info("job type resumed", "job_type", jobType)
+	return nil
+}
+
+func (s *jobAdminService) ListWorkflows(ctx context.Context, filter worker.WorkflowFilter) ([]*worker.Workflow, error) {
+	if err := s.requireWorkflows(); err != nil {
+		return nil, err
+	}
+	if err := validateWorkflowStatus(filter.Status); err != nil {
+		return nil, err
+	}
+	return s.workflows.List(ctx, filter)
+}
+
+func (s *jobAdminService) GetWorkflow(ctx context.Context, id string) (*worker.Workflow, error) {
+	if err := s.requireWorkflows(); err != nil {
+		return nil, err
+	}
+	return s.workflows.Get(ctx, id)
+}
+
+func (s *jobAdminService) StartWorkflow(ctx context.Context, name string, params map[string]interface{}) (*worker.Workflow, error) {
+	if err := s.requireWorkflows(); err != nil {
+		return nil, err
+	}
+	wf, err := s.workflows.Start(ctx, name, params)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("workflow started", "workflow_id", wf.ID, "workflow", name)
+	return wf, nil
+}
+
+// attachProgress adds what the jobs last reported, and whether running ones were asked to stop
+func (s *jobAdminService) attachProgress(ctx context.Context, jobs ...*worker.Job) error {
+	if s.progress == nil || len(jobs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	progress, err := s.progress.GetMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		job.Progress = progress[job.ID]
+		if job.Status != worker.JobStatusProcessing {
+			continue
+		}
+		if job.CancelRequested, err = s.progress.CancelRequested(ctx, job.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *jobAdminService) requireQueue() error {
+	if s.queue == nil {
+		return apperrors.Internal("job queue is not configured")
+	}
+	return nil
+}
+
+func (s *jobAdminService) requireWorkflows() error {
+	if s.workflows == nil {
+		return apperrors.Internal("workflows are not configured")
+	}
+	return nil
+}
+
+func validateJobType(jobType worker.JobType, allowEmpty bool) error {
+	if jobType == "" {
+		if allowEmpty {
+			return nil
+		}
+		return apperrors.Validation("job type is required")
+	}
+	for _, known := range worker.JobTypes {
+		if jobType == known {
+			return nil
+		}
+	}
+	return apperrors.ValidationF("unknown job type %q", jobType)
+}
+
+// jobFinished reports whether the job will not run again
+func jobFinished(job *worker.Job) bool {
+	switch job.Status {
+	case worker.JobStatusCompleted, worker.JobStatusFailed, worker.JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func validateJobStatus(status worker.JobStatus) error {
+	switch status {
+	case "", worker.JobStatusPending, worker.JobStatusRetrying, worker.JobStatusProcessing, worker.JobStatusFailed:
+		return nil
+	}
+	return apperrors.ValidationF("cannot list jobs with status %q", status)
+}
+
+func validateWorkflowStatus(status worker.WorkflowStatus) error {
+	switch status {
+	case "", worker.WorkflowStatusRunning, worker.WorkflowStatusCompleted, worker.WorkflowStatusFailed, worker.WorkflowStatusCancelled:
+		return nil
+	}
+	return apperrors.ValidationF("unknown workflow status %q", status)
+}
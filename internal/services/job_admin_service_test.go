@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// stubJobQueue implements the queue calls the admin service makes; the rest panic
+type stubJobQueue struct {
+	worker.Queue
+	dead      []*worker.Job
+	retryErrs map[string]error
+	retried   []string
+	paused    []worker.JobType
+	filters   []worker.JobFilter
+	jobs      map[string]*worker.Job
+}
+
+func (q *stubJobQueue) ListJobs(ctx context.Context, filter worker.JobFilter) ([]*worker.Job, error) {
+	q.filters = append(q.filters, filter)
+	var matched []*worker.Job
+	for _, job := range q.dead {
+		if filter.Type == "" || job.Type == filter.Type {
+			matched = append(matched, job)
+		}
+	}
+	if filter.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[filter.Offset:]
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (q *stubJobQueue) RetryJob(ctx context.Context, id string) (*worker.Job, error) {
+	if err := q.retryErrs[id]; err != nil {
+		return nil, err
+	}
+	q.retried = append(q.retried, id)
+	return &worker.Job{ID: id, Status: worker.JobStatusPending}, nil
+}
+
+func (q *stubJobQueue) GetJob(ctx context.Context, id string) (*worker.Job, error) {
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, apperrors.NotFound("job " + id + " not found")
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (q *stubJobQueue) CancelJob(ctx context.Context, id string) (*worker.Job, error) {
+	job, err := q.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == worker.JobStatusProcessing {
+		return nil, apperrors.Conflict("job is processing")
+	}
+	delete(q.jobs, id)
+	job.Status = worker.JobStatusCancelled
+	return job, nil
+}
+
+func (q *stubJobQueue) PauseJobType(ctx context.Context, jobType worker.JobType) error {
+	q.paused = append(q.paused, jobType)
+	return nil
+}
+
+// stubProgressStore keeps progress and cancellation requests in memory
+type stubProgressStore struct {
+	progress  map[string]*worker.Progress
+	cancels   map[string]bool
+	published []*worker.Job
+	updates   chan *worker.Job
+}
+
+func newStubProgressStore() *stubProgressStore {
+	return &stubProgressStore{
+		progress: make(map[string]*worker.Progress),
+		cancels:  make(map[string]bool),
+		updates:  make(chan *worker.Job, 10),
+	}
+}
+
+func (s *stubProgressStore) GetMany(ctx context.Context, ids []string) (map[string]*worker.Progress, error) {
+	result := make(map[string]*worker.Progress)
+	for _, id := range ids {
+		if progress, ok := s.progress[id]; ok {
+			result[id] = progress
+		}
+	}
+	return result, nil
+}
+
+func (s *stubProgressStore) Publish(ctx context.Context, job *worker.Job) error {
+	s.published = append(s.published, job)
+	return nil
+}
+
+func (s *stubProgressStore) Watch(ctx context.Context, id string) (<-chan *worker.Job, error) {
+	return s.updates, nil
+}
+
+func (s *stubProgressStore) RequestCancel(ctx context.Context, id string) error {
+	s.cancels[id] = true
+	return nil
+}
+
+func (s *stubProgressStore) CancelRequested(ctx context.Context, id string) (bool, error) {
+	return s.cancels[id], nil
+}
+
+func newTestJobAdminService(queue worker.Queue, progress jobProgressStore) *jobAdminService {
+	service := NewJobAdminService(queue, nil, nil).(*jobAdminService)
+	service.progress = progress
+	return service
+}
+
+func TestJobAdminService_RetryDeadLetters(t *testing.T) {
+	dead := []*worker.Job{
+		{ID: "timeout-1", Type: worker.JobTypeScrapeFlyer, Error: "context deadline exceeded"},
+		{ID: "parse", Type: worker.JobTypeScrapeFlyer, Error: "unexpected HTML"},
+		{ID: "timeout-2", Type: worker.JobTypeUpdatePrices, Error: "context deadline exceeded"},
+		{ID: "gone", Type: worker.JobTypeScrapeFlyer, Error: "context deadline exceeded"},
+	}
+
+	tests := []struct {
+		name    string
+		filter  DeadLetterFilter
+		want    []string
+		wantErr apperrors.ErrorType
+	}{
+		{
+			name: "all dead letters",
+			want: []string{"timeout-1", "parse", "timeout-2"},
+		},
+		{
+			name:   "by type and error",
+			filter: DeadLetterFilter{Type: worker.JobTypeScrapeFlyer, ErrorContains: "deadline"},
+			want:   []string{"timeout-1"},
+		},
+		{
+			name:   "nothing matches",
+			filter: DeadLetterFilter{ErrorContains: "out of memory"},
+		},
+		{
+			name:    "unknown type",
+			filter:  DeadLetterFilter{Type: "reticulate_splines"},
+			wantErr: apperrors.ErrorTypeValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// "gone" was re-enqueued after it was listed; it is skipped, not an error
+			queue := &stubJobQueue{
+				dead:      dead,
+				retryErrs: map[string]error{"gone": apperrors.NotFound("job gone not found")},
+			}
+			service := NewJobAdminService(queue, nil, nil)
+
+			retried, err := service.RetryDeadLetters(context.Background(), tt.filter)
+			if tt.wantErr != "" {
+				if !apperrors.IsType(err, tt.wantErr) {
+					t.Fatalf("RetryDeadLetters() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RetryDeadLetters() error = %v", err)
+			}
+			if retried != len(tt.want) || fmt.Sprint(queue.retried) != fmt.Sprint(tt.want) {
+				t.Errorf("RetryDeadLetters() = %d retrying %v, want %v", retried, queue.retried, tt.want)
+			}
+			for _, filter := range queue.filters {
+				if filter.Status != worker.JobStatusFailed {
+					t.Errorf("listed jobs with status %q, want failed", filter.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestJobAdminService_RetryDeadLettersStopsOnQueueError(t *testing.T) {
+	queue := &stubJobQueue{
+		dead: []*worker.Job{
+			{ID: "a", Type: worker.JobTypeScrapeFlyer},
+			{ID: "b", Type: worker.JobTypeScrapeFlyer},
+		},
+		retryErrs: map[string]error{"b": apperrors.Internal("redis unavailable")},
+	}
+
+	retried, err := NewJobAdminService(queue, nil, nil).RetryDeadLetters(context.Background(), DeadLetterFilter{})
+	if !apperrors.IsType(err, apperrors.ErrorTypeInternal) {
+		t.Fatalf("RetryDeadLetters() error = %v, want internal", err)
+	}
+	if retried != 1 {
+		t.Errorf("RetryDeadLetters() = %d, want the 1 job retried before the error", retried)
+	}
+}
+
+func TestJobAdminService_PauseJobTypeValidates(t *testing.T) {
+	queue := &stubJobQueue{}
+	service := NewJobAdminService(queue, nil, nil)
+
+	for _, jobType := range []worker.JobType{"", "reticulate_splines"} {
+		if err := service.PauseJobType(context.Background(), jobType); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+			t.Errorf("PauseJobType(%q) error = %v, want validation", jobType, err)
+		}
+	}
+	if err := service.PauseJobType(context.Background(), worker.JobTypeScrapeFlyer); err != nil {
+		t.Fatalf("PauseJobType() error = %v", err)
+	}
+	if len(queue.paused) != 1 || queue.paused[0] != worker.JobTypeScrapeFlyer {
+		t.Errorf("paused %v, want only %s", queue.paused, worker.JobTypeScrapeFlyer)
+	}
+}
+
+func TestJobAdminService_WithoutQueue(t *testing.T) {
+	service := NewJobAdminService(nil, nil, nil)
+
+	if _, err := service.ListJobs(context.Background(), worker.JobFilter{}); !apperrors.IsType(err, apperrors.ErrorTypeInternal) {
+		t.Errorf("ListJobs() error = %v, want internal", err)
+	}
+	if _, err := service.RetryDeadLetters(context.Background(), DeadLetterFilter{}); !apperrors.IsType(err, apperrors.ErrorTypeInternal) {
+		t.Errorf("RetryDeadLetters() error = %v, want internal", err)
+	}
+}
+
+func TestJobAdminService_GetJobAttachesProgress(t *testing.T) {
+	queue := &stubJobQueue{jobs: map[string]*worker.Job{
+		"running": {ID: "running", Type: worker.JobTypeScrapeFlyer, Status: worker.JobStatusProcessing},
+	}}
+	progress := newStubProgressStore()
+	progress.progress["running"] = &worker.Progress{Percent: 30, Step: "scraping"}
+	progress.cancels["running"] = true
+
+	job, err := newTestJobAdminService(queue, progress).GetJob(context.Background(), "running")
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if job.Progress == nil || job.Progress.Percent != 30 || !job.CancelRequested {
+		t.Errorf("GetJob() = %+v with progress %+v, want 30%% and cancellation requested", job, job.Progress)
+	}
+}
+
+func TestJobAdminService_CancelJob(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        worker.JobStatus
+		withProgress  bool
+		wantStatus    worker.JobStatus
+		wantRequested bool
+		wantErr       apperrors.ErrorType
+	}{
+		{
+			name:         "waiting job is removed",
+			status:       worker.JobStatusPending,
+			withProgress: true,
+			wantStatus:   worker.JobStatusCancelled,
+		},
+		{
+			name:          "running job is asked to stop",
+			status:        worker.JobStatusProcessing,
+			withProgress:  true,
+			wantStatus:    worker.JobStatusProcessing,
+			wantRequested: true,
+		},
+		{
+			name:    "running job without progress store",
+			status:  worker.JobStatusProcessing,
+			wantErr: apperrors.ErrorTypeConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &stubJobQueue{jobs: map[string]*worker.Job{
+				"job": {ID: "job", Type: worker.JobTypeScrapeFlyer, Status: tt.status},
+			}}
+			service := NewJobAdminService(queue, nil, nil)
+			progress := newStubProgressStore()
+			if tt.withProgress {
+				service = newTestJobAdminService(queue, progress)
+			}
+
+			job, err := service.CancelJob(context.Background(), "job")
+			if tt.wantErr != "" {
+				if !apperrors.IsType(err, tt.wantErr) {
+					t.Fatalf("CancelJob() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CancelJob() error = %v", err)
+			}
+			if job.Status != tt.wantStatus || job.CancelRequested != tt.wantRequested {
+				t.Errorf("CancelJob() = status %s, cancel requested %v; want %s, %v",
+					job.Status, job.CancelRequested, tt.wantStatus, tt.wantRequested)
+			}
+			if progress.cancels["job"] != tt.wantRequested {
+				t.Errorf("cancellation requested = %v, want %v", progress.cancels["job"], tt.wantRequested)
+			}
+		})
+	}
+}
+
+func TestJobAdminService_WatchJobEndsWhenJobFinishes(t *testing.T) {
+	queue := &stubJobQueue{jobs: map[string]*worker.Job{
+		"job": {ID: "job", Type: worker.JobTypeScrapeFlyer, Status: worker.JobStatusProcessing},
+	}}
+	progress := newStubProgressStore()
+	progress.updates <- &worker.Job{ID: "job", Status: worker.JobStatusProcessing, Progress: &worker.Progress{Percent: 50}}
+	progress.updates <- &worker.Job{ID: "job", Status: worker.JobStatusRetrying}
+	progress.updates <- &worker.Job{ID: "job", Status: worker.JobStatusCompleted, Progress: &worker.Progress{Percent: 100}}
+	progress.updates <- &worker.Job{ID: "job", Status: worker.JobStatusProcessing}
+
+	jobs, err := newTestJobAdminService(queue, progress).WatchJob(context.Background(), "job")
+	if err != nil {
+		t.Fatalf("WatchJob() error = %v", err)
+	}
+
+	var statuses []worker.JobStatus
+	for job := range jobs {
+		statuses = append(statuses, job.Status)
+	}
+	want := []worker.JobStatus{worker.JobStatusProcessing, worker.JobStatusProcessing, worker.JobStatusRetrying, worker.JobStatusCompleted}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("WatchJob() sent %v, want %v", statuses, want)
+	}
+}
+
+func TestJobAdminService_WatchJobNotFound(t *testing.T) {
+	service := newTestJobAdminService(&stubJobQueue{}, newStubProgressStore())
+
+	if _, err := service.WatchJob(context.Background(), "missing"); !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Errorf("WatchJob() error = %v, want not found", err)
+	}
+	if _, err := NewJobAdminService(&stubJobQueue{}, nil, nil).WatchJob(context.Background(), "missing"); !apperrors.IsType(err, apperrors.ErrorTypeInternal) {
+		t.Errorf("WatchJob() without progress store error = %v, want internal", err)
+	}
+}
+
+// stubWorkflowEngine starts workflows in memory
+type stubWorkflowEngine struct {
+	workflows map[string]*worker.Workflow
+	filters   []worker.WorkflowFilter
+}
+
+func (e *stubWorkflowEngine) Start(ctx context.Context, name string, params map[string]interface{}) (*worker.Workflow, error) {
+	if name != worker.WorkflowFlyerPipeline {
+		return nil, apperrors.ValidationF("unknown workflow %q", name)
+	}
+	wf := &worker.Workflow{ID: fmt.Sprintf("wf-%d", len(e.workflows)+1), Name: name, Status: worker.WorkflowStatusRunning, Params: params}
+	e.workflows[wf.ID] = wf
+	return wf, nil
+}
+
+func (e *stubWorkflowEngine) Get(ctx context.Context, id string) (*worker.Workflow, error) {
+	wf, ok := e.workflows[id]
+	if !ok {
+		return nil, apperrors.NotFound("workflow " + id + " not found")
+	}
+	return wf, nil
+}
+
+func (e *stubWorkflowEngine) List(ctx context.Context, filter worker.WorkflowFilter) ([]*worker.Workflow, error) {
+	e.filters = append(e.filters, filter)
+	var workflows []*worker.Workflow
+	for _, wf := range e.workflows {
+		workflows = append(workflows, wf)
+	}
+	return workflows, nil
+}
+
+func TestJobAdminService_Workflows(t *testing.T) {
+	ctx := context.Background()
+	engine := &stubWorkflowEngine{workflows: make(map[string]*worker.Workflow)}
+	service := NewJobAdminService(&stubJobQueue{}, nil, nil).(*jobAdminService)
+	service.workflows = engine
+
+	wf, err := service.StartWorkflow(ctx, worker.WorkflowFlyerPipeline, map[string]interface{}{"stores": []string{"iki"}})
+	if err != nil {
+		t.Fatalf("StartWorkflow() error = %v", err)
+	}
+	if got, err := service.GetWorkflow(ctx, wf.ID); err != nil || got.Name != worker.WorkflowFlyerPipeline {
+		t.Errorf("GetWorkflow() = %+v, %v", got, err)
+	}
+	if _, err := service.StartWorkflow(ctx, "unknown", nil); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("StartWorkflow(unknown) error = %v, want validation", err)
+	}
+
+	listed, err := service.ListWorkflows(ctx, worker.WorkflowFilter{Status: worker.WorkflowStatusRunning})
+	if err != nil || len(listed) != 1 {
+		t.Errorf("ListWorkflows() = %+v, %v", listed, err)
+	}
+	if _, err := service.ListWorkflows(ctx, worker.WorkflowFilter{Status: "stuck"}); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("ListWorkflows(stuck) error = %v, want validation", err)
+	}
+	if len(engine.filters) != 1 {
+		t.Errorf("engine listed %d times, want once for the valid filter", len(engine.filters))
+	}
+
+	withoutEngine := NewJobAdminService(&stubJobQueue{}, nil, nil)
+	if _, err := withoutEngine.StartWorkflow(ctx, worker.WorkflowFlyerPipeline, nil); !apperrors.IsType(err, apperrors.ErrorTypeInternal) {
+		t.Errorf("StartWorkflow() without an engine error = %v, want internal", err)
+	}
+}
@@ -0,0 +1,127 @@
+package matching
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+// Thresholds split match scores into automatic matches, matches queued for
+// review and misses that get a new product master
+type Thresholds struct {
+	AutoMatch float64
+	Review    float64
+}
+
+// DefaultThresholds apply until a model has been trained for the category
+var DefaultThresholds = Thresholds{AutoMatch: 0.85, Review: 0.65}
+
+// learnedModelTTL is how long trained models are cached before reloading
+const learnedModelTTL = 10 * time.Minute
+
+// learnedModel is a trained logistic regression bound to the strategy order
+type learnedModel struct {
+	model      *LogisticModel
+	thresholds Thresholds
+}
+
+// learnedModels caches the matcher_models rows by lower-cased category
+type learnedModels struct {
+	mu       sync.RWMutex
+	byCat    map[string]*learnedModel
+	loadedAt time.Time
+}
+
+// newLearnedModel binds stored weights to the given strategy order; strategies
+// missing from the stored weights get a zero coefficient
+func newLearnedModel(row *models.MatcherModel, strategies []MatchStrategy) *learnedModel {
+	weights := make([]float64, len(strategies))
+	for i, strategy := range strategies {
+		weights[i] = row.Weights[strategy.Name()]
+	}
+	return &learnedModel{
+		model: &LogisticModel{Weights: weights, Bias: row.Bias},
+		thresholds: Thresholds{
+			AutoMatch: row.AutoMatchThreshold,
+			Review:    row.ReviewThreshold,
+		},
+	}
+}
+
+// learnedFor returns the trained model for the category, falling back to the
+// global model; nil means the hard-coded weights apply
+func (m *CompositeMatcher) learnedFor(ctx context.Context, category *string) *learnedModel {
+	m.refreshLearned(ctx)
+
+	m.learned.mu.RLock()
+	defer m.learned.mu.RUnlock()
+
+	if category != nil {
+		if lm, ok := m.learned.byCat[strings.ToLower(*category)]; ok {
+			return lm
+		}
+	}
+	return m.learned.byCat[""]
+}
+
+// refreshLearned reloads trained models once the cache expires. Load failures
+// (e.g. before the migration ran) keep the previous models until the next TTL.
+func (m *CompositeMatcher) refreshLearned(ctx context.Context) {
+	if m.db == nil {
+		return
+	}
+
+	m.learned.mu.RLock()
+	fresh := time.Since(m.learned.loadedAt) < learnedModelTTL
+	m.learned.mu.RUnlock()
+	if fresh {
+		return
+	}
+
+	m.learned.mu.Lock()
+	defer m.learned.mu.Unlock()
+	if time.Since(m.learned.loadedAt) < learnedModelTTL {
+		return
+	}
+	m.learned.loadedAt = time.Now()
+
+	var rows []*models.MatcherModel
+	if err := m.db.NewSelect().Model(&rows).Scan(ctx); err != nil {
+		return
+	}
+
+	byCat := make(map[string]*learnedModel, len(rows))
+	for _, row := range rows {
+		byCat[strings.ToLower(row.Category)] = newLearnedModel(row, m.strategies)
+	}
+	m.learned.byCat = byCat
+}
+
+// Thresholds returns the auto-match and review thresholds for the category
+func (m *CompositeMatcher) Thresholds(ctx context.Context, category *string) Thresholds {
+	if lm := m.learnedFor(ctx, category); lm != nil {
+		return lm.thresholds
+	}
+	return DefaultThresholds
+}
+
+// StrategyNames lists the strategies in feature order
+func (m *CompositeMatcher) StrategyNames() []string {
+	names := make([]string, len(m.strategies))
+	for i, strategy := range m.strategies {
+		names[i] = strategy.Name()
+	}
+	return names
+}
+
+// Features returns the per-strategy scores used as model features
+func (m *CompositeMatcher) Features(product *models.Product, master *models.ProductMaster) []float64 {
+	features := make([]float64, len(m.strategies))
+	for i, strategy := range m.strategies {
+		features[i] = strategy.Score(product, master)
+	}
+	return features
+}
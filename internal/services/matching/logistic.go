@@ -0,0 +1,198 @@
+package matching
+
+import (
+	"math"
+	"sort"
+)
+
+// TrainingSample is one reviewed product/master pair: the per-strategy scores
+// and whether a reviewer accepted the match
+type TrainingSample struct {
+	Features []float64
+	Accepted bool
+	Category string
+}
+
+// LogisticOptions control gradient descent for TrainLogistic
+type LogisticOptions struct {
+	Iterations   int
+	LearningRate float64
+	L2           float64 // ridge penalty keeps weights finite on separable data
+}
+
+// DefaultLogisticOptions are tuned for the handful of [0,1] strategy scores
+var DefaultLogisticOptions = LogisticOptions{
+	Iterations:   2000,
+	LearningRate: 0.5,
+	L2:           0.01,
+}
+
+// LogisticModel is a binary logistic regression over strategy scores
+type LogisticModel struct {
+	Weights []float64
+	Bias    float64
+}
+
+// Predict returns the probability that the features describe a true match
+func (lm *LogisticModel) Predict(features []float64) float64 {
+	z := lm.Bias
+	for i, w := range lm.Weights {
+		if i < len(features) {
+			z += w * features[i]
+		}
+	}
+	return sigmoid(z)
+}
+
+// TrainLogistic fits a logistic regression with batch gradient descent.
+// Positive and negative samples are weighted equally so that a review queue
+// dominated by one outcome does not pull every prediction towards it.
+func TrainLogistic(samples []TrainingSample, featureCount int, opts LogisticOptions) *LogisticModel {
+	model := &LogisticModel{Weights: make([]float64, featureCount)}
+	if len(samples) == 0 {
+		return model
+	}
+
+	positives := 0
+	for _, s := range samples {
+		if s.Accepted {
+			positives++
+		}
+	}
+	negatives := len(samples) - positives
+	posWeight, negWeight := 1.0, 1.0
+	if positives > 0 && negatives > 0 {
+		posWeight = float64(len(samples)) / (2 * float64(positives))
+		negWeight = float64(len(samples)) / (2 * float64(negatives))
+	}
+
+	n := float64(len(samples))
+	gradW := make([]float64, featureCount)
+	for iter := 0; iter < opts.Iterations; iter++ {
+		for i := range gradW {
+			gradW[i] = 0
+		}
+		gradB := 0.0
+
+		for _, s := range samples {
+			label, weight := 0.0, negWeight
+			if s.Accepted {
+				label, weight = 1.0, posWeight
+			}
+			diff := (model.Predict(s.Features) - label) * weight
+			for i := 0; i < featureCount && i < len(s.Features); i++ {
+				gradW[i] += diff * s.Features[i]
+			}
+			gradB += diff
+		}
+
+		for i := range model.Weights {
+			model.Weights[i] -= opts.LearningRate * (gradW[i]/n + opts.L2*model.Weights[i])
+		}
+		model.Bias -= opts.LearningRate * gradB / n
+	}
+
+	return model
+}
+
+// Evaluation summarizes how a scorer classifies samples at a threshold
+type Evaluation struct {
+	Samples   int
+	Threshold float64
+	Precision float64
+	Recall    float64
+}
+
+// Evaluate computes precision and recall of scores at or above threshold
+// being treated as matches
+func Evaluate(samples []TrainingSample, score func(TrainingSample) float64, threshold float64) Evaluation {
+	var tp, fp, fn int
+	for _, s := range samples {
+		predicted := score(s) >= threshold
+		switch {
+		case predicted && s.Accepted:
+			tp++
+		case predicted && !s.Accepted:
+			fp++
+		case !predicted && s.Accepted:
+			fn++
+		}
+	}
+
+	eval := Evaluation{Samples: len(samples), Threshold: threshold}
+	if tp+fp > 0 {
+		eval.Precision = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		eval.Recall = float64(tp) / float64(tp+fn)
+	}
+	return eval
+}
+
+// ChooseThresholds picks the auto-match threshold as the lowest score that
+// still reaches targetPrecision, and the review threshold as the highest score
+// that keeps targetRecall, so only near-certain matches skip review and few
+// true matches end up as new masters. Defaults are kept when the samples
+// cannot support a choice.
+func ChooseThresholds(samples []TrainingSample, score func(TrainingSample) float64, targetPrecision, targetRecall float64) Thresholds {
+	thresholds := DefaultThresholds
+	if len(samples) == 0 {
+		return thresholds
+	}
+
+	type scored struct {
+		score    float64
+		accepted bool
+	}
+	ranked := make([]scored, len(samples))
+	positives := 0
+	for i, s := range samples {
+		ranked[i] = scored{score: score(s), accepted: s.Accepted}
+		if s.Accepted {
+			positives++
+		}
+	}
+	if positives == 0 {
+		return thresholds
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	// Walk from the highest score down; at each distinct score the counts are
+	// those of treating every sample at or above it as a match
+	autoMatch, review := math.NaN(), math.NaN()
+	tp, fp := 0, 0
+	for i := 0; i < len(ranked); i++ {
+		if ranked[i].accepted {
+			tp++
+		} else {
+			fp++
+		}
+		if i+1 < len(ranked) && ranked[i+1].score == ranked[i].score {
+			continue
+		}
+
+		precision := float64(tp) / float64(tp+fp)
+		recall := float64(tp) / float64(positives)
+		if precision >= targetPrecision {
+			autoMatch = ranked[i].score
+		}
+		if math.IsNaN(review) && recall >= targetRecall {
+			review = ranked[i].score
+		}
+	}
+
+	if !math.IsNaN(autoMatch) {
+		thresholds.AutoMatch = autoMatch
+	}
+	if !math.IsNaN(review) {
+		thresholds.Review = review
+	}
+	if thresholds.Review > thresholds.AutoMatch {
+		thresholds.Review = thresholds.AutoMatch
+	}
+	return thresholds
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
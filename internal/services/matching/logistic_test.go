@@ -0,0 +1,105 @@
+package matching
+
+import (
+	"context"
+	"testing"
+)
+
+// reviewedSamples simulates reviews where exact/fuzzy name agreement decides
+// the outcome and brand/category agreement alone does not
+func reviewedSamples(category string, n int) []TrainingSample {
+	samples := make([]TrainingSample, 0, n)
+	for i := 0; i < n; i++ {
+		strength := float64(i%10) / 10
+		accepted := i%2 == 0
+		features := []float64{0, 0, 0.5 + strength/2, 0}
+		if accepted {
+			features[1] = 0.7 + strength*0.3
+			if i%4 == 0 {
+				features[0] = 1
+			}
+		}
+		samples = append(samples, TrainingSample{Features: features, Accepted: accepted, Category: category})
+	}
+	return samples
+}
+
+func TestTrainLogistic_SeparatesReviewedMatches(t *testing.T) {
+	samples := reviewedSamples("dairy", 100)
+	model := TrainLogistic(samples, 4, DefaultLogisticOptions)
+
+	for _, s := range samples {
+		p := model.Predict(s.Features)
+		if s.Accepted && p < 0.5 {
+			t.Fatalf("accepted sample %v predicted %.3f", s.Features, p)
+		}
+		if !s.Accepted && p >= 0.5 {
+			t.Fatalf("rejected sample %v predicted %.3f", s.Features, p)
+		}
+	}
+	if model.Weights[1] <= model.Weights[2] {
+		t.Fatalf("expected fuzzy name to outweigh brand/category, got %v", model.Weights)
+	}
+}
+
+func TestChooseThresholds(t *testing.T) {
+	samples := []TrainingSample{
+		{Features: []float64{0.95}, Accepted: true},
+		{Features: []float64{0.90}, Accepted: true},
+		{Features: []float64{0.80}, Accepted: false},
+		{Features: []float64{0.75}, Accepted: true},
+		{Features: []float64{0.40}, Accepted: false},
+	}
+	score := func(s TrainingSample) float64 { return s.Features[0] }
+
+	thresholds := ChooseThresholds(samples, score, 1.0, 1.0)
+	if thresholds.AutoMatch != 0.90 {
+		t.Fatalf("expected auto-match threshold 0.90, got %.2f", thresholds.AutoMatch)
+	}
+	if thresholds.Review != 0.75 {
+		t.Fatalf("expected review threshold 0.75, got %.2f", thresholds.Review)
+	}
+
+	eval := Evaluate(samples, score, thresholds.AutoMatch)
+	if eval.Precision != 1 || eval.Recall < 0.66 || eval.Recall > 0.67 {
+		t.Fatalf("unexpected evaluation: %+v", eval)
+	}
+
+	if got := ChooseThresholds(nil, score, 1, 1); got != DefaultThresholds {
+		t.Fatalf("expected default thresholds without samples, got %+v", got)
+	}
+}
+
+func TestTrainer_TrainsGlobalAndCategoryModels(t *testing.T) {
+	opts := DefaultTrainerOptions
+	opts.MinSamples = 40
+	trainer := NewTrainer(nil, opts)
+
+	samples := append(reviewedSamples("dairy", 60), reviewedSamples("bakery", 20)...)
+	report, err := trainer.Train(context.Background(), samples)
+	if err != nil {
+		t.Fatalf("Train returned error: %v", err)
+	}
+
+	if len(report.Categories) != 2 || report.Categories[0].Category != "" || report.Categories[1].Category != "dairy" {
+		t.Fatalf("expected global and dairy models, got %+v", report.Categories)
+	}
+
+	dairy := report.Categories[1]
+	if dairy.After.Samples != 12 || dairy.TrainingSamples != 48 {
+		t.Fatalf("unexpected holdout split: train=%d test=%d", dairy.TrainingSamples, dairy.After.Samples)
+	}
+	if dairy.After.Precision < dairy.Before.Precision {
+		t.Fatalf("expected trained precision %.2f to be at least %.2f", dairy.After.Precision, dairy.Before.Precision)
+	}
+	if _, ok := dairy.Model.Weights["fuzzy_name"]; !ok {
+		t.Fatalf("expected weights keyed by strategy name, got %v", dairy.Model.Weights)
+	}
+	if dairy.Model.ReviewThreshold > dairy.Model.AutoMatchThreshold {
+		t.Fatalf("review threshold %.2f above auto-match %.2f", dairy.Model.ReviewThreshold, dairy.Model.AutoMatchThreshold)
+	}
+
+	if _, err := trainer.Train(context.Background(), reviewedSamples("dairy", 10)); err == nil {
+		t.Fatalf("expected error with too few reviewed matches")
+	}
+}
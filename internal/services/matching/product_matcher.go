@@ -2,6 +2,8 @@ package matching
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"math"
 	"strings"
 
@@ -28,6 +30,7 @@ type CompositeMatcher struct {
 	db         *bun.DB
 	normalizer *normalize.LithuanianNormalizer
 	strategies []MatchStrategy
+	learned    learnedModels
 }
 
 func NewCompositeMatcher(db *bun.DB) *CompositeMatcher {
@@ -47,6 +50,18 @@ func NewCompositeMatcher(db *bun.DB) *CompositeMatcher {
 }
 
 func (m *CompositeMatcher) FindBestMatches(ctx context.Context, product *models.Product, limit int) ([]*MatchResult, error) {
+	// An exact GTIN hit identifies the master; no need to score name candidates
+	if master, err := m.findByBarcode(ctx, product); err != nil {
+		return nil, err
+	} else if master != nil {
+		return []*MatchResult{{
+			Master:     master,
+			Score:      1.0,
+			Method:     "barcode",
+			Confidence: 1.0,
+		}}, nil
+	}
+
 	var candidates []*models.ProductMaster
 
 	query := m.db.NewSelect().
@@ -54,6 +69,8 @@ func (m *CompositeMatcher) FindBestMatches(ctx context.Context, product *models.
 		Where("pm.status = ?", models.ProductMasterStatusActive).
 		Where("pm.confidence_score >= ?", 0.3)
 
+	query = m.excludeRejected(query, product)
+
 	if product.Brand != nil && *product.Brand != "" {
 		query = query.Where("(pm.brand = ? OR pm.brand IS NULL)", *product.Brand)
 	}
@@ -69,14 +86,22 @@ func (m *CompositeMatcher) FindBestMatches(ctx context.Context, product *models.
 		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to fetch master candidates")
 	}
 
+	// Scores of a trained model are probabilities; keep everything the
+	// review band could still use
+	method, minScore := "composite", 0.5
+	learned := m.learnedFor(ctx, product.Category)
+	if learned != nil {
+		method, minScore = "learned", math.Min(minScore, learned.thresholds.Review)
+	}
+
 	var results []*MatchResult
 	for _, master := range candidates {
-		score := m.calculateCompositeScore(product, master)
-		if score >= 0.5 {
+		score := m.scoreCandidate(product, master, learned)
+		if score >= minScore {
 			results = append(results, &MatchResult{
 				Master:     master,
 				Score:      score,
-				Method:     "composite",
+				Method:     method,
 				Confidence: score,
 			})
 		}
@@ -91,14 +116,66 @@ func (m *CompositeMatcher) FindBestMatches(ctx context.Context, product *models.
 	return results, nil
 }
 
-func (m *CompositeMatcher) calculateCompositeScore(product *models.Product, master *models.ProductMaster) float64 {
+func (m *CompositeMatcher) findByBarcode(ctx context.Context, product *models.Product) (*models.ProductMaster, error) {
+	gtin, ok := productGTIN(product)
+	if !ok {
+		return nil, nil
+	}
+
+	master := new(models.ProductMaster)
+	query := m.db.NewSelect().
+		Model(master).
+		Where("pm.barcode = ?", gtin).
+		Where("pm.status = ?", models.ProductMasterStatusActive)
+
+	err := m.excludeRejected(query, product).
+		Order("pm.confidence_score DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to fetch master by barcode")
+	}
+
+	return master, nil
+}
+
+// RejectionKey returns the key a rejected product/master pair is remembered
+// under, so the same product in later flyers is never proposed for the master
+func (m *CompositeMatcher) RejectionKey(productName string) string {
+	return m.normalizer.NormalizeForSearch(productName)
+}
+
+// excludeRejected filters out masters a reviewer rejected for this product
+func (m *CompositeMatcher) excludeRejected(query *bun.SelectQuery, product *models.Product) *bun.SelectQuery {
+	key := m.RejectionKey(product.Name)
+	if key == "" {
+		return query
+	}
+	return query.Where(`NOT EXISTS (
+		SELECT 1 FROM product_master_match_rejections pmr
+		WHERE pmr.product_master_id = pm.id AND pmr.normalized_name = ?
+	)`, key)
+}
+
+func (m *CompositeMatcher) scoreCandidate(product *models.Product, master *models.ProductMaster, learned *learnedModel) float64 {
+	features := m.Features(product, master)
+	if learned != nil {
+		return learned.model.Predict(features)
+	}
+	return m.WeightedScore(features)
+}
+
+// WeightedScore combines strategy scores with the hard-coded strategy weights
+func (m *CompositeMatcher) WeightedScore(features []float64) float64 {
 	var totalScore float64
 	var totalWeight float64
 
-	for _, strategy := range m.strategies {
-		score := strategy.Score(product, master)
+	for i, strategy := range m.strategies {
 		weight := strategy.Weight()
-		totalScore += score * weight
+		totalScore += features[i] * weight
 		totalWeight += weight
 	}
 
@@ -139,11 +216,15 @@ type FuzzyNameMatcher struct {
 	weight float64
 }
 
+// nameStemmer lets FuzzyNameMatcher compare inflected forms ("pieno" vs "pienas")
+var nameStemmer = normalize.NewLithuanianStemmer()
+
 func (m *FuzzyNameMatcher) Score(product *models.Product, master *models.ProductMaster) float64 {
-	similarity := calculateTrigramSimilarity(product.NormalizedName, master.NormalizedName)
+	productStem := nameStemmer.StemText(product.NormalizedName)
+	similarity := stemmedSimilarity(product.NormalizedName, productStem, master.NormalizedName)
 
 	for _, altName := range master.AlternativeNames {
-		altSim := calculateTrigramSimilarity(product.NormalizedName, altName)
+		altSim := stemmedSimilarity(product.NormalizedName, productStem, altName)
 		if altSim > similarity {
 			similarity = altSim
 		}
@@ -156,6 +237,16 @@ func (m *FuzzyNameMatcher) Score(product *models.Product, master *models.Product
 	return similarity
 }
 
+// stemmedSimilarity is the better of the trigram similarity of the names as
+// written and of their stems
+func stemmedSimilarity(productName, productStem, masterName string) float64 {
+	similarity := calculateTrigramSimilarity(productName, masterName)
+	if stemSim := calculateTrigramSimilarity(productStem, nameStemmer.StemText(masterName)); stemSim > similarity {
+		similarity = stemSim
+	}
+	return similarity
+}
+
 func (m *FuzzyNameMatcher) Weight() float64 {
 	return m.weight
 }
@@ -207,7 +298,17 @@ type BarcodeMatcher struct {
 }
 
 func (m *BarcodeMatcher) Score(product *models.Product, master *models.ProductMaster) float64 {
-	return 0.0
+	productCode, ok := productGTIN(product)
+	if !ok || master.Barcode == nil {
+		return 0.0
+	}
+
+	masterCode, err := normalize.NormalizeGTIN(*master.Barcode)
+	if err != nil || masterCode != productCode {
+		return 0.0
+	}
+
+	return 1.0
 }
 
 func (m *BarcodeMatcher) Weight() float64 {
@@ -218,6 +319,18 @@ func (m *BarcodeMatcher) Name() string {
 	return "barcode"
 }
 
+// productGTIN returns the product's barcode in GTIN-14 form, if it has a valid one
+func productGTIN(product *models.Product) (string, bool) {
+	if product.Barcode == nil {
+		return "", false
+	}
+	gtin, err := normalize.NormalizeGTIN(*product.Barcode)
+	if err != nil {
+		return "", false
+	}
+	return gtin, true
+}
+
 func calculateTrigramSimilarity(s1, s2 string) float64 {
 	if s1 == s2 {
 		return 1.0
@@ -1,9 +1,12 @@
 package matching
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
 )
 
 func TestExactNameMatcher(t *testing.T) {
@@ -207,3 +210,143 @@ func TestBrandCategoryMatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestBarcodeMatcher(t *testing.T) {
+	matcher := &BarcodeMatcher{weight: 1.0}
+
+	ean13 := "4006381333931"
+	gtin14 := "04006381333931"
+	other := "96385074"
+	invalid := "4006381333932"
+
+	tests := []struct {
+		name          string
+		product       *models.Product
+		master        *models.ProductMaster
+		expectedScore float64
+		description   string
+	}{
+		{
+			name:          "same_gtin_different_forms",
+			product:       &models.Product{Barcode: &ean13},
+			master:        &models.ProductMaster{Barcode: &gtin14},
+			expectedScore: 1.0,
+			description:   "EAN-13 and its GTIN-14 form should match exactly",
+		},
+		{
+			name:          "different_gtin",
+			product:       &models.Product{Barcode: &ean13},
+			master:        &models.ProductMaster{Barcode: &other},
+			expectedScore: 0.0,
+			description:   "Different barcodes should not match",
+		},
+		{
+			name:          "invalid_check_digit",
+			product:       &models.Product{Barcode: &invalid},
+			master:        &models.ProductMaster{Barcode: &invalid},
+			expectedScore: 0.0,
+			description:   "Barcodes failing check-digit validation should be ignored",
+		},
+		{
+			name:          "missing_barcode",
+			product:       &models.Product{},
+			master:        &models.ProductMaster{Barcode: &gtin14},
+			expectedScore: 0.0,
+			description:   "Products without a barcode should not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := matcher.Score(tt.product, tt.master)
+			if score != tt.expectedScore {
+				t.Errorf("%s: expected score %.2f, got %.2f",
+					tt.description, tt.expectedScore, score)
+			}
+		})
+	}
+}
+
+// fuzzyVariationQueries are the queries of test_fuzzy_variations.sh plus
+// inflected forms, each with the catalog product it should find first
+var fuzzyVariationQueries = []struct {
+	query string
+	want  string
+}{
+	{"pienas", "Pienas DVARO 2,5% 1 l"},
+	{"penas", "Pienas DVARO 2,5% 1 l"},
+	{"pianes", "Pienas DVARO 2,5% 1 l"},
+	{"pien", "Pienas DVARO 2,5% 1 l"},
+	{"duona", "Duona JUODOJI 800 g"},
+	{"dona", "Duona JUODOJI 800 g"},
+	{"PIENAS", "Pienas DVARO 2,5% 1 l"},
+	{"pieno", "Pienas DVARO 2,5% 1 l"},
+	{"obuolių", "Obuoliai LIGOL 1 kg"},
+	{"sūrio", "Sūris DŽIUGAS 180 g"},
+	{"kiaušinių", "Kiaušiniai M dydžio 10 vnt."},
+	{"vištienos", "Vištiena šviežia filė 1 kg"},
+	{"jogurtų", "Jogurtas graikiškas 400 g"},
+	{"sviesto", "Sviestas ROKIŠKIO 82% 180 g"},
+	{"bulvių", "Bulvės šviežios 2 kg"},
+}
+
+// TestFuzzyNameMatcher_QueryVariationsRecall measures how many queries find
+// their product, with and without stemming. Like pg_trgm word_similarity, a
+// query finds a product when it is similar enough to one of the product's words
+// and no other catalog product scores higher.
+func TestFuzzyNameMatcher_QueryVariationsRecall(t *testing.T) {
+	const threshold = 0.5
+	normalizer := normalize.NewLithuanianNormalizer()
+
+	var catalog []string
+	seen := map[string]bool{}
+	for _, q := range fuzzyVariationQueries {
+		if !seen[q.want] {
+			seen[q.want] = true
+			catalog = append(catalog, q.want)
+		}
+	}
+
+	recall := func(stem bool) float64 {
+		wordScore := func(query, name string) float64 {
+			best := 0.0
+			for _, word := range strings.Fields(name) {
+				s := calculateTrigramSimilarity(query, word)
+				if stem {
+					s = stemmedSimilarity(query, nameStemmer.Stem(query), word)
+				}
+				best = math.Max(best, s)
+			}
+			return best
+		}
+
+		hits := 0
+		for _, q := range fuzzyVariationQueries {
+			query := normalizer.NormalizeForSearch(q.query)
+			best, bestScore := "", 0.0
+			for _, name := range catalog {
+				if s := wordScore(query, normalizer.NormalizeForSearch(name)); s > bestScore {
+					best, bestScore = name, s
+				}
+			}
+			if best == q.want && bestScore >= threshold {
+				hits++
+			} else if stem {
+				t.Logf("query %q found %q (%.2f), want %q", q.query, best, bestScore, q.want)
+			}
+		}
+		return float64(hits) / float64(len(fuzzyVariationQueries))
+	}
+
+	withoutStemming, withStemming := recall(false), recall(true)
+	t.Logf("recall without stemming %.2f, with stemming %.2f", withoutStemming, withStemming)
+
+	if withStemming <= withoutStemming {
+		t.Errorf("expected stemming to improve recall over %.2f, got %.2f", withoutStemming, withStemming)
+	}
+	// Heavy typos ("pianes", "dona") stay below the threshold; search catches
+	// them with its lower trigram threshold
+	if withStemming < 0.85 {
+		t.Errorf("expected recall of at least 0.85 with stemming, got %.2f", withStemming)
+	}
+}
@@ -0,0 +1,241 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// TrainerOptions configure offline training of matcher weights
+type TrainerOptions struct {
+	MinSamples      int     // reviewed matches needed before a category gets its own model
+	TargetPrecision float64 // precision required above the auto-match threshold
+	TargetRecall    float64 // share of true matches that must score above the review threshold
+	HoldoutEvery    int     // every Nth reviewed match is kept out of training for evaluation
+	Logistic        LogisticOptions
+}
+
+// DefaultTrainerOptions are used by cmd/train-matcher unless overridden
+var DefaultTrainerOptions = TrainerOptions{
+	MinSamples:      50,
+	TargetPrecision: 0.95,
+	TargetRecall:    0.95,
+	HoldoutEvery:    5,
+	Logistic:        DefaultLogisticOptions,
+}
+
+// CategoryReport describes the model trained for one category; the empty
+// category is the global model
+type CategoryReport struct {
+	Category        string
+	TrainingSamples int
+	Before          Evaluation
+	After           Evaluation
+	Model           *models.MatcherModel
+}
+
+// TrainingReport is the outcome of a training run
+type TrainingReport struct {
+	Samples    int
+	Categories []*CategoryReport
+}
+
+// Trainer learns CompositeMatcher weights and thresholds from reviewed matches
+type Trainer struct {
+	db      *bun.DB
+	matcher *CompositeMatcher
+	opts    TrainerOptions
+}
+
+// NewTrainer creates a trainer for the matcher's strategies
+func NewTrainer(db *bun.DB, opts TrainerOptions) *Trainer {
+	return &Trainer{
+		db:      db,
+		matcher: NewCompositeMatcher(db),
+		opts:    opts,
+	}
+}
+
+// StrategyNames lists the matcher strategies in feature order
+func (t *Trainer) StrategyNames() []string {
+	return t.matcher.StrategyNames()
+}
+
+// LoadSamples turns accepted and rejected product_master_matches into
+// training samples, ordered by match ID so holdout splits are stable
+func (t *Trainer) LoadSamples(ctx context.Context) ([]TrainingSample, error) {
+	var matches []*models.ProductMasterMatch
+	if err := t.db.NewSelect().
+		Model(&matches).
+		Relation("Product").
+		Relation("Master").
+		Where("pmm.review_status IN (?)", bun.In([]models.MatchReviewStatus{
+			models.MatchReviewStatusApproved,
+			models.MatchReviewStatusRejected,
+		})).
+		Order("pmm.id ASC").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load reviewed matches: %w", err)
+	}
+
+	samples := make([]TrainingSample, 0, len(matches))
+	for _, match := range matches {
+		if match.Product == nil || match.Master == nil {
+			continue
+		}
+
+		// Score the product the way FindMatchingMastersWithScores does
+		product := *match.Product
+		product.NormalizedName = t.matcher.normalizer.NormalizeForSearch(product.Name)
+
+		category := match.Master.Category
+		if product.Category != nil && *product.Category != "" {
+			category = product.Category
+		}
+
+		sample := TrainingSample{
+			Features: t.matcher.Features(&product, match.Master),
+			Accepted: match.ReviewStatus == string(models.MatchReviewStatusApproved),
+		}
+		if category != nil {
+			sample.Category = strings.ToLower(*category)
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// Train fits a global model and one model per category with enough reviewed
+// matches, and evaluates each against the currently active scorer on held-out
+// samples
+func (t *Trainer) Train(ctx context.Context, samples []TrainingSample) (*TrainingReport, error) {
+	report := &TrainingReport{Samples: len(samples)}
+
+	groups := map[string][]TrainingSample{"": samples}
+	for _, s := range samples {
+		if s.Category != "" {
+			groups[s.Category] = append(groups[s.Category], s)
+		}
+	}
+
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		group := groups[category]
+		if !t.trainable(group) {
+			if category == "" {
+				return nil, fmt.Errorf("not enough reviewed matches to train: need %d with both accepted and rejected, have %d", t.opts.MinSamples, len(group))
+			}
+			continue
+		}
+		report.Categories = append(report.Categories, t.trainCategory(ctx, category, group))
+	}
+
+	return report, nil
+}
+
+func (t *Trainer) trainCategory(ctx context.Context, category string, samples []TrainingSample) *CategoryReport {
+	train, test := t.split(samples)
+
+	var categoryPtr *string
+	if category != "" {
+		categoryPtr = &category
+	}
+	current := t.matcher.learnedFor(ctx, categoryPtr)
+	currentThresholds := DefaultThresholds
+	currentScore := func(s TrainingSample) float64 { return t.matcher.WeightedScore(s.Features) }
+	if current != nil {
+		currentThresholds = current.thresholds
+		currentScore = func(s TrainingSample) float64 { return current.model.Predict(s.Features) }
+	}
+
+	model := TrainLogistic(train, len(t.matcher.strategies), t.opts.Logistic)
+	score := func(s TrainingSample) float64 { return model.Predict(s.Features) }
+	thresholds := ChooseThresholds(train, score, t.opts.TargetPrecision, t.opts.TargetRecall)
+
+	after := Evaluate(test, score, thresholds.AutoMatch)
+	weights := make(map[string]float64, len(model.Weights))
+	for i, name := range t.matcher.StrategyNames() {
+		weights[name] = model.Weights[i]
+	}
+
+	return &CategoryReport{
+		Category:        category,
+		TrainingSamples: len(train),
+		Before:          Evaluate(test, currentScore, currentThresholds.AutoMatch),
+		After:           after,
+		Model: &models.MatcherModel{
+			Category:           category,
+			Weights:            weights,
+			Bias:               model.Bias,
+			AutoMatchThreshold: thresholds.AutoMatch,
+			ReviewThreshold:    thresholds.Review,
+			TrainingSamples:    len(train),
+			Precision:          &after.Precision,
+			Recall:             &after.Recall,
+			TrainedAt:          time.Now(),
+		},
+	}
+}
+
+// Save persists the trained models, replacing earlier ones per category
+func (t *Trainer) Save(ctx context.Context, report *TrainingReport) error {
+	return t.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, cat := range report.Categories {
+			if _, err := tx.NewInsert().
+				Model(cat.Model).
+				On("CONFLICT (category) DO UPDATE").
+				Set("weights = EXCLUDED.weights").
+				Set("bias = EXCLUDED.bias").
+				Set("auto_match_threshold = EXCLUDED.auto_match_threshold").
+				Set("review_threshold = EXCLUDED.review_threshold").
+				Set("training_samples = EXCLUDED.training_samples").
+				Set("precision = EXCLUDED.precision").
+				Set("recall = EXCLUDED.recall").
+				Set("trained_at = EXCLUDED.trained_at").
+				Exec(ctx); err != nil {
+				return fmt.Errorf("failed to save matcher model for category %q: %w", cat.Category, err)
+			}
+		}
+		return nil
+	})
+}
+
+// trainable checks the group has enough samples of both outcomes
+func (t *Trainer) trainable(samples []TrainingSample) bool {
+	if len(samples) < t.opts.MinSamples {
+		return false
+	}
+	accepted := 0
+	for _, s := range samples {
+		if s.Accepted {
+			accepted++
+		}
+	}
+	return accepted > 0 && accepted < len(samples)
+}
+
+// split keeps every HoldoutEvery-th sample for evaluation
+func (t *Trainer) split(samples []TrainingSample) (train, test []TrainingSample) {
+	if t.opts.HoldoutEvery <= 1 {
+		return samples, samples
+	}
+	for i, s := range samples {
+		if i%t.opts.HoldoutEvery == t.opts.HoldoutEvery-1 {
+			test = append(test, s)
+		} else {
+			train = append(train, s)
+		}
+	}
+	return train, test
+}
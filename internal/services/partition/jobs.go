@@ -0,0 +1,47 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+)
+
+// JobHandler runs the scheduled manage_partitions jobs, keeping the products
+// partitions ready ahead of inserts and archiving the ones past retention
+type JobHandler struct {
+	manager *Manager
+	logger  *slog.Logger
+}
+
+// NewJobHandler creates a handler for manage_partitions jobs
+func NewJobHandler(manager *Manager) *JobHandler {
+	return &JobHandler{
+		manager: manager,
+		logger:  slog.Default().With("worker", "manage_partitions"),
+	}
+}
+
+// Register installs the handler on a worker processor
+func (h *JobHandler) Register(processor *worker.WorkerProcessor) {
+	processor.RegisterHandler(worker.JobTypeManagePartitions, h.Handle)
+}
+
+// Handle processes one manage_partitions job. A returned error makes the queue retry the job.
+func (h *JobHandler) Handle(ctx context.Context, job *worker.Job) error {
+	result, err := h.manager.Maintain(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to maintain products partitions: %w", err)
+	}
+
+	progress := worker.ProgressFromContext(ctx)
+	progress.Add(ctx, "created", int64(len(result.Created)))
+	progress.Add(ctx, "archived", int64(len(result.Archived)))
+
+	h.logger.Info("maintained products partitions",
+		"job_id", job.ID,
+		"created", result.Created,
+		"archived", result.Archived)
+	return nil
+}
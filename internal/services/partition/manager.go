@@ -0,0 +1,418 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// ArchiveSchema holds the partitions detached from products once they pass the
+// retention window
+const ArchiveSchema = "product_archive"
+
+const (
+	productsTable     = "products"
+	defaultWeeksAhead = 4
+	defaultRetention  = 8
+)
+
+// partitionBoundPattern matches the range of a partition as pg_get_expr prints it:
+// FOR VALUES FROM ('2025-01-06') TO ('2025-01-13')
+var partitionBoundPattern = regexp.MustCompile(`FROM \('(\d{4}-\d{2}-\d{2})[^']*'\) TO \('(\d{4}-\d{2}-\d{2})[^']*'\)`)
+
+// HealthStatus is how well the partitions cover the weeks products are inserted for
+type HealthStatus string
+
+const (
+	HealthStatusHealthy   HealthStatus = "healthy"
+	HealthStatusDegraded  HealthStatus = "degraded"
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// Partition is one weekly partition of products, holding the rows whose
+// valid_from falls in [From, To)
+type Partition struct {
+	Name string    `json:"name"`
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Health reports whether inserts for the current and the coming weeks have a
+// partition, and whether partitions past the retention window are still attached
+type Health struct {
+	Status       HealthStatus `json:"status"`
+	Message      string       `json:"message"`
+	Partitions   int          `json:"partitions"`
+	CoveredUntil *time.Time   `json:"covered_until,omitempty"`
+	MissingWeeks []string     `json:"missing_weeks,omitempty"`
+	Expired      []string     `json:"expired,omitempty"`
+}
+
+// MaintenanceResult lists the partitions one Maintain run created and archived
+type MaintenanceResult struct {
+	Created  []string `json:"created"`
+	Archived []string `json:"archived"`
+}
+
+// Manager keeps the weekly partitions of products: it creates them ahead of the
+// inserts needing them and moves the ones past retention to ArchiveSchema. On
+// databases other than Postgres products isn't partitioned and it does nothing.
+type Manager struct {
+	db             *bun.DB
+	weeksAhead     int
+	retentionWeeks int
+	now            func() time.Time
+
+	// ensured holds the weeks known to have a partition, so inserts only pay for
+	// the check once per week and process
+	mu      sync.Mutex
+	ensured map[time.Time]bool
+}
+
+// NewManager creates a partition manager. Zero config values use 4 weeks ahead
+// and 8 weeks of retention.
+func NewManager(db *bun.DB, cfg config.PartitionConfig) *Manager {
+	m := &Manager{
+		db:             db,
+		weeksAhead:     cfg.WeeksAhead,
+		retentionWeeks: cfg.RetentionWeeks,
+		now:            time.Now,
+		ensured:        make(map[time.Time]bool),
+	}
+	if m.weeksAhead <= 0 {
+		m.weeksAhead = defaultWeeksAhead
+	}
+	if m.retentionWeeks <= 0 {
+		m.retentionWeeks = defaultRetention
+	}
+	return m
+}
+
+// EnsureForDates makes sure products valid from the given dates can be inserted,
+// creating the partitions of their weeks when missing
+func (m *Manager) EnsureForDates(ctx context.Context, dates ...time.Time) error {
+	if !m.Partitioned() {
+		return nil
+	}
+
+	m.mu.Lock()
+	var weeks []time.Time
+	for _, date := range dates {
+		start := weekStart(date)
+		if !m.ensured[start] {
+			weeks = append(weeks, start)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, start := range uniqueWeeks(weeks) {
+		if _, err := m.ensureWeek(ctx, start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureForProducts makes sure the partitions of the products' valid_from exist
+// before they are inserted
+func (m *Manager) EnsureForProducts(ctx context.Context, products []*models.Product) error {
+	dates := make([]time.Time, 0, len(products))
+	for _, product := range products {
+		dates = append(dates, product.ValidFrom)
+	}
+	return m.EnsureForDates(ctx, dates...)
+}
+
+// Partitions returns the partitions attached to products, ordered by week
+func (m *Manager) Partitions(ctx context.Context) ([]Partition, error) {
+	if !m.Partitioned() {
+		return nil, nil
+	}
+
+	var rows []struct {
+		Name  string `bun:"name"`
+		Bound string `bun:"bound"`
+	}
+	err := m.db.NewRaw(`
+		SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = ?::regclass`, productsTable).Scan(ctx, &rows)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list products partitions")
+	}
+
+	partitions := make([]Partition, 0, len(rows))
+	for _, row := range rows {
+		from, to, ok := parseBound(row.Bound)
+		if !ok {
+			// A DEFAULT partition has no range and covers nothing on its own
+			continue
+		}
+		partitions = append(partitions, Partition{Name: row.Name, From: from, To: to})
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].From.Before(partitions[j].From) })
+	return partitions, nil
+}
+
+// Detached returns the tables in ArchiveSchema holding partitions detached from
+// products, ordered by name
+func (m *Manager) Detached(ctx context.Context) ([]string, error) {
+	if !m.Partitioned() {
+		return nil, nil
+	}
+
+	var names []string
+	err := m.db.NewSelect().
+		Table("pg_tables").
+		Column("tablename").
+		Where("schemaname = ?", ArchiveSchema).
+		Order("tablename").
+		Scan(ctx, &names)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list detached products partitions")
+	}
+	return names, nil
+}
+
+// DropDetached deletes a detached partition from ArchiveSchema, once its rows
+// are kept elsewhere
+func (m *Manager) DropDetached(ctx context.Context, name string) error {
+	if _, err := m.db.ExecContext(ctx, "DROP TABLE ?", bun.Ident(ArchiveSchema+"."+name)); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to drop detached products partition %s", name)
+	}
+	return nil
+}
+
+// Maintain creates the partitions of the current week and the configured weeks
+// ahead, and detaches the partitions that ended before the retention window
+// into ArchiveSchema
+func (m *Manager) Maintain(ctx context.Context) (*MaintenanceResult, error) {
+	result := &MaintenanceResult{Created: []string{}, Archived: []string{}}
+	if !m.Partitioned() {
+		return result, nil
+	}
+
+	partitions, err := m.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := weekStart(m.now())
+	for i := 0; i <= m.weeksAhead; i++ {
+		start := current.AddDate(0, 0, 7*i)
+		if covered(partitions, start) {
+			m.markEnsured(start)
+			continue
+		}
+		name, err := m.ensureWeek(ctx, start)
+		if err != nil {
+			return result, err
+		}
+		result.Created = append(result.Created, name)
+	}
+
+	for _, partition := range expired(partitions, m.retentionCutoff()) {
+		if err := m.archive(ctx, partition); err != nil {
+			return result, err
+		}
+		result.Archived = append(result.Archived, partition.Name)
+	}
+	return result, nil
+}
+
+// Health checks the partitions cover the current week and the configured weeks
+// ahead. A missing current week is unhealthy as every insert for it fails; a
+// missing later week or a partition left past retention is degraded.
+func (m *Manager) Health(ctx context.Context) *Health {
+	if !m.Partitioned() {
+		return &Health{Status: HealthStatusHealthy, Message: "Products are not partitioned on this database"}
+	}
+
+	partitions, err := m.Partitions(ctx)
+	if err != nil {
+		return &Health{Status: HealthStatusUnhealthy, Message: err.Error()}
+	}
+	return assessHealth(partitions, m.now(), m.weeksAhead, m.retentionCutoff())
+}
+
+// ensureWeek creates the partition of the week starting at start through
+// ensure_partition_for_date, which does nothing when it already exists
+func (m *Manager) ensureWeek(ctx context.Context, start time.Time) (string, error) {
+	var name string
+	err := m.db.NewRaw("SELECT ensure_partition_for_date(?::date)", start.Format("2006-01-02")).Scan(ctx, &name)
+	if err != nil {
+		return "", apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to ensure products partition for week of %s", start.Format("2006-01-02"))
+	}
+	m.markEnsured(start)
+	return name, nil
+}
+
+// archive detaches a partition from products and moves it to ArchiveSchema. A
+// week can be archived again after late products recreated its partition; the
+// later table then gets a numbered name next to the earlier one.
+func (m *Manager) archive(ctx context.Context, partition Partition) error {
+	err := m.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE ? DETACH PARTITION ?", bun.Ident(productsTable), bun.Ident(partition.Name)); err != nil {
+			return err
+		}
+		name, err := archivedName(ctx, tx, partition.Name)
+		if err != nil {
+			return err
+		}
+		if name != partition.Name {
+			if _, err := tx.ExecContext(ctx, "ALTER TABLE ? RENAME TO ?", bun.Ident(partition.Name), bun.Ident(name)); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, "ALTER TABLE ? SET SCHEMA ?", bun.Ident(name), bun.Ident(ArchiveSchema))
+		return err
+	})
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to archive products partition %s", partition.Name)
+	}
+
+	m.mu.Lock()
+	delete(m.ensured, partition.From)
+	m.mu.Unlock()
+	return nil
+}
+
+// archivedName returns the first of name, name_2, name_3... not yet taken in ArchiveSchema
+func archivedName(ctx context.Context, db bun.IDB, name string) (string, error) {
+	candidate := name
+	for i := 2; ; i++ {
+		taken, err := db.NewSelect().
+			Table("pg_tables").
+			Where("schemaname = ?", ArchiveSchema).
+			Where("tablename = ?", candidate).
+			Exists(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+}
+
+func (m *Manager) markEnsured(start time.Time) {
+	m.mu.Lock()
+	m.ensured[start] = true
+	m.mu.Unlock()
+}
+
+// retentionCutoff is the start of the oldest week whose partition stays attached
+func (m *Manager) retentionCutoff() time.Time {
+	return weekStart(m.now()).AddDate(0, 0, -7*m.retentionWeeks)
+}
+
+// Partitioned reports whether products is partitioned, which it is on Postgres
+func (m *Manager) Partitioned() bool {
+	return m.db.Dialect().Name() == dialect.PG
+}
+
+// assessHealth rates how the partitions cover the weeks from now's through
+// weeksAhead later
+func assessHealth(partitions []Partition, now time.Time, weeksAhead int, cutoff time.Time) *Health {
+	health := &Health{Status: HealthStatusHealthy, Partitions: len(partitions)}
+
+	current := weekStart(now)
+	contiguous := true
+	for i := 0; i <= weeksAhead; i++ {
+		start := current.AddDate(0, 0, 7*i)
+		if !covered(partitions, start) {
+			health.MissingWeeks = append(health.MissingWeeks, start.Format("2006-01-02"))
+			contiguous = false
+			continue
+		}
+		if contiguous {
+			end := start.AddDate(0, 0, 7)
+			health.CoveredUntil = &end
+		}
+	}
+	for _, partition := range expired(partitions, cutoff) {
+		health.Expired = append(health.Expired, partition.Name)
+	}
+
+	switch {
+	case health.CoveredUntil == nil:
+		health.Status = HealthStatusUnhealthy
+		health.Message = fmt.Sprintf("No products partition for the week of %s", current.Format("2006-01-02"))
+	case len(health.MissingWeeks) > 0:
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("%d of the next %d weeks have no products partition", len(health.MissingWeeks), weeksAhead)
+	case len(health.Expired) > 0:
+		health.Status = HealthStatusDegraded
+		health.Message = fmt.Sprintf("%d products partitions are past retention and still attached", len(health.Expired))
+	default:
+		health.Message = fmt.Sprintf("Products partitions cover the weeks until %s", health.CoveredUntil.Format("2006-01-02"))
+	}
+	return health
+}
+
+// covered reports whether rows valid from the week starting at start have a partition
+func covered(partitions []Partition, start time.Time) bool {
+	for _, partition := range partitions {
+		if !partition.From.After(start) && partition.To.After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// expired returns the partitions that ended on or before cutoff
+func expired(partitions []Partition, cutoff time.Time) []Partition {
+	var old []Partition
+	for _, partition := range partitions {
+		if !partition.To.After(cutoff) {
+			old = append(old, partition)
+		}
+	}
+	return old
+}
+
+// weekStart returns the Monday starting the week of t's date, like Postgres'
+// date_trunc('week', ...)
+func weekStart(t time.Time) time.Time {
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+func uniqueWeeks(weeks []time.Time) []time.Time {
+	seen := make(map[time.Time]bool, len(weeks))
+	unique := weeks[:0]
+	for _, start := range weeks {
+		if !seen[start] {
+			seen[start] = true
+			unique = append(unique, start)
+		}
+	}
+	return unique
+}
+
+func parseBound(bound string) (from, to time.Time, ok bool) {
+	match := partitionBoundPattern.FindStringSubmatch(bound)
+	if match == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	from, err := time.Parse("2006-01-02", match[1])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	to, err = time.Parse("2006-01-02", match[2])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
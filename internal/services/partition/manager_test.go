@@ -0,0 +1,266 @@
+package partition
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/config"
+	"github.com/kainuguru/kainuguru-api/internal/migrator"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// weekly returns attached partitions for the weeks starting at the given Mondays
+func weekly(mondays ...string) []Partition {
+	partitions := make([]Partition, 0, len(mondays))
+	for _, monday := range mondays {
+		from := date(monday)
+		partitions = append(partitions, Partition{
+			Name: "products_" + monday,
+			From: from,
+			To:   from.AddDate(0, 0, 7),
+		})
+	}
+	return partitions
+}
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		in   time.Time
+		want string
+	}{
+		{in: date("2025-01-06"), want: "2025-01-06"},                                                  // Monday
+		{in: date("2025-01-12"), want: "2025-01-06"},                                                  // Sunday
+		{in: date("2025-01-01"), want: "2024-12-30"},                                                  // across the year
+		{in: time.Date(2025, 3, 2, 23, 30, 0, 0, time.FixedZone("EET", 2*60*60)), want: "2025-02-24"}, // the date counts, not the UTC instant
+	}
+
+	for _, tt := range tests {
+		if got := weekStart(tt.in).Format("2006-01-02"); got != tt.want {
+			t.Errorf("weekStart(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseBound(t *testing.T) {
+	from, to, ok := parseBound("FOR VALUES FROM ('2025-01-06') TO ('2025-01-13')")
+	if !ok || !from.Equal(date("2025-01-06")) || !to.Equal(date("2025-01-13")) {
+		t.Errorf("parseBound(range) = %s, %s, %v", from, to, ok)
+	}
+
+	if _, _, ok := parseBound("DEFAULT"); ok {
+		t.Error("parseBound(DEFAULT) parsed a range")
+	}
+}
+
+func TestAssessHealth(t *testing.T) {
+	now := date("2025-03-05") // a Wednesday
+	cutoff := date("2025-01-06")
+
+	tests := []struct {
+		name        string
+		partitions  []Partition
+		wantStatus  HealthStatus
+		wantUntil   string
+		wantMissing []string
+		wantExpired []string
+	}{
+		{
+			name:       "current and two weeks ahead",
+			partitions: weekly("2025-02-24", "2025-03-03", "2025-03-10", "2025-03-17"),
+			wantStatus: HealthStatusHealthy,
+			wantUntil:  "2025-03-24",
+		},
+		{
+			name:        "next week missing",
+			partitions:  weekly("2025-03-03", "2025-03-17"),
+			wantStatus:  HealthStatusDegraded,
+			wantUntil:   "2025-03-10",
+			wantMissing: []string{"2025-03-10"},
+		},
+		{
+			name:        "current week missing",
+			partitions:  weekly("2025-03-10", "2025-03-17"),
+			wantStatus:  HealthStatusUnhealthy,
+			wantMissing: []string{"2025-03-03"},
+		},
+		{
+			name:        "partition past retention still attached",
+			partitions:  weekly("2024-12-30", "2025-01-06", "2025-03-03", "2025-03-10", "2025-03-17"),
+			wantStatus:  HealthStatusDegraded,
+			wantUntil:   "2025-03-24",
+			wantExpired: []string{"products_2024-12-30"},
+		},
+		{
+			name: "a wider partition covers several weeks",
+			partitions: []Partition{
+				{Name: "products_march", From: date("2025-03-01"), To: date("2025-04-01")},
+			},
+			wantStatus: HealthStatusHealthy,
+			wantUntil:  "2025-03-24",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := assessHealth(tt.partitions, now, 2, cutoff)
+
+			if health.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s (%s)", health.Status, tt.wantStatus, health.Message)
+			}
+			var until string
+			if health.CoveredUntil != nil {
+				until = health.CoveredUntil.Format("2006-01-02")
+			}
+			if until != tt.wantUntil {
+				t.Errorf("CoveredUntil = %q, want %q", until, tt.wantUntil)
+			}
+			if !reflect.DeepEqual(health.MissingWeeks, tt.wantMissing) {
+				t.Errorf("MissingWeeks = %v, want %v", health.MissingWeeks, tt.wantMissing)
+			}
+			if !reflect.DeepEqual(health.Expired, tt.wantExpired) {
+				t.Errorf("Expired = %v, want %v", health.Expired, tt.wantExpired)
+			}
+			if health.Partitions != len(tt.partitions) || health.Message == "" {
+				t.Errorf("Partitions = %d, Message = %q", health.Partitions, health.Message)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	partitions := weekly("2024-12-23", "2024-12-30", "2025-01-06")
+
+	var names []string
+	for _, partition := range expired(partitions, date("2025-01-06")) {
+		names = append(names, partition.Name)
+	}
+	want := []string{"products_2024-12-23", "products_2024-12-30"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expired() = %v, want %v", names, want)
+	}
+}
+
+func TestNewManager_Defaults(t *testing.T) {
+	m := NewManager(nil, config.PartitionConfig{})
+	if m.weeksAhead != 4 || m.retentionWeeks != 8 {
+		t.Errorf("defaults = %d weeks ahead, %d weeks retention, want 4 and 8", m.weeksAhead, m.retentionWeeks)
+	}
+
+	m = NewManager(nil, config.PartitionConfig{WeeksAhead: 2, RetentionWeeks: 12})
+	m.now = func() time.Time { return date("2025-03-05") }
+	if got := m.retentionCutoff().Format("2006-01-02"); got != "2024-12-09" {
+		t.Errorf("retentionCutoff() = %s, want 2024-12-09", got)
+	}
+}
+
+func TestManager_DoesNothingWithoutPostgres(t *testing.T) {
+	sqldb, err := sql.Open(sqliteshim.DriverName(), "file::memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	m := NewManager(db, config.PartitionConfig{})
+
+	products := []*models.Product{{ValidFrom: date("2025-04-01")}}
+	if err := m.EnsureForProducts(ctx, products); err != nil {
+		t.Errorf("EnsureForProducts() error = %v", err)
+	}
+	result, err := m.Maintain(ctx)
+	if err != nil || len(result.Created) != 0 || len(result.Archived) != 0 {
+		t.Errorf("Maintain() = %+v, %v, want nothing done", result, err)
+	}
+	if health := m.Health(ctx); health.Status != HealthStatusHealthy {
+		t.Errorf("Health() = %+v, want healthy", health)
+	}
+}
+
+// postgresTestDB connects to the database at POSTGRES_TEST_DSN and migrates it,
+// skipping the test without one
+func postgresTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	if err := migrator.New(db).Up(context.Background(), migrator.RunOptions{}); err != nil {
+		t.Fatalf("failed to migrate %s: %v", dsn, err)
+	}
+	return db
+}
+
+func TestManager_EnsuresArchivedWeekAgain(t *testing.T) {
+	db := postgresTestDB(t)
+	ctx := context.Background()
+	m := NewManager(db, config.PartitionConfig{})
+
+	week := date("1999-01-04")
+	var archived []string
+	t.Cleanup(func() {
+		for _, name := range archived {
+			_, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS ?.?", bun.Ident(ArchiveSchema), bun.Ident(name))
+		}
+	})
+
+	// Archive the week twice: a late product recreates the partition in between
+	for round := 1; round <= 2; round++ {
+		if err := m.EnsureForDates(ctx, week); err != nil {
+			t.Fatalf("round %d: EnsureForDates() error = %v", round, err)
+		}
+		partitions, err := m.Partitions(ctx)
+		if err != nil {
+			t.Fatalf("round %d: Partitions() error = %v", round, err)
+		}
+		var partition *Partition
+		for i := range partitions {
+			if partitions[i].From.Equal(week) {
+				partition = &partitions[i]
+			}
+		}
+		if partition == nil {
+			t.Fatalf("round %d: no partition attached for the week of %s", round, week.Format("2006-01-02"))
+		}
+
+		want, err := archivedName(ctx, db, partition.Name)
+		if err != nil {
+			t.Fatalf("archivedName() error = %v", err)
+		}
+		if round == 2 && want != partition.Name+"_2" {
+			t.Errorf("round 2 archives as %s, want %s_2 next to the first round's table", want, partition.Name)
+		}
+		if err := m.archive(ctx, *partition); err != nil {
+			t.Fatalf("round %d: archive() error = %v", round, err)
+		}
+		archived = append(archived, want)
+	}
+
+	partitions, err := m.Partitions(ctx)
+	if err != nil {
+		t.Fatalf("Partitions() error = %v", err)
+	}
+	if covered(partitions, week) {
+		t.Error("archived week is still attached")
+	}
+}
@@ -47,6 +47,11 @@ func NewProductMasterServiceWithRepository(db *bun.DB, repo productmaster.Reposi
 }
 
 // Basic CRUD operations
+// maxMergeRedirects bounds how many merged_into_id hops GetByID follows
+const maxMergeRedirects = 5
+
+// GetByID returns the product master, following merge redirects so that IDs
+// of masters merged into another one keep resolving
 func (s *productMasterService) GetByID(ctx context.Context, id int64) (*models.ProductMaster, error) {
 	master, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -56,6 +61,17 @@ func (s *productMasterService) GetByID(ctx context.Context, id int64) (*models.P
 		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get product master by ID %d", id)
 	}
 
+	for hops := 0; hops < maxMergeRedirects && master.MergedIntoID != nil; hops++ {
+		target, err := s.repo.GetByID(ctx, *master.MergedIntoID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to follow merge redirect of product master %d", master.ID)
+		}
+		master = target
+	}
+
 	return master, nil
 }
 
@@ -159,6 +175,47 @@ func (s *productMasterService) GetByCanonicalName(ctx context.Context, name stri
 	return master, nil
 }
 
+// GetByBarcode returns the active product master with the given EAN/UPC/GTIN barcode
+func (s *productMasterService) GetByBarcode(ctx context.Context, barcode string) (*models.ProductMaster, error) {
+	gtin, err := normalize.NormalizeGTIN(barcode)
+	if err != nil {
+		return nil, apperrors.Validation(fmt.Sprintf("invalid barcode %q", barcode))
+	}
+
+	master, err := s.repo.GetByBarcode(ctx, gtin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("product master not found with barcode %s", gtin))
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get product master by barcode")
+	}
+
+	return master, nil
+}
+
+// AssignBarcode stores the barcode on a product master in canonical GTIN-14 form
+func (s *productMasterService) AssignBarcode(ctx context.Context, masterID int64, barcode string) (*models.ProductMaster, error) {
+	gtin, err := normalize.NormalizeGTIN(barcode)
+	if err != nil {
+		return nil, apperrors.Validation(fmt.Sprintf("invalid barcode %q", barcode))
+	}
+
+	master, err := s.GetByID(ctx, masterID)
+	if err != nil {
+		return nil, err
+	}
+	if master.Barcode != nil && *master.Barcode == gtin {
+		return master, nil
+	}
+
+	master.Barcode = &gtin
+	if err := s.Update(ctx, master); err != nil {
+		return nil, err
+	}
+
+	return master, nil
+}
+
 func (s *productMasterService) GetActiveProductMasters(ctx context.Context) ([]*models.ProductMaster, error) {
 	masters, err := s.repo.GetActive(ctx)
 	if err != nil {
@@ -255,6 +312,12 @@ func (s *productMasterService) MatchProduct(ctx context.Context, productID int,
 	return nil
 }
 
+// GetMatchThresholds returns the auto-match and review thresholds for the
+// category, learned from reviewed matches when a model has been trained
+func (s *productMasterService) GetMatchThresholds(ctx context.Context, category *string) matching.Thresholds {
+	return s.matcher.Thresholds(ctx, category)
+}
+
 // FindBestMatch finds the best matching product masters for a product
 func (s *productMasterService) FindBestMatch(ctx context.Context, product *models.Product, limit int) ([]*ProductMasterMatch, error) {
 	matchResults, err := s.matcher.FindBestMatches(ctx, product, limit)
@@ -289,6 +352,7 @@ func (s *productMasterService) CreateFromProduct(ctx context.Context, product *m
 		Category:        product.Category,
 		Subcategory:     product.Subcategory,
 		Tags:            product.Tags,
+		Barcode:         product.Barcode,
 		MatchCount:      1,
 		ConfidenceScore: 0.5,
 		LastSeenDate:    &now,
@@ -336,6 +400,7 @@ func (s *productMasterService) CreateMasterFromProduct(ctx context.Context, prod
 		Category:        product.Category,
 		Subcategory:     product.Subcategory,
 		Tags:            product.Tags,
+		Barcode:         product.Barcode,
 		StandardUnit:    product.UnitType,
 		MatchCount:      1,
 		ConfidenceScore: 0.5,
@@ -394,16 +459,218 @@ func (s *productMasterService) DeactivateProductMaster(ctx context.Context, mast
 }
 
 func (s *productMasterService) MarkAsDuplicate(ctx context.Context, masterID int64, duplicateOfID int64) error {
-	if err := s.repo.MarkAsDuplicate(ctx, masterID, duplicateOfID); err != nil {
-		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to mark as duplicate")
+	_, err := s.MergeProductMasters(ctx, []int64{masterID}, duplicateOfID)
+	return err
+}
+
+// MergeProductMasters merges the source masters into the target, re-linking
+// everything that references them and leaving a redirect behind
+func (s *productMasterService) MergeProductMasters(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error) {
+	if len(sourceIDs) == 0 {
+		return nil, apperrors.Validation("at least one source product master is required")
+	}
+
+	seen := make(map[int64]bool, len(sourceIDs))
+	unique := make([]int64, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		if id == targetID {
+			return nil, apperrors.ValidationF("product master %d cannot be merged into itself", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	target, err := s.repo.MergeMasters(ctx, unique, targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, apperrors.NotFound("product master not found for merge")
+		case errors.Is(err, productmaster.ErrMasterNotActive):
+			return nil, apperrors.Validation(err.Error())
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to merge product masters")
+	}
+
+	s.logger.Info("product masters merged",
+		slog.Any("source_ids", unique),
+		slog.Int64("target_id", targetID),
+		slog.Int("match_count", target.MatchCount),
+	)
+
+	return target, nil
+}
+
+// SplitProductMaster moves the given products of a master to a new master
+// created from the first of them
+func (s *productMasterService) SplitProductMaster(ctx context.Context, masterID int64, productIDs []int) (*models.ProductMaster, error) {
+	if len(productIDs) == 0 {
+		return nil, apperrors.Validation("at least one product is required to split a product master")
+	}
+
+	seen := make(map[int]bool, len(productIDs))
+	unique := make([]int, 0, len(productIDs))
+	for _, id := range productIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	product, err := s.repo.GetProduct(ctx, unique[0])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("product not found with ID %d", unique[0]))
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get product %d", unique[0])
+	}
+
+	now := time.Now()
+	genericName := s.normalizeProductName(product.Name, product.Brand)
+
+	master := &models.ProductMaster{
+		Name:            genericName,
+		NormalizedName:  s.normalizer.NormalizeForSearch(genericName),
+		Brand:           product.Brand,
+		Category:        product.Category,
+		Subcategory:     product.Subcategory,
+		Tags:            product.Tags,
+		Barcode:         product.Barcode,
+		StandardUnit:    product.UnitType,
+		ConfidenceScore: 0.5,
+		Status:          string(models.ProductMasterStatusActive),
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
 
-	s.logger.Info("product master marked as duplicate",
+	if product.UnitSize != nil {
+		master.StandardUnit = product.UnitSize
+	}
+
+	if err := s.repo.SplitMaster(ctx, masterID, unique, master); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, apperrors.NotFound(fmt.Sprintf("product master not found: %d", masterID))
+		case errors.Is(err, productmaster.ErrMasterNotActive), errors.Is(err, productmaster.ErrProductsNotInMaster):
+			return nil, apperrors.Validation(err.Error())
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to split product master")
+	}
+
+	s.logger.Info("product master split",
 		slog.Int64("master_id", masterID),
-		slog.Int64("duplicate_of_id", duplicateOfID),
+		slog.Int64("new_master_id", master.ID),
+		slog.Int("product_count", len(unique)),
 	)
 
-	return nil
+	return master, nil
+}
+
+// Match review operations
+
+// GetPendingMatches returns matches waiting for review, most confident first
+func (s *productMasterService) GetPendingMatches(ctx context.Context, limit, offset int) ([]*models.ProductMasterMatch, error) {
+	matches, err := s.repo.GetPendingMatches(ctx, limit, offset)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get pending matches")
+	}
+
+	return matches, nil
+}
+
+// AcceptMatch approves a pending match and links the product to its master
+func (s *productMasterService) AcceptMatch(ctx context.Context, matchID int64, reviewerID string) (*models.ProductMasterMatch, error) {
+	if err := s.repo.AcceptMatch(ctx, matchID, reviewerID, time.Now()); err != nil {
+		return nil, matchReviewError(err, matchID, "failed to accept match")
+	}
+
+	s.logger.Info("product master match accepted",
+		slog.Int64("match_id", matchID),
+		slog.String("reviewer", reviewerID),
+	)
+
+	return s.getMatch(ctx, matchID)
+}
+
+// RejectMatch rejects a pending match and remembers the pair as a negative
+// example so the matcher never proposes it again
+func (s *productMasterService) RejectMatch(ctx context.Context, matchID int64, reviewerID string) (*models.ProductMasterMatch, error) {
+	match, err := s.getMatch(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RejectMatch(ctx, matchID, s.rejectionKey(match), reviewerID, time.Now()); err != nil {
+		return nil, matchReviewError(err, matchID, "failed to reject match")
+	}
+
+	s.logger.Info("product master match rejected",
+		slog.Int64("match_id", matchID),
+		slog.Int64("master_id", match.ProductMasterID),
+		slog.String("reviewer", reviewerID),
+	)
+
+	return s.getMatch(ctx, matchID)
+}
+
+// ReassignMatch rejects a pending match and links the product to another master
+func (s *productMasterService) ReassignMatch(ctx context.Context, matchID, masterID int64, reviewerID string) (*models.ProductMasterMatch, error) {
+	match, err := s.getMatch(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if match.ProductMasterID == masterID {
+		return nil, apperrors.ValidationF("match %d already proposes product master %d", matchID, masterID)
+	}
+
+	reassigned, err := s.repo.ReassignMatch(ctx, matchID, masterID, s.rejectionKey(match), reviewerID, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("product master not found: %d", masterID))
+		}
+		return nil, matchReviewError(err, matchID, "failed to reassign match")
+	}
+
+	s.logger.Info("product master match reassigned",
+		slog.Int64("match_id", matchID),
+		slog.Int64("from_master_id", match.ProductMasterID),
+		slog.Int64("to_master_id", masterID),
+		slog.String("reviewer", reviewerID),
+	)
+
+	return s.getMatch(ctx, reassigned.ID)
+}
+
+func (s *productMasterService) getMatch(ctx context.Context, matchID int64) (*models.ProductMasterMatch, error) {
+	match, err := s.repo.GetMatch(ctx, matchID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("product master match not found: %d", matchID))
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get product master match %d", matchID)
+	}
+
+	return match, nil
+}
+
+// rejectionKey returns the key the rejected pair is remembered under
+func (s *productMasterService) rejectionKey(match *models.ProductMasterMatch) string {
+	if match.Product == nil {
+		return ""
+	}
+	return s.matcher.RejectionKey(match.Product.Name)
+}
+
+// matchReviewError maps repository errors of a review decision to application errors
+func matchReviewError(err error, matchID int64, message string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apperrors.NotFound(fmt.Sprintf("product master match not found: %d", matchID))
+	case errors.Is(err, productmaster.ErrMatchAlreadyReviewed), errors.Is(err, productmaster.ErrMasterNotActive):
+		return apperrors.Validation(err.Error())
+	}
+	return apperrors.Wrap(err, apperrors.ErrorTypeInternal, message)
 }
 
 // Statistics
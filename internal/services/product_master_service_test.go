@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"io"
 	"log/slog"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/kainuguru/kainuguru-api/internal/models"
 	"github.com/kainuguru/kainuguru-api/internal/productmaster"
+	"github.com/kainuguru/kainuguru-api/internal/services/matching"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
 	"github.com/kainuguru/kainuguru-api/pkg/normalize"
 )
 
@@ -48,6 +51,30 @@ func TestProductMasterService_CreateUsesRepo(t *testing.T) {
 	}
 }
 
+func TestProductMasterService_GetByBarcodeNormalizesGTIN(t *testing.T) {
+	repo := &productMasterRepoStub{getByBarcodeFunc: func(ctx context.Context, gtin string) (*models.ProductMaster, error) {
+		if gtin != "04006381333931" {
+			t.Fatalf("expected GTIN-14, got %q", gtin)
+		}
+		return &models.ProductMaster{ID: 7}, nil
+	}}
+	svc := &productMasterService{repo: repo}
+
+	master, err := svc.GetByBarcode(context.Background(), "4006381333931")
+	if err != nil || master.ID != 7 {
+		t.Fatalf("expected master 7, got %+v (err=%v)", master, err)
+	}
+
+	if _, err := svc.GetByBarcode(context.Background(), "4006381333932"); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error for bad check digit, got %v", err)
+	}
+
+	repo.getByBarcodeFunc = nil
+	if _, err := svc.GetByBarcode(context.Background(), "96385074"); !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
 func TestProductMasterService_UpdatePropagatesErrors(t *testing.T) {
 	want := errors.New("boom")
 	repo := &productMasterRepoStub{updateFunc: func(ctx context.Context, master *models.ProductMaster) (int64, error) {
@@ -60,6 +87,139 @@ func TestProductMasterService_UpdatePropagatesErrors(t *testing.T) {
 	}
 }
 
+func TestProductMasterService_GetByIDFollowsMergeRedirect(t *testing.T) {
+	repo := &productMasterRepoStub{getByIDFunc: func(ctx context.Context, id int64) (*models.ProductMaster, error) {
+		switch id {
+		case 1:
+			target := int64(2)
+			return &models.ProductMaster{ID: 1, Status: string(models.ProductMasterStatusMerged), MergedIntoID: &target}, nil
+		case 2:
+			return &models.ProductMaster{ID: 2, Status: string(models.ProductMasterStatusActive)}, nil
+		}
+		return nil, sql.ErrNoRows
+	}}
+	svc := &productMasterService{repo: repo}
+
+	master, err := svc.GetByID(context.Background(), 1)
+	if err != nil || master.ID != 2 {
+		t.Fatalf("expected redirect to master 2, got %+v (err=%v)", master, err)
+	}
+}
+
+func TestProductMasterService_MergeProductMasters(t *testing.T) {
+	repo := &productMasterRepoStub{mergeFunc: func(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error) {
+		if len(sourceIDs) != 2 || sourceIDs[0] != 3 || sourceIDs[1] != 4 || targetID != 1 {
+			t.Fatalf("unexpected merge arguments: %v -> %d", sourceIDs, targetID)
+		}
+		return &models.ProductMaster{ID: targetID}, nil
+	}}
+	svc := &productMasterService{repo: repo, logger: noopLogger()}
+
+	master, err := svc.MergeProductMasters(context.Background(), []int64{3, 4, 3}, 1)
+	if err != nil || master.ID != 1 {
+		t.Fatalf("expected merged master 1, got %+v (err=%v)", master, err)
+	}
+
+	if _, err := svc.MergeProductMasters(context.Background(), []int64{1, 3}, 1); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error when merging into itself, got %v", err)
+	}
+	if _, err := svc.MergeProductMasters(context.Background(), nil, 1); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error without sources, got %v", err)
+	}
+
+	repo.mergeFunc = func(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error) {
+		return nil, productmaster.ErrMasterNotActive
+	}
+	if _, err := svc.MergeProductMasters(context.Background(), []int64{3}, 1); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error for inactive master, got %v", err)
+	}
+
+	repo.mergeFunc = func(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error) {
+		return nil, sql.ErrNoRows
+	}
+	if err := svc.MarkAsDuplicate(context.Background(), 3, 1); !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Fatalf("expected MarkAsDuplicate to merge and report not found, got %v", err)
+	}
+}
+
+func TestProductMasterService_SplitProductMaster(t *testing.T) {
+	brand := "Dvaro"
+	repo := &productMasterRepoStub{
+		getProductFunc: func(ctx context.Context, productID int) (*models.Product, error) {
+			return &models.Product{ID: productID, Name: "Dvaro pienas 3.2%", Brand: &brand}, nil
+		},
+		splitFunc: func(ctx context.Context, masterID int64, productIDs []int, newMaster *models.ProductMaster) error {
+			if masterID != 5 || len(productIDs) != 2 {
+				t.Fatalf("unexpected split arguments: %d %v", masterID, productIDs)
+			}
+			if !newMaster.IsActive() || newMaster.Brand == nil || *newMaster.Brand != brand {
+				t.Fatalf("unexpected new master: %+v", newMaster)
+			}
+			newMaster.ID = 9
+			return nil
+		},
+	}
+	svc := &productMasterService{repo: repo, logger: noopLogger(), normalizer: normalize.NewLithuanianNormalizer()}
+
+	master, err := svc.SplitProductMaster(context.Background(), 5, []int{11, 12, 11})
+	if err != nil || master.ID != 9 {
+		t.Fatalf("expected new master 9, got %+v (err=%v)", master, err)
+	}
+
+	repo.splitFunc = func(ctx context.Context, masterID int64, productIDs []int, newMaster *models.ProductMaster) error {
+		return productmaster.ErrProductsNotInMaster
+	}
+	if _, err := svc.SplitProductMaster(context.Background(), 5, []int{13}); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error for foreign products, got %v", err)
+	}
+}
+
+func TestProductMasterService_RejectMatchRemembersNegativeExample(t *testing.T) {
+	const reviewer = "6f1c2a8e-6c1e-4bb8-9a55-2d6a3f0e0a11"
+	var rejectedKey string
+	repo := &productMasterRepoStub{
+		getMatchFunc: func(ctx context.Context, matchID int64) (*models.ProductMasterMatch, error) {
+			return &models.ProductMasterMatch{
+				ID:              matchID,
+				ProductMasterID: 4,
+				ReviewStatus:    string(models.MatchReviewStatusPending),
+				Product:         &models.Product{ID: 12, Name: "Šviežias Pienas"},
+			}, nil
+		},
+		rejectMatchFunc: func(ctx context.Context, matchID int64, rejectionKey, reviewerID string) error {
+			if reviewerID != reviewer {
+				t.Fatalf("expected reviewer to be forwarded, got %q", reviewerID)
+			}
+			rejectedKey = rejectionKey
+			return nil
+		},
+	}
+	svc := &productMasterService{repo: repo, logger: noopLogger(), matcher: matching.NewCompositeMatcher(nil)}
+
+	if _, err := svc.RejectMatch(context.Background(), 3, reviewer); err != nil {
+		t.Fatalf("RejectMatch returned error: %v", err)
+	}
+	if want := svc.matcher.RejectionKey("Šviežias Pienas"); rejectedKey != want || rejectedKey == "" {
+		t.Fatalf("expected rejection key %q, got %q", want, rejectedKey)
+	}
+
+	repo.rejectMatchFunc = func(ctx context.Context, matchID int64, rejectionKey, reviewerID string) error {
+		return productmaster.ErrMatchAlreadyReviewed
+	}
+	if _, err := svc.RejectMatch(context.Background(), 3, reviewer); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error for reviewed match, got %v", err)
+	}
+
+	if _, err := svc.ReassignMatch(context.Background(), 3, 4, reviewer); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Fatalf("expected validation error when reassigning to the proposed master, got %v", err)
+	}
+
+	repo.getMatchFunc = nil
+	if _, err := svc.AcceptMatch(context.Background(), 99, reviewer); !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
 type productMasterRepoStub struct {
 	getByIDFunc      func(ctx context.Context, id int64) (*models.ProductMaster, error)
 	getByIDsFunc     func(ctx context.Context, ids []int64) ([]*models.ProductMaster, error)
@@ -68,6 +228,7 @@ type productMasterRepoStub struct {
 	updateFunc       func(ctx context.Context, master *models.ProductMaster) (int64, error)
 	softDeleteFunc   func(ctx context.Context, id int64) (int64, error)
 	getCanonicalFunc func(ctx context.Context, normalizedName string) (*models.ProductMaster, error)
+	getByBarcodeFunc func(ctx context.Context, gtin string) (*models.ProductMaster, error)
 	getActiveFunc    func(ctx context.Context) ([]*models.ProductMaster, error)
 	getVerifiedFunc  func(ctx context.Context) ([]*models.ProductMaster, error)
 	getForReviewFunc func(ctx context.Context) ([]*models.ProductMaster, error)
@@ -76,6 +237,11 @@ type productMasterRepoStub struct {
 	getOverallFunc   func(ctx context.Context) (*productmaster.OverallStats, error)
 	createMasterFunc func(ctx context.Context, product *models.Product, master *models.ProductMaster) error
 	getProductFunc   func(ctx context.Context, productID int) (*models.Product, error)
+	mergeFunc        func(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error)
+	splitFunc        func(ctx context.Context, masterID int64, productIDs []int, newMaster *models.ProductMaster) error
+	getMatchFunc     func(ctx context.Context, matchID int64) (*models.ProductMasterMatch, error)
+	rejectMatchFunc  func(ctx context.Context, matchID int64, rejectionKey, reviewerID string) error
+	reassignFunc     func(ctx context.Context, matchID, masterID int64, rejectionKey, reviewerID string) (*models.ProductMasterMatch, error)
 }
 
 func (s *productMasterRepoStub) GetByID(ctx context.Context, id int64) (*models.ProductMaster, error) {
@@ -127,6 +293,13 @@ func (s *productMasterRepoStub) GetByCanonicalName(ctx context.Context, normaliz
 	return &models.ProductMaster{}, nil
 }
 
+func (s *productMasterRepoStub) GetByBarcode(ctx context.Context, gtin string) (*models.ProductMaster, error) {
+	if s.getByBarcodeFunc != nil {
+		return s.getByBarcodeFunc(ctx, gtin)
+	}
+	return nil, sql.ErrNoRows
+}
+
 func (s *productMasterRepoStub) GetActive(ctx context.Context) ([]*models.ProductMaster, error) {
 	if s.getActiveFunc != nil {
 		return s.getActiveFunc(ctx)
@@ -167,6 +340,20 @@ func (s *productMasterRepoStub) MarkAsDuplicate(ctx context.Context, masterID, d
 	return nil
 }
 
+func (s *productMasterRepoStub) MergeMasters(ctx context.Context, sourceIDs []int64, targetID int64) (*models.ProductMaster, error) {
+	if s.mergeFunc != nil {
+		return s.mergeFunc(ctx, sourceIDs, targetID)
+	}
+	return &models.ProductMaster{ID: targetID}, nil
+}
+
+func (s *productMasterRepoStub) SplitMaster(ctx context.Context, masterID int64, productIDs []int, newMaster *models.ProductMaster) error {
+	if s.splitFunc != nil {
+		return s.splitFunc(ctx, masterID, productIDs, newMaster)
+	}
+	return nil
+}
+
 func (s *productMasterRepoStub) CreateMasterWithMatch(ctx context.Context, product *models.Product, master *models.ProductMaster) error {
 	if s.createMasterFunc != nil {
 		return s.createMasterFunc(ctx, product, master)
@@ -212,6 +399,39 @@ func (s *productMasterRepoStub) MarkProductForReview(ctx context.Context, produc
 	return nil
 }
 
+func (s *productMasterRepoStub) CreatePendingMatch(ctx context.Context, match *models.ProductMasterMatch) error {
+	return nil
+}
+
+func (s *productMasterRepoStub) GetMatch(ctx context.Context, matchID int64) (*models.ProductMasterMatch, error) {
+	if s.getMatchFunc != nil {
+		return s.getMatchFunc(ctx, matchID)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *productMasterRepoStub) GetPendingMatches(ctx context.Context, limit, offset int) ([]*models.ProductMasterMatch, error) {
+	return []*models.ProductMasterMatch{}, nil
+}
+
+func (s *productMasterRepoStub) AcceptMatch(ctx context.Context, matchID int64, reviewerID string, reviewedAt time.Time) error {
+	return nil
+}
+
+func (s *productMasterRepoStub) RejectMatch(ctx context.Context, matchID int64, rejectionKey, reviewerID string, reviewedAt time.Time) error {
+	if s.rejectMatchFunc != nil {
+		return s.rejectMatchFunc(ctx, matchID, rejectionKey, reviewerID)
+	}
+	return nil
+}
+
+func (s *productMasterRepoStub) ReassignMatch(ctx context.Context, matchID, masterID int64, rejectionKey, reviewerID string, reviewedAt time.Time) (*models.ProductMasterMatch, error) {
+	if s.reassignFunc != nil {
+		return s.reassignFunc(ctx, matchID, masterID, rejectionKey, reviewerID)
+	}
+	return &models.ProductMasterMatch{ID: matchID, ProductMasterID: masterID}, nil
+}
+
 func (s *productMasterRepoStub) GetMasterProductCounts(ctx context.Context) ([]productmaster.MasterProductCount, error) {
 	return []productmaster.MasterProductCount{}, nil
 }
@@ -92,7 +92,32 @@ func (s *productService) CreateBatch(ctx context.Context, products []*models.Pro
 		return nil
 	}
 
-	// Validate and enrich products
+	if err := prepareProductsForInsert(products); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateBatch(ctx, products); err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to insert products batch")
+	}
+
+	return nil
+}
+
+// ReplacePageProducts replaces all products extracted from a flyer page with the given set
+func (s *productService) ReplacePageProducts(ctx context.Context, flyerPageID int, products []*models.Product) error {
+	if err := prepareProductsForInsert(products); err != nil {
+		return err
+	}
+
+	if err := s.repo.ReplaceByFlyerPage(ctx, flyerPageID, products); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to replace products for flyer page %d", flyerPageID)
+	}
+
+	return nil
+}
+
+// prepareProductsForInsert validates products and fills derived fields before insertion
+func prepareProductsForInsert(products []*models.Product) error {
 	now := time.Now()
 	for _, p := range products {
 		// Validate required fields
@@ -105,8 +130,9 @@ func (s *productService) CreateBatch(ctx context.Context, products []*models.Pro
 			p.NormalizedName = NormalizeProductText(p.Name)
 		}
 
-		// Generate search vector
+		// Generate search vector and stems
 		p.SearchVector = GenerateSearchVector(p.NormalizedName)
+		p.StemmedName = StemProductText(p.NormalizedName)
 
 		// Set timestamps
 		if p.CreatedAt.IsZero() {
@@ -131,11 +157,6 @@ func (s *productService) CreateBatch(ctx context.Context, products []*models.Pro
 			p.UnitSize = &standardized
 		}
 	}
-
-	if err := s.repo.CreateBatch(ctx, products); err != nil {
-		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to insert products batch")
-	}
-
 	return nil
 }
 
@@ -151,6 +172,9 @@ func (s *productService) Update(ctx context.Context, product *models.Product) er
 	if product.SearchVector == "" && product.NormalizedName != "" {
 		product.SearchVector = GenerateSearchVector(product.NormalizedName)
 	}
+	if product.StemmedName == "" && product.NormalizedName != "" {
+		product.StemmedName = StemProductText(product.NormalizedName)
+	}
 
 	if err := s.repo.Update(ctx, product); err != nil {
 		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to update product")
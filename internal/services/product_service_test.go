@@ -424,6 +424,7 @@ type productRepoStub struct {
 	getValidProductsFunc   func(ctx context.Context, storeIDs []int, filters *product.Filters) ([]*models.Product, error)
 	getProductsOnSaleFunc  func(ctx context.Context, storeIDs []int, filters *product.Filters) ([]*models.Product, error)
 	createBatchFunc        func(ctx context.Context, products []*models.Product) error
+	replaceByFlyerPageFunc func(ctx context.Context, flyerPageID int, products []*models.Product) error
 	updateFunc             func(ctx context.Context, product *models.Product) error
 }
 
@@ -497,6 +498,13 @@ func (s *productRepoStub) CreateBatch(ctx context.Context, products []*models.Pr
 	return nil
 }
 
+func (s *productRepoStub) ReplaceByFlyerPage(ctx context.Context, flyerPageID int, products []*models.Product) error {
+	if s.replaceByFlyerPageFunc != nil {
+		return s.replaceByFlyerPageFunc(ctx, flyerPageID, products)
+	}
+	return nil
+}
+
 func (s *productRepoStub) Update(ctx context.Context, product *models.Product) error {
 	if s.updateFunc != nil {
 		return s.updateFunc(ctx, product)
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
 )
 
 // NormalizeProductText normalizes Lithuanian text for search
@@ -16,6 +17,15 @@ func NormalizeProductText(text string) string {
 	return text
 }
 
+// productStemmer reduces product names to stems for inflection-tolerant search
+var productStemmer = normalize.NewLithuanianStemmer()
+
+// StemProductText reduces every word of a normalized product name to its
+// Lithuanian stem ("obuolių sultys" -> "obuol sult")
+func StemProductText(normalizedName string) string {
+	return productStemmer.StemText(sanitizeForTsvector(normalizedName))
+}
+
 // sanitizeForTsvector removes special characters that cause tsvector parsing errors
 // These characters are special in PostgreSQL tsvector syntax: : & | ! ( ) * '
 func sanitizeForTsvector(text string) string {
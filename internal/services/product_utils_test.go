@@ -86,6 +86,39 @@ func TestGenerateSearchVector(t *testing.T) {
 	}
 }
 
+func TestStemProductText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "inflected forms share stems",
+			input:    "obuolių sultys",
+			expected: "obuol sult",
+		},
+		{
+			name:     "numbers and units kept",
+			input:    "pienas 2.5% 1 l",
+			expected: "pien 2.5% 1 l",
+		},
+		{
+			name:     "tsvector characters removed",
+			input:    "sūris (fermentinis)",
+			expected: "sur fermentin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := StemProductText(tt.input)
+			if result != tt.expected {
+				t.Errorf("StemProductText(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestValidateProduct(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -35,7 +35,7 @@ func TestPriceComparisonIntegration(t *testing.T) {
 
 	// Test: Compare product prices
 	t.Run("CompareProductPrices", func(t *testing.T) {
-		comparison, err := svc.CompareProductPrices(ctx, masterID)
+		comparison, err := svc.CompareProductPrices(ctx, masterID, nil)
 		require.NoError(t, err)
 		assert.NotNil(t, comparison)
 		assert.Greater(t, len(comparison.StorePrices), 0)
@@ -39,6 +39,9 @@ type OptimizerOptions struct {
 	MaxDistance           *float64  `json:"max_distance"`           // Max distance in km
 	PrioritizeSavings     bool      `json:"prioritize_savings"`     // Optimize for cost
 	PrioritizeConvenience bool      `json:"prioritize_convenience"` // Optimize for fewer stores
+
+	// Loyalty programmes of the shopper; card and app prices count only in their stores
+	LoyaltyPrograms models.LoyaltyPrograms `json:"loyalty_programs"`
 }
 
 // Location represents a geographic location
@@ -126,7 +129,7 @@ func (s *shoppingOptimizerService) OptimizeShoppingList(ctx context.Context, pro
 	)
 
 	// Get price comparisons for all products
-	comparisons, err := s.priceComparison.ComparePricesForList(ctx, productMasterIDs)
+	comparisons, err := s.priceComparison.ComparePricesForList(ctx, productMasterIDs, options.LoyaltyPrograms)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get price comparisons: %w", err)
 	}
@@ -11,12 +11,14 @@ import (
 	"github.com/uptrace/bun"
 )
 
-// PriceComparisonService handles price comparison across stores
+// PriceComparisonService handles price comparison across stores.
+// Prices are those a shopper in the given loyalty programmes is eligible for;
+// pass nil to compare prices without any loyalty card or app.
 type PriceComparisonService interface {
-	CompareProductPrices(ctx context.Context, productMasterID int64) (*ProductPriceComparison, error)
-	GetBestPriceForProduct(ctx context.Context, productMasterID int64) (*StorePriceInfo, error)
-	ComparePricesForList(ctx context.Context, productMasterIDs []int64) ([]*ProductPriceComparison, error)
-	GetStoreWithBestPrices(ctx context.Context, productMasterIDs []int64) (*StoreSavingsAnalysis, error)
+	CompareProductPrices(ctx context.Context, productMasterID int64, loyalty models.LoyaltyPrograms) (*ProductPriceComparison, error)
+	GetBestPriceForProduct(ctx context.Context, productMasterID int64, loyalty models.LoyaltyPrograms) (*StorePriceInfo, error)
+	ComparePricesForList(ctx context.Context, productMasterIDs []int64, loyalty models.LoyaltyPrograms) ([]*ProductPriceComparison, error)
+	GetStoreWithBestPrices(ctx context.Context, productMasterIDs []int64, loyalty models.LoyaltyPrograms) (*StoreSavingsAnalysis, error)
 }
 
 type priceComparisonService struct {
@@ -44,19 +46,26 @@ type ProductPriceComparison struct {
 	LastUpdated      time.Time        `json:"last_updated"`
 }
 
-// StorePriceInfo contains price info for a specific store
+// StorePriceInfo contains price info for a specific store.
+// EffectivePrice is the per-item price when buying PromotionQuantity items, so
+// multi-buy deals ("1+1", "3 už 2 €") compare fairly against plain discounts.
+// Card and app prices count only when LoyaltyMember is set.
 type StorePriceInfo struct {
-	StoreID       int       `json:"store_id"`
-	StoreName     string    `json:"store_name"`
-	Price         float64   `json:"price"`
-	OriginalPrice *float64  `json:"original_price,omitempty"`
-	DiscountPct   *float64  `json:"discount_pct,omitempty"`
-	ValidFrom     time.Time `json:"valid_from"`
-	ValidTo       time.Time `json:"valid_to"`
-	FlyerID       int       `json:"flyer_id"`
-	ProductID     int64     `json:"product_id"`
-	InStock       bool      `json:"in_stock"`
-	Distance      *float64  `json:"distance,omitempty"` // Distance in km if location provided
+	StoreID           int               `json:"store_id"`
+	StoreName         string            `json:"store_name"`
+	Price             float64           `json:"price"`
+	EffectivePrice    float64           `json:"effective_price"`
+	PromotionQuantity int               `json:"promotion_quantity"`
+	LoyaltyMember     bool              `json:"loyalty_member"`
+	Promotion         *models.Promotion `json:"promotion,omitempty"`
+	OriginalPrice     *float64          `json:"original_price,omitempty"`
+	DiscountPct       *float64          `json:"discount_pct,omitempty"`
+	ValidFrom         time.Time         `json:"valid_from"`
+	ValidTo           time.Time         `json:"valid_to"`
+	FlyerID           int               `json:"flyer_id"`
+	ProductID         int64             `json:"product_id"`
+	InStock           bool              `json:"in_stock"`
+	Distance          *float64          `json:"distance,omitempty"` // Distance in km if location provided
 }
 
 // StoreSavingsAnalysis contains savings analysis for a store
@@ -82,7 +91,7 @@ type StoreComparison struct {
 }
 
 // CompareProductPrices compares prices for a product across all stores
-func (s *priceComparisonService) CompareProductPrices(ctx context.Context, productMasterID int64) (*ProductPriceComparison, error) {
+func (s *priceComparisonService) CompareProductPrices(ctx context.Context, productMasterID int64, loyalty models.LoyaltyPrograms) (*ProductPriceComparison, error) {
 	// Get all current products for this master
 	var products []struct {
 		models.Product
@@ -120,15 +129,21 @@ func (s *priceComparisonService) CompareProductPrices(ctx context.Context, produ
 	var maxPrice float64
 
 	for _, p := range products {
+		promotion := p.GetPromotion()
+		member := loyalty.CoversStore(p.Store.Code)
 		priceInfo := StorePriceInfo{
-			StoreID:   p.StoreID,
-			StoreName: p.Store.Name,
-			Price:     p.CurrentPrice,
-			ValidFrom: p.Flyer.ValidFrom,
-			ValidTo:   p.Flyer.ValidTo,
-			FlyerID:   p.FlyerID,
-			ProductID: int64(p.ID),
-			InStock:   true,
+			StoreID:           p.StoreID,
+			StoreName:         p.Store.Name,
+			Price:             p.BestPrice(member),
+			EffectivePrice:    p.EligiblePrice(member),
+			PromotionQuantity: promotion.QualifyingQuantity(),
+			LoyaltyMember:     member,
+			Promotion:         promotion,
+			ValidFrom:         p.Flyer.ValidFrom,
+			ValidTo:           p.Flyer.ValidTo,
+			FlyerID:           p.FlyerID,
+			ProductID:         int64(p.ID),
+			InStock:           true,
 		}
 
 		// Calculate discount if original price exists
@@ -139,37 +154,37 @@ func (s *priceComparisonService) CompareProductPrices(ctx context.Context, produ
 		}
 
 		comparison.StorePrices = append(comparison.StorePrices, priceInfo)
-		totalPrice += p.CurrentPrice
+		totalPrice += priceInfo.EffectivePrice
 
 		// Track min/max
-		if minPrice == nil || p.CurrentPrice < minPrice.Price {
+		if minPrice == nil || priceInfo.EffectivePrice < minPrice.EffectivePrice {
 			priceCopy := priceInfo
 			minPrice = &priceCopy
 		}
-		if p.CurrentPrice > maxPrice {
-			maxPrice = p.CurrentPrice
+		if priceInfo.EffectivePrice > maxPrice {
+			maxPrice = priceInfo.EffectivePrice
 		}
 	}
 
 	// Calculate statistics
 	if len(comparison.StorePrices) > 0 {
 		comparison.AveragePrice = totalPrice / float64(len(comparison.StorePrices))
-		comparison.PriceRange = maxPrice - minPrice.Price
+		comparison.PriceRange = maxPrice - minPrice.EffectivePrice
 		comparison.BestPrice = minPrice
-		comparison.SavingsPotential = maxPrice - minPrice.Price
+		comparison.SavingsPotential = maxPrice - minPrice.EffectivePrice
 	}
 
-	// Sort by price (cheapest first)
+	// Sort by effective price (cheapest first)
 	sort.Slice(comparison.StorePrices, func(i, j int) bool {
-		return comparison.StorePrices[i].Price < comparison.StorePrices[j].Price
+		return comparison.StorePrices[i].EffectivePrice < comparison.StorePrices[j].EffectivePrice
 	})
 
 	return comparison, nil
 }
 
 // GetBestPriceForProduct returns the best current price for a product
-func (s *priceComparisonService) GetBestPriceForProduct(ctx context.Context, productMasterID int64) (*StorePriceInfo, error) {
-	comparison, err := s.CompareProductPrices(ctx, productMasterID)
+func (s *priceComparisonService) GetBestPriceForProduct(ctx context.Context, productMasterID int64, loyalty models.LoyaltyPrograms) (*StorePriceInfo, error) {
+	comparison, err := s.CompareProductPrices(ctx, productMasterID, loyalty)
 	if err != nil {
 		return nil, err
 	}
@@ -178,11 +193,11 @@ func (s *priceComparisonService) GetBestPriceForProduct(ctx context.Context, pro
 }
 
 // ComparePricesForList compares prices for multiple products
-func (s *priceComparisonService) ComparePricesForList(ctx context.Context, productMasterIDs []int64) ([]*ProductPriceComparison, error) {
+func (s *priceComparisonService) ComparePricesForList(ctx context.Context, productMasterIDs []int64, loyalty models.LoyaltyPrograms) ([]*ProductPriceComparison, error) {
 	comparisons := make([]*ProductPriceComparison, 0, len(productMasterIDs))
 
 	for _, masterID := range productMasterIDs {
-		comparison, err := s.CompareProductPrices(ctx, masterID)
+		comparison, err := s.CompareProductPrices(ctx, masterID, loyalty)
 		if err != nil {
 			s.logger.Warn("failed to compare prices for product",
 				slog.Int64("master_id", masterID),
@@ -197,9 +212,9 @@ func (s *priceComparisonService) ComparePricesForList(ctx context.Context, produ
 }
 
 // GetStoreWithBestPrices finds the store with the best overall prices for a list
-func (s *priceComparisonService) GetStoreWithBestPrices(ctx context.Context, productMasterIDs []int64) (*StoreSavingsAnalysis, error) {
+func (s *priceComparisonService) GetStoreWithBestPrices(ctx context.Context, productMasterIDs []int64, loyalty models.LoyaltyPrograms) (*StoreSavingsAnalysis, error) {
 	// Get price comparisons for all products
-	comparisons, err := s.ComparePricesForList(ctx, productMasterIDs)
+	comparisons, err := s.ComparePricesForList(ctx, productMasterIDs, loyalty)
 	if err != nil {
 		return nil, err
 	}
@@ -220,8 +235,8 @@ func (s *priceComparisonService) GetStoreWithBestPrices(ctx context.Context, pro
 					products:  make(map[int64]float64),
 				}
 			}
-			storeTotals[priceInfo.StoreID].products[comp.ProductMasterID] = priceInfo.Price
-			storeTotals[priceInfo.StoreID].totalPrice += priceInfo.Price
+			storeTotals[priceInfo.StoreID].products[comp.ProductMasterID] = priceInfo.EffectivePrice
+			storeTotals[priceInfo.StoreID].totalPrice += priceInfo.EffectivePrice
 		}
 	}
 
@@ -3,6 +3,7 @@ package services
 import (
 	"sync"
 
+	"github.com/kainuguru/kainuguru-api/internal/enrichmentrun"
 	"github.com/kainuguru/kainuguru-api/internal/extractionjob"
 	"github.com/kainuguru/kainuguru-api/internal/flyer"
 	"github.com/kainuguru/kainuguru-api/internal/flyerpage"
@@ -42,6 +43,9 @@ type ExtractionJobRepositoryFactoryFunc func(db *bun.DB) extractionjob.Repositor
 // PriceHistoryRepositoryFactoryFunc creates a price history repository for the provided DB handle.
 type PriceHistoryRepositoryFactoryFunc func(db *bun.DB) pricehistory.Repository
 
+// EnrichmentRunRepositoryFactoryFunc creates an enrichment run repository for the provided DB handle.
+type EnrichmentRunRepositoryFactoryFunc func(db *bun.DB) enrichmentrun.Repository
+
 var (
 	storeRepoFactory            StoreRepositoryFactoryFunc
 	flyerRepoFactory            FlyerRepositoryFactoryFunc
@@ -52,6 +56,7 @@ var (
 	shoppingListItemRepoFactory ShoppingListItemRepositoryFactoryFunc
 	extractionJobRepoFactory    ExtractionJobRepositoryFactoryFunc
 	priceHistoryRepoFactory     PriceHistoryRepositoryFactoryFunc
+	enrichmentRunRepoFactory    EnrichmentRunRepositoryFactoryFunc
 	repoFactoryMu               sync.RWMutex
 )
 
@@ -118,6 +123,13 @@ func RegisterPriceHistoryRepositoryFactory(factory PriceHistoryRepositoryFactory
 	priceHistoryRepoFactory = factory
 }
 
+// RegisterEnrichmentRunRepositoryFactory wires the constructor used by NewEnrichmentRunService.
+func RegisterEnrichmentRunRepositoryFactory(factory EnrichmentRunRepositoryFactoryFunc) {
+	repoFactoryMu.Lock()
+	defer repoFactoryMu.Unlock()
+	enrichmentRunRepoFactory = factory
+}
+
 func newShoppingListRepository(db *bun.DB) shoppinglist.Repository {
 	repoFactoryMu.RLock()
 	factory := shoppingListRepoFactory
@@ -207,3 +219,13 @@ func newPriceHistoryRepository(db *bun.DB) pricehistory.Repository {
 	}
 	return factory(db)
 }
+
+func newEnrichmentRunRepository(db *bun.DB) enrichmentrun.Repository {
+	repoFactoryMu.RLock()
+	factory := enrichmentRunRepoFactory
+	repoFactoryMu.RUnlock()
+	if factory == nil {
+		panic("enrichment run repository factory not registered")
+	}
+	return factory(db)
+}
@@ -25,9 +25,7 @@ type IKIScraper struct {
 func NewIKIScraper(config ScraperConfig) *IKIScraper {
 	return &IKIScraper{
 		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
+		client: NewHTTPClient(config.Timeout),
 		store: Store{
 			ID:      1,
 			Name:    "IKI",
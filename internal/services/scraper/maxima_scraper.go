@@ -39,9 +39,7 @@ type MaximaScraper struct {
 func NewMaximaScraper(config ScraperConfig) *MaximaScraper {
 	return &MaximaScraper{
 		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
+		client: NewHTTPClient(config.Timeout),
 		store: Store{
 			ID:      2,
 			Name:    "Maxima",
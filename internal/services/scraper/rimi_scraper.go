@@ -22,9 +22,7 @@ type RimiScraper struct {
 func NewRimiScraper(config ScraperConfig) *RimiScraper {
 	return &RimiScraper{
 		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
+		client: NewHTTPClient(config.Timeout),
 		store: Store{
 			ID:      3,
 			Name:    "Rimi",
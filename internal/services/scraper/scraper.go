@@ -2,7 +2,11 @@ package scraper
 
 import (
 	"context"
+	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // Store represents a grocery store configuration
@@ -93,6 +97,15 @@ func DefaultScraperConfig() ScraperConfig {
 	}
 }
 
+// NewHTTPClient creates the client scrapers fetch store pages and flyers with. Requests
+// are traced as spans of the scrape, but no trace context is sent to the stores.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport, otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator())),
+	}
+}
+
 // ScraperError represents scraping-specific errors
 type ScraperError struct {
 	Store     string `json:"store"`
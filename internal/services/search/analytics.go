@@ -0,0 +1,328 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/lib/pq"
+	"github.com/uptrace/bun"
+)
+
+const (
+	// analyticsBufferSize bounds the events and clicks waiting to be written;
+	// when the buffer is full new ones are dropped instead of blocking searches
+	analyticsBufferSize = 4096
+
+	// analyticsBatchSize is the most events and clicks written in one transaction
+	analyticsBatchSize = 200
+
+	// analyticsFlushInterval is how long recorded events wait for a batch to fill up
+	analyticsFlushInterval = 5 * time.Second
+
+	// analyticsWriteTimeout bounds a single batch write
+	analyticsWriteTimeout = 10 * time.Second
+)
+
+// analyticsRecord is a search event or a result click waiting to be written
+type analyticsRecord struct {
+	event *SearchAnalytics
+	click *SearchClick
+}
+
+// analyticsRecorder writes search events and clicks to the database in batches
+// from a single goroutine, updating the daily rollups in the same transaction
+type analyticsRecorder struct {
+	db      *bun.DB
+	logger  *slog.Logger
+	records chan analyticsRecord
+	dropped atomic.Int64
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// newAnalyticsRecorder creates a recorder and starts its writer goroutine
+func newAnalyticsRecorder(db *bun.DB, logger *slog.Logger) *analyticsRecorder {
+	r := &analyticsRecorder{
+		db:      db,
+		logger:  logger,
+		records: make(chan analyticsRecord, analyticsBufferSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// record queues a search event or click without blocking; it reports false
+// when the buffer is full and the record was dropped
+func (r *analyticsRecorder) record(rec analyticsRecord) bool {
+	select {
+	case r.records <- rec:
+		return true
+	default:
+		r.dropped.Add(1)
+		return false
+	}
+}
+
+// close writes everything buffered so far and stops the writer goroutine;
+// records queued afterwards are never written
+func (r *analyticsRecorder) close(ctx context.Context) error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("search analytics not flushed: %w", ctx.Err())
+	}
+}
+
+func (r *analyticsRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(analyticsFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]analyticsRecord, 0, analyticsBatchSize)
+	for {
+		select {
+		case rec := <-r.records:
+			batch = append(batch, rec)
+			if len(batch) == analyticsBatchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		case <-r.stop:
+			for {
+				select {
+				case rec := <-r.records:
+					batch = append(batch, rec)
+					if len(batch) == analyticsBatchSize {
+						r.flush(batch)
+						batch = batch[:0]
+					}
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes a batch, logging instead of retrying on failure so a database
+// outage cannot make the buffer back up into searches
+func (r *analyticsRecorder) flush(batch []analyticsRecord) {
+	if dropped := r.dropped.Swap(0); dropped > 0 {
+		r.logger.Warn("search analytics buffer full, records dropped", "dropped", dropped)
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	var events []*models.SearchEvent
+	var clicks []*models.SearchClick
+	for _, rec := range batch {
+		switch {
+		case rec.event != nil:
+			events = append(events, searchEventFromAnalytics(rec.event))
+		case rec.click != nil:
+			clicks = append(clicks, searchClickModel(rec.click))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), analyticsWriteTimeout)
+	defer cancel()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := writeSearchEvents(ctx, tx, events); err != nil {
+			return err
+		}
+		return writeSearchClicks(ctx, tx, clicks)
+	})
+	if err != nil {
+		r.logger.Error("failed to write search analytics", "error", err, "events", len(events), "clicks", len(clicks))
+	}
+}
+
+// writeSearchEvents inserts events and adds them to the daily rollups
+func writeSearchEvents(ctx context.Context, tx bun.Tx, events []*models.SearchEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if _, err := tx.NewInsert().
+		Model(&events).
+		On("CONFLICT (search_id) DO NOTHING").
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to insert search events: %w", err)
+	}
+
+	stats := rollupSearchEvents(events)
+	if _, err := tx.NewInsert().
+		Model(&stats).
+		On("CONFLICT (day, normalized_query) DO UPDATE").
+		Set("search_count = sds.search_count + EXCLUDED.search_count").
+		Set("zero_result_count = sds.zero_result_count + EXCLUDED.zero_result_count").
+		Set("error_count = sds.error_count + EXCLUDED.error_count").
+		Set("total_execution_ms = sds.total_execution_ms + EXCLUDED.total_execution_ms").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update search daily stats: %w", err)
+	}
+	return nil
+}
+
+// writeSearchClicks inserts clicks and adds them to the rollup of the day and
+// query of their search. The first click on a search also marks it clicked.
+func writeSearchClicks(ctx context.Context, tx bun.Tx, clicks []*models.SearchClick) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	if _, err := tx.NewInsert().Model(&clicks).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to insert search clicks: %w", err)
+	}
+
+	searchIDs := make([]string, len(clicks))
+	for i, click := range clicks {
+		searchIDs[i] = click.SearchID
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		WITH counts AS (
+			SELECT search_id, COUNT(*) AS clicks
+			FROM unnest(?::uuid[]) AS c(search_id)
+			GROUP BY search_id
+		), first_clicks AS (
+			UPDATE search_events se
+			SET first_clicked_at = NOW()
+			FROM counts
+			WHERE se.search_id = counts.search_id AND se.first_clicked_at IS NULL
+			RETURNING se.search_id
+		)
+		INSERT INTO search_daily_stats AS sds (day, normalized_query, click_count, clicked_search_count, updated_at)
+		SELECT (se.created_at AT TIME ZONE 'UTC')::date, se.normalized_query,
+			SUM(counts.clicks), COUNT(first_clicks.search_id), NOW()
+		FROM counts
+		JOIN search_events se ON se.search_id = counts.search_id
+		LEFT JOIN first_clicks ON first_clicks.search_id = counts.search_id
+		GROUP BY 1, 2
+		ON CONFLICT (day, normalized_query) DO UPDATE SET
+			click_count = sds.click_count + EXCLUDED.click_count,
+			clicked_search_count = sds.clicked_search_count + EXCLUDED.clicked_search_count,
+			updated_at = EXCLUDED.updated_at
+	`, pq.Array(searchIDs))
+	if err != nil {
+		return fmt.Errorf("failed to update search click stats: %w", err)
+	}
+	return nil
+}
+
+// rollupSearchEvents sums events per UTC day and normalized query
+func rollupSearchEvents(events []*models.SearchEvent) []*models.SearchDailyStat {
+	type key struct {
+		day   time.Time
+		query string
+	}
+
+	now := time.Now()
+	byKey := make(map[key]*models.SearchDailyStat)
+	for _, event := range events {
+		k := key{day: searchDay(event.CreatedAt), query: event.NormalizedQuery}
+		stat, ok := byKey[k]
+		if !ok {
+			stat = &models.SearchDailyStat{Day: k.day, NormalizedQuery: k.query, UpdatedAt: now}
+			byKey[k] = stat
+		}
+
+		stat.SearchCount++
+		stat.TotalExecutionMs += int64(event.ExecutionMs)
+		if event.Failed {
+			stat.ErrorCount++
+		} else if event.ResultCount == 0 {
+			stat.ZeroResultCount++
+		}
+	}
+
+	stats := make([]*models.SearchDailyStat, 0, len(byKey))
+	for _, stat := range byKey {
+		stats = append(stats, stat)
+	}
+	// Stable order keeps concurrent upserts from deadlocking on the same rows
+	sort.Slice(stats, func(i, j int) bool {
+		if !stats[i].Day.Equal(stats[j].Day) {
+			return stats[i].Day.Before(stats[j].Day)
+		}
+		return stats[i].NormalizedQuery < stats[j].NormalizedQuery
+	})
+	return stats
+}
+
+// searchDay returns the UTC day a search is reported under
+func searchDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func searchEventFromAnalytics(a *SearchAnalytics) *models.SearchEvent {
+	timestamp := a.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return &models.SearchEvent{
+		SearchID:        a.SearchID,
+		QueryText:       a.QueryText,
+		NormalizedQuery: NormalizeSearchQuery(a.QueryText),
+		ResultCount:     a.ResultCount,
+		ExecutionMs:     int(a.ExecutionTime.Milliseconds()),
+		MethodUsed:      a.MethodUsed,
+		Failed:          a.Failed,
+		UserID:          optionalString(a.UserID),
+		IPAddress:       optionalString(a.IPAddress),
+		UserAgent:       optionalString(a.UserAgent),
+		CreatedAt:       timestamp,
+	}
+}
+
+func searchClickModel(c *SearchClick) *models.SearchClick {
+	timestamp := c.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	var position *int
+	if c.Position != nil {
+		p := *c.Position
+		position = &p
+	}
+
+	return &models.SearchClick{
+		SearchID:  c.SearchID,
+		ProductID: int64(c.ProductID),
+		Position:  position,
+		CreatedAt: timestamp,
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
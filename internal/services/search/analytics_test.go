@@ -0,0 +1,138 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+func TestRollupSearchEvents(t *testing.T) {
+	morning := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	nextDay := time.Date(2026, 3, 3, 0, 30, 0, 0, time.UTC)
+
+	events := []*models.SearchEvent{
+		{NormalizedQuery: "pienas", ResultCount: 12, ExecutionMs: 40, CreatedAt: morning},
+		{NormalizedQuery: "pienas", ResultCount: 0, ExecutionMs: 60, CreatedAt: morning.Add(time.Hour)},
+		{NormalizedQuery: "pienas", Failed: true, ExecutionMs: 20, CreatedAt: morning.Add(2 * time.Hour)},
+		{NormalizedQuery: "duona", ResultCount: 3, ExecutionMs: 30, CreatedAt: morning},
+		{NormalizedQuery: "pienas", ResultCount: 5, ExecutionMs: 10, CreatedAt: nextDay},
+	}
+
+	stats := rollupSearchEvents(events)
+	if len(stats) != 3 {
+		t.Fatalf("got %d rollups, want 3", len(stats))
+	}
+
+	// Sorted by day, then query
+	if stats[0].NormalizedQuery != "duona" || stats[1].NormalizedQuery != "pienas" || !stats[2].Day.Equal(searchDay(nextDay)) {
+		t.Fatalf("unexpected rollup order: %+v, %+v, %+v", stats[0], stats[1], stats[2])
+	}
+
+	pienas := stats[1]
+	if !pienas.Day.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day = %v, want 2026-03-02", pienas.Day)
+	}
+	if pienas.SearchCount != 3 || pienas.ZeroResultCount != 1 || pienas.ErrorCount != 1 || pienas.TotalExecutionMs != 120 {
+		t.Errorf("pienas rollup = %+v, want 3 searches, 1 zero-result, 1 error, 120ms", pienas)
+	}
+	if stats[2].SearchCount != 1 || stats[2].ZeroResultCount != 0 {
+		t.Errorf("next day rollup = %+v, want 1 search with results", stats[2])
+	}
+}
+
+func TestSearchDay_UsesUTC(t *testing.T) {
+	vilnius := time.FixedZone("EET", 2*60*60)
+	// 01:00 in Vilnius is still the previous day in UTC
+	got := searchDay(time.Date(2026, 3, 3, 1, 0, 0, 0, vilnius))
+	want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("searchDay() = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyticsRecorder_DropsWhenFull(t *testing.T) {
+	// No writer goroutine, so nothing drains the buffer
+	r := &analyticsRecorder{records: make(chan analyticsRecord, 1)}
+
+	if !r.record(analyticsRecord{event: &SearchAnalytics{QueryText: "pienas"}}) {
+		t.Fatal("first record should be queued")
+	}
+	if r.record(analyticsRecord{event: &SearchAnalytics{QueryText: "duona"}}) {
+		t.Fatal("record into a full buffer should be dropped")
+	}
+	if got := r.dropped.Load(); got != 1 {
+		t.Errorf("dropped = %d, want 1", got)
+	}
+}
+
+func TestValidateSearchClick(t *testing.T) {
+	position := 2
+	negative := -1
+
+	tests := []struct {
+		name    string
+		click   *SearchClick
+		wantErr bool
+	}{
+		{
+			name:  "valid click",
+			click: &SearchClick{SearchID: "3f1c2a7e-5b8d-4c1e-9a2f-6d7e8f901234", ProductID: 42, Position: &position},
+		},
+		{
+			name:    "nil click",
+			wantErr: true,
+		},
+		{
+			name:    "search ID is not a UUID",
+			click:   &SearchClick{SearchID: "abc", ProductID: 42},
+			wantErr: true,
+		},
+		{
+			name:    "missing product",
+			click:   &SearchClick{SearchID: "3f1c2a7e-5b8d-4c1e-9a2f-6d7e8f901234"},
+			wantErr: true,
+		},
+		{
+			name:    "negative position",
+			click:   &SearchClick{SearchID: "3f1c2a7e-5b8d-4c1e-9a2f-6d7e8f901234", ProductID: 42, Position: &negative},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSearchClick(tt.click)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSearchClick() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSearchAnalyticsFilter(t *testing.T) {
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		filter  *SearchAnalyticsFilter
+		wantErr bool
+	}{
+		{"valid filter", &SearchAnalyticsFilter{From: to.AddDate(0, 0, -29), To: to, Limit: 20}, false},
+		{"single day", &SearchAnalyticsFilter{From: to, To: to, Limit: 1}, false},
+		{"nil filter", nil, true},
+		{"from after to", &SearchAnalyticsFilter{From: to.AddDate(0, 0, 1), To: to, Limit: 20}, true},
+		{"range too long", &SearchAnalyticsFilter{From: to.AddDate(-2, 0, 0), To: to, Limit: 20}, true},
+		{"limit too large", &SearchAnalyticsFilter{From: to, To: to, Limit: 101}, true},
+		{"zero limit", &SearchAnalyticsFilter{From: to, To: to}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSearchAnalyticsFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSearchAnalyticsFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
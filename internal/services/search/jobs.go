@@ -0,0 +1,32 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+)
+
+// SuggestionsJobHandler runs the scheduled refresh_search_suggestions jobs,
+// rebuilding autocomplete suggestions from the recorded searches
+type SuggestionsJobHandler struct {
+	searchSvc Service
+}
+
+// NewSuggestionsJobHandler creates a handler for refresh_search_suggestions jobs
+func NewSuggestionsJobHandler(searchSvc Service) *SuggestionsJobHandler {
+	return &SuggestionsJobHandler{searchSvc: searchSvc}
+}
+
+// Register installs the handler on a worker processor
+func (h *SuggestionsJobHandler) Register(processor *worker.WorkerProcessor) {
+	processor.RegisterHandler(worker.JobTypeRefreshSearchSuggestions, h.Handle)
+}
+
+// Handle processes one refresh_search_suggestions job. A returned error makes the queue retry the job.
+func (h *SuggestionsJobHandler) Handle(ctx context.Context, job *worker.Job) error {
+	if err := h.searchSvc.RefreshSearchSuggestions(ctx); err != nil {
+		return fmt.Errorf("failed to refresh search suggestions: %w", err)
+	}
+	return nil
+}
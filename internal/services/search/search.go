@@ -19,9 +19,26 @@ type SearchRequest struct {
 	Limit       int      `json:"limit" validate:"min=1,max=100"`
 	Offset      int      `json:"offset" validate:"min=0"`
 	PreferFuzzy bool     `json:"prefer_fuzzy"`
+	SortBy      string   `json:"sort_by,omitempty" validate:"omitempty,oneof=relevance price unit_price"`
+
+	// Stores whose loyalty programme the shopper belongs to; card and app prices
+	// only count towards the price order there
+	LoyaltyStoreIDs []int `json:"loyalty_store_ids,omitempty"`
+
+	// Shopper running the search, recorded with its search analytics
+	UserID string `json:"user_id,omitempty"`
 }
 
+// Sort orders of SearchRequest.SortBy; an empty value sorts by relevance
+const (
+	SortByRelevance = "relevance"
+	SortByPrice     = "price"      // Effective per-item price the shopper is eligible for, cheapest first
+	SortByUnitPrice = "unit_price" // Effective price per kg, l or vnt., cheapest first within the most common unit; unknown sizes last
+)
+
 type SearchResponse struct {
+	// SearchID identifies the search in analytics; clicks on its results are reported with it
+	SearchID    string                `json:"search_id"`
 	Products    []ProductSearchResult `json:"products"`
 	TotalCount  int                   `json:"total_count"`
 	QueryTime   time.Duration         `json:"query_time"`
@@ -83,15 +100,64 @@ type QueryCorrection struct {
 }
 
 type SearchAnalytics struct {
+	SearchID      string        `json:"search_id"`
 	QueryText     string        `json:"query_text"`
 	ResultCount   int           `json:"result_count"`
 	ExecutionTime time.Duration `json:"execution_time"`
 	MethodUsed    string        `json:"method_used"`
+	Failed        bool          `json:"failed"`
+	UserID        string        `json:"user_id,omitempty"`
 	UserAgent     string        `json:"user_agent,omitempty"`
 	IPAddress     string        `json:"ip_address,omitempty"`
 	Timestamp     time.Time     `json:"timestamp"`
 }
 
+// SearchClick is a product opened from the results of a search
+type SearchClick struct {
+	SearchID  string    `json:"search_id" validate:"required,uuid"`
+	ProductID int       `json:"product_id" validate:"required,gt=0"`
+	Position  *int      `json:"position,omitempty" validate:"omitempty,gte=0"` // 0-based position in the result list
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecentSearch is a query a shopper searched recently
+type RecentSearch struct {
+	Query       string    `json:"query"`
+	ResultCount int       `json:"result_count"`
+	SearchedAt  time.Time `json:"searched_at"`
+}
+
+// MaxSearchAnalyticsRange is the longest period a search analytics report covers
+const MaxSearchAnalyticsRange = 366 * 24 * time.Hour
+
+// SearchAnalyticsFilter selects the days of the daily rollups to report on (UTC, inclusive)
+type SearchAnalyticsFilter struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Limit int       `json:"limit" validate:"min=1,max=100"`
+}
+
+// SearchQueryStats aggregates the searches of one normalized query
+type SearchQueryStats struct {
+	Query              string        `json:"query"`
+	SearchCount        int64         `json:"search_count"`
+	ZeroResultCount    int64         `json:"zero_result_count"`
+	ClickCount         int64         `json:"click_count"`
+	ClickedSearchCount int64         `json:"clicked_search_count"`
+	ClickThroughRate   float64       `json:"click_through_rate"`
+	AverageQueryTime   time.Duration `json:"average_query_time"`
+	LastSearchedOn     time.Time     `json:"last_searched_on"`
+}
+
+// SearchClickThroughStats aggregates the searches and result clicks of one day
+type SearchClickThroughStats struct {
+	Day                time.Time `json:"day"`
+	SearchCount        int64     `json:"search_count"`
+	ClickCount         int64     `json:"click_count"`
+	ClickedSearchCount int64     `json:"clicked_search_count"`
+	ClickThroughRate   float64   `json:"click_through_rate"`
+}
+
 type Service interface {
 	// Core search functionality
 	SearchProducts(ctx context.Context, req *SearchRequest) (*SearchResponse, error)
@@ -111,12 +177,31 @@ type Service interface {
 
 	// Search analytics and monitoring
 	LogSearchAnalytics(ctx context.Context, analytics *SearchAnalytics) error
+	RecordSearchClick(ctx context.Context, click *SearchClick) error
+	GetTopSearchQueries(ctx context.Context, filter *SearchAnalyticsFilter) ([]*SearchQueryStats, error)
+	GetZeroResultQueries(ctx context.Context, filter *SearchAnalyticsFilter) ([]*SearchQueryStats, error)
+	GetSearchClickThrough(ctx context.Context, filter *SearchAnalyticsFilter) ([]*SearchClickThroughStats, error)
+
+	// Per-user search history
+	GetRecentSearches(ctx context.Context, userID string, limit int) ([]*RecentSearch, error)
+	ClearRecentSearches(ctx context.Context, userID string) error
 
 	// Search health and performance
 	GetSearchHealth(ctx context.Context) (*SearchHealthStatus, error)
 	RefreshSearchSuggestions(ctx context.Context) error
+
+	// Synonym and query-rewrite dictionary administration
+	ListSynonyms(ctx context.Context, synonymType string) ([]*models.SearchSynonym, error)
+	CreateSynonym(ctx context.Context, entry *models.SearchSynonym) (*models.SearchSynonym, error)
+	UpdateSynonym(ctx context.Context, id int64, entry *models.SearchSynonym) (*models.SearchSynonym, error)
+	DeleteSynonym(ctx context.Context, id int64) error
+
+	// Close writes buffered search analytics and stops the background writer
+	Close(ctx context.Context) error
 }
 
+// SearchHealthStatus reports index usage and, from the daily rollups of the
+// last SearchHealthWindow, search volume, latency and errors
 type SearchHealthStatus struct {
 	IndexStatus      map[string]bool `json:"index_status"`
 	LastRefreshTime  time.Time       `json:"last_refresh_time"`
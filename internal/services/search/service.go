@@ -3,24 +3,59 @@ package search
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/cache"
 	"github.com/kainuguru/kainuguru-api/internal/models"
 	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
 	"github.com/lib/pq"
 	"github.com/uptrace/bun"
 )
 
 type searchService struct {
-	db     *bun.DB
-	logger *slog.Logger
+	db           *bun.DB
+	logger       *slog.Logger
+	synonymCache *cache.SynonymCache
+	analytics    *analyticsRecorder
+
+	// synonyms is this instance's copy of the synonym dictionary, reloaded from
+	// Redis or the database after synonymRefreshInterval
+	synonymsMu       sync.RWMutex
+	synonyms         *SynonymDictionary
+	synonymsLoadedAt time.Time
 }
 
+// recentSearchesMaxAge is how far back GetRecentSearches looks
+const recentSearchesMaxAge = 90 * 24 * time.Hour
+
+// SearchHealthWindow is the period, in whole UTC days up to today, GetSearchHealth
+// reports search volume, latency and errors for
+const SearchHealthWindow = 7 * 24 * time.Hour
+
+// synonymRefreshInterval is how long an instance reuses its synonym dictionary
+// before checking Redis again, bounding how stale other instances get after an edit
+const synonymRefreshInterval = time.Minute
+
 func NewSearchService(db *bun.DB, logger *slog.Logger) Service {
+	return NewSearchServiceWithCache(db, logger, nil)
+}
+
+// NewSearchServiceWithCache creates a search service sharing its synonym
+// dictionary with other instances through Redis
+func NewSearchServiceWithCache(db *bun.DB, logger *slog.Logger, synonymCache *cache.SynonymCache) Service {
 	return &searchService{
-		db:     db,
-		logger: logger,
+		db:           db,
+		logger:       logger,
+		synonymCache: synonymCache,
+		analytics:    newAnalyticsRecorder(db, logger),
 	}
 }
 
@@ -32,11 +67,43 @@ func (s *searchService) SearchProducts(ctx context.Context, req *SearchRequest)
 	// Sanitize the query
 	req.Query = SanitizeQuery(req.Query)
 
+	var response *SearchResponse
+	var err error
 	if req.PreferFuzzy {
-		return s.FuzzySearchProducts(ctx, req)
+		response, err = s.FuzzySearchProducts(ctx, req)
+	} else {
+		response, err = s.HybridSearchProducts(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if response.TotalCount < didYouMeanMaxResults {
+		response.Suggestions = s.didYouMean(ctx, req.Query)
+	}
+	return response, nil
+}
+
+// didYouMeanMaxResults is the result count below which searches suggest corrected queries
+const didYouMeanMaxResults = 3
+
+// didYouMean returns up to three corrections of a query that found little or nothing.
+// Failures only cost the suggestions, never the search.
+func (s *searchService) didYouMean(ctx context.Context, query string) []string {
+	corrections, err := s.SuggestQueryCorrections(ctx, &CorrectionRequest{Query: query, Limit: 3})
+	if err != nil {
+		s.logger.Warn("failed to suggest query corrections", "error", err, "query", query)
+		return nil
 	}
 
-	return s.HybridSearchProducts(ctx, req)
+	normalized := NormalizeSearchQuery(query)
+	var suggestions []string
+	for _, correction := range corrections.Corrections {
+		if NormalizeSearchQuery(correction.Suggestion) != normalized {
+			suggestions = append(suggestions, correction.Suggestion)
+		}
+	}
+	return suggestions
 }
 
 func (s *searchService) FuzzySearchProducts(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
@@ -46,21 +113,31 @@ func (s *searchService) FuzzySearchProducts(ctx context.Context, req *SearchRequ
 
 	req.Query = SanitizeQuery(req.Query)
 	startTime := time.Now()
+	searchID := uuid.NewString()
 
-	query := `
+	rewrite := s.rewriteQuery(ctx, req.Query)
+	merged := len(rewrite.Alternates) > 0
+	queryArg, stemmedArg := rewrite.searchArgs()
+
+	source := "fuzzy_search_products($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) s"
+	if merged {
+		source = variantSearchSource("fuzzy_search_products", "$2, $3, $4, $5, $6, $7, $8, $9, $10, $11",
+			fuzzySearchColumns, "combined_similarity")
+	}
+	query := orderedSearchQuery(`
 		SELECT
-			product_id, name, brand, category, current_price,
-			store_id, flyer_id, name_similarity, brand_similarity, combined_similarity
-		FROM fuzzy_search_products($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
+			s.product_id, s.name, s.brand, s.category, s.current_price,
+			s.store_id, s.flyer_id, s.name_similarity, s.brand_similarity, s.combined_similarity
+		FROM `+source, "s.combined_similarity", req.SortBy, merged)
+	limit, offset, outer := searchPagination(req, merged)
 
-	s.logger.Info("executing fuzzy search", "query", req.Query, "limit", req.Limit, "offset", req.Offset, "tags", req.Tags, "flyer_ids", req.FlyerIDs)
+	s.logger.Info("executing fuzzy search", "query", rewrite.Query, "alternates", rewrite.Alternates, "limit", req.Limit, "offset", req.Offset, "sort_by", req.SortBy, "tags", req.Tags, "flyer_ids", req.FlyerIDs)
 
-	rows, err := s.db.DB.QueryContext(ctx, query,
-		req.Query,
+	args := []interface{}{
+		queryArg,
 		0.15, // similarity_threshold - lowered to 0.15 to catch typos (combined_similarity uses weighted formula)
-		req.Limit,
-		req.Offset,
+		limit,
+		offset,
 		pq.Array(req.StoreIDs),
 		req.Category,
 		req.MinPrice,
@@ -68,9 +145,20 @@ func (s *searchService) FuzzySearchProducts(ctx context.Context, req *SearchRequ
 		req.OnSaleOnly,
 		pq.Array(req.Tags),
 		pq.Array(req.FlyerIDs),
-	)
+		stemmedArg,
+	}
+	rows, err := s.db.DB.QueryContext(ctx, query, append(args, outer...)...)
 	if err != nil {
 		s.logger.Error("fuzzy search failed", "error", err, "query", req.Query)
+		s.LogSearchAnalytics(ctx, &SearchAnalytics{
+			SearchID:      searchID,
+			QueryText:     req.Query,
+			ExecutionTime: time.Since(startTime),
+			MethodUsed:    "fuzzy",
+			Failed:        true,
+			UserID:        req.UserID,
+			Timestamp:     startTime,
+		})
 		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "fuzzy search failed")
 	}
 	defer rows.Close()
@@ -130,9 +218,9 @@ func (s *searchService) FuzzySearchProducts(ctx context.Context, req *SearchRequ
 		s.logger.Warn("no product IDs to load")
 	}
 
-	// Build results with loaded products
+	// Build results with loaded products, in the order the search returned them
 	var results []ProductSearchResult
-	for _, product := range products {
+	for _, product := range inSearchOrder(products, productIDs) {
 		if score, ok := scoresMap[product.ID]; ok {
 			s.logger.Info("adding product to results", "product_id", product.ID, "name", product.Name, "score", score)
 			results = append(results, ProductSearchResult{
@@ -154,11 +242,9 @@ func (s *searchService) FuzzySearchProducts(ctx context.Context, req *SearchRequ
 
 	// Get total count for pagination
 	var totalCount int
-	countQuery := `
-		SELECT COUNT(*) FROM fuzzy_search_products($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
-	err = s.db.DB.QueryRowContext(ctx, countQuery, req.Query, 0.15, 1000000, 0,
-		pq.Array(req.StoreIDs), req.Category, req.MinPrice, req.MaxPrice, req.OnSaleOnly, pq.Array(req.Tags), pq.Array(req.FlyerIDs)).Scan(&totalCount)
+	countQuery := `SELECT COUNT(*) FROM ` + source
+	err = s.db.DB.QueryRowContext(ctx, countQuery, queryArg, 0.15, 1000000, 0,
+		pq.Array(req.StoreIDs), req.Category, req.MinPrice, req.MaxPrice, req.OnSaleOnly, pq.Array(req.Tags), pq.Array(req.FlyerIDs), stemmedArg).Scan(&totalCount)
 	if err != nil {
 		s.logger.Warn("failed to get total count", "error", err)
 		totalCount = len(results)
@@ -167,15 +253,18 @@ func (s *searchService) FuzzySearchProducts(ctx context.Context, req *SearchRequ
 	queryTime := time.Since(startTime)
 
 	// Log analytics
-	go s.LogSearchAnalytics(context.Background(), &SearchAnalytics{
+	s.LogSearchAnalytics(ctx, &SearchAnalytics{
+		SearchID:      searchID,
 		QueryText:     req.Query,
-		ResultCount:   len(results),
+		ResultCount:   totalCount,
 		ExecutionTime: queryTime,
 		MethodUsed:    "fuzzy",
+		UserID:        req.UserID,
 		Timestamp:     startTime,
 	})
 
 	return &SearchResponse{
+		SearchID:   searchID,
 		Products:   results,
 		TotalCount: totalCount,
 		QueryTime:  queryTime,
@@ -190,18 +279,28 @@ func (s *searchService) HybridSearchProducts(ctx context.Context, req *SearchReq
 
 	req.Query = SanitizeQuery(req.Query)
 	startTime := time.Now()
+	searchID := uuid.NewString()
 
-	query := `
-		SELECT
-			product_id, name, brand, current_price,
-			store_id, flyer_id, search_score, match_type
-		FROM hybrid_search_products($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
+	rewrite := s.rewriteQuery(ctx, req.Query)
+	merged := len(rewrite.Alternates) > 0
+	queryArg, stemmedArg := rewrite.searchArgs()
 
-	rows, err := s.db.DB.QueryContext(ctx, query,
-		req.Query,
-		req.Limit,
-		req.Offset,
+	source := "hybrid_search_products($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) s"
+	if merged {
+		source = variantSearchSource("hybrid_search_products", "$2, $3, $4, $5, $6, $7, $8, $9, $10, $11",
+			hybridSearchColumns, "search_score")
+	}
+	query := orderedSearchQuery(`
+		SELECT
+			s.product_id, s.name, s.brand, s.current_price,
+			s.store_id, s.flyer_id, s.search_score, s.match_type
+		FROM `+source, "s.search_score", req.SortBy, merged)
+	limit, offset, outer := searchPagination(req, merged)
+
+	args := []interface{}{
+		queryArg,
+		limit,
+		offset,
 		pq.Array(req.StoreIDs),
 		req.MinPrice,
 		req.MaxPrice,
@@ -210,9 +309,20 @@ func (s *searchService) HybridSearchProducts(ctx context.Context, req *SearchReq
 		req.OnSaleOnly,
 		pq.Array(req.Tags),
 		pq.Array(req.FlyerIDs),
-	)
+		stemmedArg,
+	}
+	rows, err := s.db.DB.QueryContext(ctx, query, append(args, outer...)...)
 	if err != nil {
 		s.logger.Error("hybrid search failed", "error", err, "query", req.Query)
+		s.LogSearchAnalytics(ctx, &SearchAnalytics{
+			SearchID:      searchID,
+			QueryText:     req.Query,
+			ExecutionTime: time.Since(startTime),
+			MethodUsed:    "hybrid",
+			Failed:        true,
+			UserID:        req.UserID,
+			Timestamp:     startTime,
+		})
 		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "hybrid search failed")
 	}
 	defer rows.Close()
@@ -261,9 +371,9 @@ func (s *searchService) HybridSearchProducts(ctx context.Context, req *SearchReq
 		}
 	}
 
-	// Build results with loaded products
+	// Build results with loaded products, in the order the search returned them
 	var results []ProductSearchResult
-	for _, product := range products {
+	for _, product := range inSearchOrder(products, productIDs) {
 		if score, ok := scoresMap[product.ID]; ok {
 			matchType := matchTypeMap[product.ID]
 			results = append(results, ProductSearchResult{
@@ -280,11 +390,9 @@ func (s *searchService) HybridSearchProducts(ctx context.Context, req *SearchReq
 
 	// Get total count
 	var totalCount int
-	countQuery := `
-		SELECT COUNT(*) FROM hybrid_search_products($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
-	err = s.db.QueryRowContext(ctx, countQuery, req.Query, 1000000, 0,
-		pq.Array(req.StoreIDs), req.MinPrice, req.MaxPrice, req.PreferFuzzy, req.Category, req.OnSaleOnly, pq.Array(req.Tags), pq.Array(req.FlyerIDs)).Scan(&totalCount)
+	countQuery := `SELECT COUNT(*) FROM ` + source
+	err = s.db.QueryRowContext(ctx, countQuery, queryArg, 1000000, 0,
+		pq.Array(req.StoreIDs), req.MinPrice, req.MaxPrice, req.PreferFuzzy, req.Category, req.OnSaleOnly, pq.Array(req.Tags), pq.Array(req.FlyerIDs), stemmedArg).Scan(&totalCount)
 	if err != nil {
 		s.logger.Warn("failed to get total count", "error", err)
 		totalCount = len(results)
@@ -293,15 +401,18 @@ func (s *searchService) HybridSearchProducts(ctx context.Context, req *SearchReq
 	queryTime := time.Since(startTime)
 
 	// Log analytics
-	go s.LogSearchAnalytics(context.Background(), &SearchAnalytics{
+	s.LogSearchAnalytics(ctx, &SearchAnalytics{
+		SearchID:      searchID,
 		QueryText:     req.Query,
-		ResultCount:   len(results),
+		ResultCount:   totalCount,
 		ExecutionTime: queryTime,
 		MethodUsed:    "hybrid",
+		UserID:        req.UserID,
 		Timestamp:     startTime,
 	})
 
 	return &SearchResponse{
+		SearchID:   searchID,
 		Products:   results,
 		TotalCount: totalCount,
 		QueryTime:  queryTime,
@@ -367,7 +478,8 @@ func (s *searchService) FindSimilarProducts(ctx context.Context, req *SimilarPro
 	for rows.Next() {
 		var (
 			productID, storeID            int
-			name, brand                   string
+			name                          string
+			brand                         sql.NullString
 			currentPrice, similarityScore float64
 		)
 
@@ -396,9 +508,9 @@ func (s *searchService) FindSimilarProducts(ctx context.Context, req *SimilarPro
 		}
 	}
 
-	// Build results with loaded products
+	// Build results with loaded products, most similar first
 	var products []SimilarProduct
-	for _, product := range loadedProducts {
+	for _, product := range inSearchOrder(loadedProducts, productIDs) {
 		if similarity, ok := similarityMap[product.ID]; ok {
 			products = append(products, SimilarProduct{
 				Product:         product,
@@ -447,13 +559,26 @@ func (s *searchService) SuggestQueryCorrections(ctx context.Context, req *Correc
 	}, nil
 }
 
+// LogSearchAnalytics queues a search event for the batched analytics writer.
+// It never blocks; events are dropped with a warning when the writer falls behind.
 func (s *searchService) LogSearchAnalytics(ctx context.Context, analytics *SearchAnalytics) error {
-	s.logger.Info("search analytics",
-		"query", analytics.QueryText,
-		"result_count", analytics.ResultCount,
-		"execution_time_ms", analytics.ExecutionTime.Milliseconds(),
-		"method", analytics.MethodUsed,
-	)
+	if analytics.SearchID == "" {
+		analytics.SearchID = uuid.NewString()
+	}
+	s.analytics.record(analyticsRecord{event: analytics})
+	return nil
+}
+
+// RecordSearchClick queues a click on a search result for the batched analytics writer
+func (s *searchService) RecordSearchClick(ctx context.Context, click *SearchClick) error {
+	if err := ValidateSearchClick(click); err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid search click")
+	}
+
+	if click.Timestamp.IsZero() {
+		click.Timestamp = time.Now()
+	}
+	s.analytics.record(analyticsRecord{click: click})
 	return nil
 }
 
@@ -491,9 +616,26 @@ func (s *searchService) GetSearchHealth(ctx context.Context) (*SearchHealthStatu
 		s.logger.Warn("failed to get suggestion count", "error", err)
 	}
 
-	health.AverageQueryTime = 50 * time.Millisecond // Placeholder
-	health.TotalSearches = 0                        // Placeholder
-	health.ErrorRate = 0.01                         // Placeholder
+	// Search volume, latency and errors from the daily rollups
+	var totals struct {
+		Searches    int64 `bun:"searches"`
+		Errors      int64 `bun:"errors"`
+		ExecutionMs int64 `bun:"execution_ms"`
+	}
+	err = s.db.NewSelect().
+		Model((*models.SearchDailyStat)(nil)).
+		ColumnExpr("COALESCE(SUM(sds.search_count), 0) AS searches").
+		ColumnExpr("COALESCE(SUM(sds.error_count), 0) AS errors").
+		ColumnExpr("COALESCE(SUM(sds.total_execution_ms), 0) AS execution_ms").
+		Where("sds.day > ?", searchDay(time.Now().Add(-SearchHealthWindow))).
+		Scan(ctx, &totals)
+	if err != nil {
+		s.logger.Warn("failed to get search stats", "error", err)
+	} else if totals.Searches > 0 {
+		health.TotalSearches = totals.Searches
+		health.AverageQueryTime = time.Duration(totals.ExecutionMs/totals.Searches) * time.Millisecond
+		health.ErrorRate = float64(totals.Errors) / float64(totals.Searches)
+	}
 
 	return health, nil
 }
@@ -509,3 +651,512 @@ func (s *searchService) RefreshSearchSuggestions(ctx context.Context) error {
 	s.logger.Info("search suggestions refreshed successfully")
 	return nil
 }
+
+// GetTopSearchQueries returns the most searched normalized queries in the filter's days
+func (s *searchService) GetTopSearchQueries(ctx context.Context, filter *SearchAnalyticsFilter) ([]*SearchQueryStats, error) {
+	if err := ValidateSearchAnalyticsFilter(filter); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid search analytics filter")
+	}
+
+	stats, err := s.searchQueryStats(ctx, filter, false)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get top search queries")
+	}
+	return stats, nil
+}
+
+// GetZeroResultQueries returns the normalized queries that most often found
+// nothing in the filter's days
+func (s *searchService) GetZeroResultQueries(ctx context.Context, filter *SearchAnalyticsFilter) ([]*SearchQueryStats, error) {
+	if err := ValidateSearchAnalyticsFilter(filter); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid search analytics filter")
+	}
+
+	stats, err := s.searchQueryStats(ctx, filter, true)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get zero-result search queries")
+	}
+	return stats, nil
+}
+
+// GetSearchClickThrough returns searches and result clicks per day, oldest first.
+// The filter's limit does not apply; there is one entry per day with searches.
+func (s *searchService) GetSearchClickThrough(ctx context.Context, filter *SearchAnalyticsFilter) ([]*SearchClickThroughStats, error) {
+	if err := ValidateSearchAnalyticsFilter(filter); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid search analytics filter")
+	}
+
+	var stats []*SearchClickThroughStats
+	err := s.db.NewSelect().
+		Model((*models.SearchDailyStat)(nil)).
+		ColumnExpr("sds.day AS day").
+		ColumnExpr("SUM(sds.search_count) AS search_count").
+		ColumnExpr("SUM(sds.click_count) AS click_count").
+		ColumnExpr("SUM(sds.clicked_search_count) AS clicked_search_count").
+		Where("sds.day BETWEEN ? AND ?", searchDay(filter.From), searchDay(filter.To)).
+		Group("sds.day").
+		Order("sds.day ASC").
+		Scan(ctx, &stats)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get search click-through")
+	}
+
+	for _, day := range stats {
+		day.ClickThroughRate = clickThroughRate(day.ClickedSearchCount, day.SearchCount)
+	}
+	return stats, nil
+}
+
+// searchQueryStats sums the daily rollups per normalized query, ordered by
+// search count or, for zeroResults, by zero-result count
+func (s *searchService) searchQueryStats(ctx context.Context, filter *SearchAnalyticsFilter, zeroResults bool) ([]*SearchQueryStats, error) {
+	var rows []struct {
+		Query              string    `bun:"query"`
+		SearchCount        int64     `bun:"search_count"`
+		ZeroResultCount    int64     `bun:"zero_result_count"`
+		ClickCount         int64     `bun:"click_count"`
+		ClickedSearchCount int64     `bun:"clicked_search_count"`
+		ExecutionMs        int64     `bun:"execution_ms"`
+		LastSearchedOn     time.Time `bun:"last_searched_on"`
+	}
+
+	query := s.db.NewSelect().
+		Model((*models.SearchDailyStat)(nil)).
+		ColumnExpr("sds.normalized_query AS query").
+		ColumnExpr("SUM(sds.search_count) AS search_count").
+		ColumnExpr("SUM(sds.zero_result_count) AS zero_result_count").
+		ColumnExpr("SUM(sds.click_count) AS click_count").
+		ColumnExpr("SUM(sds.clicked_search_count) AS clicked_search_count").
+		ColumnExpr("SUM(sds.total_execution_ms) AS execution_ms").
+		ColumnExpr("MAX(sds.day) AS last_searched_on").
+		Where("sds.day BETWEEN ? AND ?", searchDay(filter.From), searchDay(filter.To)).
+		Group("sds.normalized_query").
+		Limit(filter.Limit)
+	if zeroResults {
+		query = query.
+			Having("SUM(sds.zero_result_count) > 0").
+			Order("zero_result_count DESC", "search_count DESC", "query ASC")
+	} else {
+		query = query.
+			Having("SUM(sds.search_count) > 0").
+			Order("search_count DESC", "query ASC")
+	}
+
+	if err := query.Scan(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	stats := make([]*SearchQueryStats, len(rows))
+	for i, row := range rows {
+		stats[i] = &SearchQueryStats{
+			Query:              row.Query,
+			SearchCount:        row.SearchCount,
+			ZeroResultCount:    row.ZeroResultCount,
+			ClickCount:         row.ClickCount,
+			ClickedSearchCount: row.ClickedSearchCount,
+			ClickThroughRate:   clickThroughRate(row.ClickedSearchCount, row.SearchCount),
+			LastSearchedOn:     row.LastSearchedOn,
+		}
+		if row.SearchCount > 0 {
+			stats[i].AverageQueryTime = time.Duration(row.ExecutionMs/row.SearchCount) * time.Millisecond
+		}
+	}
+	return stats, nil
+}
+
+// clickThroughRate is the share of searches with at least one result click
+func clickThroughRate(clickedSearches, searches int64) float64 {
+	if searches == 0 {
+		return 0
+	}
+	return float64(clickedSearches) / float64(searches)
+}
+
+// GetRecentSearches returns the user's latest distinct queries that did not fail,
+// newest first. Searches show up once the analytics writer has flushed them.
+func (s *searchService) GetRecentSearches(ctx context.Context, userID string, limit int) ([]*RecentSearch, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, apperrors.Validation("user ID must be a UUID")
+	}
+	if limit < 1 || limit > 50 {
+		return nil, apperrors.Validation("limit must be between 1 and 50")
+	}
+
+	var searches []*RecentSearch
+	err := s.db.NewSelect().
+		TableExpr("(?) AS latest", s.db.NewSelect().
+			Model((*models.SearchEvent)(nil)).
+			DistinctOn("se.normalized_query").
+			ColumnExpr("se.query_text AS query").
+			ColumnExpr("se.result_count").
+			ColumnExpr("se.created_at AS searched_at").
+			Where("se.user_id = ?", userID).
+			Where("se.failed = FALSE").
+			Where("se.created_at >= ?", time.Now().Add(-recentSearchesMaxAge)).
+			OrderExpr("se.normalized_query, se.created_at DESC")).
+		ColumnExpr("latest.*").
+		OrderExpr("latest.searched_at DESC").
+		Limit(limit).
+		Scan(ctx, &searches)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get recent searches")
+	}
+	return searches, nil
+}
+
+// ClearRecentSearches detaches the user's searches from their account. The
+// searches stay in the analytics anonymously.
+func (s *searchService) ClearRecentSearches(ctx context.Context, userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return apperrors.Validation("user ID must be a UUID")
+	}
+
+	_, err := s.db.NewUpdate().
+		Model((*models.SearchEvent)(nil)).
+		Set("user_id = NULL").
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to clear recent searches")
+	}
+
+	s.logger.Info("recent searches cleared", "user_id", userID)
+	return nil
+}
+
+// Close writes buffered search analytics and stops the background writer
+func (s *searchService) Close(ctx context.Context) error {
+	return s.analytics.close(ctx)
+}
+
+// ListSynonyms returns the search dictionary entries, optionally of one type
+func (s *searchService) ListSynonyms(ctx context.Context, synonymType string) ([]*models.SearchSynonym, error) {
+	var entries []*models.SearchSynonym
+	query := s.db.NewSelect().
+		Model(&entries).
+		Order("ss.type ASC", "ss.term ASC")
+	if synonymType != "" {
+		query = query.Where("ss.type = ?", synonymType)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list search synonyms")
+	}
+	return entries, nil
+}
+
+// CreateSynonym adds a search dictionary entry and invalidates the cached dictionary
+func (s *searchService) CreateSynonym(ctx context.Context, entry *models.SearchSynonym) (*models.SearchSynonym, error) {
+	NormalizeSearchSynonym(entry)
+	if err := ValidateSearchSynonym(entry); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid search synonym")
+	}
+	if err := s.checkSynonymUnique(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	if _, err := s.db.NewInsert().Model(entry).Returning("*").Exec(ctx); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to create search synonym")
+	}
+
+	s.invalidateSynonyms(ctx)
+	s.logger.Info("search synonym created", "id", entry.ID, "type", entry.Type, "term", entry.Term)
+	return entry, nil
+}
+
+// UpdateSynonym replaces the type, term and replacements of a search dictionary entry
+func (s *searchService) UpdateSynonym(ctx context.Context, id int64, entry *models.SearchSynonym) (*models.SearchSynonym, error) {
+	existing := new(models.SearchSynonym)
+	err := s.db.NewSelect().Model(existing).Where("ss.id = ?", id).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("search synonym not found")
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get search synonym")
+	}
+
+	NormalizeSearchSynonym(entry)
+	if err := ValidateSearchSynonym(entry); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid search synonym")
+	}
+	entry.ID = id
+	if err := s.checkSynonymUnique(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	existing.Type = entry.Type
+	existing.Term = entry.Term
+	existing.Replacements = entry.Replacements
+	existing.UpdatedAt = time.Now()
+	_, err = s.db.NewUpdate().
+		Model(existing).
+		Column("type", "term", "replacements", "updated_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to update search synonym")
+	}
+
+	s.invalidateSynonyms(ctx)
+	s.logger.Info("search synonym updated", "id", existing.ID, "type", existing.Type, "term", existing.Term)
+	return existing, nil
+}
+
+// DeleteSynonym removes a search dictionary entry and invalidates the cached dictionary
+func (s *searchService) DeleteSynonym(ctx context.Context, id int64) error {
+	result, err := s.db.NewDelete().
+		Model((*models.SearchSynonym)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to delete search synonym")
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apperrors.NotFound("search synonym not found")
+	}
+
+	s.invalidateSynonyms(ctx)
+	s.logger.Info("search synonym deleted", "id", id)
+	return nil
+}
+
+// checkSynonymUnique rejects a second entry with the same type and term
+func (s *searchService) checkSynonymUnique(ctx context.Context, entry *models.SearchSynonym) error {
+	exists, err := s.db.NewSelect().
+		Model((*models.SearchSynonym)(nil)).
+		Where("ss.type = ?", entry.Type).
+		Where("ss.term = ?", entry.Term).
+		Where("ss.id != ?", entry.ID).
+		Exists(ctx)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to check search synonym")
+	}
+	if exists {
+		return apperrors.Conflict("a " + entry.Type + " entry for this term already exists")
+	}
+	return nil
+}
+
+// rewriteQuery applies the synonym dictionary to a sanitized query
+func (s *searchService) rewriteQuery(ctx context.Context, query string) QueryRewrite {
+	rewrite := s.synonymDictionary(ctx).Rewrite(query)
+	if rewrite.Query != query || len(rewrite.Alternates) > 0 {
+		s.logger.Info("search query rewritten", "query", query, "rewritten", rewrite.Query, "alternates", rewrite.Alternates)
+	}
+	return rewrite
+}
+
+// synonymDictionary returns this instance's synonym dictionary, reloading it once
+// it is older than synonymRefreshInterval. Load failures keep the previous
+// dictionary so search never fails because of the dictionary.
+func (s *searchService) synonymDictionary(ctx context.Context) *SynonymDictionary {
+	s.synonymsMu.RLock()
+	dictionary, loadedAt := s.synonyms, s.synonymsLoadedAt
+	s.synonymsMu.RUnlock()
+	if dictionary != nil && time.Since(loadedAt) < synonymRefreshInterval {
+		return dictionary
+	}
+
+	entries, err := s.loadSynonyms(ctx)
+	if err != nil {
+		s.logger.Warn("failed to load search synonyms", "error", err)
+		if dictionary == nil {
+			dictionary = NewSynonymDictionary(nil)
+		}
+	} else {
+		dictionary = NewSynonymDictionary(entries)
+	}
+
+	s.synonymsMu.Lock()
+	s.synonyms = dictionary
+	s.synonymsLoadedAt = time.Now()
+	s.synonymsMu.Unlock()
+	return dictionary
+}
+
+// loadSynonyms reads the dictionary from Redis, falling back to the database and
+// caching what it read there
+func (s *searchService) loadSynonyms(ctx context.Context) ([]*models.SearchSynonym, error) {
+	if s.synonymCache != nil {
+		entries, err := s.synonymCache.GetSynonyms(ctx)
+		if err != nil {
+			s.logger.Warn("failed to read cached search synonyms", "error", err)
+		} else if entries != nil {
+			return entries, nil
+		}
+	}
+
+	entries, err := s.ListSynonyms(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if s.synonymCache != nil {
+		if err := s.synonymCache.SaveSynonyms(ctx, entries); err != nil {
+			s.logger.Warn("failed to cache search synonyms", "error", err)
+		}
+	}
+	return entries, nil
+}
+
+// invalidateSynonyms drops the local and the Redis copy of the dictionary after an edit
+func (s *searchService) invalidateSynonyms(ctx context.Context) {
+	s.synonymsMu.Lock()
+	s.synonyms = nil
+	s.synonymsMu.Unlock()
+
+	if s.synonymCache != nil {
+		if err := s.synonymCache.Invalidate(ctx); err != nil {
+			s.logger.Warn("failed to invalidate cached search synonyms", "error", err)
+		}
+	}
+}
+
+// queryStemmer reduces queries to the stems stored in products.stemmed_name
+var queryStemmer = normalize.NewLithuanianStemmer()
+
+// stemmedQuery returns the query's Lithuanian stems, or nil so the search
+// functions fall back to the unstemmed query
+func stemmedQuery(query string) interface{} {
+	stemmed := queryStemmer.StemText(query)
+	if stemmed == "" {
+		return nil
+	}
+	return stemmed
+}
+
+// searchArgs returns the $1 query and $12 stems arguments of the search functions:
+// plain values for a single query, or text arrays of every variant for
+// variantSearchSource
+func (r QueryRewrite) searchArgs() (interface{}, interface{}) {
+	if len(r.Alternates) == 0 {
+		return r.Query, stemmedQuery(r.Query)
+	}
+
+	variants := r.Variants()
+	stems := make([]string, len(variants))
+	for i, variant := range variants {
+		stems[i] = queryStemmer.StemText(variant)
+	}
+	return pq.Array(variants), pq.Array(stems)
+}
+
+// searchAllRows is passed as the search function's limit when results are re-sorted outside it
+const searchAllRows = 1000000
+
+// orderedSearchQuery wraps a search function query in the requested sort order. Relevance
+// order comes from the function itself, unless the results of several query variants are
+// merged; price and unit price order re-sort every match by effective price (per kg, l or
+// vnt. for unit price, grouped by unit base with unknown sizes last). Re-sorted queries paginate with the extra $13/$14(/$15)
+// parameters from searchPagination.
+func orderedSearchQuery(query, scoreColumn, sortBy string, merged bool) string {
+	var order string
+	switch sortBy {
+	case SortByPrice:
+		order = `
+			CASE WHEN s.store_id = ANY($15)
+				THEN COALESCE(p.effective_price, s.current_price)
+				ELSE COALESCE(p.effective_regular_price, p.effective_price, s.current_price)
+			END ASC`
+	case SortByUnitPrice:
+		// Prices per kg, l and vnt. do not compare, so matches are grouped by unit
+		// base, the base most of them share first
+		unitPrice := `
+			CASE WHEN s.store_id = ANY($15)
+				THEN COALESCE(p.effective_price, s.current_price) / NULLIF(p.unit_quantity, 0)
+				ELSE p.normalized_unit_price
+			END`
+		order = `
+			(` + unitPrice + `) IS NULL,
+			COUNT(*) OVER (PARTITION BY p.unit_base) DESC, p.unit_base,` + unitPrice + ` ASC`
+	default:
+		if !merged {
+			return query
+		}
+		return query + `
+		ORDER BY ` + scoreColumn + ` DESC, s.product_id ASC
+		LIMIT $13 OFFSET $14
+	`
+	}
+	return query + `
+		JOIN products p ON p.id = s.product_id
+		ORDER BY` + order + `,
+			` + scoreColumn + ` DESC, s.product_id ASC
+		LIMIT $13 OFFSET $14
+	`
+}
+
+// searchPagination returns the limit and offset for the search function and the extra
+// arguments of the outer query built by orderedSearchQuery
+func searchPagination(req *SearchRequest, merged bool) (int, int, []interface{}) {
+	switch {
+	case req.SortBy == SortByPrice || req.SortBy == SortByUnitPrice:
+		return searchAllRows, 0, []interface{}{req.Limit, req.Offset, pq.Array(req.LoyaltyStoreIDs)}
+	case merged:
+		// Every variant must return the whole first pages for the merged page to be right
+		return req.Offset + req.Limit, 0, []interface{}{req.Limit, req.Offset}
+	default:
+		return req.Limit, req.Offset, nil
+	}
+}
+
+// Columns returned by the search functions, as selected from variantSearchSource
+var (
+	fuzzySearchColumns = []string{
+		"product_id", "name", "brand", "category", "current_price",
+		"store_id", "flyer_id", "name_similarity", "brand_similarity", "combined_similarity",
+	}
+	hybridSearchColumns = []string{
+		"product_id", "name", "brand", "current_price",
+		"store_id", "flyer_id", "search_score", "match_type",
+	}
+)
+
+// variantSearchSource returns a FROM item running a search function once per query
+// variant, passed as text arrays in $1 (queries) and $12 (their stems) with the first
+// variant being the query itself. Each product keeps its best score; scores from
+// synonym variants are scaled by SynonymWeight and their match type becomes "synonym",
+// so exact matches rank first.
+func variantSearchSource(function, args string, columns []string, scoreColumn string) string {
+	selected := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case scoreColumn:
+			selected[i] = "f." + column + " * v.weight AS " + column
+		case "match_type":
+			selected[i] = "CASE WHEN v.weight < 1 THEN 'synonym' ELSE f.match_type END AS match_type"
+		default:
+			selected[i] = "f." + column
+		}
+	}
+
+	return fmt.Sprintf(`(
+			SELECT DISTINCT ON (f.product_id) %s
+			FROM (
+				SELECT q.query, NULLIF(q.stemmed, '') AS stemmed,
+					CASE WHEN q.ord = 1 THEN 1.0 ELSE %s END::float AS weight
+				FROM unnest($1::text[], $12::text[]) WITH ORDINALITY AS q(query, stemmed, ord)
+			) v
+			CROSS JOIN LATERAL %s(v.query, %s, v.stemmed) f
+			ORDER BY f.product_id, f.%s * v.weight DESC
+		) s`, strings.Join(selected, ", "), strconv.FormatFloat(SynonymWeight, 'f', -1, 64), function, args, scoreColumn)
+}
+
+// inSearchOrder orders loaded products like the search result IDs
+func inSearchOrder(products []*models.Product, productIDs []int) []*models.Product {
+	byID := make(map[int]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	ordered := make([]*models.Product, 0, len(products))
+	for _, id := range productIDs {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered
+}
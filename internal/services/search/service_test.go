@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/migrator"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// postgresTestDB connects to the migrated database at POSTGRES_TEST_DSN; the search
+// functions only exist on Postgres
+func postgresTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	if err := migrator.New(db).Up(context.Background(), migrator.RunOptions{}); err != nil {
+		t.Fatalf("failed to migrate %s: %v", dsn, err)
+	}
+	return db
+}
+
+func TestSearchProducts_UnitPriceGroupsUnitBases(t *testing.T) {
+	db := postgresTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	store := &models.Store{Code: fmt.Sprintf("unit-sort-%d", now.UnixNano()), Name: "Unit sort"}
+	if _, err := db.NewInsert().Model(store).Exec(ctx); err != nil {
+		t.Fatalf("failed to insert store: %v", err)
+	}
+	flyer := &models.Flyer{StoreID: store.ID, ValidFrom: now.AddDate(0, 0, -1), ValidTo: now.AddDate(0, 0, 1)}
+	if _, err := db.NewInsert().Model(flyer).Exec(ctx); err != nil {
+		t.Fatalf("failed to insert flyer: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.NewDelete().Model((*models.Product)(nil)).Where("flyer_id = ?", flyer.ID).Exec(ctx)
+		_, _ = db.NewDelete().Model(flyer).WherePK().Exec(ctx)
+		_, _ = db.NewDelete().Model(store).WherePK().Exec(ctx)
+	})
+	if _, err := db.ExecContext(ctx, "SELECT ensure_partition_for_date(?)", flyer.ValidFrom); err != nil {
+		t.Fatalf("failed to ensure partition: %v", err)
+	}
+
+	product := func(name string, base string, unitPrice float64) *models.Product {
+		p := &models.Product{
+			FlyerID:        flyer.ID,
+			StoreID:        store.ID,
+			Name:           name,
+			NormalizedName: name,
+			CurrentPrice:   unitPrice,
+			IsAvailable:    true,
+			ValidFrom:      flyer.ValidFrom,
+			ValidTo:        flyer.ValidTo,
+		}
+		if base != "" {
+			quantity := 1.0
+			p.UnitQuantity, p.UnitBase, p.NormalizedUnitPrice = &quantity, &base, &unitPrice
+		}
+		return p
+	}
+	// The kilogram is the cheapest unit price, but most matches are sold by the litre
+	products := []*models.Product{
+		product("kefyras", "l", 1.50),
+		product("kefyras", "kg", 0.50),
+		product("kefyras", "", 0.20),
+		product("kefyras", "l", 0.90),
+	}
+	if _, err := db.NewInsert().Model(&products).Exec(ctx); err != nil {
+		t.Fatalf("failed to insert products: %v", err)
+	}
+
+	svc := NewSearchService(db, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	for _, fuzzy := range []bool{false, true} {
+		resp, err := svc.SearchProducts(ctx, &SearchRequest{
+			Query:       "kefyras",
+			StoreIDs:    []int{store.ID},
+			Limit:       10,
+			PreferFuzzy: fuzzy,
+			SortBy:      SortByUnitPrice,
+		})
+		if err != nil {
+			t.Fatalf("SearchProducts(fuzzy=%v) error = %v", fuzzy, err)
+		}
+
+		var got []int
+		for _, result := range resp.Products {
+			got = append(got, result.Product.ID)
+		}
+		want := []int{products[3].ID, products[0].ID, products[1].ID, products[2].ID}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("SearchProducts(fuzzy=%v) order = %v, want %v: litres, then kilograms, then unknown sizes", fuzzy, got, want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+const (
+	// SynonymWeight scales the score of products found only through a synonym or
+	// expansion, so they rank slightly below exact matches of the query
+	SynonymWeight = 0.9
+
+	// maxQueryAlternates caps the synonym variants searched besides the query itself
+	maxQueryAlternates = 4
+)
+
+// QueryRewrite is a search query after applying the synonym dictionary
+type QueryRewrite struct {
+	// Query is the search query with stop-phrases removed and brand aliases
+	// replaced by the canonical brand
+	Query string
+	// Alternates are variants of Query with a term replaced by a synonym or expansion
+	Alternates []string
+}
+
+// Variants returns Query followed by its alternates
+func (r QueryRewrite) Variants() []string {
+	return append([]string{r.Query}, r.Alternates...)
+}
+
+// synonymRule replaces a phrase of normalized words
+type synonymRule struct {
+	phrase       []string
+	replacements []string
+}
+
+// SynonymDictionary rewrites search queries with curated synonyms, one-way
+// expansions, brand aliases and stop-phrases. Phrases match whole words of the
+// normalized query (lowercase, without diacritics); words that are not rewritten
+// keep their original spelling.
+type SynonymDictionary struct {
+	stopPhrases  []synonymRule
+	brandAliases []synonymRule
+	expansions   []synonymRule
+}
+
+// NewSynonymDictionary builds a dictionary from search synonym entries
+func NewSynonymDictionary(entries []*models.SearchSynonym) *SynonymDictionary {
+	d := &SynonymDictionary{}
+	expansions := make(map[string][]string)
+
+	addExpansion := func(term string, replacements ...string) {
+		for _, replacement := range replacements {
+			if replacement != term && !slices.Contains(expansions[term], replacement) {
+				expansions[term] = append(expansions[term], replacement)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		term := NormalizeSearchQuery(entry.Term)
+		if term == "" {
+			continue
+		}
+		replacements := normalizeReplacements(entry.Replacements)
+
+		switch models.SearchSynonymType(entry.Type) {
+		case models.SearchSynonymTypeStopPhrase:
+			d.stopPhrases = append(d.stopPhrases, synonymRule{phrase: strings.Fields(term)})
+		case models.SearchSynonymTypeBrandAlias:
+			if len(replacements) > 0 {
+				d.brandAliases = append(d.brandAliases, synonymRule{
+					phrase:       strings.Fields(term),
+					replacements: replacements[:1],
+				})
+			}
+		case models.SearchSynonymTypeExpansion:
+			addExpansion(term, replacements...)
+		case models.SearchSynonymTypeSynonym:
+			// Every member of the group expands to all the others
+			group := append([]string{term}, replacements...)
+			for _, member := range group {
+				addExpansion(member, group...)
+			}
+		}
+	}
+
+	terms := make([]string, 0, len(expansions))
+	for term := range expansions {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	for _, term := range terms {
+		d.expansions = append(d.expansions, synonymRule{
+			phrase:       strings.Fields(term),
+			replacements: expansions[term],
+		})
+	}
+
+	// Longer phrases first so "coca cola zero" wins over "coca cola"
+	for _, rules := range [][]synonymRule{d.stopPhrases, d.brandAliases, d.expansions} {
+		sort.SliceStable(rules, func(i, j int) bool {
+			return len(rules[i].phrase) > len(rules[j].phrase)
+		})
+	}
+
+	return d
+}
+
+// Len returns the number of dictionary rules
+func (d *SynonymDictionary) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.stopPhrases) + len(d.brandAliases) + len(d.expansions)
+}
+
+// Rewrite applies the dictionary to a sanitized query. Stop-phrases are removed
+// unless nothing else is left, brand aliases are replaced by the canonical brand,
+// and every synonym or expansion found in the result yields an alternate query.
+func (d *SynonymDictionary) Rewrite(query string) QueryRewrite {
+	if d.Len() == 0 {
+		return QueryRewrite{Query: query}
+	}
+
+	words := strings.Fields(query)
+	folded := make([]string, len(words))
+	for i, word := range words {
+		folded[i] = foldWord(word)
+	}
+
+	// 1. Stop-phrases
+	keptWords, keptFolded := words, folded
+	for _, rule := range d.stopPhrases {
+		keptWords, keptFolded = replacePhrase(keptWords, keptFolded, rule.phrase, nil)
+	}
+	if len(keptWords) > 0 {
+		words, folded = keptWords, keptFolded
+	}
+
+	// 2. Brand aliases
+	for _, rule := range d.brandAliases {
+		words, folded = replacePhrase(words, folded, rule.phrase, strings.Fields(rule.replacements[0]))
+	}
+
+	rewrite := QueryRewrite{Query: strings.Join(words, " ")}
+	seen := map[string]bool{strings.Join(folded, " "): true}
+
+	// 3. Synonyms and expansions
+	for _, rule := range d.expansions {
+		start := indexPhrase(folded, rule.phrase)
+		if start < 0 {
+			continue
+		}
+		end := start + len(rule.phrase)
+		for _, replacement := range rule.replacements {
+			if len(rewrite.Alternates) == maxQueryAlternates {
+				return rewrite
+			}
+			alternate := make([]string, 0, len(words))
+			alternate = append(alternate, folded[:start]...)
+			alternate = append(alternate, replacement)
+			alternate = append(alternate, folded[end:]...)
+			key := strings.Join(alternate, " ")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			rewrite.Alternates = append(rewrite.Alternates, key)
+		}
+	}
+
+	return rewrite
+}
+
+// replacePhrase replaces every occurrence of phrase in the folded words, keeping
+// words and folded in step; replacement words are already normalized
+func replacePhrase(words, folded, phrase, replacement []string) ([]string, []string) {
+	start := indexPhrase(folded, phrase)
+	if start < 0 {
+		return words, folded
+	}
+
+	var newWords, newFolded []string
+	for start >= 0 {
+		end := start + len(phrase)
+		newWords = append(append(newWords, words[:start]...), replacement...)
+		newFolded = append(append(newFolded, folded[:start]...), replacement...)
+		words, folded = words[end:], folded[end:]
+		start = indexPhrase(folded, phrase)
+	}
+	return append(newWords, words...), append(newFolded, folded...)
+}
+
+// indexPhrase returns the index of the first occurrence of phrase in words, or -1
+func indexPhrase(words, phrase []string) int {
+	if len(phrase) == 0 {
+		return -1
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// foldWord normalizes a query word for dictionary lookups, ignoring surrounding punctuation
+func foldWord(word string) string {
+	return strings.Trim(NormalizeSearchQuery(word), `.,;:!?"'()`)
+}
+
+// normalizeReplacements normalizes replacements, dropping empty and duplicate ones
+func normalizeReplacements(replacements []string) []string {
+	normalized := make([]string, 0, len(replacements))
+	for _, replacement := range replacements {
+		replacement = NormalizeSearchQuery(replacement)
+		if replacement != "" && !slices.Contains(normalized, replacement) {
+			normalized = append(normalized, replacement)
+		}
+	}
+	return normalized
+}
+
+// NormalizeSearchSynonym normalizes the term and replacements of a dictionary
+// entry the way queries are matched against them
+func NormalizeSearchSynonym(entry *models.SearchSynonym) {
+	entry.Type = strings.ToLower(strings.TrimSpace(entry.Type))
+	entry.Term = NormalizeSearchQuery(entry.Term)
+	entry.Replacements = normalizeReplacements(entry.Replacements)
+	if entry.Type == string(models.SearchSynonymTypeStopPhrase) {
+		entry.Replacements = []string{}
+	}
+}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+)
+
+func testSynonymDictionary() *SynonymDictionary {
+	return NewSynonymDictionary([]*models.SearchSynonym{
+		{Type: "synonym", Term: "bulvės", Replacements: []string{"kartofliai"}},
+		{Type: "expansion", Term: "gaivieji gėrimai", Replacements: []string{"limonadas", "sultys"}},
+		{Type: "brand_alias", Term: "coca cola", Replacements: []string{"coca-cola"}},
+		{Type: "brand_alias", Term: "rokiskio", Replacements: []string{"rokiškio sūris"}},
+		{Type: "stop_phrase", Term: "akcija"},
+		{Type: "stop_phrase", Term: "pigiausia kaina"},
+	})
+}
+
+func TestSynonymDictionary_Rewrite(t *testing.T) {
+	dictionary := testSynonymDictionary()
+
+	tests := []struct {
+		name           string
+		query          string
+		wantQuery      string
+		wantAlternates []string
+	}{
+		{
+			name:      "no rule matches",
+			query:     "Pienas 2,5%",
+			wantQuery: "Pienas 2,5%",
+		},
+		{
+			name:           "synonym both ways",
+			query:          "Bulvės",
+			wantQuery:      "Bulvės",
+			wantAlternates: []string{"kartofliai"},
+		},
+		{
+			name:           "synonym reverse direction keeps other words",
+			query:          "jauni kartofliai",
+			wantQuery:      "jauni kartofliai",
+			wantAlternates: []string{"jauni bulves"},
+		},
+		{
+			name:           "one-way expansion",
+			query:          "gaivieji gėrimai",
+			wantQuery:      "gaivieji gėrimai",
+			wantAlternates: []string{"limonadas", "sultys"},
+		},
+		{
+			name:      "expansion is not reversed",
+			query:     "limonadas",
+			wantQuery: "limonadas",
+		},
+		{
+			name:      "brand alias replaced",
+			query:     "Coca Cola zero",
+			wantQuery: "coca-cola zero",
+		},
+		{
+			name:           "stop-phrases removed",
+			query:          "akcija bulvės pigiausia kaina",
+			wantQuery:      "bulvės",
+			wantAlternates: []string{"kartofliai"},
+		},
+		{
+			name:      "stop-phrase kept when nothing else is left",
+			query:     "Akcija",
+			wantQuery: "Akcija",
+		},
+		{
+			name:      "punctuation around words ignored",
+			query:     "akcija, rokiskio",
+			wantQuery: "rokiskio suris",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dictionary.Rewrite(tt.query)
+			if got.Query != tt.wantQuery {
+				t.Errorf("Rewrite().Query = %q, want %q", got.Query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(got.Alternates, tt.wantAlternates) {
+				t.Errorf("Rewrite().Alternates = %q, want %q", got.Alternates, tt.wantAlternates)
+			}
+		})
+	}
+}
+
+func TestSynonymDictionary_RewriteCapsAlternates(t *testing.T) {
+	dictionary := NewSynonymDictionary([]*models.SearchSynonym{
+		{Type: "expansion", Term: "vaisiai", Replacements: []string{"obuoliai", "kriaušės", "bananai", "slyvos", "vynuogės"}},
+	})
+
+	got := dictionary.Rewrite("vaisiai")
+	if len(got.Alternates) != maxQueryAlternates {
+		t.Errorf("len(Alternates) = %d, want %d", len(got.Alternates), maxQueryAlternates)
+	}
+}
+
+func TestSynonymDictionary_RewriteEmpty(t *testing.T) {
+	var dictionary *SynonymDictionary
+	got := dictionary.Rewrite("pienas")
+	if got.Query != "pienas" || len(got.Alternates) != 0 {
+		t.Errorf("Rewrite() = %+v, want the query unchanged", got)
+	}
+}
+
+func TestValidateSearchSynonym(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   *models.SearchSynonym
+		wantErr bool
+	}{
+		{
+			name:  "valid synonym",
+			entry: &models.SearchSynonym{Type: "synonym", Term: "Bulvės", Replacements: []string{"Kartofliai"}},
+		},
+		{
+			name:  "stop-phrase without replacements",
+			entry: &models.SearchSynonym{Type: "stop_phrase", Term: "akcija", Replacements: []string{"ignored"}},
+		},
+		{
+			name:    "unknown type",
+			entry:   &models.SearchSynonym{Type: "antonym", Term: "pienas", Replacements: []string{"vanduo"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty term",
+			entry:   &models.SearchSynonym{Type: "synonym", Term: "  ", Replacements: []string{"pienas"}},
+			wantErr: true,
+		},
+		{
+			name:    "expansion without replacements",
+			entry:   &models.SearchSynonym{Type: "expansion", Term: "vaisiai"},
+			wantErr: true,
+		},
+		{
+			name:    "brand alias with two brands",
+			entry:   &models.SearchSynonym{Type: "brand_alias", Term: "dvaro", Replacements: []string{"Dvaro", "Dvaro pienas"}},
+			wantErr: true,
+		},
+		{
+			name:    "replacement equal to term after normalization",
+			entry:   &models.SearchSynonym{Type: "synonym", Term: "Sūris", Replacements: []string{"suris"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			NormalizeSearchSynonym(tt.entry)
+			err := ValidateSearchSynonym(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSearchSynonym() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchPagination_MergedVariants(t *testing.T) {
+	req := &SearchRequest{Query: "bulves", Limit: 20, Offset: 40}
+
+	limit, offset, outer := searchPagination(req, true)
+	if limit != 60 || offset != 0 {
+		t.Errorf("searchPagination() = %d, %d, want 60, 0", limit, offset)
+	}
+	if !reflect.DeepEqual(outer, []interface{}{20, 40}) {
+		t.Errorf("searchPagination() outer = %v, want [20 40]", outer)
+	}
+
+	limit, offset, outer = searchPagination(req, false)
+	if limit != 20 || offset != 40 || outer != nil {
+		t.Errorf("searchPagination() = %d, %d, %v, want 20, 40, nil", limit, offset, outer)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/models"
 	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
 )
 
@@ -43,6 +45,11 @@ func ValidateSearchRequest(req *SearchRequest) error {
 		return apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid category")
 	}
 
+	// Validate sort order
+	if err := validateSortBy(req.SortBy); err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeValidation, "invalid sort order")
+	}
+
 	return nil
 }
 
@@ -97,6 +104,91 @@ func ValidateCorrectionRequest(req *CorrectionRequest) error {
 	return nil
 }
 
+// ValidateSearchSynonym validates a normalized search dictionary entry
+func ValidateSearchSynonym(entry *models.SearchSynonym) error {
+	if entry == nil {
+		return apperrors.Validation("search synonym cannot be nil")
+	}
+
+	synonymType := models.SearchSynonymType(entry.Type)
+	if !synonymType.IsValid() {
+		return apperrors.Validation("type must be synonym, expansion, brand_alias or stop_phrase")
+	}
+
+	if entry.Term == "" {
+		return apperrors.Validation("term cannot be empty")
+	}
+	if utf8.RuneCountInString(entry.Term) > 255 {
+		return apperrors.Validation("term cannot exceed 255 characters")
+	}
+
+	switch synonymType {
+	case models.SearchSynonymTypeStopPhrase:
+		return nil
+	case models.SearchSynonymTypeBrandAlias:
+		if len(entry.Replacements) != 1 {
+			return apperrors.Validation("brand alias must have exactly one canonical brand")
+		}
+	default:
+		if len(entry.Replacements) == 0 {
+			return apperrors.Validation("at least one replacement is required")
+		}
+	}
+
+	for _, replacement := range entry.Replacements {
+		if replacement == entry.Term {
+			return apperrors.Validation("replacement cannot equal the term")
+		}
+		if utf8.RuneCountInString(replacement) > 255 {
+			return apperrors.Validation("replacement cannot exceed 255 characters")
+		}
+	}
+
+	return nil
+}
+
+// ValidateSearchClick validates a search result click
+func ValidateSearchClick(click *SearchClick) error {
+	if click == nil {
+		return apperrors.Validation("search click cannot be nil")
+	}
+
+	if _, err := uuid.Parse(click.SearchID); err != nil {
+		return apperrors.Validation("search ID must be a UUID")
+	}
+
+	if click.ProductID <= 0 {
+		return apperrors.Validation("product ID must be greater than 0")
+	}
+
+	if click.Position != nil && *click.Position < 0 {
+		return apperrors.Validation("position cannot be negative")
+	}
+
+	return nil
+}
+
+// ValidateSearchAnalyticsFilter validates a search analytics report filter
+func ValidateSearchAnalyticsFilter(filter *SearchAnalyticsFilter) error {
+	if filter == nil {
+		return apperrors.Validation("search analytics filter cannot be nil")
+	}
+
+	if filter.From.After(filter.To) {
+		return apperrors.Validation("from cannot be after to")
+	}
+
+	if filter.To.Sub(filter.From) > MaxSearchAnalyticsRange {
+		return apperrors.Validation("date range cannot exceed 366 days")
+	}
+
+	if filter.Limit < 1 || filter.Limit > 100 {
+		return apperrors.Validation("limit must be between 1 and 100")
+	}
+
+	return nil
+}
+
 func validateQuery(query string) error {
 	query = strings.TrimSpace(query)
 
@@ -275,3 +367,12 @@ func NormalizeSearchQuery(query string) string {
 
 	return query
 }
+
+func validateSortBy(sortBy string) error {
+	switch sortBy {
+	case "", SortByRelevance, SortByPrice, SortByUnitPrice:
+		return nil
+	default:
+		return apperrors.Validation("sort order must be relevance, price or unit_price")
+	}
+}
@@ -24,6 +24,33 @@ func TestValidateSearchRequest(t *testing.T) {
 			req:     nil,
 			wantErr: true,
 		},
+		{
+			name: "price sort",
+			req: &SearchRequest{
+				Query:  "milk",
+				Limit:  10,
+				SortBy: SortByPrice,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unit price sort",
+			req: &SearchRequest{
+				Query:  "milk",
+				Limit:  10,
+				SortBy: SortByUnitPrice,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown sort",
+			req: &SearchRequest{
+				Query:  "milk",
+				Limit:  10,
+				SortBy: "cheapest",
+			},
+			wantErr: true,
+		},
 		{
 			name: "empty query",
 			req: &SearchRequest{
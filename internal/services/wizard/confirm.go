@@ -349,8 +349,6 @@ func (s *wizardService) applyReplaceDecision(
 		Brand:              flyerProduct.Brand,
 		Price:              flyerProduct.CurrentPrice,
 		Unit:               flyerProduct.UnitType,
-		SizeValue:          nil, // Product model uses UnitSize string, not separate value/unit
-		SizeUnit:           nil,
 		ValidFrom:          &flyerProduct.ValidFrom,
 		ValidTo:            &flyerProduct.ValidTo,
 		Estimated:          false, // Constitution-based suggestion (per spec)
@@ -358,6 +356,11 @@ func (s *wizardService) applyReplaceDecision(
 		CreatedAt:          time.Now(),
 	}
 
+	if size, unit, ok := ProductSize(&flyerProduct); ok {
+		snapshot.SizeValue = &size
+		snapshot.SizeUnit = &unit
+	}
+
 	_, err = tx.NewInsert().
 		Model(snapshot).
 		Exec(ctx)
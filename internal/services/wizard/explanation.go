@@ -34,10 +34,15 @@ func GenerateExplanation(
 
 	// 2. Size comparison
 	if suggestion.SizeValue != nil && suggestion.SizeUnit != nil {
-		if originalProduct.UnitType != nil && *originalProduct.UnitType == *suggestion.SizeUnit {
-			parts = append(parts, "similar size")
-		} else {
-			parts = append(parts, "different size")
+		if _, _, ok := ProductSize(originalProduct); ok {
+			switch sizeScore := calculateSizeScore(originalProduct, suggestion.SizeValue, suggestion.SizeUnit); {
+			case sizeScore >= 0.99:
+				parts = append(parts, "exact size match")
+			case sizeScore >= 0.75:
+				parts = append(parts, "similar size")
+			default:
+				parts = append(parts, "different size")
+			}
 		}
 	}
 
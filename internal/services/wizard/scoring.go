@@ -1,9 +1,12 @@
 package wizard
 
 import (
+	"fmt"
 	"math"
+	"strconv"
 
 	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/pkg/normalize"
 )
 
 // ScoreSuggestion calculates a deterministic score for a suggestion
@@ -46,7 +49,7 @@ func ScoreSuggestion(
 	// Lower price = higher score, normalized by original price
 	priceScore := calculatePriceScore(
 		originalProduct,
-		suggestion.Price,
+		suggestionPrice(suggestion),
 	)
 	score += weights.Price * priceScore
 
@@ -57,34 +60,107 @@ func ScoreSuggestion(
 }
 
 // calculateSizeScore computes size similarity between original and suggested product
-// Returns 1.0 for exact match, 0.5 for same unit but different size, 0.0 for different unit
+// Sizes are compared in their base unit (g, ml or vnt.): 1.0 for the same size, the
+// ratio of the smaller to the larger size otherwise ("1 l" vs "1,5 l" = 0.67),
+// 0.0 for different units (kg vs l) and 0.5 when either size is unknown
 func calculateSizeScore(original *models.Product, suggestedSize *float64, suggestedUnit *string) float64 {
 	if original == nil {
 		return 0.0
 	}
 
-	// Extract original size info from UnitSize or Weight/Volume fields
-	// For now, use simplified logic - TODO: improve parsing in Phase 3
-	if suggestedUnit == nil || suggestedSize == nil {
+	if suggestedUnit == nil || suggestedSize == nil || *suggestedSize <= 0 {
 		return 0.5 // Unknown sizes = neutral score
 	}
 
-	// Check if units match (simplified check)
-	originalUnitType := original.UnitType
-	if originalUnitType == nil {
-		return 0.5 // Unknown original unit = neutral
+	originalSize, originalUnit, ok := ProductSize(original)
+	if !ok {
+		return 0.5 // Unknown original size = neutral
 	}
 
-	if *originalUnitType != *suggestedUnit {
+	if originalUnit != *suggestedUnit {
 		return 0.0 // Different units (kg vs L) = no match
 	}
 
-	// Same unit - for now return high score
-	// TODO: Add proper size value parsing and comparison in Phase 3
-	return 0.8
+	return math.Min(originalSize, *suggestedSize) / math.Max(originalSize, *suggestedSize)
+}
+
+// sizeExtractor parses package sizes; it only reads its patterns, so it is
+// shared across sessions
+var sizeExtractor = normalize.NewUnitExtractor()
+
+// ParseSize parses a package size such as "500 g" or "6 x 0,5 l" into its total
+// value in the base unit (g, ml or vnt.)
+func ParseSize(text string) (float64, string, bool) {
+	unit := sizeExtractor.GetPackSize(text)
+	if unit == nil || unit.BaseValue <= 0 || unit.BaseUnit == "" {
+		return 0, "", false
+	}
+	return unit.BaseValue, unit.BaseUnit, true
+}
+
+// ProductSize parses a product's package size from UnitSize, falling back to its
+// name where flyers often print the size ("Pienas 2,5 %, 1 l")
+func ProductSize(product *models.Product) (float64, string, bool) {
+	if product.UnitSize != nil {
+		if value, unit, ok := ParseSize(*product.UnitSize); ok {
+			return value, unit, true
+		}
+	}
+	return ParseSize(product.Name)
+}
+
+// DescribeSizeComparison returns a human-readable comparison of the suggested
+// size with the original's, e.g. "3 l, 100% larger than 1.5 l", or "" when the
+// suggested size is unknown
+func DescribeSizeComparison(original *models.Product, suggestedSize *float64, suggestedUnit *string) string {
+	if suggestedSize == nil || suggestedUnit == nil || *suggestedSize <= 0 {
+		return ""
+	}
+	suggested := formatSize(*suggestedSize, *suggestedUnit)
+
+	if original == nil {
+		return suggested
+	}
+	originalSize, originalUnit, ok := ProductSize(original)
+	if !ok {
+		return suggested
+	}
+	if originalUnit != *suggestedUnit {
+		return fmt.Sprintf("%s, not comparable with %s", suggested, formatSize(originalSize, originalUnit))
+	}
+
+	diff := (*suggestedSize - originalSize) / originalSize * 100
+	switch {
+	case math.Abs(diff) < 1:
+		return fmt.Sprintf("Same size (%s)", suggested)
+	case diff > 0:
+		return fmt.Sprintf("%s, %.0f%% larger than %s", suggested, diff, formatSize(originalSize, originalUnit))
+	default:
+		return fmt.Sprintf("%s, %.0f%% smaller than %s", suggested, -diff, formatSize(originalSize, originalUnit))
+	}
+}
+
+// formatSize formats a base-unit size, switching to kg and l from 1000 g and 1000 ml
+func formatSize(value float64, baseUnit string) string {
+	switch {
+	case baseUnit == "g" && value >= 1000:
+		value, baseUnit = value/1000, "kg"
+	case baseUnit == "ml" && value >= 1000:
+		value, baseUnit = value/1000, "l"
+	}
+	return strconv.FormatFloat(math.Round(value*1000)/1000, 'f', -1, 64) + " " + baseUnit
+}
+
+// suggestionPrice returns the per-item price of a suggestion with its promotion applied
+func suggestionPrice(suggestion *models.Suggestion) float64 {
+	if suggestion.EffectivePrice > 0 {
+		return suggestion.EffectivePrice
+	}
+	return suggestion.Price
 }
 
 // calculatePriceScore computes price attractiveness
+// Prices are compared per item with promotions applied ("1+1" halves the price)
 // Lower price than original = higher score (up to 1.0)
 // Same price = 0.5
 // Higher price = lower score (down to 0.0)
@@ -93,7 +169,7 @@ func calculatePriceScore(original *models.Product, suggestedPrice float64) float
 		return 0.5 // Default neutral score
 	}
 
-	originalPrice := original.CurrentPrice
+	originalPrice := original.DealPrice()
 
 	if originalPrice == 0 || suggestedPrice == 0 {
 		return 0.5 // Unknown prices = neutral
@@ -133,7 +209,7 @@ func RankSuggestions(
 
 		// Calculate price difference for tie-breaking
 		if originalProduct != nil && originalProduct.CurrentPrice > 0 {
-			suggestion.PriceDifference = suggestion.Price - originalProduct.CurrentPrice
+			suggestion.PriceDifference = suggestionPrice(suggestion) - originalProduct.DealPrice()
 		}
 	}
 
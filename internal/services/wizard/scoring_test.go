@@ -23,17 +23,17 @@ func TestScoreSuggestion_Determinism(t *testing.T) {
 				Brand:     strPtr("Coca-Cola"),
 				StoreID:   1,
 				Price:     2.50,
-				SizeValue: floatPtr(1.5),
-				SizeUnit:  strPtr("L"),
+				SizeValue: floatPtr(1500),
+				SizeUnit:  strPtr("ml"),
 			},
 			originalProduct: &models.Product{
 				Brand:        strPtr("Coca-Cola"),
 				CurrentPrice: 3.00,
-				UnitType:     strPtr("L"),
+				UnitSize:     strPtr("1,5 l"),
 			},
 			userStorePreferences: map[int]bool{1: true},
 			weights:              DefaultScoringWeights(),
-			expectedScore:        6.4, // brand(3.0) + store(2.0) + size(0.8) + price(~0.6) = ~6.4
+			expectedScore:        6.6, // brand(3.0) + store(2.0) + size(1.0) + price(~0.6) = ~6.6
 			description:          "Perfect brand match in preferred store with cheaper price",
 		},
 		{
@@ -42,17 +42,17 @@ func TestScoreSuggestion_Determinism(t *testing.T) {
 				Brand:     strPtr("Coca-Cola"),
 				StoreID:   2,
 				Price:     2.50,
-				SizeValue: floatPtr(1.5),
-				SizeUnit:  strPtr("L"),
+				SizeValue: floatPtr(1500),
+				SizeUnit:  strPtr("ml"),
 			},
 			originalProduct: &models.Product{
 				Brand:        strPtr("Coca-Cola"),
 				CurrentPrice: 3.00,
-				UnitType:     strPtr("L"),
+				UnitSize:     strPtr("1,5 l"),
 			},
 			userStorePreferences: map[int]bool{1: true},
 			weights:              DefaultScoringWeights(),
-			expectedScore:        5.4, // brand(3.0) + store(1.0) + size(0.8) + price(~0.6) = ~5.4
+			expectedScore:        5.6, // brand(3.0) + store(1.0) + size(1.0) + price(~0.6) = ~5.6
 			description:          "Brand match but not in preferred store",
 		},
 		{
@@ -61,17 +61,17 @@ func TestScoreSuggestion_Determinism(t *testing.T) {
 				Brand:     strPtr("Pepsi"),
 				StoreID:   1,
 				Price:     2.50,
-				SizeValue: floatPtr(1.5),
-				SizeUnit:  strPtr("L"),
+				SizeValue: floatPtr(1500),
+				SizeUnit:  strPtr("ml"),
 			},
 			originalProduct: &models.Product{
 				Brand:        strPtr("Coca-Cola"),
 				CurrentPrice: 3.00,
-				UnitType:     strPtr("L"),
+				UnitSize:     strPtr("1,5 l"),
 			},
 			userStorePreferences: map[int]bool{1: true},
 			weights:              DefaultScoringWeights(),
-			expectedScore:        3.4, // brand(0.0) + store(2.0) + size(0.8) + price(~0.6) = ~3.4
+			expectedScore:        3.6, // brand(0.0) + store(2.0) + size(1.0) + price(~0.6) = ~3.6
 			description:          "Different brand in preferred store",
 		},
 		{
@@ -80,17 +80,17 @@ func TestScoreSuggestion_Determinism(t *testing.T) {
 				Brand:     strPtr("Coca-Cola"),
 				StoreID:   1,
 				Price:     3.50,
-				SizeValue: floatPtr(1.5),
-				SizeUnit:  strPtr("L"),
+				SizeValue: floatPtr(1500),
+				SizeUnit:  strPtr("ml"),
 			},
 			originalProduct: &models.Product{
 				Brand:        strPtr("Coca-Cola"),
 				CurrentPrice: 3.00,
-				UnitType:     strPtr("L"),
+				UnitSize:     strPtr("1,5 l"),
 			},
 			userStorePreferences: map[int]bool{1: true},
 			weights:              DefaultScoringWeights(),
-			expectedScore:        5.8,
+			expectedScore:        6.4, // brand(3.0) + store(2.0) + size(1.0) + price(~0.4) = ~6.4
 			description:          "Same brand but more expensive",
 		},
 		{
@@ -99,17 +99,17 @@ func TestScoreSuggestion_Determinism(t *testing.T) {
 				Brand:     nil,
 				StoreID:   1,
 				Price:     2.50,
-				SizeValue: floatPtr(1.5),
-				SizeUnit:  strPtr("L"),
+				SizeValue: floatPtr(1500),
+				SizeUnit:  strPtr("ml"),
 			},
 			originalProduct: &models.Product{
 				Brand:        strPtr("Coca-Cola"),
 				CurrentPrice: 3.00,
-				UnitType:     strPtr("L"),
+				UnitSize:     strPtr("1,5 l"),
 			},
 			userStorePreferences: map[int]bool{1: true},
 			weights:              DefaultScoringWeights(),
-			expectedScore:        3.4, // brand(0.0) + store(2.0) + size(0.8) + price(~0.6) = ~3.4
+			expectedScore:        3.6, // brand(0.0) + store(2.0) + size(1.0) + price(~0.6) = ~3.6
 			description:          "Handles nil brand gracefully",
 		},
 	}
@@ -272,8 +272,121 @@ func TestScoreSuggestion_WeightContribution(t *testing.T) {
 	}
 }
 
+// TestCalculateSizeScore verifies sizes are compared by relative difference in a common base unit
+func TestCalculateSizeScore(t *testing.T) {
+	tests := []struct {
+		name          string
+		original      *models.Product
+		suggestedSize string
+		expected      float64
+	}{
+		{"same size different notation", &models.Product{UnitSize: strPtr("1 l")}, "1000 ml", 1.0},
+		{"larger package", &models.Product{UnitSize: strPtr("1 l")}, "1,5 l", 0.6667},
+		{"smaller package", &models.Product{UnitSize: strPtr("500 g")}, "250 g", 0.5},
+		{"multipack matches total", &models.Product{UnitSize: strPtr("3 l")}, "6 x 0,5 l", 1.0},
+		{"size from name", &models.Product{Name: "Pienas 2,5%, 1 l"}, "0,5 l", 0.5},
+		{"different units", &models.Product{UnitSize: strPtr("1 kg")}, "1 l", 0.0},
+		{"unknown original size", &models.Product{Name: "Duona"}, "500 g", 0.5},
+		{"unknown suggested size", &models.Product{UnitSize: strPtr("500 g")}, "", 0.5},
+		{"no original product", nil, "500 g", 0.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var size *float64
+			var unit *string
+			if value, base, ok := ParseSize(tt.suggestedSize); ok {
+				size, unit = &value, &base
+			}
+
+			if got := calculateSizeScore(tt.original, size, unit); abs(got-tt.expected) > 0.0001 {
+				t.Errorf("expected size score %.4f, got %.4f", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestParseSize verifies package sizes are converted to their base unit, multipacks included
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		text     string
+		value    float64
+		unit     string
+		expected bool
+	}{
+		{"1,5 l", 1500, "ml", true},
+		{"0.5kg", 500, "g", true},
+		{"6 x 0,5 l", 3000, "ml", true},
+		{"4×125 g", 500, "g", true},
+		{"330 ml x 24", 7920, "ml", true},
+		{"10 vnt.", 10, "vnt.", true},
+		{"Duona", 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			value, unit, ok := ParseSize(tt.text)
+			if ok != tt.expected || unit != tt.unit || abs(value-tt.value) > 0.0001 {
+				t.Errorf("expected %v %q (ok=%v), got %v %q (ok=%v)", tt.value, tt.unit, tt.expected, value, unit, ok)
+			}
+		})
+	}
+}
+
+// TestDescribeSizeComparison verifies the human-readable size comparison shown with suggestions
+func TestDescribeSizeComparison(t *testing.T) {
+	tests := []struct {
+		name          string
+		original      *models.Product
+		suggestedSize string
+		expected      string
+	}{
+		{"same size", &models.Product{UnitSize: strPtr("1 l")}, "1000 ml", "Same size (1 l)"},
+		{"larger", &models.Product{UnitSize: strPtr("1,5 l")}, "6 x 0,5 l", "3 l, 100% larger than 1.5 l"},
+		{"smaller", &models.Product{UnitSize: strPtr("1 kg")}, "400 g", "400 g, 60% smaller than 1 kg"},
+		{"different units", &models.Product{UnitSize: strPtr("1 kg")}, "1 l", "1 l, not comparable with 1 kg"},
+		{"unknown original", &models.Product{Name: "Sūris"}, "200 g", "200 g"},
+		{"unknown suggestion", &models.Product{UnitSize: strPtr("1 kg")}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var size *float64
+			var unit *string
+			if value, base, ok := ParseSize(tt.suggestedSize); ok {
+				size, unit = &value, &base
+			}
+
+			if got := DescribeSizeComparison(tt.original, size, unit); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
 // Helper functions
 
+// TestCalculatePriceScore_UsesEffectivePrice verifies that multi-buy deals are scored per item
+func TestCalculatePriceScore_UsesEffectivePrice(t *testing.T) {
+	original := &models.Product{CurrentPrice: 3.00}
+
+	plain := &models.Suggestion{Price: 4.00}
+	oneForOne := &models.Suggestion{Price: 4.00, EffectivePrice: 2.00}
+
+	if score := calculatePriceScore(original, suggestionPrice(plain)); score >= 0.5 {
+		t.Fatalf("expected more expensive plain price to score below 0.5, got %.2f", score)
+	}
+	if score := calculatePriceScore(original, suggestionPrice(oneForOne)); score <= 0.5 {
+		t.Fatalf("expected 1+1 deal to score above 0.5, got %.2f", score)
+	}
+
+	// The original's own promotion counts too: 1+1 at 3.00 is 1.50 per item
+	original.SpecialDiscount = strPtr("1+1")
+	if score := calculatePriceScore(original, 2.00); score >= 0.5 {
+		t.Fatalf("expected 2.00 to be worse than the original 1+1 deal, got %.2f", score)
+	}
+}
+
 func strPtr(s string) *string {
 	return &s
 }
@@ -196,9 +196,9 @@ func (s *wizardService) StartWizard(ctx context.Context, req *StartWizardRequest
 						StoreID:         product.StoreID,
 						StoreName:       product.Store.Name,
 						Price:           product.CurrentPrice,
+						EffectivePrice:  product.DealPrice(),
+						Promotion:       product.GetPromotion(),
 						Unit:            product.UnitType,
-						SizeValue:       nil, // TODO: Parse from UnitSize
-						SizeUnit:        product.UnitSize,
 						Score:           0.0, // Will be calculated by scoring
 						Confidence:      0.0,
 						Explanation:     "",
@@ -214,6 +214,12 @@ func (s *wizardService) StartWizard(ctx context.Context, req *StartWizardRequest
 						suggestion.ProductMasterID = &pmID
 					}
 
+					if size, unit, ok := ProductSize(product); ok {
+						suggestion.SizeValue = &size
+						suggestion.SizeUnit = &unit
+						suggestion.SizeComparison = DescribeSizeComparison(item.LinkedProduct, &size, &unit)
+					}
+
 					wizItem.Suggestions = append(wizItem.Suggestions, suggestion)
 
 					// Track store for selection
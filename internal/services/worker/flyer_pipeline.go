@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"time"
+
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// WorkflowFlyerPipeline is the weekly flyer ingestion: scraping fans out into one
+// rasterize job per new flyer and one extraction job per page; once they all
+// finished, products are matched to masters, then price alerts are evaluated
+// and expired flyer items detected
+const WorkflowFlyerPipeline = "flyer_pipeline"
+
+// Parameters of the flyer_pipeline workflow
+const (
+	WorkflowParamStores       = "stores"        // store codes to scrape, all stores when empty
+	WorkflowParamRecordPrices = "record_prices" // also record the new prices in the price history
+)
+
+// FlyerPipeline declares the flyer_pipeline workflow
+func FlyerPipeline(params map[string]interface{}) (*WorkflowDefinition, error) {
+	stores, err := paramStrings(params, WorkflowParamStores)
+	if err != nil {
+		return nil, err
+	}
+	recordPrices, _ := params[WorkflowParamRecordPrices].(bool)
+
+	scrape := &Job{
+		Type:        JobTypeScrapeFlyer,
+		Priority:    8,
+		Payload:     map[string]interface{}{},
+		MaxAttempts: 3,
+		RetryDelay:  5 * time.Minute,
+	}
+	if len(stores) > 0 {
+		scrape.Payload[PayloadStores] = stores
+	}
+
+	def := &WorkflowDefinition{
+		Name:   WorkflowFlyerPipeline,
+		Params: params,
+		Steps: []WorkflowStepSpec{
+			{Name: "scrape", Job: scrape},
+			{Name: "match", Job: pipelineJob(JobTypeMatchProducts), DependsOn: []string{"scrape"}},
+			{Name: "evaluate_alerts", Job: pipelineJob(JobTypeEvaluatePriceAlerts), DependsOn: []string{"match"}},
+			{Name: "detect_expired_items", Job: pipelineJob(JobTypeExpireFlyerItems), DependsOn: []string{"match"}},
+		},
+	}
+	if recordPrices {
+		def.Steps = append(def.Steps, WorkflowStepSpec{
+			Name: "record_prices", Job: pipelineJob(JobTypeUpdatePrices), DependsOn: []string{"match"},
+		})
+	}
+	return def, nil
+}
+
+func pipelineJob(jobType JobType) *Job {
+	return &Job{
+		Type:        jobType,
+		Priority:    5,
+		Payload:     map[string]interface{}{},
+		MaxAttempts: 3,
+		RetryDelay:  time.Minute,
+	}
+}
+
+// paramStrings reads a string list parameter, which comes back from JSON as []interface{}
+func paramStrings(params map[string]interface{}, key string) ([]string, error) {
+	value, ok := params[key]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, apperrors.ValidationF("parameter %s must be a list of strings", key)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, apperrors.ValidationF("parameter %s must be a list of strings", key)
+	}
+}
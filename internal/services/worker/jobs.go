@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultQueueName is the job queue shared by the scraper, the scheduler and the workers
+const DefaultQueueName = "kainuguru:jobs"
+
+// Payload keys of extract_products jobs
+const (
+	PayloadFlyerID     = "flyer_id"
+	PayloadFlyerPageID = "flyer_page_id"
+	PayloadBatchSize   = "batch_size"
+)
+
+// Payload keys of the scheduled maintenance jobs
+const (
+	PayloadStores               = "stores"                  // scrape_flyer: store codes to scrape
+	PayloadArchiveOlderThanDays = "archive_older_than_days" // archive_data
+	PayloadCleanupOlderThanDays = "cleanup_older_than_days" // cleanup_data
+)
+
+// Payload keys of the workflow jobs
+const (
+	PayloadFlyer          = "flyer"    // rasterize_flyer: the scraped flyer
+	PayloadWorkflow       = "workflow" // run_workflow: name of the workflow to start
+	PayloadWorkflowParams = "params"   // run_workflow: parameters of the workflow
+)
+
+// PayloadTraceContext is set on every job enqueued within a trace to the W3C
+// trace context of the enqueuing span, so the job's spans join that trace
+const PayloadTraceContext = "trace_context"
+
+// NewExtractProductsJob creates the job that extracts the products of a single flyer page.
+// The ID is derived from the page, so enqueueing a page whose job is still queued or
+// running is a no-op. Later duplicates run, and skip pages already extracted or
+// still leased by another job.
+func NewExtractProductsJob(flyerID, flyerPageID int) *Job {
+	return &Job{
+		ID:       fmt.Sprintf("%s:%d", JobTypeExtractProducts, flyerPageID),
+		Type:     JobTypeExtractProducts,
+		Priority: 5,
+		Payload: map[string]interface{}{
+			PayloadFlyerID:     flyerID,
+			PayloadFlyerPageID: flyerPageID,
+		},
+		MaxAttempts: 3,
+		RetryDelay:  time.Minute,
+	}
+}
+
+// NewRunWorkflowJob creates the job that starts the named workflow, so schedules can start workflows
+func NewRunWorkflowJob(name string, params map[string]interface{}) *Job {
+	return &Job{
+		Type:     JobTypeRunWorkflow,
+		Priority: 5,
+		Payload: map[string]interface{}{
+			PayloadWorkflow:       name,
+			PayloadWorkflowParams: params,
+		},
+		MaxAttempts: 3,
+		RetryDelay:  time.Minute,
+	}
+}
+
+// PayloadString reads a string payload value
+func (j *Job) PayloadString(key string) (string, bool) {
+	value, ok := j.Payload[key].(string)
+	return value, ok
+}
+
+// PayloadMap reads an object payload value
+func (j *Job) PayloadMap(key string) (map[string]interface{}, bool) {
+	value, ok := j.Payload[key].(map[string]interface{})
+	return value, ok
+}
+
+// PayloadInt reads an integer payload value. Payloads round-trip through JSON, so
+// numbers come back as float64.
+func (j *Job) PayloadInt(key string) (int, bool) {
+	value, ok := j.Payload[key]
+	if !ok {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// PayloadStrings reads a string list payload value. Payloads round-trip through
+// JSON, so lists come back as []interface{}.
+func (j *Job) PayloadStrings(key string) ([]string, bool) {
+	value, ok := j.Payload[key]
+	if !ok {
+		return nil, false
+	}
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJobPayloadStrings(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    []string
+		wantOK  bool
+	}{
+		{
+			name:    "string slice",
+			payload: map[string]interface{}{PayloadStores: []string{"iki", "maxima"}},
+			want:    []string{"iki", "maxima"},
+			wantOK:  true,
+		},
+		{
+			name:    "decoded from json",
+			payload: map[string]interface{}{PayloadStores: []interface{}{"iki", "rimi"}},
+			want:    []string{"iki", "rimi"},
+			wantOK:  true,
+		},
+		{
+			name:    "missing",
+			payload: map[string]interface{}{},
+			wantOK:  false,
+		},
+		{
+			name:    "mixed types",
+			payload: map[string]interface{}{PayloadStores: []interface{}{"iki", 3.0}},
+			wantOK:  false,
+		},
+		{
+			name:    "not a list",
+			payload: map[string]interface{}{PayloadStores: "iki"},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Payload: tt.payload}
+			got, ok := job.PayloadStrings(PayloadStores)
+			if ok != tt.wantOK {
+				t.Fatalf("PayloadStrings() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantOK && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PayloadStrings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobPayloadStrings_RoundTrip(t *testing.T) {
+	data, err := json.Marshal(&Job{Payload: map[string]interface{}{PayloadStores: []string{"iki"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := job.PayloadStrings(PayloadStores)
+	if !ok || !reflect.DeepEqual(got, []string{"iki"}) {
+		t.Errorf("PayloadStrings() = %v, %v after round trip", got, ok)
+	}
+}
+
+func TestSetupDefaultSchedules_KnownJobTypes(t *testing.T) {
+	scheduler := NewJobScheduler(nil, nil)
+	if err := scheduler.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer scheduler.Stop()
+
+	if err := scheduler.SetupDefaultSchedules(); err != nil {
+		t.Fatal(err)
+	}
+
+	known := make(map[JobType]bool, len(JobTypes))
+	for _, jobType := range JobTypes {
+		known[jobType] = true
+	}
+
+	scheduled := scheduler.GetScheduledJobs()
+	if len(scheduled) == 0 {
+		t.Fatal("no default schedules were added")
+	}
+	for _, job := range scheduled {
+		if !known[job.JobType] {
+			t.Errorf("schedule %q uses job type %q missing from JobTypes", job.Name, job.JobType)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultLeaderTTL is how long a leader keeps its lock without renewing it;
+// a replica that dies is replaced after at most this long
+const DefaultLeaderTTL = 30 * time.Second
+
+// LeaderElector campaigns for a Redis lock so that only one replica at a time
+// runs singleton work such as the cron schedules
+type LeaderElector struct {
+	lockManager *LockManager
+	resource    string
+	ttl         time.Duration
+	leading     atomic.Bool
+}
+
+// NewLeaderElector creates an elector for the named resource
+func NewLeaderElector(redis *redis.Client, resource string, ttl time.Duration) *LeaderElector {
+	if ttl < 3*time.Second {
+		ttl = DefaultLeaderTTL
+	}
+	return &LeaderElector{
+		lockManager: NewLockManager(redis, "leader:"),
+		resource:    resource,
+		ttl:         ttl,
+	}
+}
+
+// IsLeader reports whether this replica currently holds the leadership
+func (e *LeaderElector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns until ctx is done. Whenever this replica wins, lead runs with a
+// context that is cancelled as soon as the leadership is lost or ctx is done;
+// Run waits for lead to return before campaigning again.
+func (e *LeaderElector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	retry := time.NewTicker(e.ttl / 3)
+	defer retry.Stop()
+
+	for {
+		lock, err := e.lockManager.AcquireLock(ctx, e.resource, e.ttl)
+		if err == nil {
+			e.hold(ctx, lock, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry.C:
+		}
+	}
+}
+
+// hold runs lead while renewing the lock, and releases the lock afterwards
+func (e *LeaderElector) hold(ctx context.Context, lock *DistributedLock, lead func(ctx context.Context)) {
+	log.Printf("Acquired leadership of %s", e.resource)
+	e.leading.Store(true)
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(leadCtx)
+	}()
+
+	renew := time.NewTicker(e.ttl / 3)
+	defer renew.Stop()
+
+renewal:
+	for {
+		select {
+		case <-ctx.Done():
+			break renewal
+		case <-done:
+			break renewal
+		case <-renew.C:
+			if err := lock.Extend(ctx, e.ttl); err != nil {
+				log.Printf("Lost leadership of %s: %v", e.resource, err)
+				break renewal
+			}
+		}
+	}
+
+	cancel()
+	<-done
+	e.leading.Store(false)
+
+	// Hand over right away instead of letting the lock expire
+	releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer releaseCancel()
+	if err := lock.Release(releaseCtx); err == nil {
+		log.Printf("Released leadership of %s", e.resource)
+	}
+}
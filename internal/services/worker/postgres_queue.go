@@ -0,0 +1,589 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// queuedStatuses are the rows still in the queue; completed and cancelled rows
+// stay in the table until the cleanup job deletes them
+var queuedStatuses = []string{
+	string(JobStatusPending),
+	string(JobStatusRetrying),
+	string(JobStatusProcessing),
+	string(JobStatusFailed),
+}
+
+// PostgresQueue is the Postgres backend of Queue, kept in the extraction_jobs
+// table. A job's ID is stored as job_key, unique within queue_name; dead letters
+// are the rows left failed. Workers reserve jobs with FOR UPDATE SKIP LOCKED.
+type PostgresQueue struct {
+	db                 *bun.DB
+	queueName          string
+	opts               QueueOptions
+	supportsSkipLocked bool
+}
+
+// NewPostgresQueue creates a Postgres queue holding the jobs of queueName
+func NewPostgresQueue(db *bun.DB, queueName string, opts QueueOptions) *PostgresQueue {
+	return &PostgresQueue{
+		db:                 db,
+		queueName:          queueName,
+		opts:               opts.withDefaults(),
+		supportsSkipLocked: db.Dialect().Name() == dialect.PG,
+	}
+}
+
+// VisibilityTimeout returns how long a dequeued job stays leased
+func (q *PostgresQueue) VisibilityTimeout() time.Duration {
+	return q.opts.VisibilityTimeout
+}
+
+func (q *PostgresQueue) Enqueue(ctx context.Context, job *Job) error {
+	now := q.now()
+	if err := prepareEnqueue(job, now); err != nil {
+		return err
+	}
+	injectTraceContext(ctx, job)
+
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job payload")
+	}
+
+	runAt := now
+	if job.ScheduledAt != nil {
+		runAt = job.ScheduledAt.UTC()
+	}
+
+	// A job ID that is still queued or processing keeps its row; a finished one starts over
+	_, err = q.db.ExecContext(ctx, `
+		INSERT INTO extraction_jobs (
+			queue_name, job_key, job_type, status, priority, payload,
+			attempts, max_attempts, retry_delay_ms, error_count,
+			scheduled_for, created_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?, 0, ?, ?, ?)
+		ON CONFLICT (queue_name, job_key) DO UPDATE SET
+			job_type = EXCLUDED.job_type,
+			status = EXCLUDED.status,
+			priority = EXCLUDED.priority,
+			payload = EXCLUDED.payload,
+			attempts = 0,
+			max_attempts = EXCLUDED.max_attempts,
+			retry_delay_ms = EXCLUDED.retry_delay_ms,
+			worker_id = NULL,
+			started_at = NULL,
+			completed_at = NULL,
+			error_message = NULL,
+			lease_expires_at = NULL,
+			scheduled_for = EXCLUDED.scheduled_for,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE extraction_jobs.status IN (?)
+	`,
+		q.queueName, job.ID, string(job.Type), string(JobStatusPending), job.Priority, string(payload),
+		job.MaxAttempts, job.RetryDelay.Milliseconds(),
+		runAt, now, now,
+		bun.In([]string{
+			string(JobStatusCompleted),
+			string(JobStatusFailed),
+			string(models.JobStatusCancelled),
+			string(models.JobStatusExpired),
+		}),
+	)
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to enqueue job %s", job.ID)
+	}
+	return nil
+}
+
+func (q *PostgresQueue) Dequeue(ctx context.Context, timeout time.Duration) (*Job, error) {
+	return waitForJob(ctx, timeout, q.opts.PollInterval, q.dequeue)
+}
+
+func (q *PostgresQueue) dequeue(ctx context.Context) (*Job, error) {
+	var reserved *models.ExtractionJob
+
+	err := q.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		now := q.now()
+		row := new(models.ExtractionJob)
+		query := tx.NewSelect().
+			Model(row).
+			Where("ej.queue_name = ?", q.queueName).
+			Where("ej.status IN (?)", bun.In([]string{string(JobStatusPending), string(JobStatusRetrying)})).
+			Where("ej.scheduled_for <= ?", now).
+			Where("ej.job_type NOT IN (?)", tx.NewSelect().
+				Table("job_queue_pauses").
+				Column("job_type").
+				Where("queue_name = ?", q.queueName)).
+			Order("ej.priority DESC", "ej.scheduled_for ASC", "ej.id ASC").
+			Limit(1)
+		if q.supportsSkipLocked {
+			query = query.For("UPDATE SKIP LOCKED")
+		}
+
+		if err := query.Scan(ctx); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		lease := now.Add(q.opts.VisibilityTimeout)
+		row.Status = string(JobStatusProcessing)
+		row.Attempts++
+		row.StartedAt = &now
+		row.LeaseExpiresAt = &lease
+		row.UpdatedAt = now
+
+		if _, err := tx.NewUpdate().
+			Model(row).
+			Column("status", "attempts", "started_at", "lease_expires_at", "updated_at").
+			WherePK().
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		reserved = row
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to dequeue job")
+	}
+	if reserved == nil {
+		return nil, nil
+	}
+	return jobFromRow(reserved)
+}
+
+func (q *PostgresQueue) Extend(ctx context.Context, job *Job) error {
+	now := q.now()
+	result, err := q.db.NewUpdate().
+		Model((*models.ExtractionJob)(nil)).
+		Set("lease_expires_at = ?", now.Add(q.opts.VisibilityTimeout)).
+		Set("updated_at = ?", now).
+		Where("queue_name = ?", q.queueName).
+		Where("job_key = ?", job.ID).
+		Where("status = ?", string(JobStatusProcessing)).
+		Exec(ctx)
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to extend job %s", job.ID)
+	}
+	return requireRow(result, job)
+}
+
+func (q *PostgresQueue) Complete(ctx context.Context, job *Job) error {
+	now := q.now()
+	result, err := q.db.NewUpdate().
+		Model((*models.ExtractionJob)(nil)).
+		Set("status = ?", string(JobStatusCompleted)).
+		Set("completed_at = ?", now).
+		Set("lease_expires_at = NULL").
+		Set("updated_at = ?", now).
+		Where("queue_name = ?", q.queueName).
+		Where("job_key = ?", job.ID).
+		Where("status = ?", string(JobStatusProcessing)).
+		Exec(ctx)
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to complete job %s", job.ID)
+	}
+	if err := requireRow(result, job); err != nil {
+		return err
+	}
+
+	job.Status = JobStatusCompleted
+	job.CompletedAt = &now
+	job.UpdatedAt = now
+	return nil
+}
+
+func (q *PostgresQueue) Cancel(ctx context.Context, job *Job) error {
+	now := q.now()
+	result, err := q.db.NewUpdate().
+		Model((*models.ExtractionJob)(nil)).
+		Set("status = ?", string(JobStatusCancelled)).
+		Set("completed_at = ?", now).
+		Set("lease_expires_at = NULL").
+		Set("updated_at = ?", now).
+		Where("queue_name = ?", q.queueName).
+		Where("job_key = ?", job.ID).
+		Where("status = ?", string(JobStatusProcessing)).
+		Exec(ctx)
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to cancel job %s", job.ID)
+	}
+	if err := requireRow(result, job); err != nil {
+		return err
+	}
+
+	job.Status = JobStatusCancelled
+	job.CompletedAt = &now
+	job.UpdatedAt = now
+	return nil
+}
+
+func (q *PostgresQueue) Fail(ctx context.Context, job *Job, errorMsg string) error {
+	released, err := q.release(ctx, job, errorMsg, time.Time{})
+	if err != nil {
+		return err
+	}
+	if !released {
+		return jobNotProcessing(job)
+	}
+	return nil
+}
+
+// release records a failed attempt of a processing job. A non-zero
+// expiredBefore only releases the job if its lease ended before then.
+func (q *PostgresQueue) release(ctx context.Context, job *Job, errorMsg string, expiredBefore time.Time) (bool, error) {
+	now := q.now()
+	retry := prepareFailure(job, errorMsg, now)
+
+	query := q.db.NewUpdate().
+		Model((*models.ExtractionJob)(nil)).
+		Set("status = ?", string(job.Status)).
+		Set("error_message = ?", errorMsg).
+		Set("error_count = error_count + 1").
+		Set("started_at = NULL").
+		Set("lease_expires_at = NULL").
+		Set("updated_at = ?", now).
+		Where("queue_name = ?", q.queueName).
+		Where("job_key = ?", job.ID).
+		Where("status = ?", string(JobStatusProcessing))
+	if retry {
+		query = query.Set("scheduled_for = ?", job.ScheduledAt.UTC())
+	} else {
+		query = query.Set("completed_at = ?", now)
+	}
+	if !expiredBefore.IsZero() {
+		query = query.Where("lease_expires_at < ?", expiredBefore.UTC())
+	}
+
+	result, err := query.Exec(ctx)
+	if err != nil {
+		return false, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to fail job %s", job.ID)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to fail job %s", job.ID)
+	}
+	return affected > 0, nil
+}
+
+func (q *PostgresQueue) RequeueExpired(ctx context.Context) (int, error) {
+	now := q.now()
+
+	var rows []*models.ExtractionJob
+	err := q.db.NewSelect().
+		Model(&rows).
+		Where("ej.queue_name = ?", q.queueName).
+		Where("ej.status = ?", string(JobStatusProcessing)).
+		Where("ej.lease_expires_at < ?", now).
+		Order("ej.id ASC").
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list expired jobs")
+	}
+
+	requeued := 0
+	for _, row := range rows {
+		job, err := jobFromRow(row)
+		if err != nil {
+			return requeued, err
+		}
+
+		released, err := q.release(ctx, job, errVisibilityTimeout, now)
+		if err != nil {
+			return requeued, err
+		}
+		if released {
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (q *PostgresQueue) DeadLetters(ctx context.Context, limit int) ([]*Job, error) {
+	if limit <= 0 {
+		return []*Job{}, nil
+	}
+
+	var rows []*models.ExtractionJob
+	err := q.db.NewSelect().
+		Model(&rows).
+		Where("ej.queue_name = ?", q.queueName).
+		Where("ej.status = ?", string(JobStatusFailed)).
+		Order("ej.completed_at DESC", "ej.id DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list dead letters")
+	}
+
+	jobs := make([]*Job, 0, len(rows))
+	for _, row := range rows {
+		job, err := jobFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (q *PostgresQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	now := q.now()
+	waiting := bun.In([]string{string(JobStatusPending), string(JobStatusRetrying)})
+
+	count := func(where string, args ...interface{}) (int64, error) {
+		n, err := q.db.NewSelect().
+			Model((*models.ExtractionJob)(nil)).
+			Where("ej.queue_name = ?", q.queueName).
+			Where(where, args...).
+			Count(ctx)
+		return int64(n), err
+	}
+
+	var stats QueueStats
+	var err error
+	if stats.Pending, err = count("ej.status IN (?) AND ej.scheduled_for <= ?", waiting, now); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get pending count")
+	}
+	if stats.Scheduled, err = count("ej.status IN (?) AND ej.scheduled_for > ?", waiting, now); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get scheduled count")
+	}
+	if stats.Processing, err = count("ej.status = ?", string(JobStatusProcessing)); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get processing count")
+	}
+	if stats.DeadLetter, err = count("ej.status = ?", string(JobStatusFailed)); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get dead letter count")
+	}
+	return &stats, nil
+}
+
+func (q *PostgresQueue) GetJob(ctx context.Context, id string) (*Job, error) {
+	row := new(models.ExtractionJob)
+	err := q.db.NewSelect().
+		Model(row).
+		Where("ej.queue_name = ?", q.queueName).
+		Where("ej.job_key = ?", id).
+		Where("ej.status IN (?)", bun.In(queuedStatuses)).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, jobNotFound(id)
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get job %s", id)
+	}
+	return jobFromRow(row)
+}
+
+func (q *PostgresQueue) ListJobs(ctx context.Context, filter JobFilter) ([]*Job, error) {
+	filter = filter.withDefaults()
+
+	var rows []*models.ExtractionJob
+	query := q.db.NewSelect().
+		Model(&rows).
+		Where("ej.queue_name = ?", q.queueName).
+		Where("ej.status IN (?)", bun.In(queuedStatuses)).
+		Order("ej.created_at DESC", "ej.job_key ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset)
+	if filter.Status != "" {
+		query = query.Where("ej.status = ?", string(filter.Status))
+	}
+	if filter.Type != "" {
+		query = query.Where("ej.job_type = ?", string(filter.Type))
+	}
+
+	if err := query.Scan(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list jobs")
+	}
+
+	jobs := make([]*Job, 0, len(rows))
+	for _, row := range rows {
+		job, err := jobFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (q *PostgresQueue) RetryJob(ctx context.Context, id string) (*Job, error) {
+	job, err := q.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == JobStatusProcessing {
+		return nil, jobInState(job, "retry")
+	}
+
+	// Dead and delayed jobs run now; a job that is already ready keeps its place
+	now := q.now()
+	_, err = q.db.NewUpdate().
+		Model((*models.ExtractionJob)(nil)).
+		Set("status = ?", string(JobStatusPending)).
+		Set("attempts = 0").
+		Set("error_message = NULL").
+		Set("started_at = NULL").
+		Set("completed_at = NULL").
+		Set("lease_expires_at = NULL").
+		Set("scheduled_for = ?", now).
+		Set("updated_at = ?", now).
+		Where("queue_name = ?", q.queueName).
+		Where("job_key = ?", id).
+		WhereGroup(" AND ", func(query *bun.UpdateQuery) *bun.UpdateQuery {
+			return query.
+				Where("status IN (?)", bun.In([]string{string(JobStatusFailed), string(JobStatusRetrying)})).
+				WhereOr("status = ? AND scheduled_for > ?", string(JobStatusPending), now)
+		}).
+		Exec(ctx)
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to retry job %s", id)
+	}
+	return q.GetJob(ctx, id)
+}
+
+func (q *PostgresQueue) CancelJob(ctx context.Context, id string) (*Job, error) {
+	now := q.now()
+	result, err := q.db.NewUpdate().
+		Model((*models.ExtractionJob)(nil)).
+		Set("status = ?", string(JobStatusCancelled)).
+		Set("completed_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("queue_name = ?", q.queueName).
+		Where("job_key = ?", id).
+		Where("status IN (?)", bun.In([]string{string(JobStatusPending), string(JobStatusRetrying)})).
+		Exec(ctx)
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to cancel job %s", id)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to cancel job %s", id)
+	}
+	if affected == 0 {
+		current, err := q.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, jobInState(current, "cancel")
+	}
+
+	row := new(models.ExtractionJob)
+	err = q.db.NewSelect().
+		Model(row).
+		Where("ej.queue_name = ?", q.queueName).
+		Where("ej.job_key = ?", id).
+		Scan(ctx)
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to get job %s", id)
+	}
+	return jobFromRow(row)
+}
+
+func (q *PostgresQueue) PauseJobType(ctx context.Context, jobType JobType) error {
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO job_queue_pauses (queue_name, job_type, paused_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (queue_name, job_type) DO NOTHING
+	`, q.queueName, string(jobType), q.now())
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to pause job type %s", jobType)
+	}
+	return nil
+}
+
+func (q *PostgresQueue) ResumeJobType(ctx context.Context, jobType JobType) error {
+	_, err := q.db.NewDelete().
+		Table("job_queue_pauses").
+		Where("queue_name = ?", q.queueName).
+		Where("job_type = ?", string(jobType)).
+		Exec(ctx)
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to resume job type %s", jobType)
+	}
+	return nil
+}
+
+func (q *PostgresQueue) PausedJobTypes(ctx context.Context) ([]JobType, error) {
+	var names []string
+	err := q.db.NewSelect().
+		Table("job_queue_pauses").
+		Column("job_type").
+		Where("queue_name = ?", q.queueName).
+		Order("job_type ASC").
+		Scan(ctx, &names)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list paused job types")
+	}
+
+	types := make([]JobType, len(names))
+	for i, name := range names {
+		types[i] = JobType(name)
+	}
+	return types, nil
+}
+
+// now is stored in UTC so timestamps compare the same in every dialect
+func (q *PostgresQueue) now() time.Time {
+	return q.opts.Clock().UTC()
+}
+
+// jobFromRow converts a queue row of extraction_jobs to a job
+func jobFromRow(row *models.ExtractionJob) (*Job, error) {
+	job := &Job{
+		Type:        JobType(row.JobType),
+		Status:      JobStatus(row.Status),
+		Priority:    row.Priority,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		StartedAt:   row.StartedAt,
+		CompletedAt: row.CompletedAt,
+		Attempts:    row.Attempts,
+		MaxAttempts: row.MaxAttempts,
+		RetryDelay:  time.Duration(row.RetryDelayMs) * time.Millisecond,
+	}
+	if row.JobKey != nil {
+		job.ID = *row.JobKey
+	}
+	if row.ErrorMessage != nil {
+		job.Error = *row.ErrorMessage
+	}
+	if row.ScheduledFor.After(row.CreatedAt) {
+		scheduledAt := row.ScheduledFor
+		job.ScheduledAt = &scheduledAt
+	}
+	if len(row.Payload) > 0 {
+		if err := json.Unmarshal(row.Payload, &job.Payload); err != nil {
+			return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to unmarshal payload of job %s", job.ID)
+		}
+	}
+	return job, nil
+}
+
+func requireRow(result sql.Result, job *Job) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to update job %s", job.ID)
+	}
+	if affected == 0 {
+		return jobNotProcessing(job)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package worker_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/migrator"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker/queuetest"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// The Postgres backend runs the conformance suite against the migrated database
+// at POSTGRES_TEST_DSN, in throwaway queues, so that SKIP LOCKED reservations
+// and the Postgres upserts are covered
+func TestPostgresQueue_Conformance(t *testing.T) {
+	db := postgresTestDB(t)
+
+	queuetest.Run(t, func(t *testing.T, opts worker.QueueOptions) worker.Queue {
+		return worker.NewPostgresQueue(db, postgresTestQueueName(t, db), opts)
+	})
+}
+
+// The suite also runs on SQLite, which has no row locks, so that it runs without a server
+func TestPostgresQueue_ConformanceOnSQLite(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T, opts worker.QueueOptions) worker.Queue {
+		return worker.NewPostgresQueue(setupQueueTestDB(t), worker.DefaultQueueName, opts)
+	})
+}
+
+func TestPostgresQueue_IgnoresOtherQueuesAndServiceJobs(t *testing.T) {
+	ctx := context.Background()
+	db := setupQueueTestDB(t)
+
+	// A job of the extraction job service has no queue name
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO extraction_jobs (job_type, status, priority, payload, scheduled_for, created_at, updated_at)
+		VALUES ('extract_page', 'pending', 9, '{}', '2000-01-01 00:00:00+00:00', '2000-01-01 00:00:00+00:00', '2000-01-01 00:00:00+00:00')
+	`); err != nil {
+		t.Fatalf("failed to insert service job: %v", err)
+	}
+
+	other := worker.NewPostgresQueue(db, "other", worker.QueueOptions{})
+	if err := other.Enqueue(ctx, &worker.Job{ID: "shared-id", Type: worker.JobTypeUpdatePrices}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	q := worker.NewPostgresQueue(db, worker.DefaultQueueName, worker.QueueOptions{})
+	if err := q.Enqueue(ctx, &worker.Job{ID: "shared-id", Type: worker.JobTypeUpdatePrices}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Pending != 1 {
+		t.Fatalf("Stats().Pending = %d, want 1", stats.Pending)
+	}
+
+	job, err := q.Dequeue(ctx, 0)
+	if err != nil || job == nil || job.ID != "shared-id" || job.Type != worker.JobTypeUpdatePrices {
+		t.Fatalf("Dequeue() = %+v, %v", job, err)
+	}
+	if next, err := q.Dequeue(ctx, 0); err != nil || next != nil {
+		t.Fatalf("Dequeue() = %+v, %v, want no job", next, err)
+	}
+}
+
+// postgresTestDB connects to the database at POSTGRES_TEST_DSN and migrates it,
+// skipping the test without one
+func postgresTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	if err := migrator.New(db).Up(context.Background(), migrator.RunOptions{}); err != nil {
+		t.Fatalf("failed to migrate %s: %v", dsn, err)
+	}
+	return db
+}
+
+// postgresTestQueueName returns a throwaway queue name whose jobs and pauses are
+// deleted after the test
+func postgresTestQueueName(t *testing.T, db *bun.DB) string {
+	t.Helper()
+	name := "test-" + uuid.New().String()
+	t.Cleanup(func() {
+		ctx := context.Background()
+		_, _ = db.ExecContext(ctx, "DELETE FROM extraction_jobs WHERE queue_name = ?", name)
+		_, _ = db.ExecContext(ctx, "DELETE FROM job_queue_pauses WHERE queue_name = ?", name)
+	})
+	return name
+}
+
+func setupQueueTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	sqldb, err := sql.Open(sqliteshim.DriverName(), fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	sqldb.SetMaxOpenConns(1)
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+
+	schema := `
+CREATE TABLE extraction_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_type TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    priority INTEGER NOT NULL DEFAULT 5,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    worker_id TEXT,
+    started_at DATETIME,
+    completed_at DATETIME,
+    error_message TEXT,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    scheduled_for DATETIME NOT NULL,
+    expires_at DATETIME,
+    queue_name TEXT,
+    job_key TEXT,
+    retry_delay_ms INTEGER NOT NULL DEFAULT 30000,
+    lease_expires_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX idx_jobs_queue_key ON extraction_jobs(queue_name, job_key);
+CREATE TABLE job_queue_pauses (
+    queue_name TEXT NOT NULL,
+    job_type TEXT NOT NULL,
+    paused_at DATETIME NOT NULL,
+    PRIMARY KEY (queue_name, job_type)
+);`
+	if _, err := db.ExecContext(context.Background(), schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	return db
+}
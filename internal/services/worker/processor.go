@@ -2,20 +2,31 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/kainuguru/kainuguru-api/internal/monitoring"
 	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// JobHandler runs one job. Long handlers report progress to ProgressFromContext(ctx)
+// and stop when ctx is done; context.Cause(ctx) is ErrJobCancelled when the job was
+// asked to stop rather than timed out.
 type JobHandler func(ctx context.Context, job *Job) error
 
+// JobHook is told about a job once it finished: completed, failed for good or cancelled
+type JobHook func(ctx context.Context, job *Job)
+
 type WorkerProcessor struct {
-	queue         *JobQueue
+	queue         Queue
 	handlers      map[JobType]JobHandler
+	finishHooks   []JobHook
 	concurrency   int
 	shutdownCh    chan struct{}
 	wg            sync.WaitGroup
@@ -25,17 +36,25 @@ type WorkerProcessor struct {
 	workerID      string
 	redis         *redis.Client
 	lockKeyPrefix string
+	jobTimeout    time.Duration
+	progress      *ProgressStore
+	cancelPoll    time.Duration
 }
 
 type ProcessorConfig struct {
-	Concurrency     int
-	CleanupInterval time.Duration
-	JobTimeout      time.Duration
-	WorkerID        string
-	LockKeyPrefix   string
+	Concurrency        int
+	CleanupInterval    time.Duration
+	JobTimeout         time.Duration
+	WorkerID           string
+	LockKeyPrefix      string
+	ProgressKeyPrefix  string
+	CancelPollInterval time.Duration
 }
 
-func NewWorkerProcessor(queue *JobQueue, redis *redis.Client, config ProcessorConfig) *WorkerProcessor {
+// NewWorkerProcessor creates a processor for the queue. With a Redis client a job
+// is also locked while it runs, so a job whose lease expired is not run twice, and
+// it reports progress and can be cancelled through the progress store.
+func NewWorkerProcessor(queue Queue, redis *redis.Client, config ProcessorConfig) *WorkerProcessor {
 	if config.Concurrency == 0 {
 		config.Concurrency = 5
 	}
@@ -51,6 +70,14 @@ func NewWorkerProcessor(queue *JobQueue, redis *redis.Client, config ProcessorCo
 	if config.LockKeyPrefix == "" {
 		config.LockKeyPrefix = "job_lock:"
 	}
+	if config.CancelPollInterval == 0 {
+		config.CancelPollInterval = 2 * time.Second
+	}
+
+	var progress *ProgressStore
+	if redis != nil {
+		progress = NewProgressStore(redis, config.ProgressKeyPrefix)
+	}
 
 	return &WorkerProcessor{
 		queue:         queue,
@@ -60,6 +87,9 @@ func NewWorkerProcessor(queue *JobQueue, redis *redis.Client, config ProcessorCo
 		redis:         redis,
 		workerID:      config.WorkerID,
 		lockKeyPrefix: config.LockKeyPrefix,
+		jobTimeout:    config.JobTimeout,
+		progress:      progress,
+		cancelPoll:    config.CancelPollInterval,
 		cleanupTicker: time.NewTicker(config.CleanupInterval),
 	}
 }
@@ -70,6 +100,22 @@ func (wp *WorkerProcessor) RegisterHandler(jobType JobType, handler JobHandler)
 	wp.handlers[jobType] = handler
 }
 
+// OnJobFinished installs a hook run after each job that completed, failed for good
+// or was cancelled. Failed attempts that will be retried don't run it.
+func (wp *WorkerProcessor) OnJobFinished(hook JobHook) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.finishHooks = append(wp.finishHooks, hook)
+}
+
+// HasHandler reports whether a handler is registered for the job type
+func (wp *WorkerProcessor) HasHandler(jobType JobType) bool {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	_, exists := wp.handlers[jobType]
+	return exists
+}
+
 func (wp *WorkerProcessor) Start(ctx context.Context) error {
 	wp.mu.Lock()
 	if wp.running {
@@ -149,6 +195,10 @@ func (wp *WorkerProcessor) worker(ctx context.Context, workerID int) {
 }
 
 func (wp *WorkerProcessor) processJobWithLock(ctx context.Context, job *Job, workerID int) error {
+	if wp.redis == nil {
+		return wp.processJob(ctx, job, workerID)
+	}
+
 	lockKey := wp.lockKeyPrefix + job.ID
 	lockValue := fmt.Sprintf("%s-%d", wp.workerID, workerID)
 	lockTTL := 30 * time.Minute
@@ -179,11 +229,15 @@ func (wp *WorkerProcessor) processJobWithLock(ctx context.Context, job *Job, wor
 func (wp *WorkerProcessor) processJob(ctx context.Context, job *Job, workerID int) error {
 	log.Printf("Worker %d: Processing job %s of type %s", workerID, job.ID, job.Type)
 
+	ctx, span := startJobSpan(ctx, job)
+	defer span.End()
+
 	wp.mu.RLock()
 	handler, exists := wp.handlers[job.Type]
 	wp.mu.RUnlock()
 
 	if !exists {
+		monitoring.WorkerJobsTotal.WithLabelValues(string(job.Type), "unhandled").Inc()
 		errorMsg := fmt.Sprintf("no handler registered for job type %s", job.Type)
 		err := wp.queue.Fail(ctx, job, errorMsg)
 		if err != nil {
@@ -192,31 +246,84 @@ func (wp *WorkerProcessor) processJob(ctx context.Context, job *Job, workerID in
 		return apperrors.Validation(errorMsg)
 	}
 
-	// Create context with timeout
-	jobCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
-	defer cancel()
+	// Create context with timeout, cancelled early when the job is asked to stop
+	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, wp.jobTimeout)
+	defer cancelTimeout()
+	jobCtx, cancel := context.WithCancelCause(timeoutCtx)
+	defer cancel(nil)
+
+	var reporter *ProgressReporter
+	if wp.progress != nil {
+		reporter = newProgressReporter(wp.progress, job, time.Now)
+		reporter.start(ctx)
+		defer reporter.finish(ctx, job)
+	}
+	jobCtx = withProgress(jobCtx, reporter)
+	jobCtx = withJob(jobCtx, job)
+
+	// Keep the job leased while the handler runs
+	heartbeatDone := make(chan struct{})
+	go wp.heartbeat(jobCtx, job, cancel, heartbeatDone)
 
 	// Process the job
+	start := time.Now()
 	err := handler(jobCtx, job)
+	cancelled := errors.Is(context.Cause(jobCtx), ErrJobCancelled)
+	cancel(nil)
+	<-heartbeatDone
+	monitoring.WorkerJobDurationSeconds.WithLabelValues(string(job.Type)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if err != nil && cancelled {
+		span.SetAttributes(attribute.Bool("job.cancelled", true))
+		monitoring.WorkerJobsTotal.WithLabelValues(string(job.Type), "cancelled").Inc()
+		log.Printf("Worker %d: Job %s cancelled: %v", workerID, job.ID, err)
+		if cancelErr := wp.queue.Cancel(ctx, job); cancelErr != nil {
+			return apperrors.Wrap(cancelErr, apperrors.ErrorTypeInternal, "failed to mark job as cancelled")
+		}
+		wp.jobFinished(ctx, job)
+		return nil
+	}
 	if err != nil {
+		monitoring.WorkerJobsTotal.WithLabelValues(string(job.Type), "failed").Inc()
 		log.Printf("Worker %d: Job %s failed: %v", workerID, job.ID, err)
 		failErr := wp.queue.Fail(ctx, job, err.Error())
 		if failErr != nil {
 			return apperrors.Wrap(failErr, apperrors.ErrorTypeInternal, "failed to mark job as failed")
 		}
+		if job.Status == JobStatusFailed {
+			wp.jobFinished(ctx, job)
+		}
 		return err
 	}
 
+	monitoring.WorkerJobsTotal.WithLabelValues(string(job.Type), "completed").Inc()
+
 	// Mark job as completed
 	err = wp.queue.Complete(ctx, job)
 	if err != nil {
 		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to mark job as completed")
 	}
+	wp.jobFinished(ctx, job)
 
 	log.Printf("Worker %d: Job %s completed successfully", workerID, job.ID)
 	return nil
 }
 
+// jobFinished runs the finish hooks; a job stopped by shutdown still has them run
+func (wp *WorkerProcessor) jobFinished(ctx context.Context, job *Job) {
+	wp.mu.RLock()
+	hooks := wp.finishHooks
+	wp.mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		hook(ctx, job)
+	}
+}
+
 func (wp *WorkerProcessor) cleanupWorker(ctx context.Context) {
 	defer wp.wg.Done()
 
@@ -231,9 +338,48 @@ func (wp *WorkerProcessor) cleanupWorker(ctx context.Context) {
 			log.Printf("Cleanup worker context cancelled")
 			return
 		case <-wp.cleanupTicker.C:
-			err := wp.queue.CleanupStaleJobs(ctx, 30*time.Minute)
+			requeued, err := wp.queue.RequeueExpired(ctx)
 			if err != nil {
-				log.Printf("Failed to cleanup stale jobs: %v", err)
+				log.Printf("Failed to requeue expired jobs: %v", err)
+			} else if requeued > 0 {
+				log.Printf("Requeued %d jobs whose lease expired", requeued)
+			}
+		}
+	}
+}
+
+// heartbeat renews the job's lease every third of the visibility timeout until ctx
+// ends, and cancels the job with ErrJobCancelled once it is asked to stop
+func (wp *WorkerProcessor) heartbeat(ctx context.Context, job *Job, cancel context.CancelCauseFunc, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(wp.queue.VisibilityTimeout() / 3)
+	defer ticker.Stop()
+
+	var cancelPoll <-chan time.Time
+	if wp.progress != nil {
+		pollTicker := time.NewTicker(wp.cancelPoll)
+		defer pollTicker.Stop()
+		cancelPoll = pollTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := wp.queue.Extend(ctx, job); err != nil {
+				log.Printf("Failed to extend lease of job %s: %v", job.ID, err)
+			}
+		case <-cancelPoll:
+			requested, err := wp.progress.CancelRequested(ctx, job.ID)
+			if err != nil {
+				log.Printf("Failed to check cancellation of job %s: %v", job.ID, err)
+				continue
+			}
+			if requested {
+				log.Printf("Job %s was asked to stop, cancelling it", job.ID)
+				cancel(ErrJobCancelled)
 			}
 		}
 	}
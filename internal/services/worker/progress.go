@@ -0,0 +1,314 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// ErrJobCancelled is the cause of a job's context when the job was asked to stop.
+// Handlers notice it like any other cancellation, through ctx.Done().
+var ErrJobCancelled = errors.New("job cancelled")
+
+const (
+	defaultProgressKeyPrefix = "job_progress:"
+	// progressTTL is how long progress and cancellation requests outlive their last update
+	progressTTL = 24 * time.Hour
+)
+
+// Progress is what a running job last reported about itself
+type Progress struct {
+	Percent   float64          `json:"percent"`
+	Step      string           `json:"step,omitempty"`
+	Counters  map[string]int64 `json:"counters,omitempty"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// ProgressStore keeps the progress of running jobs and requests to cancel them in
+// Redis. Every update publishes a snapshot of the job, so watchers follow its
+// progress and final outcome live.
+type ProgressStore struct {
+	redis     *redis.Client
+	keyPrefix string
+}
+
+// NewProgressStore creates a progress store; workers and admins of a queue must share keyPrefix
+func NewProgressStore(redis *redis.Client, keyPrefix string) *ProgressStore {
+	if keyPrefix == "" {
+		keyPrefix = defaultProgressKeyPrefix
+	}
+	return &ProgressStore{
+		redis:     redis,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Publish stores the job's progress, if any, and sends the job to its watchers
+func (s *ProgressStore) Publish(ctx context.Context, job *Job) error {
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job")
+	}
+
+	pipe := s.redis.TxPipeline()
+	if job.Progress != nil {
+		progressData, err := json.Marshal(job.Progress)
+		if err != nil {
+			return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job progress")
+		}
+		pipe.Set(ctx, s.progressKey(job.ID), progressData, progressTTL)
+	}
+	pipe.Publish(ctx, s.channel(job.ID), jobData)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to publish progress of job %s", job.ID)
+	}
+	return nil
+}
+
+// Get returns the job's last progress, or nil when it reported none
+func (s *ProgressStore) Get(ctx context.Context, id string) (*Progress, error) {
+	progress, err := s.GetMany(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	return progress[id], nil
+}
+
+// GetMany returns the last progress of each job that reported any
+func (s *ProgressStore) GetMany(ctx context.Context, ids []string) (map[string]*Progress, error) {
+	result := make(map[string]*Progress, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.progressKey(id)
+	}
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to load job progress")
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var progress Progress
+		if err := json.Unmarshal([]byte(data), &progress); err != nil {
+			return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to unmarshal progress of job %s", ids[i])
+		}
+		result[ids[i]] = &progress
+	}
+	return result, nil
+}
+
+// Watch sends the snapshots published for the job until ctx ends
+func (s *ProgressStore) Watch(ctx context.Context, id string) (<-chan *Job, error) {
+	sub := s.redis.Subscribe(ctx, s.channel(id))
+	// Wait for the subscription so no snapshot published after Watch returns is missed
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to watch job %s", id)
+	}
+
+	jobs := make(chan *Job)
+	go func() {
+		defer close(jobs)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var job Job
+				if err := json.Unmarshal([]byte(msg.Payload), &job); err != nil {
+					log.Printf("Failed to unmarshal snapshot of job %s: %v", id, err)
+					continue
+				}
+				select {
+				case jobs <- &job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return jobs, nil
+}
+
+// RequestCancel asks the worker running the job to stop it
+func (s *ProgressStore) RequestCancel(ctx context.Context, id string) error {
+	if err := s.redis.Set(ctx, s.cancelKey(id), "1", progressTTL).Err(); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to request cancellation of job %s", id)
+	}
+	return nil
+}
+
+// CancelRequested reports whether the job was asked to stop
+func (s *ProgressStore) CancelRequested(ctx context.Context, id string) (bool, error) {
+	n, err := s.redis.Exists(ctx, s.cancelKey(id)).Result()
+	if err != nil {
+		return false, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to check cancellation of job %s", id)
+	}
+	return n > 0, nil
+}
+
+// ClearCancel forgets a cancellation request once the job stopped
+func (s *ProgressStore) ClearCancel(ctx context.Context, id string) error {
+	if err := s.redis.Del(ctx, s.cancelKey(id)).Err(); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to clear cancellation of job %s", id)
+	}
+	return nil
+}
+
+func (s *ProgressStore) progressKey(id string) string {
+	return s.keyPrefix + id
+}
+
+func (s *ProgressStore) cancelKey(id string) string {
+	return s.keyPrefix + id + ":cancel"
+}
+
+func (s *ProgressStore) channel(id string) string {
+	return s.keyPrefix + id + ":events"
+}
+
+// ProgressReporter lets a job handler report how far its job got. Reports are best
+// effort: a failed write is logged and never fails the job. A nil reporter drops them.
+type ProgressReporter struct {
+	store *ProgressStore
+	clock func() time.Time
+
+	mu       sync.Mutex
+	job      Job
+	progress Progress
+}
+
+type progressContextKey struct{}
+
+// ProgressFromContext returns the reporter of the job running with ctx, or nil when
+// the worker keeps no progress. Reporting to a nil reporter is a no-op.
+func ProgressFromContext(ctx context.Context) *ProgressReporter {
+	reporter, _ := ctx.Value(progressContextKey{}).(*ProgressReporter)
+	return reporter
+}
+
+func withProgress(ctx context.Context, reporter *ProgressReporter) context.Context {
+	if reporter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressContextKey{}, reporter)
+}
+
+func newProgressReporter(store *ProgressStore, job *Job, clock func() time.Time) *ProgressReporter {
+	return &ProgressReporter{
+		store:    store,
+		clock:    clock,
+		job:      *job,
+		progress: Progress{UpdatedAt: clock()},
+	}
+}
+
+// Step reports the step the job is on and how far along it is, in percent
+func (r *ProgressReporter) Step(ctx context.Context, step string, percent float64) {
+	r.update(ctx, func(p *Progress) {
+		p.Step = step
+		p.Percent = clampPercent(percent)
+	})
+}
+
+// Add adds delta to one of the job's counters
+func (r *ProgressReporter) Add(ctx context.Context, counter string, delta int64) {
+	r.update(ctx, func(p *Progress) {
+		if p.Counters == nil {
+			p.Counters = make(map[string]int64)
+		}
+		p.Counters[counter] += delta
+	})
+}
+
+// Progress returns what the job reported so far
+func (r *ProgressReporter) Progress() Progress {
+	if r == nil {
+		return Progress{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress.clone()
+}
+
+func (r *ProgressReporter) update(ctx context.Context, apply func(p *Progress)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	apply(&r.progress)
+	r.progress.UpdatedAt = r.clock()
+	snapshot := r.job
+	progress := r.progress.clone()
+	snapshot.Progress = &progress
+	r.mu.Unlock()
+
+	r.publish(ctx, &snapshot)
+}
+
+// start publishes the job as running, replacing the progress of an earlier attempt
+func (r *ProgressReporter) start(ctx context.Context) {
+	r.update(ctx, func(p *Progress) {})
+}
+
+// finish publishes the job's outcome with the progress it reached
+func (r *ProgressReporter) finish(ctx context.Context, job *Job) {
+	if r == nil {
+		return
+	}
+	snapshot := *job
+	progress := r.Progress()
+	snapshot.Progress = &progress
+	r.publish(ctx, &snapshot)
+
+	if err := r.store.ClearCancel(ctx, job.ID); err != nil {
+		log.Printf("Failed to clear cancellation of job %s: %v", job.ID, err)
+	}
+}
+
+func (r *ProgressReporter) publish(ctx context.Context, job *Job) {
+	// A cancelled job still reports where it stopped
+	if err := r.store.Publish(context.WithoutCancel(ctx), job); err != nil {
+		log.Printf("Failed to report progress of job %s: %v", job.ID, err)
+	}
+}
+
+func (p Progress) clone() Progress {
+	if p.Counters != nil {
+		counters := make(map[string]int64, len(p.Counters))
+		for name, value := range p.Counters {
+			counters[name] = value
+		}
+		p.Counters = counters
+	}
+	return p
+}
+
+func clampPercent(percent float64) float64 {
+	if percent < 0 {
+		return 0
+	}
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgressFromContext_WithoutReporter(t *testing.T) {
+	ctx := context.Background()
+	reporter := ProgressFromContext(ctx)
+	if reporter != nil {
+		t.Fatalf("ProgressFromContext() = %v, want nil", reporter)
+	}
+
+	// Handlers report unconditionally; without a store the reports are dropped
+	reporter.Step(ctx, "extracting", 50)
+	reporter.Add(ctx, "products", 1)
+	if got := reporter.Progress(); got.Percent != 0 || got.Counters != nil {
+		t.Errorf("Progress() of a nil reporter = %+v", got)
+	}
+}
+
+func TestProgress_CloneCopiesCounters(t *testing.T) {
+	progress := Progress{Percent: 10, Counters: map[string]int64{"pages": 1}, UpdatedAt: time.Unix(0, 0)}
+	clone := progress.clone()
+	clone.Counters["pages"] = 2
+
+	if progress.Counters["pages"] != 1 {
+		t.Errorf("clone shares counters with the original")
+	}
+}
+
+func TestClampPercent(t *testing.T) {
+	tests := []struct {
+		in, want float64
+	}{
+		{-5, 0},
+		{0, 0},
+		{42.5, 42.5},
+		{100, 100},
+		{150, 100},
+	}
+	for _, tt := range tests {
+		if got := clampPercent(tt.in); got != tt.want {
+			t.Errorf("clampPercent(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+)
+
+func TestProgressStore_PublishAndWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := redisTestClient(t)
+	store := worker.NewProgressStore(client, redisTestPrefix(t, client)+":")
+
+	if progress, err := store.Get(ctx, "job-1"); err != nil || progress != nil {
+		t.Fatalf("Get() before any report = %+v, %v, want nil", progress, err)
+	}
+
+	snapshots, err := store.Watch(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	job := &worker.Job{
+		ID:     "job-1",
+		Type:   worker.JobTypeExtractProducts,
+		Status: worker.JobStatusProcessing,
+		Progress: &worker.Progress{
+			Percent:  50,
+			Step:     "extracting",
+			Counters: map[string]int64{"products": 12},
+		},
+	}
+	if err := store.Publish(ctx, job); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	select {
+	case got := <-snapshots:
+		if got.ID != "job-1" || got.Status != worker.JobStatusProcessing || got.Progress == nil || got.Progress.Counters["products"] != 12 {
+			t.Errorf("Watch() sent %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() sent no snapshot")
+	}
+
+	progress, err := store.GetMany(ctx, []string{"job-1", "job-2"})
+	if err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+	if len(progress) != 1 || progress["job-1"].Step != "extracting" || progress["job-1"].Percent != 50 {
+		t.Errorf("GetMany() = %+v", progress)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-snapshots:
+		if ok {
+			t.Error("Watch() sent a snapshot after ctx ended")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() channel not closed after ctx ended")
+	}
+}
+
+func TestProgressStore_CancelRequests(t *testing.T) {
+	ctx := context.Background()
+	client := redisTestClient(t)
+	store := worker.NewProgressStore(client, redisTestPrefix(t, client)+":")
+
+	expectCancelRequested := func(want bool) {
+		t.Helper()
+		got, err := store.CancelRequested(ctx, "job-1")
+		if err != nil {
+			t.Fatalf("CancelRequested() error = %v", err)
+		}
+		if got != want {
+			t.Fatalf("CancelRequested() = %v, want %v", got, want)
+		}
+	}
+
+	expectCancelRequested(false)
+	if err := store.RequestCancel(ctx, "job-1"); err != nil {
+		t.Fatalf("RequestCancel() error = %v", err)
+	}
+	expectCancelRequested(true)
+	if err := store.ClearCancel(ctx, "job-1"); err != nil {
+		t.Fatalf("ClearCancel() error = %v", err)
+	}
+	expectCancelRequested(false)
+}
+
+func TestWorkerProcessor_ReportsProgressAndCancels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := redisTestClient(t)
+	prefix := redisTestPrefix(t, client)
+
+	queue := worker.NewPostgresQueue(setupQueueTestDB(t), worker.DefaultQueueName, worker.QueueOptions{})
+	store := worker.NewProgressStore(client, prefix+":progress:")
+	processor := worker.NewWorkerProcessor(queue, client, worker.ProcessorConfig{
+		Concurrency:        1,
+		LockKeyPrefix:      prefix + ":lock:",
+		ProgressKeyPrefix:  prefix + ":progress:",
+		CancelPollInterval: 10 * time.Millisecond,
+	})
+
+	started := make(chan struct{})
+	causes := make(chan error, 1)
+	processor.RegisterHandler(worker.JobTypeUpdatePrices, func(ctx context.Context, job *worker.Job) error {
+		progress := worker.ProgressFromContext(ctx)
+		progress.Step(ctx, "updating prices", 40)
+		progress.Add(ctx, "prices", 3)
+		close(started)
+
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	if err := queue.Enqueue(ctx, &worker.Job{ID: "long", Type: worker.JobTypeUpdatePrices}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	snapshots, err := store.Watch(ctx, "long")
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	if err := processor.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		cancel()
+		_ = processor.Stop()
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler never started")
+	}
+
+	progress, err := store.Get(ctx, "long")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if progress == nil || progress.Step != "updating prices" || progress.Percent != 40 || progress.Counters["prices"] != 3 {
+		t.Fatalf("Get() = %+v", progress)
+	}
+
+	if err := store.RequestCancel(ctx, "long"); err != nil {
+		t.Fatalf("RequestCancel() error = %v", err)
+	}
+	select {
+	case cause := <-causes:
+		if !errors.Is(cause, worker.ErrJobCancelled) {
+			t.Fatalf("handler context cause = %v, want ErrJobCancelled", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not cancelled")
+	}
+
+	// The last snapshot tells watchers the job was cancelled where it stopped
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case job := <-snapshots:
+			if job.Status != worker.JobStatusCancelled {
+				continue
+			}
+			if job.Progress == nil || job.Progress.Percent != 40 {
+				t.Errorf("final snapshot progress = %+v, want the reported 40%%", job.Progress)
+			}
+			done = true
+		case <-deadline:
+			t.Fatal("no snapshot of the cancelled job")
+		}
+	}
+
+	stats, err := queue.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if *stats != (worker.QueueStats{}) {
+		t.Errorf("Stats() after cancellation = %+v, want an empty queue", *stats)
+	}
+	if requested, err := store.CancelRequested(ctx, "long"); err != nil || requested {
+		t.Errorf("CancelRequested() after the job stopped = %v, %v, want false", requested, err)
+	}
+}
@@ -2,26 +2,55 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/config"
 	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
 )
 
 type JobType string
 
 const (
-	JobTypeScrapeFlyer     JobType = "scrape_flyer"
-	JobTypeExtractProducts JobType = "extract_products"
-	JobTypeUpdatePrices    JobType = "update_prices"
-	JobTypeArchiveData     JobType = "archive_data"
-	JobTypeCleanupData     JobType = "cleanup_data"
+	JobTypeScrapeFlyer              JobType = "scrape_flyer"
+	JobTypeExtractProducts          JobType = "extract_products"
+	JobTypeUpdatePrices             JobType = "update_prices"
+	JobTypeArchiveData              JobType = "archive_data"
+	JobTypeCleanupData              JobType = "cleanup_data"
+	JobTypeRefreshSearchSuggestions JobType = "refresh_search_suggestions"
+	JobTypeExpireFlyerItems         JobType = "expire_flyer_items"
+	JobTypeCleanupSessions          JobType = "cleanup_expired_sessions"
+	JobTypeMatchProducts            JobType = "match_products"
+	JobTypeMigrateShoppingLists     JobType = "migrate_shopping_lists"
+	JobTypeRasterizeFlyer           JobType = "rasterize_flyer"
+	JobTypeEvaluatePriceAlerts      JobType = "evaluate_price_alerts"
+	JobTypeRunWorkflow              JobType = "run_workflow"
+	JobTypeManagePartitions         JobType = "manage_partitions"
+	JobTypeRunCleanups              JobType = "run_cleanups"
 )
 
+// JobTypes lists every job type; the worker daemon refuses to start unless it handles all of them
+var JobTypes = []JobType{
+	JobTypeScrapeFlyer,
+	JobTypeExtractProducts,
+	JobTypeUpdatePrices,
+	JobTypeArchiveData,
+	JobTypeCleanupData,
+	JobTypeRefreshSearchSuggestions,
+	JobTypeExpireFlyerItems,
+	JobTypeCleanupSessions,
+	JobTypeMatchProducts,
+	JobTypeMigrateShoppingLists,
+	JobTypeRasterizeFlyer,
+	JobTypeEvaluatePriceAlerts,
+	JobTypeRunWorkflow,
+	JobTypeManagePartitions,
+	JobTypeRunCleanups,
+}
+
 type JobStatus string
 
 const (
@@ -30,6 +59,7 @@ const (
 	JobStatusCompleted  JobStatus = "completed"
 	JobStatusFailed     JobStatus = "failed"
 	JobStatusRetrying   JobStatus = "retrying"
+	JobStatusCancelled  JobStatus = "cancelled"
 )
 
 type Job struct {
@@ -47,272 +77,260 @@ type Job struct {
 	Attempts    int                    `json:"attempts"`
 	MaxAttempts int                    `json:"max_attempts"`
 	RetryDelay  time.Duration          `json:"retry_delay"`
-}
 
-type JobQueue struct {
-	redis         *redis.Client
-	queueName     string
-	processingSet string
-	deadLetterSet string
+	// Progress and CancelRequested come from the progress store, not the queue
+	Progress        *Progress `json:"progress,omitempty"`
+	CancelRequested bool      `json:"cancel_requested,omitempty"`
 }
 
-func NewJobQueue(redisClient *redis.Client, queueName string) *JobQueue {
-	return &JobQueue{
-		redis:         redisClient,
-		queueName:     queueName,
-		processingSet: queueName + ":processing",
-		deadLetterSet: queueName + ":dead_letter",
-	}
+// Queue backends selectable with worker.queue_backend
+const (
+	QueueBackendRedis    = "redis"
+	QueueBackendPostgres = "postgres"
+)
+
+const (
+	// DefaultMaxAttempts is used for jobs enqueued without MaxAttempts
+	DefaultMaxAttempts = 3
+	// DefaultRetryDelay is used for jobs enqueued without RetryDelay
+	DefaultRetryDelay = 30 * time.Second
+	// MaxRetryBackoff caps the exponential retry backoff
+	MaxRetryBackoff = time.Hour
+	// DefaultVisibilityTimeout is how long a dequeued job stays leased without a heartbeat
+	DefaultVisibilityTimeout = 5 * time.Minute
+	// defaultPollInterval is how often a blocked Dequeue checks for ready jobs
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+// errVisibilityTimeout is recorded on jobs whose lease expired while processing
+const errVisibilityTimeout = "visibility timeout expired"
+
+// Queue is a persistent job queue. Every backend has the same semantics:
+//
+//   - Dequeue returns the ready job with the highest priority, oldest first
+//     within a priority. A job is ready once its ScheduledAt has passed.
+//   - A dequeued job is leased for the visibility timeout and counts an attempt.
+//     Extend renews the lease; RequeueExpired fails jobs whose lease ran out.
+//   - Fail retries the job after RetryBackoff until MaxAttempts is reached, then
+//     moves it to the dead letters.
+//   - Enqueue is a no-op for a job ID that is still queued or processing, and
+//     starts a finished or dead job ID over.
+//   - Complete, Fail, Cancel and Extend return a not found error unless the job is
+//     processing. Cancel ends a job that stopped because it was asked to.
+//   - Completed and cancelled jobs leave the queue; GetJob and ListJobs only see
+//     queued, processing and dead jobs.
+//   - RetryJob runs a dead or waiting job now with fresh attempts, CancelJob
+//     drops a job that is not processing or dead, and jobs of a paused type stay
+//     queued until the type is resumed.
+type Queue interface {
+	Enqueue(ctx context.Context, job *Job) error
+	Dequeue(ctx context.Context, timeout time.Duration) (*Job, error)
+	Extend(ctx context.Context, job *Job) error
+	Complete(ctx context.Context, job *Job) error
+	Fail(ctx context.Context, job *Job, errorMsg string) error
+	Cancel(ctx context.Context, job *Job) error
+	RequeueExpired(ctx context.Context) (int, error)
+	DeadLetters(ctx context.Context, limit int) ([]*Job, error)
+	Stats(ctx context.Context) (*QueueStats, error)
+	VisibilityTimeout() time.Duration
+
+	// Administration
+	GetJob(ctx context.Context, id string) (*Job, error)
+	ListJobs(ctx context.Context, filter JobFilter) ([]*Job, error)
+	RetryJob(ctx context.Context, id string) (*Job, error)
+	CancelJob(ctx context.Context, id string) (*Job, error)
+	PauseJobType(ctx context.Context, jobType JobType) error
+	ResumeJobType(ctx context.Context, jobType JobType) error
+	PausedJobTypes(ctx context.Context) ([]JobType, error)
 }
 
-func (q *JobQueue) Enqueue(ctx context.Context, job *Job) error {
-	if job.ID == "" {
-		job.ID = uuid.New().String()
-	}
+// JobFilter selects the jobs returned by ListJobs, newest first. Empty fields match any job.
+type JobFilter struct {
+	Status JobStatus
+	Type   JobType
+	Limit  int
+	Offset int
+}
 
-	job.CreatedAt = time.Now()
-	job.UpdatedAt = time.Now()
-	job.Status = JobStatusPending
+const (
+	defaultJobListLimit = 50
+	maxJobListLimit     = 500
+)
 
-	if job.MaxAttempts == 0 {
-		job.MaxAttempts = 3
+func (f JobFilter) withDefaults() JobFilter {
+	if f.Limit <= 0 {
+		f.Limit = defaultJobListLimit
 	}
-
-	if job.RetryDelay == 0 {
-		job.RetryDelay = 30 * time.Second
+	if f.Limit > maxJobListLimit {
+		f.Limit = maxJobListLimit
 	}
-
-	jobData, err := json.Marshal(job)
-	if err != nil {
-		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job")
+	if f.Offset < 0 {
+		f.Offset = 0
 	}
+	return f
+}
 
-	// If job is scheduled, add to scheduled set
-	if job.ScheduledAt != nil && job.ScheduledAt.After(time.Now()) {
-		score := float64(job.ScheduledAt.Unix())
-		return q.redis.ZAdd(ctx, q.queueName+":scheduled", &redis.Z{
-			Score:  score,
-			Member: string(jobData),
-		}).Err()
+// QueueOptions configures a queue backend
+type QueueOptions struct {
+	VisibilityTimeout time.Duration
+	PollInterval      time.Duration
+	// Clock overrides time.Now, for tests
+	Clock func() time.Time
+}
+
+func (o QueueOptions) withDefaults() QueueOptions {
+	if o.VisibilityTimeout <= 0 {
+		o.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
 	}
+	if o.Clock == nil {
+		o.Clock = time.Now
+	}
+	return o
+}
 
-	// Add to priority queue (higher priority = lower score for ZPOPMIN)
-	score := float64(-job.Priority) // Negative for reverse ordering
-	return q.redis.ZAdd(ctx, q.queueName, &redis.Z{
-		Score:  score,
-		Member: string(jobData),
-	}).Err()
+// QueueStats counts the jobs of a queue by state
+type QueueStats struct {
+	Pending    int64 `json:"pending"`
+	Scheduled  int64 `json:"scheduled"`
+	Processing int64 `json:"processing"`
+	DeadLetter int64 `json:"dead_letter"`
 }
 
-func (q *JobQueue) Dequeue(ctx context.Context, timeout time.Duration) (*Job, error) {
-	// First check for scheduled jobs that are ready
-	err := q.moveScheduledJobs(ctx)
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to move scheduled jobs")
-	}
+// NewQueue creates the queue backend selected by the worker config. The Redis
+// backend needs redisClient and the Postgres backend needs db.
+func NewQueue(cfg config.WorkerConfig, redisClient *redis.Client, db *bun.DB, queueName string) (Queue, error) {
+	opts := QueueOptions{VisibilityTimeout: cfg.VisibilityTimeout}
 
-	// Pop job with highest priority
-	result, err := q.redis.BZPopMin(ctx, timeout, q.queueName).Result()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return nil, nil // No jobs available
+	switch cfg.QueueBackend {
+	case "", QueueBackendRedis:
+		if redisClient == nil {
+			return nil, apperrors.Validation("redis queue backend requires a Redis connection")
+		}
+		return NewRedisQueue(redisClient, queueName, opts), nil
+	case QueueBackendPostgres:
+		if db == nil {
+			return nil, apperrors.Validation("postgres queue backend requires a database connection")
 		}
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to dequeue job")
+		return NewPostgresQueue(db, queueName, opts), nil
+	default:
+		return nil, apperrors.ValidationF("unknown queue backend %q", cfg.QueueBackend)
 	}
+}
 
-	var job Job
-	err = json.Unmarshal([]byte(result.Member.(string)), &job)
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to unmarshal job")
+// RetryBackoff is the delay before retrying a job that failed its attempts-th
+// attempt: the job's RetryDelay doubled per earlier attempt, capped at MaxRetryBackoff
+func RetryBackoff(attempts int, retryDelay time.Duration) time.Duration {
+	if retryDelay <= 0 {
+		retryDelay = DefaultRetryDelay
 	}
-
-	// Move to processing set
-	job.Status = JobStatusProcessing
-	job.StartedAt = &time.Time{}
-	*job.StartedAt = time.Now()
-	job.UpdatedAt = time.Now()
-
-	jobData, err := json.Marshal(job)
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal processing job")
+	delay := retryDelay
+	for i := 1; i < attempts && delay < MaxRetryBackoff; i++ {
+		delay *= 2
 	}
-
-	err = q.redis.SAdd(ctx, q.processingSet, string(jobData)).Err()
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to add job to processing set")
+	if delay > MaxRetryBackoff {
+		delay = MaxRetryBackoff
 	}
-
-	return &job, nil
+	return delay
 }
 
-func (q *JobQueue) Complete(ctx context.Context, job *Job) error {
-	job.Status = JobStatusCompleted
-	job.CompletedAt = &time.Time{}
-	*job.CompletedAt = time.Now()
-	job.UpdatedAt = time.Now()
-
-	jobData, err := json.Marshal(job)
-	if err != nil {
-		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal completed job")
+// prepareEnqueue validates a job and resets it to a fresh pending job
+func prepareEnqueue(job *Job, now time.Time) error {
+	if job.Type == "" {
+		return apperrors.Validation("job type is required")
+	}
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.RetryDelay <= 0 {
+		job.RetryDelay = DefaultRetryDelay
+	}
+	if job.ScheduledAt != nil && !job.ScheduledAt.After(now) {
+		job.ScheduledAt = nil
 	}
 
-	// Remove from processing set
-	return q.redis.SRem(ctx, q.processingSet, string(jobData)).Err()
+	job.Status = JobStatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.StartedAt = nil
+	job.CompletedAt = nil
+	job.Error = ""
+	job.Attempts = 0
+	return nil
 }
 
-func (q *JobQueue) Fail(ctx context.Context, job *Job, errorMsg string) error {
+// prepareFailure records a failed attempt on the job and reports whether it is
+// retried, scheduling the retry, or dead
+func prepareFailure(job *Job, errorMsg string, now time.Time) bool {
 	job.Error = errorMsg
-	job.Attempts++
-	job.UpdatedAt = time.Now()
+	job.UpdatedAt = now
+	job.StartedAt = nil
 
-	// Check if we should retry
 	if job.Attempts < job.MaxAttempts {
+		retryAt := now.Add(RetryBackoff(job.Attempts, job.RetryDelay))
 		job.Status = JobStatusRetrying
-
-		// Schedule retry with exponential backoff
-		delay := time.Duration(job.Attempts) * job.RetryDelay
-		retryAt := time.Now().Add(delay)
 		job.ScheduledAt = &retryAt
-
-		jobData, err := json.Marshal(job)
-		if err != nil {
-			return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal retry job")
-		}
-
-		// Remove from processing set
-		oldJobData, _ := json.Marshal(&Job{
-			ID:     job.ID,
-			Status: JobStatusProcessing,
-		})
-		q.redis.SRem(ctx, q.processingSet, string(oldJobData))
-
-		// Add to scheduled set for retry
-		score := float64(job.ScheduledAt.Unix())
-		return q.redis.ZAdd(ctx, q.queueName+":scheduled", &redis.Z{
-			Score:  score,
-			Member: string(jobData),
-		}).Err()
+		return true
 	}
 
-	// Move to dead letter queue
 	job.Status = JobStatusFailed
-	jobData, err := json.Marshal(job)
-	if err != nil {
-		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal failed job")
-	}
-
-	// Remove from processing set
-	oldJobData, _ := json.Marshal(&Job{
-		ID:     job.ID,
-		Status: JobStatusProcessing,
-	})
-	q.redis.SRem(ctx, q.processingSet, string(oldJobData))
-
-	// Add to dead letter set
-	return q.redis.SAdd(ctx, q.deadLetterSet, string(jobData)).Err()
+	job.ScheduledAt = nil
+	job.CompletedAt = &now
+	return false
 }
 
-func (q *JobQueue) GetQueueStats(ctx context.Context) (map[string]int64, error) {
-	pending, err := q.redis.ZCard(ctx, q.queueName).Result()
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get pending count")
-	}
-
-	processing, err := q.redis.SCard(ctx, q.processingSet).Result()
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get processing count")
-	}
-
-	scheduled, err := q.redis.ZCard(ctx, q.queueName+":scheduled").Result()
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get scheduled count")
-	}
-
-	deadLetter, err := q.redis.SCard(ctx, q.deadLetterSet).Result()
-	if err != nil {
-		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get dead letter count")
-	}
-
-	return map[string]int64{
-		"pending":     pending,
-		"processing":  processing,
-		"scheduled":   scheduled,
-		"dead_letter": deadLetter,
-	}, nil
+// prepareRetry resets a job to run now with fresh attempts
+func prepareRetry(job *Job, now time.Time) {
+	job.Status = JobStatusPending
+	job.Attempts = 0
+	job.Error = ""
+	job.ScheduledAt = nil
+	job.StartedAt = nil
+	job.CompletedAt = nil
+	job.UpdatedAt = now
 }
 
-func (q *JobQueue) moveScheduledJobs(ctx context.Context) error {
-	now := time.Now().Unix()
-
-	// Get jobs that are ready to be processed
-	jobs, err := q.redis.ZRangeByScore(ctx, q.queueName+":scheduled", &redis.ZRangeBy{
-		Min: "0",
-		Max: fmt.Sprintf("%d", now),
-	}).Result()
-	if err != nil {
-		return err
-	}
-
-	if len(jobs) == 0 {
-		return nil
-	}
-
-	// Move jobs to main queue
-	pipe := q.redis.Pipeline()
-	for _, jobStr := range jobs {
-		var job Job
-		if err := json.Unmarshal([]byte(jobStr), &job); err != nil {
-			continue
+// waitForJob polls dequeue until it returns a job, the timeout passes or ctx ends
+func waitForJob(ctx context.Context, timeout, pollInterval time.Duration, dequeue func(ctx context.Context) (*Job, error)) (*Job, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := dequeue(ctx)
+		if err != nil || job != nil {
+			return job, err
 		}
 
-		// Update job status
-		job.Status = JobStatusPending
-		job.ScheduledAt = nil
-		job.UpdatedAt = time.Now()
-
-		jobData, err := json.Marshal(job)
-		if err != nil {
-			continue
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil
+		}
+		wait := pollInterval
+		if remaining < wait {
+			wait = remaining
 		}
 
-		// Add to main queue with priority
-		score := float64(-job.Priority)
-		pipe.ZAdd(ctx, q.queueName, &redis.Z{
-			Score:  score,
-			Member: string(jobData),
-		})
-
-		// Remove from scheduled set
-		pipe.ZRem(ctx, q.queueName+":scheduled", jobStr)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil
+		case <-timer.C:
+		}
 	}
-
-	_, err = pipe.Exec(ctx)
-	return err
 }
 
-func (q *JobQueue) CleanupStaleJobs(ctx context.Context, timeout time.Duration) error {
-	// Find jobs that have been processing for too long
-	cutoff := time.Now().Add(-timeout)
-
-	members, err := q.redis.SMembers(ctx, q.processingSet).Result()
-	if err != nil {
-		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get processing jobs")
-	}
-
-	for _, member := range members {
-		var job Job
-		if err := json.Unmarshal([]byte(member), &job); err != nil {
-			continue
-		}
+func jobNotProcessing(job *Job) error {
+	return apperrors.NotFound(fmt.Sprintf("job %s is not processing", job.ID))
+}
 
-		if job.StartedAt != nil && job.StartedAt.Before(cutoff) {
-			// Job has been processing too long, move back to queue for retry
-			err := q.Fail(ctx, &job, "job processing timeout")
-			if err != nil {
-				return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to fail stale job %s", job.ID)
-			}
-		}
-	}
+func jobNotFound(id string) error {
+	return apperrors.NotFound(fmt.Sprintf("job %s not found", id))
+}
 
-	return nil
+func jobInState(job *Job, action string) error {
+	return apperrors.Conflict(fmt.Sprintf("cannot %s job %s while it is %s", action, job.ID, job.Status))
 }
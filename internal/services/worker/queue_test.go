@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/config"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		retryDelay time.Duration
+		want       time.Duration
+	}{
+		{"first failure waits the retry delay", 1, time.Minute, time.Minute},
+		{"second failure doubles", 2, time.Minute, 2 * time.Minute},
+		{"third failure doubles again", 3, time.Minute, 4 * time.Minute},
+		{"capped", 10, time.Minute, MaxRetryBackoff},
+		{"delay above the cap", 1, 2 * time.Hour, MaxRetryBackoff},
+		{"default delay", 1, 0, DefaultRetryDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryBackoff(tt.attempts, tt.retryDelay); got != tt.want {
+				t.Errorf("RetryBackoff(%d, %v) = %v, want %v", tt.attempts, tt.retryDelay, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewQueue_SelectsBackend(t *testing.T) {
+	if _, err := NewQueue(configFor(QueueBackendRedis), nil, nil, DefaultQueueName); err == nil {
+		t.Error("redis backend without a Redis client should fail")
+	}
+	if _, err := NewQueue(configFor(QueueBackendPostgres), nil, nil, DefaultQueueName); err == nil {
+		t.Error("postgres backend without a database should fail")
+	}
+	if _, err := NewQueue(configFor("kafka"), nil, nil, DefaultQueueName); err == nil {
+		t.Error("unknown backend should fail")
+	}
+}
+
+func configFor(backend string) config.WorkerConfig {
+	return config.WorkerConfig{QueueBackend: backend}
+}
@@ -0,0 +1,752 @@
+// Package queuetest is the conformance suite every worker.Queue backend must pass.
+package queuetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// Factory creates an empty queue with the given options
+type Factory func(t *testing.T, opts worker.QueueOptions) worker.Queue
+
+const visibilityTimeout = time.Minute
+
+// noWait is the Dequeue timeout of the suite; it only has to cover one poll
+const noWait = 10 * time.Millisecond
+
+// Run runs the conformance suite against the backend built by newQueue
+func Run(t *testing.T, newQueue Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, q worker.Queue, clock *Clock)
+	}{
+		{"DequeueEmpty", testDequeueEmpty},
+		{"PriorityOrder", testPriorityOrder},
+		{"FIFOWithinPriority", testFIFOWithinPriority},
+		{"ConcurrentDequeueLeasesOnce", testConcurrentDequeue},
+		{"ScheduledJobWaitsUntilDue", testScheduledJob},
+		{"PayloadRoundTrip", testPayloadRoundTrip},
+		{"CompleteRemovesJob", testComplete},
+		{"CancelRemovesRunningJob", testCancelRunningJob},
+		{"RetryBackoffThenDeadLetter", testRetryBackoff},
+		{"RetryKeepsPriority", testRetryKeepsPriority},
+		{"VisibilityTimeoutRequeues", testVisibilityTimeout},
+		{"ExtendRenewsLease", testExtend},
+		{"EnqueueIsIdempotent", testIdempotentEnqueue},
+		{"DeadJobCanBeEnqueuedAgain", testReenqueueDeadJob},
+		{"Stats", testStats},
+		{"GetJob", testGetJob},
+		{"ListJobsFiltersAndPages", testListJobs},
+		{"RetryDeadJob", testRetryDeadJob},
+		{"RetryScheduledJobRunsNow", testRetryScheduledJob},
+		{"RetryProcessingJobConflicts", testRetryProcessingJob},
+		{"CancelPendingJob", testCancelJob},
+		{"CancelProcessingJobConflicts", testCancelProcessingJob},
+		{"PauseAndResumeJobType", testPauseJobType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewClock(time.Date(2026, time.January, 5, 12, 0, 0, 0, time.UTC))
+			q := newQueue(t, worker.QueueOptions{
+				VisibilityTimeout: visibilityTimeout,
+				PollInterval:      time.Millisecond,
+				Clock:             clock.Now,
+			})
+			tt.run(t, q, clock)
+		})
+	}
+}
+
+// Clock is a manually advanced clock
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a clock stopped at now
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the clock's time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func testDequeueEmpty(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	if job := dequeue(t, q); job != nil {
+		t.Fatalf("Dequeue() = %s, want no job", job.ID)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	job, err := q.Dequeue(cancelled, time.Second)
+	if err != nil || job != nil {
+		t.Fatalf("Dequeue() with cancelled context = %v, %v, want no job", job, err)
+	}
+}
+
+func testPriorityOrder(t *testing.T, q worker.Queue, clock *Clock) {
+	enqueue(t, q, &worker.Job{ID: "low", Type: worker.JobTypeUpdatePrices, Priority: 1})
+	enqueue(t, q, &worker.Job{ID: "high", Type: worker.JobTypeUpdatePrices, Priority: 9})
+	enqueue(t, q, &worker.Job{ID: "mid", Type: worker.JobTypeUpdatePrices, Priority: 5})
+
+	expectOrder(t, q, "high", "mid", "low")
+}
+
+func testFIFOWithinPriority(t *testing.T, q worker.Queue, clock *Clock) {
+	for _, id := range []string{"first", "second", "third"} {
+		enqueue(t, q, &worker.Job{ID: id, Type: worker.JobTypeUpdatePrices, Priority: 5})
+		clock.Advance(time.Millisecond)
+	}
+
+	expectOrder(t, q, "first", "second", "third")
+}
+
+func testConcurrentDequeue(t *testing.T, q worker.Queue, clock *Clock) {
+	const jobs, consumers = 20, 4
+	for i := 0; i < jobs; i++ {
+		enqueue(t, q, &worker.Job{ID: fmt.Sprintf("job-%d", i), Type: worker.JobTypeUpdatePrices, Priority: 5})
+	}
+
+	var (
+		mu       sync.Mutex
+		leased   = map[string]int{}
+		wg       sync.WaitGroup
+		start    = make(chan struct{})
+		failures = make(chan error, consumers)
+	)
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for {
+				job, err := q.Dequeue(context.Background(), noWait)
+				if err != nil {
+					failures <- err
+					return
+				}
+				if job == nil {
+					return
+				}
+				mu.Lock()
+				leased[job.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(failures)
+
+	for err := range failures {
+		t.Errorf("concurrent Dequeue() error = %v", err)
+	}
+	for id, n := range leased {
+		if n > 1 {
+			t.Errorf("job %s was leased %d times", id, n)
+		}
+	}
+	if len(leased) != jobs {
+		t.Errorf("consumers leased %d jobs, want %d", len(leased), jobs)
+	}
+}
+
+func testScheduledJob(t *testing.T, q worker.Queue, clock *Clock) {
+	runAt := clock.Now().Add(time.Hour)
+	enqueue(t, q, &worker.Job{ID: "later", Type: worker.JobTypeUpdatePrices, Priority: 9, ScheduledAt: &runAt})
+	enqueue(t, q, &worker.Job{ID: "now", Type: worker.JobTypeUpdatePrices, Priority: 1})
+
+	expectOrder(t, q, "now")
+
+	clock.Advance(time.Hour - time.Millisecond)
+	if job := dequeue(t, q); job != nil {
+		t.Fatalf("Dequeue() = %s before its scheduled time", job.ID)
+	}
+
+	clock.Advance(time.Millisecond)
+	expectOrder(t, q, "later")
+}
+
+func testPayloadRoundTrip(t *testing.T, q worker.Queue, clock *Clock) {
+	enqueue(t, q, worker.NewExtractProductsJob(12, 345))
+
+	job := dequeue(t, q)
+	if job == nil {
+		t.Fatal("Dequeue() returned no job")
+	}
+	if job.Type != worker.JobTypeExtractProducts || job.Priority != 5 {
+		t.Errorf("Dequeue() = %s priority %d", job.Type, job.Priority)
+	}
+	if job.Status != worker.JobStatusProcessing || job.Attempts != 1 || job.MaxAttempts != 3 {
+		t.Errorf("Dequeue() status %s attempts %d/%d, want processing 1/3", job.Status, job.Attempts, job.MaxAttempts)
+	}
+	if job.RetryDelay != time.Minute {
+		t.Errorf("RetryDelay = %v, want 1m", job.RetryDelay)
+	}
+	if pageID, ok := job.PayloadInt(worker.PayloadFlyerPageID); !ok || pageID != 345 {
+		t.Errorf("PayloadInt(%s) = %d, %v, want 345", worker.PayloadFlyerPageID, pageID, ok)
+	}
+	if flyerID, ok := job.PayloadInt(worker.PayloadFlyerID); !ok || flyerID != 12 {
+		t.Errorf("PayloadInt(%s) = %d, %v, want 12", worker.PayloadFlyerID, flyerID, ok)
+	}
+}
+
+func testComplete(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "done", Type: worker.JobTypeUpdatePrices})
+
+	job := dequeue(t, q)
+	if err := q.Complete(ctx, job); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if job.Status != worker.JobStatusCompleted || job.CompletedAt == nil {
+		t.Errorf("Complete() left status %s, completed at %v", job.Status, job.CompletedAt)
+	}
+
+	if next := dequeue(t, q); next != nil {
+		t.Fatalf("Dequeue() = %s after completion", next.ID)
+	}
+	expectNotFound(t, "Complete() of a completed job", q.Complete(ctx, job))
+	expectNotFound(t, "Fail() of a completed job", q.Fail(ctx, job, "boom"))
+	expectNotFound(t, "Extend() of a completed job", q.Extend(ctx, job))
+	expectStats(t, q, worker.QueueStats{})
+}
+
+func testCancelRunningJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "stopped", Type: worker.JobTypeUpdatePrices})
+
+	expectNotFound(t, "Cancel() of a pending job", q.Cancel(ctx, &worker.Job{ID: "stopped"}))
+
+	job := dequeue(t, q)
+	if err := q.Cancel(ctx, job); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if job.Status != worker.JobStatusCancelled || job.CompletedAt == nil {
+		t.Errorf("Cancel() left job %+v", job)
+	}
+	expectStats(t, q, worker.QueueStats{})
+	expectNotFound(t, "Cancel() of a cancelled job", q.Cancel(ctx, job))
+
+	_, err := q.GetJob(ctx, "stopped")
+	expectNotFound(t, "GetJob() of a cancelled job", err)
+	enqueue(t, q, &worker.Job{ID: "stopped", Type: worker.JobTypeUpdatePrices})
+	expectOrder(t, q, "stopped")
+}
+
+func testRetryBackoff(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "flaky", Type: worker.JobTypeUpdatePrices, MaxAttempts: 3, RetryDelay: time.Minute})
+
+	// Attempts are retried after 1m, then 2m, then the job is dead
+	for attempt, backoff := range []time.Duration{time.Minute, 2 * time.Minute} {
+		job := dequeue(t, q)
+		if job == nil || job.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: Dequeue() = %+v", attempt+1, job)
+		}
+		if err := q.Fail(ctx, job, fmt.Sprintf("failure %d", attempt+1)); err != nil {
+			t.Fatalf("attempt %d: Fail() error = %v", attempt+1, err)
+		}
+		if job.Status != worker.JobStatusRetrying {
+			t.Errorf("attempt %d: Fail() left status %s, want retrying", attempt+1, job.Status)
+		}
+		expectStats(t, q, worker.QueueStats{Scheduled: 1})
+
+		clock.Advance(backoff - time.Millisecond)
+		if early := dequeue(t, q); early != nil {
+			t.Fatalf("attempt %d: job retried before its backoff", attempt+1)
+		}
+		clock.Advance(time.Millisecond)
+	}
+
+	job := dequeue(t, q)
+	if job == nil || job.Attempts != 3 {
+		t.Fatalf("last attempt: Dequeue() = %+v", job)
+	}
+	if job.Error != "failure 2" {
+		t.Errorf("retried job error = %q, want the last failure", job.Error)
+	}
+	if err := q.Fail(ctx, job, "failure 3"); err != nil {
+		t.Fatalf("last attempt: Fail() error = %v", err)
+	}
+	if job.Status != worker.JobStatusFailed {
+		t.Errorf("Fail() of the last attempt left status %s, want failed", job.Status)
+	}
+
+	clock.Advance(time.Hour)
+	if next := dequeue(t, q); next != nil {
+		t.Fatalf("dead job %s was dequeued", next.ID)
+	}
+	expectStats(t, q, worker.QueueStats{DeadLetter: 1})
+
+	dead, err := q.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("DeadLetters() error = %v", err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("DeadLetters() returned %d jobs, want 1", len(dead))
+	}
+	if dead[0].ID != "flaky" || dead[0].Status != worker.JobStatusFailed || dead[0].Attempts != 3 || dead[0].Error != "failure 3" {
+		t.Errorf("DeadLetters()[0] = %+v", dead[0])
+	}
+}
+
+func testRetryKeepsPriority(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "urgent", Type: worker.JobTypeUpdatePrices, Priority: 9, RetryDelay: time.Minute})
+
+	job := dequeue(t, q)
+	if err := q.Fail(ctx, job, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	enqueue(t, q, &worker.Job{ID: "routine", Type: worker.JobTypeUpdatePrices, Priority: 1})
+
+	clock.Advance(time.Minute)
+	expectOrder(t, q, "urgent", "routine")
+}
+
+func testVisibilityTimeout(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "stuck", Type: worker.JobTypeUpdatePrices, RetryDelay: time.Minute})
+
+	job := dequeue(t, q)
+	if job == nil {
+		t.Fatal("Dequeue() returned no job")
+	}
+	if q.VisibilityTimeout() != visibilityTimeout {
+		t.Errorf("VisibilityTimeout() = %v, want %v", q.VisibilityTimeout(), visibilityTimeout)
+	}
+
+	clock.Advance(visibilityTimeout)
+	expectRequeued(t, q, 0)
+
+	clock.Advance(time.Millisecond)
+	expectRequeued(t, q, 1)
+	expectStats(t, q, worker.QueueStats{Scheduled: 1})
+
+	// The expired lease no longer belongs to the first worker
+	expectNotFound(t, "Complete() after the lease expired", q.Complete(ctx, job))
+
+	clock.Advance(time.Minute)
+	retried := dequeue(t, q)
+	if retried == nil || retried.ID != "stuck" || retried.Attempts != 2 {
+		t.Fatalf("Dequeue() after requeue = %+v", retried)
+	}
+	if retried.Error == "" {
+		t.Error("requeued job has no error recorded")
+	}
+}
+
+func testExtend(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "slow", Type: worker.JobTypeUpdatePrices})
+
+	job := dequeue(t, q)
+	for i := 0; i < 3; i++ {
+		clock.Advance(visibilityTimeout - time.Second)
+		if err := q.Extend(ctx, job); err != nil {
+			t.Fatalf("Extend() error = %v", err)
+		}
+	}
+
+	clock.Advance(visibilityTimeout - time.Second)
+	expectRequeued(t, q, 0)
+	expectStats(t, q, worker.QueueStats{Processing: 1})
+
+	if err := q.Complete(ctx, job); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+}
+
+func testIdempotentEnqueue(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "once", Type: worker.JobTypeUpdatePrices})
+	enqueue(t, q, &worker.Job{ID: "once", Type: worker.JobTypeUpdatePrices})
+	expectStats(t, q, worker.QueueStats{Pending: 1})
+
+	job := dequeue(t, q)
+	if job == nil {
+		t.Fatal("Dequeue() returned no job")
+	}
+
+	// Enqueueing a processing job does not queue it a second time
+	enqueue(t, q, &worker.Job{ID: "once", Type: worker.JobTypeUpdatePrices})
+	expectStats(t, q, worker.QueueStats{Processing: 1})
+
+	if err := q.Complete(ctx, job); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	// A finished job ID starts over
+	enqueue(t, q, &worker.Job{ID: "once", Type: worker.JobTypeUpdatePrices})
+	again := dequeue(t, q)
+	if again == nil || again.ID != "once" || again.Attempts != 1 {
+		t.Fatalf("Dequeue() after re-enqueue = %+v", again)
+	}
+}
+
+func testReenqueueDeadJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "dead", Type: worker.JobTypeUpdatePrices, MaxAttempts: 1})
+
+	job := dequeue(t, q)
+	if err := q.Fail(ctx, job, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	expectStats(t, q, worker.QueueStats{DeadLetter: 1})
+
+	enqueue(t, q, &worker.Job{ID: "dead", Type: worker.JobTypeUpdatePrices, MaxAttempts: 1})
+	expectStats(t, q, worker.QueueStats{Pending: 1})
+
+	dead, err := q.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("DeadLetters() error = %v", err)
+	}
+	if len(dead) != 0 {
+		t.Errorf("DeadLetters() = %d jobs after re-enqueue, want 0", len(dead))
+	}
+
+	again := dequeue(t, q)
+	if again == nil || again.Attempts != 1 || again.Error != "" {
+		t.Fatalf("Dequeue() after re-enqueue = %+v", again)
+	}
+}
+
+func testStats(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	later := clock.Now().Add(time.Hour)
+
+	enqueue(t, q, &worker.Job{ID: "a", Type: worker.JobTypeUpdatePrices})
+	enqueue(t, q, &worker.Job{ID: "b", Type: worker.JobTypeUpdatePrices})
+	enqueue(t, q, &worker.Job{ID: "c", Type: worker.JobTypeUpdatePrices, ScheduledAt: &later})
+	enqueue(t, q, &worker.Job{ID: "d", Type: worker.JobTypeUpdatePrices, MaxAttempts: 1})
+	expectStats(t, q, worker.QueueStats{Pending: 3, Scheduled: 1})
+
+	processing := dequeue(t, q)
+	expectStats(t, q, worker.QueueStats{Pending: 2, Scheduled: 1, Processing: 1})
+
+	if err := q.Complete(ctx, processing); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	expectOrder(t, q, "b")
+	dead := dequeue(t, q)
+	if dead == nil || dead.ID != "d" {
+		t.Fatalf("Dequeue() = %+v, want d", dead)
+	}
+	if err := q.Fail(ctx, dead, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	expectStats(t, q, worker.QueueStats{Scheduled: 1, Processing: 1, DeadLetter: 1})
+
+	// A scheduled job counts as pending once it is due
+	clock.Advance(time.Hour)
+	expectStats(t, q, worker.QueueStats{Pending: 1, Processing: 1, DeadLetter: 1})
+}
+
+func testGetJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "inspect", Type: worker.JobTypeUpdatePrices, Payload: map[string]interface{}{"store": "maxima"}, MaxAttempts: 1})
+
+	job, err := q.GetJob(ctx, "inspect")
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if job.Status != worker.JobStatusPending || job.Payload["store"] != "maxima" {
+		t.Errorf("GetJob() = %+v", job)
+	}
+
+	running := dequeue(t, q)
+	if err := q.Fail(ctx, running, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	job, err = q.GetJob(ctx, "inspect")
+	if err != nil {
+		t.Fatalf("GetJob() of a dead job error = %v", err)
+	}
+	if job.Status != worker.JobStatusFailed || job.Error != "boom" || job.Attempts != 1 {
+		t.Errorf("GetJob() of a dead job = %+v", job)
+	}
+
+	_, err = q.GetJob(ctx, "missing")
+	expectNotFound(t, "GetJob() of an unknown job", err)
+}
+
+func testListJobs(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	for _, job := range []*worker.Job{
+		{ID: "a", Type: worker.JobTypeUpdatePrices},
+		{ID: "b", Type: worker.JobTypeScrapeFlyer},
+		{ID: "c", Type: worker.JobTypeUpdatePrices},
+		{ID: "d", Type: worker.JobTypeUpdatePrices, MaxAttempts: 1},
+	} {
+		enqueue(t, q, job)
+		clock.Advance(time.Second)
+	}
+	expectOrder(t, q, "a", "b", "c")
+	dead := dequeue(t, q)
+	if err := q.Fail(ctx, dead, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	running, err := q.GetJob(ctx, "a")
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if err := q.Complete(ctx, running); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter worker.JobFilter
+		want   []string
+	}{
+		{"all queued jobs newest first", worker.JobFilter{}, []string{"d", "c", "b"}},
+		{"by status", worker.JobFilter{Status: worker.JobStatusFailed}, []string{"d"}},
+		{"by type", worker.JobFilter{Type: worker.JobTypeUpdatePrices}, []string{"d", "c"}},
+		{"by status and type", worker.JobFilter{Status: worker.JobStatusProcessing, Type: worker.JobTypeScrapeFlyer}, []string{"b"}},
+		{"first page", worker.JobFilter{Limit: 2}, []string{"d", "c"}},
+		{"second page", worker.JobFilter{Limit: 2, Offset: 2}, []string{"b"}},
+		{"past the end", worker.JobFilter{Offset: 3}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := q.ListJobs(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListJobs() error = %v", err)
+			}
+			got := make([]string, len(jobs))
+			for i, job := range jobs {
+				got[i] = job.ID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ListJobs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testRetryDeadJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "dead", Type: worker.JobTypeUpdatePrices, MaxAttempts: 1})
+
+	job := dequeue(t, q)
+	if err := q.Fail(ctx, job, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	retried, err := q.RetryJob(ctx, "dead")
+	if err != nil {
+		t.Fatalf("RetryJob() error = %v", err)
+	}
+	if retried.Status != worker.JobStatusPending || retried.Attempts != 0 || retried.Error != "" {
+		t.Errorf("RetryJob() = %+v", retried)
+	}
+	expectStats(t, q, worker.QueueStats{Pending: 1})
+
+	again := dequeue(t, q)
+	if again == nil || again.ID != "dead" || again.Attempts != 1 {
+		t.Fatalf("Dequeue() after retry = %+v", again)
+	}
+}
+
+func testRetryScheduledJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	later := clock.Now().Add(time.Hour)
+	enqueue(t, q, &worker.Job{ID: "later", Type: worker.JobTypeUpdatePrices, ScheduledAt: &later})
+	enqueue(t, q, &worker.Job{ID: "ready", Type: worker.JobTypeUpdatePrices})
+	clock.Advance(time.Second)
+
+	if _, err := q.RetryJob(ctx, "later"); err != nil {
+		t.Fatalf("RetryJob() error = %v", err)
+	}
+	// Retrying a job that is already ready leaves it where it is
+	if _, err := q.RetryJob(ctx, "ready"); err != nil {
+		t.Fatalf("RetryJob() of a ready job error = %v", err)
+	}
+	expectStats(t, q, worker.QueueStats{Pending: 2})
+	expectOrder(t, q, "ready", "later")
+}
+
+func testRetryProcessingJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "busy", Type: worker.JobTypeUpdatePrices})
+	dequeue(t, q)
+
+	_, err := q.RetryJob(ctx, "busy")
+	expectConflict(t, "RetryJob() of a processing job", err)
+	_, err = q.RetryJob(ctx, "missing")
+	expectNotFound(t, "RetryJob() of an unknown job", err)
+}
+
+func testCancelJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	later := clock.Now().Add(time.Hour)
+	enqueue(t, q, &worker.Job{ID: "now", Type: worker.JobTypeUpdatePrices})
+	enqueue(t, q, &worker.Job{ID: "later", Type: worker.JobTypeUpdatePrices, ScheduledAt: &later})
+
+	for _, id := range []string{"now", "later"} {
+		cancelled, err := q.CancelJob(ctx, id)
+		if err != nil {
+			t.Fatalf("CancelJob(%s) error = %v", id, err)
+		}
+		if cancelled.Status != worker.JobStatusCancelled || cancelled.CompletedAt == nil {
+			t.Errorf("CancelJob(%s) = %+v", id, cancelled)
+		}
+	}
+	expectStats(t, q, worker.QueueStats{})
+
+	clock.Advance(time.Hour)
+	if job := dequeue(t, q); job != nil {
+		t.Fatalf("cancelled job %s was dequeued", job.ID)
+	}
+	_, err := q.GetJob(ctx, "now")
+	expectNotFound(t, "GetJob() of a cancelled job", err)
+	_, err = q.CancelJob(ctx, "now")
+	expectNotFound(t, "CancelJob() of a cancelled job", err)
+
+	// A cancelled job ID can be enqueued again
+	enqueue(t, q, &worker.Job{ID: "now", Type: worker.JobTypeUpdatePrices})
+	expectOrder(t, q, "now")
+}
+
+func testCancelProcessingJob(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "busy", Type: worker.JobTypeUpdatePrices})
+	enqueue(t, q, &worker.Job{ID: "dead", Type: worker.JobTypeUpdatePrices, MaxAttempts: 1})
+	dequeue(t, q)
+	dead := dequeue(t, q)
+	if err := q.Fail(ctx, dead, "boom"); err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	_, err := q.CancelJob(ctx, "busy")
+	expectConflict(t, "CancelJob() of a processing job", err)
+	_, err = q.CancelJob(ctx, "dead")
+	expectConflict(t, "CancelJob() of a dead job", err)
+	expectStats(t, q, worker.QueueStats{Processing: 1, DeadLetter: 1})
+}
+
+func testPauseJobType(t *testing.T, q worker.Queue, clock *Clock) {
+	ctx := context.Background()
+	enqueue(t, q, &worker.Job{ID: "scrape", Type: worker.JobTypeScrapeFlyer, Priority: 9})
+	enqueue(t, q, &worker.Job{ID: "prices", Type: worker.JobTypeUpdatePrices})
+
+	if err := q.PauseJobType(ctx, worker.JobTypeScrapeFlyer); err != nil {
+		t.Fatalf("PauseJobType() error = %v", err)
+	}
+	if err := q.PauseJobType(ctx, worker.JobTypeScrapeFlyer); err != nil {
+		t.Fatalf("PauseJobType() of a paused type error = %v", err)
+	}
+	paused, err := q.PausedJobTypes(ctx)
+	if err != nil {
+		t.Fatalf("PausedJobTypes() error = %v", err)
+	}
+	if len(paused) != 1 || paused[0] != worker.JobTypeScrapeFlyer {
+		t.Errorf("PausedJobTypes() = %v, want [%s]", paused, worker.JobTypeScrapeFlyer)
+	}
+
+	// Paused jobs stay queued but are skipped, including ones enqueued while paused
+	expectOrder(t, q, "prices")
+	enqueue(t, q, &worker.Job{ID: "scrape-2", Type: worker.JobTypeScrapeFlyer})
+	if job := dequeue(t, q); job != nil {
+		t.Fatalf("job %s of a paused type was dequeued", job.ID)
+	}
+	expectStats(t, q, worker.QueueStats{Pending: 2, Processing: 1})
+
+	if err := q.ResumeJobType(ctx, worker.JobTypeScrapeFlyer); err != nil {
+		t.Fatalf("ResumeJobType() error = %v", err)
+	}
+	paused, err = q.PausedJobTypes(ctx)
+	if err != nil {
+		t.Fatalf("PausedJobTypes() error = %v", err)
+	}
+	if len(paused) != 0 {
+		t.Errorf("PausedJobTypes() after resume = %v, want none", paused)
+	}
+	expectOrder(t, q, "scrape", "scrape-2")
+}
+
+func enqueue(t *testing.T, q worker.Queue, job *worker.Job) {
+	t.Helper()
+	if err := q.Enqueue(context.Background(), job); err != nil {
+		t.Fatalf("Enqueue(%s) error = %v", job.ID, err)
+	}
+}
+
+func dequeue(t *testing.T, q worker.Queue) *worker.Job {
+	t.Helper()
+	job, err := q.Dequeue(context.Background(), noWait)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	return job
+}
+
+func expectOrder(t *testing.T, q worker.Queue, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		job := dequeue(t, q)
+		if job == nil {
+			t.Fatalf("Dequeue() returned no job, want %s", id)
+		}
+		if job.ID != id {
+			t.Fatalf("Dequeue() = %s, want %s", job.ID, id)
+		}
+	}
+}
+
+func expectRequeued(t *testing.T, q worker.Queue, want int) {
+	t.Helper()
+	got, err := q.RequeueExpired(context.Background())
+	if err != nil {
+		t.Fatalf("RequeueExpired() error = %v", err)
+	}
+	if got != want {
+		t.Fatalf("RequeueExpired() = %d, want %d", got, want)
+	}
+}
+
+func expectStats(t *testing.T, q worker.Queue, want worker.QueueStats) {
+	t.Helper()
+	got, err := q.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if *got != want {
+		t.Fatalf("Stats() = %+v, want %+v", *got, want)
+	}
+}
+
+func expectNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Errorf("%s error = %v, want not found", what, err)
+	}
+}
+
+func expectConflict(t *testing.T, what string, err error) {
+	t.Helper()
+	if !apperrors.IsType(err, apperrors.ErrorTypeConflict) {
+		t.Errorf("%s error = %v, want conflict", what, err)
+	}
+}
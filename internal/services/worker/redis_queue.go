@@ -0,0 +1,615 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+)
+
+// priorityScoreFactor separates priorities in the ready set's score so that
+// the enqueue time in milliseconds only orders jobs of the same priority
+const priorityScoreFactor = 1e13
+
+// enqueueScript stores a job and queues it unless its ID is already queued or
+// processing.
+// KEYS: ready, delayed, leased, dead, jobs, priorities, held, types
+// ARGV: id, job JSON, priority, ready score, run at ms ("" when ready now), type
+var enqueueScript = redis.NewScript(`
+if redis.call('ZSCORE', KEYS[1], ARGV[1]) or redis.call('ZSCORE', KEYS[2], ARGV[1]) or redis.call('ZSCORE', KEYS[3], ARGV[1]) or redis.call('ZSCORE', KEYS[7], ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[5], ARGV[1], ARGV[2])
+redis.call('HSET', KEYS[6], ARGV[1], ARGV[3])
+redis.call('HSET', KEYS[8], ARGV[1], ARGV[6])
+redis.call('ZREM', KEYS[4], ARGV[1])
+if ARGV[5] ~= '' then
+	redis.call('ZADD', KEYS[2], ARGV[5], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+end
+return 1
+`)
+
+// dequeueScript moves due delayed jobs to the ready set, then leases the first
+// ready job and returns its ID. Ready jobs of a paused type are moved aside to
+// the held set on the way.
+// KEYS: ready, delayed, leased, priorities, held, types, paused
+// ARGV: now ms, lease deadline ms
+var dequeueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'WITHSCORES', 'LIMIT', 0, 100)
+for i = 1, #due, 2 do
+	local priority = tonumber(redis.call('HGET', KEYS[4], due[i]) or '0')
+	local score = -priority * 1e13 + tonumber(due[i + 1])
+	redis.call('ZREM', KEYS[2], due[i])
+	redis.call('ZADD', KEYS[1], string.format('%.0f', score), due[i])
+end
+for _ = 1, 1000 do
+	local next = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	if #next == 0 then
+		return false
+	end
+	redis.call('ZREM', KEYS[1], next[1])
+	local jobType = redis.call('HGET', KEYS[6], next[1])
+	if jobType and redis.call('SISMEMBER', KEYS[7], jobType) == 1 then
+		redis.call('ZADD', KEYS[5], next[2], next[1])
+	else
+		redis.call('ZADD', KEYS[3], ARGV[2], next[1])
+		return next[1]
+	end
+end
+return false
+`)
+
+// extendScript renews the lease of a processing job.
+// KEYS: leased
+// ARGV: id, lease deadline ms
+var extendScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+
+// completeScript drops a processing job.
+// KEYS: leased, jobs, priorities, types
+// ARGV: id
+var completeScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+
+// cancelScript drops a job that waits in the ready, delayed or held set.
+// KEYS: ready, delayed, held, jobs, priorities, types
+// ARGV: id
+var cancelScript = redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[1], ARGV[1]) + redis.call('ZREM', KEYS[2], ARGV[1]) + redis.call('ZREM', KEYS[3], ARGV[1])
+if removed == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[4], ARGV[1])
+redis.call('HDEL', KEYS[5], ARGV[1])
+redis.call('HDEL', KEYS[6], ARGV[1])
+return 1
+`)
+
+// retryScript moves a dead or delayed job to the ready set.
+// KEYS: ready, delayed, dead, jobs
+// ARGV: id, job JSON, ready score
+var retryScript = redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[3], ARGV[1]) + redis.call('ZREM', KEYS[2], ARGV[1])
+if removed == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[4], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// resumeScript unpauses a job type and moves its held jobs back to the ready set.
+// KEYS: paused, held, ready, types
+// ARGV: type
+var resumeScript = redis.NewScript(`
+redis.call('SREM', KEYS[1], ARGV[1])
+local held = redis.call('ZRANGE', KEYS[2], 0, -1, 'WITHSCORES')
+local resumed = 0
+for i = 1, #held, 2 do
+	if redis.call('HGET', KEYS[4], held[i]) == ARGV[1] then
+		redis.call('ZREM', KEYS[2], held[i])
+		redis.call('ZADD', KEYS[3], held[i + 1], held[i])
+		resumed = resumed + 1
+	end
+end
+return resumed
+`)
+
+// releaseScript moves a processing job to the delayed set for a retry, or to
+// the dead letters. With an expired-before bound it only releases a job whose
+// lease ended before it, so a job renewed meanwhile keeps running.
+// KEYS: leased, delayed, dead, jobs
+// ARGV: id, job JSON, retry at ms ("" when dead), now ms, expired before ms ("" for any)
+var releaseScript = redis.NewScript(`
+local lease = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not lease then
+	return 0
+end
+if ARGV[5] ~= '' and tonumber(lease) >= tonumber(ARGV[5]) then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[4], ARGV[1], ARGV[2])
+if ARGV[3] ~= '' then
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+else
+	redis.call('ZADD', KEYS[3], ARGV[4], ARGV[1])
+end
+return 1
+`)
+
+// RedisQueue is the Redis backend of Queue. Jobs are stored by ID in a hash
+// and their IDs move between sorted sets: ready (by priority, then enqueue
+// time), delayed (by run time), leased (by lease deadline), dead (by time of
+// death) and held (ready jobs of a paused type).
+type RedisQueue struct {
+	redis      *redis.Client
+	opts       QueueOptions
+	ready      string
+	delayed    string
+	leased     string
+	dead       string
+	held       string
+	jobs       string
+	priorities string
+	types      string
+	paused     string
+}
+
+// NewRedisQueue creates a Redis queue whose keys are prefixed with queueName
+func NewRedisQueue(redisClient *redis.Client, queueName string, opts QueueOptions) *RedisQueue {
+	return &RedisQueue{
+		redis:      redisClient,
+		opts:       opts.withDefaults(),
+		ready:      queueName + ":ready",
+		delayed:    queueName + ":delayed",
+		leased:     queueName + ":leased",
+		dead:       queueName + ":dead",
+		held:       queueName + ":held",
+		jobs:       queueName + ":jobs",
+		priorities: queueName + ":priorities",
+		types:      queueName + ":types",
+		paused:     queueName + ":paused",
+	}
+}
+
+// VisibilityTimeout returns how long a dequeued job stays leased
+func (q *RedisQueue) VisibilityTimeout() time.Duration {
+	return q.opts.VisibilityTimeout
+}
+
+func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
+	now := q.opts.Clock()
+	if err := prepareEnqueue(job, now); err != nil {
+		return err
+	}
+	injectTraceContext(ctx, job)
+
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job")
+	}
+
+	runAt := ""
+	if job.ScheduledAt != nil {
+		runAt = formatMillis(*job.ScheduledAt)
+	}
+
+	err = enqueueScript.Run(ctx, q.redis,
+		[]string{q.ready, q.delayed, q.leased, q.dead, q.jobs, q.priorities, q.held, q.types},
+		job.ID, jobData, job.Priority, readyScore(job.Priority, now), runAt, string(job.Type),
+	).Err()
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to enqueue job %s", job.ID)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Dequeue(ctx context.Context, timeout time.Duration) (*Job, error) {
+	return waitForJob(ctx, timeout, q.opts.PollInterval, q.dequeue)
+}
+
+func (q *RedisQueue) dequeue(ctx context.Context) (*Job, error) {
+	now := q.opts.Clock()
+	id, err := dequeueScript.Run(ctx, q.redis,
+		[]string{q.ready, q.delayed, q.leased, q.priorities, q.held, q.types, q.paused},
+		formatMillis(now), formatMillis(now.Add(q.opts.VisibilityTimeout)),
+	).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to dequeue job")
+	}
+
+	job, err := q.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		q.redis.ZRem(ctx, q.leased, id)
+		return nil, apperrors.Internal("dequeued job " + id + " has no stored data")
+	}
+
+	job.Status = JobStatusProcessing
+	job.Attempts++
+	job.StartedAt = &now
+	job.UpdatedAt = now
+
+	if err := q.store(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (q *RedisQueue) Extend(ctx context.Context, job *Job) error {
+	deadline := q.opts.Clock().Add(q.opts.VisibilityTimeout)
+	extended, err := extendScript.Run(ctx, q.redis, []string{q.leased}, job.ID, formatMillis(deadline)).Int()
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to extend job %s", job.ID)
+	}
+	if extended == 0 {
+		return jobNotProcessing(job)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Complete(ctx context.Context, job *Job) error {
+	completed, err := completeScript.Run(ctx, q.redis, []string{q.leased, q.jobs, q.priorities, q.types}, job.ID).Int()
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to complete job %s", job.ID)
+	}
+	if completed == 0 {
+		return jobNotProcessing(job)
+	}
+
+	now := q.opts.Clock()
+	job.Status = JobStatusCompleted
+	job.CompletedAt = &now
+	job.UpdatedAt = now
+	return nil
+}
+
+func (q *RedisQueue) Cancel(ctx context.Context, job *Job) error {
+	cancelled, err := completeScript.Run(ctx, q.redis, []string{q.leased, q.jobs, q.priorities, q.types}, job.ID).Int()
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to cancel job %s", job.ID)
+	}
+	if cancelled == 0 {
+		return jobNotProcessing(job)
+	}
+
+	now := q.opts.Clock()
+	job.Status = JobStatusCancelled
+	job.CompletedAt = &now
+	job.UpdatedAt = now
+	return nil
+}
+
+func (q *RedisQueue) Fail(ctx context.Context, job *Job, errorMsg string) error {
+	released, err := q.release(ctx, job, errorMsg, time.Time{})
+	if err != nil {
+		return err
+	}
+	if !released {
+		return jobNotProcessing(job)
+	}
+	return nil
+}
+
+// release records a failed attempt of a processing job. A non-zero
+// expiredBefore only releases the job if its lease ended before then.
+func (q *RedisQueue) release(ctx context.Context, job *Job, errorMsg string, expiredBefore time.Time) (bool, error) {
+	now := q.opts.Clock()
+	retry := prepareFailure(job, errorMsg, now)
+
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return false, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal failed job")
+	}
+
+	retryAt := ""
+	if retry {
+		retryAt = formatMillis(*job.ScheduledAt)
+	}
+	bound := ""
+	if !expiredBefore.IsZero() {
+		bound = formatMillis(expiredBefore)
+	}
+
+	released, err := releaseScript.Run(ctx, q.redis,
+		[]string{q.leased, q.delayed, q.dead, q.jobs},
+		job.ID, jobData, retryAt, formatMillis(now), bound,
+	).Int()
+	if err != nil {
+		return false, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to fail job %s", job.ID)
+	}
+	return released == 1, nil
+}
+
+func (q *RedisQueue) RequeueExpired(ctx context.Context) (int, error) {
+	now := q.opts.Clock()
+	ids, err := q.redis.ZRangeByScore(ctx, q.leased, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + formatMillis(now),
+	}).Result()
+	if err != nil {
+		return 0, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list expired jobs")
+	}
+
+	requeued := 0
+	for _, id := range ids {
+		job, err := q.load(ctx, id)
+		if err != nil {
+			return requeued, err
+		}
+		if job == nil {
+			q.redis.ZRem(ctx, q.leased, id)
+			continue
+		}
+
+		released, err := q.release(ctx, job, errVisibilityTimeout, now)
+		if err != nil {
+			return requeued, err
+		}
+		if released {
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (q *RedisQueue) DeadLetters(ctx context.Context, limit int) ([]*Job, error) {
+	if limit <= 0 {
+		return []*Job{}, nil
+	}
+
+	ids, err := q.redis.ZRevRange(ctx, q.dead, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list dead letters")
+	}
+
+	jobs := make([]*Job, 0, len(ids))
+	if len(ids) == 0 {
+		return jobs, nil
+	}
+
+	values, err := q.redis.HMGet(ctx, q.jobs, ids...).Result()
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to load dead letters")
+	}
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to unmarshal dead letter")
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+func (q *RedisQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	now := formatMillis(q.opts.Clock())
+
+	pipe := q.redis.Pipeline()
+	ready := pipe.ZCard(ctx, q.ready)
+	held := pipe.ZCard(ctx, q.held)
+	due := pipe.ZCount(ctx, q.delayed, "-inf", now)
+	scheduled := pipe.ZCount(ctx, q.delayed, "("+now, "+inf")
+	processing := pipe.ZCard(ctx, q.leased)
+	deadLetter := pipe.ZCard(ctx, q.dead)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to get queue stats")
+	}
+
+	return &QueueStats{
+		Pending:    ready.Val() + held.Val() + due.Val(),
+		Scheduled:  scheduled.Val(),
+		Processing: processing.Val(),
+		DeadLetter: deadLetter.Val(),
+	}, nil
+}
+
+func (q *RedisQueue) GetJob(ctx context.Context, id string) (*Job, error) {
+	job, err := q.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, jobNotFound(id)
+	}
+	return job, nil
+}
+
+func (q *RedisQueue) ListJobs(ctx context.Context, filter JobFilter) ([]*Job, error) {
+	filter = filter.withDefaults()
+
+	values, err := q.redis.HVals(ctx, q.jobs).Result()
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list jobs")
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, value := range values {
+		var job Job
+		if err := json.Unmarshal([]byte(value), &job); err != nil {
+			return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to unmarshal job")
+		}
+		if filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		if filter.Type != "" && job.Type != filter.Type {
+			continue
+		}
+		jobs = append(jobs, &job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+
+	if filter.Offset >= len(jobs) {
+		return []*Job{}, nil
+	}
+	jobs = jobs[filter.Offset:]
+	if len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+func (q *RedisQueue) RetryJob(ctx context.Context, id string) (*Job, error) {
+	job, err := q.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == JobStatusProcessing {
+		return nil, jobInState(job, "retry")
+	}
+
+	now := q.opts.Clock()
+	prepareRetry(job, now)
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job")
+	}
+
+	retried, err := retryScript.Run(ctx, q.redis,
+		[]string{q.ready, q.delayed, q.dead, q.jobs},
+		job.ID, jobData, readyScore(job.Priority, now),
+	).Int()
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to retry job %s", id)
+	}
+	if retried == 0 {
+		// Already ready, or picked up meanwhile
+		return q.GetJob(ctx, id)
+	}
+	return job, nil
+}
+
+func (q *RedisQueue) CancelJob(ctx context.Context, id string) (*Job, error) {
+	job, err := q.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != JobStatusPending && job.Status != JobStatusRetrying {
+		return nil, jobInState(job, "cancel")
+	}
+
+	cancelled, err := cancelScript.Run(ctx, q.redis,
+		[]string{q.ready, q.delayed, q.held, q.jobs, q.priorities, q.types},
+		job.ID,
+	).Int()
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to cancel job %s", id)
+	}
+	if cancelled == 0 {
+		current, err := q.GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, jobInState(current, "cancel")
+	}
+
+	now := q.opts.Clock()
+	job.Status = JobStatusCancelled
+	job.CompletedAt = &now
+	job.UpdatedAt = now
+	return job, nil
+}
+
+func (q *RedisQueue) PauseJobType(ctx context.Context, jobType JobType) error {
+	if err := q.redis.SAdd(ctx, q.paused, string(jobType)).Err(); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to pause job type %s", jobType)
+	}
+	return nil
+}
+
+func (q *RedisQueue) ResumeJobType(ctx context.Context, jobType JobType) error {
+	err := resumeScript.Run(ctx, q.redis, []string{q.paused, q.held, q.ready, q.types}, string(jobType)).Err()
+	if err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to resume job type %s", jobType)
+	}
+	return nil
+}
+
+func (q *RedisQueue) PausedJobTypes(ctx context.Context) ([]JobType, error) {
+	members, err := q.redis.SMembers(ctx, q.paused).Result()
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list paused job types")
+	}
+	sort.Strings(members)
+
+	types := make([]JobType, len(members))
+	for i, member := range members {
+		types[i] = JobType(member)
+	}
+	return types, nil
+}
+
+// load reads a stored job, or nil when there is none
+func (q *RedisQueue) load(ctx context.Context, id string) (*Job, error) {
+	data, err := q.redis.HGet(ctx, q.jobs, id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to load job %s", id)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to unmarshal job %s", id)
+	}
+	return &job, nil
+}
+
+func (q *RedisQueue) store(ctx context.Context, job *Job) error {
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal job")
+	}
+	if err := q.redis.HSet(ctx, q.jobs, job.ID, jobData).Err(); err != nil {
+		return apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to store job %s", job.ID)
+	}
+	return nil
+}
+
+// readyScore orders the ready set: higher priority first, then by time
+func readyScore(priority int, readyAt time.Time) string {
+	return strconv.FormatFloat(-float64(priority)*priorityScoreFactor+float64(readyAt.UnixMilli()), 'f', 0, 64)
+}
+
+func formatMillis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
@@ -0,0 +1,52 @@
+package worker_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker/queuetest"
+)
+
+// The Redis backend runs the conformance suite against the server at
+// REDIS_TEST_ADDR, in throwaway queues
+func TestRedisQueue_Conformance(t *testing.T) {
+	client := redisTestClient(t)
+
+	queuetest.Run(t, func(t *testing.T, opts worker.QueueOptions) worker.Queue {
+		return worker.NewRedisQueue(client, redisTestPrefix(t, client), opts)
+	})
+}
+
+// redisTestClient connects to the server at REDIS_TEST_ADDR, skipping the test without one
+func redisTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to connect to Redis at %s: %v", addr, err)
+	}
+	return client
+}
+
+// redisTestPrefix returns a throwaway key prefix whose keys are deleted after the test
+func redisTestPrefix(t *testing.T, client *redis.Client) string {
+	t.Helper()
+	prefix := "kainuguru:test:" + uuid.New().String()
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, err := client.Keys(ctx, prefix+"*").Result()
+		if err == nil && len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+	})
+	return prefix
+}
@@ -24,14 +24,14 @@ type ScheduledJob struct {
 
 type JobScheduler struct {
 	cron          *cron.Cron
-	queue         *JobQueue
+	queue         Queue
 	redis         *redis.Client
 	lockManager   *LockManager
 	scheduledJobs map[string]*ScheduledJob
 	running       bool
 }
 
-func NewJobScheduler(queue *JobQueue, redis *redis.Client) *JobScheduler {
+func NewJobScheduler(queue Queue, redis *redis.Client) *JobScheduler {
 	return &JobScheduler{
 		cron:          cron.New(cron.WithSeconds()),
 		queue:         queue,
@@ -176,12 +176,15 @@ func (js *JobScheduler) UpdateJob(jobID string, updates *ScheduledJob) error {
 func (js *JobScheduler) SetupDefaultSchedules() error {
 	defaultJobs := []*ScheduledJob{
 		{
-			Name:     "Weekly Flyer Scraping - All Stores",
+			Name:     "Weekly Flyer Pipeline - All Stores",
 			Schedule: "0 0 6 * * MON", // Every Monday at 6 AM
-			JobType:  JobTypeScrapeFlyer,
+			JobType:  JobTypeRunWorkflow,
 			Payload: map[string]interface{}{
-				"stores": []string{"iki", "maxima", "rimi"},
-				"type":   "weekly_update",
+				PayloadWorkflow: WorkflowFlyerPipeline,
+				PayloadWorkflowParams: map[string]interface{}{
+					WorkflowParamStores: []string{"iki", "maxima", "rimi"},
+				},
+				"type": "weekly_update",
 			},
 			Enabled: true,
 		},
@@ -199,8 +202,26 @@ func (js *JobScheduler) SetupDefaultSchedules() error {
 			Schedule: "0 0 2 * * SUN", // Every Sunday at 2 AM
 			JobType:  JobTypeArchiveData,
 			Payload: map[string]interface{}{
-				"archive_older_than_days": 90,
-				"type":                    "weekly_archive",
+				PayloadArchiveOlderThanDays: 90,
+				"type":                      "weekly_archive",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Daily Product Partition Maintenance",
+			Schedule: "0 30 2 * * *", // Every day at 2:30 AM
+			JobType:  JobTypeManagePartitions,
+			Payload: map[string]interface{}{
+				"type": "daily_maintenance",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Hourly Storage Cleanup Check",
+			Schedule: "0 15 * * * *", // Every hour at quarter past
+			JobType:  JobTypeRunCleanups,
+			Payload: map[string]interface{}{
+				"type": "scheduled_cleanups",
 			},
 			Enabled: true,
 		},
@@ -209,8 +230,8 @@ func (js *JobScheduler) SetupDefaultSchedules() error {
 			Schedule: "0 0 3 1 * *", // First day of every month at 3 AM
 			JobType:  JobTypeCleanupData,
 			Payload: map[string]interface{}{
-				"cleanup_older_than_days": 180,
-				"type":                    "monthly_cleanup",
+				PayloadCleanupOlderThanDays: 180,
+				"type":                      "monthly_cleanup",
 			},
 			Enabled: true,
 		},
@@ -219,8 +240,53 @@ func (js *JobScheduler) SetupDefaultSchedules() error {
 			Schedule: "0 0 * * * *", // Every hour
 			JobType:  JobTypeExtractProducts,
 			Payload: map[string]interface{}{
-				"batch_size": 50,
-				"type":       "hourly_batch",
+				PayloadBatchSize: 50,
+				"type":           "hourly_batch",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Hourly Search Suggestions Refresh",
+			Schedule: "0 30 * * * *", // Every hour at half past
+			JobType:  JobTypeRefreshSearchSuggestions,
+			Payload: map[string]interface{}{
+				"type": "hourly_refresh",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Daily Expired Flyer Items Detection",
+			Schedule: "0 0 0 * * *", // Every day at midnight
+			JobType:  JobTypeExpireFlyerItems,
+			Payload: map[string]interface{}{
+				"type": "daily_check",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Hourly Wizard Session Cleanup",
+			Schedule: "0 15 * * * *", // Every hour at quarter past
+			JobType:  JobTypeCleanupSessions,
+			Payload: map[string]interface{}{
+				"type": "hourly_cleanup",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Product Master Matching",
+			Schedule: "0 */30 * * * *", // Every 30 minutes
+			JobType:  JobTypeMatchProducts,
+			Payload: map[string]interface{}{
+				"type": "unmatched_batch",
+			},
+			Enabled: true,
+		},
+		{
+			Name:     "Daily Shopping List Migration",
+			Schedule: "0 0 1 * * *", // Every day at 1 AM, after expired items are detected
+			JobType:  JobTypeMigrateShoppingLists,
+			Payload: map[string]interface{}{
+				"type": "daily_migration",
 			},
 			Enabled: true,
 		},
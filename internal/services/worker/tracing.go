@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/kainuguru/kainuguru-api/internal/services/worker"
+
+// injectTraceContext records the trace of ctx in the job's payload. The payload
+// is copied first since callers like the scheduler enqueue the same one repeatedly.
+func injectTraceContext(ctx context.Context, job *Job) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return
+	}
+
+	traceContext := make(map[string]interface{}, len(carrier))
+	for key, value := range carrier {
+		traceContext[key] = value
+	}
+	payload := make(map[string]interface{}, len(job.Payload)+1)
+	for key, value := range job.Payload {
+		payload[key] = value
+	}
+	payload[PayloadTraceContext] = traceContext
+	job.Payload = payload
+}
+
+// startJobSpan starts the span of a job run, continuing the trace the job was
+// enqueued in, if any
+func startJobSpan(ctx context.Context, job *Job) (context.Context, trace.Span) {
+	carrier := propagation.MapCarrier{}
+	if traceContext, ok := job.PayloadMap(PayloadTraceContext); ok {
+		for key, value := range traceContext {
+			if s, ok := value.(string); ok {
+				carrier[key] = s
+			}
+		}
+	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+
+	return otel.Tracer(tracerName).Start(ctx, "job "+string(job.Type),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", string(job.Type)),
+			attribute.Int("job.attempt", job.Attempts),
+		),
+	)
+}
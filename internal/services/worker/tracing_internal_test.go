@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestStartJobSpan_ContinuesEnqueuingTrace(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "enqueue")
+	payload := map[string]interface{}{"flyer_id": 1}
+	job := &Job{ID: "job-1", Type: JobTypeScrapeFlyer, Payload: payload}
+	injectTraceContext(ctx, job)
+	parent.End()
+
+	if _, ok := payload[PayloadTraceContext]; ok {
+		t.Error("injectTraceContext() modified the caller's payload")
+	}
+
+	// The payload is stored as JSON between enqueueing and running
+	raw, err := json.Marshal(job)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var dequeued Job
+	if err := json.Unmarshal(raw, &dequeued); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	_, span := startJobSpan(context.Background(), &dequeued)
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	jobSpan := spans[1]
+	if jobSpan.Name() != "job "+string(JobTypeScrapeFlyer) {
+		t.Errorf("span name = %q", jobSpan.Name())
+	}
+	if jobSpan.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("span kind = %v, want consumer", jobSpan.SpanKind())
+	}
+	if jobSpan.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("job span parent = %v, want the enqueuing span %v", jobSpan.Parent().SpanID(), parent.SpanContext().SpanID())
+	}
+	if jobSpan.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("job span is not in the enqueuing trace")
+	}
+}
+
+func TestStartJobSpan_WithoutTraceContextStartsNewTrace(t *testing.T) {
+	recorder := setupTestTracing(t)
+
+	job := &Job{ID: "job-1", Type: JobTypeScrapeFlyer, Payload: map[string]interface{}{}}
+	injectTraceContext(context.Background(), job)
+	if _, ok := job.Payload[PayloadTraceContext]; ok {
+		t.Error("injectTraceContext() without a span added trace context to the payload")
+	}
+
+	_, span := startJobSpan(context.Background(), job)
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if spans[0].Parent().IsValid() {
+		t.Errorf("job span has parent %v, want a root span", spans[0].Parent().SpanID())
+	}
+}
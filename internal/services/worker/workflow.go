@@ -0,0 +1,782 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+type WorkflowStatus string
+
+const (
+	WorkflowStatusRunning   WorkflowStatus = "running"
+	WorkflowStatusCompleted WorkflowStatus = "completed"
+	WorkflowStatusFailed    WorkflowStatus = "failed"
+	WorkflowStatusCancelled WorkflowStatus = "cancelled"
+)
+
+type StepStatus string
+
+const (
+	StepStatusWaiting          StepStatus = "waiting"           // for the steps it depends on
+	StepStatusQueued           StepStatus = "queued"            // its job is queued or running
+	StepStatusAwaitingChildren StepStatus = "awaiting_children" // its job completed, jobs it fanned out into did not yet
+	StepStatusCompleted        StepStatus = "completed"
+	StepStatusFailed           StepStatus = "failed"
+	StepStatusCancelled        StepStatus = "cancelled"
+)
+
+// Workflow is a run of jobs that wait for each other
+type Workflow struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Status      WorkflowStatus         `json:"status"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	Steps       []*WorkflowStep        `json:"steps,omitempty"`
+}
+
+// WorkflowStep is one job of a workflow. Parent names the step whose job fanned
+// out into this one; declared steps have none.
+type WorkflowStep struct {
+	Name        string     `json:"name"`
+	Parent      string     `json:"parent,omitempty"`
+	DependsOn   []string   `json:"depends_on,omitempty"`
+	Job         *Job       `json:"job"`
+	Status      StepStatus `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// WorkflowDefinition declares the steps of a workflow. A step may only depend on
+// steps declared before it, so workflows have no cycles.
+type WorkflowDefinition struct {
+	Name   string
+	Params map[string]interface{}
+	Steps  []WorkflowStepSpec
+}
+
+// WorkflowStepSpec declares a step: its job, queued once the steps it depends on
+// completed. Jobs without an ID get one derived from the workflow and step.
+type WorkflowStepSpec struct {
+	Name      string
+	Job       *Job
+	DependsOn []string
+}
+
+// WorkflowBuilder declares a named workflow from the parameters it is started with
+type WorkflowBuilder func(params map[string]interface{}) (*WorkflowDefinition, error)
+
+// WorkflowFilter selects the workflows returned by List, newest first. Empty fields match any workflow.
+type WorkflowFilter struct {
+	Status WorkflowStatus
+	Name   string
+	Limit  int
+	Offset int
+}
+
+func (f WorkflowFilter) withDefaults() WorkflowFilter {
+	if f.Limit <= 0 {
+		f.Limit = defaultJobListLimit
+	}
+	if f.Limit > maxJobListLimit {
+		f.Limit = maxJobListLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return f
+}
+
+// WorkflowEngine runs workflows on top of a job queue. A step's job is queued
+// once the steps it depends on completed. Jobs a step's job enqueues through the
+// engine become child steps, and the step completes once they all completed too.
+// A failed or cancelled step stops the workflow; steps after it stay waiting, so
+// retrying the failed job resumes it. State is kept in Postgres whichever backend
+// queues the jobs.
+type WorkflowEngine struct {
+	db                *bun.DB
+	queue             Queue
+	clock             func() time.Time
+	supportsForUpdate bool
+
+	mu       sync.RWMutex
+	builders map[string]WorkflowBuilder
+}
+
+// NewWorkflowEngine creates a workflow engine knowing the built-in workflows
+func NewWorkflowEngine(db *bun.DB, queue Queue) *WorkflowEngine {
+	e := &WorkflowEngine{
+		db:                db,
+		queue:             queue,
+		clock:             time.Now,
+		supportsForUpdate: db.Dialect().Name() == dialect.PG,
+		builders:          make(map[string]WorkflowBuilder),
+	}
+	e.Define(WorkflowFlyerPipeline, FlyerPipeline)
+	return e
+}
+
+// Define makes a workflow startable by name
+func (e *WorkflowEngine) Define(name string, build WorkflowBuilder) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.builders[name] = build
+}
+
+// Definitions returns the names of the startable workflows, sorted
+func (e *WorkflowEngine) Definitions() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.builders))
+	for name := range e.builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Register installs the run_workflow handler and the hook moving workflows on as
+// their jobs finish
+func (e *WorkflowEngine) Register(processor *WorkerProcessor) {
+	processor.RegisterHandler(JobTypeRunWorkflow, e.handleRunWorkflow)
+	processor.OnJobFinished(e.JobFinished)
+}
+
+// Start starts the named workflow
+func (e *WorkflowEngine) Start(ctx context.Context, name string, params map[string]interface{}) (*Workflow, error) {
+	e.mu.RLock()
+	build, ok := e.builders[name]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, apperrors.ValidationF("unknown workflow %q", name)
+	}
+
+	def, err := build(params)
+	if err != nil {
+		return nil, err
+	}
+	if def.Name == "" {
+		def.Name = name
+	}
+	return e.StartDefinition(ctx, def)
+}
+
+// StartDefinition saves a workflow and queues the jobs of its steps that depend on none
+func (e *WorkflowEngine) StartDefinition(ctx context.Context, def *WorkflowDefinition) (*Workflow, error) {
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+
+	now := e.clock().UTC()
+	wf := &Workflow{
+		ID:        uuid.New().String(),
+		Name:      def.Name,
+		Status:    WorkflowStatusRunning,
+		Params:    def.Params,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	jobIDs := make(map[string]bool, len(def.Steps))
+	for _, spec := range def.Steps {
+		job := *spec.Job
+		if job.ID == "" {
+			job.ID = wf.ID + ":" + spec.Name
+		}
+		if jobIDs[job.ID] {
+			return nil, apperrors.ValidationF("workflow %s runs job %s twice", def.Name, job.ID)
+		}
+		jobIDs[job.ID] = true
+
+		status := StepStatusQueued
+		if len(spec.DependsOn) > 0 {
+			status = StepStatusWaiting
+		}
+		wf.Steps = append(wf.Steps, &WorkflowStep{
+			Name:      spec.Name,
+			DependsOn: spec.DependsOn,
+			Job:       &job,
+			Status:    status,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	row, err := workflowRow(wf)
+	if err != nil {
+		return nil, err
+	}
+	stepRows, err := workflowStepRows(wf, wf.Steps)
+	if err != nil {
+		return nil, err
+	}
+	err = e.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(row).Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewInsert().Model(&stepRows).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to save workflow %s", def.Name)
+	}
+
+	log.Printf("Started workflow %s (%s) with %d steps", wf.Name, wf.ID, len(wf.Steps))
+	for _, step := range wf.Steps {
+		if step.Status == StepStatusQueued {
+			e.enqueueStep(ctx, wf.ID, step)
+		}
+	}
+	return e.Get(ctx, wf.ID)
+}
+
+// Get returns a workflow with its steps, in the order they were added
+func (e *WorkflowEngine) Get(ctx context.Context, id string) (*Workflow, error) {
+	return e.load(ctx, e.db, id, false)
+}
+
+// List returns workflows without their steps, newest first
+func (e *WorkflowEngine) List(ctx context.Context, filter WorkflowFilter) ([]*Workflow, error) {
+	filter = filter.withDefaults()
+
+	var rows []*models.Workflow
+	query := e.db.NewSelect().Model(&rows)
+	if filter.Status != "" {
+		query = query.Where("wf.status = ?", string(filter.Status))
+	}
+	if filter.Name != "" {
+		query = query.Where("wf.name = ?", filter.Name)
+	}
+	err := query.
+		Order("wf.created_at DESC", "wf.id ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(ctx)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to list workflows")
+	}
+
+	workflows := make([]*Workflow, len(rows))
+	for i, row := range rows {
+		if workflows[i], err = workflowFromRow(row); err != nil {
+			return nil, err
+		}
+	}
+	return workflows, nil
+}
+
+// Enqueue queues a job. Enqueued while a workflow step's job runs, with the
+// job's context, the job becomes a child step of that step.
+func (e *WorkflowEngine) Enqueue(ctx context.Context, job *Job) error {
+	parent := jobFromContext(ctx)
+	if parent == nil {
+		return e.queue.Enqueue(ctx, job)
+	}
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+
+	ids, err := e.workflowsRunning(ctx, parent.ID)
+	if err != nil {
+		return err
+	}
+	spec := *job
+	for _, id := range ids {
+		_, err := e.update(ctx, id, func(c *workflowChange) error {
+			step := c.stepForJob(parent.ID)
+			if step == nil || step.Status == StepStatusWaiting {
+				return nil
+			}
+			// A retried job runs again in a step that already finished
+			if step.Status != StepStatusQueued {
+				c.set(step, StepStatusQueued, "")
+			}
+			child := c.step(spec.ID)
+			if child == nil {
+				c.add(&WorkflowStep{Name: spec.ID, Parent: step.Name, Job: &spec, Status: StepStatusQueued})
+				return nil
+			}
+			// A retried parent queues its children again
+			if child.Parent == step.Name && child.Status != StepStatusQueued {
+				c.set(child, StepStatusQueued, "")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return e.queue.Enqueue(ctx, job)
+}
+
+// JobFinished moves on the workflows whose steps ran the job. It is the worker
+// processor's finish hook; errors are logged.
+func (e *WorkflowEngine) JobFinished(ctx context.Context, job *Job) {
+	var status StepStatus
+	switch job.Status {
+	case JobStatusCompleted:
+		// Settling completes it unless it has children still running
+		status = StepStatusAwaitingChildren
+	case JobStatusFailed:
+		status = StepStatusFailed
+	case JobStatusCancelled:
+		status = StepStatusCancelled
+	default:
+		return
+	}
+
+	ids, err := e.workflowsRunning(ctx, job.ID)
+	if err != nil {
+		log.Printf("Failed to find workflows of job %s: %v", job.ID, err)
+		return
+	}
+	for _, id := range ids {
+		_, err := e.update(ctx, id, func(c *workflowChange) error {
+			if step := c.stepForJob(job.ID); step != nil && step.Status != StepStatusWaiting {
+				c.set(step, status, job.Error)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to update workflow %s after job %s: %v", id, job.ID, err)
+		}
+	}
+}
+
+func (e *WorkflowEngine) handleRunWorkflow(ctx context.Context, job *Job) error {
+	name, ok := job.PayloadString(PayloadWorkflow)
+	if !ok || name == "" {
+		return apperrors.Validation("run_workflow job names no workflow")
+	}
+	params, _ := job.PayloadMap(PayloadWorkflowParams)
+
+	_, err := e.Start(ctx, name, params)
+	return err
+}
+
+// workflowsRunning returns the workflows with a step that ran or runs the job
+func (e *WorkflowEngine) workflowsRunning(ctx context.Context, jobID string) ([]string, error) {
+	var ids []string
+	err := e.db.NewSelect().
+		Model((*models.WorkflowStep)(nil)).
+		Distinct().
+		Column("ws.workflow_id").
+		Where("ws.job_id = ?", jobID).
+		Where("ws.status <> ?", string(StepStatusWaiting)).
+		Scan(ctx, &ids)
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to find workflows of job %s", jobID)
+	}
+	return ids, nil
+}
+
+// update applies a change to a locked workflow, settles and saves it, then
+// queues the jobs of the steps that became ready
+func (e *WorkflowEngine) update(ctx context.Context, id string, apply func(c *workflowChange) error) (*Workflow, error) {
+	var change *workflowChange
+	err := e.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		wf, err := e.load(ctx, tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		change = &workflowChange{wf: wf, now: e.clock().UTC(), changed: make(map[string]bool)}
+		if err := apply(change); err != nil {
+			return err
+		}
+		if len(change.changed) == 0 {
+			return nil
+		}
+		change.settle()
+		return e.save(ctx, tx, change)
+	})
+	if err != nil {
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to update workflow %s", id)
+	}
+
+	for _, step := range change.ready {
+		e.enqueueStep(ctx, id, step)
+	}
+	return change.wf, nil
+}
+
+// enqueueStep queues a step's job, failing the step when it can't be queued
+func (e *WorkflowEngine) enqueueStep(ctx context.Context, id string, step *WorkflowStep) {
+	job := *step.Job
+	enqueueErr := e.queue.Enqueue(ctx, &job)
+	if enqueueErr == nil {
+		return
+	}
+
+	log.Printf("Failed to queue step %s of workflow %s: %v", step.Name, id, enqueueErr)
+	_, err := e.update(ctx, id, func(c *workflowChange) error {
+		if s := c.step(step.Name); s != nil && s.Status == StepStatusQueued {
+			c.set(s, StepStatusFailed, fmt.Sprintf("failed to queue job: %v", enqueueErr))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to fail step %s of workflow %s: %v", step.Name, id, err)
+	}
+}
+
+func (e *WorkflowEngine) load(ctx context.Context, db bun.IDB, id string, lock bool) (*Workflow, error) {
+	row := new(models.Workflow)
+	query := db.NewSelect().Model(row).Where("wf.id = ?", id)
+	if lock && e.supportsForUpdate {
+		query = query.For("UPDATE")
+	}
+	if err := query.Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("workflow %s not found", id))
+		}
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to load workflow %s", id)
+	}
+	wf, err := workflowFromRow(row)
+	if err != nil {
+		return nil, err
+	}
+
+	var stepRows []*models.WorkflowStep
+	err = db.NewSelect().
+		Model(&stepRows).
+		Where("ws.workflow_id = ?", id).
+		Order("ws.position ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to load steps of workflow %s", id)
+	}
+	for _, stepRow := range stepRows {
+		step, err := workflowStepFromRow(stepRow)
+		if err != nil {
+			return nil, err
+		}
+		wf.Steps = append(wf.Steps, step)
+	}
+	return wf, nil
+}
+
+func (e *WorkflowEngine) save(ctx context.Context, tx bun.Tx, c *workflowChange) error {
+	row, err := workflowRow(c.wf)
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewUpdate().
+		Model(row).
+		Column("status", "error", "updated_at", "completed_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	var changed []*WorkflowStep
+	for _, step := range c.wf.Steps {
+		if c.changed[step.Name] {
+			changed = append(changed, step)
+		}
+	}
+	stepRows, err := workflowStepRows(c.wf, changed)
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewInsert().
+		Model(&stepRows).
+		On("CONFLICT (workflow_id, name) DO UPDATE").
+		Set("status = EXCLUDED.status").
+		Set("error = EXCLUDED.error").
+		Set("updated_at = EXCLUDED.updated_at").
+		Set("completed_at = EXCLUDED.completed_at").
+		Exec(ctx)
+	return err
+}
+
+// workflowChange is a workflow being updated and the steps that changed
+type workflowChange struct {
+	wf      *Workflow
+	now     time.Time
+	changed map[string]bool
+	// ready are the steps queued by settle, whose jobs are enqueued once saved
+	ready []*WorkflowStep
+}
+
+func (c *workflowChange) step(name string) *WorkflowStep {
+	for _, step := range c.wf.Steps {
+		if step.Name == name {
+			return step
+		}
+	}
+	return nil
+}
+
+func (c *workflowChange) stepForJob(jobID string) *WorkflowStep {
+	for _, step := range c.wf.Steps {
+		if step.Job.ID == jobID {
+			return step
+		}
+	}
+	return nil
+}
+
+func (c *workflowChange) add(step *WorkflowStep) {
+	step.CreatedAt = c.now
+	step.UpdatedAt = c.now
+	c.wf.Steps = append(c.wf.Steps, step)
+	c.changed[step.Name] = true
+}
+
+func (c *workflowChange) set(step *WorkflowStep, status StepStatus, errorMsg string) {
+	step.Status = status
+	step.Error = ""
+	if status == StepStatusFailed {
+		step.Error = errorMsg
+	}
+	step.UpdatedAt = c.now
+	step.CompletedAt = nil
+	if status.finished() {
+		step.CompletedAt = &c.now
+	}
+	c.changed[step.Name] = true
+}
+
+// settle completes the steps whose children all completed, queues the steps whose
+// dependencies completed, and derives the workflow's status from its steps
+func (c *workflowChange) settle() {
+	for progressed := true; progressed; {
+		progressed = false
+
+		completed := make(map[string]bool, len(c.wf.Steps))
+		incompleteChildren := make(map[string]int)
+		for _, step := range c.wf.Steps {
+			completed[step.Name] = step.Status == StepStatusCompleted
+			if step.Parent != "" && step.Status != StepStatusCompleted {
+				incompleteChildren[step.Parent]++
+			}
+		}
+
+		for _, step := range c.wf.Steps {
+			switch step.Status {
+			case StepStatusAwaitingChildren:
+				if incompleteChildren[step.Name] == 0 {
+					c.set(step, StepStatusCompleted, "")
+					progressed = true
+				}
+			case StepStatusWaiting:
+				ready := true
+				for _, dep := range step.DependsOn {
+					ready = ready && completed[dep]
+				}
+				if ready {
+					c.set(step, StepStatusQueued, "")
+					c.ready = append(c.ready, step)
+					progressed = true
+				}
+			}
+		}
+	}
+
+	status, errorMsg := WorkflowStatusCompleted, ""
+	for _, step := range c.wf.Steps {
+		switch {
+		case step.Status == StepStatusFailed:
+			status, errorMsg = WorkflowStatusFailed, fmt.Sprintf("step %s failed: %s", step.Name, step.Error)
+		case step.Status == StepStatusCancelled && status != WorkflowStatusFailed:
+			status, errorMsg = WorkflowStatusCancelled, fmt.Sprintf("step %s was cancelled", step.Name)
+		case !step.Status.finished() && status == WorkflowStatusCompleted:
+			status = WorkflowStatusRunning
+		}
+		if status == WorkflowStatusFailed {
+			break
+		}
+	}
+
+	c.wf.Status = status
+	c.wf.Error = errorMsg
+	c.wf.UpdatedAt = c.now
+	c.wf.CompletedAt = nil
+	if status != WorkflowStatusRunning {
+		c.wf.CompletedAt = &c.now
+	}
+}
+
+func (s StepStatus) finished() bool {
+	switch s {
+	case StepStatusCompleted, StepStatusFailed, StepStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (d *WorkflowDefinition) validate() error {
+	if d.Name == "" {
+		return apperrors.Validation("workflow name is required")
+	}
+	if len(d.Steps) == 0 {
+		return apperrors.ValidationF("workflow %s has no steps", d.Name)
+	}
+
+	declared := make(map[string]bool, len(d.Steps))
+	for _, step := range d.Steps {
+		if step.Name == "" {
+			return apperrors.ValidationF("workflow %s has a step without a name", d.Name)
+		}
+		if declared[step.Name] {
+			return apperrors.ValidationF("workflow %s declares step %s twice", d.Name, step.Name)
+		}
+		if step.Job == nil || step.Job.Type == "" {
+			return apperrors.ValidationF("step %s of workflow %s has no job", step.Name, d.Name)
+		}
+		for _, dep := range step.DependsOn {
+			if !declared[dep] {
+				return apperrors.ValidationF("step %s of workflow %s depends on %s, which is not declared before it", step.Name, d.Name, dep)
+			}
+		}
+		declared[step.Name] = true
+	}
+	return nil
+}
+
+type jobContextKey struct{}
+
+// withJob records the job a handler runs, so jobs it enqueues can join its workflow
+func withJob(ctx context.Context, job *Job) context.Context {
+	return context.WithValue(ctx, jobContextKey{}, job)
+}
+
+func jobFromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(jobContextKey{}).(*Job)
+	return job
+}
+
+func workflowRow(wf *Workflow) (*models.Workflow, error) {
+	params := wf.Params
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	paramsData, err := json.Marshal(params)
+	if err != nil {
+		return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal workflow params")
+	}
+
+	row := &models.Workflow{
+		ID:          wf.ID,
+		Name:        wf.Name,
+		Status:      string(wf.Status),
+		Params:      paramsData,
+		CreatedAt:   wf.CreatedAt,
+		UpdatedAt:   wf.UpdatedAt,
+		CompletedAt: wf.CompletedAt,
+	}
+	if wf.Error != "" {
+		row.Error = &wf.Error
+	}
+	return row, nil
+}
+
+func workflowFromRow(row *models.Workflow) (*Workflow, error) {
+	wf := &Workflow{
+		ID:          row.ID,
+		Name:        row.Name,
+		Status:      WorkflowStatus(row.Status),
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		CompletedAt: row.CompletedAt,
+	}
+	if row.Error != nil {
+		wf.Error = *row.Error
+	}
+	if len(row.Params) > 0 {
+		if err := json.Unmarshal(row.Params, &wf.Params); err != nil {
+			return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to unmarshal params of workflow %s", row.ID)
+		}
+	}
+	return wf, nil
+}
+
+func workflowStepRows(wf *Workflow, steps []*WorkflowStep) ([]*models.WorkflowStep, error) {
+	positions := make(map[string]int, len(wf.Steps))
+	for i, step := range wf.Steps {
+		positions[step.Name] = i
+	}
+
+	rows := make([]*models.WorkflowStep, len(steps))
+	for i, step := range steps {
+		dependsOn := step.DependsOn
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		dependsOnData, err := json.Marshal(dependsOn)
+		if err != nil {
+			return nil, apperrors.Wrap(err, apperrors.ErrorTypeInternal, "failed to marshal step dependencies")
+		}
+		jobData, err := json.Marshal(step.Job)
+		if err != nil {
+			return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to marshal job of step %s", step.Name)
+		}
+
+		rows[i] = &models.WorkflowStep{
+			WorkflowID:  wf.ID,
+			Name:        step.Name,
+			Position:    positions[step.Name],
+			DependsOn:   dependsOnData,
+			JobID:       step.Job.ID,
+			JobType:     string(step.Job.Type),
+			Job:         jobData,
+			Status:      string(step.Status),
+			CreatedAt:   step.CreatedAt,
+			UpdatedAt:   step.UpdatedAt,
+			CompletedAt: step.CompletedAt,
+		}
+		if step.Parent != "" {
+			rows[i].Parent = &step.Parent
+		}
+		if step.Error != "" {
+			rows[i].Error = &step.Error
+		}
+	}
+	return rows, nil
+}
+
+func workflowStepFromRow(row *models.WorkflowStep) (*WorkflowStep, error) {
+	step := &WorkflowStep{
+		Name:        row.Name,
+		Status:      StepStatus(row.Status),
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		CompletedAt: row.CompletedAt,
+	}
+	if row.Parent != nil {
+		step.Parent = *row.Parent
+	}
+	if row.Error != nil {
+		step.Error = *row.Error
+	}
+	if err := json.Unmarshal(row.DependsOn, &step.DependsOn); err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to unmarshal dependencies of step %s", row.Name)
+	}
+	if err := json.Unmarshal(row.Job, &step.Job); err != nil {
+		return nil, apperrors.Wrapf(err, apperrors.ErrorTypeInternal, "failed to unmarshal job of step %s", row.Name)
+	}
+	return step, nil
+}
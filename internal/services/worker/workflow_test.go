@@ -0,0 +1,348 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	apperrors "github.com/kainuguru/kainuguru-api/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+func setupWorkflowTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+	db := setupQueueTestDB(t)
+
+	schema := `
+CREATE TABLE workflows (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    params TEXT NOT NULL DEFAULT '{}',
+    error TEXT,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    completed_at DATETIME
+);
+CREATE TABLE workflow_steps (
+    workflow_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    parent TEXT,
+    depends_on TEXT NOT NULL DEFAULT '[]',
+    job_id TEXT NOT NULL,
+    job_type TEXT NOT NULL,
+    job TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    completed_at DATETIME,
+    PRIMARY KEY (workflow_id, name)
+);`
+	if _, err := db.ExecContext(context.Background(), schema); err != nil {
+		t.Fatalf("failed to create workflow schema: %v", err)
+	}
+	return db
+}
+
+func chainDefinition() *worker.WorkflowDefinition {
+	return &worker.WorkflowDefinition{
+		Name:   "chain",
+		Params: map[string]interface{}{"stores": []interface{}{"iki"}},
+		Steps: []worker.WorkflowStepSpec{
+			{Name: "scrape", Job: &worker.Job{ID: "scrape", Type: worker.JobTypeScrapeFlyer, MaxAttempts: 1}},
+			{Name: "match", Job: &worker.Job{ID: "match", Type: worker.JobTypeMatchProducts}, DependsOn: []string{"scrape"}},
+			{Name: "alerts", Job: &worker.Job{ID: "alerts", Type: worker.JobTypeEvaluatePriceAlerts}, DependsOn: []string{"match"}},
+			{Name: "expire", Job: &worker.Job{ID: "expire", Type: worker.JobTypeExpireFlyerItems}, DependsOn: []string{"match"}},
+		},
+	}
+}
+
+// finishNext runs the next queued job to the given outcome, as a worker would
+func finishNext(t *testing.T, ctx context.Context, queue worker.Queue, engine *worker.WorkflowEngine, fail bool) *worker.Job {
+	t.Helper()
+	job, err := queue.Dequeue(ctx, 0)
+	if err != nil || job == nil {
+		t.Fatalf("Dequeue() = %+v, %v, want a job", job, err)
+	}
+	if fail {
+		err = queue.Fail(ctx, job, "boom")
+	} else {
+		err = queue.Complete(ctx, job)
+	}
+	if err != nil {
+		t.Fatalf("finishing job %s: %v", job.ID, err)
+	}
+	engine.JobFinished(ctx, job)
+	return job
+}
+
+func stepStatuses(t *testing.T, ctx context.Context, engine *worker.WorkflowEngine, id string) (worker.WorkflowStatus, map[string]worker.StepStatus) {
+	t.Helper()
+	wf, err := engine.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	statuses := make(map[string]worker.StepStatus, len(wf.Steps))
+	for _, step := range wf.Steps {
+		statuses[step.Name] = step.Status
+	}
+	return wf.Status, statuses
+}
+
+func TestWorkflowEngine_QueuesStepsOnceTheirDependenciesComplete(t *testing.T) {
+	ctx := context.Background()
+	db := setupWorkflowTestDB(t)
+	queue := worker.NewPostgresQueue(db, worker.DefaultQueueName, worker.QueueOptions{})
+	engine := worker.NewWorkflowEngine(db, queue)
+
+	wf, err := engine.StartDefinition(ctx, chainDefinition())
+	if err != nil {
+		t.Fatalf("StartDefinition() error = %v", err)
+	}
+	if wf.Status != worker.WorkflowStatusRunning || len(wf.Steps) != 4 || wf.Params["stores"] == nil {
+		t.Fatalf("StartDefinition() = %+v", wf)
+	}
+
+	if job := finishNext(t, ctx, queue, engine, false); job.ID != "scrape" {
+		t.Fatalf("first job = %s, want scrape", job.ID)
+	}
+	_, statuses := stepStatuses(t, ctx, engine, wf.ID)
+	if statuses["scrape"] != worker.StepStatusCompleted || statuses["match"] != worker.StepStatusQueued || statuses["alerts"] != worker.StepStatusWaiting {
+		t.Fatalf("steps after scrape = %v", statuses)
+	}
+
+	finishNext(t, ctx, queue, engine, false)
+	finishNext(t, ctx, queue, engine, false)
+	finishNext(t, ctx, queue, engine, false)
+
+	status, statuses := stepStatuses(t, ctx, engine, wf.ID)
+	if status != worker.WorkflowStatusCompleted {
+		t.Fatalf("workflow status = %s, want completed (steps %v)", status, statuses)
+	}
+	if job, err := queue.Dequeue(ctx, 0); err != nil || job != nil {
+		t.Fatalf("Dequeue() after the workflow = %+v, %v, want no job", job, err)
+	}
+
+	listed, err := engine.List(ctx, worker.WorkflowFilter{Status: worker.WorkflowStatusCompleted, Name: "chain"})
+	if err != nil || len(listed) != 1 || listed[0].ID != wf.ID || listed[0].Steps != nil {
+		t.Fatalf("List() = %+v, %v", listed, err)
+	}
+}
+
+func TestWorkflowEngine_RetryingAFailedStepResumes(t *testing.T) {
+	ctx := context.Background()
+	db := setupWorkflowTestDB(t)
+	queue := worker.NewPostgresQueue(db, worker.DefaultQueueName, worker.QueueOptions{})
+	engine := worker.NewWorkflowEngine(db, queue)
+
+	wf, err := engine.StartDefinition(ctx, chainDefinition())
+	if err != nil {
+		t.Fatalf("StartDefinition() error = %v", err)
+	}
+
+	finishNext(t, ctx, queue, engine, true)
+	failed, err := engine.Get(ctx, wf.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if failed.Status != worker.WorkflowStatusFailed || failed.Error != "step scrape failed: boom" || failed.CompletedAt == nil {
+		t.Fatalf("workflow after the failure = %+v", failed)
+	}
+	if _, statuses := stepStatuses(t, ctx, engine, wf.ID); statuses["match"] != worker.StepStatusWaiting {
+		t.Fatalf("steps after the failure = %v, want match waiting", statuses)
+	}
+
+	if _, err := queue.RetryJob(ctx, "scrape"); err != nil {
+		t.Fatalf("RetryJob() error = %v", err)
+	}
+	finishNext(t, ctx, queue, engine, false)
+
+	status, statuses := stepStatuses(t, ctx, engine, wf.ID)
+	if status != worker.WorkflowStatusRunning || statuses["scrape"] != worker.StepStatusCompleted || statuses["match"] != worker.StepStatusQueued {
+		t.Fatalf("workflow after the retry = %s %v, want running with match queued", status, statuses)
+	}
+}
+
+func TestWorkflowEngine_FansOutAndBackIn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := setupWorkflowTestDB(t)
+	queue := worker.NewPostgresQueue(db, worker.DefaultQueueName, worker.QueueOptions{PollInterval: 10 * time.Millisecond})
+	engine := worker.NewWorkflowEngine(db, queue)
+	processor := worker.NewWorkerProcessor(queue, nil, worker.ProcessorConfig{Concurrency: 3})
+	engine.Register(processor)
+
+	var mu sync.Mutex
+	var ran []string
+	record := func(job *worker.Job) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, job.ID)
+	}
+
+	// scrape fans out into a rasterize job per flyer, each rasterize job into a page job
+	processor.RegisterHandler(worker.JobTypeScrapeFlyer, func(ctx context.Context, job *worker.Job) error {
+		record(job)
+		for _, id := range []string{"flyer-1", "flyer-2"} {
+			if err := engine.Enqueue(ctx, &worker.Job{ID: id, Type: worker.JobTypeRasterizeFlyer}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	processor.RegisterHandler(worker.JobTypeRasterizeFlyer, func(ctx context.Context, job *worker.Job) error {
+		record(job)
+		return engine.Enqueue(ctx, &worker.Job{ID: job.ID + ":page-1", Type: worker.JobTypeExtractProducts})
+	})
+	processor.RegisterHandler(worker.JobTypeExtractProducts, func(ctx context.Context, job *worker.Job) error {
+		// Pages take longer than their flyers, so the fan-in has to wait for them
+		time.Sleep(50 * time.Millisecond)
+		record(job)
+		return nil
+	})
+	for _, jobType := range []worker.JobType{worker.JobTypeMatchProducts, worker.JobTypeEvaluatePriceAlerts, worker.JobTypeExpireFlyerItems} {
+		processor.RegisterHandler(jobType, func(ctx context.Context, job *worker.Job) error {
+			record(job)
+			return nil
+		})
+	}
+
+	wf, err := engine.StartDefinition(ctx, chainDefinition())
+	if err != nil {
+		t.Fatalf("StartDefinition() error = %v", err)
+	}
+	if err := processor.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		cancel()
+		_ = processor.Stop()
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got, err := engine.Get(ctx, wf.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got.Status != worker.WorkflowStatusRunning {
+			wf = got
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("workflow still running: %+v", got)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if wf.Status != worker.WorkflowStatusCompleted {
+		t.Fatalf("workflow status = %s (%s), want completed", wf.Status, wf.Error)
+	}
+	parents := make(map[string]string)
+	for _, step := range wf.Steps {
+		if step.Status != worker.StepStatusCompleted {
+			t.Errorf("step %s is %s, want completed", step.Name, step.Status)
+		}
+		parents[step.Name] = step.Parent
+	}
+	wantParents := map[string]string{
+		"flyer-1": "scrape", "flyer-2": "scrape",
+		"flyer-1:page-1": "flyer-1", "flyer-2:page-1": "flyer-2",
+	}
+	for name, parent := range wantParents {
+		if parents[name] != parent {
+			t.Errorf("parent of step %s = %q, want %q", name, parents[name], parent)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	position := make(map[string]int, len(ran))
+	for i, id := range ran {
+		position[id] = i
+	}
+	for _, page := range []string{"flyer-1:page-1", "flyer-2:page-1"} {
+		if position[page] > position["match"] {
+			t.Errorf("match ran before %s: %v", page, ran)
+		}
+	}
+	if position["alerts"] < position["match"] || position["expire"] < position["match"] {
+		t.Errorf("follow-up steps ran before match: %v", ran)
+	}
+}
+
+func TestWorkflowEngine_RejectsInvalidDefinitions(t *testing.T) {
+	job := func(id string) *worker.Job { return &worker.Job{ID: id, Type: worker.JobTypeUpdatePrices} }
+
+	tests := []struct {
+		name string
+		def  *worker.WorkflowDefinition
+	}{
+		{"no name", &worker.WorkflowDefinition{Steps: []worker.WorkflowStepSpec{{Name: "a", Job: job("")}}}},
+		{"no steps", &worker.WorkflowDefinition{Name: "empty"}},
+		{"unnamed step", &worker.WorkflowDefinition{Name: "wf", Steps: []worker.WorkflowStepSpec{{Job: job("")}}}},
+		{"step without job", &worker.WorkflowDefinition{Name: "wf", Steps: []worker.WorkflowStepSpec{{Name: "a"}}}},
+		{"duplicate step", &worker.WorkflowDefinition{Name: "wf", Steps: []worker.WorkflowStepSpec{
+			{Name: "a", Job: job("")}, {Name: "a", Job: job("")},
+		}}},
+		{"dependency declared later", &worker.WorkflowDefinition{Name: "wf", Steps: []worker.WorkflowStepSpec{
+			{Name: "a", Job: job(""), DependsOn: []string{"b"}}, {Name: "b", Job: job("")},
+		}}},
+		{"job run twice", &worker.WorkflowDefinition{Name: "wf", Steps: []worker.WorkflowStepSpec{
+			{Name: "a", Job: job("same")}, {Name: "b", Job: job("same")},
+		}}},
+	}
+
+	db := setupWorkflowTestDB(t)
+	queue := worker.NewPostgresQueue(db, worker.DefaultQueueName, worker.QueueOptions{})
+	engine := worker.NewWorkflowEngine(db, queue)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := engine.StartDefinition(context.Background(), tt.def); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+				t.Errorf("StartDefinition() error = %v, want a validation error", err)
+			}
+		})
+	}
+
+	if _, err := engine.Start(context.Background(), "unknown", nil); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("Start(unknown) error = %v, want a validation error", err)
+	}
+	if _, err := engine.Get(context.Background(), "missing"); !apperrors.IsType(err, apperrors.ErrorTypeNotFound) {
+		t.Errorf("Get(missing) error = %v, want not found", err)
+	}
+}
+
+func TestFlyerPipeline(t *testing.T) {
+	def, err := worker.FlyerPipeline(map[string]interface{}{
+		worker.WorkflowParamStores:       []interface{}{"iki", "maxima"},
+		worker.WorkflowParamRecordPrices: true,
+	})
+	if err != nil {
+		t.Fatalf("FlyerPipeline() error = %v", err)
+	}
+
+	steps := make(map[string]worker.WorkflowStepSpec, len(def.Steps))
+	for _, step := range def.Steps {
+		steps[step.Name] = step
+	}
+	stores, _ := steps["scrape"].Job.PayloadStrings(worker.PayloadStores)
+	if len(stores) != 2 || stores[0] != "iki" || stores[1] != "maxima" {
+		t.Errorf("scrape stores = %v, want [iki maxima]", stores)
+	}
+	for _, name := range []string{"evaluate_alerts", "detect_expired_items", "record_prices"} {
+		if deps := steps[name].DependsOn; len(deps) != 1 || deps[0] != "match" {
+			t.Errorf("step %s depends on %v, want [match]", name, deps)
+		}
+	}
+
+	if def, _ := worker.FlyerPipeline(nil); len(def.Steps) != 4 {
+		t.Errorf("FlyerPipeline(nil) has %d steps, want 4 without record_prices", len(def.Steps))
+	}
+	if _, err := worker.FlyerPipeline(map[string]interface{}{worker.WorkflowParamStores: "iki"}); !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("FlyerPipeline(stores: string) error = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/models"
+	"github.com/kainuguru/kainuguru-api/internal/monitoring"
+	"github.com/uptrace/bun"
+)
+
+// EvaluatePriceAlertsWorker checks users' price alerts against the current flyer
+// prices of their product masters.
+//
+// This worker runs after flyers were ingested and matched to:
+// - Find the cheapest current price of each alerted master, overall and per store
+// - Record a trigger on each alert whose condition the price meets
+// - Remember the price, so a price already alerted on does not trigger again
+type EvaluatePriceAlertsWorker struct {
+	db     *bun.DB
+	logger *slog.Logger
+}
+
+// NewEvaluatePriceAlertsWorker creates a new worker instance for price alert evaluation
+func NewEvaluatePriceAlertsWorker(db *bun.DB) *EvaluatePriceAlertsWorker {
+	return &EvaluatePriceAlertsWorker{
+		db:     db,
+		logger: slog.Default().With("worker", "evaluate_price_alerts"),
+	}
+}
+
+type currentPrice struct {
+	ProductMasterID int     `bun:"product_master_id"`
+	StoreID         int     `bun:"store_id"`
+	Price           float64 `bun:"price"`
+}
+
+// Run executes the price alert evaluation job
+func (w *EvaluatePriceAlertsWorker) Run(ctx context.Context) error {
+	w.logger.Info("starting price alert evaluation job")
+	startTime := time.Now()
+
+	var alerts []*models.PriceAlert
+	err := w.db.NewSelect().
+		Model(&alerts).
+		Where("pa.is_active = true").
+		Where("pa.expires_at IS NULL OR pa.expires_at > ?", time.Now()).
+		Scan(ctx)
+	if err != nil {
+		monitoring.WizardWorkerRunsTotal.WithLabelValues("evaluate_price_alerts", "error").Inc()
+		return fmt.Errorf("failed to load price alerts: %w", err)
+	}
+	if len(alerts) == 0 {
+		w.logger.Info("no active price alerts")
+		return nil
+	}
+
+	masterIDs := make([]int, 0, len(alerts))
+	for _, alert := range alerts {
+		masterIDs = append(masterIDs, alert.ProductMasterID)
+	}
+
+	var prices []currentPrice
+	err = w.db.NewSelect().
+		TableExpr("products AS p").
+		ColumnExpr("p.product_master_id, p.store_id, MIN(p.current_price) AS price").
+		Where("p.product_master_id IN (?)", bun.In(masterIDs)).
+		Where("p.valid_from <= CURRENT_DATE").
+		Where("p.valid_to >= CURRENT_DATE").
+		Where("p.current_price >= 0").
+		Group("p.product_master_id", "p.store_id").
+		Scan(ctx, &prices)
+	if err != nil {
+		monitoring.WizardWorkerRunsTotal.WithLabelValues("evaluate_price_alerts", "error").Inc()
+		return fmt.Errorf("failed to load current prices: %w", err)
+	}
+
+	// The cheapest price of each master overall, and at each store
+	cheapest := make(map[int]float64)
+	atStore := make(map[[2]int]float64)
+	for _, p := range prices {
+		atStore[[2]int{p.ProductMasterID, p.StoreID}] = p.Price
+		if current, ok := cheapest[p.ProductMasterID]; !ok || p.Price < current {
+			cheapest[p.ProductMasterID] = p.Price
+		}
+	}
+
+	triggered, updated := 0, 0
+	for _, alert := range alerts {
+		var price float64
+		var ok bool
+		if alert.StoreID != nil {
+			price, ok = atStore[[2]int{alert.ProductMasterID, *alert.StoreID}]
+		} else {
+			price, ok = cheapest[alert.ProductMasterID]
+		}
+		if !ok || (alert.LastPrice != nil && *alert.LastPrice == price) {
+			continue
+		}
+
+		if alert.ShouldTrigger(price) {
+			alert.MarkTriggered()
+			triggered++
+			w.logger.Info("price alert triggered",
+				"alert_id", alert.ID,
+				"user_id", alert.UserID,
+				"product_master_id", alert.ProductMasterID,
+				"alert_type", alert.AlertType,
+				"price", price,
+			)
+		}
+		alert.UpdateLastPrice(price)
+
+		_, err := w.db.NewUpdate().
+			Model(alert).
+			Column("last_price", "last_triggered", "trigger_count", "updated_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			monitoring.WizardWorkerRunsTotal.WithLabelValues("evaluate_price_alerts", "error").Inc()
+			return fmt.Errorf("failed to update price alert %d: %w", alert.ID, err)
+		}
+		updated++
+	}
+
+	duration := time.Since(startTime)
+	monitoring.WizardWorkerRunsTotal.WithLabelValues("evaluate_price_alerts", "success").Inc()
+	monitoring.WizardWorkerDurationSeconds.WithLabelValues("evaluate_price_alerts").Observe(duration.Seconds())
+
+	w.logger.Info("price alert evaluation job completed",
+		"alerts", len(alerts),
+		"updated", updated,
+		"triggered", triggered,
+		"duration_ms", duration.Milliseconds(),
+	)
+	return nil
+}
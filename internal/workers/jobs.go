@@ -0,0 +1,215 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/services"
+	"github.com/kainuguru/kainuguru-api/internal/services/archive"
+	"github.com/kainuguru/kainuguru-api/internal/services/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/uptrace/bun"
+)
+
+// defaultArchiveOlderThanDays is how old rows get before an archive_data job
+// moves them to archives when the job does not say
+const defaultArchiveOlderThanDays = 90
+
+// defaultCleanupOlderThanDays is how long finished extraction jobs are kept when
+// a cleanup_data job does not say
+const defaultCleanupOlderThanDays = 180
+
+// JobHandlers runs the workers of this package, and the archival and cleanup
+// maintenance, as jobs of the job queue so the worker daemon's schedules
+// drive them
+type JobHandlers struct {
+	expireFlyerItems      *ExpireFlyerItemsWorker
+	cleanupSessions       *CleanupExpiredSessionsWorker
+	productMaster         *ProductMasterWorker
+	shoppingListMigration *ShoppingListMigrationWorker
+	updatePrices          *UpdatePricesWorker
+	evaluatePriceAlerts   *EvaluatePriceAlertsWorker
+	flyerService          services.FlyerService
+	extractionJobService  services.ExtractionJobService
+	archiver              archive.ArchiverService
+	cleaner               archive.CleanerService
+	logger                *slog.Logger
+}
+
+// NewJobHandlers creates the handlers from the service factory
+func NewJobHandlers(db *bun.DB, redisClient *redis.Client, factory *services.ServiceFactory) *JobHandlers {
+	return &JobHandlers{
+		expireFlyerItems:      NewExpireFlyerItemsWorker(db),
+		cleanupSessions:       NewCleanupExpiredSessionsWorker(redisClient),
+		productMaster:         NewProductMasterWorker(db, factory.ProductMasterService()),
+		shoppingListMigration: NewShoppingListMigrationWorker(factory.ShoppingListMigrationService(), 0),
+		updatePrices:          NewUpdatePricesWorker(db),
+		evaluatePriceAlerts:   NewEvaluatePriceAlertsWorker(db),
+		flyerService:          factory.FlyerService(),
+		extractionJobService:  factory.ExtractionJobService(),
+		archiver:              factory.ArchiverService(),
+		cleaner:               factory.CleanerService(),
+		logger:                slog.Default().With("worker", "jobs"),
+	}
+}
+
+// Register installs the handlers on a worker processor
+func (h *JobHandlers) Register(processor *worker.WorkerProcessor) {
+	processor.RegisterHandler(worker.JobTypeExpireFlyerItems, h.handleExpireFlyerItems)
+	processor.RegisterHandler(worker.JobTypeCleanupSessions, h.handleCleanupSessions)
+	processor.RegisterHandler(worker.JobTypeMatchProducts, h.handleMatchProducts)
+	processor.RegisterHandler(worker.JobTypeMigrateShoppingLists, h.handleMigrateShoppingLists)
+	processor.RegisterHandler(worker.JobTypeUpdatePrices, h.handleUpdatePrices)
+	processor.RegisterHandler(worker.JobTypeEvaluatePriceAlerts, h.handleEvaluatePriceAlerts)
+	processor.RegisterHandler(worker.JobTypeArchiveData, h.handleArchiveData)
+	processor.RegisterHandler(worker.JobTypeCleanupData, h.handleCleanupData)
+	processor.RegisterHandler(worker.JobTypeRunCleanups, h.handleRunCleanups)
+}
+
+func (h *JobHandlers) handleExpireFlyerItems(ctx context.Context, job *worker.Job) error {
+	return h.expireFlyerItems.Run(ctx)
+}
+
+func (h *JobHandlers) handleCleanupSessions(ctx context.Context, job *worker.Job) error {
+	return h.cleanupSessions.Run(ctx)
+}
+
+func (h *JobHandlers) handleMatchProducts(ctx context.Context, job *worker.Job) error {
+	progress := worker.ProgressFromContext(ctx)
+	progress.Step(ctx, "matching unmatched products", 0)
+	if err := h.productMaster.ProcessUnmatchedProducts(ctx); err != nil {
+		return err
+	}
+	progress.Step(ctx, "updating master confidence", 50)
+	if err := h.productMaster.UpdateMasterConfidence(ctx); err != nil {
+		return err
+	}
+	progress.Step(ctx, "done", 100)
+	return nil
+}
+
+func (h *JobHandlers) handleMigrateShoppingLists(ctx context.Context, job *worker.Job) error {
+	return h.shoppingListMigration.RunOnce(ctx)
+}
+
+func (h *JobHandlers) handleUpdatePrices(ctx context.Context, job *worker.Job) error {
+	return h.updatePrices.Run(ctx)
+}
+
+func (h *JobHandlers) handleEvaluatePriceAlerts(ctx context.Context, job *worker.Job) error {
+	return h.evaluatePriceAlerts.Run(ctx)
+}
+
+// handleArchiveData archives flyers that have ended, following the flyer
+// service's retention, then moves the rows older than the job's
+// archive_older_than_days to archives and deletes the archives past retention.
+// Partitioned products follow the partition retention instead: the weeks the
+// partition manager detached are archived. Products and prices go before
+// flyers, which are only archived once nothing references them.
+func (h *JobHandlers) handleArchiveData(ctx context.Context, job *worker.Job) error {
+	days, ok := job.PayloadInt(worker.PayloadArchiveOlderThanDays)
+	if !ok || days <= 0 {
+		days = defaultArchiveOlderThanDays
+	}
+	olderThan := time.Duration(days) * 24 * time.Hour
+
+	progress := worker.ProgressFromContext(ctx)
+	progress.Step(ctx, "marking ended flyers archived", 0)
+	archived, err := h.flyerService.ArchiveOldFlyers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to archive old flyers: %w", err)
+	}
+	progress.Add(ctx, "flyers", int64(archived))
+
+	steps := []struct {
+		dataType archive.ArchiveDataType
+		run      func(context.Context, time.Duration) (*archive.ArchivalResult, error)
+	}{
+		{archive.ArchiveTypeProducts, h.archiver.ArchiveOldProducts},
+		{archive.ArchiveTypePriceHistory, h.archiver.ArchiveOldPrices},
+		{archive.ArchiveTypeFlyers, h.archiver.ArchiveOldFlyers},
+		{archive.ArchiveTypeExtractionJobs, h.archiver.ArchiveCompletedExtractionJobs},
+	}
+	for i, step := range steps {
+		progress.Step(ctx, fmt.Sprintf("archiving %s", step.dataType), float64(10+i*20))
+		result, err := step.run(ctx, olderThan)
+		if err != nil {
+			return err
+		}
+		progress.Add(ctx, "archived_"+string(step.dataType), int64(result.RecordsArchived))
+		if result.Error != "" {
+			h.logger.Warn("archive incomplete", "job_id", job.ID, "archive_id", result.ArchiveID, "error", result.Error)
+		}
+	}
+
+	progress.Step(ctx, "deleting expired archives", 90)
+	expired, err := h.archiver.DeleteExpiredArchives(ctx)
+	if err != nil {
+		return err
+	}
+	progress.Add(ctx, "expired_archives", int64(len(expired)))
+	progress.Step(ctx, "done", 100)
+
+	h.logger.Info("archived old data",
+		"job_id", job.ID,
+		"flyers", archived,
+		"older_than_days", days,
+		"expired_archives", len(expired))
+	return nil
+}
+
+// handleCleanupData deletes expired extraction jobs, and finished ones older
+// than the job's cleanup_older_than_days
+func (h *JobHandlers) handleCleanupData(ctx context.Context, job *worker.Job) error {
+	days, ok := job.PayloadInt(worker.PayloadCleanupOlderThanDays)
+	if !ok || days <= 0 {
+		days = defaultCleanupOlderThanDays
+	}
+
+	progress := worker.ProgressFromContext(ctx)
+	progress.Step(ctx, "deleting expired extraction jobs", 0)
+	expired, err := h.extractionJobService.CleanupExpiredJobs(ctx)
+	if err != nil {
+		return err
+	}
+	progress.Add(ctx, "expired", int64(expired))
+
+	progress.Step(ctx, "deleting finished extraction jobs", 50)
+	finished, err := h.extractionJobService.CleanupCompletedJobs(ctx, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	progress.Add(ctx, "finished", int64(finished))
+	progress.Step(ctx, "done", 100)
+
+	h.logger.Info("cleaned up extraction jobs",
+		"job_id", job.ID,
+		"expired", expired,
+		"finished", finished,
+		"older_than_days", days)
+	return nil
+}
+
+// handleRunCleanups runs the storage cleanups scheduled with the cleanup
+// command whose next run has come
+func (h *JobHandlers) handleRunCleanups(ctx context.Context, job *worker.Job) error {
+	progress := worker.ProgressFromContext(ctx)
+	progress.Step(ctx, "running due cleanups", 0)
+	results, err := h.cleaner.RunDueCleanups(ctx)
+	for _, result := range results {
+		progress.Add(ctx, result.OperationType, int64(result.FilesDeleted))
+		h.logger.Info("ran scheduled cleanup",
+			"job_id", job.ID,
+			"type", result.OperationType,
+			"files_deleted", result.FilesDeleted,
+			"bytes_freed", result.BytesFreed,
+			"errors", len(result.Errors))
+	}
+	if err != nil {
+		return err
+	}
+	progress.Step(ctx, "done", 100)
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/kainuguru/kainuguru-api/internal/models"
 	"github.com/kainuguru/kainuguru-api/internal/productmaster"
 	"github.com/kainuguru/kainuguru-api/internal/repositories"
 	"github.com/kainuguru/kainuguru-api/internal/services"
@@ -77,7 +78,8 @@ func (w *ProductMasterWorker) ProcessUnmatchedProducts(ctx context.Context) erro
 
 		if len(matches) > 0 {
 			match := matches[0]
-			if match.MatchScore >= 0.85 {
+			thresholds := w.masterService.GetMatchThresholds(ctx, product.Category)
+			if match.MatchScore >= thresholds.AutoMatch {
 				err = w.masterService.MatchProduct(ctx, product.ID, match.Master.ID)
 				if err != nil {
 					w.logger.Error("failed to match product",
@@ -94,7 +96,23 @@ func (w *ProductMasterWorker) ProcessUnmatchedProducts(ctx context.Context) erro
 					slog.Int64("master_id", match.Master.ID),
 					slog.Float64("match_score", match.MatchScore),
 				)
-			} else if match.MatchScore >= 0.65 {
+			} else if match.MatchScore >= thresholds.Review {
+				// Queue the suggestion so a reviewer can accept, reject or reassign it
+				if err := w.repo.CreatePendingMatch(ctx, &models.ProductMasterMatch{
+					ProductID:       int64(product.ID),
+					ProductMasterID: match.Master.ID,
+					Confidence:      match.MatchScore,
+					MatchType:       match.Method,
+					MatchScore:      &match.MatchScore,
+				}); err != nil {
+					w.logger.Error("failed to queue match for review",
+						slog.Int("product_id", product.ID),
+						slog.Int64("suggested_master_id", match.Master.ID),
+						slog.String("error", err.Error()),
+					)
+					failed++
+					continue
+				}
 				product.RequiresReview = true
 				if err := w.repo.MarkProductForReview(ctx, product.ID); err != nil {
 					w.logger.Error("failed to mark product for review",
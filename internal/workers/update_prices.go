@@ -0,0 +1,92 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kainuguru/kainuguru-api/internal/monitoring"
+	"github.com/uptrace/bun"
+)
+
+// UpdatePricesWorker records the flyer prices of matched products in the price
+// history and retires history rows whose flyer has ended.
+//
+// This worker runs daily to:
+// - Add one price_history row per product master, store and current flyer
+// - Keep rows recorded by earlier runs untouched, so re-runs are no-ops
+// - Mark rows past their valid_to date inactive
+type UpdatePricesWorker struct {
+	db     *bun.DB
+	logger *slog.Logger
+}
+
+// NewUpdatePricesWorker creates a new worker instance for price history updates
+func NewUpdatePricesWorker(db *bun.DB) *UpdatePricesWorker {
+	return &UpdatePricesWorker{
+		db:     db,
+		logger: slog.Default().With("worker", "update_prices"),
+	}
+}
+
+// Run executes the price history update job
+func (w *UpdatePricesWorker) Run(ctx context.Context) error {
+	w.logger.Info("starting price history update job")
+	startTime := time.Now()
+
+	// When a flyer lists the same master more than once, the cheapest offer is recorded
+	result, err := w.db.ExecContext(ctx, `
+		INSERT INTO price_history (
+			product_master_id, store_id, flyer_id,
+			price, original_price, card_price, app_price, is_on_sale,
+			recorded_at, valid_from, valid_to, sale_start_date, sale_end_date,
+			source, extraction_method, confidence, is_available, stock_level
+		)
+		SELECT DISTINCT ON (p.product_master_id, p.store_id, p.flyer_id)
+			p.product_master_id, p.store_id, p.flyer_id,
+			p.current_price, p.original_price, p.card_price, p.app_price, p.is_on_sale,
+			NOW(), p.valid_from, p.valid_to, p.sale_start_date, p.sale_end_date,
+			'flyer', p.extraction_method, LEAST(GREATEST(p.extraction_confidence, 0), 1),
+			p.is_available, p.stock_level
+		FROM products p
+		WHERE p.product_master_id IS NOT NULL
+			AND p.valid_to >= CURRENT_DATE
+			AND p.current_price >= 0
+			AND NOT EXISTS (
+				SELECT 1 FROM price_history ph
+				WHERE ph.product_master_id = p.product_master_id
+					AND ph.store_id = p.store_id
+					AND ph.flyer_id = p.flyer_id
+			)
+		ORDER BY p.product_master_id, p.store_id, p.flyer_id, p.current_price
+	`)
+	if err != nil {
+		monitoring.WizardWorkerRunsTotal.WithLabelValues("update_prices", "error").Inc()
+		return fmt.Errorf("failed to record flyer prices: %w", err)
+	}
+	recorded, _ := result.RowsAffected()
+
+	result, err = w.db.NewUpdate().
+		Table("price_history").
+		Set("is_active = false").
+		Where("is_active = true").
+		Where("valid_to < CURRENT_DATE").
+		Exec(ctx)
+	if err != nil {
+		monitoring.WizardWorkerRunsTotal.WithLabelValues("update_prices", "error").Inc()
+		return fmt.Errorf("failed to retire ended prices: %w", err)
+	}
+	retired, _ := result.RowsAffected()
+
+	duration := time.Since(startTime)
+	monitoring.WizardWorkerRunsTotal.WithLabelValues("update_prices", "success").Inc()
+	monitoring.WizardWorkerDurationSeconds.WithLabelValues("update_prices").Observe(duration.Seconds())
+
+	w.logger.Info("price history update job completed",
+		"recorded", recorded,
+		"retired", retired,
+		"duration_ms", duration.Milliseconds(),
+	)
+	return nil
+}
@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Migration: Create enrichment_runs and enrichment_run_pages tables
+-- Each cmd/enrich-flyers invocation is recorded as a run so that an interrupted
+-- run can be resumed and failed pages retried without re-processing the rest.
+
+CREATE TABLE IF NOT EXISTS enrichment_runs (
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(50) NOT NULL DEFAULT 'running', -- 'running', 'completed', 'failed', 'interrupted'
+
+    -- Options the run was started with (store, date, batch size, ...)
+    options JSONB NOT NULL DEFAULT '{}'::jsonb,
+
+    -- Aggregated progress, recomputed from enrichment_run_pages
+    pages_total INTEGER NOT NULL DEFAULT 0,
+    pages_completed INTEGER NOT NULL DEFAULT 0,
+    pages_failed INTEGER NOT NULL DEFAULT 0,
+    products_extracted INTEGER NOT NULL DEFAULT 0,
+    tokens_used INTEGER NOT NULL DEFAULT 0,
+
+    last_error TEXT,
+
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS enrichment_run_pages (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES enrichment_runs(id) ON DELETE CASCADE,
+    flyer_id INTEGER NOT NULL REFERENCES flyers(id) ON DELETE CASCADE,
+    flyer_page_id INTEGER NOT NULL REFERENCES flyer_pages(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending', -- 'pending', 'processing', 'completed', 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    products_extracted INTEGER NOT NULL DEFAULT 0,
+    tokens_used INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(run_id, flyer_page_id)
+);
+
+CREATE INDEX idx_enrichment_runs_status ON enrichment_runs(status);
+CREATE INDEX idx_enrichment_runs_started_at ON enrichment_runs(started_at DESC);
+CREATE INDEX idx_enrichment_run_pages_run_status ON enrichment_run_pages(run_id, status);
+CREATE INDEX idx_enrichment_run_pages_flyer_page ON enrichment_run_pages(flyer_page_id);
+
+-- Speeds up replacing a page's products when it is re-processed
+CREATE INDEX IF NOT EXISTS idx_products_flyer_page_id ON products(flyer_page_id);
+
+CREATE TRIGGER update_enrichment_runs_updated_at
+    BEFORE UPDATE ON enrichment_runs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_enrichment_run_pages_updated_at
+    BEFORE UPDATE ON enrichment_run_pages
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE enrichment_runs IS 'One row per enrichment CLI invocation, used for resume and retry';
+COMMENT ON TABLE enrichment_run_pages IS 'Per-page processing status, token usage and errors for an enrichment run';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_products_flyer_page_id;
+DROP TABLE IF EXISTS enrichment_run_pages;
+DROP TABLE IF EXISTS enrichment_runs;
+-- +goose StatementEnd
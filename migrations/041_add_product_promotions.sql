@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Structured promotion parsed from special_discount ("1+1", "3 už 2 €", "Antra prekė -50%")
+ALTER TABLE products ADD COLUMN IF NOT EXISTS promotion JSONB;
+
+-- Per-item price at the promotion's qualifying quantity, used for price sorting
+ALTER TABLE products ADD COLUMN IF NOT EXISTS effective_price DECIMAL(10,4);
+
+-- Products without a structured promotion cost their current price
+UPDATE products
+SET effective_price = current_price
+WHERE effective_price IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_effective_price
+ON products (effective_price)
+WHERE is_available = TRUE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_products_effective_price;
+
+ALTER TABLE products DROP COLUMN IF EXISTS effective_price;
+ALTER TABLE products DROP COLUMN IF EXISTS promotion;
+
+-- +goose StatementEnd
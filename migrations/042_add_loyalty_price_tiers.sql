@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Loyalty price tiers: card-holder ("Aitvaras", "Mano Rimi", IKI) and app-only prices
+ALTER TABLE products ADD COLUMN IF NOT EXISTS card_price DECIMAL(10,2);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS app_price DECIMAL(10,2);
+
+-- Per-item promotion price for shoppers without the store's loyalty programme
+ALTER TABLE products ADD COLUMN IF NOT EXISTS effective_regular_price DECIMAL(10,4);
+
+-- Loyalty-only promotions printed the card price as the current price
+UPDATE products
+SET card_price = current_price
+WHERE card_price IS NULL
+  AND promotion IS NOT NULL
+  AND (promotion->>'loyalty_only')::boolean IS TRUE;
+
+UPDATE products
+SET effective_regular_price = CASE
+        WHEN card_price IS NOT NULL AND original_price IS NOT NULL AND original_price > 0 THEN original_price
+        ELSE effective_price
+    END
+WHERE effective_regular_price IS NULL;
+
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS card_price DECIMAL(10,2);
+ALTER TABLE price_history ADD COLUMN IF NOT EXISTS app_price DECIMAL(10,2);
+
+-- Loyalty programmes the user belongs to (aitvaras, mano_rimi, iki)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS loyalty_programs TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE users DROP COLUMN IF EXISTS loyalty_programs;
+
+ALTER TABLE price_history DROP COLUMN IF EXISTS app_price;
+ALTER TABLE price_history DROP COLUMN IF EXISTS card_price;
+
+ALTER TABLE products DROP COLUMN IF EXISTS effective_regular_price;
+ALTER TABLE products DROP COLUMN IF EXISTS app_price;
+ALTER TABLE products DROP COLUMN IF EXISTS card_price;
+
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- EAN/UPC barcode printed on the flyer, stored as zero-padded GTIN-14
+ALTER TABLE products ADD COLUMN IF NOT EXISTS barcode VARCHAR(14);
+
+CREATE INDEX IF NOT EXISTS idx_products_barcode
+ON products (barcode)
+WHERE barcode IS NOT NULL;
+
+-- Master barcodes are GTIN-14 too; pad existing EAN-8/UPC-A/EAN-13 values
+UPDATE product_masters
+SET barcode = LPAD(barcode, 14, '0')
+WHERE barcode ~ '^[0-9]{8}$|^[0-9]{12,13}$';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_products_barcode;
+ALTER TABLE products DROP COLUMN IF EXISTS barcode;
+
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Product/master pairs rejected during match review. Products are re-created
+-- for every flyer, so pairs are keyed by the normalized product name rather
+-- than the product ID to keep the matcher from proposing them again next week.
+CREATE TABLE IF NOT EXISTS product_master_match_rejections (
+    id BIGSERIAL PRIMARY KEY,
+    product_master_id BIGINT NOT NULL REFERENCES product_masters(id) ON DELETE CASCADE,
+    normalized_name TEXT NOT NULL,
+    product_id BIGINT, -- product the rejection was made on, for auditing
+    rejected_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(product_master_id, normalized_name)
+);
+
+CREATE INDEX idx_product_master_match_rejections_name ON product_master_match_rejections(normalized_name);
+
+-- Review queue is read highest confidence first, oldest first within a score
+CREATE INDEX IF NOT EXISTS idx_product_master_matches_pending_queue
+ON product_master_matches (confidence DESC, created_at)
+WHERE review_status = 'pending';
+
+COMMENT ON TABLE product_master_match_rejections IS 'Negative matching examples: product names that must never be matched to the master';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_product_master_matches_pending_queue;
+DROP TABLE IF EXISTS product_master_match_rejections;
+
+-- +goose StatementEnd
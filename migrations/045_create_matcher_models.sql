@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Product matcher weights learned from reviewed product_master_matches.
+-- category '' holds the global model used when a category has no model of its own.
+CREATE TABLE IF NOT EXISTS matcher_models (
+    id BIGSERIAL PRIMARY KEY,
+    category VARCHAR(255) NOT NULL DEFAULT '',
+    weights JSONB NOT NULL, -- strategy name -> logistic regression coefficient
+    bias DOUBLE PRECISION NOT NULL DEFAULT 0,
+    auto_match_threshold DECIMAL(5, 4) NOT NULL CHECK (auto_match_threshold >= 0 AND auto_match_threshold <= 1),
+    review_threshold DECIMAL(5, 4) NOT NULL CHECK (review_threshold >= 0 AND review_threshold <= 1),
+    training_samples INTEGER NOT NULL DEFAULT 0,
+    precision DECIMAL(5, 4),
+    recall DECIMAL(5, 4),
+    trained_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(category)
+);
+
+COMMENT ON TABLE matcher_models IS 'Per-category product matcher weights and thresholds learned from match reviews';
+COMMENT ON COLUMN matcher_models.auto_match_threshold IS 'Score at or above which products are matched without review';
+COMMENT ON COLUMN matcher_models.review_threshold IS 'Score at or above which matches are queued for review instead of creating a new master';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS matcher_models;
+
+-- +goose StatementEnd
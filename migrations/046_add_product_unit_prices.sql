@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Package size in kg, l or vnt. and the effective regular price per that unit,
+-- computed during enrichment; existing rows are filled by cmd/backfill-unit-prices
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unit_quantity DECIMAL(12, 4);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unit_base VARCHAR(10);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS normalized_unit_price DECIMAL(12, 4);
+
+CREATE INDEX IF NOT EXISTS idx_products_unit_price
+ON products (unit_base, normalized_unit_price)
+WHERE normalized_unit_price IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_products_unit_price;
+ALTER TABLE products DROP COLUMN IF EXISTS normalized_unit_price;
+ALTER TABLE products DROP COLUMN IF EXISTS unit_base;
+ALTER TABLE products DROP COLUMN IF EXISTS unit_quantity;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Curated search dictionary applied to queries before the search functions run.
+-- Terms and replacements are stored lowercase without Lithuanian diacritics.
+CREATE TABLE IF NOT EXISTS search_synonyms (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('synonym', 'expansion', 'brand_alias', 'stop_phrase')),
+    term VARCHAR(255) NOT NULL,
+    replacements TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(type, term)
+);
+
+COMMENT ON TABLE search_synonyms IS 'Search synonyms, one-way expansions, brand aliases and stop-phrases managed by admins';
+COMMENT ON COLUMN search_synonyms.type IS 'synonym: term and replacements are interchangeable; expansion: term also searches replacements; brand_alias: term is rewritten to the first replacement; stop_phrase: term is removed from queries';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS search_synonyms;
+
+-- +goose StatementEnd
@@ -0,0 +1,67 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- One row per product search, written in batches by the search service.
+-- normalized_query is lowercase without Lithuanian diacritics so spelling
+-- variants of a query are reported together.
+CREATE TABLE IF NOT EXISTS search_events (
+    id BIGSERIAL PRIMARY KEY,
+    search_id UUID NOT NULL UNIQUE,
+    query_text VARCHAR(255) NOT NULL,
+    normalized_query VARCHAR(255) NOT NULL,
+    result_count INTEGER NOT NULL DEFAULT 0,
+    execution_ms INTEGER NOT NULL DEFAULT 0,
+    method_used VARCHAR(20) NOT NULL,
+    failed BOOLEAN NOT NULL DEFAULT FALSE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    first_clicked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_events_created_at ON search_events(created_at);
+
+-- Products opened from a search result list
+CREATE TABLE IF NOT EXISTS search_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    search_id UUID NOT NULL,
+    product_id BIGINT NOT NULL,
+    position INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_clicks_search_id ON search_clicks(search_id);
+
+-- Daily rollups per normalized query, updated with every batch of events and clicks
+CREATE TABLE IF NOT EXISTS search_daily_stats (
+    day DATE NOT NULL,
+    normalized_query VARCHAR(255) NOT NULL,
+    search_count INTEGER NOT NULL DEFAULT 0,
+    zero_result_count INTEGER NOT NULL DEFAULT 0,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    total_execution_ms BIGINT NOT NULL DEFAULT 0,
+    click_count INTEGER NOT NULL DEFAULT 0,
+    clicked_search_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (day, normalized_query)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_daily_stats_day ON search_daily_stats(day);
+
+COMMENT ON TABLE search_events IS 'Product searches with result counts and timings, for search analytics';
+COMMENT ON TABLE search_daily_stats IS 'Search analytics aggregated per day and normalized query';
+COMMENT ON COLUMN search_daily_stats.day IS 'Day of the search in UTC';
+COMMENT ON COLUMN search_daily_stats.clicked_search_count IS 'Searches with at least one result click; click-through rate is clicked_search_count / search_count';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS search_daily_stats;
+DROP TABLE IF EXISTS search_clicks;
+DROP TABLE IF EXISTS search_events;
+
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Rebuild popular_product_searches from the recorded searches of the last 90 days.
+-- Each normalized query keeps its most common spelling; queries need at least two
+-- searches that found something to become a suggestion.
+CREATE OR REPLACE FUNCTION refresh_search_suggestions()
+RETURNS void AS $$
+BEGIN
+    DELETE FROM popular_product_searches;
+
+    INSERT INTO popular_product_searches (
+        search_term,
+        normalized_term,
+        search_count,
+        result_count,
+        last_searched_at,
+        created_at,
+        updated_at
+    )
+    SELECT DISTINCT ON (t.normalized_query)
+        t.search_term,
+        normalize_lithuanian_text(t.search_term),
+        SUM(t.searches) OVER (PARTITION BY t.normalized_query),
+        t.results,
+        MAX(t.last_searched_at) OVER (PARTITION BY t.normalized_query),
+        NOW(),
+        NOW()
+    FROM (
+        SELECT
+            LOWER(se.query_text) AS search_term,
+            se.normalized_query,
+            COUNT(*) AS searches,
+            ROUND(AVG(se.result_count))::INTEGER AS results,
+            MAX(se.created_at) AS last_searched_at
+        FROM search_events se
+        WHERE se.created_at >= NOW() - INTERVAL '90 days'
+          AND se.failed = FALSE
+          AND se.result_count > 0
+        GROUP BY LOWER(se.query_text), se.normalized_query
+    ) t
+    WHERE t.normalized_query IN (
+        SELECT normalized_query
+        FROM search_events
+        WHERE created_at >= NOW() - INTERVAL '90 days'
+          AND failed = FALSE
+          AND result_count > 0
+        GROUP BY normalized_query
+        HAVING COUNT(*) >= 2
+    )
+    ORDER BY t.normalized_query, t.searches DESC, t.search_term ASC;
+
+    ANALYZE popular_product_searches;
+END;
+$$ LANGUAGE plpgsql;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+CREATE OR REPLACE FUNCTION refresh_search_suggestions()
+RETURNS void AS $$
+BEGIN
+    -- This function can be used to rebuild the popular_product_searches table
+    -- from search analytics or other sources. For now, it's a placeholder.
+    -- In production, this would analyze search logs and update the table.
+
+    -- Clean up old entries (older than 90 days)
+    DELETE FROM popular_product_searches
+    WHERE last_searched_at < NOW() - INTERVAL '90 days';
+
+    -- Update normalized terms for all entries
+    UPDATE popular_product_searches
+    SET normalized_term = normalize_lithuanian_text(search_term)
+    WHERE normalized_term IS NULL OR normalized_term = '';
+
+    -- Vacuum the table to reclaim space
+    -- Note: VACUUM cannot run inside a function, so we just update stats
+    ANALYZE popular_product_searches;
+END;
+$$ LANGUAGE plpgsql;
+
+-- +goose StatementEnd
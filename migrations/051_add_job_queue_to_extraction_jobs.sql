@@ -0,0 +1,174 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- extraction_jobs doubles as the Postgres backend of the worker job queue.
+-- Queue rows carry the queue name and the job's ID, unique per queue, and a
+-- processing row is leased until lease_expires_at. Rows without a queue_name
+-- stay with the extraction job service.
+ALTER TABLE extraction_jobs
+    ADD COLUMN queue_name VARCHAR(100),
+    ADD COLUMN job_key VARCHAR(255),
+    ADD COLUMN retry_delay_ms BIGINT NOT NULL DEFAULT 30000,
+    ADD COLUMN lease_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX idx_jobs_queue_key ON extraction_jobs(queue_name, job_key);
+CREATE INDEX idx_jobs_queue_ready ON extraction_jobs(queue_name, priority DESC, scheduled_for, id)
+    WHERE status IN ('pending', 'retrying');
+CREATE INDEX idx_jobs_queue_leases ON extraction_jobs(queue_name, lease_expires_at)
+    WHERE status = 'processing';
+
+-- get_next_job leaves queue rows to the job queue
+CREATE OR REPLACE FUNCTION get_next_job(job_types TEXT[], worker_id_param TEXT DEFAULT NULL)
+RETURNS SETOF extraction_jobs AS $$
+BEGIN
+    RETURN QUERY
+    UPDATE extraction_jobs
+    SET
+        status = 'processing',
+        worker_id = COALESCE(worker_id_param, 'worker_' || extract(epoch from now())),
+        started_at = NOW(),
+        attempts = attempts + 1,
+        updated_at = NOW()
+    WHERE id = (
+        SELECT id
+        FROM extraction_jobs
+        WHERE
+            status = 'pending'
+            AND queue_name IS NULL
+            AND (job_types IS NULL OR job_type = ANY(job_types))
+            AND scheduled_for <= NOW()
+            AND (expires_at IS NULL OR expires_at > NOW())
+            AND attempts < max_attempts
+        ORDER BY priority DESC, created_at ASC
+        FOR UPDATE SKIP LOCKED
+        LIMIT 1
+    )
+    RETURNING *;
+END;
+$$ LANGUAGE plpgsql;
+
+-- fail_job retries with the job queue's backoff: retry_delay_ms doubled per
+-- earlier attempt, capped at an hour
+CREATE OR REPLACE FUNCTION fail_job(job_id BIGINT, error_msg TEXT)
+RETURNS BOOLEAN AS $$
+DECLARE
+    current_attempts INTEGER;
+    max_attempts_allowed INTEGER;
+    retry_delay BIGINT;
+BEGIN
+    SELECT attempts, max_attempts, retry_delay_ms INTO current_attempts, max_attempts_allowed, retry_delay
+    FROM extraction_jobs WHERE id = job_id;
+
+    IF current_attempts >= max_attempts_allowed THEN
+        -- Mark as permanently failed
+        UPDATE extraction_jobs
+        SET
+            status = 'failed',
+            error_message = error_msg,
+            error_count = error_count + 1,
+            lease_expires_at = NULL,
+            completed_at = NOW(),
+            updated_at = NOW()
+        WHERE id = job_id;
+    ELSE
+        -- Reset to pending for retry
+        UPDATE extraction_jobs
+        SET
+            status = 'pending',
+            worker_id = NULL,
+            started_at = NULL,
+            lease_expires_at = NULL,
+            error_message = error_msg,
+            error_count = error_count + 1,
+            scheduled_for = NOW() + LEAST(
+                retry_delay * POWER(2, GREATEST(current_attempts - 1, 0)),
+                3600000
+            ) * INTERVAL '1 millisecond',
+            updated_at = NOW()
+        WHERE id = job_id;
+    END IF;
+
+    RETURN FOUND;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION fail_job(job_id BIGINT, error_msg TEXT)
+RETURNS BOOLEAN AS $$
+DECLARE
+    current_attempts INTEGER;
+    max_attempts_allowed INTEGER;
+BEGIN
+    SELECT attempts, max_attempts INTO current_attempts, max_attempts_allowed
+    FROM extraction_jobs WHERE id = job_id;
+
+    IF current_attempts >= max_attempts_allowed THEN
+        -- Mark as permanently failed
+        UPDATE extraction_jobs
+        SET
+            status = 'failed',
+            error_message = error_msg,
+            error_count = error_count + 1,
+            completed_at = NOW(),
+            updated_at = NOW()
+        WHERE id = job_id;
+    ELSE
+        -- Reset to pending for retry
+        UPDATE extraction_jobs
+        SET
+            status = 'pending',
+            worker_id = NULL,
+            started_at = NULL,
+            error_message = error_msg,
+            error_count = error_count + 1,
+            scheduled_for = NOW() + INTERVAL '5 minutes',
+            updated_at = NOW()
+        WHERE id = job_id;
+    END IF;
+
+    RETURN FOUND;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION get_next_job(job_types TEXT[], worker_id_param TEXT DEFAULT NULL)
+RETURNS SETOF extraction_jobs AS $$
+BEGIN
+    RETURN QUERY
+    UPDATE extraction_jobs
+    SET
+        status = 'processing',
+        worker_id = COALESCE(worker_id_param, 'worker_' || extract(epoch from now())),
+        started_at = NOW(),
+        attempts = attempts + 1,
+        updated_at = NOW()
+    WHERE id = (
+        SELECT id
+        FROM extraction_jobs
+        WHERE
+            status = 'pending'
+            AND (job_types IS NULL OR job_type = ANY(job_types))
+            AND scheduled_for <= NOW()
+            AND (expires_at IS NULL OR expires_at > NOW())
+            AND attempts < max_attempts
+        ORDER BY priority DESC, created_at ASC
+        FOR UPDATE SKIP LOCKED
+        LIMIT 1
+    )
+    RETURNING *;
+END;
+$$ LANGUAGE plpgsql;
+
+DELETE FROM extraction_jobs WHERE queue_name IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_jobs_queue_leases;
+DROP INDEX IF EXISTS idx_jobs_queue_ready;
+DROP INDEX IF EXISTS idx_jobs_queue_key;
+
+ALTER TABLE extraction_jobs
+    DROP COLUMN lease_expires_at,
+    DROP COLUMN retry_delay_ms,
+    DROP COLUMN job_key,
+    DROP COLUMN queue_name;
+-- +goose StatementEnd
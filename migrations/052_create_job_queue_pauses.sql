@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Job types paused in a Postgres job queue. Workers skip queued jobs of a
+-- paused type until it is resumed; the jobs themselves stay queued.
+CREATE TABLE job_queue_pauses (
+    queue_name VARCHAR(100) NOT NULL,
+    job_type VARCHAR(50) NOT NULL,
+    paused_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (queue_name, job_type)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job_queue_pauses;
+-- +goose StatementEnd
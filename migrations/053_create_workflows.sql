@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Workflows chain worker jobs: a step's job is queued once the steps it depends
+-- on completed, and a step completes once its job and every job it fanned out
+-- into completed
+CREATE TABLE workflows (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    params JSONB NOT NULL DEFAULT '{}',
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_workflows_status CHECK (status IN ('running', 'completed', 'failed', 'cancelled'))
+);
+
+CREATE INDEX idx_workflows_created_at ON workflows(created_at DESC);
+CREATE INDEX idx_workflows_status ON workflows(status, created_at DESC);
+
+-- The job of a step is kept whole so a waiting step is queued as declared
+CREATE TABLE workflow_steps (
+    workflow_id VARCHAR(64) NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL,
+    parent VARCHAR(255),
+    depends_on JSONB NOT NULL DEFAULT '[]',
+    job_id VARCHAR(255) NOT NULL,
+    job_type VARCHAR(50) NOT NULL,
+    job JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (workflow_id, name),
+    CONSTRAINT chk_workflow_steps_status CHECK (
+        status IN ('waiting', 'queued', 'awaiting_children', 'completed', 'failed', 'cancelled')
+    )
+);
+
+-- Finished jobs are matched to the steps running them
+CREATE INDEX idx_workflow_steps_job_id ON workflow_steps(job_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS workflow_steps;
+DROP TABLE IF EXISTS workflows;
+-- +goose StatementEnd
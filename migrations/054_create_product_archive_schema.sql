@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Weekly products partitions past the retention window are detached and moved
+-- here, so their rows stay restorable without slowing down queries on products
+CREATE SCHEMA IF NOT EXISTS product_archive;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP SCHEMA IF EXISTS product_archive CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Manifest of the archives written by the archiver: every archive is a
+-- gzip-compressed JSONL file of the rows moved out of their table, verified
+-- against its checksum before it is restored
+CREATE TABLE archives (
+    id VARCHAR(100) PRIMARY KEY,
+    data_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'IN_PROGRESS',
+    record_count INTEGER NOT NULL DEFAULT 0,
+    start_date TIMESTAMP WITH TIME ZONE,
+    end_date TIMESTAMP WITH TIME ZONE,
+    archive_size BIGINT NOT NULL DEFAULT 0,
+    uncompressed_size BIGINT NOT NULL DEFAULT 0,
+    storage_path TEXT NOT NULL,
+    compression_type VARCHAR(20) NOT NULL DEFAULT 'gzip',
+    compression_ratio DOUBLE PRECISION NOT NULL DEFAULT 0,
+    checksum VARCHAR(64),
+    is_encrypted BOOLEAN NOT NULL DEFAULT FALSE,
+    retention_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    restorable BOOLEAN NOT NULL DEFAULT TRUE,
+    tags JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    restored_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT chk_archives_status CHECK (status IN ('PENDING', 'IN_PROGRESS', 'COMPLETED', 'FAILED'))
+);
+
+CREATE INDEX idx_archives_data_type ON archives(data_type, created_at DESC);
+CREATE INDEX idx_archives_retention ON archives(retention_until) WHERE status = 'COMPLETED';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS archives;
+-- +goose StatementEnd
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- History of the cleanup service's operations, for statistics
+CREATE TABLE cleanup_operations (
+    id BIGSERIAL PRIMARY KEY,
+    operation_type VARCHAR(50) NOT NULL,
+    files_processed INTEGER NOT NULL DEFAULT 0,
+    files_deleted INTEGER NOT NULL DEFAULT 0,
+    bytes_freed BIGINT NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    errors JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_cleanup_operations_created_at ON cleanup_operations(created_at DESC);
+
+-- Images shared by several flyer pages after deduplication, by content hash.
+-- reference_count is the number of pages using the image; the file is deleted
+-- once it drops to zero.
+CREATE TABLE image_blobs (
+    content_hash VARCHAR(64) PRIMARY KEY,
+    path TEXT NOT NULL UNIQUE,
+    size BIGINT NOT NULL DEFAULT 0,
+    reference_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Cleanups run regularly by the worker, one schedule per cleanup type
+CREATE TABLE cleanup_schedules (
+    type VARCHAR(50) PRIMARY KEY,
+    schedule VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    config JSONB NOT NULL DEFAULT '{}',
+    last_run TIMESTAMP WITH TIME ZONE,
+    next_run TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cleanup_schedules;
+DROP TABLE IF EXISTS image_blobs;
+DROP TABLE IF EXISTS cleanup_operations;
+-- +goose StatementEnd
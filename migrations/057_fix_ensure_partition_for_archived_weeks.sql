@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Only partitions attached to products count as existing: a week whose partition
+-- was detached into product_archive keeps its name there, and products for that
+-- week, like a late flyer or a restored archive, need a new partition.
+CREATE OR REPLACE FUNCTION ensure_partition_for_date(target_date DATE)
+RETURNS text AS $$
+DECLARE
+    start_date date;
+    end_date date;
+    partition_name text;
+BEGIN
+    start_date := date_trunc('week', target_date)::date;
+    end_date := start_date + INTERVAL '7 days';
+    partition_name := 'products_' || to_char(start_date, 'YYYY_WW');
+
+    IF NOT EXISTS (
+        SELECT 1
+        FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'public.products'::regclass
+          AND c.relname = partition_name
+    ) THEN
+        EXECUTE format(
+            'CREATE TABLE public.%I PARTITION OF public.products FOR VALUES FROM (%L) TO (%L)',
+            partition_name, start_date, end_date
+        );
+
+        -- Create indexes on new partition
+        EXECUTE format('CREATE INDEX ON public.%I (flyer_id)', partition_name);
+        EXECUTE format('CREATE INDEX ON public.%I (store_id)', partition_name);
+        EXECUTE format('CREATE INDEX ON public.%I (product_master_id)', partition_name);
+        EXECUTE format('CREATE INDEX ON public.%I USING gin(search_vector)', partition_name);
+        EXECUTE format('CREATE INDEX ON public.%I USING gin(normalized_name gin_trgm_ops)', partition_name);
+    END IF;
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ensure_partition_for_date(target_date DATE)
+RETURNS text AS $$
+DECLARE
+    start_date date;
+    end_date date;
+    partition_name text;
+BEGIN
+    start_date := date_trunc('week', target_date)::date;
+    end_date := start_date + INTERVAL '7 days';
+    partition_name := 'products_' || to_char(start_date, 'YYYY_WW');
+
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_class WHERE relname = partition_name
+    ) THEN
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF products FOR VALUES FROM (%L) TO (%L)',
+            partition_name, start_date, end_date
+        );
+
+        -- Create indexes on new partition
+        EXECUTE format('CREATE INDEX ON %I (flyer_id)', partition_name);
+        EXECUTE format('CREATE INDEX ON %I (store_id)', partition_name);
+        EXECUTE format('CREATE INDEX ON %I (product_master_id)', partition_name);
+        EXECUTE format('CREATE INDEX ON %I USING gin(search_vector)', partition_name);
+        EXECUTE format('CREATE INDEX ON %I USING gin(normalized_name gin_trgm_ops)', partition_name);
+    END IF;
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations, so the migrator runs the ones it
+// was built with wherever it is started from
+package migrations
+
+import "embed"
+
+// FS holds the numbered migrations, including the ones skipped with a .sql.skip suffix
+//
+//go:embed *.sql *.sql.skip
+var FS embed.FS
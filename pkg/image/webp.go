@@ -0,0 +1,74 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// WebPOptions controls how an image is recompressed to WebP
+type WebPOptions struct {
+	Quality   int `json:"quality"`    // 1-100
+	MaxWidth  int `json:"max_width"`  // 0 keeps the width
+	MaxHeight int `json:"max_height"` // 0 keeps the height
+}
+
+// WebPEncoder recompresses images to WebP with ImageMagick, which has the
+// WebP coder where the Go standard library only decodes
+type WebPEncoder struct {
+	command string
+}
+
+// NewWebPEncoder creates an encoder running ImageMagick's convert
+func NewWebPEncoder() *WebPEncoder {
+	return &WebPEncoder{command: "convert"}
+}
+
+// Available reports whether ImageMagick is installed
+func (e *WebPEncoder) Available() bool {
+	_, err := exec.LookPath(e.command)
+	return err == nil
+}
+
+// EncodeWebP reads an image in any format ImageMagick knows and writes it as WebP
+func (e *WebPEncoder) EncodeWebP(ctx context.Context, r io.Reader, w io.Writer, options WebPOptions) error {
+	quality := options.Quality
+	if quality <= 0 || quality > 100 {
+		return fmt.Errorf("invalid WebP quality %d, want 1-100", quality)
+	}
+
+	args := []string{"-"}
+	if options.MaxWidth > 0 || options.MaxHeight > 0 {
+		args = append(args, "-resize", resizeGeometry(options.MaxWidth, options.MaxHeight))
+	}
+	args = append(args, "-strip", "-quality", fmt.Sprintf("%d", quality), "webp:-")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.command, args...)
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("webp conversion failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("webp conversion failed: %w", err)
+	}
+	return nil
+}
+
+// resizeGeometry only shrinks images larger than the bounds, keeping the aspect ratio
+func resizeGeometry(maxWidth, maxHeight int) string {
+	geometry := ""
+	if maxWidth > 0 {
+		geometry += fmt.Sprintf("%d", maxWidth)
+	}
+	geometry += "x"
+	if maxHeight > 0 {
+		geometry += fmt.Sprintf("%d", maxHeight)
+	}
+	return geometry + ">"
+}
@@ -0,0 +1,63 @@
+package normalize
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidGTIN is returned for codes that are not valid EAN/UPC/GTIN barcodes
+var ErrInvalidGTIN = errors.New("invalid GTIN")
+
+// gtinLength is the length of the canonical GTIN-14 form
+const gtinLength = 14
+
+// NormalizeGTIN validates an EAN-8, UPC-A (12), EAN-13 or GTIN-14 barcode and
+// returns it in canonical GTIN-14 form, zero-padded on the left. Spaces and
+// dashes are ignored, so "477 1234 56789 8" and "04771234567898" normalize alike.
+func NormalizeGTIN(code string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == ' ' || r == '-':
+			return -1
+		default:
+			return 'x'
+		}
+	}, strings.TrimSpace(code))
+
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", ErrInvalidGTIN
+	}
+	if strings.ContainsRune(digits, 'x') {
+		return "", ErrInvalidGTIN
+	}
+
+	digits = strings.Repeat("0", gtinLength-len(digits)) + digits
+	if GTINCheckDigit(digits[:gtinLength-1]) != int(digits[gtinLength-1]-'0') {
+		return "", ErrInvalidGTIN
+	}
+	return digits, nil
+}
+
+// IsValidGTIN checks if the code is a valid EAN/UPC/GTIN barcode
+func IsValidGTIN(code string) bool {
+	_, err := NormalizeGTIN(code)
+	return err == nil
+}
+
+// GTINCheckDigit calculates the GS1 mod-10 check digit for the digits preceding it
+func GTINCheckDigit(body string) int {
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		digit := int(body[i] - '0')
+		// Weights alternate 3, 1, 3, ... starting from the rightmost body digit
+		if (len(body)-1-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return (10 - sum%10) % 10
+}
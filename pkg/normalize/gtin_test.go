@@ -0,0 +1,48 @@
+package normalize
+
+import "testing"
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{code: "4006381333931", want: "04006381333931"},   // EAN-13
+		{code: "400-638133393-1", want: "04006381333931"}, // dashes ignored
+		{code: "96385074", want: "00000096385074"},        // EAN-8
+		{code: "036000291452", want: "00036000291452"},    // UPC-A
+		{code: "10012345678902", want: "10012345678902"},  // GTIN-14
+		{code: "4006381333932", wantErr: true},            // wrong check digit
+		{code: "40063813339", wantErr: true},              // unsupported length
+		{code: "40063813339A1", wantErr: true},
+		{code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := NormalizeGTIN(tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGTINCheckDigit(t *testing.T) {
+	if got := GTINCheckDigit("400638133393"); got != 1 {
+		t.Fatalf("expected check digit 1, got %d", got)
+	}
+	if got := GTINCheckDigit("9638507"); got != 4 {
+		t.Fatalf("expected check digit 4, got %d", got)
+	}
+}
@@ -13,6 +13,7 @@ type LithuanianNormalizer struct {
 	stopWords    map[string]bool
 	brandMap     map[string]string
 	patterns     map[string]*regexp.Regexp
+	stemmer      *LithuanianStemmer
 }
 
 // NewLithuanianNormalizer creates a new Lithuanian text normalizer
@@ -22,6 +23,7 @@ func NewLithuanianNormalizer() *LithuanianNormalizer {
 		stopWords:    createStopWords(),
 		brandMap:     createBrandMap(),
 		patterns:     createPatterns(),
+		stemmer:      NewLithuanianStemmer(),
 	}
 }
 
@@ -157,6 +159,12 @@ func (ln *LithuanianNormalizer) NormalizeForSearch(text string) string {
 	return strings.TrimSpace(normalized)
 }
 
+// StemForSearch normalizes text for search and reduces every word to its stem,
+// so inflected forms ("pieno", "pienas") compare equal
+func (ln *LithuanianNormalizer) StemForSearch(text string) string {
+	return ln.stemmer.StemText(ln.NormalizeForSearch(text))
+}
+
 // NormalizeProductName specifically normalizes product names
 func (ln *LithuanianNormalizer) NormalizeProductName(name string) string {
 	if name == "" {
@@ -0,0 +1,89 @@
+package normalize
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// lithuanianSuffixes are noun and adjective case endings with diacritics
+// removed, longest first so that "obuoliai" loses "iai" rather than "ai"
+var lithuanianSuffixes = []string{
+	// 4-5 letters: plural locative, instrumental and dative
+	"iuose", "iomis",
+	"uose", "iose", "iams", "iems", "omis", "emis", "imis", "iais", "ioje", "iaus",
+	// 3 letters
+	"iai", "iui", "iam", "ius", "ies", "ios", "ams", "ems", "oms", "ims",
+	"ais", "ose", "ese", "yse", "oje", "eje", "uje", "aus",
+	// 2 letters
+	"as", "is", "ys", "us", "es", "os", "ai", "ei", "ui", "am", "em", "om", "im",
+	"oj", "ej", "iu", "io", "ia", "ie",
+	// 1 letter
+	"a", "e", "i", "o", "u", "s", "y",
+}
+
+const (
+	// minStemmableLength keeps short words such as units ("vnt", "kg") intact
+	minStemmableLength = 4
+	// minStemLength is the shortest stem left after stripping an ending
+	minStemLength = 2
+)
+
+// LithuanianStemmer reduces inflected Lithuanian words to a common stem by
+// stripping case endings, so that "pienas", "pieno" and "pieną" all become
+// "pien". It is a light suffix stripper for search and matching, not a full
+// morphological analyzer: stems are diacritic-free and not always words.
+type LithuanianStemmer struct {
+	diacriticMap map[rune]rune
+}
+
+// NewLithuanianStemmer creates a new Lithuanian stemmer
+func NewLithuanianStemmer() *LithuanianStemmer {
+	return &LithuanianStemmer{
+		diacriticMap: createDiacriticMap(),
+	}
+}
+
+// Stem returns the stem of a single word. Words containing digits or other
+// non-letters, and words shorter than four letters, are only lowercased and
+// stripped of diacritics.
+func (ls *LithuanianStemmer) Stem(word string) string {
+	folded := ls.fold(word)
+	if utf8.RuneCountInString(folded) < minStemmableLength {
+		return folded
+	}
+	for _, r := range folded {
+		if !unicode.IsLetter(r) {
+			return folded
+		}
+	}
+
+	for _, suffix := range lithuanianSuffixes {
+		if strings.HasSuffix(folded, suffix) &&
+			utf8.RuneCountInString(folded)-utf8.RuneCountInString(suffix) >= minStemLength {
+			return strings.TrimSuffix(folded, suffix)
+		}
+	}
+	return folded
+}
+
+// StemText stems every whitespace-separated word of text
+func (ls *LithuanianStemmer) StemText(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = ls.Stem(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// fold lowercases the word and removes Lithuanian diacritics
+func (ls *LithuanianStemmer) fold(word string) string {
+	var result strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if replacement, exists := ls.diacriticMap[r]; exists {
+			r = replacement
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
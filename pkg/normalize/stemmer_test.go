@@ -0,0 +1,51 @@
+package normalize
+
+import "testing"
+
+func TestLithuanianStemmer_Stem(t *testing.T) {
+	stemmer := NewLithuanianStemmer()
+
+	// Each group lists inflected forms that must share a stem
+	groups := [][]string{
+		{"pienas", "pieno", "pieną", "pienui", "pienu"},
+		{"obuolys", "obuoliai", "obuolių", "obuolius", "obuoliams"},
+		{"sūris", "sūrio", "sūriai", "sūrių"},
+		{"duona", "duonos", "duoną"},
+		{"varškė", "varškės"},
+		{"jogurtas", "jogurtai", "jogurtų"},
+		{"kiaušiniai", "kiaušinių", "kiaušinis"},
+		{"bulvės", "bulvių"},
+		{"ryžiai", "ryžių"},
+		{"vištiena", "VIŠTIENOS", "vistiena"},
+	}
+
+	for _, group := range groups {
+		want := stemmer.Stem(group[0])
+		for _, word := range group[1:] {
+			if got := stemmer.Stem(word); got != want {
+				t.Errorf("expected %q to stem like %q (%q), got %q", word, group[0], want, got)
+			}
+		}
+	}
+}
+
+func TestLithuanianStemmer_KeepsShortAndNumericWords(t *testing.T) {
+	stemmer := NewLithuanianStemmer()
+
+	tests := map[string]string{
+		"vnt":   "vnt",
+		"kg":    "kg",
+		"2,5%":  "2,5%",
+		"500ml": "500ml",
+		"Alus":  "al",
+	}
+	for word, want := range tests {
+		if got := stemmer.Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+
+	if got := stemmer.StemText("Obuolių sultys 1 l"); got != "obuol sult 1 l" {
+		t.Errorf("unexpected StemText result %q", got)
+	}
+}
@@ -479,6 +479,45 @@ func (ue *UnitExtractor) GetPrimaryUnit(units []Unit) *Unit {
 	return bestUnit
 }
 
+// Multipack item counts written before ("6 x 0,5 l") or after ("0,5 l x 6")
+// the item size
+var (
+	packCountPrefix = regexp.MustCompile(`(?i)(\d+)\s*[x×]\s*$`)
+	packCountSuffix = regexp.MustCompile(`(?i)^\s*[x×]\s*(\d+)\b`)
+)
+
+// GetPackSize returns the total size of the package described by text: the
+// primary unit multiplied by the item count of multipacks such as "6 x 0,5 l"
+func (ue *UnitExtractor) GetPackSize(text string) *Unit {
+	primary := ue.GetPrimaryUnit(ue.ExtractUnits(text))
+	if primary == nil {
+		return nil
+	}
+	unit := *primary
+
+	idx := strings.Index(text, unit.Original)
+	if idx < 0 {
+		return &unit
+	}
+
+	count, original := 0, unit.Original
+	if match := packCountPrefix.FindStringSubmatch(text[:idx]); match != nil {
+		count, _ = strconv.Atoi(match[1])
+		original = match[0] + original
+	} else if match := packCountSuffix.FindStringSubmatch(text[idx+len(unit.Original):]); match != nil {
+		count, _ = strconv.Atoi(match[1])
+		original += match[0]
+	}
+	if count <= 1 {
+		return &unit
+	}
+
+	unit.Value *= float64(count)
+	unit.BaseValue *= float64(count)
+	unit.Original = original
+	return &unit
+}
+
 // ConvertUnit converts a unit to a different unit of the same type
 func (ue *UnitExtractor) ConvertUnit(unit Unit, targetUnit string) (*Unit, error) {
 	if unit.BaseValue == 0 || unit.BaseUnit == "" {
@@ -6,11 +6,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/kainuguru/kainuguru-api/pkg/openai")
+
+// imageTokenEstimate approximates the prompt tokens of one high-detail image
+const imageTokenEstimate = 1105
+
+// maxRateLimitBackoff caps the cooldown applied after a 429 response
+const maxRateLimitBackoff = 2 * time.Minute
+
 // ClientConfig holds configuration for OpenAI client
 type ClientConfig struct {
 	APIKey      string        `json:"api_key"`
@@ -68,10 +85,34 @@ func DefaultClientConfig(apiKey string) ClientConfig {
 	}
 }
 
+// RateLimiter throttles requests before they are sent to the provider.
+// Implementations may share their budget across processes (see cache.LLMRateLimiter).
+type RateLimiter interface {
+	// Wait blocks until a request estimated to use the given number of tokens may be sent
+	Wait(ctx context.Context, estimatedTokens int) error
+	// Record reports the tokens a request actually used so the budget can be corrected
+	Record(ctx context.Context, estimatedTokens, actualTokens int)
+}
+
+// RateLimitError is returned when the provider keeps answering 429 after all retries
+type RateLimitError struct {
+	Attempts   int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited after %d attempts (retry after %s)", e.Attempts, e.RetryAfter)
+}
+
 // Client wraps OpenAI API interactions
 type Client struct {
 	config     ClientConfig
 	httpClient *http.Client
+	limiter    RateLimiter
+
+	// A 429 seen by one request pauses every request of this client until the cooldown ends
+	mu            sync.Mutex
+	cooldownUntil time.Time
 }
 
 // NewClient creates a new OpenAI client
@@ -84,6 +125,11 @@ func NewClient(config ClientConfig) *Client {
 	}
 }
 
+// SetRateLimiter installs a limiter consulted before every request
+func (c *Client) SetRateLimiter(limiter RateLimiter) {
+	c.limiter = limiter
+}
+
 // VisionRequest represents a request to OpenAI Vision API
 type VisionRequest struct {
 	Model       string    `json:"model"`
@@ -232,48 +278,71 @@ func (c *Client) BatchAnalyzeImages(ctx context.Context, imageURLs []string, pro
 	return responses, nil
 }
 
-// makeVisionRequest makes the actual HTTP request to OpenAI Vision API
+// makeVisionRequest makes the actual HTTP request to OpenAI Vision API, traced
+// as one span across its retries with the model and the tokens used
 func (c *Client) makeVisionRequest(ctx context.Context, request VisionRequest) (*VisionResponse, error) {
-	// Serialize request
-	requestBody, err := json.Marshal(request)
+	ctx, span := tracer.Start(ctx, "chat "+request.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.GenAIOperationNameChat,
+			semconv.GenAISystemOpenAI,
+			semconv.GenAIRequestModel(request.Model),
+			semconv.GenAIRequestMaxTokens(request.MaxTokens),
+		),
+	)
+	defer span.End()
+
+	response, err := c.sendVisionRequest(ctx, request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
+	span.SetAttributes(
+		semconv.GenAIResponseModel(response.Model),
+		semconv.GenAIUsageInputTokens(response.Usage.PromptTokens),
+		semconv.GenAIUsageOutputTokens(response.Usage.CompletionTokens),
+		attribute.Int("gen_ai.usage.total_tokens", response.Usage.TotalTokens),
+	)
+	return response, nil
+}
 
-	// Create HTTP request
-	url := c.config.BaseURL + "/chat/completions"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+func (c *Client) sendVisionRequest(ctx context.Context, request VisionRequest) (*VisionResponse, error) {
+	// Serialize request
+	requestBody, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-	httpReq.Header.Set("User-Agent", c.config.UserAgent)
+	estimatedTokens := estimateRequestTokens(request)
 
-	// Add OpenRouter specific headers if configured
-	if c.config.Referer != "" {
-		httpReq.Header.Set("HTTP-Referer", c.config.Referer)
-	}
-	if c.config.AppTitle != "" {
-		httpReq.Header.Set("X-Title", c.config.AppTitle)
-	}
-
-	// Make request with retries
-	var response *VisionResponse
+	var lastRateLimit *RateLimitError
 	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
-		resp, err := c.httpClient.Do(httpReq)
+		if err := c.waitForCooldown(ctx); err != nil {
+			return nil, err
+		}
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx, estimatedTokens); err != nil {
+				return nil, fmt.Errorf("rate limiter wait failed: %w", err)
+			}
+		}
+
+		resp, err := c.doRequest(ctx, requestBody)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			if attempt == c.config.MaxRetries {
 				return nil, fmt.Errorf("request failed after %d attempts: %v", c.config.MaxRetries+1, err)
 			}
-			time.Sleep(c.config.RetryDelay * time.Duration(attempt+1))
+			if err := sleepContext(ctx, c.config.RetryDelay*time.Duration(attempt+1)); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
-		defer resp.Body.Close()
 		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			return nil, fmt.Errorf("failed to read response body: %v", err)
 		}
@@ -281,18 +350,32 @@ func (c *Client) makeVisionRequest(ctx context.Context, request VisionRequest) (
 		// Handle different status codes
 		switch resp.StatusCode {
 		case http.StatusOK:
+			var response *VisionResponse
 			if err := json.Unmarshal(body, &response); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal response: %v (body preview: %s)", err, string(body[:min(200, len(body))]))
 			}
+			if c.limiter != nil {
+				c.limiter.Record(ctx, estimatedTokens, response.GetTokenUsage())
+			}
 			return response, nil
 
 		case http.StatusTooManyRequests:
-			if attempt == c.config.MaxRetries {
-				return nil, fmt.Errorf("rate limited after %d attempts", c.config.MaxRetries+1)
+			// Honour Retry-After when present, otherwise back off exponentially with jitter.
+			// The cooldown is shared so that concurrent workers back off together.
+			delay := retryAfter(resp.Header)
+			if delay <= 0 {
+				delay = c.config.RetryDelay * time.Duration(1<<attempt)
+				delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+			}
+			if delay > maxRateLimitBackoff {
+				delay = maxRateLimitBackoff
 			}
-			// Exponential backoff for rate limiting
-			delay := c.config.RetryDelay * time.Duration(1<<attempt)
-			time.Sleep(delay)
+			c.extendCooldown(delay)
+			trace.SpanFromContext(ctx).AddEvent("rate limited", trace.WithAttributes(
+				attribute.Int("attempt", attempt+1),
+				attribute.String("retry_after", delay.String()),
+			))
+			lastRateLimit = &RateLimitError{Attempts: attempt + 1, RetryAfter: delay}
 			continue
 
 		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
@@ -307,7 +390,92 @@ func (c *Client) makeVisionRequest(ctx context.Context, request VisionRequest) (
 		}
 	}
 
-	return response, nil
+	return nil, lastRateLimit
+}
+
+// doRequest sends a single chat completion request; the body is rebuilt for every attempt
+func (c *Client) doRequest(ctx context.Context, requestBody []byte) (*http.Response, error) {
+	url := c.config.BaseURL + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	httpReq.Header.Set("User-Agent", c.config.UserAgent)
+
+	// Add OpenRouter specific headers if configured
+	if c.config.Referer != "" {
+		httpReq.Header.Set("HTTP-Referer", c.config.Referer)
+	}
+	if c.config.AppTitle != "" {
+		httpReq.Header.Set("X-Title", c.config.AppTitle)
+	}
+
+	return c.httpClient.Do(httpReq)
+}
+
+// waitForCooldown blocks while a rate limit cooldown is active
+func (c *Client) waitForCooldown(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Until(c.cooldownUntil)
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, wait)
+}
+
+// extendCooldown pauses all requests of this client for at least the given delay
+func (c *Client) extendCooldown(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(c.cooldownUntil) {
+		c.cooldownUntil = until
+	}
+}
+
+// retryAfter parses the Retry-After header (seconds or HTTP date)
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// estimateRequestTokens approximates the total tokens a request will consume
+func estimateRequestTokens(request VisionRequest) int {
+	tokens := request.MaxTokens
+	for _, msg := range request.Messages {
+		for _, content := range msg.Content {
+			if content.ImageURL != nil {
+				tokens += imageTokenEstimate
+				continue
+			}
+			tokens += EstimateTokens(content.Text)
+		}
+	}
+	return tokens
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // ExtractText analyzes an image and extracts text content
@@ -0,0 +1,118 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recordingLimiter struct {
+	waits    int32
+	recorded int32
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context, estimatedTokens int) error {
+	atomic.AddInt32(&l.waits, 1)
+	return nil
+}
+
+func (l *recordingLimiter) Record(ctx context.Context, estimatedTokens, actualTokens int) {
+	atomic.AddInt32(&l.recorded, int32(actualTokens))
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := DefaultClientConfig("sk-test")
+	cfg.BaseURL = server.URL
+	cfg.RetryDelay = time.Millisecond
+	cfg.MaxRetries = 2
+	return NewClient(cfg)
+}
+
+func TestClient_RetriesAfterRateLimit(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}],"usage":{"total_tokens":42}}`))
+	})
+	limiter := &recordingLimiter{}
+	client.SetRateLimiter(limiter)
+
+	resp, err := client.AnalyzeImage(context.Background(), "https://example.com/page.jpg", "prompt")
+	if err != nil {
+		t.Fatalf("AnalyzeImage returned error: %v", err)
+	}
+	if resp.GetContent() != "ok" {
+		t.Fatalf("unexpected content %q", resp.GetContent())
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls (one 429 retry), got %d", calls)
+	}
+	if limiter.waits != 2 {
+		t.Fatalf("expected limiter to be consulted before every attempt, got %d", limiter.waits)
+	}
+	if limiter.recorded != 42 {
+		t.Fatalf("expected actual token usage to be recorded, got %d", limiter.recorded)
+	}
+}
+
+func TestClient_ReturnsRateLimitErrorWhenRetriesExhausted(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := client.AnalyzeImage(context.Background(), "https://example.com/page.jpg", "prompt")
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if rateErr.Attempts != 3 || calls != 3 {
+		t.Fatalf("expected 3 attempts, got error attempts=%d calls=%d", rateErr.Attempts, calls)
+	}
+}
+
+func TestClient_RateLimitCooldownIsShared(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	client.extendCooldown(50 * time.Millisecond)
+
+	start := time.Now()
+	if err := client.waitForCooldown(context.Background()); err != nil {
+		t.Fatalf("waitForCooldown returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("expected to wait for the cooldown, waited %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.extendCooldown(time.Minute)
+	if err := client.waitForCooldown(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancellation, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	if d := retryAfter(header); d != 0 {
+		t.Fatalf("expected zero without header, got %s", d)
+	}
+	header.Set("Retry-After", "2")
+	if d := retryAfter(header); d != 2*time.Second {
+		t.Fatalf("expected 2s, got %s", d)
+	}
+}